@@ -0,0 +1,154 @@
+//-----------------------------------------------------------------------------
+/*
+
+Model Diffing
+
+Compare two revisions of a model and find the material that has been added
+and removed. The old revision may be an SDF3 or a previously exported mesh.
+
+The added and removed regions are SDF3s, so they can be rendered to meshes
+and viewed together (e.g. added in green, removed in red) to review a change.
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/deadsy/sdfx/obj"
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Default display colors for the diff regions.
+var (
+	AddedColor     = color.RGBA{0, 200, 0, 255}
+	RemovedColor   = color.RGBA{220, 0, 0, 255}
+	UnchangedColor = color.RGBA{160, 160, 160, 255}
+)
+
+// Diff is the material difference between two revisions of a model.
+type Diff struct {
+	Old, New sdf.SDF3 // model revisions
+	Added    sdf.SDF3 // material in the new model but not in the old model
+	Removed  sdf.SDF3 // material in the old model but not in the new model
+}
+
+// NewDiff returns the material difference between two revisions of a model.
+func NewDiff(old, new sdf.SDF3) (*Diff, error) {
+	if old == nil {
+		return nil, sdf.ErrMsg("old == nil")
+	}
+	if new == nil {
+		return nil, sdf.ErrMsg("new == nil")
+	}
+	return &Diff{
+		Old:     old,
+		New:     new,
+		Added:   sdf.Difference3D(new, old),
+		Removed: sdf.Difference3D(old, new),
+	}, nil
+}
+
+// NewMeshDiff returns the material difference between a previously exported
+// mesh (the old revision) and a new revision of a model. The mesh must be a
+// closed surface, see obj.ImportTriMesh.
+func NewMeshDiff(mesh []*sdf.Triangle3, new sdf.SDF3) (*Diff, error) {
+	if len(mesh) == 0 {
+		return nil, sdf.ErrMsg("no triangles")
+	}
+	return NewDiff(obj.ImportTriMesh(mesh, 20, 3, 5), new)
+}
+
+// NewSTLDiff returns the material difference between an STL file (the old
+// revision) and a new revision of a model.
+func NewSTLDiff(path string, new sdf.SDF3) (*Diff, error) {
+	mesh, err := render.LoadSTL(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewMeshDiff(mesh, new)
+}
+
+//-----------------------------------------------------------------------------
+
+// Volume estimates the volume of an SDF3 by sampling the bounding box on a
+// grid with the given number of cells on the longest axis.
+func Volume(s sdf.SDF3, cells int) float64 {
+	bb := s.BoundingBox()
+	size := bb.Size()
+	if cells <= 0 || size.MaxComponent() <= 0 {
+		return 0
+	}
+	resolution := size.MaxComponent() / float64(cells)
+	nx := int(math.Ceil(size.X / resolution))
+	ny := int(math.Ceil(size.Y / resolution))
+	nz := int(math.Ceil(size.Z / resolution))
+	// sample at the cell centers
+	base := bb.Min.AddScalar(0.5 * resolution)
+	n := 0
+	for i := 0; i < nx; i++ {
+		for j := 0; j < ny; j++ {
+			for k := 0; k < nz; k++ {
+				p := base.Add(v3.Vec{float64(i), float64(j), float64(k)}.MulScalar(resolution))
+				if s.Evaluate(p) < 0 {
+					n++
+				}
+			}
+		}
+	}
+	return float64(n) * resolution * resolution * resolution
+}
+
+// Volumes estimates the added and removed volumes of a diff.
+func (d *Diff) Volumes(cells int) (added, removed float64) {
+	return Volume(d.Added, cells), Volume(d.Removed, cells)
+}
+
+// String returns a summary of the diff.
+func (d *Diff) String() string {
+	added, removed := d.Volumes(100)
+	return fmt.Sprintf("added %g, removed %g", added, removed)
+}
+
+//-----------------------------------------------------------------------------
+
+// Meshes renders the added and removed regions to triangle meshes.
+func (d *Diff) Meshes(r render.Render3) (added, removed []*sdf.Triangle3) {
+	return render.ToTriangles(d.Added, r), render.ToTriangles(d.Removed, r)
+}
+
+// To3MF renders the diff to a 3MF file with the added and removed regions as
+// separately colored parts. If unchanged is true the material common to both
+// revisions is included as a third part.
+func (d *Diff) To3MF(path string, r render.Render3, unchanged bool) error {
+	added, removed := d.Meshes(r)
+	parts := []render.Part3MF{
+		{Name: "added", Color: AddedColor, Mesh: added},
+		{Name: "removed", Color: RemovedColor, Mesh: removed},
+	}
+	if unchanged {
+		common := render.ToTriangles(sdf.Intersect3D(d.Old, d.New), r)
+		parts = append(parts, render.Part3MF{Name: "unchanged", Color: UnchangedColor, Mesh: common})
+	}
+	return render.Save3MF(path, parts)
+}
+
+// ToSTL renders the added and removed regions to separate STL files.
+// The files are named <prefix>_added.stl and <prefix>_removed.stl.
+func (d *Diff) ToSTL(prefix string, r render.Render3) error {
+	added, removed := d.Meshes(r)
+	if err := render.SaveSTL(prefix+"_added.stl", added); err != nil {
+		return err
+	}
+	return render.SaveSTL(prefix+"_removed.stl", removed)
+}
+
+//-----------------------------------------------------------------------------
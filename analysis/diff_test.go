@@ -0,0 +1,38 @@
+//-----------------------------------------------------------------------------
+/*
+
+Model Diffing Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Diff(t *testing.T) {
+	old, _ := sdf.Box3D(v3.Vec{10, 10, 10}, 0)
+	// extend the box by 5 in x
+	new, _ := sdf.Box3D(v3.Vec{15, 10, 10}, 0)
+	new = sdf.Transform3D(new, sdf.Translate3d(v3.Vec{2.5, 0, 0}))
+	d, err := NewDiff(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added, removed := d.Volumes(50)
+	if added < 450 || added > 550 {
+		t.Errorf("expected added volume ~500, got %g", added)
+	}
+	if removed != 0 {
+		t.Errorf("expected removed volume 0, got %g", removed)
+	}
+}
+
+//-----------------------------------------------------------------------------
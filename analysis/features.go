@@ -0,0 +1,182 @@
+//-----------------------------------------------------------------------------
+/*
+
+Small Feature Checks
+
+A mesh rendered with a cell size can't show features smaller than a cell:
+walls thinner than a cell break up or vanish, and holes and gaps narrower
+than a cell close up. Which of these happens depends on where the features
+fall on the sampling grid, so a part can look different at slightly
+different resolutions.
+
+SmallFeatures finds the walls, holes and gaps smaller than a cell size: the
+walls are the thin regions of the part, the holes and gaps are the thin
+regions of the space around it. CheckSmallFeatures reports them before
+rendering and can suppress them: the part is closed (filling holes and gaps)
+and then opened (removing walls), so the rendered mesh is the same however
+it falls on the grid.
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// complementSDF3 is the space around a solid.
+type complementSDF3 struct {
+	s sdf.SDF3
+}
+
+func (c *complementSDF3) Evaluate(p v3.Vec) float64 {
+	return -c.s.Evaluate(p)
+}
+
+func (c *complementSDF3) BoundingBox() sdf.Box3 {
+	return c.s.BoundingBox()
+}
+
+//-----------------------------------------------------------------------------
+
+// minFeaturePoints is the number of thin points for a feature. Inexact
+// distance fields give isolated thin points at the edges where surfaces meet.
+const minFeaturePoints = 3
+
+// features returns the thin regions with enough points to be features.
+func features(t *Thickness) []ThinRegion {
+	var regions []ThinRegion
+	for _, r := range t.Regions {
+		if r.Count >= minFeaturePoints {
+			regions = append(regions, r)
+		}
+	}
+	return regions
+}
+
+// Features are the features of a part smaller than a cell size.
+type Features struct {
+	CellSize float64      // render cell size
+	Walls    []ThinRegion // walls thinner than the cell size
+	Gaps     []ThinRegion // holes and gaps narrower than the cell size
+}
+
+// SmallFeatures returns the features of a part smaller than a cell size.
+func SmallFeatures(s sdf.SDF3, cellSize float64) (*Features, error) {
+	if cellSize <= 0 {
+		return nil, sdf.ErrMsg("cellSize <= 0")
+	}
+	walls, err := WallThickness(s, cellSize, 0)
+	if err != nil {
+		return nil, err
+	}
+	gaps, err := WallThickness(&complementSDF3{s}, cellSize, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &Features{CellSize: cellSize, Walls: features(walls), Gaps: features(gaps)}, nil
+}
+
+// OK returns true if the part has no features smaller than the cell size.
+func (f *Features) OK() bool {
+	return len(f.Walls) == 0 && len(f.Gaps) == 0
+}
+
+func (f *Features) String() string {
+	var sb strings.Builder
+	if f.OK() {
+		fmt.Fprintf(&sb, "no features smaller than the cell size %.3g\n", f.CellSize)
+		return sb.String()
+	}
+	report := func(kind string, regions []ThinRegion) {
+		for _, r := range regions {
+			fmt.Fprintf(&sb, "  %s %.3g at %v..%v\n", kind, r.Min, r.Box.Min, r.Box.Max)
+		}
+	}
+	fmt.Fprintf(&sb, "%d walls thinner and %d gaps narrower than the cell size %.3g\n",
+		len(f.Walls), len(f.Gaps), f.CellSize)
+	report("wall", f.Walls)
+	report("gap", f.Gaps)
+	return sb.String()
+}
+
+//-----------------------------------------------------------------------------
+
+// SmallFeatureAction is what to do about features smaller than the render cell size.
+type SmallFeatureAction int
+
+// Small feature actions.
+const (
+	SmallFeaturesIgnore   SmallFeatureAction = iota // render the part as it is
+	SmallFeaturesWarn                               // report the small features
+	SmallFeaturesSuppress                           // report and remove the small features
+)
+
+// ParseSmallFeatureAction returns the action for a name: "ignore" (or ""),
+// "warn" or "suppress".
+func ParseSmallFeatureAction(name string) (SmallFeatureAction, error) {
+	switch name {
+	case "", "ignore":
+		return SmallFeaturesIgnore, nil
+	case "warn":
+		return SmallFeaturesWarn, nil
+	case "suppress":
+		return SmallFeaturesSuppress, nil
+	}
+	return 0, sdf.ErrMsg(fmt.Sprintf("unknown small feature action \"%s\"", name))
+}
+
+// SuppressSmallFeatures returns a part with the walls, holes and gaps smaller
+// than a cell size removed. The result is a grid with half the cell size.
+func SuppressSmallFeatures(s sdf.SDF3, cellSize float64) (sdf.SDF3, error) {
+	if cellSize <= 0 {
+		return nil, sdf.ErrMsg("cellSize <= 0")
+	}
+	// features thinner than twice the radius are removed
+	r := 0.5 * cellSize
+	cell := 0.5 * cellSize
+	g, err := sdf.VoxelizeBand(s, cell, 2*r+3*cell)
+	if err != nil {
+		return nil, err
+	}
+	if g, err = g.Close(r); err != nil {
+		return nil, err
+	}
+	if g, err = g.Open(r); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// CheckSmallFeatures checks a part for features smaller than the render cell
+// size before rendering. The features are reported to a writer, and with
+// the suppress action the part without them is returned.
+func CheckSmallFeatures(s sdf.SDF3, cellSize float64, action SmallFeatureAction, w io.Writer) (sdf.SDF3, error) {
+	if action == SmallFeaturesIgnore {
+		return s, nil
+	}
+	f, err := SmallFeatures(s, cellSize)
+	if err != nil {
+		return nil, err
+	}
+	if f.OK() {
+		return s, nil
+	}
+	fmt.Fprintf(w, "warning: %s", f)
+	if action != SmallFeaturesSuppress {
+		fmt.Fprintf(w, "these features may break up or vanish, use a smaller cell size to render them\n")
+		return s, nil
+	}
+	fmt.Fprintf(w, "suppressing the small features\n")
+	return SuppressSmallFeatures(s, cellSize)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,83 @@
+//-----------------------------------------------------------------------------
+/*
+
+Small Feature Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_SmallFeatures(t *testing.T) {
+	base, _ := sdf.Box3D(v3.Vec{20, 20, 10}, 0)
+	// a 0.4 thick fin
+	fin, _ := sdf.Box3D(v3.Vec{0.4, 10, 8}, 0)
+	fin = sdf.Transform3D(fin, sdf.Translate3d(v3.Vec{0, 0, 9}))
+	// a 0.4 wide slot
+	slot, _ := sdf.Box3D(v3.Vec{10, 0.4, 6}, 0)
+	slot = sdf.Transform3D(slot, sdf.Translate3d(v3.Vec{4, 6, 3}))
+	s := sdf.Difference3D(sdf.Union3D(base, fin), slot)
+
+	f, err := SmallFeatures(base, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.OK() {
+		t.Errorf("expected no small features, got %s", f)
+	}
+	f, err = SmallFeatures(s, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Walls) != 1 || len(f.Gaps) != 1 {
+		t.Fatalf("expected a wall and a gap, got %s", f)
+	}
+	if f.Walls[0].Box.Max.Z < 6 {
+		t.Errorf("expected the wall in the fin, got %s", f)
+	}
+	if r := f.Gaps[0]; r.Box.Min.Y < 5.5 || r.Box.Max.Y > 6.5 {
+		t.Errorf("expected the gap in the slot, got %s", f)
+	}
+
+	// warn only reports
+	var w bytes.Buffer
+	x, err := CheckSmallFeatures(s, 1, SmallFeaturesWarn, &w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x != s || !strings.Contains(w.String(), "1 walls thinner and 1 gaps narrower") {
+		t.Errorf("bad warning %q", w.String())
+	}
+
+	// suppress removes the fin and fills the slot
+	x, err = CheckSmallFeatures(s, 1, SmallFeaturesSuppress, &w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := x.Evaluate(v3.Vec{0, 0, 9}); d <= 0 {
+		t.Errorf("expected the fin to be removed, got %f", d)
+	}
+	if d := x.Evaluate(v3.Vec{4, 6, 3}); d >= 0 {
+		t.Errorf("expected the slot to be filled, got %f", d)
+	}
+	if d := x.Evaluate(v3.Vec{-10, -5, -2}); d > 0.25 || d < -0.25 {
+		t.Errorf("expected the surface, got %f", d)
+	}
+
+	if _, err := ParseSmallFeatureAction("hide"); err == nil {
+		t.Error("expected an error for an unknown action")
+	}
+}
+
+//-----------------------------------------------------------------------------
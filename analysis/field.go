@@ -0,0 +1,64 @@
+//-----------------------------------------------------------------------------
+/*
+
+Analysis Fields
+
+Scalar fields of a part for coloring a mesh (see render.ColorField), sampled
+at points on the surface.
+
+ThicknessField: the distance through the part from a surface point along
+the inward normal. The ray is sphere traced through the part, so an exact
+(or conservative) distance field is needed.
+
+CurvatureField: the mean curvature of the surface, half the laplacian of the
+distance field. It is positive for convex and negative for concave surfaces.
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// ThicknessField returns the thickness of a part through its surface points,
+// up to a maximum thickness.
+func ThicknessField(s sdf.SDF3, maxThickness float64) (sdf.ScalarField, error) {
+	if maxThickness <= 0 {
+		return nil, sdf.ErrMsg("maxThickness <= 0")
+	}
+	eps := 1e-3 * maxThickness
+	return func(p v3.Vec) float64 {
+		n := sdf.Normal3(s, p, eps)
+		// start just inside the surface
+		t := eps + math.Max(s.Evaluate(p), 0)
+		for t < maxThickness {
+			d := s.Evaluate(p.Sub(n.MulScalar(t)))
+			if d >= 0 {
+				return t
+			}
+			t += math.Max(-d, eps)
+		}
+		return maxThickness
+	}, nil
+}
+
+// CurvatureField returns the mean curvature of the surface of a part. The
+// curvature is sampled over a distance h.
+func CurvatureField(s sdf.SDF3, h float64) (sdf.ScalarField, error) {
+	if h <= 0 {
+		return nil, sdf.ErrMsg("h <= 0")
+	}
+	return func(p v3.Vec) float64 {
+		_, lap := gradient(s, p, h)
+		return 0.5 * lap
+	}, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,63 @@
+//-----------------------------------------------------------------------------
+/*
+
+Analysis Field Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_ThicknessField(t *testing.T) {
+	// a 2 thick plate
+	s, _ := sdf.Box3D(v3.Vec{20, 20, 2}, 0)
+	f, err := ThicknessField(s, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		p v3.Vec
+		t float64
+	}{
+		{v3.Vec{0, 0, 1}, 2},
+		{v3.Vec{3, -2, -1}, 2},
+		{v3.Vec{10, 0, 0}, 10}, // across the plate, up to the maximum
+	}
+	for _, test := range tests {
+		if x := f(test.p); math.Abs(x-test.t) > 0.05 {
+			t.Errorf("%v: expected a thickness of %g, got %g", test.p, test.t, x)
+		}
+	}
+	if _, err := ThicknessField(s, 0); err == nil {
+		t.Error("expected an error for a zero maximum")
+	}
+}
+
+func Test_CurvatureField(t *testing.T) {
+	s, _ := sdf.Sphere3D(5)
+	f, err := CurvatureField(s, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k := f(v3.Vec{0, 0, 5}); math.Abs(k-0.2) > 1e-3 {
+		t.Errorf("expected a sphere curvature of 0.2, got %g", k)
+	}
+	box, _ := sdf.Box3D(v3.Vec{20, 20, 20}, 0)
+	hole := sdf.Difference3D(box, s)
+	f, _ = CurvatureField(hole, 0.1)
+	if k := f(v3.Vec{5, 0, 0}); math.Abs(k+0.2) > 1e-3 {
+		t.Errorf("expected a hole curvature of -0.2, got %g", k)
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,109 @@
+//-----------------------------------------------------------------------------
+/*
+
+Mesh Properties
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// MeshVolume returns the volume enclosed by a closed triangle mesh.
+// The triangles must be wound anti-clockwise when viewed from outside.
+func MeshVolume(mesh []*sdf.Triangle3) float64 {
+	v := 0.0
+	for _, t := range mesh {
+		// signed volume of the tetrahedron with the origin
+		v += t[0].Dot(t[1].Cross(t[2]))
+	}
+	return math.Abs(v) / 6
+}
+
+// MeshArea returns the surface area of a triangle mesh.
+func MeshArea(mesh []*sdf.Triangle3) float64 {
+	a := 0.0
+	for _, t := range mesh {
+		a += t[1].Sub(t[0]).Cross(t[2].Sub(t[0])).Length()
+	}
+	return 0.5 * a
+}
+
+// MeshBoundingBox returns the bounding box of a triangle mesh.
+func MeshBoundingBox(mesh []*sdf.Triangle3) sdf.Box3 {
+	if len(mesh) == 0 {
+		return sdf.Box3{}
+	}
+	bb := mesh[0].BoundingBox()
+	for _, t := range mesh[1:] {
+		bb = bb.Extend(t.BoundingBox())
+	}
+	return bb
+}
+
+//-----------------------------------------------------------------------------
+
+// MassProperties are the mass properties of a closed triangle mesh of unit density.
+type MassProperties struct {
+	Volume   float64       // enclosed volume
+	Area     float64       // surface area
+	Centroid v3.Vec        // center of mass
+	Inertia  [3][3]float64 // inertia tensor about the center of mass
+}
+
+// MeshMassProperties returns the mass properties of a closed triangle mesh
+// of unit density. Multiply the volume and inertia by the density for a
+// solid of another density.
+func MeshMassProperties(mesh []*sdf.Triangle3) *MassProperties {
+	// sum the tetrahedra formed with the origin
+	vol := 0.0
+	var moment v3.Vec
+	var c [3][3]float64 // second moments (covariance) about the origin
+	for _, t := range mesh {
+		d := t[0].Dot(t[1].Cross(t[2]))
+		vol += d
+		sum := t[0].Add(t[1]).Add(t[2])
+		moment = moment.Add(sum.MulScalar(d))
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				x := sum.Get(i) * sum.Get(j)
+				for _, p := range t {
+					x += p.Get(i) * p.Get(j)
+				}
+				c[i][j] += d * x
+			}
+		}
+	}
+	mp := &MassProperties{Area: MeshArea(mesh)}
+	if vol == 0 {
+		return mp
+	}
+	// vol is 6x the volume, c is 120x the covariance, negative for a mesh wound inside out
+	mp.Volume = math.Abs(vol) / 6
+	mp.Centroid = moment.DivScalar(4 * vol)
+	// move the covariance to the centroid
+	m := mp.Centroid
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			c[i][j] = c[i][j]/120*math.Copysign(1, vol) - mp.Volume*m.Get(i)*m.Get(j)
+		}
+	}
+	trace := c[0][0] + c[1][1] + c[2][2]
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			mp.Inertia[i][j] = -c[i][j]
+		}
+		mp.Inertia[i][i] += trace
+	}
+	return mp
+}
+
+//-----------------------------------------------------------------------------
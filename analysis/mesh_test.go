@@ -0,0 +1,72 @@
+//-----------------------------------------------------------------------------
+/*
+
+Mesh Properties Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// boxMesh returns the 12 triangles of a box, wound anti-clockwise from outside.
+func boxMesh(b sdf.Box3) []*sdf.Triangle3 {
+	p, q := b.Min, b.Max
+	v := []v3.Vec{
+		{p.X, p.Y, p.Z}, {q.X, p.Y, p.Z}, {q.X, q.Y, p.Z}, {p.X, q.Y, p.Z},
+		{p.X, p.Y, q.Z}, {q.X, p.Y, q.Z}, {q.X, q.Y, q.Z}, {p.X, q.Y, q.Z},
+	}
+	quads := [][4]int{
+		{0, 3, 2, 1}, // -z
+		{4, 5, 6, 7}, // +z
+		{0, 1, 5, 4}, // -y
+		{3, 7, 6, 2}, // +y
+		{0, 4, 7, 3}, // -x
+		{1, 2, 6, 5}, // +x
+	}
+	var mesh []*sdf.Triangle3
+	for _, q := range quads {
+		mesh = append(mesh, &sdf.Triangle3{v[q[0]], v[q[1]], v[q[2]]}, &sdf.Triangle3{v[q[0]], v[q[2]], v[q[3]]})
+	}
+	return mesh
+}
+
+func Test_MeshMassProperties(t *testing.T) {
+	// 2 x 4 x 6 box centered on (1, 2, 3)
+	mesh := boxMesh(sdf.Box3{Min: v3.Vec{0, 0, 0}, Max: v3.Vec{2, 4, 6}})
+	mp := MeshMassProperties(mesh)
+	const tol = 1e-9
+	if math.Abs(mp.Volume-48) > tol || math.Abs(mp.Area-88) > tol {
+		t.Errorf("expected volume 48 area 88, got %f %f", mp.Volume, mp.Area)
+	}
+	if mp.Centroid.Sub(v3.Vec{1, 2, 3}).Length() > tol {
+		t.Errorf("expected centroid (1, 2, 3), got %v", mp.Centroid)
+	}
+	// I = m (b^2 + c^2) / 12
+	want := [3][3]float64{{208, 0, 0}, {0, 160, 0}, {0, 0, 80}}
+	for i := range want {
+		for j := range want[i] {
+			if math.Abs(mp.Inertia[i][j]-want[i][j]) > 1e-6 {
+				t.Fatalf("expected inertia %v, got %v", want, mp.Inertia)
+			}
+		}
+	}
+	// an inside out mesh has the same properties
+	for _, tri := range mesh {
+		tri[1], tri[2] = tri[2], tri[1]
+	}
+	if mp2 := MeshMassProperties(mesh); *mp2 != *mp {
+		t.Errorf("expected %v, got %v", mp, mp2)
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,220 @@
+//-----------------------------------------------------------------------------
+/*
+
+Overhang Analysis
+
+Find the downward facing surfaces of a part that exceed an overhang angle
+for a build direction. These are the surfaces that need support when the
+part is printed.
+
+The overhang angle is measured from the vertical, so a vertical wall has an
+overhang of 0 degrees and a downward facing horizontal surface has an
+overhang of 90 degrees. Surfaces resting on the build plate are excluded.
+
+The surface is sampled on a grid and the surface normals come from the SDF
+gradient. Each grid point within half a cell of the surface represents a
+patch of the surface, so the overhang area is the sum of the patch areas.
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// DefaultCells is the default number of sampling cells on the longest axis of a part.
+const DefaultCells = 100
+
+// SurfacePoint is a sampled point on the surface of a part.
+type SurfacePoint struct {
+	Point  v3.Vec  // position on the surface
+	Normal v3.Vec  // outward surface normal
+	Area   float64 // area of the surface patch
+}
+
+// SurfaceSamples samples the surface of a part on a grid with the given cells on its longest axis.
+// Returns the surface points and the sampling resolution.
+func SurfaceSamples(s sdf.SDF3, cells int) ([]SurfacePoint, float64) {
+	bb := s.BoundingBox()
+	size := bb.Size()
+	h := size.MaxComponent() / float64(cells)
+	eps := h * 0.01
+	// grid points within this distance of the surface are projected onto it
+	near := 0.5 * h
+	var points []SurfacePoint
+	nx := int(math.Ceil(size.X/h)) + 1
+	ny := int(math.Ceil(size.Y/h)) + 1
+	nz := int(math.Ceil(size.Z/h)) + 1
+	for i := 0; i < nx; i++ {
+		for j := 0; j < ny; j++ {
+			for k := 0; k < nz; k++ {
+				p := bb.Min.Add(v3.Vec{float64(i), float64(j), float64(k)}.MulScalar(h))
+				d := s.Evaluate(p)
+				if math.Abs(d) > near {
+					continue
+				}
+				g, _ := gradient(s, p, eps)
+				gl := g.Length()
+				if gl == 0 {
+					continue
+				}
+				n := g.DivScalar(gl)
+				// the band |d| < h/2 is h/|g| thick, so this grid cell holds h*h*|g| of surface
+				points = append(points, SurfacePoint{p.Sub(n.MulScalar(d)), n, h * h * gl})
+			}
+		}
+	}
+	return points, h
+}
+
+// OverhangPoint is a sampled point on an overhanging surface.
+type OverhangPoint struct {
+	SurfacePoint
+	Angle float64 // overhang angle (radians)
+}
+
+// Overhang is the result of an overhang analysis.
+type Overhang struct {
+	BuildDir      v3.Vec          // build direction
+	MaxAngle      float64         // maximum overhang angle without support (radians)
+	Resolution    float64         // sampling resolution
+	Points        []OverhangPoint // overhanging surface points
+	Area          float64         // area of the overhanging surfaces
+	ProjectedArea float64         // area of the overhanging surfaces projected onto the build plate
+	SurfaceArea   float64         // total surface area of the part
+	sdf           sdf.SDF3
+}
+
+//-----------------------------------------------------------------------------
+
+// OverhangParms defines the parameters for an overhang analysis.
+type OverhangParms struct {
+	BuildDir  v3.Vec  // build direction
+	MaxAngle  float64 // maximum overhang angle without support (radians)
+	Cells     int     // sampling cells on the longest axis of the part
+	PlateSkip float64 // height above the build plate to ignore (0 = the sampling resolution)
+}
+
+// Overhangs returns the surfaces of a part that exceed the overhang angle (radians)
+// for the build direction. The part is sampled with DefaultCells on its longest axis.
+func Overhangs(s sdf.SDF3, buildDir v3.Vec, maxAngle float64) (*Overhang, error) {
+	return OverhangsCells(s, buildDir, maxAngle, DefaultCells)
+}
+
+// OverhangsCells returns the surfaces of a part that exceed the overhang angle (radians)
+// for the build direction. The part is sampled with the given cells on its longest axis.
+func OverhangsCells(s sdf.SDF3, buildDir v3.Vec, maxAngle float64, cells int) (*Overhang, error) {
+	return OverhangsWithParms(s, &OverhangParms{BuildDir: buildDir, MaxAngle: maxAngle, Cells: cells})
+}
+
+// OverhangsWithParms returns the surfaces of a part that exceed the overhang angle
+// for the build direction of the parameters.
+func OverhangsWithParms(s sdf.SDF3, k *OverhangParms) (*Overhang, error) {
+	if s == nil {
+		return nil, sdf.ErrMsg("s == nil")
+	}
+	if k.BuildDir.Length() == 0 {
+		return nil, sdf.ErrMsg("buildDir == 0")
+	}
+	if k.MaxAngle < 0 || k.MaxAngle > 0.5*sdf.Pi {
+		return nil, sdf.ErrMsg("maxAngle must be 0..pi/2")
+	}
+	if k.Cells <= 0 {
+		return nil, sdf.ErrMsg("cells <= 0")
+	}
+	if k.PlateSkip < 0 {
+		return nil, sdf.ErrMsg("PlateSkip < 0")
+	}
+	points, h := SurfaceSamples(s, k.Cells)
+	skip := k.PlateSkip
+	if skip == 0 {
+		skip = h
+	}
+	return newOverhang(s, points, h, k.BuildDir.Normalize(), k.MaxAngle, skip), nil
+}
+
+// newOverhang returns the overhangs of a part from its surface samples.
+// Surfaces within skip of the build plate are excluded.
+func newOverhang(s sdf.SDF3, points []SurfacePoint, h float64, up v3.Vec, maxAngle, skip float64) *Overhang {
+	o := &Overhang{
+		BuildDir:   up,
+		MaxAngle:   maxAngle,
+		Resolution: h,
+		sdf:        s,
+	}
+	// the build plate is the lowest point of the bounding box
+	plate := math.MaxFloat64
+	for _, v := range s.BoundingBox().Vertices() {
+		plate = math.Min(plate, v.Dot(up))
+	}
+	for _, sp := range points {
+		o.SurfaceArea += sp.Area
+		down := -sp.Normal.Dot(up)
+		if down <= 0 {
+			// upward facing
+			continue
+		}
+		if sp.Point.Dot(up)-plate < skip {
+			// on the build plate
+			continue
+		}
+		angle := math.Asin(math.Min(down, 1))
+		if angle <= maxAngle {
+			continue
+		}
+		o.Points = append(o.Points, OverhangPoint{sp, angle})
+		o.Area += sp.Area
+		o.ProjectedArea += sp.Area * down
+	}
+	return o
+}
+
+//-----------------------------------------------------------------------------
+
+// OK returns true if the part has no overhangs that need support.
+func (o *Overhang) OK() bool {
+	return len(o.Points) == 0
+}
+
+func (o *Overhang) String() string {
+	if o.OK() {
+		return fmt.Sprintf("no overhangs over %.1f degrees (surface area %.4g)", sdf.RtoD(o.MaxAngle), o.SurfaceArea)
+	}
+	return fmt.Sprintf("overhangs over %.1f degrees: area %.4g (%.1f%% of %.4g), projected area %.4g",
+		sdf.RtoD(o.MaxAngle), o.Area, 100*o.Area/o.SurfaceArea, o.SurfaceArea, o.ProjectedArea)
+}
+
+// Mask returns an SDF3 for the part material just inside the overhanging surfaces.
+// It returns nil if there are no overhangs.
+func (o *Overhang) Mask() sdf.SDF3 {
+	if o.OK() {
+		return nil
+	}
+	centers := make([]v3.Vec, len(o.Points))
+	radii := make([]float64, len(o.Points))
+	for i, p := range o.Points {
+		centers[i] = p.Point
+		radii[i] = o.Resolution
+	}
+	return sdf.Intersect3D(o.sdf, newBallsSDF3(centers, radii))
+}
+
+// Mesh renders the overhang mask. It returns nil if there are no overhangs.
+func (o *Overhang) Mesh(r render.Render3) []*sdf.Triangle3 {
+	s := o.Mask()
+	if s == nil {
+		return nil
+	}
+	return render.ToTriangles(s, r)
+}
+
+//-----------------------------------------------------------------------------
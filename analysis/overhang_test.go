@@ -0,0 +1,78 @@
+//-----------------------------------------------------------------------------
+/*
+
+Overhang Analysis Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// near returns true if x is within a fraction of an expected value.
+func near(x, expected, fraction float64) bool {
+	return math.Abs(x-expected) <= fraction*math.Abs(expected)
+}
+
+func Test_Overhangs(t *testing.T) {
+	up := v3.Vec{0, 0, 1}
+
+	// a box has no overhangs, the bottom face is on the build plate
+	box, _ := sdf.Box3D(v3.Vec{20, 10, 5}, 0)
+	o, err := Overhangs(box, up, sdf.DtoR(45))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !o.OK() || !near(o.SurfaceArea, 2*(200+100+50), 0.05) {
+		t.Errorf("box: %s", o)
+	}
+	// upside down the box still rests on a face
+	if o, _ = Overhangs(box, up.Neg(), sdf.DtoR(45)); !o.OK() {
+		t.Errorf("inverted box: %s", o)
+	}
+
+	// an inverted cone (radius 2 at the bottom, 12 at the top) overhangs at 45 degrees
+	cone, _ := sdf.Cone3D(10, 2, 12, 0)
+	lateral := sdf.Pi * (2 + 12) * math.Sqrt(10*10+10*10)
+	support := sdf.Pi * (12*12 - 2*2)
+	o, _ = Overhangs(cone, up, sdf.DtoR(40))
+	if o.OK() || !near(o.Area, lateral, 0.05) || !near(o.ProjectedArea, support, 0.05) {
+		t.Errorf("cone: expected area %.4g, projected area %.4g, got %s", lateral, support, o)
+	}
+	for _, p := range o.Points {
+		if math.Abs(p.Angle-sdf.DtoR(45)) > sdf.DtoR(5) {
+			t.Errorf("cone: %v: expected 45 degrees, got %.1f", p.Point, sdf.RtoD(p.Angle))
+			break
+		}
+	}
+	if o, _ = Overhangs(cone, up, sdf.DtoR(50)); !o.OK() {
+		t.Errorf("cone: expected no overhangs over 50 degrees, got %s", o)
+	}
+	// skipping the lower half of the cone halves the support area
+	o, _ = OverhangsWithParms(cone, &OverhangParms{BuildDir: up, MaxAngle: sdf.DtoR(40), Cells: DefaultCells, PlateSkip: 5})
+	if !near(o.ProjectedArea, sdf.Pi*(12*12-7*7), 0.05) {
+		t.Errorf("cone: expected projected area %.4g above the skip, got %s", sdf.Pi*(12*12-7*7), o)
+	}
+
+	for _, k := range []OverhangParms{
+		{BuildDir: v3.Vec{}, MaxAngle: 1, Cells: 10},
+		{BuildDir: up, MaxAngle: -1, Cells: 10},
+		{BuildDir: up, MaxAngle: 1, Cells: 0},
+		{BuildDir: up, MaxAngle: 1, Cells: 10, PlateSkip: -1},
+	} {
+		if _, err := OverhangsWithParms(cone, &k); err == nil {
+			t.Errorf("expected an error for %+v", k)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
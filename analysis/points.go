@@ -0,0 +1,132 @@
+//-----------------------------------------------------------------------------
+/*
+
+Point Sets
+
+Spatial hashing of sampled points and the union of balls on the points.
+Used to group the sampled points of an analysis into regions and to build
+an SDF3 that highlights the regions.
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// pointGrid is a spatial hash of points.
+type pointGrid struct {
+	size  float64
+	cells map[v3i.Vec][]int
+}
+
+func newPointGrid(points []v3.Vec, size float64) *pointGrid {
+	g := &pointGrid{size: size, cells: make(map[v3i.Vec][]int)}
+	for i, p := range points {
+		k := g.key(p)
+		g.cells[k] = append(g.cells[k], i)
+	}
+	return g
+}
+
+func (g *pointGrid) key(p v3.Vec) v3i.Vec {
+	return v3i.Vec{
+		int(math.Floor(p.X / g.size)),
+		int(math.Floor(p.Y / g.size)),
+		int(math.Floor(p.Z / g.size)),
+	}
+}
+
+// near calls fn for the points in the cells neighboring p.
+func (g *pointGrid) near(p v3.Vec, fn func(i int)) {
+	k := g.key(p)
+	for x := k.X - 1; x <= k.X+1; x++ {
+		for y := k.Y - 1; y <= k.Y+1; y++ {
+			for z := k.Z - 1; z <= k.Z+1; z++ {
+				for _, i := range g.cells[v3i.Vec{x, y, z}] {
+					fn(i)
+				}
+			}
+		}
+	}
+}
+
+// clusters groups points into connected clusters. Points within link of each
+// other are connected. Returns the cluster of each point and the number of clusters.
+// Clusters are numbered in the order of their first point.
+func clusters(points []v3.Vec, link float64) ([]int, int) {
+	grid := newPointGrid(points, link)
+	cluster := make([]int, len(points))
+	for i := range cluster {
+		cluster[i] = -1
+	}
+	n := 0
+	for i := range points {
+		if cluster[i] >= 0 {
+			continue
+		}
+		// flood fill a new cluster
+		cluster[i] = n
+		stack := []int{i}
+		for len(stack) > 0 {
+			j := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			grid.near(points[j], func(k int) {
+				if cluster[k] < 0 && points[k].Sub(points[j]).Length() <= link {
+					cluster[k] = n
+					stack = append(stack, k)
+				}
+			})
+		}
+		n++
+	}
+	return cluster, n
+}
+
+//-----------------------------------------------------------------------------
+
+// ballsSDF3 is a union of balls.
+type ballsSDF3 struct {
+	centers []v3.Vec
+	radii   []float64
+	rMax    float64 // maximum ball radius
+	grid    *pointGrid
+	bb      sdf.Box3
+}
+
+func newBallsSDF3(centers []v3.Vec, radii []float64) *ballsSDF3 {
+	s := &ballsSDF3{centers: centers, radii: radii}
+	bb := sdf.Box3{Min: centers[0], Max: centers[0]}
+	for i, c := range centers {
+		s.rMax = math.Max(s.rMax, radii[i])
+		bb = bb.Include(c)
+	}
+	// points outside the neighboring cells are at least 2*rMax away
+	s.grid = newPointGrid(centers, 2*s.rMax)
+	s.bb = bb.Enlarge(v3.Vec{1, 1, 1}.MulScalar(2 * s.rMax))
+	return s
+}
+
+// Evaluate returns the minimum distance to the balls.
+func (s *ballsSDF3) Evaluate(p v3.Vec) float64 {
+	d := s.rMax
+	s.grid.near(p, func(i int) {
+		d = math.Min(d, p.Sub(s.centers[i]).Length()-s.radii[i])
+	})
+	return d
+}
+
+// BoundingBox returns the bounding box of the balls.
+func (s *ballsSDF3) BoundingBox() sdf.Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,362 @@
+//-----------------------------------------------------------------------------
+/*
+
+Printability Report
+
+Score a part for a printing process (FDM, SLA, SLS) as it is oriented,
+with +z as the build direction and the build plate at the bottom of its
+bounding box. The report collects:
+
+- flat base: the area of the downward facing surfaces on the build plate,
+  relative to the footprint of the part.
+- overhangs: the surfaces that exceed the overhang angle of the process.
+- trapped volumes: enclosed voids that can't drain resin or powder.
+- minimum feature size: walls thinner than the process can make.
+
+Each issue found reduces the score (0..100) by a penalty, and is reported
+with its location so it can be found in the model. The penalties are
+heuristic: the score is for comparing designs and orientations, not a
+prediction that a print will succeed.
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// PrintProcess defines the limits and penalties of a printing process.
+type PrintProcess struct {
+	Name        string
+	MaxOverhang float64 // overhang angle from vertical without support (radians)
+	MinFeature  float64 // minimum wall thickness
+	MinBase     float64 // minimum base area relative to the footprint (0 = not needed)
+	Trapped     bool    // trapped volumes can't be cleaned out
+	Overhang    float64 // penalty per percent of overhanging surface
+}
+
+// Printing processes.
+var (
+	ProcessFDM = &PrintProcess{"FDM", sdf.DtoR(45), 0.8, 0.1, false, 1}
+	ProcessSLA = &PrintProcess{"SLA", sdf.DtoR(60), 0.3, 0, true, 0.5}
+	ProcessSLS = &PrintProcess{"SLS", 0.5 * sdf.Pi, 0.7, 0, true, 0}
+)
+
+// Process returns the printing process for a process name (e.g. material.PLA.Process).
+func Process(name string) (*PrintProcess, error) {
+	for _, p := range []*PrintProcess{ProcessFDM, ProcessSLA, ProcessSLS} {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+	}
+	return nil, sdf.ErrMsg(fmt.Sprintf("unknown process \"%s\"", name))
+}
+
+// Penalty limits for each kind of issue.
+const (
+	maxOverhangPenalty = 40.0
+	maxBasePenalty     = 20.0
+	trappedPenalty     = 15.0 // per trapped volume
+	maxTrappedPenalty  = 30.0
+	featurePenalty     = 10.0 // per thin region
+	maxFeaturePenalty  = 30.0
+)
+
+// printBaseAngle is the maximum angle from horizontal for a surface to rest on the build plate.
+var printBaseAngle = sdf.DtoR(5)
+
+//-----------------------------------------------------------------------------
+
+// IssueKind is the kind of a printability issue.
+type IssueKind int
+
+// Printability issue kinds.
+const (
+	IssueBase     IssueKind = iota // small flat base
+	IssueOverhang                  // overhang that needs support
+	IssueTrapped                   // trapped volume
+	IssueFeature                   // feature smaller than the process minimum
+)
+
+func (k IssueKind) String() string {
+	switch k {
+	case IssueBase:
+		return "base"
+	case IssueOverhang:
+		return "overhang"
+	case IssueTrapped:
+		return "trapped"
+	case IssueFeature:
+		return "feature"
+	}
+	return "unknown"
+}
+
+// PrintIssue is a printability issue at a location of the part.
+type PrintIssue struct {
+	Kind    IssueKind
+	Box     sdf.Box3 // location of the issue
+	Value   float64  // base area, overhang area, trapped volume or thickness
+	Penalty float64  // score penalty
+}
+
+// Printability is the result of a printability analysis.
+type Printability struct {
+	Process         *PrintProcess
+	Resolution      float64      // sampling resolution
+	SurfaceArea     float64      // total surface area
+	BaseArea        float64      // area resting on the build plate
+	BaseFraction    float64      // base area relative to the footprint
+	OverhangArea    float64      // area of the overhanging surfaces
+	OverhangPercent float64      // overhanging surface as a percentage of the surface area
+	TrappedVolume   float64      // total trapped volume
+	MinFeature      float64      // minimum feature thickness found, 0 if none are too thin
+	Score           float64      // printability score 0..100 (higher is better)
+	Issues          []PrintIssue // issues, largest penalty first
+}
+
+//-----------------------------------------------------------------------------
+
+// PrintabilityReport analyzes a part for a printing process. The part is
+// sampled with the given cells on its longest axis (0 for DefaultCells).
+func PrintabilityReport(s sdf.SDF3, p *PrintProcess, cells int) (*Printability, error) {
+	if s == nil {
+		return nil, sdf.ErrMsg("s == nil")
+	}
+	if p == nil {
+		return nil, sdf.ErrMsg("p == nil")
+	}
+	if cells < 0 {
+		return nil, sdf.ErrMsg("cells < 0")
+	}
+	if p.MaxOverhang < 0 || p.MaxOverhang > 0.5*sdf.Pi {
+		return nil, sdf.ErrMsg("MaxOverhang must be 0..pi/2")
+	}
+	if p.MinFeature <= 0 {
+		return nil, sdf.ErrMsg("MinFeature <= 0")
+	}
+	if cells == 0 {
+		cells = DefaultCells
+	}
+	points, h := SurfaceSamples(s, cells)
+	o := newOverhang(s, points, h, v3.Vec{0, 0, 1}, p.MaxOverhang, h)
+	r := &Printability{
+		Process:      p,
+		Resolution:   o.Resolution,
+		SurfaceArea:  o.SurfaceArea,
+		OverhangArea: o.Area,
+	}
+	if o.SurfaceArea > 0 {
+		r.OverhangPercent = 100 * o.Area / o.SurfaceArea
+	}
+	r.base(s, points)
+	r.overhangs(o)
+	r.trapped(s)
+	if err := r.features(s); err != nil {
+		return nil, err
+	}
+	// aggregate the penalties
+	sort.SliceStable(r.Issues, func(i, j int) bool {
+		return r.Issues[i].Penalty > r.Issues[j].Penalty
+	})
+	r.Score = 100
+	for _, x := range r.Issues {
+		r.Score -= x.Penalty
+	}
+	r.Score = math.Max(r.Score, 0)
+	return r, nil
+}
+
+// base finds the area of the part resting on the build plate.
+func (r *Printability) base(s sdf.SDF3, points []SurfacePoint) {
+	h := r.Resolution
+	bb := s.BoundingBox()
+	size := bb.Size()
+	minBase := math.Cos(printBaseAngle)
+	var box sdf.Box3
+	for _, sp := range points {
+		if sp.Point.Z-bb.Min.Z < h && -sp.Normal.Z >= minBase {
+			if r.BaseArea == 0 {
+				box = sdf.Box3{Min: sp.Point, Max: sp.Point}
+			}
+			box = box.Include(sp.Point)
+			r.BaseArea += sp.Area
+		}
+	}
+	footprint := size.X * size.Y
+	if footprint > 0 {
+		r.BaseFraction = r.BaseArea / footprint
+	}
+	if r.BaseFraction >= r.Process.MinBase {
+		return
+	}
+	if r.BaseArea == 0 {
+		// the lowest part of the bounding box
+		box = sdf.Box3{Min: bb.Min, Max: v3.Vec{bb.Max.X, bb.Max.Y, bb.Min.Z + h}}
+	}
+	r.Issues = append(r.Issues, PrintIssue{
+		Kind:    IssueBase,
+		Box:     box,
+		Value:   r.BaseArea,
+		Penalty: maxBasePenalty * (1 - r.BaseFraction/r.Process.MinBase),
+	})
+}
+
+// overhangs groups the overhanging surface points into issues.
+func (r *Printability) overhangs(o *Overhang) {
+	if o.OK() || r.Process.Overhang == 0 {
+		return
+	}
+	points := make([]v3.Vec, len(o.Points))
+	for i, p := range o.Points {
+		points[i] = p.Point
+	}
+	cluster, n := clusters(points, 2*r.Resolution)
+	issues := make([]PrintIssue, n)
+	for i, p := range o.Points {
+		x := &issues[cluster[i]]
+		if x.Value == 0 {
+			x.Box = sdf.Box3{Min: p.Point, Max: p.Point}
+		}
+		x.Kind = IssueOverhang
+		x.Box = x.Box.Include(p.Point)
+		x.Value += p.Area
+	}
+	// share the overhang penalty by area
+	penalty := math.Min(r.Process.Overhang*r.OverhangPercent, maxOverhangPenalty)
+	for i := range issues {
+		issues[i].Penalty = penalty * issues[i].Value / o.Area
+	}
+	r.Issues = append(r.Issues, issues...)
+}
+
+// trapped finds the voids of the part that are not connected to the outside.
+func (r *Printability) trapped(s sdf.SDF3) {
+	if !r.Process.Trapped {
+		return
+	}
+	h := r.Resolution
+	// the grid has an outside layer of samples around the part
+	bb := s.BoundingBox()
+	bb = sdf.Box3{Min: bb.Min.SubScalar(h), Max: bb.Max.AddScalar(h)}
+	size := bb.Size()
+	n := v3i.Vec{
+		int(math.Ceil(size.X/h)) + 1,
+		int(math.Ceil(size.Y/h)) + 1,
+		int(math.Ceil(size.Z/h)) + 1,
+	}
+	index := func(i, j, k int) int { return i + n.X*(j+n.Y*k) }
+	position := func(i, j, k int) v3.Vec {
+		return bb.Min.Add(v3.Vec{float64(i), float64(j), float64(k)}.MulScalar(h))
+	}
+	// grid point states, trapped volumes are numbered from outside + 1
+	const (
+		material = iota
+		void
+		outside
+	)
+	state := make([]int, n.X*n.Y*n.Z)
+	for k := 0; k < n.Z; k++ {
+		for j := 0; j < n.Y; j++ {
+			for i := 0; i < n.X; i++ {
+				if s.Evaluate(position(i, j, k)) > 0 {
+					state[index(i, j, k)] = void
+				}
+			}
+		}
+	}
+	// flood fill a region of voids from a grid point
+	fill := func(i, j, k, id int) (int, sdf.Box3) {
+		count := 0
+		box := sdf.Box3{Min: position(i, j, k), Max: position(i, j, k)}
+		state[index(i, j, k)] = id
+		stack := []v3i.Vec{{i, j, k}}
+		for len(stack) > 0 {
+			p := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			count++
+			box = box.Include(position(p.X, p.Y, p.Z))
+			for _, d := range []v3i.Vec{{1, 0, 0}, {-1, 0, 0}, {0, 1, 0}, {0, -1, 0}, {0, 0, 1}, {0, 0, -1}} {
+				q := p.Add(d)
+				if q.X < 0 || q.Y < 0 || q.Z < 0 || q.X >= n.X || q.Y >= n.Y || q.Z >= n.Z {
+					continue
+				}
+				if x := index(q.X, q.Y, q.Z); state[x] == void {
+					state[x] = id
+					stack = append(stack, q)
+				}
+			}
+		}
+		return count, box
+	}
+	fill(0, 0, 0, outside)
+	penalty := 0.0
+	id := outside + 1
+	for k := 0; k < n.Z; k++ {
+		for j := 0; j < n.Y; j++ {
+			for i := 0; i < n.X; i++ {
+				if state[index(i, j, k)] != void {
+					continue
+				}
+				count, box := fill(i, j, k, id)
+				id++
+				volume := float64(count) * h * h * h
+				r.TrappedVolume += volume
+				x := math.Min(trappedPenalty, maxTrappedPenalty-penalty)
+				penalty += x
+				r.Issues = append(r.Issues, PrintIssue{IssueTrapped, box, volume, x})
+			}
+		}
+	}
+}
+
+// features finds the parts of the model thinner than the process minimum feature size.
+func (r *Printability) features(s sdf.SDF3) error {
+	t, err := WallThickness(s, r.Process.MinFeature, 0)
+	if err != nil {
+		return err
+	}
+	r.MinFeature = t.Min()
+	penalty := 0.0
+	for _, x := range t.Regions {
+		y := math.Min(featurePenalty, maxFeaturePenalty-penalty)
+		penalty += y
+		r.Issues = append(r.Issues, PrintIssue{IssueFeature, x.Box, x.Min, y})
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// OK returns true if there are no printability issues.
+func (r *Printability) OK() bool {
+	return len(r.Issues) == 0
+}
+
+func (r *Printability) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s printability %.0f/100 (resolution %g)\n", r.Process.Name, r.Score, r.Resolution)
+	fmt.Fprintf(&sb, "  base area %.4g (%.1f%% of footprint)\n", r.BaseArea, 100*r.BaseFraction)
+	fmt.Fprintf(&sb, "  overhangs %.4g (%.1f%% of surface)\n", r.OverhangArea, r.OverhangPercent)
+	fmt.Fprintf(&sb, "  trapped volume %.4g\n", r.TrappedVolume)
+	if r.MinFeature > 0 {
+		fmt.Fprintf(&sb, "  minimum feature %.3g (limit %g)\n", r.MinFeature, r.Process.MinFeature)
+	}
+	for i, x := range r.Issues {
+		fmt.Fprintf(&sb, "  %d: %s %.4g at %v..%v (-%.1f)\n", i, x.Kind, x.Value, x.Box.Min, x.Box.Max, x.Penalty)
+	}
+	return sb.String()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,64 @@
+//-----------------------------------------------------------------------------
+/*
+
+Printability Report Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Printability(t *testing.T) {
+	// a solid block is fine for any process
+	block, _ := sdf.Box3D(v3.Vec{20, 20, 10}, 0)
+	for _, p := range []*PrintProcess{ProcessFDM, ProcessSLA, ProcessSLS} {
+		r, err := PrintabilityReport(block, p, 50)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !r.OK() || r.Score != 100 {
+			t.Errorf("expected no issues, got %s", r)
+		}
+	}
+
+	// a sealed hollow block traps resin but prints on FDM
+	void, _ := sdf.Box3D(v3.Vec{10, 10, 4}, 0)
+	hollow := sdf.Difference3D(block, void)
+	r, _ := PrintabilityReport(hollow, ProcessSLA, 50)
+	if r.TrappedVolume < 0.8*400 || r.TrappedVolume > 1.2*400 {
+		t.Errorf("expected a trapped volume ~400, got %s", r)
+	}
+	if len(r.Issues) == 0 || r.Issues[0].Kind != IssueTrapped || !r.Issues[0].Box.Contains(v3.Vec{}) {
+		t.Errorf("expected a trapped volume at the origin, got %s", r)
+	}
+	r, _ = PrintabilityReport(hollow, ProcessFDM, 50)
+	if r.TrappedVolume != 0 {
+		t.Errorf("expected no trapped volume for FDM, got %s", r)
+	}
+
+	// a sphere has no flat base and overhangs
+	sphere, _ := sdf.Sphere3D(10)
+	r, _ = PrintabilityReport(sphere, ProcessFDM, 50)
+	kinds := map[IssueKind]bool{}
+	for _, x := range r.Issues {
+		kinds[x.Kind] = true
+	}
+	if !kinds[IssueBase] || !kinds[IssueOverhang] || r.Score >= 100 {
+		t.Errorf("expected base and overhang issues, got %s", r)
+	}
+
+	if p, err := Process("sla"); err != nil || p != ProcessSLA {
+		t.Error("expected the SLA process")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,181 @@
+//-----------------------------------------------------------------------------
+/*
+
+Resolution Advisor
+
+Estimate the smallest feature of a part and recommend the render
+resolution needed to capture it.
+
+The smallest feature is the thinnest wall, or the narrowest hole or gap,
+found by probing the part and the space around it with the wall thickness
+analysis (see thickness.go). The probe starts with a threshold the size of
+the part and a coarse sampling, and the threshold and sampling are halved
+while it finds regions thinner than the threshold. The edges and corners
+where surfaces meet are thin regions at any threshold, but they are measured
+at about half the threshold or more, so only regions well below the
+threshold are taken as features. A feature is measured again by the finer
+probes until the threshold is half its size, and the finest measurement is
+used. If there are no features, the smallest region measured is used, e.g.
+the thickness of a plate. The probe also stops at a minimum size. Features
+smaller than the minimum size are not seen, so the result is then a lower
+bound on the resolution.
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// ResolutionParms are the parameters of the resolution advisor.
+type ResolutionParms struct {
+	MinSize         float64 // smallest feature probed (0 == 1/128 of the bounding box)
+	CellsPerFeature float64 // render cells across the smallest feature (0 == 2)
+}
+
+// Resolution is the resolution recommended to render a part.
+type Resolution struct {
+	Feature   float64  // size of the smallest feature
+	Wall      bool     // the smallest feature is a wall, else a hole or gap
+	Box       sdf.Box3 // where the smallest feature is
+	Limit     bool     // there may be features smaller than MinSize
+	CellSize  float64  // recommended cell size
+	MeshCells int      // recommended cells on the longest axis of the bounding box
+	size      float64  // longest axis of the bounding box
+}
+
+// minFeatureRatio is the thickness/threshold ratio below which thin regions
+// are features. The edges where surfaces meet give thin regions at every
+// threshold, but they are measured at more than about half the threshold.
+const minFeatureRatio = 0.45
+
+// smallest returns the thinnest region of a set of regions.
+func smallest(regions []ThinRegion) (ThinRegion, bool) {
+	if len(regions) == 0 {
+		return ThinRegion{}, false
+	}
+	// the regions are sorted thinnest first
+	return regions[0], true
+}
+
+// AdviseResolution estimates the smallest feature of a part and returns the
+// render resolution needed to capture it.
+func AdviseResolution(s sdf.SDF3, k *ResolutionParms) (*Resolution, error) {
+	if s == nil {
+		return nil, sdf.ErrMsg("s == nil")
+	}
+	bb := s.BoundingBox()
+	size := bb.Size().MaxComponent()
+	if !(size > 0) {
+		return nil, sdf.ErrMsg("empty bounding box")
+	}
+	minSize := size / 128
+	perFeature := 2.0
+	if k != nil {
+		if k.MinSize < 0 || k.CellsPerFeature < 0 {
+			return nil, sdf.ErrMsg("MinSize < 0 || CellsPerFeature < 0")
+		}
+		if k.MinSize > 0 {
+			minSize = k.MinSize
+		}
+		if k.CellsPerFeature > 0 {
+			perFeature = k.CellsPerFeature
+		}
+	}
+	r := &Resolution{Feature: size, Box: bb, size: size}
+	found, last := false, false
+	for threshold := size; ; threshold *= 0.5 {
+		if threshold < minSize {
+			// a feature found by the finest probe may be smaller
+			r.Limit = last
+			break
+		}
+		walls, err := WallThickness(s, threshold, 0)
+		if err != nil {
+			return nil, err
+		}
+		gaps, err := WallThickness(&complementSDF3{s}, threshold, 0)
+		if err != nil {
+			return nil, err
+		}
+		wall, okWall := smallest(features(walls))
+		gap, okGap := smallest(features(gaps))
+		if !okWall && !okGap {
+			// nothing thinner than the threshold
+			break
+		}
+		x := Resolution{Feature: wall.Min, Wall: true, Box: wall.Box}
+		if !okWall || (okGap && gap.Min < wall.Min) {
+			x = Resolution{Feature: gap.Min, Box: gap.Box}
+		}
+		last = x.Feature < minFeatureRatio*threshold
+		if last {
+			// a feature, measured more finely than before
+			r.Feature, r.Wall, r.Box = x.Feature, x.Wall, x.Box
+			found = true
+		} else if !found && x.Feature < r.Feature {
+			// the smallest measurement so far
+			r.Feature, r.Wall, r.Box = x.Feature, x.Wall, x.Box
+		}
+	}
+	r.CellSize = r.Feature / perFeature
+	r.MeshCells = int(math.Ceil(size / r.CellSize))
+	return r, nil
+}
+
+// OK returns true if a number of mesh cells on the longest axis of the
+// bounding box is enough to capture the smallest feature.
+func (r *Resolution) OK(meshCells int) bool {
+	return !r.Limit && meshCells >= r.MeshCells
+}
+
+func (r *Resolution) String() string {
+	var sb strings.Builder
+	kind := "gap"
+	if r.Wall {
+		kind = "wall"
+	}
+	if r.Limit {
+		fmt.Fprintf(&sb, "smallest %s %.3g or less at %v..%v\n", kind, r.Feature, r.Box.Min, r.Box.Max)
+	} else {
+		fmt.Fprintf(&sb, "smallest %s %.3g at %v..%v\n", kind, r.Feature, r.Box.Min, r.Box.Max)
+	}
+	fmt.Fprintf(&sb, "recommended cell size %.3g (%d cells on the longest axis)\n", r.CellSize, r.MeshCells)
+	return sb.String()
+}
+
+// CheckResolution reports to a writer if a number of mesh cells on the
+// longest axis of the bounding box is too coarse to capture the smallest
+// feature of a part. It returns true if the resolution is enough.
+func CheckResolution(s sdf.SDF3, meshCells int, w io.Writer) (bool, error) {
+	if meshCells <= 0 {
+		return false, sdf.ErrMsg("meshCells <= 0")
+	}
+	r, err := AdviseResolution(s, nil)
+	if err != nil {
+		return false, err
+	}
+	return r.Check(meshCells, w), nil
+}
+
+// Check reports to a writer if a number of mesh cells on the longest axis
+// of the bounding box is too coarse. It returns true if the resolution is enough.
+func (r *Resolution) Check(meshCells int, w io.Writer) bool {
+	if r.OK(meshCells) {
+		return true
+	}
+	fmt.Fprintf(w, "warning: %d cells (cell size %.3g) may not capture the smallest feature\n", meshCells, r.size/float64(meshCells))
+	fmt.Fprintf(w, "%s", r)
+	return false
+}
+
+//-----------------------------------------------------------------------------
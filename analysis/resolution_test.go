@@ -0,0 +1,86 @@
+//-----------------------------------------------------------------------------
+/*
+
+Resolution Advisor Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_AdviseResolution(t *testing.T) {
+	base, _ := sdf.Box3D(v3.Vec{40, 40, 10}, 0)
+	r, err := AdviseResolution(base, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Wall || math.Abs(r.Feature-10) > 1 {
+		t.Errorf("expected a 10 wall, got %s", r)
+	}
+
+	// a 0.5 thick fin
+	fin, _ := sdf.Box3D(v3.Vec{0.5, 20, 8}, 0)
+	fin = sdf.Transform3D(fin, sdf.Translate3d(v3.Vec{0, 0, 9}))
+	s := sdf.Union3D(base, fin)
+	r, err = AdviseResolution(s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Wall || math.Abs(r.Feature-0.5) > 0.1 || r.Box.Min.Z < 5 {
+		t.Errorf("expected the fin, got %s", r)
+	}
+	if r.Limit || r.MeshCells < 150 || r.MeshCells > 250 {
+		t.Errorf("expected about 200 cells, got %s", r)
+	}
+	if r.OK(100) || !r.OK(300) {
+		t.Errorf("expected 100 cells to be too few, got %s", r)
+	}
+
+	// a 1 wide slot
+	slot, _ := sdf.Box3D(v3.Vec{20, 1, 6}, 0)
+	slot = sdf.Transform3D(slot, sdf.Translate3d(v3.Vec{0, 0, 3}))
+	r, err = AdviseResolution(sdf.Difference3D(base, slot), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Wall || math.Abs(r.Feature-1) > 0.2 {
+		t.Errorf("expected the slot, got %s", r)
+	}
+
+	// the fin is smaller than the probe limit
+	r, err = AdviseResolution(s, &ResolutionParms{MinSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Limit || r.OK(1000) {
+		t.Errorf("expected the probe limit, got %s", r)
+	}
+
+	// the plate needs 8 cells
+	var w bytes.Buffer
+	ok, err := CheckResolution(base, 4, &w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok || !strings.Contains(w.String(), "warning: 4 cells") {
+		t.Errorf("bad warning %q", w.String())
+	}
+	w.Reset()
+	if ok, _ := CheckResolution(base, 10, &w); !ok || w.Len() != 0 {
+		t.Errorf("unexpected warning %q", w.String())
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,262 @@
+//-----------------------------------------------------------------------------
+/*
+
+Wall Thickness Analysis
+
+Find the regions of a part that are thinner than a threshold.
+
+The local thickness of a part is twice the distance from its medial axis
+(the ridge of the interior distance field) to the surface. The part is
+sampled on a grid near the surface and the medial axis is located where
+the gradient of the distance field collapses. For a thin wall the distance
+field slopes down to the ridge from both sides, so the sampled gradient is
+close to zero. At a convex edge the two sides meet at an angle and the
+sampled gradient stays large, so edges and corners are not reported as thin.
+
+Walls thinner than about the sampling resolution may not be sampled at all,
+so the resolution should be a fraction of the threshold.
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// maxRidgeGradient is the sampled gradient length below which a point is on the medial axis.
+// A ridge between surfaces that face each other at angle a has a gradient of cos(a/2),
+// so this excludes convex edges sharper than about 120 degrees.
+const maxRidgeGradient = 0.5
+
+// blockSize is the number of samples on each side of a block of the coarse grid.
+const blockSize = 8
+
+// ThinPoint is a point on the medial axis of a part where the part is thin.
+type ThinPoint struct {
+	Point     v3.Vec  // point on the medial axis
+	Thickness float64 // local thickness
+}
+
+// ThinRegion is a connected group of thin points.
+type ThinRegion struct {
+	Box   sdf.Box3 // bounding box of the medial points
+	Min   float64  // minimum thickness
+	Count int      // number of thin points
+}
+
+// Thickness is the result of a wall thickness analysis.
+type Thickness struct {
+	Threshold  float64      // minimum acceptable thickness
+	Resolution float64      // sampling resolution
+	Points     []ThinPoint  // thin points
+	Regions    []ThinRegion // connected thin regions (thinnest first)
+	sdf        sdf.SDF3
+}
+
+//-----------------------------------------------------------------------------
+
+// WallThickness returns the regions of a part thinner than the threshold.
+// The part is sampled at the resolution. A resolution of 0 defaults to a quarter of the threshold.
+func WallThickness(s sdf.SDF3, threshold, resolution float64) (*Thickness, error) {
+	if s == nil {
+		return nil, sdf.ErrMsg("s == nil")
+	}
+	if threshold <= 0 {
+		return nil, sdf.ErrMsg("threshold <= 0")
+	}
+	if resolution < 0 {
+		return nil, sdf.ErrMsg("resolution < 0")
+	}
+	if resolution == 0 {
+		resolution = 0.25 * threshold
+	}
+	t := &Thickness{
+		Threshold:  threshold,
+		Resolution: resolution,
+		sdf:        s,
+	}
+	t.sample()
+	t.regions()
+	return t, nil
+}
+
+// sample finds the thin points on the medial axis.
+func (t *Thickness) sample() {
+	h := t.Resolution
+	bb := t.sdf.BoundingBox()
+	size := bb.Size()
+	n := v3i.Vec{
+		int(math.Ceil(size.X / h)),
+		int(math.Ceil(size.Y / h)),
+		int(math.Ceil(size.Z / h)),
+	}
+	// thin medial points are within half the threshold of the surface
+	rMax := 0.5 * t.Threshold
+	// blocks further than this from the surface contain no thin points
+	blockReach := 0.5*math.Sqrt(3)*blockSize*h + rMax
+	for bx := 0; bx < n.X; bx += blockSize {
+		for by := 0; by < n.Y; by += blockSize {
+			for bz := 0; bz < n.Z; bz += blockSize {
+				b0 := bb.Min.Add(v3.Vec{float64(bx), float64(by), float64(bz)}.MulScalar(h))
+				center := b0.Add(v3.Vec{1, 1, 1}.MulScalar(0.5 * blockSize * h))
+				if math.Abs(t.sdf.Evaluate(center)) > blockReach {
+					continue
+				}
+				for i := bx; i < minInt(bx+blockSize, n.X); i++ {
+					for j := by; j < minInt(by+blockSize, n.Y); j++ {
+						for k := bz; k < minInt(bz+blockSize, n.Z); k++ {
+							p := bb.Min.Add(v3.Vec{float64(i) + 0.5, float64(j) + 0.5, float64(k) + 0.5}.MulScalar(h))
+							t.check(p, rMax)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// check adds a sample point to the thin points if it is on a thin part of the medial axis.
+func (t *Thickness) check(p v3.Vec, rMax float64) {
+	h := t.Resolution
+	d := t.sdf.Evaluate(p)
+	if d >= 0 || -d >= rMax {
+		return
+	}
+	// some sample is within h/2 of the ridge, so sample the gradient across it
+	eps := 2 * h
+	g, lap := gradient(t.sdf, p, eps)
+	if g.Length() >= maxRidgeGradient {
+		return
+	}
+	// The field is a minimum on the medial axis. The gradient also collapses
+	// where the field is a maximum, e.g. the internal seams of a union.
+	if lap <= 0 {
+		return
+	}
+	// the sample is about g*eps from the ridge, so the medial distance is underestimated by that
+	r := -d + g.Length()*eps
+	if r >= rMax {
+		return
+	}
+	t.Points = append(t.Points, ThinPoint{p, 2 * r})
+}
+
+// gradient returns the central difference gradient and laplacian of an SDF3.
+func gradient(s sdf.SDF3, p v3.Vec, eps float64) (v3.Vec, float64) {
+	d := s.Evaluate(p)
+	x0 := s.Evaluate(p.Add(v3.Vec{X: -eps}))
+	x1 := s.Evaluate(p.Add(v3.Vec{X: eps}))
+	y0 := s.Evaluate(p.Add(v3.Vec{Y: -eps}))
+	y1 := s.Evaluate(p.Add(v3.Vec{Y: eps}))
+	z0 := s.Evaluate(p.Add(v3.Vec{Z: -eps}))
+	z1 := s.Evaluate(p.Add(v3.Vec{Z: eps}))
+	g := v3.Vec{X: x1 - x0, Y: y1 - y0, Z: z1 - z0}.DivScalar(2 * eps)
+	lap := (x0 + x1 + y0 + y1 + z0 + z1 - 6*d) / (eps * eps)
+	return g, lap
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+//-----------------------------------------------------------------------------
+
+// regions groups the thin points into connected regions.
+func (t *Thickness) regions() {
+	t.Regions = nil
+	if len(t.Points) == 0 {
+		return
+	}
+	// points on the sample grid are connected if they are neighbors
+	centers := make([]v3.Vec, len(t.Points))
+	for i, p := range t.Points {
+		centers[i] = p.Point
+	}
+	cluster, n := clusters(centers, 2*t.Resolution)
+	t.Regions = make([]ThinRegion, n)
+	for i := range t.Regions {
+		t.Regions[i].Min = math.MaxFloat64
+	}
+	for i, tp := range t.Points {
+		r := &t.Regions[cluster[i]]
+		if r.Count == 0 {
+			r.Box = sdf.Box3{Min: tp.Point, Max: tp.Point}
+		}
+		r.Box = r.Box.Include(tp.Point)
+		r.Min = math.Min(r.Min, tp.Thickness)
+		r.Count++
+	}
+	sort.SliceStable(t.Regions, func(i, j int) bool {
+		return t.Regions[i].Min < t.Regions[j].Min
+	})
+}
+
+//-----------------------------------------------------------------------------
+
+// Min returns the minimum thickness found. It returns 0 if there are no thin points.
+func (t *Thickness) Min() float64 {
+	if len(t.Regions) == 0 {
+		return 0
+	}
+	return t.Regions[0].Min
+}
+
+// OK returns true if no part of the model is thinner than the threshold.
+func (t *Thickness) OK() bool {
+	return len(t.Points) == 0
+}
+
+func (t *Thickness) String() string {
+	var sb strings.Builder
+	if t.OK() {
+		fmt.Fprintf(&sb, "no walls thinner than %g (resolution %g)\n", t.Threshold, t.Resolution)
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "%d regions thinner than %g (resolution %g), minimum %.3g\n",
+		len(t.Regions), t.Threshold, t.Resolution, t.Min())
+	for i, r := range t.Regions {
+		fmt.Fprintf(&sb, "  %d: min %.3g at %v..%v (%d points)\n", i, r.Min, r.Box.Min, r.Box.Max, r.Count)
+	}
+	return sb.String()
+}
+
+// Highlight returns an SDF3 for the thin regions of the part. It returns nil if there are no thin regions.
+func (t *Thickness) Highlight() sdf.SDF3 {
+	if t.OK() {
+		return nil
+	}
+	// the thin material is within the medial distance of the medial points
+	centers := make([]v3.Vec, len(t.Points))
+	radii := make([]float64, len(t.Points))
+	for i, p := range t.Points {
+		centers[i] = p.Point
+		radii[i] = 0.5*p.Thickness + t.Resolution
+	}
+	return sdf.Intersect3D(t.sdf, newBallsSDF3(centers, radii))
+}
+
+// Mesh renders the thin regions of the part. It returns nil if there are no thin regions.
+func (t *Thickness) Mesh(r render.Render3) []*sdf.Triangle3 {
+	s := t.Highlight()
+	if s == nil {
+		return nil
+	}
+	return render.ToTriangles(s, r)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,47 @@
+//-----------------------------------------------------------------------------
+/*
+
+Wall Thickness Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package analysis
+
+import (
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_WallThickness(t *testing.T) {
+	base, _ := sdf.Box3D(v3.Vec{20, 20, 10}, 0)
+	th, err := WallThickness(base, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !th.OK() {
+		t.Errorf("expected no thin regions, got %s", th)
+	}
+	// add a 0.4 thick fin
+	fin, _ := sdf.Box3D(v3.Vec{0.4, 10, 8}, 0)
+	fin = sdf.Transform3D(fin, sdf.Translate3d(v3.Vec{0, 0, 9}))
+	th, err = WallThickness(sdf.Union3D(base, fin), 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(th.Regions) != 1 {
+		t.Fatalf("expected 1 thin region, got %s", th)
+	}
+	if min := th.Min(); min < 0.3 || min > 0.5 {
+		t.Errorf("expected minimum thickness ~0.4, got %g", min)
+	}
+	if s := th.Highlight(); s.Evaluate(v3.Vec{0, 0, 9}) >= 0 {
+		t.Errorf("expected the fin to be highlighted")
+	}
+}
+
+//-----------------------------------------------------------------------------
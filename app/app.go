@@ -0,0 +1,329 @@
+//-----------------------------------------------------------------------------
+/*
+
+Model Generator Programs
+
+Main turns a model program into a configurable generator. The model
+declares its parameters (with defaults, ranges and choices) as a catalog
+part, and Main handles the command line: the parameters are set from a
+parameter file (JSON or TOML) and/or flags, and the model is rendered to
+the output files.
+
+	func main() {
+		app.Main(catalog.NewPart("box", "a box with a lid", []catalog.Param{
+			{Name: "width", Doc: "outer width", Default: 40.0, Unit: "mm", Min: 10, Max: 200},
+			{Name: "walls", Doc: "wall thickness", Default: 2.0, Unit: "mm", Min: 0.8, Max: 5},
+		}, build))
+	}
+
+	$ ./box -params
+	$ ./box -width 60 -o box.stl,box.3mf
+	$ ./box -p big.toml -walls 3 -dump big.json
+	$ ./box -p big.toml -watch
+
+The parameter values are the defaults, overridden by the parameter file,
+overridden by the flags. With -watch the outputs are rendered again each
+time the parameter file changes. With -small warn the features smaller than
+a mesh cell (thin walls, narrow holes and gaps) are reported, and with
+-small suppress they are also removed from the model before rendering.
+
+*/
+//-----------------------------------------------------------------------------
+
+package app
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deadsy/sdfx/analysis"
+	"github.com/deadsy/sdfx/catalog"
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// watchPeriod is the polling period for parameter file changes.
+const watchPeriod = 500 * time.Millisecond
+
+// DefaultCells is the default number of mesh cells on the longest axis.
+const DefaultCells = 200
+
+// Options are the defaults of a generator program.
+type Options struct {
+	Outputs []string // default output files (default <name>.stl)
+	Cells   int      // default mesh cells on the longest axis (default DefaultCells)
+}
+
+// Main is the command line entry point for a model generator program.
+func Main(p catalog.Parametric) {
+	MainWithOptions(p, Options{})
+}
+
+// MainWithOptions is the command line entry point for a model generator program with non-default settings.
+func MainWithOptions(p catalog.Parametric, opts Options) {
+	if err := Run(p, opts, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// options are the command line options of a generator.
+type options struct {
+	params  string   // parameter file
+	outputs []string // output files
+	cells   int      // mesh cells on the longest axis
+	dump    string   // file for the resolved parameters
+	watch   bool     // render again on parameter file changes
+	small   analysis.SmallFeatureAction
+}
+
+// Run runs a model generator with command line arguments.
+func Run(p catalog.Parametric, defaults Options, args []string, w io.Writer) error {
+	if len(defaults.Outputs) == 0 {
+		defaults.Outputs = []string{p.Name() + ".stl"}
+	}
+	if defaults.Cells <= 0 {
+		defaults.Cells = DefaultCells
+	}
+	fs := flag.NewFlagSet(p.Name(), flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() {
+		fmt.Fprintf(w, "%s: %s\n", p.Name(), p.Doc())
+		fmt.Fprintf(w, "usage: %s [-p file] [-param value ...] [-o files] [-cells n] [-small action] [-dump file] [-watch] [-params]\n", p.Name())
+		catalog.WriteInfo(w, p)
+	}
+	var opts options
+	var outputs string
+	fs.StringVar(&opts.params, "p", "", "parameter file (.json or .toml)")
+	fs.StringVar(&outputs, "o", strings.Join(defaults.Outputs, ","), "output files, comma separated (.stl, .3mf, .obj or .step)")
+	fs.IntVar(&opts.cells, "cells", defaults.Cells, "mesh cells on the longest axis")
+	small := fs.String("small", "ignore", "features smaller than a cell: ignore, warn or suppress")
+	fs.StringVar(&opts.dump, "dump", "", "write the parameter values to a file (.json or .toml)")
+	fs.BoolVar(&opts.watch, "watch", false, "render again when the parameter file changes")
+	list := fs.Bool("params", false, "list the parameters and exit")
+	values := make(map[string]*string)
+	for _, x := range p.Params() {
+		if fs.Lookup(x.Name) != nil {
+			return sdf.ErrMsg(fmt.Sprintf("parameter \"%s\" has the name of a flag", x.Name))
+		}
+		values[x.Name] = fs.String(x.Name, fmt.Sprint(x.Default), x.Doc)
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return sdf.ErrMsg(fmt.Sprintf("unexpected arguments %v", fs.Args()))
+	}
+	if *list {
+		return catalog.WriteInfo(w, p)
+	}
+	var err error
+	if opts.small, err = analysis.ParseSmallFeatureAction(*small); err != nil {
+		return err
+	}
+	if opts.watch && opts.params == "" {
+		return sdf.ErrMsg("-watch needs a parameter file")
+	}
+	for _, o := range strings.Split(outputs, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			opts.outputs = append(opts.outputs, o)
+		}
+	}
+	// only the flags that were set override the parameter file
+	flags := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if _, ok := values[f.Name]; ok {
+			flags[f.Name] = *values[f.Name]
+		}
+	})
+
+	if !opts.watch {
+		return generate(p, flags, &opts, w)
+	}
+	var last time.Time
+	for {
+		fi, err := os.Stat(opts.params)
+		if err == nil && !fi.ModTime().Equal(last) {
+			last = fi.ModTime()
+			if err := generate(p, flags, &opts, w); err != nil {
+				// keep watching, the file may be fixed
+				fmt.Fprintf(w, "error: %s\n", err)
+			}
+			fmt.Fprintf(w, "watching %s\n", opts.params)
+		}
+		time.Sleep(watchPeriod)
+	}
+}
+
+// generate resolves the parameter values, builds the model and writes the outputs.
+func generate(p catalog.Parametric, flags map[string]string, opts *options, w io.Writer) error {
+	v, err := Resolve(p, opts.params, flags)
+	if err != nil {
+		return err
+	}
+	if opts.dump != "" {
+		if err := SaveParams(opts.dump, p, v); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "wrote %s\n", opts.dump)
+	}
+	s, err := p.Build(v)
+	if err != nil {
+		return err
+	}
+	cell := s.BoundingBox().Size().MaxComponent() / float64(opts.cells)
+	if s, err = analysis.CheckSmallFeatures(s, cell, opts.small, w); err != nil {
+		return err
+	}
+	r := render.NewMarchingCubesOctree(opts.cells)
+	fmt.Fprintf(w, "rendering %s (%s)\n", p.Name(), r.Info(s))
+	mesh := render.ToTriangles(s, r)
+	for _, path := range opts.outputs {
+		if err := saveMesh(path, p.Name(), mesh); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "wrote %s\n", path)
+	}
+	return nil
+}
+
+// saveMesh writes a mesh to a file with the format given by the file extension.
+func saveMesh(path, name string, mesh []*sdf.Triangle3) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".stl":
+		return render.SaveSTL(path, mesh)
+	case ".3mf":
+		return render.Save3MF(path, []render.Part3MF{{Name: name, Color: color.RGBA{128, 128, 128, 255}, Mesh: mesh}})
+	case ".obj":
+		return render.SaveOBJ(path, mesh)
+	case ".step", ".stp":
+		return render.SaveSTEPWithOptions(path, mesh, render.STEPOptions{ProductName: name})
+	}
+	return sdf.ErrMsg(fmt.Sprintf("unknown output file type \"%s\"", path))
+}
+
+//-----------------------------------------------------------------------------
+// Parameter files
+
+// Resolve returns the parameter values of a part: the defaults, overridden
+// by a parameter file (if path is not empty), overridden by text values
+// (e.g. from flags).
+func Resolve(p catalog.Parametric, path string, args map[string]string) (catalog.Values, error) {
+	v := catalog.Defaults(p)
+	if path != "" {
+		var err error
+		if v, err = LoadParams(path, p); err != nil {
+			return nil, err
+		}
+	}
+	params := p.Params()
+	for k, s := range args {
+		var param *catalog.Param
+		for i := range params {
+			if params[i].Name == k {
+				param = &params[i]
+			}
+		}
+		if param == nil {
+			return nil, sdf.ErrMsg(fmt.Sprintf("%s has no parameter \"%s\"", p.Name(), k))
+		}
+		x, err := param.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		v[k] = x
+	}
+	return v, nil
+}
+
+// LoadParams reads the parameter values of a part from a JSON or TOML file
+// (by file extension). Missing parameters have their default values.
+func LoadParams(path string, p catalog.Parametric) (catalog.Values, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var v catalog.Values
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		v, err = catalog.ParseJSON(p, data)
+	case ".toml":
+		var in map[string]interface{}
+		if in, err = parseTOML(data); err == nil {
+			v, err = catalog.ParseMap(p, in)
+		}
+	default:
+		return nil, sdf.ErrMsg(fmt.Sprintf("unknown parameter file type \"%s\"", path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return v, nil
+}
+
+// SaveParams writes the parameter values of a part to a JSON or TOML file
+// (by file extension), in parameter order.
+func SaveParams(path string, p catalog.Parametric, v catalog.Values) error {
+	var names []string
+	for _, x := range p.Params() {
+		names = append(names, x.Name)
+	}
+	// values that are not declared parameters go last
+	var extra []string
+	for k := range v {
+		if !contains(names, k) {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	names = append(names, extra...)
+
+	var sb strings.Builder
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		sb.WriteString("{\n")
+		for i, k := range names {
+			key, _ := json.Marshal(k)
+			val, err := json.Marshal(v[k])
+			if err != nil {
+				return err
+			}
+			sep := ","
+			if i == len(names)-1 {
+				sep = ""
+			}
+			fmt.Fprintf(&sb, "  %s: %s%s\n", key, val, sep)
+		}
+		sb.WriteString("}\n")
+	case ".toml":
+		fmt.Fprintf(&sb, "# %s parameters\n", p.Name())
+		for _, k := range names {
+			fmt.Fprintf(&sb, "%s = %s\n", tomlKey(k), tomlValue(v[k]))
+		}
+	default:
+		return sdf.ErrMsg(fmt.Sprintf("unknown parameter file type \"%s\"", path))
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
+
+// contains returns true if a string is in a list.
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+//-----------------------------------------------------------------------------
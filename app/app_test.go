@@ -0,0 +1,116 @@
+//-----------------------------------------------------------------------------
+/*
+
+Model Generator Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/deadsy/sdfx/catalog"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+var testBox = catalog.NewPart("box", "a test box", []catalog.Param{
+	{Name: "width", Doc: "box width", Default: 10.0, Min: 1, Max: 100},
+	{Name: "holes", Doc: "number of holes", Default: 2, Min: 0, Max: 8},
+	{Name: "lid", Doc: "with a lid", Default: false},
+	{Name: "style", Doc: "edge style", Default: "flat", Choices: []string{"flat", "round"}},
+}, func(v catalog.Values) (sdf.SDF3, error) {
+	return sdf.Box3D(v3.Vec{v.Float("width"), 5, 5}, 0)
+})
+
+func Test_Resolve(t *testing.T) {
+	dir := t.TempDir()
+	toml := filepath.Join(dir, "p.toml")
+	os.WriteFile(toml, []byte("# test\nwidth = 20 # a whole number\n\"holes\" = 4\nlid = true\n"), 0644)
+
+	v, err := Resolve(testBox, toml, map[string]string{"style": "round", "holes": "6"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := catalog.Values{"width": 20.0, "holes": 6, "lid": true, "style": "round"}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("expected %v, got %v", want, v)
+	}
+
+	// round trip through both file types
+	for _, name := range []string{"q.json", "q.toml"} {
+		path := filepath.Join(dir, name)
+		if err := SaveParams(path, testBox, v); err != nil {
+			t.Fatal(err)
+		}
+		x, err := LoadParams(path, testBox)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(x, want) {
+			t.Errorf("%s: expected %v, got %v", name, want, x)
+		}
+	}
+
+	// bad parameters
+	for _, s := range []string{
+		"width = 200\n",
+		"holes = 2.5\n",
+		"style = \"square\"\n",
+		"depth = 3\n",
+		"width = 1\nwidth = 2\n",
+		"[box]\nwidth = 2\n",
+		"width = [1, 2]\n",
+		"style = \"flat\n",
+		"width 20\n",
+	} {
+		os.WriteFile(toml, []byte(s), 0644)
+		if _, err := Resolve(testBox, toml, nil); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+	if _, err := Resolve(testBox, "", map[string]string{"width": "0"}); err == nil {
+		t.Error("expected an error for an out of range flag")
+	}
+}
+
+func Test_Run(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "box.stl")
+	dump := filepath.Join(dir, "box.json")
+	var w bytes.Buffer
+	if err := Run(testBox, Options{}, []string{"-width", "30", "-cells", "20", "-o", out, "-dump", dump}, &w); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Error(err)
+	}
+	v, err := LoadParams(dump, testBox)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Float("width") != 30 {
+		t.Errorf("expected width 30, got %v", v["width"])
+	}
+
+	w.Reset()
+	if err := Run(testBox, Options{}, []string{"-params"}, &w); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(w.Bytes(), []byte("[1, 100]")) {
+		t.Errorf("expected the parameter ranges, got %s", w.String())
+	}
+	if err := Run(testBox, Options{}, []string{"-watch"}, &w); err == nil {
+		t.Error("expected an error for -watch without a parameter file")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,216 @@
+//-----------------------------------------------------------------------------
+/*
+
+TOML Parameter Files
+
+Parameter files are flat, so only the key/value subset of TOML is read:
+bare or quoted keys, and string, integer, float and boolean values. Tables,
+arrays and dates are rejected.
+
+	# box parameters
+	width = 60.0
+	holes = 4
+	lid = true
+	style = "flat"
+
+*/
+//-----------------------------------------------------------------------------
+
+package app
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// parseTOML parses the key/value pairs of a flat TOML document.
+// Integers are returned as int, floats as float64.
+func parseTOML(data []byte) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for n, line := range strings.Split(string(data), "\n") {
+		k, v, err := parseTOMLLine(line)
+		if err != nil {
+			return nil, sdf.ErrMsg(fmt.Sprintf("line %d: %s", n+1, err))
+		}
+		if k == "" {
+			continue
+		}
+		if _, ok := m[k]; ok {
+			return nil, sdf.ErrMsg(fmt.Sprintf("line %d: key \"%s\" is repeated", n+1, k))
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+// parseTOMLLine parses a TOML line. The key is empty for a blank or comment line.
+func parseTOMLLine(line string) (string, interface{}, error) {
+	line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+	if line == "" || line[0] == '#' {
+		return "", nil, nil
+	}
+	if line[0] == '[' {
+		return "", nil, sdf.ErrMsg("tables are not supported")
+	}
+	key, rest, err := tomlKeyPrefix(line)
+	if err != nil {
+		return "", nil, err
+	}
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "=") {
+		return "", nil, sdf.ErrMsg("expected '='")
+	}
+	val, rest, err := tomlValuePrefix(strings.TrimSpace(rest[1:]))
+	if err != nil {
+		return "", nil, err
+	}
+	rest = strings.TrimSpace(rest)
+	if rest != "" && rest[0] != '#' {
+		return "", nil, sdf.ErrMsg(fmt.Sprintf("unexpected \"%s\"", rest))
+	}
+	return key, val, nil
+}
+
+// tomlBare returns true if a character can be used in a bare key.
+func tomlBare(c byte) bool {
+	return c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// tomlKeyPrefix parses the key at the start of a line.
+func tomlKeyPrefix(s string) (string, string, error) {
+	if s[0] == '"' || s[0] == '\'' {
+		k, rest, err := tomlStringPrefix(s)
+		if err != nil {
+			return "", "", err
+		}
+		if strings.HasPrefix(strings.TrimSpace(rest), ".") {
+			return "", "", sdf.ErrMsg("dotted keys are not supported")
+		}
+		return k, rest, nil
+	}
+	i := 0
+	for i < len(s) && tomlBare(s[i]) {
+		i++
+	}
+	if i == 0 {
+		return "", "", sdf.ErrMsg("expected a key")
+	}
+	if strings.HasPrefix(strings.TrimSpace(s[i:]), ".") {
+		return "", "", sdf.ErrMsg("dotted keys are not supported")
+	}
+	return s[:i], s[i:], nil
+}
+
+// tomlStringPrefix parses the basic or literal string at the start of s.
+func tomlStringPrefix(s string) (string, string, error) {
+	q := s[0]
+	if strings.HasPrefix(s, strings.Repeat(string(q), 3)) {
+		return "", "", sdf.ErrMsg("multi-line strings are not supported")
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if q == '"' {
+				i++
+			}
+		case q:
+			if q == '\'' {
+				return s[1:i], s[i+1:], nil
+			}
+			x, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", sdf.ErrMsg(fmt.Sprintf("bad string %s", s[:i+1]))
+			}
+			return x, s[i+1:], nil
+		}
+	}
+	return "", "", sdf.ErrMsg("unterminated string")
+}
+
+// tomlValuePrefix parses the value at the start of s.
+func tomlValuePrefix(s string) (interface{}, string, error) {
+	if s == "" {
+		return nil, "", sdf.ErrMsg("expected a value")
+	}
+	switch s[0] {
+	case '"', '\'':
+		return tomlStringPrefix(s)
+	case '[', '{':
+		return nil, "", sdf.ErrMsg("arrays and inline tables are not supported")
+	}
+	i := strings.IndexAny(s, " \t#")
+	if i < 0 {
+		i = len(s)
+	}
+	tok, rest := s[:i], s[i:]
+	switch tok {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	case "inf", "+inf":
+		return math.Inf(1), rest, nil
+	case "-inf":
+		return math.Inf(-1), rest, nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), rest, nil
+	}
+	num := strings.ReplaceAll(tok, "_", "")
+	if x, err := strconv.ParseInt(num, 0, 0); err == nil {
+		return int(x), rest, nil
+	}
+	if strings.HasPrefix(num, "0x") || strings.HasPrefix(num, "0o") || strings.HasPrefix(num, "0b") {
+		return nil, "", sdf.ErrMsg(fmt.Sprintf("bad value \"%s\"", tok))
+	}
+	if x, err := strconv.ParseFloat(num, 64); err == nil {
+		return x, rest, nil
+	}
+	return nil, "", sdf.ErrMsg(fmt.Sprintf("bad value \"%s\"", tok))
+}
+
+//-----------------------------------------------------------------------------
+
+// tomlKey returns a TOML key, quoted if it isn't a bare key.
+func tomlKey(k string) string {
+	for i := 0; i < len(k); i++ {
+		if !tomlBare(k[i]) {
+			return strconv.Quote(k)
+		}
+	}
+	if k == "" {
+		return `""`
+	}
+	return k
+}
+
+// tomlValue returns a TOML value.
+func tomlValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return "inf"
+		case math.IsInf(v, -1):
+			return "-inf"
+		case math.IsNaN(v):
+			return "nan"
+		}
+		// floats keep a decimal point, so they read back as floats
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eE") {
+			s += ".0"
+		}
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+//-----------------------------------------------------------------------------
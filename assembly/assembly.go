@@ -0,0 +1,596 @@
+//-----------------------------------------------------------------------------
+/*
+
+Assemblies
+
+Position the parts of a multi-part model by intent rather than by
+translation numbers. A Part is an SDF3 that publishes named anchor frames
+(a point and an orientation). An Assembly holds parts and constraints
+between their anchors, and solves for the transform of each part.
+
+	base := assembly.NewPart("base", baseSDF).BoundingBoxAnchors()
+	lid := assembly.NewPart("lid", lidSDF).BoundingBoxAnchors()
+	a := assembly.New()
+	a.Add(base, lid)
+	a.Mate(base.At("top"), lid.At("bottom"))
+	if err := a.Solve(); err != nil {
+		...
+	}
+	s := a.SDF3()
+
+The constraints are:
+
+	Mate        the frames coincide with opposed z axes (face to face)
+	Align       the frames coincide with the same z axes
+	Coincident  the frame origins coincide
+	Concentric  the z axes are on the same line
+	Parallel    the z axes point the same way
+	Distance    the origin of b is a distance along the z axis of a
+
+Mate and Align fix all degrees of freedom between two parts. The others
+can be combined, e.g. Concentric + Distance places a shaft in a bore at a
+depth and leaves the rotation about the axis where it was.
+
+The first part added is fixed where it is, unless other parts are fixed
+with Fix. Each part is first placed from the constraints that connect it to
+an already placed part, then the constraints are solved together with a
+damped least squares (Levenberg-Marquardt) iteration, so closed loops and
+several constraints on a part are satisfied. Degrees of freedom that no
+constraint fixes keep the initial placement.
+
+*/
+//-----------------------------------------------------------------------------
+
+package assembly
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"gonum.org/v1/gonum/mat"
+)
+
+//-----------------------------------------------------------------------------
+
+// Frame is an anchor frame: an origin and orthonormal z (normal) and x (reference) axes.
+type Frame struct {
+	Origin v3.Vec
+	Z, X   v3.Vec
+}
+
+// NewFrame returns a frame with a z axis and a reference direction for the x axis.
+// The x axis is the part of the reference direction perpendicular to the z axis.
+func NewFrame(origin, z, x v3.Vec) (Frame, error) {
+	if z.Length() == 0 {
+		return Frame{}, sdf.ErrMsg("zero length z axis")
+	}
+	z = z.Normalize()
+	x = x.Sub(z.MulScalar(x.Dot(z)))
+	if x.Length() < 1e-9 {
+		return Frame{}, sdf.ErrMsg("x axis is parallel to z axis")
+	}
+	return Frame{Origin: origin, Z: z, X: x.Normalize()}, nil
+}
+
+// Y returns the y axis of the frame.
+func (f Frame) Y() v3.Vec {
+	return f.Z.Cross(f.X)
+}
+
+// Matrix returns the matrix that maps frame coordinates to world coordinates.
+func (f Frame) Matrix() sdf.M44 {
+	x, y, z, o := f.X, f.Y(), f.Z, f.Origin
+	return sdf.M44{
+		x.X, y.X, z.X, o.X,
+		x.Y, y.Y, z.Y, o.Y,
+		x.Z, y.Z, z.Z, o.Z,
+		0, 0, 0, 1}
+}
+
+// Transform returns the frame transformed by a rigid transform.
+func (f Frame) Transform(m sdf.M44) Frame {
+	return Frame{
+		Origin: m.MulPosition(f.Origin),
+		Z:      mulDir(m, f.Z),
+		X:      mulDir(m, f.X),
+	}
+}
+
+// flip returns the frame with the z axis reversed (and the x axis kept).
+func (f Frame) flip() Frame {
+	return Frame{Origin: f.Origin, Z: f.Z.Neg(), X: f.X}
+}
+
+// mulDir returns a direction transformed by the rotation of a matrix.
+func mulDir(m sdf.M44, v v3.Vec) v3.Vec {
+	return v3.Vec{
+		m[0]*v.X + m[1]*v.Y + m[2]*v.Z,
+		m[4]*v.X + m[5]*v.Y + m[6]*v.Z,
+		m[8]*v.X + m[9]*v.Y + m[10]*v.Z,
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// Part is an SDF3 with named anchor frames in its own coordinates.
+// The build metadata (source, material, quantity, parameters) goes into the bill of materials.
+type Part struct {
+	sdf.SDF3
+	name     string
+	anchors  map[string]Frame
+	source   string
+	material string
+	quantity int
+	params   []Param
+}
+
+// NewPart returns a part without anchors.
+func NewPart(name string, s sdf.SDF3) *Part {
+	return &Part{SDF3: s, name: name, anchors: make(map[string]Frame)}
+}
+
+// Name returns the part name.
+func (p *Part) Name() string {
+	return p.name
+}
+
+// Anchor publishes a named anchor frame.
+func (p *Part) Anchor(name string, f Frame) *Part {
+	p.anchors[name] = f
+	return p
+}
+
+// BoundingBoxAnchors publishes anchors at the centers of the bounding box
+// faces, with the z axes pointing out: "top" (+z), "bottom" (-z), "right"
+// (+x), "left" (-x), "back" (+y) and "front" (-y). The "center" anchor is
+// at the center of the bounding box with the z axis up.
+func (p *Part) BoundingBoxAnchors() *Part {
+	bb := p.BoundingBox()
+	c := bb.Center()
+	h := bb.Size().MulScalar(0.5)
+	x, y, z := v3.Vec{1, 0, 0}, v3.Vec{0, 1, 0}, v3.Vec{0, 0, 1}
+	p.Anchor("center", Frame{c, z, x})
+	p.Anchor("top", Frame{c.Add(v3.Vec{0, 0, h.Z}), z, x})
+	p.Anchor("bottom", Frame{c.Sub(v3.Vec{0, 0, h.Z}), z.Neg(), x})
+	p.Anchor("right", Frame{c.Add(v3.Vec{h.X, 0, 0}), x, y})
+	p.Anchor("left", Frame{c.Sub(v3.Vec{h.X, 0, 0}), x.Neg(), y})
+	p.Anchor("back", Frame{c.Add(v3.Vec{0, h.Y, 0}), y, x})
+	p.Anchor("front", Frame{c.Sub(v3.Vec{0, h.Y, 0}), y.Neg(), x})
+	return p
+}
+
+// Ref refers to a named anchor of a part.
+type Ref struct {
+	Part   *Part
+	Anchor string
+}
+
+// At returns a reference to a named anchor of the part.
+func (p *Part) At(anchor string) Ref {
+	return Ref{p, anchor}
+}
+
+// frame returns the anchor frame in part coordinates.
+func (r Ref) frame() (Frame, error) {
+	if r.Part == nil {
+		return Frame{}, sdf.ErrMsg("nil part")
+	}
+	f, ok := r.Part.anchors[r.Anchor]
+	if !ok {
+		return Frame{}, sdf.ErrMsg(fmt.Sprintf("part \"%s\" has no anchor \"%s\"", r.Part.name, r.Anchor))
+	}
+	return f, nil
+}
+
+func (r Ref) String() string {
+	return r.Part.name + "." + r.Anchor
+}
+
+//-----------------------------------------------------------------------------
+
+// kind is the kind of a constraint.
+type kind int
+
+const (
+	mate kind = iota
+	align
+	coincident
+	concentric
+	parallel
+	distance
+)
+
+var kindName = [...]string{"mate", "align", "coincident", "concentric", "parallel", "distance"}
+
+// constraint is a constraint between two anchors.
+type constraint struct {
+	kind kind
+	a, b Ref
+	fa   Frame   // anchor a in part coordinates
+	fb   Frame   // anchor b in part coordinates
+	d    float64 // distance
+}
+
+// Assembly is a set of parts with constraints between their anchors.
+type Assembly struct {
+	parts       []*Part
+	fixed       map[*Part]sdf.M44
+	constraints []*constraint
+	pose        map[*Part]sdf.M44
+	err         error
+}
+
+// New returns an empty assembly.
+func New() *Assembly {
+	return &Assembly{
+		fixed: make(map[*Part]sdf.M44),
+		pose:  make(map[*Part]sdf.M44),
+	}
+}
+
+// Add adds parts to the assembly. Part names are unique.
+func (a *Assembly) Add(parts ...*Part) error {
+	for _, p := range parts {
+		if a.index(p) >= 0 {
+			return sdf.ErrMsg(fmt.Sprintf("part \"%s\" is already in the assembly", p.name))
+		}
+		for _, x := range a.parts {
+			if x.name == p.name {
+				return sdf.ErrMsg(fmt.Sprintf("duplicate part name \"%s\"", p.name))
+			}
+		}
+		a.parts = append(a.parts, p)
+	}
+	return nil
+}
+
+// index returns the index of a part, or -1 if it isn't in the assembly.
+func (a *Assembly) index(p *Part) int {
+	for i, x := range a.parts {
+		if x == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// Fix fixes a part with a transform.
+func (a *Assembly) Fix(p *Part, m sdf.M44) {
+	a.fixed[p] = m
+}
+
+// add adds a constraint. Errors are reported by Solve.
+func (a *Assembly) add(k kind, ra, rb Ref, d float64) {
+	c := &constraint{kind: k, a: ra, b: rb, d: d}
+	var err error
+	if c.fa, err = ra.frame(); err == nil {
+		c.fb, err = rb.frame()
+	}
+	if err == nil && ra.Part == rb.Part {
+		err = sdf.ErrMsg(fmt.Sprintf("%s: %s and %s are on the same part", kindName[k], ra, rb))
+	}
+	if err == nil && (a.index(ra.Part) < 0 || a.index(rb.Part) < 0) {
+		err = sdf.ErrMsg(fmt.Sprintf("%s: %s or %s is not in the assembly", kindName[k], ra, rb))
+	}
+	if err != nil && a.err == nil {
+		a.err = err
+	}
+	a.constraints = append(a.constraints, c)
+}
+
+// Mate places the anchors face to face: the origins coincide, the z axes are opposed and the x axes are aligned.
+func (a *Assembly) Mate(ra, rb Ref) {
+	a.add(mate, ra, rb, 0)
+}
+
+// Align makes the anchor frames coincide.
+func (a *Assembly) Align(ra, rb Ref) {
+	a.add(align, ra, rb, 0)
+}
+
+// Coincident makes the anchor origins coincide.
+func (a *Assembly) Coincident(ra, rb Ref) {
+	a.add(coincident, ra, rb, 0)
+}
+
+// Concentric puts the anchor z axes on the same line, pointing the same way.
+func (a *Assembly) Concentric(ra, rb Ref) {
+	a.add(concentric, ra, rb, 0)
+}
+
+// Parallel makes the anchor z axes point the same way.
+func (a *Assembly) Parallel(ra, rb Ref) {
+	a.add(parallel, ra, rb, 0)
+}
+
+// Distance puts the origin of anchor b at a (signed) distance along the z axis of anchor a.
+func (a *Assembly) Distance(ra, rb Ref, d float64) {
+	a.add(distance, ra, rb, d)
+}
+
+//-----------------------------------------------------------------------------
+
+// initial returns the pose of part b that satisfies a constraint, given the pose of part a.
+func (c *constraint) initial(poseA sdf.M44) sdf.M44 {
+	fa := c.fa.Transform(poseA)
+	switch c.kind {
+	case mate, align, concentric:
+		if c.kind == mate {
+			fa = fa.flip()
+		}
+		return fa.Matrix().Mul(c.fb.Matrix().Inverse())
+	case distance:
+		return sdf.Translate3d(fa.Origin.Add(fa.Z.MulScalar(c.d)).Sub(c.fb.Origin))
+	case coincident:
+		return sdf.Translate3d(fa.Origin.Sub(c.fb.Origin))
+	}
+	// parallel
+	return sdf.RotateToVector(c.fb.Z, fa.Z)
+}
+
+// residual appends the residuals of a constraint for the part poses.
+// Directions are scaled by a length so they are comparable to positions.
+func (c *constraint) residual(poseA, poseB sdf.M44, scale float64, r []float64) []float64 {
+	fa := c.fa.Transform(poseA)
+	fb := c.fb.Transform(poseB)
+	add := func(v v3.Vec) {
+		r = append(r, v.X, v.Y, v.Z)
+	}
+	switch c.kind {
+	case mate:
+		add(fb.Origin.Sub(fa.Origin))
+		add(fb.Z.Add(fa.Z).MulScalar(scale))
+		add(fb.X.Sub(fa.X).MulScalar(scale))
+	case align:
+		add(fb.Origin.Sub(fa.Origin))
+		add(fb.Z.Sub(fa.Z).MulScalar(scale))
+		add(fb.X.Sub(fa.X).MulScalar(scale))
+	case coincident:
+		add(fb.Origin.Sub(fa.Origin))
+	case concentric:
+		add(fb.Z.Sub(fa.Z).MulScalar(scale))
+		add(fb.Origin.Sub(fa.Origin).Cross(fa.Z))
+	case parallel:
+		add(fb.Z.Sub(fa.Z).MulScalar(scale))
+	case distance:
+		r = append(r, fb.Origin.Sub(fa.Origin).Dot(fa.Z)-c.d)
+	}
+	return r
+}
+
+//-----------------------------------------------------------------------------
+
+// place finds the initial poses: fixed parts, then parts connected to placed parts.
+// It returns the parts that are free to move.
+func (a *Assembly) place() map[*Part]bool {
+	free := make(map[*Part]bool)
+	placed := make(map[*Part]bool)
+	fixed := make(map[*Part]bool)
+	for p, m := range a.fixed {
+		a.pose[p] = m
+		placed[p], fixed[p] = true, true
+	}
+	for {
+		progress := true
+		for progress {
+			progress = false
+			for _, c := range a.constraints {
+				pa, pb := c.a.Part, c.b.Part
+				if placed[pa] && !placed[pb] {
+					a.pose[pb] = c.initial(a.pose[pa])
+				} else if placed[pb] && !placed[pa] {
+					// the reverse constraint places a from b, the solver fixes a distance
+					r := &constraint{kind: c.kind, fa: c.fb, fb: c.fa}
+					a.pose[pa] = r.initial(a.pose[pb])
+				} else {
+					continue
+				}
+				placed[pa], placed[pb] = true, true
+				free[pa], free[pb] = !fixed[pa], !fixed[pb]
+				progress = true
+			}
+		}
+		// fix the first part that isn't placed where it is
+		var next *Part
+		for _, p := range a.parts {
+			if !placed[p] {
+				next = p
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		a.pose[next] = sdf.Identity3d()
+		placed[next], fixed[next] = true, true
+	}
+	return free
+}
+
+// poseMatrix returns a pose moved by rotation vector w about a center and translation t.
+func poseMatrix(base sdf.M44, center v3.Vec, x []float64) sdf.M44 {
+	w := v3.Vec{x[0], x[1], x[2]}
+	t := v3.Vec{x[3], x[4], x[5]}
+	m := base
+	if angle := w.Length(); angle > 0 {
+		m = sdf.Translate3d(center).Mul(sdf.Rotate3d(w, angle)).Mul(sdf.Translate3d(center.Neg())).Mul(m)
+	}
+	return sdf.Translate3d(t).Mul(m)
+}
+
+// Solve solves for the part transforms.
+func (a *Assembly) Solve() error {
+	if a.err != nil {
+		return a.err
+	}
+	if len(a.parts) == 0 {
+		return sdf.ErrMsg("empty assembly")
+	}
+	a.pose = make(map[*Part]sdf.M44)
+	free := a.place()
+
+	// the free parts and their rotation centers
+	var vars []*Part
+	index := make(map[*Part]int)
+	centers := make(map[*Part]v3.Vec)
+	scale := 0.0
+	for _, p := range a.parts {
+		bb := p.BoundingBox()
+		scale = math.Max(scale, bb.Size().MaxComponent())
+		if free[p] {
+			index[p] = len(vars)
+			vars = append(vars, p)
+			centers[p] = a.pose[p].MulPosition(bb.Center())
+		}
+	}
+	if scale == 0 {
+		scale = 1
+	}
+	base := make(map[*Part]sdf.M44)
+	for p, m := range a.pose {
+		base[p] = m
+	}
+	poses := func(x []float64) map[*Part]sdf.M44 {
+		m := make(map[*Part]sdf.M44, len(base))
+		for p, b := range base {
+			if i, ok := index[p]; ok {
+				m[p] = poseMatrix(b, centers[p], x[6*i:6*i+6])
+			} else {
+				m[p] = b
+			}
+		}
+		return m
+	}
+	residuals := func(x []float64) []float64 {
+		m := poses(x)
+		var r []float64
+		for _, c := range a.constraints {
+			r = c.residual(m[c.a.Part], m[c.b.Part], scale, r)
+		}
+		return r
+	}
+	cost := func(r []float64) float64 {
+		s := 0.0
+		for _, x := range r {
+			s += x * x
+		}
+		return s
+	}
+
+	x := make([]float64, 6*len(vars))
+	r := residuals(x)
+	tol := 1e-9 * scale
+	if len(x) > 0 {
+		x, r = levenbergMarquardt(x, r, residuals, cost, tol)
+	}
+	for i, ri := range r {
+		if math.Abs(ri) > 1e-6*scale || math.IsNaN(ri) {
+			return sdf.ErrMsg(fmt.Sprintf("the constraints can't be satisfied (%s)", a.residualConstraint(i)))
+		}
+	}
+	a.pose = poses(x)
+	return nil
+}
+
+// residualConstraint returns the name of the constraint of a residual index.
+func (a *Assembly) residualConstraint(i int) string {
+	n := 0
+	for _, c := range a.constraints {
+		n += len(c.residual(sdf.Identity3d(), sdf.Identity3d(), 1, nil))
+		if i < n {
+			return fmt.Sprintf("%s %s %s", kindName[c.kind], c.a, c.b)
+		}
+	}
+	return "unknown"
+}
+
+// levenbergMarquardt minimizes the squared residuals, starting from x with residuals r.
+func levenbergMarquardt(x, r []float64, residuals func([]float64) []float64, cost func([]float64) float64, tol float64) ([]float64, []float64) {
+	const h = 1e-7
+	n := len(x)
+	lambda := 1e-3
+	c := cost(r)
+	for iter := 0; iter < 200 && c > tol*tol; iter++ {
+		// numeric jacobian
+		m := len(r)
+		j := mat.NewDense(m, n, nil)
+		xh := append([]float64{}, x...)
+		for k := 0; k < n; k++ {
+			xh[k] = x[k] + h
+			rh := residuals(xh)
+			xh[k] = x[k]
+			for i := 0; i < m; i++ {
+				j.Set(i, k, (rh[i]-r[i])/h)
+			}
+		}
+		var jtj mat.Dense
+		jtj.Mul(j.T(), j)
+		var jtr mat.VecDense
+		jtr.MulVec(j.T(), mat.NewVecDense(m, r))
+		improved := false
+		for tries := 0; tries < 20; tries++ {
+			a := mat.DenseCopyOf(&jtj)
+			for k := 0; k < n; k++ {
+				a.Set(k, k, a.At(k, k)+lambda)
+			}
+			var step mat.VecDense
+			if err := step.SolveVec(a, &jtr); err != nil {
+				lambda *= 10
+				continue
+			}
+			xn := make([]float64, n)
+			for k := range xn {
+				xn[k] = x[k] - step.AtVec(k)
+			}
+			rn := residuals(xn)
+			if cn := cost(rn); cn < c {
+				x, r, c = xn, rn, cn
+				lambda = math.Max(lambda/10, 1e-12)
+				improved = true
+				break
+			}
+			lambda *= 10
+		}
+		if !improved {
+			break
+		}
+	}
+	return x, r
+}
+
+//-----------------------------------------------------------------------------
+
+// Transform returns the solved transform of a part.
+func (a *Assembly) Transform(p *Part) sdf.M44 {
+	if m, ok := a.pose[p]; ok {
+		return m
+	}
+	return sdf.Identity3d()
+}
+
+// Frame returns an anchor frame in assembly coordinates.
+func (a *Assembly) Frame(r Ref) (Frame, error) {
+	f, err := r.frame()
+	if err != nil {
+		return Frame{}, err
+	}
+	return f.Transform(a.Transform(r.Part)), nil
+}
+
+// Placed returns a part placed with its solved transform.
+func (a *Assembly) Placed(p *Part) sdf.SDF3 {
+	return sdf.Transform3D(p.SDF3, a.Transform(p))
+}
+
+// SDF3 returns the union of the placed parts.
+func (a *Assembly) SDF3() sdf.SDF3 {
+	s := make([]sdf.SDF3, len(a.parts))
+	for i, p := range a.parts {
+		s[i] = a.Placed(p)
+	}
+	return sdf.Union3D(s...)
+}
+
+//-----------------------------------------------------------------------------
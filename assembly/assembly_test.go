@@ -0,0 +1,134 @@
+//-----------------------------------------------------------------------------
+/*
+
+Assembly Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package assembly
+
+import (
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+const tolerance = 1e-6
+
+func box(t *testing.T, name string, size v3.Vec) *Part {
+	s, err := sdf.Box3D(size, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewPart(name, s).BoundingBoxAnchors()
+}
+
+func checkFrame(t *testing.T, a *Assembly, r Ref, origin, z v3.Vec) {
+	t.Helper()
+	f, err := a.Frame(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Origin.Equals(origin, tolerance) || !f.Z.Equals(z, tolerance) {
+		t.Errorf("%s: got %v %v, expected %v %v", r, f.Origin, f.Z, origin, z)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Mate(t *testing.T) {
+	base := box(t, "base", v3.Vec{100, 50, 10})
+	lid := box(t, "lid", v3.Vec{100, 50, 4})
+	a := New()
+	if err := a.Add(base, lid); err != nil {
+		t.Fatal(err)
+	}
+	a.Mate(base.At("top"), lid.At("bottom"))
+	if err := a.Solve(); err != nil {
+		t.Fatal(err)
+	}
+	checkFrame(t, a, lid.At("bottom"), v3.Vec{0, 0, 5}, v3.Vec{0, 0, -1})
+	checkFrame(t, a, lid.At("top"), v3.Vec{0, 0, 9}, v3.Vec{0, 0, 1})
+	// the base is fixed
+	checkFrame(t, a, base.At("top"), v3.Vec{0, 0, 5}, v3.Vec{0, 0, 1})
+	// the union covers both parts
+	s := a.SDF3()
+	if s.Evaluate(v3.Vec{0, 0, 7}) >= 0 || s.Evaluate(v3.Vec{0, 0, 10}) <= 0 {
+		t.Error("bad assembly SDF")
+	}
+
+	// top to top turns the lid over
+	a = New()
+	a.Add(base, lid)
+	a.Mate(base.At("top"), lid.At("top"))
+	if err := a.Solve(); err != nil {
+		t.Fatal(err)
+	}
+	checkFrame(t, a, lid.At("top"), v3.Vec{0, 0, 5}, v3.Vec{0, 0, -1})
+	checkFrame(t, a, lid.At("bottom"), v3.Vec{0, 0, 9}, v3.Vec{0, 0, 1})
+}
+
+func Test_ConcentricDistance(t *testing.T) {
+	plate := box(t, "plate", v3.Vec{60, 40, 10})
+	f, _ := NewFrame(v3.Vec{20, 10, 5}, v3.Vec{0, 0, -1}, v3.Vec{1, 0, 0})
+	plate.Anchor("hole", f)
+
+	cyl, err := sdf.Cylinder3D(20, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a peg lying along the x axis
+	peg := NewPart("peg", sdf.Transform3D(cyl, sdf.RotateY(sdf.DtoR(90))))
+	f, _ = NewFrame(v3.Vec{-10, 0, 0}, v3.Vec{-1, 0, 0}, v3.Vec{0, 0, 1})
+	peg.Anchor("tip", f)
+
+	a := New()
+	a.Add(plate, peg)
+	a.Concentric(plate.At("hole"), peg.At("tip"))
+	a.Distance(plate.At("hole"), peg.At("tip"), 4)
+	if err := a.Solve(); err != nil {
+		t.Fatal(err)
+	}
+	// the peg stands in the hole, the tip 4mm below the surface
+	checkFrame(t, a, peg.At("tip"), v3.Vec{20, 10, 1}, v3.Vec{0, 0, -1})
+	s := a.Placed(peg)
+	if s.Evaluate(v3.Vec{20, 10, 15}) >= 0 || s.Evaluate(v3.Vec{20, 10, 0}) <= 0 {
+		t.Error("bad peg placement")
+	}
+}
+
+func Test_Errors(t *testing.T) {
+	base := box(t, "base", v3.Vec{10, 10, 10})
+	lid := box(t, "lid", v3.Vec{10, 10, 2})
+
+	a := New()
+	if err := a.Add(base, box(t, "base", v3.Vec{1, 1, 1})); err == nil {
+		t.Error("expected a duplicate name error")
+	}
+
+	a = New()
+	a.Add(base, lid)
+	a.Mate(base.At("top"), lid.At("nope"))
+	if err := a.Solve(); err == nil {
+		t.Error("expected an unknown anchor error")
+	}
+
+	// conflicting constraints
+	a = New()
+	a.Add(base, lid)
+	a.Mate(base.At("top"), lid.At("bottom"))
+	a.Distance(base.At("top"), lid.At("bottom"), 3)
+	if err := a.Solve(); err == nil {
+		t.Error("expected an unsatisfiable constraints error")
+	}
+
+	if _, err := NewFrame(v3.Vec{}, v3.Vec{0, 0, 1}, v3.Vec{0, 0, 2}); err == nil {
+		t.Error("expected a parallel axes error")
+	}
+}
+
+//-----------------------------------------------------------------------------
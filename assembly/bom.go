@@ -0,0 +1,276 @@
+//-----------------------------------------------------------------------------
+/*
+
+Bill of Materials
+
+Parts carry build metadata: the catalog part they were built from, the
+material, the number to make and the parameter values. The bill of
+materials lists them as CSV or JSON so a project with many printed parts
+can generate its build list. Estimate adds the material usage, print time
+and cost of each part for quoting.
+
+	washer, err := assembly.CatalogPart("washer", "washer", map[string]string{"inner": "4"})
+	washer.SetMaterial("PETG").SetQuantity(8)
+	...
+	b := a.BOM()
+	b.Estimate(render.NewMarchingCubesOctree(200), fab.DefaultFDM)
+	b.WriteCSV(os.Stdout)
+
+*/
+//-----------------------------------------------------------------------------
+
+package assembly
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/deadsy/sdfx/catalog"
+	"github.com/deadsy/sdfx/fab"
+	"github.com/deadsy/sdfx/material"
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// Param is a named parameter value of a part.
+type Param struct {
+	Name  string
+	Value interface{}
+}
+
+// CatalogPart returns a part built from the catalog, with the resolved parameter values as metadata.
+func CatalogPart(name, catalogName string, args map[string]string) (*Part, error) {
+	c, v, err := catalog.Resolve(catalogName, args)
+	if err != nil {
+		return nil, err
+	}
+	s, err := c.Build(v)
+	if err != nil {
+		return nil, err
+	}
+	p := NewPart(name, s)
+	p.source = catalogName
+	for _, x := range c.Params() {
+		p.SetParam(x.Name, v[x.Name])
+	}
+	return p, nil
+}
+
+// SetMaterial sets the material of the part.
+func (p *Part) SetMaterial(material string) *Part {
+	p.material = material
+	return p
+}
+
+// SetQuantity sets the number of parts to make (default 1).
+func (p *Part) SetQuantity(n int) *Part {
+	p.quantity = n
+	return p
+}
+
+// SetParam sets a named parameter value of the part.
+func (p *Part) SetParam(name string, value interface{}) *Part {
+	for i := range p.params {
+		if p.params[i].Name == name {
+			p.params[i].Value = value
+			return p
+		}
+	}
+	p.params = append(p.params, Param{name, value})
+	return p
+}
+
+// Quantity returns the number of parts to make.
+func (p *Part) Quantity() int {
+	if p.quantity <= 0 {
+		return 1
+	}
+	return p.quantity
+}
+
+//-----------------------------------------------------------------------------
+
+// BOMItem is a line of a bill of materials.
+type BOMItem struct {
+	Name     string        `json:"name"`
+	Source   string        `json:"source,omitempty"` // catalog part name
+	Material string        `json:"material,omitempty"`
+	Quantity int           `json:"quantity"`
+	Params   []Param       `json:"-"`
+	Estimate *fab.Estimate `json:"estimate,omitempty"` // estimate for one part
+	part     *Part
+}
+
+// MarshalJSON encodes the item with the parameters as an object in parameter order.
+func (b BOMItem) MarshalJSON() ([]byte, error) {
+	type item BOMItem
+	x, err := json.Marshal(item(b))
+	if err != nil || len(b.Params) == 0 {
+		return x, err
+	}
+	var sb strings.Builder
+	sb.Write(x[:len(x)-1])
+	sb.WriteString(`,"params":{`)
+	for i, p := range b.Params {
+		k, _ := json.Marshal(p.Name)
+		v, err := json.Marshal(p.Value)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.Write(k)
+		sb.WriteString(":")
+		sb.Write(v)
+	}
+	sb.WriteString("}}")
+	return []byte(sb.String()), nil
+}
+
+// BOM is a bill of materials.
+type BOM struct {
+	Items []BOMItem `json:"items"`
+}
+
+// NewBOM returns the bill of materials for a set of parts.
+func NewBOM(parts ...*Part) *BOM {
+	b := &BOM{}
+	for _, p := range parts {
+		b.Items = append(b.Items, BOMItem{
+			Name:     p.name,
+			Source:   p.source,
+			Material: p.material,
+			Quantity: p.Quantity(),
+			Params:   append([]Param(nil), p.params...),
+			part:     p,
+		})
+	}
+	return b
+}
+
+// BOM returns the bill of materials for the parts of the assembly.
+func (a *Assembly) BOM() *BOM {
+	return NewBOM(a.parts...)
+}
+
+// Total returns the total number of parts to make.
+func (b *BOM) Total() int {
+	n := 0
+	for _, x := range b.Items {
+		n += x.Quantity
+	}
+	return n
+}
+
+// Estimate renders each part with a material and estimates its material usage,
+// print time and cost on a machine. Parts without a material are not estimated.
+func (b *BOM) Estimate(r render.Render3, machine *fab.Machine) error {
+	for i := range b.Items {
+		x := &b.Items[i]
+		if x.Material == "" || x.part == nil {
+			continue
+		}
+		m, err := material.Get(x.Material)
+		if err != nil {
+			return err
+		}
+		e, err := fab.EstimateSDF3(x.part.SDF3, r, m, machine)
+		if err != nil {
+			return sdf.ErrMsg(fmt.Sprintf("part \"%s\": %s", x.Name, err))
+		}
+		x.Estimate = e
+	}
+	return nil
+}
+
+// Cost returns the total estimated cost of the parts to make.
+func (b *BOM) Cost() float64 {
+	cost := 0.0
+	for _, x := range b.Items {
+		if x.Estimate != nil {
+			cost += x.Estimate.Cost * float64(x.Quantity)
+		}
+	}
+	return cost
+}
+
+// estimated returns true if any item of the bill of materials has an estimate.
+func (b *BOM) estimated() bool {
+	for _, x := range b.Items {
+		if x.Estimate != nil {
+			return true
+		}
+	}
+	return false
+}
+
+//-----------------------------------------------------------------------------
+
+// WriteCSV writes the bill of materials as CSV with a header line.
+// The parameters are a single "name=value; ..." column.
+// Estimated bills of materials have per part volume (mm^3), mass (g), time
+// and cost columns, and a total cost column for the quantity.
+func (b *BOM) WriteCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	header := []string{"name", "source", "material", "quantity", "parameters"}
+	estimated := b.estimated()
+	if estimated {
+		header = append(header, "volume", "mass", "time", "cost", "total_cost")
+	}
+	c.Write(header)
+	for _, x := range b.Items {
+		params := make([]string, len(x.Params))
+		for i, p := range x.Params {
+			params[i] = fmt.Sprintf("%s=%v", p.Name, p.Value)
+		}
+		line := []string{x.Name, x.Source, x.Material, strconv.Itoa(x.Quantity), strings.Join(params, "; ")}
+		if e := x.Estimate; e != nil {
+			line = append(line,
+				fmt.Sprintf("%.1f", e.Volume),
+				fmt.Sprintf("%.1f", e.Mass),
+				e.Time.String(),
+				fmt.Sprintf("%.2f", e.Cost),
+				fmt.Sprintf("%.2f", e.Cost*float64(x.Quantity)),
+			)
+		} else if estimated {
+			line = append(line, "", "", "", "", "")
+		}
+		c.Write(line)
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// WriteJSON writes the bill of materials as indented JSON.
+func (b *BOM) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(b)
+}
+
+// Save writes the bill of materials to a file, as JSON for a ".json" path and as CSV otherwise.
+func (b *BOM) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	write := b.WriteCSV
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		write = b.WriteJSON
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,137 @@
+//-----------------------------------------------------------------------------
+/*
+
+Bill of Materials Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package assembly
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/fab"
+	"github.com/deadsy/sdfx/render"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_BOM(t *testing.T) {
+	base := box(t, "base", v3.Vec{40, 40, 5}).SetMaterial("PLA").SetParam("width", 40.0)
+	washer, err := CatalogPart("washer", "washer", map[string]string{"inner": "4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	washer.SetMaterial("PETG").SetQuantity(8)
+
+	if _, err := CatalogPart("x", "washer", map[string]string{"nope": "1"}); err == nil {
+		t.Error("expected an unknown parameter error")
+	}
+
+	a := New()
+	if err := a.Add(base, washer); err != nil {
+		t.Fatal(err)
+	}
+	b := a.BOM()
+	if b.Total() != 9 {
+		t.Errorf("expected 9 parts, got %d", b.Total())
+	}
+
+	var buf bytes.Buffer
+	if err := b.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[0] != "name,source,material,quantity,parameters" || lines[1] != "base,,PLA,1,width=40" {
+		t.Fatalf("bad CSV:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(lines[2], "washer,washer,PETG,8,thickness=1.5; inner=4;") {
+		t.Errorf("bad washer line: %s", lines[2])
+	}
+
+	buf.Reset()
+	if err := b.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var x struct {
+		Items []struct {
+			Name     string
+			Material string
+			Quantity int
+			Params   map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &x); err != nil {
+		t.Fatal(err)
+	}
+	if len(x.Items) != 2 || x.Items[1].Quantity != 8 || x.Items[1].Params["inner"] != 4.0 || x.Items[0].Material != "PLA" {
+		t.Errorf("bad JSON:\n%s", buf.String())
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_BOMEstimate(t *testing.T) {
+	base := box(t, "base", v3.Vec{40, 40, 5}).SetMaterial("PLA").SetQuantity(3)
+	spacer := box(t, "spacer", v3.Vec{10, 10, 10})
+	b := NewBOM(base, spacer)
+	if err := b.Estimate(render.NewMarchingCubesOctree(50), fab.DefaultFDM); err != nil {
+		t.Fatal(err)
+	}
+	e := b.Items[0].Estimate
+	if e == nil || b.Items[1].Estimate != nil {
+		t.Fatal("expected an estimate for the part with a material only")
+	}
+	if math.Abs(e.Volume-8000) > 80 || e.Cost <= 0 {
+		t.Errorf("bad estimate: %s", e)
+	}
+	if math.Abs(b.Cost()-3*e.Cost) > 1e-9 {
+		t.Errorf("total cost %g, expected %g", b.Cost(), 3*e.Cost)
+	}
+
+	var buf bytes.Buffer
+	if err := b.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[0] != "name,source,material,quantity,parameters,volume,mass,time,cost,total_cost" {
+		t.Fatalf("bad CSV:\n%s", buf.String())
+	}
+	if want := fmt.Sprintf("base,,PLA,3,,%.1f,%.1f,%s,%.2f,%.2f", e.Volume, e.Mass, e.Time, e.Cost, 3*e.Cost); lines[1] != want {
+		t.Errorf("bad base line: %s, expected %s", lines[1], want)
+	}
+	if lines[2] != "spacer,,,1,,,,,," {
+		t.Errorf("bad spacer line: %s", lines[2])
+	}
+
+	buf.Reset()
+	if err := b.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var x struct {
+		Items []struct {
+			Estimate *fab.Estimate
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &x); err != nil {
+		t.Fatal(err)
+	}
+	if len(x.Items) != 2 || x.Items[0].Estimate == nil || x.Items[0].Estimate.Cost != e.Cost || x.Items[1].Estimate != nil {
+		t.Errorf("bad JSON:\n%s", buf.String())
+	}
+
+	// materials without an estimate model are an error
+	base.SetMaterial("Aluminum")
+	if err := NewBOM(base).Estimate(render.NewMarchingCubesOctree(50), fab.DefaultFDM); err == nil {
+		t.Error("expected an error for a CNC material")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,252 @@
+//-----------------------------------------------------------------------------
+/*
+
+Parametric Part Catalog
+
+A registry of reusable parametric parts. Each part describes its parameters
+(name, type, default, range or choices) and builds an SDF3 from a set of
+parameter values. The schema lets tools list the parts, show their
+parameters and build them from command line flags or other text input.
+
+Parts are published as Go packages that register them in an init function.
+A program picks up the parts of a package by importing it:
+
+	import _ "example.com/fixtures/catalog"
+
+	func main() {
+		catalog.Main()
+	}
+
+The catalog includes some of the sdfx obj parts (bolt, nut, washer, standoff).
+
+*/
+//-----------------------------------------------------------------------------
+
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// Param describes a part parameter. The parameter type is the type of the
+// default value: float64, int, bool or string.
+type Param struct {
+	Name     string      // parameter name
+	Doc      string      // one line description
+	Default  interface{} // default value
+	Unit     string      // unit of a numeric value (e.g. "mm", "deg")
+	Min, Max float64     // numeric range (Min == Max: no range)
+	Choices  []string    // allowed string values (nil: any value)
+}
+
+// Type returns the name of the parameter type.
+func (p *Param) Type() string {
+	switch p.Default.(type) {
+	case float64:
+		return "float"
+	case int:
+		return "int"
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	}
+	return "unknown"
+}
+
+// check returns an error if a value is not valid for the parameter.
+func (p *Param) check(v interface{}) error {
+	var x float64
+	switch v := v.(type) {
+	case float64:
+		x = v
+	case int:
+		x = float64(v)
+	case string:
+		if p.Choices == nil {
+			return nil
+		}
+		for _, c := range p.Choices {
+			if v == c {
+				return nil
+			}
+		}
+		return sdf.ErrMsg(fmt.Sprintf("%s: \"%s\" is not one of %s", p.Name, v, strings.Join(p.Choices, ", ")))
+	default:
+		return nil
+	}
+	if p.Min != p.Max && (x < p.Min || x > p.Max) {
+		return sdf.ErrMsg(fmt.Sprintf("%s: %g is not in [%g, %g]", p.Name, x, p.Min, p.Max))
+	}
+	return nil
+}
+
+// Parse parses a parameter value from a string.
+func (p *Param) Parse(s string) (interface{}, error) {
+	var v interface{}
+	var err error
+	switch p.Default.(type) {
+	case float64:
+		v, err = strconv.ParseFloat(s, 64)
+	case int:
+		v, err = strconv.Atoi(s)
+	case bool:
+		v, err = strconv.ParseBool(s)
+	case string:
+		v = s
+	default:
+		return nil, sdf.ErrMsg(fmt.Sprintf("%s: unknown parameter type", p.Name))
+	}
+	if err != nil {
+		return nil, sdf.ErrMsg(fmt.Sprintf("%s: bad %s value \"%s\"", p.Name, p.Type(), s))
+	}
+	return v, p.check(v)
+}
+
+//-----------------------------------------------------------------------------
+
+// Values are named parameter values.
+type Values map[string]interface{}
+
+// Float returns a float64 parameter value.
+func (v Values) Float(name string) float64 {
+	switch x := v[name].(type) {
+	case float64:
+		return x
+	case int:
+		return float64(x)
+	}
+	return 0
+}
+
+// Int returns an int parameter value.
+func (v Values) Int(name string) int {
+	x, _ := v[name].(int)
+	return x
+}
+
+// Bool returns a bool parameter value.
+func (v Values) Bool(name string) bool {
+	x, _ := v[name].(bool)
+	return x
+}
+
+// String returns a string parameter value.
+func (v Values) String(name string) string {
+	x, _ := v[name].(string)
+	return x
+}
+
+//-----------------------------------------------------------------------------
+
+// Parametric is a parametric part.
+type Parametric interface {
+	Name() string                     // part name
+	Doc() string                      // one line description
+	Params() []Param                  // parameter schema
+	Build(v Values) (sdf.SDF3, error) // build the part from a complete set of values
+}
+
+// funcPart is a parametric part built by a function.
+type funcPart struct {
+	name, doc string
+	params    []Param
+	build     func(v Values) (sdf.SDF3, error)
+}
+
+func (p *funcPart) Name() string                     { return p.name }
+func (p *funcPart) Doc() string                      { return p.doc }
+func (p *funcPart) Params() []Param                  { return p.params }
+func (p *funcPart) Build(v Values) (sdf.SDF3, error) { return p.build(v) }
+
+// NewPart returns a parametric part built by a function.
+func NewPart(name, doc string, params []Param, build func(v Values) (sdf.SDF3, error)) Parametric {
+	return &funcPart{name, doc, params, build}
+}
+
+//-----------------------------------------------------------------------------
+
+var parts = map[string]Parametric{}
+
+// Register adds a part to the catalog. It panics if the name is already used,
+// so two packages can't silently replace each other's parts.
+func Register(p Parametric) {
+	name := p.Name()
+	if _, ok := parts[name]; ok {
+		panic(fmt.Sprintf("catalog: part \"%s\" is already registered", name))
+	}
+	parts[name] = p
+}
+
+// Lookup returns a named part.
+func Lookup(name string) (Parametric, error) {
+	p, ok := parts[name]
+	if !ok {
+		return nil, sdf.ErrMsg(fmt.Sprintf("part \"%s\" is not in the catalog", name))
+	}
+	return p, nil
+}
+
+// List returns the catalog parts sorted by name.
+func List() []Parametric {
+	list := make([]Parametric, 0, len(parts))
+	for _, p := range parts {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// Defaults returns the default parameter values of a part.
+func Defaults(p Parametric) Values {
+	v := Values{}
+	for _, x := range p.Params() {
+		v[x.Name] = x.Default
+	}
+	return v
+}
+
+// Resolve returns a named part and its parameter values. The arguments are
+// parameter values as text, parameters without an argument have their
+// default value.
+func Resolve(name string, args map[string]string) (Parametric, Values, error) {
+	p, err := Lookup(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	v := Defaults(p)
+	schema := make(map[string]*Param)
+	params := p.Params()
+	for i := range params {
+		schema[params[i].Name] = &params[i]
+	}
+	for k, s := range args {
+		x, ok := schema[k]
+		if !ok {
+			return nil, nil, sdf.ErrMsg(fmt.Sprintf("%s has no parameter \"%s\"", name, k))
+		}
+		if v[k], err = x.Parse(s); err != nil {
+			return nil, nil, err
+		}
+	}
+	return p, v, nil
+}
+
+// New builds a named part. The arguments are parameter values as text,
+// parameters without an argument have their default value.
+func New(name string, args map[string]string) (sdf.SDF3, error) {
+	p, v, err := Resolve(name, args)
+	if err != nil {
+		return nil, err
+	}
+	return p.Build(v)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,102 @@
+//-----------------------------------------------------------------------------
+/*
+
+Parametric Part Catalog Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package catalog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Catalog(t *testing.T) {
+	Register(NewPart("test_box", "a box", []Param{
+		{Name: "size", Default: 10.0, Min: 1, Max: 100},
+		{Name: "round", Default: false},
+	}, func(v Values) (sdf.SDF3, error) {
+		r := 0.0
+		if v.Bool("round") {
+			r = 1
+		}
+		x := v.Float("size")
+		return sdf.Box3D(v3.Vec{x, x, x}, r)
+	}))
+	s, err := New("test_box", map[string]string{"size": "20"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := s.BoundingBox().Size().X; x != 20 {
+		t.Errorf("size %f", x)
+	}
+	for _, args := range []map[string]string{
+		{"size": "200"},
+		{"size": "big"},
+		{"round": "maybe"},
+		{"color": "red"},
+	} {
+		if _, err := New("test_box", args); err == nil {
+			t.Errorf("expected an error for %v", args)
+		}
+	}
+	if _, err := New("no_such_part", nil); err == nil {
+		t.Error("expected an error for a missing part")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a repeated name")
+		}
+	}()
+	Register(NewPart("test_box", "", nil, nil))
+}
+
+func Test_CatalogParts(t *testing.T) {
+	for _, p := range List() {
+		if strings.HasPrefix(p.Name(), "test_") {
+			continue
+		}
+		if _, err := p.Build(Defaults(p)); err != nil {
+			t.Errorf("%s: %s", p.Name(), err)
+		}
+	}
+	if _, err := New("nut", map[string]string{"style": "square"}); err == nil {
+		t.Error("expected an error for a bad choice")
+	}
+}
+
+func Test_CatalogMain(t *testing.T) {
+	var buf bytes.Buffer
+	if err := run([]string{"list"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "bolt") {
+		t.Errorf("bolt not listed:\n%s", buf.String())
+	}
+	buf.Reset()
+	if err := run([]string{"info", "washer"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "-remove") {
+		t.Errorf("bad info:\n%s", buf.String())
+	}
+	path := filepath.Join(t.TempDir(), "washer.stl")
+	if err := run([]string{"build", "washer", "-outer", "8", "-cells", "20", "-o", path}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error(err)
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,141 @@
+//-----------------------------------------------------------------------------
+/*
+
+Catalog Command Line
+
+A program that imports the catalog packages it wants calls Main to list,
+describe and build the parts. The part parameters are command line flags.
+
+	$ ./parts list
+	$ ./parts info bolt
+	$ ./parts build bolt -thread M4x0.7 -length 16 -o bolt.stl
+
+*/
+//-----------------------------------------------------------------------------
+
+package catalog
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// Main is the command line entry point for a catalog program.
+func Main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage:\n")
+	fmt.Fprintf(w, "  %s list\n", filepath.Base(os.Args[0]))
+	fmt.Fprintf(w, "  %s info <part>\n", filepath.Base(os.Args[0]))
+	fmt.Fprintf(w, "  %s build <part> [-param value ...] [-o file] [-cells n]\n", filepath.Base(os.Args[0]))
+}
+
+func run(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		usage(w)
+		return sdf.ErrMsg("no command")
+	}
+	switch args[0] {
+	case "list":
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		for _, p := range List() {
+			fmt.Fprintf(tw, "%s\t%s\n", p.Name(), p.Doc())
+		}
+		return tw.Flush()
+	case "info":
+		if len(args) != 2 {
+			usage(w)
+			return sdf.ErrMsg("no part name")
+		}
+		p, err := Lookup(args[1])
+		if err != nil {
+			return err
+		}
+		return WriteInfo(w, p)
+	case "build":
+		if len(args) < 2 {
+			usage(w)
+			return sdf.ErrMsg("no part name")
+		}
+		return build(args[1], args[2:], w)
+	}
+	usage(w)
+	return sdf.ErrMsg(fmt.Sprintf("unknown command \"%s\"", args[0]))
+}
+
+// WriteInfo writes the parameter schema of a part as a table of flags.
+func WriteInfo(w io.Writer, p Parametric) error {
+	fmt.Fprintf(w, "%s: %s\n", p.Name(), p.Doc())
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, x := range p.Params() {
+		limits := ""
+		if x.Min != x.Max {
+			limits = fmt.Sprintf("[%g, %g]", x.Min, x.Max)
+		}
+		if x.Choices != nil {
+			limits = strings.Join(x.Choices, "|")
+		}
+		fmt.Fprintf(tw, "  -%s\t%s\t%v\t%s\t%s\n", x.Name, x.Type(), x.Default, limits, x.Doc)
+	}
+	return tw.Flush()
+}
+
+// build builds a part with parameters from command line flags and renders it to a file.
+func build(name string, args []string, w io.Writer) error {
+	p, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(w)
+	out := fs.String("o", name+".stl", "output file (.stl, .3mf or .obj)")
+	cells := fs.Int("cells", 200, "mesh cells on the longest axis")
+	values := make(map[string]*string)
+	for _, x := range p.Params() {
+		values[x.Name] = fs.String(x.Name, fmt.Sprint(x.Default), x.Doc)
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// only the flags that were set, the rest have their defaults
+	set := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if _, ok := values[f.Name]; ok {
+			set[f.Name] = *values[f.Name]
+		}
+	})
+	s, err := New(name, set)
+	if err != nil {
+		return err
+	}
+	r := render.NewMarchingCubesOctree(*cells)
+	fmt.Fprintf(w, "rendering %s (%s)\n", *out, r.Info(s))
+	mesh := render.ToTriangles(s, r)
+	switch strings.ToLower(filepath.Ext(*out)) {
+	case ".stl":
+		return render.SaveSTL(*out, mesh)
+	case ".3mf":
+		return render.Save3MF(*out, []render.Part3MF{{Name: name, Color: color.RGBA{128, 128, 128, 255}, Mesh: mesh}})
+	case ".obj":
+		return render.SaveOBJ(*out, mesh)
+	}
+	return sdf.ErrMsg(fmt.Sprintf("unknown output file type \"%s\"", *out))
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,94 @@
+//-----------------------------------------------------------------------------
+/*
+
+Catalog Parts
+
+The obj parts that are included in the catalog.
+
+*/
+//-----------------------------------------------------------------------------
+
+package catalog
+
+import (
+	"github.com/deadsy/sdfx/obj"
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+func init() {
+	Register(NewPart("bolt", "hex or knurled head bolt",
+		[]Param{
+			{Name: "thread", Doc: "thread name", Default: "M6x1"},
+			{Name: "style", Doc: "head style", Default: "hex", Choices: []string{"hex", "knurl"}},
+			{Name: "tolerance", Doc: "subtract from the external thread radius", Default: 0.0, Unit: "mm", Min: 0, Max: 1},
+			{Name: "length", Doc: "total length", Default: 20.0, Unit: "mm", Min: 0, Max: 1000},
+			{Name: "shank", Doc: "non threaded length", Default: 5.0, Unit: "mm", Min: 0, Max: 1000},
+		},
+		func(v Values) (sdf.SDF3, error) {
+			return obj.Bolt(&obj.BoltParms{
+				Thread:      v.String("thread"),
+				Style:       v.String("style"),
+				Tolerance:   v.Float("tolerance"),
+				TotalLength: v.Float("length"),
+				ShankLength: v.Float("shank"),
+			})
+		}))
+
+	Register(NewPart("nut", "hex or knurled nut",
+		[]Param{
+			{Name: "thread", Doc: "thread name", Default: "M6x1"},
+			{Name: "style", Doc: "nut style", Default: "hex", Choices: []string{"hex", "knurl"}},
+			{Name: "tolerance", Doc: "add to the internal thread radius", Default: 0.0, Unit: "mm", Min: 0, Max: 1},
+		},
+		func(v Values) (sdf.SDF3, error) {
+			return obj.Nut(&obj.NutParms{
+				Thread:    v.String("thread"),
+				Style:     v.String("style"),
+				Tolerance: v.Float("tolerance"),
+			})
+		}))
+
+	Register(NewPart("washer", "flat washer, optionally partial",
+		[]Param{
+			{Name: "thickness", Doc: "thickness", Default: 1.5, Unit: "mm"},
+			{Name: "inner", Doc: "inner radius", Default: 3.2, Unit: "mm"},
+			{Name: "outer", Doc: "outer radius", Default: 6.0, Unit: "mm"},
+			{Name: "remove", Doc: "fraction of the washer removed", Default: 0.0, Min: 0, Max: 1},
+		},
+		func(v Values) (sdf.SDF3, error) {
+			return obj.Washer3D(&obj.WasherParms{
+				Thickness:   v.Float("thickness"),
+				InnerRadius: v.Float("inner"),
+				OuterRadius: v.Float("outer"),
+				Remove:      v.Float("remove"),
+			})
+		}))
+
+	Register(NewPart("standoff", "board standoff with a screw hole and gussets",
+		[]Param{
+			{Name: "height", Doc: "pillar height", Default: 10.0, Unit: "mm"},
+			{Name: "diameter", Doc: "pillar diameter", Default: 6.0, Unit: "mm"},
+			{Name: "hole_depth", Doc: "hole depth (< 0 is a support stub)", Default: 8.0, Unit: "mm"},
+			{Name: "hole_diameter", Doc: "hole diameter", Default: 2.4, Unit: "mm"},
+			{Name: "webs", Doc: "number of gussets", Default: 4, Min: 0, Max: 12},
+			{Name: "web_height", Doc: "gusset height", Default: 6.0, Unit: "mm"},
+			{Name: "web_diameter", Doc: "gusset diameter", Default: 12.0, Unit: "mm"},
+			{Name: "web_width", Doc: "gusset width", Default: 2.0, Unit: "mm"},
+		},
+		func(v Values) (sdf.SDF3, error) {
+			return obj.Standoff3D(&obj.StandoffParms{
+				PillarHeight:   v.Float("height"),
+				PillarDiameter: v.Float("diameter"),
+				HoleDepth:      v.Float("hole_depth"),
+				HoleDiameter:   v.Float("hole_diameter"),
+				NumberWebs:     v.Int("webs"),
+				WebHeight:      v.Float("web_height"),
+				WebDiameter:    v.Float("web_diameter"),
+				WebWidth:       v.Float("web_width"),
+			})
+		}))
+}
+
+//-----------------------------------------------------------------------------
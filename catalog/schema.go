@@ -0,0 +1,316 @@
+//-----------------------------------------------------------------------------
+/*
+
+Parameter Schema Export
+
+Export the parameters of a part as a JSON Schema, so a web frontend can
+generate a configurator form and send the values back as JSON.
+
+Catalog parts use their parameter list. Parameter structs (e.g. the obj
+XxxParms structs) are exported by reflection: the JSON types come from the
+field types, the defaults from the field values of the struct passed in,
+and ranges, units and enums from an optional field tag:
+
+	type FlangeParms struct {
+		Diameter float64 `sdfx:"min=10,max=200,unit=mm" doc:"outer diameter"`
+		Holes    int     `sdfx:"min=3,max=12"`
+		Style    string  `sdfx:"enum=flat|raised"`
+	}
+
+The property names are the encoding/json names of the fields, so the values
+sent back can be decoded into the struct with json.Unmarshal.
+
+*/
+//-----------------------------------------------------------------------------
+
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// schemaVersion is the JSON Schema dialect.
+const schemaVersion = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a JSON Schema for part parameters.
+type JSONSchema struct {
+	Schema      string                 `json:"$schema,omitempty"`
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Type        string                 `json:"type,omitempty"`
+	Properties  map[string]*JSONSchema `json:"properties,omitempty"`
+	Order       []string               `json:"x-order,omitempty"` // property order for forms
+	Required    []string               `json:"required,omitempty"`
+	Items       *JSONSchema            `json:"items,omitempty"`
+	MinItems    *int                   `json:"minItems,omitempty"`
+	MaxItems    *int                   `json:"maxItems,omitempty"`
+	Default     interface{}            `json:"default,omitempty"`
+	Minimum     *float64               `json:"minimum,omitempty"`
+	Maximum     *float64               `json:"maximum,omitempty"`
+	Enum        []interface{}          `json:"enum,omitempty"`
+	Unit        string                 `json:"x-unit,omitempty"`
+	Additional  *bool                  `json:"additionalProperties,omitempty"`
+}
+
+// JSON returns the indented JSON form of a schema.
+func (s *JSONSchema) JSON() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// object returns an empty object schema.
+func object(title, doc string) *JSONSchema {
+	no := false
+	return &JSONSchema{
+		Title:       title,
+		Description: doc,
+		Type:        "object",
+		Properties:  make(map[string]*JSONSchema),
+		Additional:  &no,
+	}
+}
+
+// add adds a property to an object schema.
+func (s *JSONSchema) add(name string, p *JSONSchema) {
+	s.Properties[name] = p
+	s.Order = append(s.Order, name)
+	s.Required = append(s.Required, name)
+}
+
+//-----------------------------------------------------------------------------
+
+// PartSchema returns the JSON Schema for the parameters of a catalog part.
+func PartSchema(p Parametric) *JSONSchema {
+	s := object(p.Name(), p.Doc())
+	s.Schema = schemaVersion
+	for _, x := range p.Params() {
+		prop := &JSONSchema{
+			Description: x.Doc,
+			Default:     x.Default,
+			Unit:        x.Unit,
+		}
+		switch x.Default.(type) {
+		case float64:
+			prop.Type = "number"
+		case int:
+			prop.Type = "integer"
+		case bool:
+			prop.Type = "boolean"
+		case string:
+			prop.Type = "string"
+		}
+		if x.Min != x.Max {
+			lo, hi := x.Min, x.Max
+			prop.Minimum, prop.Maximum = &lo, &hi
+		}
+		for _, c := range x.Choices {
+			prop.Enum = append(prop.Enum, c)
+		}
+		s.add(x.Name, prop)
+	}
+	// parameters have defaults, so none are required
+	s.Required = nil
+	return s
+}
+
+// ParseJSON parses a JSON object of parameter values for a catalog part.
+// Missing parameters have their default values.
+func ParseJSON(p Parametric, data []byte) (Values, error) {
+	var in map[string]interface{}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, err
+	}
+	return ParseMap(p, in)
+}
+
+// ParseMap checks a map of decoded parameter values (e.g. from JSON or
+// TOML) for a catalog part. Whole numbers can be given for int and float
+// parameters. Missing parameters have their default values.
+func ParseMap(p Parametric, in map[string]interface{}) (Values, error) {
+	v := Defaults(p)
+	params := p.Params()
+	for k, x := range in {
+		var param *Param
+		for i := range params {
+			if params[i].Name == k {
+				param = &params[i]
+			}
+		}
+		if param == nil {
+			return nil, sdf.ErrMsg(fmt.Sprintf("%s has no parameter \"%s\"", p.Name(), k))
+		}
+		// JSON numbers are float64
+		if f, ok := x.(float64); ok {
+			if _, isInt := param.Default.(int); isInt {
+				if f != float64(int(f)) {
+					return nil, sdf.ErrMsg(fmt.Sprintf("%s: %g is not an integer", k, f))
+				}
+				x = int(f)
+			}
+		}
+		if i, ok := x.(int); ok {
+			if _, isFloat := param.Default.(float64); isFloat {
+				x = float64(i)
+			}
+		}
+		if reflect.TypeOf(x) != reflect.TypeOf(param.Default) {
+			return nil, sdf.ErrMsg(fmt.Sprintf("%s: expected a %s value", k, param.Type()))
+		}
+		if err := param.check(x); err != nil {
+			return nil, err
+		}
+		v[k] = x
+	}
+	return v, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// StructSchema returns the JSON Schema for a parameter struct (or a pointer to one).
+// The field values of v are the defaults.
+func StructSchema(title string, v interface{}) (*JSONSchema, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, sdf.ErrMsg(fmt.Sprintf("%s is not a struct", rv.Type()))
+	}
+	s, err := valueSchema(rv, "")
+	if err != nil {
+		return nil, err
+	}
+	s.Schema = schemaVersion
+	s.Title = title
+	return s, nil
+}
+
+// valueSchema returns the schema for a value with a field tag.
+func valueSchema(v reflect.Value, tag reflect.StructTag) (*JSONSchema, error) {
+	s := &JSONSchema{Description: tag.Get("doc")}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		s.Type = "number"
+		s.Default = v.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s.Type = "integer"
+		s.Default = v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.Type = "integer"
+		s.Default = v.Uint()
+		zero := 0.0
+		s.Minimum = &zero
+	case reflect.Bool:
+		s.Type = "boolean"
+		s.Default = v.Bool()
+	case reflect.String:
+		s.Type = "string"
+		s.Default = v.String()
+	case reflect.Array, reflect.Slice:
+		s.Type = "array"
+		items, err := valueSchema(reflect.Zero(v.Type().Elem()), "")
+		if err != nil {
+			return nil, err
+		}
+		items.Default = nil
+		s.Items = items
+		if v.Kind() == reflect.Array {
+			n := v.Len()
+			s.MinItems, s.MaxItems = &n, &n
+		}
+		if v.Kind() == reflect.Array || !v.IsNil() {
+			s.Default = v.Interface()
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			return valueSchema(reflect.Zero(v.Type().Elem()), tag)
+		}
+		return valueSchema(v.Elem(), tag)
+	case reflect.Struct:
+		obj := object("", s.Description)
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, ok := jsonName(f)
+			if !ok {
+				continue
+			}
+			p, err := valueSchema(v.Field(i), f.Tag)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+			obj.add(name, p)
+		}
+		s = obj
+	default:
+		return nil, sdf.ErrMsg(fmt.Sprintf("unsupported type %s", v.Type()))
+	}
+	if err := s.parseTag(tag.Get("sdfx")); err != nil {
+		return nil, err
+	}
+	if s.Type == "object" && s.Unit != "" {
+		// the unit of a vector applies to its components
+		for _, p := range s.Properties {
+			if p.Unit == "" && p.Type == "number" {
+				p.Unit = s.Unit
+			}
+		}
+	}
+	return s, nil
+}
+
+// jsonName returns the encoding/json name of a struct field, and false if it isn't encoded.
+func jsonName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		// unexported
+		return "", false
+	}
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return name, true
+}
+
+// parseTag sets the schema range, unit and enum from an sdfx field tag.
+func (s *JSONSchema) parseTag(tag string) error {
+	if tag == "" {
+		return nil
+	}
+	for _, kv := range strings.Split(tag, ",") {
+		k, v, _ := strings.Cut(kv, "=")
+		switch k {
+		case "min", "max":
+			x, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return sdf.ErrMsg(fmt.Sprintf("bad %s \"%s\"", k, v))
+			}
+			if k == "min" {
+				s.Minimum = &x
+			} else {
+				s.Maximum = &x
+			}
+		case "unit":
+			s.Unit = v
+		case "enum":
+			for _, x := range strings.Split(v, "|") {
+				s.Enum = append(s.Enum, x)
+			}
+		default:
+			return sdf.ErrMsg(fmt.Sprintf("unknown sdfx tag \"%s\"", k))
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,122 @@
+//-----------------------------------------------------------------------------
+/*
+
+Parameter Schema Export Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package catalog
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/deadsy/sdfx/obj"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_PartSchema(t *testing.T) {
+	p, err := Lookup("bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := PartSchema(p)
+	js, err := s.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back map[string]interface{}
+	if err := json.Unmarshal(js, &back); err != nil {
+		t.Fatal(err)
+	}
+	length := s.Properties["length"]
+	if length.Type != "number" || length.Unit != "mm" || *length.Maximum != 1000 || length.Default != 20.0 {
+		t.Errorf("bad length schema %+v", length)
+	}
+	if style := s.Properties["style"]; len(style.Enum) != 2 {
+		t.Errorf("bad style schema %+v", style)
+	}
+	if len(s.Order) != len(p.Params()) || s.Order[0] != "thread" {
+		t.Errorf("bad property order %v", s.Order)
+	}
+
+	v, err := ParseJSON(p, []byte(`{"length": 30, "style": "knurl"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Float("length") != 30 || v.String("style") != "knurl" || v.String("thread") != "M6x1" {
+		t.Errorf("bad values %v", v)
+	}
+	for _, js := range []string{`{"length": 3000}`, `{"style": "round"}`, `{"length": "long"}`, `{"color": 1}`} {
+		if _, err := ParseJSON(p, []byte(js)); err == nil {
+			t.Errorf("expected an error for %s", js)
+		}
+	}
+	standoff, _ := Lookup("standoff")
+	if _, err := ParseJSON(standoff, []byte(`{"webs": 2.5}`)); err == nil {
+		t.Error("expected an error for a fractional integer")
+	}
+}
+
+func Test_StructSchema(t *testing.T) {
+	k := &obj.PanelBoxParms{
+		Size:     v3.Vec{50, 40, 60},
+		Wall:     2.5,
+		SideTabs: "TbtbT",
+	}
+	s, err := StructSchema("panel box", k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wall := s.Properties["Wall"]
+	if wall.Type != "number" || wall.Default != 2.5 || *wall.Minimum != 0 || wall.Unit != "mm" {
+		t.Errorf("bad Wall schema %+v", wall)
+	}
+	size := s.Properties["Size"]
+	if size.Type != "object" || size.Properties["Y"].Default != 40.0 || size.Properties["Y"].Unit != "mm" {
+		t.Errorf("bad Size schema %+v", size)
+	}
+	if tabs := s.Properties["SideTabs"]; tabs.Type != "string" || tabs.Default != "TbtbT" {
+		t.Errorf("bad SideTabs schema %+v", tabs)
+	}
+	if _, err := s.JSON(); err != nil {
+		t.Fatal(err)
+	}
+	// the values round trip into the struct
+	var back obj.PanelBoxParms
+	if err := json.Unmarshal([]byte(`{"Size": {"X": 1, "Y": 2, "Z": 3}, "Wall": 4}`), &back); err != nil || back.Size.Y != 2 {
+		t.Errorf("bad round trip %v %v", back, err)
+	}
+
+	if _, err := StructSchema("x", 1.0); err == nil {
+		t.Error("expected an error for a non-struct")
+	}
+	type bad struct {
+		A float64 `sdfx:"minimum=1"`
+	}
+	if _, err := StructSchema("x", bad{}); err == nil {
+		t.Error("expected an error for a bad tag")
+	}
+	type arrays struct {
+		Config [6]bool
+		Points []float64 `sdfx:"unit=mm"`
+		skip   int
+		Hidden int `json:"-"`
+		Named  int `json:"named,omitempty" sdfx:"enum=1|2"`
+	}
+	s, err = StructSchema("arrays", arrays{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := s.Properties["Config"]; c.Type != "array" || *c.MaxItems != 6 || c.Items.Type != "boolean" {
+		t.Errorf("bad Config schema %+v", c)
+	}
+	if len(s.Properties) != 3 || s.Properties["named"] == nil {
+		t.Errorf("bad properties %v", s.Order)
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,513 @@
+//-----------------------------------------------------------------------------
+/*
+
+sdfx: mesh file tools.
+
+	sdfx convert part.stl part.3mf
+	sdfx convert -unit in part.step part_in.step
+	sdfx remesh -cells 300 scan.obj scan.stl
+	sdfx inspect -density 1.24 -check part.stl
+	sdfx slice -z 5 part.3mf section.svg
+	sdfx slice -layer 0.2 part.stl layers.svg
+	sdfx run -D width=60 -o plate.stl,plate.3mf plate.lua
+	sdfx advise -cells 200 plate.lua
+	sdfx project -o build -part panel axoloti.json
+
+The mesh formats are STL, 3MF, OBJ and STEP (faceted, planar faces only).
+The lengths are in millimeters, the -unit flag sets the unit of the output
+file.
+
+convert: convert a mesh between file formats.
+
+remesh: re-mesh a closed mesh as an SDF3 at a resolution (cells on the
+longest axis). This gives an evenly sampled, watertight mesh. -open removes
+spikes and ridges thinner than twice a radius, -close fills cracks and holes
+narrower than twice a radius, e.g. to clean up a scan.
+
+inspect: print the bounding box and mass properties of meshes. The mass is
+printed for a density in g/cm^3. -check validates the mesh (holes,
+non-manifold edges, flipped faces and self-intersections).
+
+slice: write a cross section at a height as an SVG file, or with -layer
+a cross section per layer (layers_0001.svg, ...).
+
+run: run a model script (see the script package) and render the SDF3 it
+returns. -D sets the script parameters, and with -watch the model is
+rendered again each time the script changes. -small warn reports the
+features smaller than a mesh cell (thin walls, narrow holes and gaps), which
+may break up or vanish, and -small suppress also removes them.
+
+advise: estimate the smallest feature (wall, hole or gap) of a model script
+or a mesh and print the mesh cells needed to capture it. With -cells it
+warns if that number of cells is too few.
+
+project: render the parts of a project file (see the project package) and
+update its output manifest. The part models must be stored as node trees,
+parts built by registered builders need the program that registers them.
+
+*/
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/deadsy/sdfx/analysis"
+	"github.com/deadsy/sdfx/project"
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/script"
+	"github.com/deadsy/sdfx/sdf"
+	"github.com/deadsy/sdfx/units"
+)
+
+//-----------------------------------------------------------------------------
+
+// saveMesh writes a mesh to a file with the format given by the file extension.
+func saveMesh(path string, mesh []*sdf.Triangle3, u units.Unit) error {
+	opts := render.ExportOptions{Unit: u}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".stl":
+		return render.SaveSTLWithOptions(path, mesh, opts)
+	case ".3mf":
+		return render.Save3MFWithOptions(path, []render.Part3MF{{Name: name, Color: color.RGBA{128, 128, 128, 255}, Mesh: mesh}}, opts)
+	case ".obj":
+		if u != units.Millimeter {
+			return sdf.ErrMsg("obj files have no unit")
+		}
+		return render.SaveOBJ(path, mesh)
+	case ".step", ".stp":
+		return render.SaveSTEPWithOptions(path, mesh, render.STEPOptions{ProductName: name, Unit: u})
+	}
+	return sdf.ErrMsg(fmt.Sprintf("unknown output file type \"%s\"", path))
+}
+
+// loadMesh loads a mesh file, and checks it has triangles.
+func loadMesh(path string) ([]*sdf.Triangle3, error) {
+	mesh, err := render.LoadMesh(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(mesh) == 0 {
+		return nil, sdf.ErrMsg(fmt.Sprintf("%s has no triangles", path))
+	}
+	return mesh, nil
+}
+
+// newFlags returns the flag set of a command.
+func newFlags(name, args string, w io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() {
+		fmt.Fprintf(w, "usage: sdfx %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the flags of a command and checks the number of arguments.
+func parse(fs *flag.FlagSet, args []string, min, max int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < min || (max >= 0 && fs.NArg() > max) {
+		fs.Usage()
+		return sdf.ErrMsg("wrong number of arguments")
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func convert(args []string, w io.Writer) error {
+	fs := newFlags("convert", "input output", w)
+	unit := fs.String("unit", "mm", "output unit (mm, um, cm, m, in or ft)")
+	if err := parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+	u, err := units.ParseUnit(*unit)
+	if err != nil {
+		return err
+	}
+	mesh, err := loadMesh(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := saveMesh(fs.Arg(1), mesh, u); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "wrote %s (%d triangles)\n", fs.Arg(1), len(mesh))
+	return nil
+}
+
+// morph voxelizes a mesh and opens and/or closes it.
+func morph(mesh []*sdf.Triangle3, cells int, open, close float64) (sdf.SDF3, error) {
+	bb := sdf.Box3{}
+	for i, t := range mesh {
+		tb := t.BoundingBox()
+		if i == 0 {
+			bb = tb
+		} else {
+			bb = bb.Extend(tb)
+		}
+	}
+	cell := bb.Size().MaxComponent() / float64(cells)
+	// each operation narrows the band by its radius
+	g, err := sdf.VoxelizeMesh(mesh, cell, open+close+3*cell)
+	if err != nil {
+		return nil, err
+	}
+	if open > 0 {
+		if g, err = g.Open(open); err != nil {
+			return nil, err
+		}
+	}
+	if close > 0 {
+		if g, err = g.Close(close); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func remesh(args []string, w io.Writer) error {
+	fs := newFlags("remesh", "input output", w)
+	cells := fs.Int("cells", 200, "mesh cells on the longest axis")
+	unit := fs.String("unit", "mm", "output unit (mm, um, cm, m, in or ft)")
+	open := fs.Float64("open", 0, "remove features thinner than twice this radius")
+	close := fs.Float64("close", 0, "fill gaps narrower than twice this radius")
+	if err := parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+	u, err := units.ParseUnit(*unit)
+	if err != nil {
+		return err
+	}
+	mesh, err := loadMesh(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var s sdf.SDF3
+	if *open > 0 || *close > 0 {
+		s, err = morph(mesh, *cells, *open, *close)
+	} else {
+		s, err = sdf.Mesh3D(mesh)
+	}
+	if err != nil {
+		return err
+	}
+	r := render.NewMarchingCubesOctree(*cells)
+	fmt.Fprintf(w, "rendering %s (%s)\n", fs.Arg(0), r.Info(s))
+	out := render.ToTriangles(s, r)
+	if err := saveMesh(fs.Arg(1), out, u); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "wrote %s (%d triangles)\n", fs.Arg(1), len(out))
+	return nil
+}
+
+func inspect(args []string, w io.Writer) error {
+	fs := newFlags("inspect", "files...", w)
+	density := fs.Float64("density", 0, "density (g/cm^3) for the mass")
+	check := fs.Bool("check", false, "validate the mesh")
+	if err := parse(fs, args, 1, -1); err != nil {
+		return err
+	}
+	for i, path := range fs.Args() {
+		mesh, err := loadMesh(path)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		bb := analysis.MeshBoundingBox(mesh)
+		mp := analysis.MeshMassProperties(mesh)
+		fmt.Fprintf(w, "file       %s\n", path)
+		fmt.Fprintf(w, "triangles  %d\n", len(mesh))
+		fmt.Fprintf(w, "bbox min   %.4f %.4f %.4f\n", bb.Min.X, bb.Min.Y, bb.Min.Z)
+		fmt.Fprintf(w, "bbox max   %.4f %.4f %.4f\n", bb.Max.X, bb.Max.Y, bb.Max.Z)
+		size := bb.Size()
+		fmt.Fprintf(w, "size       %.4f %.4f %.4f\n", size.X, size.Y, size.Z)
+		fmt.Fprintf(w, "area       %.4f mm^2\n", mp.Area)
+		fmt.Fprintf(w, "volume     %.4f mm^3\n", mp.Volume)
+		fmt.Fprintf(w, "centroid   %.4f %.4f %.4f\n", mp.Centroid.X, mp.Centroid.Y, mp.Centroid.Z)
+		// inertia for unit density (mm^5), or kg mm^2 for a density
+		k, unit := 1.0, "mm^5"
+		if *density > 0 {
+			// g/cm^3 = 1e-6 kg/mm^3
+			k, unit = *density*1e-6, "kg mm^2"
+			fmt.Fprintf(w, "mass       %.4f g\n", mp.Volume**density*1e-3)
+		}
+		for j, row := range mp.Inertia {
+			label := ""
+			if j == 0 {
+				label = "inertia"
+			}
+			fmt.Fprintf(w, "%-10s %12.4g %12.4g %12.4g", label, row[0]*k, row[1]*k, row[2]*k)
+			if j == 0 {
+				fmt.Fprintf(w, " %s (about the centroid)", unit)
+			}
+			fmt.Fprintln(w)
+		}
+		if *check {
+			r := render.Validate(mesh)
+			fmt.Fprintf(w, "vertices   %d\n", r.Vertices)
+			fmt.Fprintf(w, "check      degenerate %d, repeated %d, boundary edges %d, holes %d, non-manifold edges %d, flipped %d, self-intersections %d\n",
+				r.Degenerate, r.Repeated, len(r.BoundaryEdges), r.Holes, len(r.NonManifoldEdges), len(r.Flipped), len(r.SelfIntersections))
+		}
+	}
+	return nil
+}
+
+func slice(args []string, w io.Writer) error {
+	fs := newFlags("slice", "input output.svg", w)
+	z := fs.Float64("z", 0, "height of the cross section (default the middle of the mesh)")
+	layer := fs.Float64("layer", 0, "layer height for a cross section per layer")
+	style := fs.String("style", "fill:none;stroke:black;stroke-width:0.1", "svg line style")
+	if err := parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+	zSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "z" {
+			zSet = true
+		}
+	})
+	mesh, err := loadMesh(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	bb := analysis.MeshBoundingBox(mesh)
+	out := fs.Arg(1)
+	if *layer <= 0 {
+		if !zSet {
+			*z = bb.Center().Z
+		}
+		l := render.SliceMesh(mesh, *z)
+		if err := render.SaveSVG(out, *style, l.Lines()); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "wrote %s (z %g, %d contours)\n", out, *z, len(l.Contours))
+		return nil
+	}
+	// slice through the middle of each layer
+	base := strings.TrimSuffix(out, filepath.Ext(out))
+	n := 0
+	for h := bb.Min.Z + 0.5**layer; h < bb.Max.Z; h += *layer {
+		n++
+		l := render.SliceMesh(mesh, h)
+		if err := render.SaveSVG(fmt.Sprintf("%s_%04d.svg", base, n), *style, l.Lines()); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(w, "wrote %s_0001.svg ... %s_%04d.svg\n", base, base, n)
+	return nil
+}
+
+// defines are the name=value flags of a script.
+type defines map[string]string
+
+func (d defines) String() string {
+	return ""
+}
+
+func (d defines) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return sdf.ErrMsg(fmt.Sprintf("expected name=value, got \"%s\"", s))
+	}
+	d[k] = v
+	return nil
+}
+
+// watchPeriod is the polling period for script changes.
+const watchPeriod = 500 * time.Millisecond
+
+func run(args []string, w io.Writer) error {
+	fs := newFlags("run", "script.lua", w)
+	outputs := fs.String("o", "", "output files, comma separated (default <script>.stl)")
+	cells := fs.Int("cells", 200, "mesh cells on the longest axis")
+	unit := fs.String("unit", "mm", "output unit (mm, um, cm, m, in or ft)")
+	watch := fs.Bool("watch", false, "render again when the script changes")
+	small := fs.String("small", "ignore", "features smaller than a cell: ignore, warn or suppress")
+	params := make(defines)
+	fs.Var(params, "D", "set a script parameter (name=value), repeatable")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	u, err := units.ParseUnit(*unit)
+	if err != nil {
+		return err
+	}
+	action, err := analysis.ParseSmallFeatureAction(*small)
+	if err != nil {
+		return err
+	}
+	path := fs.Arg(0)
+	var files []string
+	for _, o := range strings.Split(*outputs, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			files = append(files, o)
+		}
+	}
+	if len(files) == 0 {
+		files = []string{strings.TrimSuffix(path, filepath.Ext(path)) + ".stl"}
+	}
+	generate := func() error {
+		s, err := script.Model3(path, script.Options{Output: w, Params: params})
+		if err != nil {
+			return err
+		}
+		cell := s.BoundingBox().Size().MaxComponent() / float64(*cells)
+		if s, err = analysis.CheckSmallFeatures(s, cell, action, w); err != nil {
+			return err
+		}
+		r := render.NewMarchingCubesOctree(*cells)
+		fmt.Fprintf(w, "rendering %s (%s)\n", path, r.Info(s))
+		mesh := render.ToTriangles(s, r)
+		for _, f := range files {
+			if err := saveMesh(f, mesh, u); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "wrote %s (%d triangles)\n", f, len(mesh))
+		}
+		return nil
+	}
+	if !*watch {
+		return generate()
+	}
+	var last time.Time
+	for {
+		fi, err := os.Stat(path)
+		if err == nil && !fi.ModTime().Equal(last) {
+			last = fi.ModTime()
+			if err := generate(); err != nil {
+				// keep watching, the script may be fixed
+				fmt.Fprintf(w, "error: %s\n", err)
+			}
+			fmt.Fprintf(w, "watching %s\n", path)
+		}
+		time.Sleep(watchPeriod)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func advise(args []string, w io.Writer) error {
+	fs := newFlags("advise", "part.lua|mesh", w)
+	cells := fs.Int("cells", 0, "check a number of mesh cells on the longest axis")
+	min := fs.Float64("min", 0, "smallest feature probed (default 1/128 of the part)")
+	params := make(defines)
+	fs.Var(params, "D", "set a script parameter (name=value), repeatable")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	path := fs.Arg(0)
+	var s sdf.SDF3
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".lua") {
+		s, err = script.Model3(path, script.Options{Output: w, Params: params})
+	} else {
+		var mesh []*sdf.Triangle3
+		if mesh, err = loadMesh(path); err == nil {
+			s, err = sdf.Mesh3D(mesh)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	r, err := analysis.AdviseResolution(s, &analysis.ResolutionParms{MinSize: *min})
+	if err != nil {
+		return err
+	}
+	if *cells <= 0 || r.Check(*cells, w) {
+		fmt.Fprintf(w, "%s", r)
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func renderProject(args []string, w io.Writer) error {
+	fs := newFlags("project", "project.json", w)
+	dir := fs.String("o", ".", "output directory")
+	part := fs.String("part", "", "render a single part (default all parts)")
+	update := fs.Bool("u", true, "update the output manifest in the project file")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	path := fs.Arg(0)
+	p, err := project.Load(path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return err
+	}
+	if *part != "" {
+		err = p.RenderPart(*part, *dir)
+	} else {
+		err = p.RenderAll(*dir)
+	}
+	if err != nil {
+		return err
+	}
+	if *update {
+		return p.Save(path)
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+var commands = []struct {
+	name, doc string
+	run       func(args []string, w io.Writer) error
+}{
+	{"convert", "convert a mesh between file formats", convert},
+	{"remesh", "re-mesh a mesh at a resolution", remesh},
+	{"inspect", "print the bounding box and mass properties of meshes", inspect},
+	{"slice", "write cross sections as SVG files", slice},
+	{"run", "render a model script", run},
+	{"advise", "recommend a mesh resolution for the smallest feature", advise},
+	{"project", "render the parts of a project file", renderProject},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: sdfx <command> [flags] args...\n\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", c.name, c.doc)
+	}
+	fmt.Fprintf(w, "\nmesh files: .stl .3mf .obj .step\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(1)
+	}
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:], os.Stdout); err != nil {
+				if err != flag.ErrHelp {
+					fmt.Fprintf(os.Stderr, "error: %s\n", err)
+				}
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	usage(os.Stderr)
+	os.Exit(1)
+}
+
+//-----------------------------------------------------------------------------
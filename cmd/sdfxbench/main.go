@@ -0,0 +1,231 @@
+//-----------------------------------------------------------------------------
+/*
+
+sdfxbench: compare benchmark runs and check performance budgets.
+
+	go test -run XXX -bench . -count 5 ./sdf ./render > old.txt
+	(make changes)
+	go test -run XXX -bench . -count 5 ./sdf ./render > new.txt
+	sdfxbench old.txt new.txt
+	sdfxbench -budget cmd/sdfxbench/budgets.txt new.txt
+
+The input is "go test -bench" output. A benchmark run several times
+(-count) is summarized by the median ns/op. With two files the benchmarks
+are compared, and slowdowns by more than the threshold percentage are
+regressions. A budget file has lines of a regular expression matching
+"package:benchmark" names and the maximum ns/op. The exit status is 1 if
+there are regressions or budget overruns.
+
+*/
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//-----------------------------------------------------------------------------
+
+// result is the set of ns/op values of a benchmark.
+type result struct {
+	ns []float64
+}
+
+// median returns the median ns/op.
+func (r *result) median() float64 {
+	x := append([]float64(nil), r.ns...)
+	sort.Float64s(x)
+	n := len(x)
+	if n%2 == 1 {
+		return x[n/2]
+	}
+	return (x[n/2-1] + x[n/2]) / 2
+}
+
+// procSuffix is the GOMAXPROCS suffix of a benchmark name.
+var procSuffix = regexp.MustCompile(`-\d+$`)
+
+// parse reads "go test -bench" output. Benchmarks are named "package:benchmark".
+func parse(name string) (map[string]*result, []string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	results := make(map[string]*result)
+	var order []string
+	pkg := ""
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 2 && fields[0] == "pkg:" {
+			pkg = path.Base(fields[1])
+			continue
+		}
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") || fields[3] != "ns/op" {
+			continue
+		}
+		ns, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			continue
+		}
+		key := pkg + ":" + procSuffix.ReplaceAllString(fields[0], "")
+		r, ok := results[key]
+		if !ok {
+			r = &result{}
+			results[key] = r
+			order = append(order, key)
+		}
+		r.ns = append(r.ns, ns)
+	}
+	return results, order, s.Err()
+}
+
+// fmtNs formats a time in ns/op.
+func fmtNs(ns float64) string {
+	switch {
+	case ns >= 1e9:
+		return fmt.Sprintf("%.2fs", ns/1e9)
+	case ns >= 1e6:
+		return fmt.Sprintf("%.2fms", ns/1e6)
+	case ns >= 1e3:
+		return fmt.Sprintf("%.2fµs", ns/1e3)
+	}
+	return fmt.Sprintf("%.2fns", ns)
+}
+
+//-----------------------------------------------------------------------------
+
+// budget is the maximum ns/op for the benchmarks matching a pattern.
+type budget struct {
+	re *regexp.Regexp
+	ns float64
+}
+
+// readBudgets reads a budget file.
+func readBudgets(name string) ([]budget, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var budgets []budget
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"pattern ns/op\"", name, n)
+		}
+		re, err := regexp.Compile(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", name, n, err)
+		}
+		ns, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", name, n, err)
+		}
+		budgets = append(budgets, budget{re, ns})
+	}
+	return budgets, s.Err()
+}
+
+//-----------------------------------------------------------------------------
+
+func run() (bool, error) {
+	threshold := flag.Float64("threshold", 10, "regression threshold (percent slower)")
+	budgetFile := flag.String("budget", "", "budget file to check the (new) results against")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: sdfxbench [options] [old.txt] new.txt\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
+	if len(args) < 1 || len(args) > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cur, order, err := parse(args[len(args)-1])
+	if err != nil {
+		return false, err
+	}
+	if len(cur) == 0 {
+		return false, fmt.Errorf("%s: no benchmark results", args[len(args)-1])
+	}
+	ok := true
+
+	if len(args) == 2 {
+		old, _, err := parse(args[0])
+		if err != nil {
+			return false, err
+		}
+		w := 0
+		for _, k := range order {
+			if len(k) > w {
+				w = len(k)
+			}
+		}
+		fmt.Printf("%-*s %12s %12s %8s\n", w, "benchmark", "old", "new", "delta")
+		for _, k := range order {
+			n := cur[k].median()
+			o, found := old[k]
+			if !found {
+				fmt.Printf("%-*s %12s %12s %8s\n", w, k, "-", fmtNs(n), "new")
+				continue
+			}
+			delta := 100 * (n - o.median()) / o.median()
+			mark := ""
+			if delta > *threshold {
+				mark = " REGRESSION"
+				ok = false
+			}
+			fmt.Printf("%-*s %12s %12s %+7.1f%%%s\n", w, k, fmtNs(o.median()), fmtNs(n), delta, mark)
+		}
+	} else {
+		for _, k := range order {
+			fmt.Printf("%s %s (%d runs)\n", k, fmtNs(cur[k].median()), len(cur[k].ns))
+		}
+	}
+
+	if *budgetFile != "" {
+		budgets, err := readBudgets(*budgetFile)
+		if err != nil {
+			return false, err
+		}
+		for _, k := range order {
+			for _, b := range budgets {
+				if b.re.MatchString(k) && cur[k].median() > b.ns {
+					fmt.Printf("over budget: %s %s > %s\n", k, fmtNs(cur[k].median()), fmtNs(b.ns))
+					ok = false
+				}
+			}
+		}
+	}
+	return ok, nil
+}
+
+func main() {
+	ok, err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,27 @@
+//-----------------------------------------------------------------------------
+/*
+
+sdfxparts: list, describe and build the parts of the sdfx catalog.
+
+	sdfxparts list
+	sdfxparts info bolt
+	sdfxparts build bolt -thread M4x0.7 -length 16 -o bolt.stl
+
+Programs that add their own catalog packages can copy this and import them.
+
+*/
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"github.com/deadsy/sdfx/catalog"
+)
+
+//-----------------------------------------------------------------------------
+
+func main() {
+	catalog.Main()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,70 @@
+//-----------------------------------------------------------------------------
+/*
+
+sdfxview: live preview of STL files in a browser.
+
+The STL file is reloaded when it changes, so a program that writes the
+file (e.g. with render.ToSTL) gives an edit/preview loop:
+
+	sdfxview part.stl &
+	go run . # writes part.stl, the browser shows the new mesh
+
+*/
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/deadsy/sdfx/render"
+)
+
+//-----------------------------------------------------------------------------
+
+// watch reloads the STL file when it changes.
+func watch(v *render.Viewer, path string, poll time.Duration) {
+	var last time.Time
+	for {
+		info, err := os.Stat(path)
+		if err == nil && !info.ModTime().Equal(last) {
+			// wait for the writer to finish
+			time.Sleep(poll)
+			mesh, err := render.LoadSTL(path)
+			if err != nil {
+				log.Printf("%s", err)
+			} else if err := v.UpdateMesh(mesh); err != nil {
+				log.Printf("%s", err)
+			} else {
+				log.Printf("loaded %s (%d triangles)", path, len(mesh))
+				last = info.ModTime()
+			}
+		}
+		time.Sleep(poll)
+	}
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "http service address")
+	poll := flag.Duration("poll", 500*time.Millisecond, "file polling interval")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] file.stl\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	v := render.NewViewer(nil)
+	go watch(v, flag.Arg(0), *poll)
+	log.Printf("preview at http://%s/", *addr)
+	log.Fatal(http.ListenAndServe(*addr, v))
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,25 @@
+//go:build js && wasm
+
+//-----------------------------------------------------------------------------
+/*
+
+sdfxwasm: the sdfx browser API as a WebAssembly module (see the web package).
+
+	GOOS=js GOARCH=wasm go build -o sdfx.wasm ./cmd/sdfxwasm
+
+*/
+//-----------------------------------------------------------------------------
+
+package main
+
+import "github.com/deadsy/sdfx/web"
+
+//-----------------------------------------------------------------------------
+
+func main() {
+	web.Register()
+	// keep the module alive for the JavaScript calls
+	select {}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,192 @@
+//-----------------------------------------------------------------------------
+/*
+
+Design Rule Checking
+
+A model is a set of named parts. Rules declare constraints on the parts
+(minimum wall thickness, minimum hole size, maximum overhang, minimum
+clearance between parts). The checker runs the rules over the model and
+returns a structured report of the violations.
+
+*/
+//-----------------------------------------------------------------------------
+
+package drc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// defaultCells is the default number of sampling cells on the longest axis.
+const defaultCells = 100
+
+// defaultMaxViolations is the default number of violations recorded per rule.
+const defaultMaxViolations = 100
+
+// Model is a set of named parts to be checked.
+type Model struct {
+	parts         map[string]sdf.SDF3
+	names         []string // part names in the order they were added
+	Cells         int      // sampling cells on the longest axis of a part
+	MaxViolations int      // maximum violations recorded per rule result
+}
+
+// NewModel returns an empty model.
+func NewModel() *Model {
+	return &Model{
+		parts:         make(map[string]sdf.SDF3),
+		Cells:         defaultCells,
+		MaxViolations: defaultMaxViolations,
+	}
+}
+
+// AddPart adds a named part to the model.
+func (m *Model) AddPart(name string, s sdf.SDF3) error {
+	if s == nil {
+		return sdf.ErrMsg("s == nil")
+	}
+	if _, ok := m.parts[name]; ok {
+		return sdf.ErrMsg(fmt.Sprintf("part \"%s\" already exists", name))
+	}
+	m.parts[name] = s
+	m.names = append(m.names, name)
+	return nil
+}
+
+// Part returns a named part of the model.
+func (m *Model) Part(name string) (sdf.SDF3, error) {
+	s, ok := m.parts[name]
+	if !ok {
+		return nil, sdf.ErrMsg(fmt.Sprintf("part \"%s\" not found", name))
+	}
+	return s, nil
+}
+
+// Names returns the part names of the model.
+func (m *Model) Names() []string {
+	return m.names
+}
+
+// selectParts returns the named parts, or all parts if no names are given.
+func (m *Model) selectParts(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return m.names, nil
+	}
+	for _, name := range names {
+		if _, err := m.Part(name); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// Violation is a single location where a rule is not met.
+type Violation struct {
+	Position v3.Vec  `json:"position"` // location of the violation
+	Value    float64 `json:"value"`    // measured value
+}
+
+// Result is the outcome of running a rule against a part.
+type Result struct {
+	Rule       string      `json:"rule"`            // rule description
+	Part       string      `json:"part"`            // part name
+	Limit      float64     `json:"limit"`           // rule limit
+	Passed     bool        `json:"passed"`          // true if there are no violations
+	Count      int         `json:"count"`           // total number of violations
+	Worst      float64     `json:"worst,omitempty"` // worst measured value
+	Violations []Violation `json:"violations,omitempty"`
+	Err        string      `json:"error,omitempty"` // rule could not be checked
+}
+
+// add records a violation in the result.
+func (r *Result) add(max int, p v3.Vec, value float64, worse func(a, b float64) bool) {
+	if r.Count == 0 || worse(value, r.Worst) {
+		r.Worst = value
+	}
+	r.Count++
+	r.Passed = false
+	if len(r.Violations) < max {
+		r.Violations = append(r.Violations, Violation{p, value})
+	}
+}
+
+// Rule is a design rule that can be checked against a model.
+type Rule interface {
+	String() string
+	Check(m *Model) []Result
+}
+
+//-----------------------------------------------------------------------------
+
+// Report is the set of results from checking a model.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Check runs the rules over the model and returns a report.
+func Check(m *Model, rules ...Rule) *Report {
+	r := &Report{}
+	for _, rule := range rules {
+		r.Results = append(r.Results, rule.Check(m)...)
+	}
+	return r
+}
+
+// Passed returns true if all rules passed.
+func (r *Report) Passed() bool {
+	for i := range r.Results {
+		if !r.Results[i].Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns the results that did not pass.
+func (r *Report) Failed() []Result {
+	var failed []Result
+	for _, x := range r.Results {
+		if !x.Passed {
+			failed = append(failed, x)
+		}
+	}
+	return failed
+}
+
+// String returns a human readable summary of the report.
+func (r *Report) String() string {
+	var sb strings.Builder
+	for _, x := range r.Results {
+		status := "PASS"
+		if !x.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&sb, "%s %s: %s (limit %g)", status, x.Part, x.Rule, x.Limit)
+		if x.Err != "" {
+			fmt.Fprintf(&sb, " error: %s", x.Err)
+		} else if x.Count != 0 {
+			fmt.Fprintf(&sb, " %d violations, worst %g", x.Count, x.Worst)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// WriteJSON writes the report as JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,91 @@
+//-----------------------------------------------------------------------------
+/*
+
+Design Rule Check Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package drc
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Check(t *testing.T) {
+	thick, _ := sdf.Box3D(v3.Vec{10, 10, 10}, 0)
+	thin, _ := sdf.Box3D(v3.Vec{10, 10, 0.5}, 0)
+	thin = sdf.Transform3D(thin, sdf.Translate3d(v3.Vec{0, 0, 5.5}))
+
+	m := NewModel()
+	m.Cells = 40
+	if err := m.AddPart("thick", thick); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddPart("thin", thin); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddPart("thin", thin); err == nil {
+		t.Error("expected duplicate part error")
+	}
+
+	r := Check(m, &MinWall{Thickness: 1})
+	if len(r.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(r.Results))
+	}
+	if !r.Results[0].Passed || r.Results[1].Passed {
+		t.Errorf("unexpected min wall results\n%s", r)
+	}
+
+	r = Check(m, &MinClearance{Distance: 1, A: "thick", B: "thin"})
+	if r.Passed() {
+		t.Errorf("expected clearance violation\n%s", r)
+	}
+	r = Check(m, &MinClearance{Distance: 0.2, A: "thick", B: "thin"})
+	if !r.Passed() {
+		t.Errorf("unexpected clearance violation\n%s", r)
+	}
+
+	r = Check(m, &MinHole{Diameter: 1, Parts: []string{"missing"}})
+	if r.Passed() || r.Results[0].Err == "" {
+		t.Error("expected missing part error")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_MaxOverhang(t *testing.T) {
+	// an inverted cone overhangs at 45 degrees
+	cone, _ := sdf.Cone3D(10, 2, 12, 0)
+	m := NewModel()
+	m.Cells = 50
+	if err := m.AddPart("cone", cone); err != nil {
+		t.Fatal(err)
+	}
+	r := Check(m, &MaxOverhang{Angle: sdf.DtoR(40)})
+	if r.Passed() || math.Abs(r.Results[0].Worst-sdf.DtoR(45)) > sdf.DtoR(5) {
+		t.Errorf("expected a 45 degree overhang\n%s", r)
+	}
+	if r = Check(m, &MaxOverhang{Angle: sdf.DtoR(50)}); !r.Passed() {
+		t.Errorf("unexpected overhang\n%s", r)
+	}
+	// printed upside down the cone rests on its wide end
+	if r = Check(m, &MaxOverhang{Angle: sdf.DtoR(50), BuildDir: v3.Vec{0, 0, -1}}); !r.Passed() {
+		t.Errorf("unexpected overhang upside down\n%s", r)
+	}
+	// everything is skipped above the top of the cone
+	if r = Check(m, &MaxOverhang{Angle: sdf.DtoR(40), PlateSkip: 11}); !r.Passed() {
+		t.Errorf("unexpected overhang with a plate skip\n%s", r)
+	}
+	if r = Check(m, &MaxOverhang{Angle: -1}); r.Passed() || r.Results[0].Err == "" {
+		t.Error("expected a bad angle error")
+	}
+}
+
+//-----------------------------------------------------------------------------
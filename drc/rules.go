@@ -0,0 +1,190 @@
+//-----------------------------------------------------------------------------
+/*
+
+Design Rules
+
+Rules are checked by sampling points on the surface of a part (see
+analysis.SurfaceSamples) and probing the SDF along the surface normal at
+each point. The overhang rule uses the overhang analysis.
+
+*/
+//-----------------------------------------------------------------------------
+
+package drc
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/analysis"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// probe marches from p in direction v and returns the distance at which the
+// sign of the SDF becomes inside (inside == true) or outside (inside == false).
+// Returns maxDist if there is no crossing within maxDist.
+func probe(s sdf.SDF3, p, v v3.Vec, inside bool, step, maxDist float64) float64 {
+	for t := step; t <= maxDist; t += step {
+		d := s.Evaluate(p.Add(v.MulScalar(t)))
+		if (inside && d < 0) || (!inside && d > 0) {
+			return t
+		}
+	}
+	return maxDist
+}
+
+// less and greater are used to select the worst violation value.
+func less(a, b float64) bool    { return a < b }
+func greater(a, b float64) bool { return a > b }
+
+//-----------------------------------------------------------------------------
+
+// surfaceRule runs a per surface point check for each selected part.
+func surfaceRule(m *Model, rule fmt.Stringer, names []string, limit float64,
+	check func(s sdf.SDF3, sp analysis.SurfacePoint, resolution float64) (float64, bool),
+	worse func(a, b float64) bool) []Result {
+	names, err := m.selectParts(names)
+	if err != nil {
+		return []Result{{Rule: rule.String(), Limit: limit, Err: err.Error()}}
+	}
+	results := make([]Result, 0, len(names))
+	for _, name := range names {
+		s := m.parts[name]
+		r := Result{Rule: rule.String(), Part: name, Limit: limit, Passed: true}
+		points, resolution := analysis.SurfaceSamples(s, m.Cells)
+		for _, sp := range points {
+			if value, bad := check(s, sp, resolution); bad {
+				r.add(m.MaxViolations, sp.Point, value, worse)
+			}
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+//-----------------------------------------------------------------------------
+
+// MinWall requires a minimum wall thickness.
+type MinWall struct {
+	Thickness float64  // minimum wall thickness
+	Parts     []string // parts to check (all parts if empty)
+}
+
+func (r *MinWall) String() string {
+	return "minimum wall thickness"
+}
+
+// Check checks the minimum wall thickness rule.
+func (r *MinWall) Check(m *Model) []Result {
+	return surfaceRule(m, r, r.Parts, r.Thickness,
+		func(s sdf.SDF3, sp analysis.SurfacePoint, resolution float64) (float64, bool) {
+			// march inward until we leave the material
+			step := 0.25 * math.Min(resolution, r.Thickness)
+			t := probe(s, sp.Point, sp.Normal.Neg(), false, step, r.Thickness)
+			return t, t < r.Thickness
+		}, less)
+}
+
+//-----------------------------------------------------------------------------
+
+// MinHole requires a minimum size for holes and gaps within a part.
+type MinHole struct {
+	Diameter float64  // minimum hole diameter or gap width
+	Parts    []string // parts to check (all parts if empty)
+}
+
+func (r *MinHole) String() string {
+	return "minimum hole size"
+}
+
+// Check checks the minimum hole rule.
+func (r *MinHole) Check(m *Model) []Result {
+	return surfaceRule(m, r, r.Parts, r.Diameter,
+		func(s sdf.SDF3, sp analysis.SurfacePoint, resolution float64) (float64, bool) {
+			// march outward until we re-enter the material
+			step := 0.25 * math.Min(resolution, r.Diameter)
+			t := probe(s, sp.Point, sp.Normal, true, step, r.Diameter)
+			return t, t < r.Diameter
+		}, less)
+}
+
+//-----------------------------------------------------------------------------
+
+// MaxOverhang limits the overhang angle of downward facing surfaces.
+// The overhang angle is measured from the vertical, so a downward facing
+// horizontal surface has an overhang of 90 degrees. Surfaces resting on
+// the build plate (the minimum of the bounding box along the build direction)
+// are excluded.
+type MaxOverhang struct {
+	Angle     float64  // maximum overhang angle (radians)
+	BuildDir  v3.Vec   // build direction (defaults to +z)
+	Parts     []string // parts to check (all parts if empty)
+	PlateSkip float64  // height above the build plate to ignore (defaults to the sampling resolution)
+}
+
+func (r *MaxOverhang) String() string {
+	return "maximum overhang angle"
+}
+
+// Check checks the maximum overhang rule.
+func (r *MaxOverhang) Check(m *Model) []Result {
+	up := r.BuildDir
+	if up.Length() == 0 {
+		up = v3.Vec{0, 0, 1}
+	}
+	names, err := m.selectParts(r.Parts)
+	if err != nil {
+		return []Result{{Rule: r.String(), Limit: r.Angle, Err: err.Error()}}
+	}
+	results := make([]Result, 0, len(names))
+	for _, name := range names {
+		res := Result{Rule: r.String(), Part: name, Limit: r.Angle, Passed: true}
+		o, err := analysis.OverhangsWithParms(m.parts[name], &analysis.OverhangParms{
+			BuildDir:  up,
+			MaxAngle:  r.Angle,
+			Cells:     m.Cells,
+			PlateSkip: r.PlateSkip,
+		})
+		if err != nil {
+			res.Passed = false
+			res.Err = err.Error()
+		} else {
+			for _, p := range o.Points {
+				res.add(m.MaxViolations, p.Point, p.Angle, greater)
+			}
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+//-----------------------------------------------------------------------------
+
+// MinClearance requires a minimum clearance between two named parts.
+// Interference between the parts is reported as a negative clearance.
+type MinClearance struct {
+	Distance float64 // minimum clearance
+	A, B     string  // part names
+}
+
+func (r *MinClearance) String() string {
+	return fmt.Sprintf("minimum clearance to \"%s\"", r.B)
+}
+
+// Check checks the minimum clearance rule.
+func (r *MinClearance) Check(m *Model) []Result {
+	b, err := m.Part(r.B)
+	if err != nil {
+		return []Result{{Rule: r.String(), Part: r.A, Limit: r.Distance, Err: err.Error()}}
+	}
+	return surfaceRule(m, r, []string{r.A}, r.Distance,
+		func(s sdf.SDF3, sp analysis.SurfacePoint, resolution float64) (float64, bool) {
+			d := b.Evaluate(sp.Point)
+			return d, d < r.Distance
+		}, less)
+}
+
+//-----------------------------------------------------------------------------
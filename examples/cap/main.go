@@ -5,34 +5,39 @@ Tube Cap
 
 This is a simple round cap that fits onto the outside of a tube.
 
+The dimensions are parameters, e.g.
+
+	$ ./cap -params
+	$ ./cap -inner_diameter 50 -o cap.stl,cap.3mf
+
 */
 //-----------------------------------------------------------------------------
 
 package main
 
 import (
-	"log"
-
-	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/app"
+	"github.com/deadsy/sdfx/catalog"
 	"github.com/deadsy/sdfx/sdf"
 	v3 "github.com/deadsy/sdfx/vec/v3"
 )
 
 //-----------------------------------------------------------------------------
 
-const wallThickness = 2.0
-const innerDiameter = 75.5
-const innerHeight = 15.0
-
-//-----------------------------------------------------------------------------
-
-// material shrinkage
-const shrink = 1.0 / 0.999 // PLA ~0.1%
-//const shrink = 1.0/0.995; // ABS ~0.5%
+var params = []catalog.Param{
+	{Name: "wall_thickness", Doc: "wall thickness", Default: 2.0, Unit: "mm", Min: 0.5, Max: 10},
+	{Name: "inner_diameter", Doc: "tube outer diameter", Default: 75.5, Unit: "mm", Min: 5, Max: 500},
+	{Name: "inner_height", Doc: "depth of the cap", Default: 15.0, Unit: "mm", Min: 2, Max: 200},
+	// material shrinkage: PLA ~0.1%, ABS ~0.5%
+	{Name: "shrink", Doc: "material shrinkage", Default: 0.001, Min: 0, Max: 0.05},
+}
 
 //-----------------------------------------------------------------------------
 
-func tubeCap() (sdf.SDF3, error) {
+func tubeCap(v catalog.Values) (sdf.SDF3, error) {
+	wallThickness := v.Float("wall_thickness")
+	innerDiameter := v.Float("inner_diameter")
+	innerHeight := v.Float("inner_height")
 
 	h := innerHeight + wallThickness
 	r := (innerDiameter * 0.5) + wallThickness
@@ -44,22 +49,20 @@ func tubeCap() (sdf.SDF3, error) {
 	h = innerHeight
 	r = innerDiameter * 0.5
 	inner, err := sdf.Cylinder3D(h, r, 1.0)
-	inner = sdf.Transform3D(inner, sdf.Translate3d(v3.Vec{0, 0, wallThickness * 0.5}))
 	if err != nil {
 		return nil, err
 	}
+	inner = sdf.Transform3D(inner, sdf.Translate3d(v3.Vec{0, 0, wallThickness * 0.5}))
 
-	return sdf.Difference3D(outer, inner), nil
+	c := sdf.Difference3D(outer, inner)
+	return sdf.ScaleUniform3D(c, 1/(1-v.Float("shrink"))), nil
 }
 
 //-----------------------------------------------------------------------------
 
 func main() {
-	c, err := tubeCap()
-	if err != nil {
-		log.Fatalf("error: %s", err)
-	}
-	render.ToSTL(sdf.ScaleUniform3D(c, shrink), "cap.stl", render.NewMarchingCubesOctree(120))
+	p := catalog.NewPart("cap", "a round cap for the outside of a tube", params, tubeCap)
+	app.MainWithOptions(p, app.Options{Cells: 120})
 }
 
 //-----------------------------------------------------------------------------
@@ -0,0 +1,78 @@
+//-----------------------------------------------------------------------------
+/*
+
+Bed Adhesion Aids
+
+Generate a brim or raft from the silhouette of a part on the build plate,
+for printers or processes where the slicer can't generate them.
+
+The silhouette is a slice through the part just above the build plate
+(the bottom of the part bounding box).
+
+*/
+//-----------------------------------------------------------------------------
+
+package fab
+
+import (
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// AdhesionParms defines the parameters for a brim or raft.
+type AdhesionParms struct {
+	Offset      float64 // brim width, or the raft margin around the part
+	Thickness   float64 // brim or raft thickness
+	Gap         float64 // brim: gap between the part and brim, raft: gap between the raft top and the part
+	SliceHeight float64 // height of the silhouette slice above the build plate (default Thickness/2)
+}
+
+// silhouette returns the silhouette of a part on the build plate.
+func silhouette(part sdf.SDF3, k *AdhesionParms) (sdf.SDF2, float64, error) {
+	if part == nil {
+		return nil, 0, sdf.ErrMsg("part == nil")
+	}
+	if k.Offset <= 0 {
+		return nil, 0, sdf.ErrMsg("Offset <= 0")
+	}
+	if k.Thickness <= 0 {
+		return nil, 0, sdf.ErrMsg("Thickness <= 0")
+	}
+	if k.Gap < 0 {
+		return nil, 0, sdf.ErrMsg("Gap < 0")
+	}
+	h := k.SliceHeight
+	if h <= 0 {
+		h = 0.5 * k.Thickness
+	}
+	floor := part.BoundingBox().Min.Z
+	return sdf.Slice2D(part, v3.Vec{0, 0, floor + h}, v3.Vec{0, 0, 1}), floor, nil
+}
+
+// Brim returns a brim around the silhouette of a part on the build plate.
+// The brim sits on the build plate with the part.
+func Brim(part sdf.SDF3, k *AdhesionParms) (sdf.SDF3, error) {
+	s, floor, err := silhouette(part, k)
+	if err != nil {
+		return nil, err
+	}
+	ring := sdf.Difference2D(sdf.Offset2D(s, k.Gap+k.Offset), sdf.Offset2D(s, k.Gap))
+	brim := sdf.Extrude3D(ring, k.Thickness)
+	return sdf.Transform3D(brim, sdf.Translate3d(v3.Vec{0, 0, floor + 0.5*k.Thickness})), nil
+}
+
+// Raft returns a raft below the silhouette of a part.
+// The raft is below the build plate of the part. Print the part and raft
+// together, with the bottom of the raft on the build plate.
+func Raft(part sdf.SDF3, k *AdhesionParms) (sdf.SDF3, error) {
+	s, floor, err := silhouette(part, k)
+	if err != nil {
+		return nil, err
+	}
+	raft := sdf.Extrude3D(sdf.Offset2D(s, k.Offset), k.Thickness)
+	return sdf.Transform3D(raft, sdf.Translate3d(v3.Vec{0, 0, floor - k.Gap - 0.5*k.Thickness})), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,160 @@
+//-----------------------------------------------------------------------------
+/*
+
+Cost and Print Time Estimation
+
+Rough estimates of material usage, print time and cost for quoting.
+
+FDM: The material used is the shell (perimeters and top/bottom skins) plus
+the infill fraction of the interior. The print time is the extrusion time
+at the machine's volumetric rate plus a per layer overhead.
+
+SLA/SLS: The material used is the part volume. The print time is the number
+of layers times the layer time.
+
+*/
+//-----------------------------------------------------------------------------
+
+package fab
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/deadsy/sdfx/analysis"
+	"github.com/deadsy/sdfx/material"
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// Machine is the set of machine parameters used for estimation.
+type Machine struct {
+	Name             string
+	LayerHeight      float64 // layer height (mm)
+	LineWidth        float64 // extrusion line width (mm)
+	Perimeters       int     // number of perimeters
+	Infill           float64 // infill fraction (0..1)
+	Speed            float64 // print speed (mm/s)
+	LayerTime        float64 // layer overhead, or exposure/recoat time for SLA/SLS (s)
+	FilamentDiameter float64 // filament diameter (mm)
+	Rate             float64 // machine cost per hour
+}
+
+// Default machine profiles.
+var (
+	DefaultFDM = &Machine{
+		Name:             "FDM",
+		LayerHeight:      0.2,
+		LineWidth:        0.45,
+		Perimeters:       3,
+		Infill:           0.2,
+		Speed:            60,
+		LayerTime:        2,
+		FilamentDiameter: 1.75,
+		Rate:             1,
+	}
+	DefaultSLA = &Machine{
+		Name:        "SLA",
+		LayerHeight: 0.05,
+		LayerTime:   10,
+		Rate:        2,
+	}
+	DefaultSLS = &Machine{
+		Name:        "SLS",
+		LayerHeight: 0.1,
+		LayerTime:   12,
+		Rate:        10,
+	}
+)
+
+//-----------------------------------------------------------------------------
+
+// Estimate is the estimated material usage, print time and cost of a part.
+type Estimate struct {
+	Material       string        `json:"material"`
+	Machine        string        `json:"machine"`
+	Volume         float64       `json:"volume"`                    // part volume (mm^3)
+	MaterialVolume float64       `json:"material_volume"`           // material used (mm^3)
+	Mass           float64       `json:"mass"`                      // material mass (g)
+	FilamentLength float64       `json:"filament_length,omitempty"` // filament length (mm)
+	Layers         int           `json:"layers"`                    // number of layers
+	Time           time.Duration `json:"time"`                      // print time
+	MaterialCost   float64       `json:"material_cost"`
+	MachineCost    float64       `json:"machine_cost"`
+	Cost           float64       `json:"cost"` // total cost
+}
+
+// EstimateMesh estimates the material usage, print time and cost of a closed triangle mesh.
+func EstimateMesh(mesh []*sdf.Triangle3, m *material.Profile, machine *Machine) (*Estimate, error) {
+	if len(mesh) == 0 {
+		return nil, sdf.ErrMsg("no triangles")
+	}
+	if m == nil || machine == nil {
+		return nil, sdf.ErrMsg("no material or machine")
+	}
+	if machine.LayerHeight <= 0 {
+		return nil, sdf.ErrMsg("layer height <= 0")
+	}
+	volume := analysis.MeshVolume(mesh)
+	area := analysis.MeshArea(mesh)
+	height := analysis.MeshBoundingBox(mesh).Size().Z
+	e := &Estimate{
+		Material: m.Name,
+		Machine:  machine.Name,
+		Volume:   volume,
+		Layers:   int(math.Ceil(height / machine.LayerHeight)),
+	}
+	var seconds float64
+	switch m.Process {
+	case "FDM":
+		if machine.LineWidth <= 0 || machine.Speed <= 0 || machine.FilamentDiameter <= 0 {
+			return nil, sdf.ErrMsg("bad FDM machine parameters")
+		}
+		// the shell is the surface area times the wall thickness (limited to the part volume)
+		shell := math.Min(volume, area*float64(machine.Perimeters)*machine.LineWidth)
+		e.MaterialVolume = shell + sdf.Clamp(machine.Infill, 0, 1)*(volume-shell)
+		r := 0.5 * machine.FilamentDiameter
+		e.FilamentLength = e.MaterialVolume / (math.Pi * r * r)
+		rate := machine.Speed * machine.LineWidth * machine.LayerHeight // mm^3/s
+		seconds = e.MaterialVolume/rate + float64(e.Layers)*machine.LayerTime
+	case "SLA", "SLS":
+		e.MaterialVolume = volume
+		seconds = float64(e.Layers) * machine.LayerTime
+	default:
+		return nil, sdf.ErrMsg(fmt.Sprintf("can't estimate for process \"%s\"", m.Process))
+	}
+	e.Mass = m.Mass(e.MaterialVolume)
+	e.Time = time.Duration(seconds * float64(time.Second)).Round(time.Second)
+	e.MaterialCost = e.Mass * 1e-3 * m.Cost
+	e.MachineCost = e.Time.Hours() * machine.Rate
+	e.Cost = e.MaterialCost + e.MachineCost
+	return e, nil
+}
+
+// EstimateSDF3 renders an SDF3 and estimates its material usage, print time and cost.
+func EstimateSDF3(s sdf.SDF3, r render.Render3, m *material.Profile, machine *Machine) (*Estimate, error) {
+	return EstimateMesh(render.ToTriangles(s, r), m, machine)
+}
+
+func (e *Estimate) String() string {
+	s := fmt.Sprintf("%s/%s: volume %.1f mm^3, material %.1f g", e.Machine, e.Material, e.Volume, e.Mass)
+	if e.FilamentLength != 0 {
+		s += fmt.Sprintf(" (%.2f m)", e.FilamentLength*1e-3)
+	}
+	s += fmt.Sprintf(", %d layers, time %s, cost %.2f", e.Layers, e.Time, e.Cost)
+	return s
+}
+
+// WriteJSON writes the estimate as JSON.
+func (e *Estimate) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,44 @@
+//-----------------------------------------------------------------------------
+/*
+
+Estimation Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package fab
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/material"
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Estimate(t *testing.T) {
+	s, _ := sdf.Box3D(v3.Vec{20, 20, 10}, 0)
+	e, err := EstimateSDF3(s, render.NewMarchingCubesOctree(50), material.PLA, DefaultFDM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(e.Volume-4000) > 40 {
+		t.Errorf("expected volume ~4000, got %g", e.Volume)
+	}
+	if e.MaterialVolume >= e.Volume || e.Layers != 50 || e.Time == 0 || e.Cost <= 0 {
+		t.Errorf("bad estimate %s", e)
+	}
+	e, err = EstimateSDF3(s, render.NewMarchingCubesOctree(50), material.Resin, DefaultSLA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.MaterialVolume != e.Volume || e.Layers != 200 {
+		t.Errorf("bad estimate %s", e)
+	}
+}
+
+//-----------------------------------------------------------------------------
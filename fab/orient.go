@@ -0,0 +1,240 @@
+//-----------------------------------------------------------------------------
+/*
+
+Print Orientation
+
+Search the orientations of a part for the one that is best to print.
+Each orientation is scored with a weighted objective:
+
+- support volume: the overhanging surface area (projected onto the plate)
+  times its height above the plate. This ignores supports that land on the
+  part, so it is an upper bound.
+- base area: the area of the downward facing surfaces on the build plate.
+- height: the height of the part.
+
+The surface is sampled once and the samples are scored for each candidate
+direction, so searching many orientations is cheap. The candidates are
+spread over a sphere and include the normals of the largest flat surfaces.
+
+*/
+//-----------------------------------------------------------------------------
+
+package fab
+
+import (
+	"math"
+	"sort"
+
+	"github.com/deadsy/sdfx/analysis"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// defaultBaseAngle is the default maximum angle from horizontal for a surface
+// to rest on the build plate (5 degrees).
+const defaultBaseAngle = 5.0 * sdf.Pi / 180.0
+
+// flatCandidates is the number of flat surface normals added to the candidate directions.
+const flatCandidates = 20
+
+// OrientParms defines the parameters for the orientation search.
+type OrientParms struct {
+	Angle      float64 // overhang angle from vertical that needs support (radians)
+	Support    float64 // weight for the support volume
+	Base       float64 // weight for the base area
+	Height     float64 // weight for the part height
+	BaseAngle  float64 // maximum angle from horizontal for a surface on the build plate (radians, default 5 degrees)
+	Candidates int     // number of candidate down directions (default 200)
+	Cells      int     // surface sampling cells on the longest axis (default 100)
+}
+
+// DefaultOrient is the default orientation search.
+var DefaultOrient = OrientParms{
+	Angle:   sdf.DtoR(45),
+	Support: 1,
+	Base:    0.5,
+	Height:  0.25,
+}
+
+// Orientation is a scored print orientation.
+type Orientation struct {
+	Down          v3.Vec  // direction of the part that faces the build plate
+	Transform     sdf.M44 // places the part on the build plate (z = 0) centered on the z-axis
+	SupportVolume float64 // estimated support volume
+	BaseArea      float64 // area in contact with the build plate
+	Height        float64 // part height
+	Score         float64 // weighted objective (lower is better)
+}
+
+// orientRotation returns the rotation that points down along -z.
+func orientRotation(down v3.Vec) sdf.M44 {
+	if down.Dot(v3.Vec{0, 0, 1}) > 1-1e-9 {
+		// RotateToVector would reflect the part
+		return sdf.RotateX(sdf.Pi)
+	}
+	return sdf.RotateToVector(down, v3.Vec{0, 0, -1})
+}
+
+// candidateDirections returns the axis directions, n directions spread over a sphere
+// and the normals of the largest flat surfaces.
+func candidateDirections(n int, points []analysis.SurfacePoint) []v3.Vec {
+	dirs := []v3.Vec{
+		{0, 0, -1}, {0, 0, 1},
+		{1, 0, 0}, {-1, 0, 0},
+		{0, 1, 0}, {0, -1, 0},
+	}
+	// fibonacci sphere
+	golden := sdf.Pi * (3 - math.Sqrt(5))
+	for i := 0; i < n; i++ {
+		z := 1 - 2*(float64(i)+0.5)/float64(n)
+		r := math.Sqrt(1 - z*z)
+		theta := golden * float64(i)
+		dirs = append(dirs, v3.Vec{r * math.Cos(theta), r * math.Sin(theta), z})
+	}
+	// Flat surfaces only rest on the plate if they face exactly down, so group the
+	// surface normals by direction and add the mean normal of the largest groups.
+	type bucket struct {
+		key    [3]int
+		normal v3.Vec
+		area   float64
+	}
+	const quantize = 20
+	buckets := make(map[[3]int]*bucket)
+	for _, sp := range points {
+		key := [3]int{
+			int(math.Round(sp.Normal.X * quantize)),
+			int(math.Round(sp.Normal.Y * quantize)),
+			int(math.Round(sp.Normal.Z * quantize)),
+		}
+		b, ok := buckets[key]
+		if !ok {
+			b = &bucket{key: key}
+			buckets[key] = b
+		}
+		b.normal = b.normal.Add(sp.Normal.MulScalar(sp.Area))
+		b.area += sp.Area
+	}
+	bs := make([]*bucket, 0, len(buckets))
+	for _, b := range buckets {
+		bs = append(bs, b)
+	}
+	sort.Slice(bs, func(i, j int) bool {
+		if bs[i].area != bs[j].area {
+			return bs[i].area > bs[j].area
+		}
+		// deterministic order for equal areas
+		a, b := bs[i].key, bs[j].key
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		if a[1] != b[1] {
+			return a[1] < b[1]
+		}
+		return a[2] < b[2]
+	})
+	for i := 0; i < len(bs) && i < flatCandidates; i++ {
+		dirs = append(dirs, bs[i].normal.Normalize())
+	}
+	return dirs
+}
+
+// score scores an orientation of the surface samples.
+func (k *OrientParms) score(points []analysis.SurfacePoint, resolution, area, size float64, down v3.Vec) Orientation {
+	up := down.Neg()
+	lo, hi := math.MaxFloat64, -math.MaxFloat64
+	for _, sp := range points {
+		h := sp.Point.Dot(up)
+		lo = math.Min(lo, h)
+		hi = math.Max(hi, h)
+	}
+	o := Orientation{Down: down, Height: hi - lo}
+	baseAngle := k.BaseAngle
+	if baseAngle == 0 {
+		baseAngle = defaultBaseAngle
+	}
+	minBase := math.Cos(baseAngle)
+	minOverhang := math.Sin(k.Angle)
+	for _, sp := range points {
+		d := -sp.Normal.Dot(up)
+		if d <= 0 {
+			// upward facing
+			continue
+		}
+		h := sp.Point.Dot(up) - lo
+		if h < resolution {
+			if d >= minBase {
+				o.BaseArea += sp.Area
+			}
+			continue
+		}
+		if d > minOverhang {
+			o.SupportVolume += sp.Area * d * h
+		}
+	}
+	// normalize each term so the weights are comparable
+	o.Score = k.Support*o.SupportVolume/(area*size) - k.Base*o.BaseArea/area + k.Height*o.Height/size
+	// place the part on the plate
+	r := orientRotation(down)
+	bb := sdf.Box3{Min: v3.Vec{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64}, Max: v3.Vec{-math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}}
+	for _, sp := range points {
+		bb = bb.Include(r.MulPosition(sp.Point))
+	}
+	c := bb.Center()
+	o.Transform = sdf.Translate3d(v3.Vec{-c.X, -c.Y, -bb.Min.Z}).Mul(r)
+	return o
+}
+
+// Orientations returns the candidate print orientations of a part, best first.
+func Orientations(s sdf.SDF3, k *OrientParms) ([]Orientation, error) {
+	if s == nil {
+		return nil, sdf.ErrMsg("s == nil")
+	}
+	if k.Angle <= 0 || k.Angle >= sdf.Pi*0.5 {
+		return nil, sdf.ErrMsg("Angle not in (0, Pi/2)")
+	}
+	if k.Support < 0 || k.Base < 0 || k.Height < 0 {
+		return nil, sdf.ErrMsg("weight < 0")
+	}
+	if k.BaseAngle < 0 || k.BaseAngle >= sdf.Pi*0.5 {
+		return nil, sdf.ErrMsg("BaseAngle not in [0, Pi/2)")
+	}
+	candidates := k.Candidates
+	if candidates == 0 {
+		candidates = 200
+	}
+	cells := k.Cells
+	if cells == 0 {
+		cells = analysis.DefaultCells
+	}
+	points, resolution := analysis.SurfaceSamples(s, cells)
+	if len(points) == 0 {
+		return nil, sdf.ErrMsg("no surface")
+	}
+	area := 0.0
+	for _, sp := range points {
+		area += sp.Area
+	}
+	size := s.BoundingBox().Size().Length()
+	var os []Orientation
+	for _, down := range candidateDirections(candidates, points) {
+		os = append(os, k.score(points, resolution, area, size, down))
+	}
+	sort.SliceStable(os, func(i, j int) bool {
+		return os[i].Score < os[j].Score
+	})
+	return os, nil
+}
+
+// Orient returns the best print orientation of a part.
+// Use Transform3D(s, o.Transform) to orient the part for printing.
+func Orient(s sdf.SDF3, k *OrientParms) (*Orientation, error) {
+	os, err := Orientations(s, k)
+	if err != nil {
+		return nil, err
+	}
+	return &os[0], nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,379 @@
+//-----------------------------------------------------------------------------
+/*
+
+Build Plate Layout
+
+Place several parts on a build plate without overlaps, rather than
+positioning them by hand with Translate3d.
+
+The plate is a grid of cells. The footprint of each part (its outline seen
+from above) is rasterized onto the grid as either its bounding box, or the
+convex hull of the cells it covers. The footprints are placed largest first
+at the lowest (then leftmost) free position, with a gap of at least the
+spacing between them. The footprints are conservative, so parts may be a
+cell further apart than needed.
+
+The plate is centered on the origin and the parts sit on it at z = 0.
+
+*/
+//-----------------------------------------------------------------------------
+
+package fab
+
+import (
+	"math"
+	"sort"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// PlateMode is the footprint used to nest the parts.
+type PlateMode int
+
+const (
+	// PlateBox nests the bounding boxes of the parts.
+	PlateBox PlateMode = iota
+	// PlateHull nests the convex hulls of the part footprints.
+	PlateHull
+)
+
+// plateCells is the default number of cells on the longest side of the build plate.
+const plateCells = 256
+
+// PlateParms defines the build plate for a layout.
+type PlateParms struct {
+	Size    v2.Vec    // build plate size (x, y)
+	Spacing float64   // minimum gap between parts
+	Mode    PlateMode // footprint used to nest the parts
+	Rotate  bool      // allow parts to be rotated 90 degrees about z
+	Cell    float64   // grid cell size (default longest plate side / 256)
+}
+
+//-----------------------------------------------------------------------------
+
+// span is a range of cells [lo, hi) in a row, empty if lo >= hi.
+type span struct {
+	lo, hi int
+}
+
+// footprint is the rasterized footprint of a part, a span for each row.
+type footprint struct {
+	rows []span
+	area int
+}
+
+// dilate returns the footprint grown by r cells on every side. The rows of a
+// convex footprint are single spans, so the result is too.
+func (f *footprint) dilate(r int) *footprint {
+	n := len(f.rows) + 2*r
+	out := &footprint{rows: make([]span, n)}
+	for j := range out.rows {
+		s := span{math.MaxInt, math.MinInt}
+		for k := j - 2*r; k <= j; k++ {
+			if k < 0 || k >= len(f.rows) || f.rows[k].lo >= f.rows[k].hi {
+				continue
+			}
+			s.lo = min(s.lo, f.rows[k].lo)
+			s.hi = max(s.hi, f.rows[k].hi+2*r)
+		}
+		out.rows[j] = s
+	}
+	return out
+}
+
+// width returns the number of columns spanned by a footprint.
+func (f *footprint) width() int {
+	w := 0
+	for _, s := range f.rows {
+		w = max(w, s.hi)
+	}
+	return w
+}
+
+// boxFootprint returns the footprint of a bounding box.
+func boxFootprint(bb sdf.Box3, cell float64) *footprint {
+	nx := int(math.Ceil(bb.Size().X / cell))
+	ny := int(math.Ceil(bb.Size().Y / cell))
+	f := &footprint{rows: make([]span, ny), area: nx * ny}
+	for j := range f.rows {
+		f.rows[j] = span{0, nx}
+	}
+	return f
+}
+
+// hullFootprint returns the convex hull of the cells covered by a part.
+func hullFootprint(s sdf.SDF3, cell float64) *footprint {
+	bb := s.BoundingBox()
+	nx := int(math.Ceil(bb.Size().X / cell))
+	ny := int(math.Ceil(bb.Size().Y / cell))
+	// a column of cells is covered if the part is within the half diagonal
+	// (plus a margin) of the column center line
+	r := cell * math.Sqrt2 * 0.5
+	eps := 0.1 * cell
+	var corners []v2.Vec
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			p := v3.Vec{
+				X: bb.Min.X + (float64(i)+0.5)*cell,
+				Y: bb.Min.Y + (float64(j)+0.5)*cell,
+				Z: bb.Min.Z,
+			}
+			for p.Z <= bb.Max.Z {
+				d := s.Evaluate(p)
+				if d < r+eps {
+					x, y := float64(i), float64(j)
+					corners = append(corners, v2.Vec{X: x, Y: y}, v2.Vec{X: x + 1, Y: y}, v2.Vec{X: x, Y: y + 1}, v2.Vec{X: x + 1, Y: y + 1})
+					break
+				}
+				// the ball of radius d doesn't reach the cell column within this step
+				p.Z += math.Sqrt(d*d - r*r)
+			}
+		}
+	}
+	f := &footprint{rows: make([]span, ny)}
+	if len(corners) == 0 {
+		return f
+	}
+	hull := convexHull(corners)
+	ymin, ymax := math.Inf(1), math.Inf(-1)
+	for _, v := range hull {
+		ymin = math.Min(ymin, v.Y)
+		ymax = math.Max(ymax, v.Y)
+	}
+	for j := range f.rows {
+		if float64(j+1) <= ymin || float64(j) >= ymax {
+			continue
+		}
+		// the hull is convex so it crosses a row in a single range
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, y := range []float64{float64(j), float64(j) + 1} {
+			for k := range hull {
+				a, b := hull[k], hull[(k+1)%len(hull)]
+				if (a.Y <= y && b.Y >= y) || (b.Y <= y && a.Y >= y) {
+					x0, x1 := a.X, b.X
+					if a.Y != b.Y {
+						x0 = a.X + (y-a.Y)/(b.Y-a.Y)*(b.X-a.X)
+						x1 = x0
+					}
+					lo = math.Min(lo, math.Min(x0, x1))
+					hi = math.Max(hi, math.Max(x0, x1))
+				}
+			}
+		}
+		if lo < hi {
+			f.rows[j] = span{int(math.Floor(lo + 1e-9)), int(math.Ceil(hi - 1e-9))}
+			f.area += f.rows[j].hi - f.rows[j].lo
+		}
+	}
+	return f
+}
+
+// convexHull returns the convex hull of a set of points (counter clockwise).
+func convexHull(points []v2.Vec) []v2.Vec {
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].X != points[j].X {
+			return points[i].X < points[j].X
+		}
+		return points[i].Y < points[j].Y
+	})
+	cross := func(o, a, b v2.Vec) float64 {
+		return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+	}
+	hull := make([]v2.Vec, 0, 2*len(points))
+	// lower hull
+	for _, p := range points {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	// upper hull
+	n := len(hull) + 1
+	for i := len(points) - 2; i >= 0; i-- {
+		p := points[i]
+		for len(hull) >= n && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	return hull[:len(hull)-1]
+}
+
+//-----------------------------------------------------------------------------
+
+// plate is the occupied cells of the build plate.
+type plate struct {
+	nx, ny   int
+	occupied [][]bool
+	sum      [][]int // per row prefix sums of the occupied cells
+}
+
+func newPlate(nx, ny int) *plate {
+	p := &plate{nx: nx, ny: ny, occupied: make([][]bool, ny), sum: make([][]int, ny)}
+	for j := range p.sum {
+		p.occupied[j] = make([]bool, nx)
+		p.sum[j] = make([]int, nx+1)
+	}
+	return p
+}
+
+// free returns true if the cells of a footprint at (x, y) are free.
+// The footprint may extend beyond the plate, those cells are free.
+func (p *plate) free(f *footprint, x, y int) bool {
+	for j, s := range f.rows {
+		row := y + j
+		if row < 0 || row >= p.ny || s.lo >= s.hi {
+			continue
+		}
+		lo := min(max(x+s.lo, 0), p.nx)
+		hi := min(max(x+s.hi, 0), p.nx)
+		if p.sum[row][hi]-p.sum[row][lo] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// fill marks the cells of a footprint at (x, y) as occupied.
+func (p *plate) fill(f *footprint, x, y int) {
+	for j, s := range f.rows {
+		if s.lo >= s.hi {
+			continue
+		}
+		occupied, sum := p.occupied[y+j], p.sum[y+j]
+		for i := x + s.lo; i < x+s.hi; i++ {
+			occupied[i] = true
+		}
+		for i, o := range occupied {
+			sum[i+1] = sum[i]
+			if o {
+				sum[i+1]++
+			}
+		}
+	}
+}
+
+// place returns the lowest, then leftmost, position of a footprint on the
+// plate with its dilated footprint clear of the other parts.
+func (p *plate) place(f, dilated *footprint, r int) (int, int, bool) {
+	w := f.width()
+	for y := 0; y+len(f.rows) <= p.ny; y++ {
+		for x := 0; x+w <= p.nx; x++ {
+			if p.free(dilated, x-r, y-r) {
+				return x, y, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+//-----------------------------------------------------------------------------
+
+// platePart is a part (in one of its orientations) to be placed.
+type platePart struct {
+	rotation sdf.M44
+	bb       sdf.Box3
+	f        *footprint
+	dilated  *footprint
+}
+
+// Plate returns the transforms that place the parts on the build plate
+// without overlaps, in the order of the parts.
+func Plate(parts []sdf.SDF3, k *PlateParms) ([]sdf.M44, error) {
+	if len(parts) == 0 {
+		return nil, sdf.ErrMsg("no parts")
+	}
+	if k.Size.X <= 0 || k.Size.Y <= 0 {
+		return nil, sdf.ErrMsg("Size <= 0")
+	}
+	if k.Spacing < 0 {
+		return nil, sdf.ErrMsg("Spacing < 0")
+	}
+	if k.Mode != PlateBox && k.Mode != PlateHull {
+		return nil, sdf.ErrMsg("unknown Mode")
+	}
+	cell := k.Cell
+	if cell == 0 {
+		cell = math.Max(k.Size.X, k.Size.Y) / plateCells
+	}
+	if cell < 0 {
+		return nil, sdf.ErrMsg("Cell < 0")
+	}
+	r := int(math.Ceil(k.Spacing/cell - 1e-9))
+
+	// the footprints of each orientation of the parts
+	rotations := []sdf.M44{sdf.Identity3d()}
+	if k.Rotate {
+		rotations = append(rotations, sdf.RotateZ(sdf.Pi*0.5))
+	}
+	candidates := make([][]platePart, len(parts))
+	for i, s := range parts {
+		if s == nil {
+			return nil, sdf.ErrMsg("nil part")
+		}
+		for _, m := range rotations {
+			x := sdf.Transform3D(s, m)
+			pp := platePart{rotation: m, bb: x.BoundingBox()}
+			if k.Mode == PlateHull {
+				pp.f = hullFootprint(x, cell)
+			} else {
+				pp.f = boxFootprint(pp.bb, cell)
+			}
+			pp.dilated = pp.f.dilate(r)
+			candidates[i] = append(candidates[i], pp)
+		}
+	}
+
+	// largest first
+	order := make([]int, len(parts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return candidates[order[a]][0].f.area > candidates[order[b]][0].f.area
+	})
+
+	p := newPlate(int(math.Floor(k.Size.X/cell)), int(math.Floor(k.Size.Y/cell)))
+	origin := k.Size.MulScalar(-0.5)
+	transforms := make([]sdf.M44, len(parts))
+	for _, i := range order {
+		best, bx, by := -1, 0, 0
+		for c, pp := range candidates[i] {
+			x, y, ok := p.place(pp.f, pp.dilated, r)
+			if ok && (best < 0 || y < by || (y == by && x < bx)) {
+				best, bx, by = c, x, y
+			}
+		}
+		if best < 0 {
+			return nil, sdf.ErrMsg("parts don't fit on the build plate")
+		}
+		pp := candidates[i][best]
+		p.fill(pp.f, bx, by)
+		v := v3.Vec{
+			X: origin.X + float64(bx)*cell - pp.bb.Min.X,
+			Y: origin.Y + float64(by)*cell - pp.bb.Min.Y,
+			Z: -pp.bb.Min.Z,
+		}
+		transforms[i] = sdf.Translate3d(v).Mul(pp.rotation)
+	}
+	return transforms, nil
+}
+
+// PlateUnion returns the union of the parts placed on the build plate.
+func PlateUnion(parts []sdf.SDF3, k *PlateParms) (sdf.SDF3, error) {
+	transforms, err := Plate(parts, k)
+	if err != nil {
+		return nil, err
+	}
+	placed := make([]sdf.SDF3, len(parts))
+	for i, s := range parts {
+		placed[i] = sdf.Transform3D(s, transforms[i])
+	}
+	return sdf.Union3D(placed...), nil
+}
+
+//-----------------------------------------------------------------------------
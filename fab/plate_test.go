@@ -0,0 +1,107 @@
+//-----------------------------------------------------------------------------
+/*
+
+Build Plate Layout Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package fab
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Plate(t *testing.T) {
+	var parts []sdf.SDF3
+	for i := 0; i < 6; i++ {
+		b, _ := sdf.Box3D(v3.Vec{X: 10 + 5*float64(i), Y: 20, Z: 5}, 0)
+		parts = append(parts, sdf.Transform3D(b, sdf.Translate3d(v3.Vec{X: 100, Y: -30, Z: 7})))
+	}
+	k := &PlateParms{Size: v2.Vec{X: 100, Y: 100}, Spacing: 3}
+	transforms, err := Plate(parts, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var boxes []sdf.Box3
+	for i, s := range parts {
+		bb := sdf.Transform3D(s, transforms[i]).BoundingBox()
+		if math.Abs(bb.Min.Z) > 1e-9 {
+			t.Errorf("part %d is not on the plate", i)
+		}
+		if bb.Min.X < -50-1e-9 || bb.Min.Y < -50-1e-9 || bb.Max.X > 50+1e-9 || bb.Max.Y > 50+1e-9 {
+			t.Errorf("part %d is off the plate %v", i, bb)
+		}
+		for j, other := range boxes {
+			gap := math.Max(math.Max(other.Min.X-bb.Max.X, bb.Min.X-other.Max.X), math.Max(other.Min.Y-bb.Max.Y, bb.Min.Y-other.Max.Y))
+			if gap < k.Spacing-1e-9 {
+				t.Errorf("parts %d and %d are %g apart", j, i, gap)
+			}
+		}
+		boxes = append(boxes, bb)
+	}
+
+	// too many parts
+	k.Size = v2.Vec{X: 40, Y: 40}
+	if _, err := Plate(parts, k); err == nil {
+		t.Error("expected an error for a full plate")
+	}
+}
+
+func Test_PlateHull(t *testing.T) {
+	// diagonal bars have large bounding boxes and thin hulls
+	bar, _ := sdf.Box3D(v3.Vec{X: 60, Y: 4, Z: 4}, 0)
+	bar = sdf.Transform3D(bar, sdf.RotateZ(sdf.DtoR(45)))
+	parts := []sdf.SDF3{bar, bar}
+	k := &PlateParms{Size: v2.Vec{X: 60, Y: 50}, Spacing: 2}
+	if _, err := Plate(parts, k); err == nil {
+		t.Error("expected the bounding boxes not to fit")
+	}
+	k.Mode = PlateHull
+	transforms, err := Plate(parts, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := sdf.Transform3D(bar, transforms[0])
+	b := sdf.Transform3D(bar, transforms[1])
+	for x := -30.0; x <= 30; x += 0.25 {
+		for y := -25.0; y <= 25; y += 0.25 {
+			p := v3.Vec{X: x, Y: y, Z: 2}
+			if b.Evaluate(p) <= 0 && a.Evaluate(p) < k.Spacing {
+				t.Fatalf("parts are closer than %g at %v", k.Spacing, p)
+			}
+		}
+	}
+	s, err := PlateUnion(parts, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bb := s.BoundingBox()
+	if bb.Max.X > 30+1e-9 || bb.Max.Y > 25+1e-9 {
+		t.Errorf("parts are off the plate %v", bb)
+	}
+
+	// rotated parts
+	long, _ := sdf.Box3D(v3.Vec{X: 10, Y: 40, Z: 5}, 0)
+	k = &PlateParms{Size: v2.Vec{X: 50, Y: 25}, Spacing: 2}
+	if _, err := Plate([]sdf.SDF3{long}, k); err == nil {
+		t.Error("expected the part not to fit")
+	}
+	k.Rotate = true
+	transforms, err = Plate([]sdf.SDF3{long}, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := sdf.Transform3D(long, transforms[0]).BoundingBox().Size(); size.X < 39 {
+		t.Errorf("expected the part to be rotated, size %v", size)
+	}
+}
+
+//-----------------------------------------------------------------------------
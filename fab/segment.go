@@ -0,0 +1,125 @@
+//-----------------------------------------------------------------------------
+/*
+
+Segment Sets
+
+A set of tapered capsules (round cones) between pairs of points. Support
+structures are built from segments. The segments are kept with their
+bounding boxes so distant segments can be skipped during evaluation.
+
+*/
+//-----------------------------------------------------------------------------
+
+package fab
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// segment is a round cone between two points.
+type segment struct {
+	a, b   v3.Vec  // end points
+	ra, rb float64 // end radii
+	bb     sdf.Box3
+}
+
+// newSegment returns a round cone segment.
+func newSegment(a, b v3.Vec, ra, rb float64) segment {
+	r := math.Max(ra, rb)
+	bb := sdf.Box3{Min: a.Min(b).SubScalar(r), Max: a.Max(b).AddScalar(r)}
+	return segment{a, b, ra, rb, bb}
+}
+
+// evaluate returns the distance to a round cone.
+// See: https://iquilezles.org/articles/distfunctions/
+func (s *segment) evaluate(p v3.Vec) float64 {
+	ba := s.b.Sub(s.a)
+	l2 := ba.Length2()
+	rr := s.ra - s.rb
+	a2 := l2 - rr*rr
+	if a2 <= 0 {
+		// one end sphere contains the other
+		if s.ra > s.rb {
+			return p.Sub(s.a).Length() - s.ra
+		}
+		return p.Sub(s.b).Length() - s.rb
+	}
+	il2 := 1 / l2
+	pa := p.Sub(s.a)
+	y := pa.Dot(ba)
+	z := y - l2
+	x2 := pa.MulScalar(l2).Sub(ba.MulScalar(y)).Length2()
+	y2 := y * y * l2
+	z2 := z * z * l2
+	k := sdf.Sign(rr) * rr * rr * x2
+	if sdf.Sign(z)*a2*z2 > k {
+		return math.Sqrt(x2+z2)*il2 - s.rb
+	}
+	if sdf.Sign(y)*a2*y2 < k {
+		return math.Sqrt(x2+y2)*il2 - s.ra
+	}
+	return (math.Sqrt(x2*a2*il2)+y*rr)*il2 - s.ra
+}
+
+// boxDist2 returns the squared distance from a point to a box (0 within the box).
+func boxDist2(b sdf.Box3, p v3.Vec) float64 {
+	return b.Min.Sub(p).Max(p.Sub(b.Max)).Max(v3.Vec{}).Length2()
+}
+
+//-----------------------------------------------------------------------------
+
+// SegmentsSDF3 is the union of a set of round cone segments above a floor.
+type SegmentsSDF3 struct {
+	segments []segment
+	floor    float64 // segments are cut off below this height
+	bb       sdf.Box3
+}
+
+// newSegments returns the union of a set of segments cut off below a floor height.
+func newSegments(segments []segment, floor float64) *SegmentsSDF3 {
+	s := SegmentsSDF3{segments: segments, floor: floor}
+	for i := range segments {
+		if i == 0 {
+			s.bb = segments[i].bb
+		} else {
+			s.bb = s.bb.Extend(segments[i].bb)
+		}
+	}
+	s.bb.Min.Z = math.Max(s.bb.Min.Z, floor)
+	return &s
+}
+
+// Evaluate returns the minimum distance to a set of segments.
+func (s *SegmentsSDF3) Evaluate(p v3.Vec) float64 {
+	// start with the segment that has the nearest bounding box
+	near, dmin := 0, math.MaxFloat64
+	for i := range s.segments {
+		if x := boxDist2(s.segments[i].bb, p); x < dmin {
+			near, dmin = i, x
+		}
+	}
+	d := s.segments[near].evaluate(p)
+	for i := range s.segments {
+		if i == near {
+			continue
+		}
+		if x := boxDist2(s.segments[i].bb, p); x > 0 && (d <= 0 || x > d*d) {
+			// this segment can't be closer
+			continue
+		}
+		d = math.Min(d, s.segments[i].evaluate(p))
+	}
+	return math.Max(d, s.floor-p.Z)
+}
+
+// BoundingBox returns the bounding box of a set of segments.
+func (s *SegmentsSDF3) BoundingBox() sdf.Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
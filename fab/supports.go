@@ -0,0 +1,235 @@
+//-----------------------------------------------------------------------------
+/*
+
+Support Structures
+
+Generate tree/pillar supports for the overhangs of a part. The overhangs
+are found with the overhang analysis. Each overhang contact gets
+a tapered tip. Tips with a clear path to the build plate are grouped and
+joined by branches to a common trunk, other tips get a pillar down to the
+plate or to the part surface below them.
+
+The supports are a separate SDF3 so they can be exported alongside the part
+for slicers that accept pre-made supports.
+
+*/
+//-----------------------------------------------------------------------------
+
+package fab
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/analysis"
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// SupportParms defines the parameters for support generation.
+type SupportParms struct {
+	Angle         float64 // overhang angle from vertical that needs support (radians)
+	Spacing       float64 // spacing between support contacts
+	Gap           float64 // gap between the supports and the part
+	TipRadius     float64 // radius of the contact tip
+	TipLength     float64 // length of the contact tip
+	BranchRadius  float64 // radius of branches and pillars
+	TrunkRadius   float64 // radius of trunks
+	MergeDistance float64 // contacts within this xy distance share a trunk (0 = pillars only)
+	BaseRadius    float64 // radius of the foot on the build plate (0 = no foot)
+	BaseHeight    float64 // height of the foot on the build plate
+	Cells         int     // overhang sampling cells on the longest axis (default 100)
+}
+
+// contact is a support contact point.
+type contact struct {
+	top    v3.Vec // top of the contact tip
+	bottom v3.Vec // bottom of the contact tip
+}
+
+//-----------------------------------------------------------------------------
+
+// overhangContacts returns the support contacts for the overhangs of a part.
+func overhangContacts(part sdf.SDF3, k *SupportParms) ([]contact, error) {
+	cells := k.Cells
+	if cells == 0 {
+		cells = analysis.DefaultCells
+	}
+	o, err := analysis.OverhangsCells(part, v3.Vec{0, 0, 1}, k.Angle, cells)
+	if err != nil {
+		return nil, err
+	}
+	// thin the overhang points to the contact spacing
+	type key struct{ x, y, z int }
+	seen := make(map[key]bool)
+	var contacts []contact
+	for _, v := range o.Points {
+		p := v.Point
+		id := key{
+			int(math.Floor(p.X / k.Spacing)),
+			int(math.Floor(p.Y / k.Spacing)),
+			int(math.Floor(p.Z / k.Spacing)),
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		top := p.Sub(v3.Vec{0, 0, k.Gap + k.TipRadius})
+		contacts = append(contacts, contact{top, top.Sub(v3.Vec{0, 0, k.TipLength})})
+	}
+	return contacts, nil
+}
+
+// clearPath returns true if a segment of the given radius from a to b doesn't hit the part.
+func clearPath(part sdf.SDF3, a, b v3.Vec, r float64) bool {
+	l := b.Sub(a).Length()
+	n := int(math.Ceil(l/r)) + 1
+	for i := 0; i <= n; i++ {
+		p := a.Add(b.Sub(a).MulScalar(float64(i) / float64(n)))
+		if part.Evaluate(p) < r {
+			return false
+		}
+	}
+	return true
+}
+
+// pillarBase returns the base of a vertical pillar from p down to the floor.
+// The pillar stops on the part surface if the part is below p.
+// Returns true if the pillar reaches the floor.
+func pillarBase(part sdf.SDF3, p v3.Vec, r, gap, floor float64) (v3.Vec, bool) {
+	for z := p.Z; z > floor; {
+		d := part.Evaluate(v3.Vec{p.X, p.Y, z})
+		if d < r+gap {
+			// rest on the part
+			return v3.Vec{p.X, p.Y, z + r + gap}, false
+		}
+		z -= math.Max(d-r-gap, 0.25*r)
+	}
+	return v3.Vec{p.X, p.Y, floor}, true
+}
+
+// Supports returns the support structures for the overhangs of a part.
+// The build plate is the bottom of the part bounding box and the build direction is +z.
+// Returns an empty SDF3 if the part has no overhangs that need support.
+func Supports(part sdf.SDF3, k *SupportParms) (sdf.SDF3, error) {
+	if part == nil {
+		return nil, sdf.ErrMsg("part == nil")
+	}
+	if k.Angle <= 0 || k.Angle >= sdf.Pi*0.5 {
+		return nil, sdf.ErrMsg("Angle not in (0, Pi/2)")
+	}
+	if k.Spacing <= 0 {
+		return nil, sdf.ErrMsg("Spacing <= 0")
+	}
+	if k.TipRadius <= 0 || k.BranchRadius <= 0 || k.TrunkRadius <= 0 {
+		return nil, sdf.ErrMsg("radius <= 0")
+	}
+	if k.TipLength < 0 || k.Gap < 0 || k.MergeDistance < 0 {
+		return nil, sdf.ErrMsg("length < 0")
+	}
+	floor := part.BoundingBox().Min.Z
+	contacts, err := overhangContacts(part, k)
+	if err != nil {
+		return nil, err
+	}
+	if len(contacts) == 0 {
+		return sdf.Empty3D(), nil
+	}
+
+	var segments []segment
+	foot := func(p v3.Vec, r float64) {
+		if k.BaseRadius > r && k.BaseHeight > 0 {
+			segments = append(segments, newSegment(v3.Vec{p.X, p.Y, floor}, v3.Vec{p.X, p.Y, floor + k.BaseHeight}, k.BaseRadius, r))
+		}
+	}
+	pillar := func(c contact) {
+		base, onFloor := pillarBase(part, c.bottom, k.BranchRadius, k.Gap, floor)
+		segments = append(segments, newSegment(c.bottom, base, k.BranchRadius, k.BranchRadius))
+		if onFloor {
+			foot(base, k.BranchRadius)
+		}
+	}
+
+	// add the contact tips, and group the tips with a clear path to the floor
+	groups := make(map[[2]int][]contact)
+	var order [][2]int
+	for _, c := range contacts {
+		segments = append(segments, newSegment(c.top, c.bottom, k.TipRadius, k.BranchRadius))
+		if k.MergeDistance == 0 {
+			pillar(c)
+			continue
+		}
+		if _, onFloor := pillarBase(part, c.bottom, k.BranchRadius, k.Gap, floor); !onFloor {
+			pillar(c)
+			continue
+		}
+		id := [2]int{int(math.Floor(c.bottom.X / k.MergeDistance)), int(math.Floor(c.bottom.Y / k.MergeDistance))}
+		if _, ok := groups[id]; !ok {
+			order = append(order, id)
+		}
+		groups[id] = append(groups[id], c)
+	}
+
+	// join the tips of each group to a trunk
+	for _, id := range order {
+		group := groups[id]
+		if len(group) == 1 {
+			pillar(group[0])
+			continue
+		}
+		// the trunk is at the xy centroid, with its top below the lowest tip
+		var xy v2.Vec
+		zmin := math.MaxFloat64
+		for _, c := range group {
+			xy = xy.Add(v2.Vec{c.bottom.X, c.bottom.Y})
+			zmin = math.Min(zmin, c.bottom.Z)
+		}
+		xy = xy.DivScalar(float64(len(group)))
+		top := v3.Vec{xy.X, xy.Y, zmin - k.MergeDistance}
+		bottom := v3.Vec{xy.X, xy.Y, floor}
+		if top.Z-floor < k.MergeDistance || !clearPath(part, top, bottom, k.TrunkRadius+k.Gap) {
+			// no room for a trunk
+			for _, c := range group {
+				pillar(c)
+			}
+			continue
+		}
+		trunk := false
+		for _, c := range group {
+			if !clearPath(part, c.bottom, top, k.BranchRadius+k.Gap) {
+				pillar(c)
+				continue
+			}
+			segments = append(segments, newSegment(c.bottom, top, k.BranchRadius, k.TrunkRadius))
+			trunk = true
+		}
+		if trunk {
+			segments = append(segments, newSegment(top, bottom, k.TrunkRadius, k.TrunkRadius))
+			foot(bottom, k.TrunkRadius)
+		}
+	}
+
+	// keep the supports clear of the part
+	return sdf.Difference3D(newSegments(segments, floor), sdf.Offset3D(part, k.Gap)), nil
+}
+
+//-----------------------------------------------------------------------------
+
+// ToSTLWithSupports renders a part and its supports to separate STL files.
+// The files are named <prefix>.stl and <prefix>_supports.stl.
+func ToSTLWithSupports(part sdf.SDF3, k *SupportParms, prefix string, r render.Render3) error {
+	supports, err := Supports(part, k)
+	if err != nil {
+		return err
+	}
+	render.ToSTL(part, prefix+".stl", r)
+	if _, empty := supports.(*sdf.EmptySDF3); !empty {
+		render.ToSTL(supports, prefix+"_supports.stl", r)
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
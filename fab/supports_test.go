@@ -0,0 +1,142 @@
+//-----------------------------------------------------------------------------
+/*
+
+Support Structure Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package fab
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func testSupportParms() *SupportParms {
+	return &SupportParms{
+		Angle:         sdf.DtoR(45),
+		Spacing:       4,
+		Gap:           0.2,
+		TipRadius:     0.3,
+		TipLength:     1,
+		BranchRadius:  0.6,
+		TrunkRadius:   1,
+		MergeDistance: 6,
+		Cells:         50,
+	}
+}
+
+func Test_Supports(t *testing.T) {
+	k := testSupportParms()
+
+	// no overhangs
+	box, _ := sdf.Box3D(v3.Vec{X: 20, Y: 20, Z: 10}, 0)
+	s, err := Supports(box, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(*sdf.EmptySDF3); !ok {
+		t.Errorf("expected empty supports for a box, got %T", s)
+	}
+
+	// a table: a slab on a post overhangs on all sides
+	post, _ := sdf.Box3D(v3.Vec{X: 4, Y: 4, Z: 10}, 0)
+	slab, _ := sdf.Box3D(v3.Vec{X: 20, Y: 20, Z: 2}, 0)
+	part := sdf.Union3D(post, sdf.Transform3D(slab, sdf.Translate3d(v3.Vec{Z: 6})))
+	s, err = Supports(part, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(*sdf.EmptySDF3); ok {
+		t.Fatal("expected supports for a table")
+	}
+	pb, sb := part.BoundingBox(), s.BoundingBox()
+	if math.Abs(sb.Min.Z-pb.Min.Z) > 1e-9 {
+		t.Errorf("supports start at z = %g, expected the floor %g", sb.Min.Z, pb.Min.Z)
+	}
+	if sb.Max.Z > 5.5 {
+		t.Errorf("supports reach z = %g, above the slab underside", sb.Max.Z)
+	}
+	// the supports hold up the slab corners and stay clear of the part
+	for _, x := range []float64{-8, 8} {
+		for _, y := range []float64{-8, 8} {
+			if d := s.Evaluate(v3.Vec{X: x, Y: y, Z: 0}); d > 3 {
+				t.Errorf("no support near the corner (%g, %g): distance %g", x, y, d)
+			}
+		}
+	}
+	for i := 0; i < 20000; i++ {
+		p := sb.Random()
+		if s.Evaluate(p) < 0 && part.Evaluate(p) < k.Gap-1e-9 {
+			t.Fatalf("%v is within the gap to the part", p)
+		}
+	}
+
+	// invalid parameters
+	k.Spacing = 0
+	if _, err := Supports(part, k); err == nil {
+		t.Error("expected an error for a zero spacing")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Segments(t *testing.T) {
+	a, b := v3.Vec{X: 1, Y: 2, Z: 3}, v3.Vec{X: 4, Y: -2, Z: 8}
+	ab := b.Sub(a)
+	// distance to the line segment from a to b
+	line := func(p v3.Vec) float64 {
+		h := sdf.Clamp(p.Sub(a).Dot(ab)/ab.Length2(), 0, 1)
+		return p.Sub(a.Add(ab.MulScalar(h))).Length()
+	}
+	bb := sdf.Box3{Min: v3.Vec{X: -10, Y: -10, Z: -10}, Max: v3.Vec{X: 15, Y: 15, Z: 20}}
+
+	// a constant radius segment is a capsule
+	capsule := newSegment(a, b, 1.5, 1.5)
+	for i := 0; i < 1000; i++ {
+		p := bb.Random()
+		if d0, d1 := capsule.evaluate(p), line(p)-1.5; math.Abs(d0-d1) > 1e-9 {
+			t.Fatalf("capsule %v: distance %g, expected %g", p, d0, d1)
+		}
+	}
+
+	// an end sphere containing the other is a sphere
+	sphere := newSegment(a, a.Add(v3.Vec{X: 1}), 3, 1)
+	for i := 0; i < 1000; i++ {
+		p := bb.Random()
+		if d0, d1 := sphere.evaluate(p), p.Sub(a).Length()-3; math.Abs(d0-d1) > 1e-9 {
+			t.Fatalf("sphere %v: distance %g, expected %g", p, d0, d1)
+		}
+	}
+
+	// the set is the union of its segments cut off at the floor
+	segments := []segment{
+		newSegment(a, b, 1, 0.5),
+		newSegment(b, v3.Vec{X: 4, Y: -2, Z: -5}, 0.5, 0.5),
+		newSegment(v3.Vec{X: -6, Y: 6, Z: 0}, v3.Vec{X: -6, Y: 6, Z: 10}, 2, 1),
+		newSegment(v3.Vec{X: 8, Y: 8, Z: 8}, a, 0.4, 1),
+	}
+	s := newSegments(segments, -2)
+	if s.BoundingBox().Min.Z != -2 {
+		t.Errorf("bounding box %v is below the floor", s.BoundingBox())
+	}
+	for i := 0; i < 10000; i++ {
+		p := bb.Random()
+		d := math.MaxFloat64
+		for j := range segments {
+			d = math.Min(d, segments[j].evaluate(p))
+		}
+		d = math.Max(d, -2-p.Z)
+		if d0 := s.Evaluate(p); d0 != d {
+			t.Fatalf("segments %v: distance %g, expected %g", p, d0, d)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,289 @@
+//-----------------------------------------------------------------------------
+/*
+
+G-Code Generation
+
+Slice an SDF3 and generate Marlin flavored G-code for an FDM printer.
+
+The pipeline is:
+
+1. Toolpaths: slice the part into layers of perimeter and infill toolpaths.
+2. Generate: convert the toolpaths into G-code moves.
+
+This is a basic slicer: perimeters, solid top/bottom skins, rectilinear or
+gyroid sparse infill, retraction on travel moves and a vase (spiral) mode.
+There are no supports, bridging or overhang detection.
+
+Extrusion uses relative E distances (M83).
+
+*/
+//-----------------------------------------------------------------------------
+
+package gcode
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// Config is the set of slicing and printer parameters.
+type Config struct {
+	LayerHeight      float64       // layer height (mm)
+	LineWidth        float64       // extrusion line width (mm)
+	FilamentDiameter float64       // filament diameter (mm)
+	Perimeters       int           // number of perimeters
+	TopLayers        int           // number of solid top layers
+	BottomLayers     int           // number of solid bottom layers
+	Infill           float64       // sparse infill density (0..1)
+	InfillPattern    InfillPattern // sparse infill pattern
+	Vase             bool          // vase mode: solid bottom layers and a single spiral perimeter
+	NozzleTemp       float64       // nozzle temperature (C)
+	BedTemp          float64       // bed temperature (C)
+	FanSpeed         float64       // part cooling fan speed after the first layer (0..1)
+	PrintSpeed       float64       // print speed (mm/s)
+	FirstLayerSpeed  float64       // first layer print speed (mm/s)
+	TravelSpeed      float64       // travel speed (mm/s)
+	RetractLength    float64       // retraction length (mm, 0 = none)
+	RetractSpeed     float64       // retraction speed (mm/s)
+	RetractMinTravel float64       // minimum travel distance for a retraction (mm)
+	Center           v2.Vec        // build plate position of the part center
+}
+
+// DefaultConfig is a PLA profile for a 0.4mm nozzle on a 220x220 bed.
+var DefaultConfig = Config{
+	LayerHeight:      0.2,
+	LineWidth:        0.45,
+	FilamentDiameter: 1.75,
+	Perimeters:       2,
+	TopLayers:        4,
+	BottomLayers:     3,
+	Infill:           0.2,
+	InfillPattern:    Rectilinear,
+	NozzleTemp:       210,
+	BedTemp:          60,
+	FanSpeed:         1,
+	PrintSpeed:       50,
+	FirstLayerSpeed:  20,
+	TravelSpeed:      150,
+	RetractLength:    1,
+	RetractSpeed:     35,
+	RetractMinTravel: 1.5,
+	Center:           v2.Vec{110, 110},
+}
+
+func (cfg *Config) validate() error {
+	if cfg.LayerHeight <= 0 {
+		return sdf.ErrMsg("LayerHeight <= 0")
+	}
+	if cfg.LineWidth <= 0 {
+		return sdf.ErrMsg("LineWidth <= 0")
+	}
+	if cfg.FilamentDiameter <= 0 {
+		return sdf.ErrMsg("FilamentDiameter <= 0")
+	}
+	if cfg.Perimeters < 1 {
+		return sdf.ErrMsg("Perimeters < 1")
+	}
+	if cfg.TopLayers < 0 || cfg.BottomLayers < 0 {
+		return sdf.ErrMsg("TopLayers/BottomLayers < 0")
+	}
+	if cfg.Infill < 0 || cfg.Infill > 1 {
+		return sdf.ErrMsg("Infill must be 0..1")
+	}
+	if cfg.PrintSpeed <= 0 || cfg.FirstLayerSpeed <= 0 || cfg.TravelSpeed <= 0 {
+		return sdf.ErrMsg("speed <= 0")
+	}
+	if cfg.RetractLength < 0 {
+		return sdf.ErrMsg("RetractLength < 0")
+	}
+	if cfg.RetractLength > 0 && cfg.RetractSpeed <= 0 {
+		return sdf.ErrMsg("RetractSpeed <= 0")
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// writer emits G-code and tracks the nozzle state.
+type writer struct {
+	w         *bufio.Writer
+	cfg       *Config
+	pos       v2.Vec
+	z         float64
+	retracted bool
+	eScale    float64 // filament length per unit length of extrusion for the current layer
+	err       error
+}
+
+func (w *writer) printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.w, format, args...)
+}
+
+func (w *writer) retract() {
+	if w.cfg.RetractLength == 0 || w.retracted {
+		return
+	}
+	w.printf("G1 E%.5f F%.0f\n", -w.cfg.RetractLength, 60*w.cfg.RetractSpeed)
+	w.retracted = true
+}
+
+func (w *writer) unretract() {
+	if !w.retracted {
+		return
+	}
+	w.printf("G1 E%.5f F%.0f\n", w.cfg.RetractLength, 60*w.cfg.RetractSpeed)
+	w.retracted = false
+}
+
+// travel moves to a point without extruding.
+func (w *writer) travel(p v2.Vec) {
+	if p.Sub(w.pos).Length() > w.cfg.RetractMinTravel {
+		w.retract()
+	}
+	w.printf("G0 X%.3f Y%.3f F%.0f\n", p.X, p.Y, 60*w.cfg.TravelSpeed)
+	w.pos = p
+}
+
+// extrude moves to a point while extruding.
+func (w *writer) extrude(p v2.Vec, z, speed float64) {
+	w.unretract()
+	e := p.Sub(w.pos).Length() * w.eScale
+	if z != w.z {
+		w.printf("G1 X%.3f Y%.3f Z%.3f E%.5f F%.0f\n", p.X, p.Y, z, e, 60*speed)
+		w.z = z
+	} else {
+		w.printf("G1 X%.3f Y%.3f E%.5f F%.0f\n", p.X, p.Y, e, 60*speed)
+	}
+	w.pos = p
+}
+
+func (w *writer) header(layers int) {
+	cfg := w.cfg
+	w.printf("; generated by sdfx\n")
+	w.printf("; layers %d, layer height %.3f, line width %.3f\n", layers, cfg.LayerHeight, cfg.LineWidth)
+	w.printf("M140 S%.0f\n", cfg.BedTemp)
+	w.printf("M104 S%.0f\n", cfg.NozzleTemp)
+	w.printf("M190 S%.0f\n", cfg.BedTemp)
+	w.printf("M109 S%.0f\n", cfg.NozzleTemp)
+	w.printf("G21\n")
+	w.printf("G90\n")
+	w.printf("M83\n")
+	w.printf("G28\n")
+	w.printf("G92 E0\n")
+	w.printf("M107\n")
+}
+
+func (w *writer) footer() {
+	w.retract()
+	w.printf("M107\n")
+	w.printf("M104 S0\n")
+	w.printf("M140 S0\n")
+	w.printf("G91\n")
+	w.printf("G0 Z10 F%.0f\n", 60*w.cfg.TravelSpeed)
+	w.printf("G90\n")
+	w.printf("M84\n")
+}
+
+// layer emits the moves for a layer. zPrev is the height of the previous layer.
+func (w *writer) layer(i int, l *Layer, zPrev float64) {
+	cfg := w.cfg
+	w.printf(";LAYER:%d\n", i)
+	if i == 1 && cfg.FanSpeed > 0 {
+		w.printf("M106 S%.0f\n", 255*math.Min(cfg.FanSpeed, 1))
+	}
+	speed := cfg.PrintSpeed
+	if i == 0 {
+		speed = cfg.FirstLayerSpeed
+	}
+	// filament length = extruded volume / filament cross section
+	h := l.Z - zPrev
+	r := 0.5 * cfg.FilamentDiameter
+	w.eScale = cfg.LineWidth * h / (sdf.Pi * r * r)
+
+	if !l.Spiral {
+		w.retract()
+		w.printf("G0 Z%.3f F%.0f\n", l.Z, 60*cfg.TravelSpeed)
+		w.z = l.Z
+	}
+	kind := PathKind(-1)
+	for _, p := range l.Paths {
+		if len(p.Points) < 2 {
+			continue
+		}
+		if p.Kind != kind {
+			w.printf(";TYPE:%s\n", p.Kind)
+			kind = p.Kind
+		}
+		pts := p.Points
+		if p.Closed {
+			pts = append(pts[:len(pts):len(pts)], pts[0])
+		}
+		w.travel(pts[0])
+		if l.Spiral {
+			// rise from the previous layer to this layer along the path
+			length, total := 0.0, p.Length()
+			for _, q := range pts[1:] {
+				length += q.Sub(w.pos).Length()
+				w.extrude(q, zPrev+h*length/total, speed)
+			}
+			continue
+		}
+		for _, q := range pts[1:] {
+			w.extrude(q, l.Z, speed)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// Generate writes the G-code for the layer toolpaths.
+func Generate(out io.Writer, layers []Layer, cfg *Config) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	w := &writer{
+		w:   bufio.NewWriter(out),
+		cfg: cfg,
+	}
+	w.header(len(layers))
+	zPrev := 0.0
+	for i := range layers {
+		w.layer(i, &layers[i], zPrev)
+		zPrev = layers[i].Z
+	}
+	w.footer()
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+// Save slices a part and writes the G-code to a file.
+func Save(path string, s sdf.SDF3, cfg *Config) error {
+	layers, err := Toolpaths(s, cfg)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Generate(f, layers, cfg); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//-----------------------------------------------------------------------------
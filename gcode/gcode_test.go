@@ -0,0 +1,82 @@
+//-----------------------------------------------------------------------------
+/*
+
+G-Code Generation Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package gcode
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// extruded returns the total filament length of some G-code.
+func extruded(t *testing.T, gcode string) float64 {
+	e := 0.0
+	scanner := bufio.NewScanner(strings.NewReader(gcode))
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text()) {
+			if field[0] != 'E' {
+				continue
+			}
+			var x float64
+			if _, err := fmt.Sscanf(field, "E%f", &x); err != nil {
+				t.Fatal(err)
+			}
+			e += x
+		}
+	}
+	return e
+}
+
+func Test_Generate(t *testing.T) {
+	box, err := sdf.Box3D(v3.Vec{20, 20, 4}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hole, err := sdf.Cylinder3D(10, 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := sdf.Difference3D(box, hole)
+	volume := 20*20*4 - 25*sdf.Pi*4
+
+	for _, pattern := range []InfillPattern{Rectilinear, Gyroid} {
+		cfg := DefaultConfig
+		cfg.InfillPattern = pattern
+		cfg.Infill = 1
+		cfg.TopLayers = 2
+		cfg.BottomLayers = 2
+		layers, err := Toolpaths(s, &cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(layers) != 20 {
+			t.Fatalf("expected 20 layers, got %d", len(layers))
+		}
+		var b bytes.Buffer
+		if err := Generate(&b, layers, &cfg); err != nil {
+			t.Fatal(err)
+		}
+		// a solid part extrudes its volume
+		r := 0.5 * cfg.FilamentDiameter
+		v := extruded(t, b.String()) * sdf.Pi * r * r
+		if math.Abs(v-volume)/volume > 0.1 {
+			t.Errorf("pattern %d: extruded volume %f, part volume %f", pattern, v, volume)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
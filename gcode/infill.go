@@ -0,0 +1,215 @@
+//-----------------------------------------------------------------------------
+/*
+
+Infill Patterns
+
+Rectilinear: parallel lines at an angle, clipped to the region contours.
+The lines are on a fixed grid so the lines of sparse infill stack up from
+layer to layer.
+
+Gyroid: the zero level set of the gyroid function at the layer height.
+The lines shift from layer to layer to build a 3d lattice.
+
+*/
+//-----------------------------------------------------------------------------
+
+package gcode
+
+import (
+	"math"
+	"sort"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// InfillPattern is the type of sparse infill.
+type InfillPattern int
+
+// Infill patterns.
+const (
+	Rectilinear InfillPattern = iota // parallel lines, alternating direction each layer
+	Gyroid                           // gyroid lattice
+)
+
+//-----------------------------------------------------------------------------
+// Rectilinear
+
+// rectilinear returns the rectilinear infill of a region with a line spacing and angle.
+func (sl *slicer) rectilinear(region sdf.SDF2, spacing, angle float64, kind PathKind) []Path {
+	contours := render.Contours(region, sl.resolution)
+	if len(contours) == 0 {
+		return nil
+	}
+	// rotate the contours so the infill lines are horizontal
+	m := sdf.Rotate2d(-angle)
+	var edges [][2]v2.Vec
+	yMin, yMax := math.MaxFloat64, -math.MaxFloat64
+	for _, c := range contours {
+		for i := range c {
+			p0 := m.MulPosition(c[i])
+			p1 := m.MulPosition(c[(i+1)%len(c)])
+			edges = append(edges, [2]v2.Vec{p0, p1})
+			yMin = math.Min(yMin, p0.Y)
+			yMax = math.Max(yMax, p0.Y)
+		}
+	}
+	inv := sdf.Rotate2d(angle)
+	var paths []Path
+	var xs []float64
+	for k := math.Ceil(yMin / spacing); k*spacing <= yMax; k++ {
+		y := k * spacing
+		xs = xs[:0]
+		for _, e := range edges {
+			p0, p1 := e[0], e[1]
+			if (p0.Y <= y) == (p1.Y <= y) {
+				continue
+			}
+			t := (y - p0.Y) / (p1.Y - p0.Y)
+			xs = append(xs, p0.X+t*(p1.X-p0.X))
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			a := inv.MulPosition(v2.Vec{xs[i], y})
+			b := inv.MulPosition(v2.Vec{xs[i+1], y})
+			paths = append(paths, Path{Kind: kind, Points: []v2.Vec{a, b}})
+		}
+	}
+	return paths
+}
+
+//-----------------------------------------------------------------------------
+// Gyroid
+
+// gyroidLength is the length of the gyroid lines relative to straight lines with the same spacing.
+const gyroidLength = 1.2
+
+// gyroidSDF2 is the gyroid function at a z height. It is not a distance
+// function but it has the zero level set needed for the infill lines.
+type gyroidSDF2 struct {
+	k  float64 // 2 pi / period
+	z  float64
+	bb sdf.Box2
+}
+
+func (s *gyroidSDF2) Evaluate(p v2.Vec) float64 {
+	x, y, z := s.k*p.X, s.k*p.Y, s.k*s.z
+	return math.Sin(x)*math.Cos(y) + math.Sin(y)*math.Cos(z) + math.Sin(z)*math.Cos(x)
+}
+
+func (s *gyroidSDF2) BoundingBox() sdf.Box2 {
+	return s.bb
+}
+
+// lineCollector is a Line2Writer that collects the line segments.
+type lineCollector struct {
+	lines []*sdf.Line2
+}
+
+func (c *lineCollector) Write(in []*sdf.Line2) error {
+	c.lines = append(c.lines, in...)
+	return nil
+}
+
+func (c *lineCollector) Close() error {
+	return nil
+}
+
+// gyroid returns the gyroid infill of a region with a line spacing.
+func (sl *slicer) gyroid(region sdf.SDF2, spacing, z float64) []Path {
+	bb := region.BoundingBox()
+	g := &gyroidSDF2{
+		// A gyroid period has two wavy lines, about gyroidLength times longer
+		// than straight lines, so stretch the period for the same density.
+		k:  sdf.Pi / (gyroidLength * spacing),
+		z:  z,
+		bb: bb,
+	}
+	cells := int(math.Ceil(bb.Size().MaxComponent() / sl.resolution))
+	c := &lineCollector{}
+	render.NewMarchingSquaresUniform(cells).Render(g, c)
+	// keep the segments inside the region
+	var lines []*sdf.Line2
+	for _, l := range c.lines {
+		if region.Evaluate(l[0].Add(l[1]).MulScalar(0.5)) < 0 {
+			lines = append(lines, l)
+		}
+	}
+	var paths []Path
+	for _, pl := range chainLines(lines, sl.resolution*1e-3) {
+		paths = append(paths, Path{Kind: SparseInfill, Points: pl})
+	}
+	return paths
+}
+
+// chainLines joins unordered line segments into polylines.
+// Segment endpoints closer than tolerance are joined.
+func chainLines(lines []*sdf.Line2, tolerance float64) [][]v2.Vec {
+	type key struct{ x, y int64 }
+	toKey := func(p v2.Vec) key {
+		return key{int64(math.Floor(p.X / tolerance)), int64(math.Floor(p.Y / tolerance))}
+	}
+	ends := make(map[key][]int, 2*len(lines))
+	for i, l := range lines {
+		for _, p := range l {
+			k := toKey(p)
+			ends[k] = append(ends[k], i)
+		}
+	}
+	used := make([]bool, len(lines))
+	// next returns an unused segment with an end point at p and its other end point.
+	next := func(p v2.Vec) (int, v2.Vec) {
+		k := toKey(p)
+		for dx := int64(-1); dx <= 1; dx++ {
+			for dy := int64(-1); dy <= 1; dy++ {
+				for _, i := range ends[key{k.x + dx, k.y + dy}] {
+					if used[i] {
+						continue
+					}
+					if lines[i][0].Sub(p).Length() < tolerance {
+						return i, lines[i][1]
+					}
+					if lines[i][1].Sub(p).Length() < tolerance {
+						return i, lines[i][0]
+					}
+				}
+			}
+		}
+		return -1, v2.Vec{}
+	}
+	// extend follows the segments from p
+	extend := func(p v2.Vec) []v2.Vec {
+		var pl []v2.Vec
+		for {
+			j, q := next(p)
+			if j < 0 {
+				return pl
+			}
+			used[j] = true
+			pl = append(pl, q)
+			p = q
+		}
+	}
+	var polylines [][]v2.Vec
+	for i, l := range lines {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		fwd := extend(l[1])
+		rev := extend(l[0])
+		pl := make([]v2.Vec, 0, len(fwd)+len(rev)+2)
+		for j := len(rev) - 1; j >= 0; j-- {
+			pl = append(pl, rev[j])
+		}
+		pl = append(pl, l[0], l[1])
+		pl = append(pl, fwd...)
+		polylines = append(polylines, pl)
+	}
+	return polylines
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,382 @@
+//-----------------------------------------------------------------------------
+/*
+
+Toolpaths
+
+Convert the layers of an SDF3 into extrusion toolpaths.
+
+Each layer is a slice through the middle of the layer. The distance field
+of a slice through an SDF3 is the 3d distance, which is too small near the
+top and bottom surfaces of the part to offset correctly. So the slice is
+contoured and the contours are turned back into an exact 2d distance field.
+
+The perimeters are the contours of the slice offset inwards by the line
+width. The region inside the perimeters is split into solid infill (near the top and bottom
+surfaces of the part) and sparse infill (the interior). A point of a layer
+is in the interior if it is inside the part for the top and bottom layer
+counts above and below it.
+
+Offsetting and splitting the regions are done with SDF2 operations, and
+the contours come from marching squares.
+
+*/
+//-----------------------------------------------------------------------------
+
+package gcode
+
+import (
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// infillOverlap is the overlap of the infill and the inner perimeter (fraction of the line width).
+const infillOverlap = 0.25
+
+// PathKind is the type of a toolpath.
+type PathKind int
+
+// Toolpath types.
+const (
+	OuterPerimeter PathKind = iota // outer perimeter
+	InnerPerimeter                 // inner perimeters
+	SolidInfill                    // top and bottom solid infill
+	SparseInfill                   // interior infill
+)
+
+func (k PathKind) String() string {
+	return [...]string{"WALL-OUTER", "WALL-INNER", "SKIN", "FILL"}[k]
+}
+
+// Path is an extrusion toolpath.
+type Path struct {
+	Kind   PathKind
+	Points []v2.Vec
+	Closed bool // the last point connects to the first point
+}
+
+// Length returns the extrusion length of a path.
+func (p *Path) Length() float64 {
+	l := 0.0
+	for i := 1; i < len(p.Points); i++ {
+		l += p.Points[i].Sub(p.Points[i-1]).Length()
+	}
+	if p.Closed && len(p.Points) > 1 {
+		l += p.Points[0].Sub(p.Points[len(p.Points)-1]).Length()
+	}
+	return l
+}
+
+// Layer is the set of toolpaths for a layer.
+type Layer struct {
+	Z      float64 // height of the top of the layer above the build plate
+	Spiral bool    // the nozzle rises along the paths from the previous layer (vase mode)
+	Paths  []Path
+}
+
+//-----------------------------------------------------------------------------
+
+// slicer holds the state for generating the layers of a part.
+type slicer struct {
+	s          sdf.SDF3 // part placed on the build plate
+	cfg        *Config
+	layers     int        // number of layers
+	slices     []sdf.SDF2 // layer cross sections (nil = empty)
+	resolution float64
+}
+
+// slice returns the 2d distance field of the cross section of the part in layer i.
+// It returns nil if the cross section is empty.
+func (sl *slicer) slice(i int) sdf.SDF2 {
+	z := (float64(i) + 0.5) * sl.cfg.LayerHeight
+	s := sdf.Slice2D(sl.s, v3.Vec{0, 0, z}, v3.Vec{0, 0, 1})
+	var lines []*sdf.Line2
+	for _, c := range render.Contours(s, sl.resolution) {
+		lines = append(lines, c.Lines()...)
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	s, err := sdf.Mesh2D(lines)
+	if err != nil {
+		return nil
+	}
+	// margin for the outer perimeter
+	margin := sl.cfg.LineWidth + 2*sl.resolution
+	return newGridSDF2(s, s.BoundingBox().Enlarge(v2.Vec{margin, margin}), sl.resolution)
+}
+
+//-----------------------------------------------------------------------------
+
+// gridSDF2 is an SDF2 sampled on a grid with bilinear interpolation.
+// The mesh distance field is slow, and each slice is evaluated many times.
+type gridSDF2 struct {
+	bb         sdf.Box2
+	resolution float64
+	nx, ny     int
+	d          []float64
+}
+
+// newGridSDF2 samples an SDF2 on a grid over a bounding box.
+func newGridSDF2(s sdf.SDF2, bb sdf.Box2, resolution float64) *gridSDF2 {
+	size := bb.Size()
+	nx := int(math.Ceil(size.X/resolution)) + 1
+	ny := int(math.Ceil(size.Y/resolution)) + 1
+	g := &gridSDF2{
+		bb:         sdf.Box2{Min: bb.Min, Max: bb.Min.Add(v2.Vec{float64(nx - 1), float64(ny - 1)}.MulScalar(resolution))},
+		resolution: resolution,
+		nx:         nx,
+		ny:         ny,
+		d:          make([]float64, nx*ny),
+	}
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			g.d[j*nx+i] = s.Evaluate(bb.Min.Add(v2.Vec{float64(i), float64(j)}.MulScalar(resolution)))
+		}
+	}
+	return g
+}
+
+// Evaluate returns the interpolated distance. Outside the grid the distance
+// to the grid is added to the distance at the closest grid point.
+func (g *gridSDF2) Evaluate(p v2.Vec) float64 {
+	q := p.Clamp(g.bb.Min, g.bb.Max)
+	u := q.Sub(g.bb.Min).DivScalar(g.resolution)
+	i := int(math.Min(math.Floor(u.X), float64(g.nx-2)))
+	j := int(math.Min(math.Floor(u.Y), float64(g.ny-2)))
+	fx, fy := u.X-float64(i), u.Y-float64(j)
+	d00 := g.d[j*g.nx+i]
+	d10 := g.d[j*g.nx+i+1]
+	d01 := g.d[(j+1)*g.nx+i]
+	d11 := g.d[(j+1)*g.nx+i+1]
+	d := (d00*(1-fx)+d10*fx)*(1-fy) + (d01*(1-fx)+d11*fx)*fy
+	return d + p.Sub(q).Length()
+}
+
+// BoundingBox returns the bounding box of the grid.
+func (g *gridSDF2) BoundingBox() sdf.Box2 {
+	return g.bb
+}
+
+// core returns the cross section of layer i that is inside the part
+// for the given layers above and below. It returns nil if it is empty.
+func (sl *slicer) core(i, above, below int) sdf.SDF2 {
+	if i-below < 0 || i+above >= sl.layers {
+		return nil
+	}
+	s0, s1 := sl.slices[i+above], sl.slices[i-below]
+	if s0 == nil || s1 == nil {
+		return nil
+	}
+	return sdf.Intersect2D(s0, s1)
+}
+
+// contourPaths returns the closed paths of the contours of an SDF2.
+func (sl *slicer) contourPaths(s sdf.SDF2, kind PathKind) []Path {
+	var paths []Path
+	for _, c := range render.Contours(s, sl.resolution) {
+		paths = append(paths, Path{Kind: kind, Points: c, Closed: true})
+	}
+	return paths
+}
+
+// layer returns the toolpaths for layer i.
+func (sl *slicer) layer(i int) Layer {
+	cfg := sl.cfg
+	w := cfg.LineWidth
+	l := Layer{Z: float64(i+1) * cfg.LayerHeight}
+	s := sl.slices[i]
+	if s == nil {
+		return l
+	}
+	angle := sdf.DtoR(45)
+	if i%2 == 1 {
+		angle = -angle
+	}
+
+	if cfg.Vase && i >= cfg.BottomLayers {
+		// a single spiral perimeter
+		paths := sl.contourPaths(sdf.Offset2D(s, -0.5*w), OuterPerimeter)
+		if len(paths) != 0 {
+			l.Spiral = true
+			l.Paths = []Path{largest(paths)}
+		}
+		return l
+	}
+
+	// perimeters, inner first
+	for k := cfg.Perimeters - 1; k >= 0; k-- {
+		kind := InnerPerimeter
+		if k == 0 {
+			kind = OuterPerimeter
+		}
+		l.Paths = append(l.Paths, sl.contourPaths(sdf.Offset2D(s, -(float64(k)+0.5)*w), kind)...)
+	}
+
+	// infill regions
+	region := sdf.Offset2D(s, -(float64(cfg.Perimeters)-infillOverlap)*w)
+	var solid, sparse sdf.SDF2
+	if core := sl.core(i, cfg.TopLayers, cfg.BottomLayers); cfg.Vase || core == nil {
+		solid = region
+	} else {
+		solid = sdf.Difference2D(region, core)
+		sparse = sdf.Intersect2D(region, core)
+	}
+	l.Paths = append(l.Paths, sl.rectilinear(solid, w, angle, SolidInfill)...)
+	if sparse != nil && cfg.Infill > 0 {
+		spacing := w / cfg.Infill
+		switch cfg.InfillPattern {
+		case Gyroid:
+			l.Paths = append(l.Paths, sl.gyroid(sparse, spacing, l.Z)...)
+		default:
+			l.Paths = append(l.Paths, sl.rectilinear(sparse, spacing, angle, SparseInfill)...)
+		}
+	}
+	return l
+}
+
+// largest returns the path with the largest enclosed area.
+func largest(paths []Path) Path {
+	best, area := 0, 0.0
+	for i, p := range paths {
+		if a := math.Abs(render.Contour(p.Points).Area()); a > area {
+			best, area = i, a
+		}
+	}
+	return paths[best]
+}
+
+//-----------------------------------------------------------------------------
+
+// orderPaths reorders runs of paths of the same kind to reduce the travel between them.
+// Closed paths are rotated to start at the point closest to the previous path end.
+func orderPaths(paths []Path, start v2.Vec) []Path {
+	out := make([]Path, 0, len(paths))
+	pos := start
+	for i := 0; i < len(paths); {
+		// find the run of paths of the same kind
+		j := i
+		for j < len(paths) && paths[j].Kind == paths[i].Kind {
+			j++
+		}
+		run := paths[i:j]
+		used := make([]bool, len(run))
+		for range run {
+			best, bestIdx, bestDist, reverse := -1, 0, math.MaxFloat64, false
+			for k, p := range run {
+				if used[k] {
+					continue
+				}
+				if p.Closed {
+					for m, v := range p.Points {
+						if d := v.Sub(pos).Length2(); d < bestDist {
+							best, bestIdx, bestDist, reverse = k, m, d, false
+						}
+					}
+					continue
+				}
+				if d := p.Points[0].Sub(pos).Length2(); d < bestDist {
+					best, bestIdx, bestDist, reverse = k, 0, d, false
+				}
+				if d := p.Points[len(p.Points)-1].Sub(pos).Length2(); d < bestDist {
+					best, bestIdx, bestDist, reverse = k, 0, d, true
+				}
+			}
+			used[best] = true
+			p := run[best]
+			pts := make([]v2.Vec, len(p.Points))
+			if p.Closed {
+				n := copy(pts, p.Points[bestIdx:])
+				copy(pts[n:], p.Points[:bestIdx])
+				pos = pts[0]
+			} else {
+				copy(pts, p.Points)
+				if reverse {
+					for a, b := 0, len(pts)-1; a < b; a, b = a+1, b-1 {
+						pts[a], pts[b] = pts[b], pts[a]
+					}
+				}
+				pos = pts[len(pts)-1]
+			}
+			out = append(out, Path{Kind: p.Kind, Points: pts, Closed: p.Closed})
+		}
+		i = j
+	}
+	return out
+}
+
+//-----------------------------------------------------------------------------
+
+// parallel calls f(i) for i in 0..n-1 with a pool of workers.
+func parallel(n int, f func(i int)) {
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// Toolpaths returns the layer toolpaths for printing a part.
+// The part is placed on the build plate (z = 0) centered on cfg.Center.
+func Toolpaths(s sdf.SDF3, cfg *Config) ([]Layer, error) {
+	if s == nil {
+		return nil, sdf.ErrMsg("s == nil")
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	bb := s.BoundingBox()
+	c := bb.Center()
+	s = sdf.Transform3D(s, sdf.Translate3d(v3.Vec{cfg.Center.X - c.X, cfg.Center.Y - c.Y, -bb.Min.Z}))
+	sl := &slicer{
+		s:          s,
+		cfg:        cfg,
+		layers:     int(math.Ceil(bb.Size().Z/cfg.LayerHeight - 1e-9)),
+		resolution: cfg.LineWidth * 0.25,
+	}
+	if sl.layers <= 0 {
+		return nil, sdf.ErrMsg("part has no height")
+	}
+	sl.slices = make([]sdf.SDF2, sl.layers)
+	parallel(sl.layers, func(i int) {
+		sl.slices[i] = sl.slice(i)
+	})
+	layers := make([]Layer, sl.layers)
+	parallel(sl.layers, func(i int) {
+		layers[i] = sl.layer(i)
+	})
+
+	// order the paths, starting each layer where the previous layer ended
+	pos := cfg.Center
+	for i := range layers {
+		layers[i].Paths = orderPaths(layers[i].Paths, pos)
+		if n := len(layers[i].Paths); n != 0 {
+			p := layers[i].Paths[n-1]
+			pos = p.Points[len(p.Points)-1]
+			if p.Closed {
+				pos = p.Points[0]
+			}
+		}
+	}
+	return layers, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,160 @@
+//-----------------------------------------------------------------------------
+/*
+
+Material Profiles
+
+A material profile has the physical properties (density, shrinkage) and
+the design defaults (minimum wall, minimum hole, clearance) of a material
+and process. Profiles are kept in a registry by name so a project can
+select a material once and use it to compensate for shrinkage, estimate
+the mass of parts, and build design rules.
+
+Values are typical defaults and should be tuned for a particular machine.
+
+*/
+//-----------------------------------------------------------------------------
+
+package material
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/deadsy/sdfx/analysis"
+	"github.com/deadsy/sdfx/drc"
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// Profile is a material profile.
+type Profile struct {
+	Name      string  // material name
+	Process   string  // manufacturing process
+	Density   float64 // density (g/cm^3)
+	Shrinkage float64 // linear shrinkage as a fraction (0.005 = 0.5%)
+	MinWall   float64 // minimum wall thickness (mm)
+	MinHole   float64 // minimum hole diameter (mm)
+	Clearance float64 // clearance between mating parts (mm)
+	Cost      float64 // cost per kg
+}
+
+func (m *Profile) String() string {
+	return fmt.Sprintf("%s (%s)", m.Name, m.Process)
+}
+
+//-----------------------------------------------------------------------------
+
+// Standard material profiles.
+var (
+	PLA      = &Profile{"PLA", "FDM", 1.24, 0.001, 0.8, 1.0, 0.2, 20}
+	PETG     = &Profile{"PETG", "FDM", 1.27, 0.004, 0.8, 1.0, 0.25, 25}
+	ABS      = &Profile{"ABS", "FDM", 1.04, 0.005, 1.0, 1.0, 0.3, 22}
+	TPU      = &Profile{"TPU", "FDM", 1.21, 0.005, 1.2, 1.5, 0.4, 35}
+	Resin    = &Profile{"Resin", "SLA", 1.15, 0.007, 0.5, 0.5, 0.1, 50}
+	PA12     = &Profile{"PA12", "SLS", 1.01, 0.03, 0.8, 1.5, 0.4, 80}
+	Aluminum = &Profile{"Aluminum", "CNC", 2.70, 0, 0.5, 1.0, 0.05, 6}
+)
+
+// The registry is locked so profiles can be registered while other goroutines look them up.
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]*Profile{}
+)
+
+func init() {
+	for _, m := range []*Profile{PLA, PETG, ABS, TPU, Resin, PA12, Aluminum} {
+		Register(m)
+	}
+}
+
+// Register adds a material profile to the registry (replacing any profile of the same name).
+func Register(m *Profile) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry[strings.ToLower(m.Name)] = m
+}
+
+// Get returns a material profile by name (case insensitive).
+func Get(name string) (*Profile, error) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	m, ok := registry[strings.ToLower(name)]
+	if !ok {
+		return nil, sdf.ErrMsg(fmt.Sprintf("material \"%s\" not found", name))
+	}
+	return m, nil
+}
+
+// Names returns the names of the registered material profiles.
+func Names() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	names := make([]string, 0, len(registry))
+	for _, m := range registry {
+		names = append(names, m.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//-----------------------------------------------------------------------------
+// Compensation
+
+// ScaleFactor returns the scale factor that compensates for shrinkage.
+func (m *Profile) ScaleFactor() float64 {
+	return 1 / (1 - m.Shrinkage)
+}
+
+// Compensate scales an SDF3 to compensate for material shrinkage.
+func (m *Profile) Compensate(s sdf.SDF3) sdf.SDF3 {
+	if m.Shrinkage == 0 {
+		return s
+	}
+	return sdf.ScaleUniform3D(s, m.ScaleFactor())
+}
+
+// HoleSize returns the modelled size of a hole for a mating part of the given size.
+func (m *Profile) HoleSize(size float64) float64 {
+	return size + m.Clearance
+}
+
+// ShaftSize returns the modelled size of a shaft for a mating hole of the given size.
+func (m *Profile) ShaftSize(size float64) float64 {
+	return size - m.Clearance
+}
+
+//-----------------------------------------------------------------------------
+// Mass Properties
+
+// Mass returns the mass (g) for a volume (mm^3).
+func (m *Profile) Mass(volume float64) float64 {
+	return m.Density * volume * 1e-3
+}
+
+// PartMass estimates the mass (g) of a solid part.
+// cells is the number of sampling cells on the longest axis.
+func (m *Profile) PartMass(s sdf.SDF3, cells int) float64 {
+	return m.Mass(analysis.Volume(s, cells))
+}
+
+//-----------------------------------------------------------------------------
+// Design Rules
+
+// Rules returns the design rules for parts made from the material.
+// The rules apply to the named parts (all parts if none are given).
+func (m *Profile) Rules(parts ...string) []drc.Rule {
+	return []drc.Rule{
+		&drc.MinWall{Thickness: m.MinWall, Parts: parts},
+		&drc.MinHole{Diameter: m.MinHole, Parts: parts},
+	}
+}
+
+// ClearanceRule returns a design rule for the clearance between two mating parts.
+func (m *Profile) ClearanceRule(a, b string) drc.Rule {
+	return &drc.MinClearance{Distance: m.Clearance, A: a, B: b}
+}
+
+//-----------------------------------------------------------------------------
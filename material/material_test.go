@@ -0,0 +1,134 @@
+//-----------------------------------------------------------------------------
+/*
+
+Material Profile Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package material
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/deadsy/sdfx/drc"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Registry(t *testing.T) {
+	m, err := Get("pla")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != PLA {
+		t.Errorf("expected %s, got %s", PLA, m)
+	}
+	if _, err := Get("unobtainium"); err == nil {
+		t.Error("expected an error for an unknown material")
+	}
+
+	// a registered profile replaces the profile of the same name
+	pla := &Profile{"PLA", "FDM", 1.25, 0.002, 0.8, 1.0, 0.2, 20}
+	Register(pla)
+	defer Register(PLA)
+	if m, _ := Get("PLA"); m != pla {
+		t.Errorf("expected the replacement profile, got %+v", m)
+	}
+	names := Names()
+	if len(names) != 7 {
+		t.Errorf("expected 7 names, got %v", names)
+	}
+	for i := 1; i < len(names); i++ {
+		if names[i-1] >= names[i] {
+			t.Errorf("names are not sorted %v", names)
+		}
+	}
+}
+
+func Test_RegistryConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("test%d", i)
+			Register(&Profile{Name: name, Density: 1})
+			if _, err := Get(name); err != nil {
+				t.Error(err)
+			}
+			Names()
+		}(i)
+	}
+	wg.Wait()
+	registryLock.Lock()
+	for i := 0; i < 8; i++ {
+		delete(registry, fmt.Sprintf("test%d", i))
+	}
+	registryLock.Unlock()
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Compensate(t *testing.T) {
+	s, _ := sdf.Box3D(v3.Vec{10, 20, 30}, 0)
+	if Aluminum.Compensate(s) != s {
+		t.Error("expected no compensation without shrinkage")
+	}
+	c := ABS.Compensate(s)
+	// the part shrinks back to the modelled size
+	size := c.BoundingBox().Size().MulScalar(1 - ABS.Shrinkage)
+	if size.Sub(v3.Vec{10, 20, 30}).Length() > 1e-9 {
+		t.Errorf("expected compensated size %v, got %v", v3.Vec{10, 20, 30}, size)
+	}
+	if d := c.Evaluate(v3.Vec{5 * ABS.ScaleFactor(), 0, 0}); math.Abs(d) > 1e-9 {
+		t.Errorf("expected the scaled face at distance 0, got %g", d)
+	}
+}
+
+func Test_PartMass(t *testing.T) {
+	// 10 cm^3 of aluminum
+	s, _ := sdf.Box3D(v3.Vec{10, 20, 50}, 0)
+	if m := Aluminum.PartMass(s, 100); math.Abs(m-27) > 0.3 {
+		t.Errorf("expected a mass of ~27g, got %g", m)
+	}
+	if m := PLA.Mass(1000); math.Abs(m-1.24) > 1e-9 {
+		t.Errorf("expected a mass of 1.24g, got %g", m)
+	}
+}
+
+func Test_Rules(t *testing.T) {
+	thick, _ := sdf.Box3D(v3.Vec{10, 10, 10}, 0)
+	thin, _ := sdf.Box3D(v3.Vec{10, 10, 0.6}, 0)
+	thin = sdf.Transform3D(thin, sdf.Translate3d(v3.Vec{0, 0, 5.5}))
+	m := drc.NewModel()
+	m.Cells = 40
+	m.AddPart("thick", thick)
+	m.AddPart("thin", thin)
+
+	// 0.6 mm is thick enough for resin, not for PLA
+	if r := drc.Check(m, PLA.Rules("thin")...); r.Passed() {
+		t.Errorf("expected a PLA wall violation\n%s", r)
+	}
+	if r := drc.Check(m, Resin.Rules("thin")...); !r.Passed() {
+		t.Errorf("unexpected resin wall violation\n%s", r)
+	}
+	if r := drc.Check(m, PLA.Rules("thick")...); !r.Passed() {
+		t.Errorf("unexpected PLA wall violation\n%s", r)
+	}
+
+	// the parts are 0.2 mm apart
+	if r := drc.Check(m, PETG.ClearanceRule("thick", "thin")); r.Passed() {
+		t.Errorf("expected a PETG clearance violation\n%s", r)
+	}
+	if r := drc.Check(m, Resin.ClearanceRule("thick", "thin")); !r.Passed() {
+		t.Errorf("unexpected resin clearance violation\n%s", r)
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,110 @@
+//-----------------------------------------------------------------------------
+/*
+
+Band Channels
+
+Cutters for recessing zip ties and hose clamps into printed mounts.
+The cutter is the volume of a band of rectangular cross-section wrapped
+around a cylinder or around an arbitrary region. Subtract it from the
+mount to leave a channel for the band.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// BandParms defines the parameters for a zip tie or hose clamp band.
+type BandParms struct {
+	Width     float64 // band width
+	Thickness float64 // band thickness
+	Clearance float64 // clearance added to the width and thickness of the channel
+}
+
+// ZipTie returns the band parameters for common zip tie sizes (width in mm).
+func ZipTie(width float64) (*BandParms, error) {
+	// width, thickness
+	sizes := map[float64]float64{
+		2.5: 1.0,
+		3.6: 1.2,
+		4.8: 1.3,
+		7.6: 1.8,
+		9.0: 2.0,
+	}
+	t, ok := sizes[width]
+	if !ok {
+		return nil, sdf.ErrMsg("unknown zip tie width")
+	}
+	return &BandParms{
+		Width:     width,
+		Thickness: t,
+		Clearance: 0.3,
+	}, nil
+}
+
+func (k *BandParms) validate() error {
+	if k.Width <= 0 {
+		return sdf.ErrMsg("Width <= 0")
+	}
+	if k.Thickness <= 0 {
+		return sdf.ErrMsg("Thickness <= 0")
+	}
+	if k.Clearance < 0 {
+		return sdf.ErrMsg("Clearance < 0")
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// CylinderBand3D returns the cutter for a band wrapped around a cylinder.
+// The cylinder is on the z-axis and the band is centered on the z = 0 plane.
+func CylinderBand3D(k *BandParms, radius float64) (sdf.SDF3, error) {
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+	if radius <= 0 {
+		return nil, sdf.ErrMsg("radius <= 0")
+	}
+	t := k.Thickness + k.Clearance
+	w := k.Width + 2*k.Clearance
+	s := sdf.Box2D(v2.Vec{t, w}, 0)
+	s = sdf.Transform2D(s, sdf.Translate2d(v2.Vec{radius + 0.5*t, 0}))
+	return sdf.Revolve3D(s)
+}
+
+// Band3D returns the cutter for a band wrapped around an arbitrary region.
+// The band follows the surface of the region and is masked to a slab centered
+// on the plane through p with normal n.
+func Band3D(k *BandParms, s sdf.SDF3, p, n v3.Vec) (sdf.SDF3, error) {
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, sdf.ErrMsg("s == nil")
+	}
+	if n.Length() == 0 {
+		return nil, sdf.ErrMsg("n == 0")
+	}
+	n = n.Normalize()
+	// the region distance field may not be exact (e.g. smooth unions)
+	outer, err := sdf.CorrectedOffset3D(s, k.Thickness+k.Clearance)
+	if err != nil {
+		return nil, err
+	}
+	band := sdf.Difference3D(outer, s)
+	// mask the band to the slab
+	h := 0.5*k.Width + k.Clearance
+	band = sdf.Cut3D(band, p.Sub(n.MulScalar(h)), n)
+	band = sdf.Cut3D(band, p.Add(n.MulScalar(h)), n.Neg())
+	return band, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,285 @@
+//-----------------------------------------------------------------------------
+/*
+
+Dials, Scales and Pointers
+
+Graduated scales for instrument dials, knob skirts, gauge faces and rulers.
+The 2d outlines can be extruded for embossing or subtracted for engraving.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/golang/freetype/truetype"
+)
+
+//-----------------------------------------------------------------------------
+
+// GraduationParms defines the tick marks and numerals of a scale.
+type GraduationParms struct {
+	Divisions  int            // number of minor divisions
+	MajorEvery int            // minor divisions per major graduation
+	MidEvery   int            // minor divisions per mid graduation (0 = none)
+	Major      [2]float64     // length/width of major tick marks
+	Mid        [2]float64     // length/width of mid tick marks
+	Minor      [2]float64     // length/width of minor tick marks
+	Font       *truetype.Font // font for the numerals (nil = no numerals)
+	TextHeight float64        // height of the numerals
+	Start      float64        // value of the first major graduation
+	Step       float64        // value increment per major graduation
+	Format     string         // numeral format (default "%g")
+}
+
+// tick returns the length/width of the tick mark for the i-th graduation.
+func (k *GraduationParms) tick(i int) ([2]float64, bool) {
+	if i%k.MajorEvery == 0 {
+		return k.Major, true
+	}
+	if k.MidEvery > 0 && i%k.MidEvery == 0 {
+		return k.Mid, false
+	}
+	return k.Minor, false
+}
+
+// numeral returns the numeral for the i-th graduation.
+func (k *GraduationParms) numeral(i int) (sdf.SDF2, error) {
+	format := k.Format
+	if format == "" {
+		format = "%g"
+	}
+	s := fmt.Sprintf(format, k.Start+float64(i/k.MajorEvery)*k.Step)
+	return sdf.Text2D(k.Font, sdf.NewText(s), k.TextHeight)
+}
+
+// validate checks the graduation parameters.
+func (k *GraduationParms) validate() error {
+	if k.Divisions <= 0 {
+		return sdf.ErrMsg("Divisions <= 0")
+	}
+	if k.MajorEvery <= 0 {
+		return sdf.ErrMsg("MajorEvery <= 0")
+	}
+	if k.MidEvery < 0 {
+		return sdf.ErrMsg("MidEvery < 0")
+	}
+	for _, t := range [][2]float64{k.Major, k.Mid, k.Minor} {
+		if t[0] < 0 || t[1] < 0 {
+			return sdf.ErrMsg("tick length/width < 0")
+		}
+	}
+	if k.Major[0] == 0 || k.Major[1] == 0 {
+		return sdf.ErrMsg("major tick length/width == 0")
+	}
+	if k.Font != nil && k.TextHeight <= 0 {
+		return sdf.ErrMsg("TextHeight <= 0")
+	}
+	return nil
+}
+
+// tickMark returns a tick mark along the x-axis from x = -length to x = 0.
+func tickMark(t [2]float64) sdf.SDF2 {
+	if t[0] == 0 || t[1] == 0 {
+		return nil
+	}
+	s := sdf.Box2D(v2.Vec{t[0], t[1]}, 0)
+	return sdf.Transform2D(s, sdf.Translate2d(v2.Vec{-0.5 * t[0], 0}))
+}
+
+//-----------------------------------------------------------------------------
+// Dials
+
+// DialParms defines the parameters for a circular dial.
+type DialParms struct {
+	Graduations GraduationParms // tick marks and numerals
+	Radius      float64         // outer radius of the tick marks
+	StartAngle  float64         // angle of the first graduation (degrees, anticlockwise from +x)
+	Sweep       float64         // angle from the first to the last graduation (degrees, +ve is clockwise)
+	TextRadius  float64         // radius of the numeral centers
+	Thickness   float64         // thickness (3d only)
+}
+
+// Dial2D returns the tick marks and numerals of a circular dial centered on the origin.
+func Dial2D(k *DialParms) (sdf.SDF2, error) {
+	g := &k.Graduations
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+	if k.Radius <= g.Major[0] {
+		return nil, sdf.ErrMsg("Radius <= major tick length")
+	}
+	if k.Sweep == 0 || math.Abs(k.Sweep) > 360 {
+		return nil, sdf.ErrMsg("Sweep must be non-zero and <= 360 degrees")
+	}
+	if g.Font != nil && k.TextRadius <= 0 {
+		return nil, sdf.ErrMsg("TextRadius <= 0")
+	}
+
+	n := g.Divisions + 1
+	if math.Abs(k.Sweep) == 360 {
+		// the last graduation is the first graduation
+		n--
+	}
+
+	var ss []sdf.SDF2
+	for i := 0; i < n; i++ {
+		theta := sdf.DtoR(k.StartAngle - k.Sweep*float64(i)/float64(g.Divisions))
+		t, major := g.tick(i)
+		if s := tickMark(t); s != nil {
+			m := sdf.Rotate2d(theta).Mul(sdf.Translate2d(v2.Vec{k.Radius, 0}))
+			ss = append(ss, sdf.Transform2D(s, m))
+		}
+		if major && g.Font != nil {
+			s, err := g.numeral(i)
+			if err != nil {
+				return nil, err
+			}
+			p := v2.Vec{math.Cos(theta), math.Sin(theta)}.MulScalar(k.TextRadius)
+			ss = append(ss, sdf.Transform2D(s, sdf.Translate2d(p)))
+		}
+	}
+	return sdf.Union2D(ss...), nil
+}
+
+// Dial3D returns the tick marks and numerals of a circular dial extruded from z = 0 to z = Thickness.
+func Dial3D(k *DialParms) (sdf.SDF3, error) {
+	if k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("Thickness <= 0")
+	}
+	s, err := Dial2D(k)
+	if err != nil {
+		return nil, err
+	}
+	return extrudeMarks(s, k.Thickness), nil
+}
+
+//-----------------------------------------------------------------------------
+// Rulers
+
+// RulerParms defines the parameters for a linear scale.
+type RulerParms struct {
+	Graduations GraduationParms // tick marks and numerals
+	Length      float64         // distance from the first to the last graduation
+	TextOffset  float64         // y offset of the numeral centers
+	Thickness   float64         // thickness (3d only)
+}
+
+// Ruler2D returns the tick marks and numerals of a linear scale.
+// The graduations run along the x-axis from 0 to Length with the
+// tick marks extending from y = 0 in the +y direction.
+func Ruler2D(k *RulerParms) (sdf.SDF2, error) {
+	g := &k.Graduations
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+	if k.Length <= 0 {
+		return nil, sdf.ErrMsg("Length <= 0")
+	}
+
+	var ss []sdf.SDF2
+	for i := 0; i <= g.Divisions; i++ {
+		x := k.Length * float64(i) / float64(g.Divisions)
+		t, major := g.tick(i)
+		if s := tickMark(t); s != nil {
+			m := sdf.Translate2d(v2.Vec{x, 0}).Mul(sdf.Rotate2d(-0.5 * sdf.Pi))
+			ss = append(ss, sdf.Transform2D(s, m))
+		}
+		if major && g.Font != nil {
+			s, err := g.numeral(i)
+			if err != nil {
+				return nil, err
+			}
+			ss = append(ss, sdf.Transform2D(s, sdf.Translate2d(v2.Vec{x, k.TextOffset})))
+		}
+	}
+	return sdf.Union2D(ss...), nil
+}
+
+// Ruler3D returns the tick marks and numerals of a linear scale extruded from z = 0 to z = Thickness.
+func Ruler3D(k *RulerParms) (sdf.SDF3, error) {
+	if k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("Thickness <= 0")
+	}
+	s, err := Ruler2D(k)
+	if err != nil {
+		return nil, err
+	}
+	return extrudeMarks(s, k.Thickness), nil
+}
+
+//-----------------------------------------------------------------------------
+// Pointers
+
+// PointerParms defines the parameters for a dial pointer.
+type PointerParms struct {
+	Length    float64 // length from the pivot to the tip
+	Tail      float64 // length of the tail behind the pivot
+	Width     float64 // width at the pivot
+	HubRadius float64 // radius of the hub at the pivot
+	Thickness float64 // thickness (3d only)
+}
+
+// Pointer2D returns a tapered pointer pivoting on the origin and pointing along the +x axis.
+func Pointer2D(k *PointerParms) (sdf.SDF2, error) {
+	if k.Length <= 0 {
+		return nil, sdf.ErrMsg("Length <= 0")
+	}
+	if k.Tail < 0 {
+		return nil, sdf.ErrMsg("Tail < 0")
+	}
+	if k.Width <= 0 {
+		return nil, sdf.ErrMsg("Width <= 0")
+	}
+	if k.HubRadius < 0 {
+		return nil, sdf.ErrMsg("HubRadius < 0")
+	}
+	w := 0.5 * k.Width
+	p := sdf.NewPolygon()
+	p.Add(k.Length, 0)
+	p.Add(0, w)
+	if k.Tail > 0 {
+		p.Add(-k.Tail, w)
+		p.Add(-k.Tail, -w)
+	}
+	p.Add(0, -w)
+	s, err := sdf.Polygon2D(p.Vertices())
+	if err != nil {
+		return nil, err
+	}
+	if k.HubRadius > 0 {
+		hub, err := sdf.Circle2D(k.HubRadius)
+		if err != nil {
+			return nil, err
+		}
+		s = sdf.Union2D(s, hub)
+	}
+	return s, nil
+}
+
+// Pointer3D returns a tapered pointer extruded from z = 0 to z = Thickness.
+func Pointer3D(k *PointerParms) (sdf.SDF3, error) {
+	if k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("Thickness <= 0")
+	}
+	s, err := Pointer2D(k)
+	if err != nil {
+		return nil, err
+	}
+	return extrudeMarks(s, k.Thickness), nil
+}
+
+//-----------------------------------------------------------------------------
+
+// extrudeMarks extrudes a 2d outline from z = 0 to z = h.
+func extrudeMarks(s sdf.SDF2, h float64) sdf.SDF3 {
+	return sdf.Transform3D(sdf.Extrude3D(s, h), sdf.Translate3d(v3.Vec{0, 0, 0.5 * h}))
+}
+
+//-----------------------------------------------------------------------------
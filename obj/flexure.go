@@ -0,0 +1,353 @@
+//-----------------------------------------------------------------------------
+/*
+
+Compliant Mechanism Flexures
+
+Flexure elements for printable precision mechanisms:
+
+Leaf flexure: a thin beam between two mounting blocks.
+Cross pivot: two leaves crossing at their midpoints (a planar cross axis pivot).
+Parallel stage: two parallel leaves guiding a stage in a straight(ish) line.
+Clip: a cantilever snap clip with a hook.
+
+The stiffness estimates are from small deflection beam formulas with the
+leaves clamped at both ends (the clip is a cantilever). The modulus is
+Young's modulus in MPa (N/mm^2), giving stiffness in N/mm and N.mm/rad.
+Printed parts are anisotropic, so treat the estimates as a starting point.
+
+The leaves lie along x in the xy plane, bend in the y direction, and are
+Width thick in z (the print direction). Parts are centered on z = 0.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// FlexureStiffness is an estimate of the stiffness of a flexure.
+type FlexureStiffness struct {
+	Translation float64 // in the compliant direction (N/mm), 0 if not applicable
+	Rotation    float64 // about the compliant axis (N.mm/rad), 0 if not applicable
+	Axial       float64 // along the leaves (N/mm)
+}
+
+// FlexureLeaf is the beam of a flexure.
+type FlexureLeaf struct {
+	Length    float64 // free length of the leaf
+	Thickness float64 // thickness in the bending direction
+	Width     float64 // width (z)
+}
+
+// validate checks the leaf dimensions.
+func (k *FlexureLeaf) validate() error {
+	if k.Length <= 0 {
+		return sdf.ErrMsg("leaf Length <= 0")
+	}
+	if k.Thickness <= 0 || k.Thickness >= k.Length {
+		return sdf.ErrMsg("leaf Thickness must be 0..Length")
+	}
+	if k.Width <= 0 {
+		return sdf.ErrMsg("leaf Width <= 0")
+	}
+	return nil
+}
+
+// secondMoment returns the second moment of area for bending.
+func (k *FlexureLeaf) secondMoment() float64 {
+	return k.Width * k.Thickness * k.Thickness * k.Thickness / 12
+}
+
+// axial returns the axial stiffness of the leaf.
+func (k *FlexureLeaf) axial(modulus float64) float64 {
+	return modulus * k.Width * k.Thickness / k.Length
+}
+
+// Strain returns the maximum strain in the leaf for a guided (s-shaped) deflection.
+func (k *FlexureLeaf) Strain(deflection float64) float64 {
+	return 3 * k.Thickness * deflection / (k.Length * k.Length)
+}
+
+// beam returns the leaf as a box from (0, 0) to (Length, 0) with an overlap at each end.
+func (k *FlexureLeaf) beam(overlap float64) (sdf.SDF3, error) {
+	l := k.Length + 2*overlap
+	s, err := sdf.Box3D(v3.Vec{l, k.Thickness, k.Width}, 0)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Transform3D(s, sdf.Translate3d(v3.Vec{0.5 * k.Length, 0, 0})), nil
+}
+
+// flexureBlock returns a block of a given xy size centered on a point.
+func flexureBlock(size v2.Vec, width float64, center v2.Vec) (sdf.SDF3, error) {
+	s, err := sdf.Box3D(v3.Vec{size.X, size.Y, width}, 0)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Transform3D(s, sdf.Translate3d(v3.Vec{center.X, center.Y, 0})), nil
+}
+
+// flexureUnion returns the union of the blocks and leaves with optional root fillets.
+func flexureUnion(fillet float64, s ...sdf.SDF3) (sdf.SDF3, error) {
+	if fillet < 0 {
+		return nil, sdf.ErrMsg("Fillet < 0")
+	}
+	u := sdf.Union3D(s...)
+	if fillet > 0 {
+		u.(*sdf.UnionSDF3).SetMin(sdf.PolyMin(fillet))
+	}
+	return u, nil
+}
+
+//-----------------------------------------------------------------------------
+// Leaf Flexure
+
+// LeafFlexureParms defines the parameters for a leaf flexure.
+type LeafFlexureParms struct {
+	Leaf   FlexureLeaf
+	Block  v2.Vec  // xy size of the mounting blocks at each end
+	Fillet float64 // root fillet radius
+}
+
+// LeafFlexure3D returns a leaf flexure. The leaf runs from x = 0 to x = Length.
+func LeafFlexure3D(k *LeafFlexureParms) (sdf.SDF3, error) {
+	if err := k.Leaf.validate(); err != nil {
+		return nil, err
+	}
+	if k.Block.Y <= k.Leaf.Thickness || k.Block.X <= 0 {
+		return nil, sdf.ErrMsg("bad Block size")
+	}
+	leaf, err := k.Leaf.beam(0.5 * k.Block.X)
+	if err != nil {
+		return nil, err
+	}
+	b0, err := flexureBlock(k.Block, k.Leaf.Width, v2.Vec{-0.5 * k.Block.X, 0})
+	if err != nil {
+		return nil, err
+	}
+	b1, err := flexureBlock(k.Block, k.Leaf.Width, v2.Vec{k.Leaf.Length + 0.5*k.Block.X, 0})
+	if err != nil {
+		return nil, err
+	}
+	return flexureUnion(k.Fillet, b0, b1, leaf)
+}
+
+// Stiffness returns the stiffness of a leaf flexure.
+// Translation is for a guided end, rotation is for a free (moment loaded) end.
+func (k *LeafFlexureParms) Stiffness(modulus float64) FlexureStiffness {
+	ei := modulus * k.Leaf.secondMoment()
+	l := k.Leaf.Length
+	return FlexureStiffness{
+		Translation: 12 * ei / (l * l * l),
+		Rotation:    ei / l,
+		Axial:       k.Leaf.axial(modulus),
+	}
+}
+
+//-----------------------------------------------------------------------------
+// Cross Pivot
+
+// CrossPivotParms defines the parameters for a cross axis pivot.
+type CrossPivotParms struct {
+	Leaf   FlexureLeaf
+	Angle  float64 // angle between the leaves (degrees)
+	Block  v2.Vec  // xy size of the base (bottom) and the moving (top) blocks
+	Fillet float64 // root fillet radius
+}
+
+// CrossPivot3D returns a cross axis pivot. The pivot axis is the z axis.
+// The base block is below (-y) and the moving block is above (+y) the pivot.
+func CrossPivot3D(k *CrossPivotParms) (sdf.SDF3, error) {
+	if err := k.Leaf.validate(); err != nil {
+		return nil, err
+	}
+	if k.Angle < 20 || k.Angle > 160 {
+		return nil, sdf.ErrMsg("Angle must be 20..160 degrees")
+	}
+	half := 0.5 * sdf.DtoR(k.Angle)
+	// the leaf ends are at +/- (dx, dy) from the pivot
+	dx := 0.5 * k.Leaf.Length * math.Sin(half)
+	dy := 0.5 * k.Leaf.Length * math.Cos(half)
+	if k.Block.Y <= 0 || k.Block.X < 2*(dx+0.5*k.Block.Y*math.Tan(half))+k.Leaf.Thickness {
+		return nil, sdf.ErrMsg("bad Block size")
+	}
+	overlap := 0.5 * k.Block.Y / math.Cos(half)
+	var parts []sdf.SDF3
+	for _, sign := range []float64{1, -1} {
+		leaf, err := k.Leaf.beam(overlap)
+		if err != nil {
+			return nil, err
+		}
+		// center the leaf on the pivot and tilt it from the y axis
+		m := sdf.RotateZ(0.5*sdf.Pi - sign*half).Mul(sdf.Translate3d(v3.Vec{-0.5 * k.Leaf.Length, 0, 0}))
+		parts = append(parts, sdf.Transform3D(leaf, m))
+	}
+	for _, sign := range []float64{1, -1} {
+		b, err := flexureBlock(k.Block, k.Leaf.Width, v2.Vec{0, sign * (dy + 0.5*k.Block.Y)})
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, b)
+	}
+	return flexureUnion(k.Fillet, parts...)
+}
+
+// Stiffness returns the rotational stiffness of a cross axis pivot.
+func (k *CrossPivotParms) Stiffness(modulus float64) FlexureStiffness {
+	ei := modulus * k.Leaf.secondMoment()
+	// each leaf bends into a circular arc: M = EI/L * theta
+	return FlexureStiffness{
+		Rotation: 2 * ei / k.Leaf.Length,
+		Axial:    2 * k.Leaf.axial(modulus) * math.Cos(0.5*sdf.DtoR(k.Angle)),
+	}
+}
+
+//-----------------------------------------------------------------------------
+// Parallel Guiding Stage
+
+// ParallelStageParms defines the parameters for a parallel guiding stage.
+type ParallelStageParms struct {
+	Leaf       FlexureLeaf
+	Separation float64 // distance between the leaf centerlines
+	Block      float64 // x length of the base and stage blocks
+	Fillet     float64 // root fillet radius
+}
+
+// ParallelStage3D returns a parallel guiding stage. The base block is at x < 0,
+// the stage block is at x > Length and the stage moves along y.
+func ParallelStage3D(k *ParallelStageParms) (sdf.SDF3, error) {
+	if err := k.Leaf.validate(); err != nil {
+		return nil, err
+	}
+	if k.Separation <= 2*k.Leaf.Thickness {
+		return nil, sdf.ErrMsg("Separation <= 2 * Thickness")
+	}
+	if k.Block <= 0 {
+		return nil, sdf.ErrMsg("Block <= 0")
+	}
+	size := v2.Vec{k.Block, k.Separation + k.Leaf.Thickness}
+	var parts []sdf.SDF3
+	for _, y := range []float64{-0.5 * k.Separation, 0.5 * k.Separation} {
+		leaf, err := k.Leaf.beam(0.5 * k.Block)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, sdf.Transform3D(leaf, sdf.Translate3d(v3.Vec{0, y, 0})))
+	}
+	for _, x := range []float64{-0.5 * k.Block, k.Leaf.Length + 0.5*k.Block} {
+		b, err := flexureBlock(size, k.Leaf.Width, v2.Vec{x, 0})
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, b)
+	}
+	return flexureUnion(k.Fillet, parts...)
+}
+
+// Stiffness returns the stiffness of a parallel guiding stage.
+func (k *ParallelStageParms) Stiffness(modulus float64) FlexureStiffness {
+	ei := modulus * k.Leaf.secondMoment()
+	l := k.Leaf.Length
+	return FlexureStiffness{
+		Translation: 24 * ei / (l * l * l),
+		Axial:       2 * k.Leaf.axial(modulus),
+	}
+}
+
+//-----------------------------------------------------------------------------
+// Compliant Clip
+
+// ClipParms defines the parameters for a cantilever snap clip.
+type ClipParms struct {
+	Leaf        FlexureLeaf
+	Hook        float64 // hook depth (the clip deflection on insertion)
+	LeadAngle   float64 // insertion face angle from the leaf axis (degrees)
+	RetainAngle float64 // retaining face angle from the leaf axis (degrees, 90 = permanent)
+	Base        v2.Vec  // xy size of the base block
+	Fillet      float64 // root fillet radius
+}
+
+// Clip3D returns a cantilever snap clip. The leaf runs from the base at x = 0
+// to x = Length and the hook points to +y.
+func Clip3D(k *ClipParms) (sdf.SDF3, error) {
+	if err := k.Leaf.validate(); err != nil {
+		return nil, err
+	}
+	if k.Hook <= 0 {
+		return nil, sdf.ErrMsg("Hook <= 0")
+	}
+	if k.LeadAngle <= 0 || k.LeadAngle >= 90 {
+		return nil, sdf.ErrMsg("LeadAngle must be 0..90 degrees")
+	}
+	if k.RetainAngle <= 0 || k.RetainAngle > 90 {
+		return nil, sdf.ErrMsg("RetainAngle must be 0..90 degrees")
+	}
+	if k.Base.X <= 0 || k.Base.Y <= k.Leaf.Thickness {
+		return nil, sdf.ErrMsg("bad Base size")
+	}
+	lead := k.Hook / math.Tan(sdf.DtoR(k.LeadAngle))
+	retain := k.Hook / math.Tan(sdf.DtoR(k.RetainAngle))
+	if lead+retain > k.Leaf.Length {
+		return nil, sdf.ErrMsg("hook is longer than the leaf")
+	}
+	leaf, err := k.Leaf.beam(0.5 * k.Base.X)
+	if err != nil {
+		return nil, err
+	}
+	base, err := flexureBlock(k.Base, k.Leaf.Width, v2.Vec{-0.5 * k.Base.X, 0})
+	if err != nil {
+		return nil, err
+	}
+	// hook profile on the +y face of the leaf tip
+	t := 0.5 * k.Leaf.Thickness
+	l := k.Leaf.Length
+	p := sdf.NewPolygon()
+	p.Add(l, -t)
+	p.Add(l, t)
+	p.Add(l-lead, t+k.Hook)
+	p.Add(l-lead-retain, t)
+	p.Add(l-lead-retain, -t)
+	profile, err := sdf.Polygon2D(p.Vertices())
+	if err != nil {
+		return nil, err
+	}
+	hook := sdf.Extrude3D(profile, k.Leaf.Width)
+	s, err := flexureUnion(k.Fillet, base, leaf)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Union3D(s, hook), nil
+}
+
+// Stiffness returns the tip stiffness of a snap clip.
+func (k *ClipParms) Stiffness(modulus float64) FlexureStiffness {
+	ei := modulus * k.Leaf.secondMoment()
+	l := k.Leaf.Length
+	return FlexureStiffness{
+		Translation: 3 * ei / (l * l * l),
+		Axial:       k.Leaf.axial(modulus),
+	}
+}
+
+// Strain returns the maximum strain in the clip leaf when it deflects by the hook depth.
+func (k *ClipParms) Strain() float64 {
+	l := k.Leaf.Length
+	return 1.5 * k.Leaf.Thickness * k.Hook / (l * l)
+}
+
+// InsertionForce returns the force to push the clip in, for a friction coefficient mu.
+func (k *ClipParms) InsertionForce(modulus, mu float64) float64 {
+	f := k.Stiffness(modulus).Translation * k.Hook
+	a := math.Tan(sdf.DtoR(k.LeadAngle))
+	return f * (mu + a) / math.Max(1-mu*a, 1e-6)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,152 @@
+//-----------------------------------------------------------------------------
+/*
+
+Standard Mounting Hole Patterns
+
+Named hole patterns for common mounting standards. The holes are centered
+on the origin.
+
+VESA: https://en.wikipedia.org/wiki/Flat_Display_Mounting_Interface
+NEMA: stepper motor face plates (hole spacing and pilot diameter)
+Servo: mounting lug holes of the servos in the servo database
+Drives: bottom mounting holes of 2.5" and 3.5" drives (SFF-8201, SFF-8301)
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// MountPattern is a named pattern of mounting holes.
+type MountPattern struct {
+	Name          string   // name of the pattern
+	Holes         []v2.Vec // hole positions
+	HoleDiameter  float64  // clearance hole diameter
+	PilotDiameter float64  // center pilot/boss hole diameter (0 = none)
+}
+
+type mountPatternDatabase map[string]*MountPattern
+
+var mountPatternDB = initMountPatternLookup()
+
+// rectangle returns the holes at the corners of a rectangle.
+func rectangle(x, y float64) []v2.Vec {
+	x *= 0.5
+	y *= 0.5
+	return []v2.Vec{{-x, -y}, {x, -y}, {x, y}, {-x, y}}
+}
+
+// Add adds a mounting pattern to the database.
+func (m mountPatternDatabase) Add(name string, holes []v2.Vec, holeDiameter, pilotDiameter float64) {
+	m[name] = &MountPattern{
+		Name:          name,
+		Holes:         holes,
+		HoleDiameter:  holeDiameter,
+		PilotDiameter: pilotDiameter,
+	}
+}
+
+// initMountPatternLookup adds a collection of standard mounting patterns to the database.
+func initMountPatternLookup() mountPatternDatabase {
+	m := make(mountPatternDatabase)
+
+	// VESA (M4 for <= 200x100, M6 above)
+	m.Add("vesa_50", rectangle(50, 50), 4.5, 0)
+	m.Add("vesa_75", rectangle(75, 75), 4.5, 0)
+	m.Add("vesa_100", rectangle(100, 100), 4.5, 0)
+	m.Add("vesa_200x100", rectangle(200, 100), 4.5, 0)
+	m.Add("vesa_200", rectangle(200, 200), 6.6, 0)
+	m.Add("vesa_300", rectangle(300, 300), 6.6, 0)
+	m.Add("vesa_400", rectangle(400, 400), 6.6, 0)
+
+	// NEMA stepper motor faces
+	m.Add("nema_8", rectangle(16, 16), 2.4, 15)
+	m.Add("nema_11", rectangle(23, 23), 2.6, 22)
+	m.Add("nema_14", rectangle(26, 26), 3.4, 22)
+	m.Add("nema_17", rectangle(31, 31), 3.4, 22)
+	m.Add("nema_23", rectangle(47.14, 47.14), 5.2, 38.1)
+	m.Add("nema_34", rectangle(69.6, 69.6), 6.6, 73)
+
+	// servo mounting lugs
+	for name, k := range servoDB {
+		m.Add("servo_"+name, rectangle(k.Hole.X, k.Hole.Y), 2*k.HoleRadius, 0)
+	}
+
+	// 2.5" drive bottom (M3)
+	m.Add("drive_2.5", rectangle(61.72, 76.6), 3.4, 0)
+	// 3.5" drive bottom (6-32 UNC), 3 pairs of holes
+	m.Add("drive_3.5", []v2.Vec{
+		{-47.625, -42.865}, {47.625, -42.865},
+		{-47.625, 1.585}, {47.625, 1.585},
+		{-47.625, 42.865}, {47.625, 42.865},
+	}, 3.8, 0)
+
+	return m
+}
+
+// MountPatternLookup returns a named mounting pattern.
+func MountPatternLookup(name string) (*MountPattern, error) {
+	k, ok := mountPatternDB[name]
+	if !ok {
+		return nil, fmt.Errorf("mount pattern \"%s\" not found", name)
+	}
+	return k, nil
+}
+
+// MountPatternNames returns the names of the mounting patterns in the database.
+func MountPatternNames() []string {
+	names := make([]string, 0, len(mountPatternDB))
+	for name := range mountPatternDB {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//-----------------------------------------------------------------------------
+
+// MountPattern2D returns the 2d holes of a mounting pattern.
+func MountPattern2D(k *MountPattern) (sdf.SDF2, error) {
+	if k.HoleDiameter <= 0 {
+		return nil, sdf.ErrMsg("HoleDiameter <= 0")
+	}
+	if k.PilotDiameter < 0 {
+		return nil, sdf.ErrMsg("PilotDiameter < 0")
+	}
+	hole, err := sdf.Circle2D(0.5 * k.HoleDiameter)
+	if err != nil {
+		return nil, err
+	}
+	s := sdf.Multi2D(hole, k.Holes)
+	if k.PilotDiameter > 0 {
+		pilot, err := sdf.Circle2D(0.5 * k.PilotDiameter)
+		if err != nil {
+			return nil, err
+		}
+		s = sdf.Union2D(s, pilot)
+	}
+	return s, nil
+}
+
+// MountPattern3D returns the 3d holes of a mounting pattern.
+func MountPattern3D(k *MountPattern, depth float64) (sdf.SDF3, error) {
+	if depth <= 0 {
+		return nil, sdf.ErrMsg("depth <= 0")
+	}
+	s, err := MountPattern2D(k)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Extrude3D(s, depth), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -38,6 +38,7 @@ type PanelParms struct {
 	HoleDiameter float64    // diameter of panel holes
 	HoleMargin   [4]float64 // hole margins for top, right, bottom, left
 	HolePattern  [4]string  // hole pattern for top, right, bottom, left
+	Mount        string     // named mounting pattern centered on the panel ("" = none)
 	Thickness    float64    // panel thickness (3d only)
 }
 
@@ -45,6 +46,20 @@ type PanelParms struct {
 func Panel2D(k *PanelParms) (sdf.SDF2, error) {
 	// panel
 	s0 := sdf.Box2D(k.Size, k.CornerRadius)
+
+	// mounting pattern
+	if k.Mount != "" {
+		mp, err := MountPatternLookup(k.Mount)
+		if err != nil {
+			return nil, err
+		}
+		mount, err := MountPattern2D(mp)
+		if err != nil {
+			return nil, err
+		}
+		s0 = sdf.Difference2D(s0, mount)
+	}
+
 	if k.HoleDiameter <= 0.0 {
 		// no holes
 		return s0, nil
@@ -143,14 +143,14 @@ func filterTabs(pattern string, tab rune) string {
 
 // PanelBoxParms defines the parameters for a 4 part panel box.
 type PanelBoxParms struct {
-	Size       v3.Vec  // outer box dimensions (width, height, length)
-	Wall       float64 // wall thickness
-	Panel      float64 // front/back panel thickness
-	Rounding   float64 // radius of corner rounding
-	FrontInset float64 // inset depth of box front
-	BackInset  float64 // inset depth of box back
-	Clearance  float64 // fit clearance (typically 0.05)
-	Hole       float64 // diameter of screw holes
+	Size       v3.Vec  `sdfx:"unit=mm"`       // outer box dimensions (width, height, length)
+	Wall       float64 `sdfx:"min=0,unit=mm"` // wall thickness
+	Panel      float64 `sdfx:"min=0,unit=mm"` // front/back panel thickness
+	Rounding   float64 `sdfx:"min=0,unit=mm"` // radius of corner rounding
+	FrontInset float64 `sdfx:"min=0,unit=mm"` // inset depth of box front
+	BackInset  float64 `sdfx:"min=0,unit=mm"` // inset depth of box back
+	Clearance  float64 `sdfx:"min=0,unit=mm"` // fit clearance (typically 0.05)
+	Hole       float64 `sdfx:"min=0,unit=mm"` // diameter of screw holes
 	SideTabs   string  // tab pattern b/B (bottom) t/T (top) . (empty)
 }
 
@@ -0,0 +1,372 @@
+//-----------------------------------------------------------------------------
+/*
+
+Pegboard and French Cleat Accessories
+
+Holders (hooks and rings) for tools and other objects, sized to the held
+object, on a back plate that hangs on a pegboard or a French cleat.
+
+The board (or wall) is in the x-z plane with its front face at y = 0.
+The back plate is in front of the board and the holder projects out along
++y. The z-axis is up and the bottom of the back plate is at z = 0.
+
+Boards:
+
+pegboard: 1/4" holes on a 1" grid in 1/4" hardboard
+pegboard_1/8: 3/16" holes on a 1" grid in 1/8" hardboard
+skadis: IKEA SKÅDIS, 5x15mm slots on a 40mm grid in 5mm board
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// box3D returns a box between two corners.
+func box3D(a, b v3.Vec) sdf.SDF3 {
+	s, _ := sdf.Box3D(b.Sub(a).Abs(), 0)
+	return sdf.Transform3D(s, sdf.Translate3d(a.Add(b).MulScalar(0.5)))
+}
+
+//-----------------------------------------------------------------------------
+// Holders
+
+// HolderStyle is the type of holder on an accessory.
+type HolderStyle int
+
+// Holder styles.
+const (
+	HolderHook HolderStyle = iota // straight hook(s) with an upturned tip
+	HolderRing                    // ring for a round object
+)
+
+// HolderParms defines the parameters for a holder.
+type HolderParms struct {
+	Style     HolderStyle
+	Diameter  float64 // diameter of the held object (ring)
+	Width     float64 // width of the held object between two hooks (0 = single hook)
+	Length    float64 // reach of a hook from the back plate
+	Thickness float64 // material thickness of the holder
+	Clearance float64 // clearance around the held object
+}
+
+// holder3D returns a holder attached to a back plate front face at y = 0.
+// The holder is at the bottom of the plate (z = 0) and centered on x = 0.
+func holder3D(k *HolderParms) (sdf.SDF3, error) {
+	if k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("holder Thickness <= 0")
+	}
+	if k.Clearance < 0 {
+		return nil, sdf.ErrMsg("holder Clearance < 0")
+	}
+	t := k.Thickness
+	switch k.Style {
+	case HolderHook:
+		if k.Length <= t {
+			return nil, sdf.ErrMsg("hook Length <= Thickness")
+		}
+		if k.Width < 0 {
+			return nil, sdf.ErrMsg("hook Width < 0")
+		}
+		// prong with an upturned tip
+		prong := sdf.Union3D(
+			box3D(v3.Vec{-0.5 * t, 0, 0}, v3.Vec{0.5 * t, k.Length, t}),
+			box3D(v3.Vec{-0.5 * t, k.Length - t, 0}, v3.Vec{0.5 * t, k.Length, 2.5 * t}),
+		)
+		if k.Width == 0 {
+			return prong, nil
+		}
+		x := 0.5*(k.Width+t) + k.Clearance
+		return sdf.Multi3D(prong, []v3.Vec{{-x, 0, 0}, {x, 0, 0}}), nil
+	case HolderRing:
+		if k.Diameter <= 0 {
+			return nil, sdf.ErrMsg("ring Diameter <= 0")
+		}
+		ri := 0.5*k.Diameter + k.Clearance
+		ro := ri + t
+		ring2d := sdf.Difference2D(
+			sdf.Union2D(
+				sdf.Box2D(v2.Vec{2 * ro, ro}, 0),
+				sdf.Transform2D(circle2D(ro), sdf.Translate2d(v2.Vec{0, 0.5 * ro})),
+			),
+			sdf.Transform2D(circle2D(ri), sdf.Translate2d(v2.Vec{0, 0.5 * ro})),
+		)
+		// the ring profile is in x-y with its back edge at y = 0
+		ring := extrudeMarks(sdf.Transform2D(ring2d, sdf.Translate2d(v2.Vec{0, 0.5 * ro})), t)
+		return ring, nil
+	}
+	return nil, sdf.ErrMsg("unknown holder style")
+}
+
+// circle2D returns a circle, the radius must be > 0.
+func circle2D(r float64) sdf.SDF2 {
+	s, _ := sdf.Circle2D(r)
+	return s
+}
+
+//-----------------------------------------------------------------------------
+// Pegboards
+
+// PegboardParms defines a pegboard system.
+type PegboardParms struct {
+	Name      string  // name of the board
+	Pitch     v2.Vec  // hole spacing in x and z
+	Hole      v2.Vec  // hole size in x and z
+	Round     bool    // round holes (diameter Hole.X) or slots
+	Thickness float64 // board thickness
+}
+
+type pegboardDatabase map[string]*PegboardParms
+
+var pegboardDB = initPegboardLookup()
+
+// Add adds a pegboard to the database.
+func (m pegboardDatabase) Add(k *PegboardParms) {
+	m[k.Name] = k
+}
+
+// initPegboardLookup adds a collection of pegboards to the database.
+func initPegboardLookup() pegboardDatabase {
+	m := make(pegboardDatabase)
+	m.Add(&PegboardParms{"pegboard", v2.Vec{25.4, 25.4}, v2.Vec{6.35, 6.35}, true, 6.35})
+	m.Add(&PegboardParms{"pegboard_1/8", v2.Vec{25.4, 25.4}, v2.Vec{4.76, 4.76}, true, 3.18})
+	m.Add(&PegboardParms{"skadis", v2.Vec{40, 40}, v2.Vec{5, 15}, false, 5})
+	return m
+}
+
+// PegboardLookup returns the parameters for a named pegboard.
+func PegboardLookup(name string) (*PegboardParms, error) {
+	k, ok := pegboardDB[name]
+	if !ok {
+		return nil, fmt.Errorf("pegboard \"%s\" not found", name)
+	}
+	return k, nil
+}
+
+// PegHolderParms defines the parameters for a pegboard accessory.
+type PegHolderParms struct {
+	Board     string      // pegboard name
+	Pegs      int         // number of pegs in each row
+	Thickness float64     // back plate thickness
+	Margin    float64     // back plate margin around the pegs
+	Clearance float64     // peg clearance in the board holes
+	Holder    HolderParms // holder on the front of the plate
+}
+
+// peg returns a peg that goes through the board (-y) and optionally turns up behind it
+// to lock the accessory in place. The peg is centered on the origin at the front face
+// of the board.
+func peg(b *PegboardParms, clearance float64, lock bool) sdf.SDF3 {
+	size := b.Hole.SubScalar(2 * clearance)
+	var profile sdf.SDF2
+	if b.Round {
+		profile = circle2D(0.5 * size.X)
+	} else {
+		profile = sdf.Box2D(size, 0.25*size.X)
+	}
+	depth := b.Thickness
+	if lock {
+		depth += clearance + size.X
+	}
+	// the profile is in x-z, extrude it along -y
+	s := sdf.Extrude3D(profile, depth)
+	s = sdf.Transform3D(s, sdf.Translate3d(v3.Vec{0, -0.5 * depth, 0}).Mul(sdf.RotateX(sdf.DtoR(90))))
+	if !lock {
+		return s
+	}
+	// turn up behind the board
+	up := box3D(
+		v3.Vec{-0.5 * size.X, -depth, 0},
+		v3.Vec{0.5 * size.X, -depth + size.X, 0.5*size.Y + b.Hole.Y},
+	)
+	return sdf.Union3D(s, up)
+}
+
+// PegHolder3D returns a pegboard accessory. The back plate has a row of locking pegs
+// at the top and a row of straight pegs one pitch below.
+func PegHolder3D(k *PegHolderParms) (sdf.SDF3, error) {
+	b, err := PegboardLookup(k.Board)
+	if err != nil {
+		return nil, err
+	}
+	if k.Pegs < 1 {
+		return nil, sdf.ErrMsg("Pegs < 1")
+	}
+	if k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("Thickness <= 0")
+	}
+	if k.Margin < 0 || k.Clearance < 0 {
+		return nil, sdf.ErrMsg("Margin/Clearance < 0")
+	}
+	if 2*k.Clearance >= math.Min(b.Hole.X, b.Hole.Y) {
+		return nil, sdf.ErrMsg("Clearance too large for the board holes")
+	}
+	// back plate
+	w := float64(k.Pegs-1)*b.Pitch.X + b.Hole.X + 2*k.Margin
+	h := b.Pitch.Y + b.Hole.Y + 2*k.Margin
+	plate := box3D(v3.Vec{-0.5 * w, 0, 0}, v3.Vec{0.5 * w, k.Thickness, h})
+	// pegs
+	zTop := h - k.Margin - 0.5*b.Hole.Y
+	var top, bottom v3.VecSet
+	for i := 0; i < k.Pegs; i++ {
+		x := (float64(i) - 0.5*float64(k.Pegs-1)) * b.Pitch.X
+		top = append(top, v3.Vec{x, 0, zTop})
+		bottom = append(bottom, v3.Vec{x, 0, zTop - b.Pitch.Y})
+	}
+	pegs := sdf.Union3D(
+		sdf.Multi3D(peg(b, k.Clearance, true), top),
+		sdf.Multi3D(peg(b, k.Clearance, false), bottom),
+	)
+	// holder
+	holder, err := holder3D(&k.Holder)
+	if err != nil {
+		return nil, err
+	}
+	holder = sdf.Transform3D(holder, sdf.Translate3d(v3.Vec{0, k.Thickness, 0}))
+	return sdf.Union3D(plate, pegs, holder), nil
+}
+
+//-----------------------------------------------------------------------------
+// French Cleats
+
+// FrenchCleatParms defines the parameters for a French cleat.
+type FrenchCleatParms struct {
+	Length        float64 // length of the cleat along x
+	Height        float64 // height of the cleat (at the front face)
+	Thickness     float64 // thickness of the cleat
+	Angle         float64 // bevel angle from horizontal (degrees, typically 45)
+	Clearance     float64 // clearance between the wall cleat and the mating cleat
+	Screws        int     // number of screw holes in the wall cleat
+	ScrewDiameter float64 // screw hole diameter
+}
+
+// extrudeX extrudes a 2d profile in the y-z plane along the x-axis (centered).
+func extrudeX(s sdf.SDF2, length float64) sdf.SDF3 {
+	// (a, b, e) -> (e, a, b)
+	m := sdf.RotateZ(sdf.DtoR(90)).Mul(sdf.RotateX(sdf.DtoR(90)))
+	return sdf.Transform3D(sdf.Extrude3D(s, length), m)
+}
+
+func (k *FrenchCleatParms) validate() error {
+	if k.Length <= 0 || k.Height <= 0 || k.Thickness <= 0 {
+		return sdf.ErrMsg("Length/Height/Thickness <= 0")
+	}
+	if k.Angle <= 0 || k.Angle >= 90 {
+		return sdf.ErrMsg("Angle must be 0..90")
+	}
+	if k.Clearance < 0 {
+		return sdf.ErrMsg("Clearance < 0")
+	}
+	if k.Thickness*math.Tan(sdf.DtoR(k.Angle)) >= k.Height {
+		return sdf.ErrMsg("bevel is taller than the cleat")
+	}
+	return nil
+}
+
+// bevel returns the rise of the bevel across the cleat thickness.
+func (k *FrenchCleatParms) bevel() float64 {
+	return k.Thickness * math.Tan(sdf.DtoR(k.Angle))
+}
+
+// FrenchCleat3D returns a wall cleat. The back is against the wall at y = 0,
+// the bottom is at z = 0 and the top is beveled down towards the wall.
+func FrenchCleat3D(k *FrenchCleatParms) (sdf.SDF3, error) {
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+	t, h, b := k.Thickness, k.Height, k.bevel()
+	p := sdf.NewPolygon()
+	p.Add(0, 0)
+	p.Add(t, 0)
+	p.Add(t, h)
+	p.Add(0, h-b)
+	s2, err := sdf.Polygon2D(p.Vertices())
+	if err != nil {
+		return nil, err
+	}
+	s := extrudeX(s2, k.Length)
+	if k.Screws > 0 {
+		if k.ScrewDiameter <= 0 {
+			return nil, sdf.ErrMsg("ScrewDiameter <= 0")
+		}
+		hole, err := CounterSunkHole3D(t, 0.5*k.ScrewDiameter)
+		if err != nil {
+			return nil, err
+		}
+		// countersunk on the front face, along y
+		hole = sdf.Transform3D(hole, sdf.RotateX(sdf.DtoR(-90)))
+		var ps v3.VecSet
+		for i := 0; i < k.Screws; i++ {
+			x := (float64(i)+0.5)/float64(k.Screws)*k.Length - 0.5*k.Length
+			ps = append(ps, v3.Vec{x, 0.5 * t, 0.5 * (h - b)})
+		}
+		s = sdf.Difference3D(s, sdf.Multi3D(hole, ps))
+	}
+	return s, nil
+}
+
+// CleatMate3D returns the mating cleat in its hanging position on the wall cleat.
+// It sits on the wall cleat bevel (less the clearance) and its front face is at y = Thickness.
+func CleatMate3D(k *FrenchCleatParms) (sdf.SDF3, error) {
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+	t, h, b := k.Thickness, k.Height, k.bevel()
+	c := k.Clearance
+	p := sdf.NewPolygon()
+	p.Add(c, h-b+c*b/t+c)
+	p.Add(t, h+c)
+	p.Add(t, 2*h)
+	p.Add(c, 2*h)
+	s2, err := sdf.Polygon2D(p.Vertices())
+	if err != nil {
+		return nil, err
+	}
+	return extrudeX(s2, k.Length), nil
+}
+
+// CleatHolderParms defines the parameters for a French cleat accessory.
+type CleatHolderParms struct {
+	Cleat     FrenchCleatParms // cleat dimensions
+	Height    float64          // back plate height (0 = twice the cleat height)
+	Thickness float64          // back plate thickness
+	Holder    HolderParms      // holder on the front of the plate
+}
+
+// CleatHolder3D returns a French cleat accessory in its hanging position on the wall cleat.
+// The back plate is in front of the wall cleat (y = Cleat.Thickness) with its top level with
+// the top of the mating cleat.
+func CleatHolder3D(k *CleatHolderParms) (sdf.SDF3, error) {
+	mate, err := CleatMate3D(&k.Cleat)
+	if err != nil {
+		return nil, err
+	}
+	if k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("Thickness <= 0")
+	}
+	h := k.Height
+	if h == 0 {
+		h = 2 * k.Cleat.Height
+	}
+	y0 := k.Cleat.Thickness
+	zTop := 2 * k.Cleat.Height
+	plate := box3D(v3.Vec{-0.5 * k.Cleat.Length, y0, zTop - h}, v3.Vec{0.5 * k.Cleat.Length, y0 + k.Thickness, zTop})
+	holder, err := holder3D(&k.Holder)
+	if err != nil {
+		return nil, err
+	}
+	holder = sdf.Transform3D(holder, sdf.Translate3d(v3.Vec{0, y0 + k.Thickness, zTop - h}))
+	return sdf.Union3D(plate, mate, holder), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,242 @@
+//-----------------------------------------------------------------------------
+/*
+
+Rib Reinforcement
+
+Heuristic rib layouts for stiffening a plate or the floor of an enclosure
+against a set of loads. This is not an analysis: it places ribs where
+experience says they help.
+
+Radial: ribs radiate from each load point. If the load has an in-plane
+component the first rib is aligned with it. Rib height is scaled by the
+square root of the relative load magnitude.
+
+Grid: two sets of parallel ribs along the principal directions of the
+loads (the principal axes of the in-plane load components, or of the load
+positions when the loads are normal to the plate). The grid is centered on
+the load centroid.
+
+The ribs stand on the plate at the height (z) of the load points, on the
+open side of the surface (found by probing the base part above the first
+load). Ribs are clipped to the outline of the base part at that height.
+The rib cross section is a trapezoid with draft on both sides so the ribs
+print and mold cleanly.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// RibLayout is the rib layout style.
+type RibLayout int
+
+// Rib layouts.
+const (
+	RibRadial RibLayout = iota // ribs radiating from each load point
+	RibGrid                    // grid along the principal load directions
+)
+
+// RibLoad is a load applied to the part.
+type RibLoad struct {
+	Position  v3.Vec  // load point on the plate surface
+	Direction v3.Vec  // load direction
+	Magnitude float64 // load magnitude (relative)
+}
+
+// RibParms defines the parameters for rib reinforcement.
+type RibParms struct {
+	Layout    RibLayout
+	Loads     []RibLoad
+	Height    float64 // rib height
+	Thickness float64 // rib thickness at the root
+	Draft     float64 // draft angle of the rib sides (degrees)
+	Count     int     // number of ribs per load (radial)
+	Spacing   float64 // rib spacing (grid)
+	Fillet    float64 // root fillet radius (0 = none)
+}
+
+// ribOverlap is the depth of the ribs into the base (relative to the rib thickness).
+const ribOverlap = 0.25
+
+//-----------------------------------------------------------------------------
+
+// rib returns a rib of a given length along +x centered on the origin, standing on z = 0.
+func (k *RibParms) rib(length, height float64) (sdf.SDF3, error) {
+	top := k.Thickness - 2*height*math.Tan(sdf.DtoR(k.Draft))
+	if top <= 0 {
+		return nil, sdf.ErrMsg("Draft is too large for the Height/Thickness")
+	}
+	base := -ribOverlap * k.Thickness
+	t0 := 0.5 * (k.Thickness + 2*(-base)*math.Tan(sdf.DtoR(k.Draft)))
+	p := sdf.NewPolygon()
+	p.Add(-t0, base)
+	p.Add(t0, base)
+	p.Add(0.5*top, height)
+	p.Add(-0.5*top, height)
+	profile, err := sdf.Polygon2D(p.Vertices())
+	if err != nil {
+		return nil, err
+	}
+	// profile (x, y) and extrusion z to world (y, z, x)
+	m := sdf.M44{
+		0, 0, 1, 0,
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, 0, 1,
+	}
+	return sdf.Transform3D(sdf.Extrude3D(profile, length), m), nil
+}
+
+// principal returns the principal direction angle of a set of weighted 2d vectors.
+func principal(v []v2.Vec, w []float64) float64 {
+	var sxx, syy, sxy float64
+	for i, a := range v {
+		sxx += w[i] * a.X * a.X
+		syy += w[i] * a.Y * a.Y
+		sxy += w[i] * a.X * a.Y
+	}
+	return 0.5 * math.Atan2(2*sxy, sxx-syy)
+}
+
+// Ribs3D returns a base part reinforced with ribs for a set of loads.
+func Ribs3D(base sdf.SDF3, k *RibParms) (sdf.SDF3, error) {
+	if len(k.Loads) == 0 {
+		return nil, sdf.ErrMsg("no loads")
+	}
+	if k.Height <= 0 {
+		return nil, sdf.ErrMsg("Height <= 0")
+	}
+	if k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("Thickness <= 0")
+	}
+	if k.Draft < 0 || k.Draft >= 45 {
+		return nil, sdf.ErrMsg("Draft must be 0..45 degrees")
+	}
+	if k.Fillet < 0 {
+		return nil, sdf.ErrMsg("Fillet < 0")
+	}
+
+	// rib plane and side
+	var center v3.Vec
+	maxMag := 0.0
+	for _, l := range k.Loads {
+		center = center.Add(l.Position)
+		maxMag = math.Max(maxMag, l.Magnitude)
+	}
+	center = center.DivScalar(float64(len(k.Loads)))
+	z := center.Z
+	up := 1.0
+	eps := 0.05 * k.Thickness
+	if base.Evaluate(v3.Vec{k.Loads[0].Position.X, k.Loads[0].Position.Y, z + eps}) < 0 {
+		up = -1
+	}
+
+	bb := base.BoundingBox()
+	size := bb.Size()
+	diagonal := math.Hypot(size.X, size.Y)
+
+	// place a rib along a direction through a point (xy), length l, height h
+	var ribs []sdf.SDF3
+	place := func(p v2.Vec, theta, l, h float64) error {
+		r, err := k.rib(l, h)
+		if err != nil {
+			return err
+		}
+		m := sdf.Translate3d(v3.Vec{p.X, p.Y, z}).Mul(sdf.RotateZ(theta))
+		if up < 0 {
+			m = m.Mul(sdf.MirrorXY())
+		}
+		ribs = append(ribs, sdf.Transform3D(r, m))
+		return nil
+	}
+
+	switch k.Layout {
+	case RibRadial:
+		if k.Count < 1 {
+			return nil, sdf.ErrMsg("Count < 1")
+		}
+		for _, l := range k.Loads {
+			h := k.Height
+			if maxMag > 0 {
+				h *= math.Sqrt(math.Max(l.Magnitude, 0) / maxMag)
+			}
+			if h <= 0 {
+				continue
+			}
+			theta0 := 0.0
+			if d := (v2.Vec{l.Direction.X, l.Direction.Y}); d.Length() > 1e-6*l.Direction.Length() {
+				theta0 = math.Atan2(d.Y, d.X)
+			}
+			p := v2.Vec{l.Position.X, l.Position.Y}
+			for i := 0; i < k.Count; i++ {
+				theta := theta0 + sdf.Tau*float64(i)/float64(k.Count)
+				dir := v2.Vec{math.Cos(theta), math.Sin(theta)}
+				// the rib runs from the load point outwards
+				if err := place(p.Add(dir.MulScalar(0.5*diagonal)), theta, diagonal, h); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case RibGrid:
+		if k.Spacing <= k.Thickness {
+			return nil, sdf.ErrMsg("Spacing <= Thickness")
+		}
+		// principal directions from the in-plane loads, or from the load positions
+		var v []v2.Vec
+		var w []float64
+		for _, l := range k.Loads {
+			d := v2.Vec{l.Direction.X, l.Direction.Y}
+			if d.Length() > 1e-6*l.Direction.Length() {
+				v = append(v, d.Normalize())
+				w = append(w, math.Max(l.Magnitude, 0)+1e-9)
+			}
+		}
+		if len(v) == 0 {
+			for _, l := range k.Loads {
+				v = append(v, v2.Vec{l.Position.X - center.X, l.Position.Y - center.Y})
+				w = append(w, 1)
+			}
+		}
+		theta := principal(v, w)
+		c := v2.Vec{center.X, center.Y}
+		n := int(math.Ceil(0.5 * diagonal / k.Spacing))
+		for _, a := range []float64{theta, theta + 0.5*sdf.Pi} {
+			normal := v2.Vec{-math.Sin(a), math.Cos(a)}
+			for i := -n; i <= n; i++ {
+				// rib lines through the centroid, offset along the normal
+				if err := place(c.Add(normal.MulScalar(float64(i)*k.Spacing)), a, 2*diagonal, k.Height); err != nil {
+					return nil, err
+				}
+			}
+		}
+	default:
+		return nil, sdf.ErrMsg("unknown rib layout")
+	}
+	if len(ribs) == 0 {
+		return nil, sdf.ErrMsg("no ribs")
+	}
+
+	// clip the ribs to the outline of the base at the rib plane
+	outline := sdf.Slice2D(base, v3.Vec{0, 0, z - up*eps}, v3.Vec{0, 0, 1})
+	lo, hi := z-up*ribOverlap*k.Thickness, z+up*k.Height
+	clip := sdf.Transform3D(sdf.Extrude3D(outline, math.Abs(hi-lo)), sdf.Translate3d(v3.Vec{0, 0, 0.5 * (lo + hi)}))
+	ribs3 := sdf.Intersect3D(clip, sdf.Union3D(ribs...))
+
+	s := sdf.Union3D(base, ribs3)
+	if k.Fillet > 0 {
+		s.(*sdf.UnionSDF3).SetMin(sdf.PolyMin(k.Fillet))
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
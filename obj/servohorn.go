@@ -0,0 +1,293 @@
+//-----------------------------------------------------------------------------
+/*
+
+Servo Horns and Pockets
+
+Parametric RC servo horns with a splined hub, and pocket cutters for
+mounting servos in brackets.
+
+Spline presets (nominal outer/root diameters):
+
+25T: Futaba, TowerPro MG995/MG996R and most clones
+23T: JR, Hitec micro
+15T: Hitec large scale
+
+Printed splines need a fit test. Adjust the clearance for the printer.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+// Splines
+
+// SplineParms defines the parameters for a servo output spline.
+type SplineParms struct {
+	Name         string  // name of the spline
+	Teeth        int     // number of teeth
+	Diameter     float64 // outer diameter of the teeth
+	RootDiameter float64 // root diameter of the teeth
+}
+
+type splineDatabase map[string]*SplineParms
+
+var splineDB = initSplineLookup()
+
+// Add adds a spline to the database.
+func (m splineDatabase) Add(name string, teeth int, diameter, rootDiameter float64) {
+	m[name] = &SplineParms{
+		Name:         name,
+		Teeth:        teeth,
+		Diameter:     diameter,
+		RootDiameter: rootDiameter,
+	}
+}
+
+// initSplineLookup adds a collection of servo splines to the database.
+func initSplineLookup() splineDatabase {
+	m := make(splineDatabase)
+	m.Add("25T", 25, 5.92, 5.5)
+	m.Add("23T", 23, 4.85, 4.45)
+	m.Add("15T", 15, 8.0, 7.2)
+	return m
+}
+
+// SplineLookup returns the parameters for a named spline.
+func SplineLookup(name string) (*SplineParms, error) {
+	k, ok := splineDB[name]
+	if !ok {
+		return nil, fmt.Errorf("spline \"%s\" not found", name)
+	}
+	return k, nil
+}
+
+// Spline2D returns the 2d profile of a servo spline centered on the origin.
+// The profile is grown by the clearance so it can be used to cut a spline bore.
+func Spline2D(k *SplineParms, clearance float64) (sdf.SDF2, error) {
+	if k.Teeth < 3 {
+		return nil, sdf.ErrMsg("Teeth < 3")
+	}
+	if k.RootDiameter <= 0 || k.Diameter <= k.RootDiameter {
+		return nil, sdf.ErrMsg("bad Diameter/RootDiameter")
+	}
+	if clearance < 0 {
+		return nil, sdf.ErrMsg("clearance < 0")
+	}
+	r0 := 0.5 * k.RootDiameter
+	r1 := 0.5 * k.Diameter
+	pitch := sdf.Tau / float64(k.Teeth)
+	p := sdf.NewPolygon()
+	for i := 0; i < k.Teeth; i++ {
+		// trapezoidal tooth
+		theta := float64(i) * pitch
+		p.Add(r0*math.Cos(theta-0.3*pitch), r0*math.Sin(theta-0.3*pitch))
+		p.Add(r1*math.Cos(theta-0.1*pitch), r1*math.Sin(theta-0.1*pitch))
+		p.Add(r1*math.Cos(theta+0.1*pitch), r1*math.Sin(theta+0.1*pitch))
+		p.Add(r0*math.Cos(theta+0.3*pitch), r0*math.Sin(theta+0.3*pitch))
+	}
+	s, err := sdf.Polygon2D(p.Vertices())
+	if err != nil {
+		return nil, err
+	}
+	if clearance > 0 {
+		s = sdf.Offset2D(s, clearance)
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+// Horns
+
+// HornParms defines the parameters for a servo horn.
+type HornParms struct {
+	Spline        string  // spline name, e.g. "25T"
+	Clearance     float64 // spline bore clearance
+	SplineDepth   float64 // depth of the spline bore
+	ScrewDiameter float64 // center screw hole diameter
+	HubDiameter   float64 // outer diameter of the hub
+	HubHeight     float64 // overall height of the hub
+	Arms          int     // number of arms (0 = round disc horn)
+	Length        float64 // distance from the center to the end of an arm (or disc radius)
+	ArmWidth      float64 // arm width at the hub
+	TipWidth      float64 // arm width at the tip
+	Thickness     float64 // thickness of the arms
+	Holes         int     // number of holes on each arm
+	HoleDiameter  float64 // arm hole diameter
+	HoleSpacing   float64 // spacing of the arm holes, measured in from the tip
+}
+
+// horn2D returns the 2d profile of the horn arms.
+func horn2D(k *HornParms) (sdf.SDF2, error) {
+	hub, err := sdf.Circle2D(0.5 * k.HubDiameter)
+	if err != nil {
+		return nil, err
+	}
+	var s sdf.SDF2
+	if k.Arms == 0 {
+		s, err = sdf.Circle2D(k.Length)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// tapered arm along +x with a round tip
+		rTip := 0.5 * k.TipWidth
+		l := k.Length - rTip
+		p := sdf.NewPolygon()
+		p.Add(0, -0.5*k.ArmWidth)
+		p.Add(l, -rTip)
+		p.Add(l, rTip)
+		p.Add(0, 0.5*k.ArmWidth)
+		arm, err := sdf.Polygon2D(p.Vertices())
+		if err != nil {
+			return nil, err
+		}
+		tip, err := sdf.Circle2D(rTip)
+		if err != nil {
+			return nil, err
+		}
+		arm = sdf.Union2D(arm, sdf.Transform2D(tip, sdf.Translate2d(v2.Vec{l, 0})))
+		s = sdf.RotateCopy2D(arm, k.Arms)
+	}
+	s = sdf.Union2D(s, hub)
+	// arm holes
+	if k.Holes > 0 {
+		hole, err := sdf.Circle2D(0.5 * k.HoleDiameter)
+		if err != nil {
+			return nil, err
+		}
+		var positions v2.VecSet
+		for i := 0; i < k.Holes; i++ {
+			positions = append(positions, v2.Vec{k.Length - 0.5*k.TipWidth - float64(i)*k.HoleSpacing, 0})
+		}
+		holes := sdf.Multi2D(hole, positions)
+		n := k.Arms
+		if n == 0 {
+			// a disc horn has 4 rows of holes
+			n = 4
+		}
+		s = sdf.Difference2D(s, sdf.RotateCopy2D(holes, n))
+	}
+	return s, nil
+}
+
+// Horn3D returns a servo horn. The spline end of the hub is at z = 0, the arms are
+// at the top of the hub, the first arm is on the +x axis and the horn rotates about the z-axis.
+func Horn3D(k *HornParms) (sdf.SDF3, error) {
+	spline, err := SplineLookup(k.Spline)
+	if err != nil {
+		return nil, err
+	}
+	if k.Arms < 0 {
+		return nil, sdf.ErrMsg("Arms < 0")
+	}
+	if k.HubDiameter <= spline.Diameter+2*k.Clearance {
+		return nil, sdf.ErrMsg("HubDiameter too small for the spline")
+	}
+	if k.Thickness <= 0 || k.HubHeight < k.Thickness {
+		return nil, sdf.ErrMsg("bad Thickness/HubHeight")
+	}
+	if k.SplineDepth <= 0 || k.SplineDepth >= k.HubHeight {
+		return nil, sdf.ErrMsg("SplineDepth must be 0..HubHeight")
+	}
+	if k.Length <= 0.5*k.HubDiameter {
+		return nil, sdf.ErrMsg("Length <= hub radius")
+	}
+	if k.Arms > 0 && (k.ArmWidth <= 0 || k.TipWidth <= 0 || k.TipWidth > k.ArmWidth) {
+		return nil, sdf.ErrMsg("bad ArmWidth/TipWidth")
+	}
+	if k.Holes > 0 && (k.HoleDiameter <= 0 || (k.Holes > 1 && k.HoleSpacing <= 0)) {
+		return nil, sdf.ErrMsg("bad HoleDiameter/HoleSpacing")
+	}
+
+	// arms at the top of the hub
+	arms2d, err := horn2D(k)
+	if err != nil {
+		return nil, err
+	}
+	arms := sdf.Extrude3D(arms2d, k.Thickness)
+	arms = sdf.Transform3D(arms, sdf.Translate3d(v3.Vec{0, 0, k.HubHeight - 0.5*k.Thickness}))
+	hub, err := sdf.Cylinder3D(k.HubHeight, 0.5*k.HubDiameter, 0)
+	if err != nil {
+		return nil, err
+	}
+	hub = sdf.Transform3D(hub, sdf.Translate3d(v3.Vec{0, 0, 0.5 * k.HubHeight}))
+	s := sdf.Union3D(hub, arms)
+
+	// spline bore and screw hole
+	spline2d, err := Spline2D(spline, k.Clearance)
+	if err != nil {
+		return nil, err
+	}
+	cuts := []sdf.SDF3{extrudeMarks(spline2d, k.SplineDepth)}
+	if k.ScrewDiameter > 0 {
+		screw, err := sdf.Cylinder3D(k.HubHeight, 0.5*k.ScrewDiameter, 0)
+		if err != nil {
+			return nil, err
+		}
+		cuts = append(cuts, sdf.Transform3D(screw, sdf.Translate3d(v3.Vec{0, 0, 0.5 * k.HubHeight})))
+	}
+	return sdf.Difference3D(s, sdf.Union3D(cuts...)), nil
+}
+
+//-----------------------------------------------------------------------------
+// Pockets
+
+// ServoPocketParms defines the parameters for a servo pocket cutter.
+type ServoPocketParms struct {
+	Clearance    float64 // clearance around the servo body
+	Depth        float64 // depth of the body pocket
+	HoleDiameter float64 // mounting tab screw hole diameter (e.g. tapping size)
+	HoleDepth    float64 // depth of the mounting tab screw holes
+	CableWidth   float64 // width of a cable slot at the end of the body away from the shaft (0 = none)
+}
+
+// ServoPocket3D returns a cutter for mounting a servo in a bracket. The servo drops
+// into the pocket and its mounting tabs rest on the surface at z = 0. The servo shaft
+// is on the z-axis.
+func ServoPocket3D(servo *ServoParms, k *ServoPocketParms) (sdf.SDF3, error) {
+	if k.Clearance < 0 {
+		return nil, sdf.ErrMsg("Clearance < 0")
+	}
+	if k.Depth <= 0 {
+		return nil, sdf.ErrMsg("Depth <= 0")
+	}
+	if k.HoleDiameter < 0 || k.HoleDepth < 0 || k.CableWidth < 0 {
+		return nil, sdf.ErrMsg("HoleDiameter/HoleDepth/CableWidth < 0")
+	}
+	body := sdf.Box2D(v2.Vec{servo.Body.X, servo.Body.Y}.AddScalar(2*k.Clearance), 0)
+	if k.CableWidth > 0 {
+		// cable slot out to the end of the mounting tabs
+		l := 0.5 * (servo.Mount.X + servo.Body.X)
+		slot := sdf.Box2D(v2.Vec{l, k.CableWidth}, 0)
+		slot = sdf.Transform2D(slot, sdf.Translate2d(v2.Vec{0.5 * l, 0}))
+		body = sdf.Union2D(body, slot)
+	}
+	s := sdf.Extrude3D(body, k.Depth)
+	s = sdf.Transform3D(s, sdf.Translate3d(v3.Vec{0, 0, -0.5 * k.Depth}))
+	if k.HoleDiameter > 0 && k.HoleDepth > 0 {
+		hole, err := sdf.Cylinder3D(k.HoleDepth, 0.5*k.HoleDiameter, 0)
+		if err != nil {
+			return nil, err
+		}
+		x := 0.5 * servo.Hole.X
+		y := 0.5 * servo.Hole.Y
+		z := -0.5 * k.HoleDepth
+		holes := sdf.Multi3D(hole, []v3.Vec{{x, y, z}, {-x, y, z}, {x, -y, z}, {-x, -y, z}})
+		s = sdf.Union3D(s, holes)
+	}
+	// position the shaft at the origin
+	xOfs := 0.5*servo.Hole.X - servo.ShaftOffset
+	return sdf.Transform3D(s, sdf.Translate3d(v3.Vec{xOfs, 0, 0})), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,215 @@
+//-----------------------------------------------------------------------------
+/*
+
+Stepper Motor Mounts and Shaft Couplers
+
+A mounting plate for a NEMA stepper motor and a matching shaft coupler,
+configured from the motor size and the shaft diameters.
+
+The plate has a bore for the motor pilot and slotted holes for the motor
+screws so the motor can slide along x (e.g. to tension a belt). The motor
+face sits against the plate at z = 0 and the plate extends to z = Thickness.
+
+The coupler is centered on the origin along the z-axis. The motor shaft
+enters from -z and the driven shaft enters from +z.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+// Stepper Motors
+
+// StepperParms defines the nominal dimensions of a stepper motor.
+type StepperParms struct {
+	Name          string  // name of the motor size, e.g. "nema_17"
+	Frame         float64 // width of the square motor frame
+	ShaftDiameter float64 // motor shaft diameter
+	ShaftLength   float64 // motor shaft length from the face plate
+}
+
+type stepperDatabase map[string]*StepperParms
+
+var stepperDB = initStepperLookup()
+
+// Add adds a stepper motor to the database.
+func (m stepperDatabase) Add(name string, frame, shaftDiameter, shaftLength float64) {
+	m[name] = &StepperParms{
+		Name:          name,
+		Frame:         frame,
+		ShaftDiameter: shaftDiameter,
+		ShaftLength:   shaftLength,
+	}
+}
+
+// initStepperLookup adds a collection of NEMA stepper motors to the database.
+// The hole pattern and pilot of each motor are in the mounting pattern database.
+func initStepperLookup() stepperDatabase {
+	m := make(stepperDatabase)
+	m.Add("nema_8", 20.3, 4, 10)
+	m.Add("nema_11", 28.2, 5, 20)
+	m.Add("nema_14", 35.2, 5, 20)
+	m.Add("nema_17", 42.3, 5, 24)
+	m.Add("nema_23", 56.4, 6.35, 21)
+	m.Add("nema_34", 86, 14, 32)
+	return m
+}
+
+// StepperLookup returns the parameters of a named stepper motor.
+func StepperLookup(name string) (*StepperParms, error) {
+	k, ok := stepperDB[name]
+	if !ok {
+		return nil, fmt.Errorf("stepper \"%s\" not found", name)
+	}
+	return k, nil
+}
+
+//-----------------------------------------------------------------------------
+// Shaft Couplers
+
+// CouplerParms defines the parameters for a rigid shaft coupler.
+type CouplerParms struct {
+	Diameter         float64 // outer diameter
+	Length           float64 // overall length
+	Bore0            float64 // motor shaft bore diameter (-z end)
+	Bore1            float64 // driven shaft bore diameter (+z end)
+	SetScrewDiameter float64 // set screw tapping diameter (0 = none)
+}
+
+// ShaftCoupler3D returns a rigid shaft coupler centered on the origin along the z-axis.
+// Each end has a radial set screw hole in the middle of the bore.
+func ShaftCoupler3D(k *CouplerParms) (sdf.SDF3, error) {
+	if k.Bore0 <= 0 || k.Bore1 <= 0 {
+		return nil, sdf.ErrMsg("Bore <= 0")
+	}
+	if k.Diameter <= math.Max(k.Bore0, k.Bore1) {
+		return nil, sdf.ErrMsg("Diameter <= Bore")
+	}
+	if k.Length <= 0 {
+		return nil, sdf.ErrMsg("Length <= 0")
+	}
+	if k.SetScrewDiameter < 0 {
+		return nil, sdf.ErrMsg("SetScrewDiameter < 0")
+	}
+	body, err := sdf.Cylinder3D(k.Length, 0.5*k.Diameter, 0)
+	if err != nil {
+		return nil, err
+	}
+	l := 0.5 * k.Length
+	var cuts []sdf.SDF3
+	for i, d := range []float64{k.Bore0, k.Bore1} {
+		z := 0.5 * l
+		if i == 0 {
+			z = -z
+		}
+		bore, err := sdf.Cylinder3D(l, 0.5*d, 0)
+		if err != nil {
+			return nil, err
+		}
+		cuts = append(cuts, sdf.Transform3D(bore, sdf.Translate3d(v3.Vec{0, 0, z})))
+		if k.SetScrewDiameter > 0 {
+			screw, err := sdf.Cylinder3D(0.5*k.Diameter, 0.5*k.SetScrewDiameter, 0)
+			if err != nil {
+				return nil, err
+			}
+			m := sdf.Translate3d(v3.Vec{0, 0.25 * k.Diameter, z}).Mul(sdf.RotateX(sdf.DtoR(90)))
+			cuts = append(cuts, sdf.Transform3D(screw, m))
+		}
+	}
+	return sdf.Difference3D(body, sdf.Union3D(cuts...)), nil
+}
+
+//-----------------------------------------------------------------------------
+// Stepper Motor Mount
+
+// StepperMountParms defines the parameters for a stepper motor mount and shaft coupler.
+type StepperMountParms struct {
+	Motor            string  // motor size, e.g. "nema_17"
+	Thickness        float64 // plate thickness
+	Margin           float64 // plate margin around the motor frame
+	CornerRadius     float64 // radius of the plate corners
+	SlotLength       float64 // adjustment travel of the motor along x (0 = fixed)
+	Clearance        float64 // clearance for the pilot bore and the shaft bores
+	ShaftDiameter    float64 // motor shaft diameter (0 = motor default)
+	DriveDiameter    float64 // driven shaft diameter (0 = motor shaft diameter)
+	CouplerDiameter  float64 // coupler outer diameter (0 = 2.5 x largest shaft)
+	CouplerLength    float64 // coupler length (0 = 5 x largest shaft)
+	SetScrewDiameter float64 // coupler set screw tapping diameter (0 = none)
+}
+
+// xSlot2D returns a round ended slot of the given width centered on the origin along x.
+func xSlot2D(width, length float64) sdf.SDF2 {
+	return sdf.Box2D(v2.Vec{length + width, width}, 0.5*width)
+}
+
+// StepperMount3D returns a stepper motor mounting plate and a matching shaft coupler.
+func StepperMount3D(k *StepperMountParms) (plate, coupler sdf.SDF3, err error) {
+	motor, err := StepperLookup(k.Motor)
+	if err != nil {
+		return nil, nil, err
+	}
+	mp, err := MountPatternLookup(k.Motor)
+	if err != nil {
+		return nil, nil, err
+	}
+	if k.Thickness <= 0 {
+		return nil, nil, sdf.ErrMsg("Thickness <= 0")
+	}
+	if k.Margin < 0 || k.SlotLength < 0 || k.Clearance < 0 {
+		return nil, nil, sdf.ErrMsg("Margin/SlotLength/Clearance < 0")
+	}
+
+	// plate, long enough for the motor to slide along the slots
+	size := v2.Vec{motor.Frame + k.SlotLength, motor.Frame}.AddScalar(2 * k.Margin)
+	s := sdf.Box2D(size, k.CornerRadius)
+
+	// pilot bore and motor screw slots
+	holes := []sdf.SDF2{xSlot2D(mp.PilotDiameter+2*k.Clearance, k.SlotLength)}
+	slot := xSlot2D(mp.HoleDiameter, k.SlotLength)
+	for _, p := range mp.Holes {
+		holes = append(holes, sdf.Transform2D(slot, sdf.Translate2d(p)))
+	}
+	s = sdf.Difference2D(s, sdf.Union2D(holes...))
+	plate = extrudeMarks(s, k.Thickness)
+
+	// shaft coupler
+	shaft := k.ShaftDiameter
+	if shaft == 0 {
+		shaft = motor.ShaftDiameter
+	}
+	drive := k.DriveDiameter
+	if drive == 0 {
+		drive = shaft
+	}
+	dMax := math.Max(shaft, drive)
+	ck := CouplerParms{
+		Diameter:         k.CouplerDiameter,
+		Length:           k.CouplerLength,
+		Bore0:            shaft + k.Clearance,
+		Bore1:            drive + k.Clearance,
+		SetScrewDiameter: k.SetScrewDiameter,
+	}
+	if ck.Diameter == 0 {
+		ck.Diameter = 2.5 * dMax
+	}
+	if ck.Length == 0 {
+		ck.Length = 5 * dMax
+	}
+	coupler, err = ShaftCoupler3D(&ck)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plate, coupler, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,203 @@
+//-----------------------------------------------------------------------------
+/*
+
+Truss Nodes
+
+Connector nodes for space frames, geodesic domes and trusses. The node is a
+spherical hub with a socket for each strut. The sockets point along the strut
+direction vectors and hold the struts with an internal thread (threaded rod)
+or a split clamp with a cross screw (plain rod or tube).
+
+The hub is made just large enough to keep the socket bores apart, so the
+node is as compact as the strut angles allow.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// SocketStyle is the way a truss node socket holds a strut.
+type SocketStyle int
+
+// Socket styles.
+const (
+	SocketThreaded SocketStyle = iota // internal thread for a threaded rod
+	SocketClamp                       // split socket with a clamp screw
+)
+
+// minStrutAngle is the minimum angle (degrees) between two struts.
+const minStrutAngle = 10.0
+
+// TrussNodeParms defines the parameters for a truss node.
+type TrussNodeParms struct {
+	Struts        []v3.Vec    // strut directions from the node center
+	Style         SocketStyle // socket style
+	Thread        string      // name of the rod thread (threaded sockets)
+	RodDiameter   float64     // diameter of the rod or tube (clamp sockets)
+	Tolerance     float64     // add to the bore radius
+	SocketDepth   float64     // depth of the socket bores
+	Wall          float64     // socket wall thickness
+	HubDiameter   float64     // hub diameter (0 = smallest hub for the strut angles)
+	Fillet        float64     // blend radius between the hub and sockets (0 = none)
+	SlotWidth     float64     // width of the clamp slot
+	ScrewDiameter float64     // diameter of the clamp screw hole
+}
+
+// validate checks the truss node parameters and returns the bore radius.
+func (k *TrussNodeParms) validate() (float64, error) {
+	if len(k.Struts) == 0 {
+		return 0, sdf.ErrMsg("no struts")
+	}
+	for i, a := range k.Struts {
+		if a.Length() == 0 {
+			return 0, fmt.Errorf("strut %d has no direction", i)
+		}
+		for j := 0; j < i; j++ {
+			if strutAngle(a, k.Struts[j]) < sdf.DtoR(minStrutAngle) {
+				return 0, fmt.Errorf("struts %d and %d are less than %g degrees apart", j, i, minStrutAngle)
+			}
+		}
+	}
+	if k.Tolerance < 0 {
+		return 0, sdf.ErrMsg("Tolerance < 0")
+	}
+	if k.SocketDepth <= 0 {
+		return 0, sdf.ErrMsg("SocketDepth <= 0")
+	}
+	if k.Wall <= 0 {
+		return 0, sdf.ErrMsg("Wall <= 0")
+	}
+	if k.HubDiameter < 0 || k.Fillet < 0 {
+		return 0, sdf.ErrMsg("HubDiameter/Fillet < 0")
+	}
+	switch k.Style {
+	case SocketThreaded:
+		t, err := sdf.ThreadLookup(k.Thread)
+		if err != nil {
+			return 0, err
+		}
+		return t.ToMillimetre().Radius + k.Tolerance, nil
+	case SocketClamp:
+		if k.RodDiameter <= 0 {
+			return 0, sdf.ErrMsg("RodDiameter <= 0")
+		}
+		if k.SlotWidth <= 0 || k.ScrewDiameter <= 0 {
+			return 0, sdf.ErrMsg("SlotWidth/ScrewDiameter <= 0")
+		}
+		return 0.5*k.RodDiameter + k.Tolerance, nil
+	}
+	return 0, sdf.ErrMsg("unknown socket style")
+}
+
+// strutAngle returns the angle between two strut directions.
+func strutAngle(a, b v3.Vec) float64 {
+	c := a.Normalize().Dot(b.Normalize())
+	return math.Acos(math.Max(-1, math.Min(1, c)))
+}
+
+// hubRadius returns the radius at which the socket bores start.
+// Bores closer to the center than this would break into each other.
+func (k *TrussNodeParms) hubRadius(rBore float64) float64 {
+	r := rBore + k.Wall
+	for i, a := range k.Struts {
+		for _, b := range k.Struts[:i] {
+			theta := math.Min(strutAngle(a, b), sdf.DtoR(90))
+			r = math.Max(r, (2*rBore+k.Wall)/math.Sin(theta))
+		}
+	}
+	return math.Max(r, 0.5*k.HubDiameter)
+}
+
+// strutRotation returns the rotation from the z-axis to a strut direction.
+func strutRotation(v v3.Vec) sdf.M44 {
+	v = v.Normalize()
+	if v.Z < -1+1e-9 {
+		// RotateToVector flips all axes for opposite vectors, use a proper rotation
+		return sdf.RotateX(sdf.Pi)
+	}
+	return sdf.RotateToVector(v3.Vec{0, 0, 1}, v)
+}
+
+// socket returns the boss and the bore of a socket along the z-axis.
+func (k *TrussNodeParms) socket(r0, rBore float64) (sdf.SDF3, sdf.SDF3, error) {
+	rBoss := rBore + k.Wall
+	l := r0 + k.SocketDepth
+	boss, err := sdf.Cylinder3D(l, rBoss, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	boss = sdf.Transform3D(boss, sdf.Translate3d(v3.Vec{0, 0, 0.5 * l}))
+	zBore := r0 + 0.5*k.SocketDepth
+
+	if k.Style == SocketThreaded {
+		t, _ := sdf.ThreadLookup(k.Thread)
+		t = t.ToMillimetre()
+		isoThread, err := sdf.ISOThread(rBore, t.Pitch, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		bore, err := sdf.Screw3D(isoThread, k.SocketDepth, t.Taper, t.Pitch, 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		return boss, sdf.Transform3D(bore, sdf.Translate3d(v3.Vec{0, 0, zBore})), nil
+	}
+
+	// clamp: an ear on the +x side of the socket with a slot and a cross screw
+	xEar := rBoss + 2*k.ScrewDiameter
+	ear := box3D(v3.Vec{0, -rBoss, r0}, v3.Vec{xEar, rBoss, l})
+	boss = sdf.Union3D(boss, ear)
+	bore, err := sdf.Cylinder3D(k.SocketDepth, rBore, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	bore = sdf.Transform3D(bore, sdf.Translate3d(v3.Vec{0, 0, zBore}))
+	slot := box3D(v3.Vec{0, -0.5 * k.SlotWidth, r0}, v3.Vec{xEar, 0.5 * k.SlotWidth, l})
+	screw, err := sdf.Cylinder3D(2*rBoss, 0.5*k.ScrewDiameter, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	screw = sdf.Transform3D(screw, sdf.Translate3d(v3.Vec{rBoss + k.ScrewDiameter, 0, zBore}).Mul(sdf.RotateX(0.5*sdf.Pi)))
+	return boss, sdf.Union3D(bore, slot, screw), nil
+}
+
+// TrussNode3D returns a truss node centered on the origin with a socket for each strut.
+func TrussNode3D(k *TrussNodeParms) (sdf.SDF3, error) {
+	rBore, err := k.validate()
+	if err != nil {
+		return nil, err
+	}
+	r0 := k.hubRadius(rBore)
+	boss, bore, err := k.socket(r0, rBore)
+	if err != nil {
+		return nil, err
+	}
+	hub, err := sdf.Sphere3D(r0)
+	if err != nil {
+		return nil, err
+	}
+	body := []sdf.SDF3{hub}
+	var bores []sdf.SDF3
+	for _, v := range k.Struts {
+		m := strutRotation(v)
+		body = append(body, sdf.Transform3D(boss, m))
+		bores = append(bores, sdf.Transform3D(bore, m))
+	}
+	s := sdf.Union3D(body...)
+	if k.Fillet > 0 {
+		s.(*sdf.UnionSDF3).SetMin(sdf.PolyMin(k.Fillet))
+	}
+	return sdf.Difference3D(s, sdf.Union3D(bores...)), nil
+}
+
+//-----------------------------------------------------------------------------
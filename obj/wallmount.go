@@ -0,0 +1,232 @@
+//-----------------------------------------------------------------------------
+/*
+
+Wall Mounting Plates
+
+A plate that screws to a wall and carries an enclosure. The plate is sized
+to the enclosure footprint and has keyhole slots to hang it on wall screws,
+screw bosses to fix the enclosure, cable pass-through grommets and an
+optional standard mounting pattern (e.g. VESA).
+
+The back of the plate (against the wall) is at z = 0 and the front of the
+plate is at z = Thickness. Keyhole slots open upwards (+y) so the plate
+drops down onto the wall screws.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+// Keyholes
+
+// KeyholeParms defines the parameters for a keyhole slot.
+type KeyholeParms struct {
+	HeadDiameter  float64 // diameter of the hole the screw head passes through
+	ShaftDiameter float64 // width of the slot the screw shaft slides along
+	Length        float64 // distance from the head hole center to the end of the slot
+	Thickness     float64 // plate thickness (3d only)
+	Undercut      float64 // depth of the screw head pocket behind the slot (3d only, 0 = none)
+}
+
+// Keyhole2D returns a 2d keyhole. The head hole is centered on the origin and the slot runs along +y.
+func Keyhole2D(k *KeyholeParms) (sdf.SDF2, error) {
+	if k.ShaftDiameter <= 0 {
+		return nil, sdf.ErrMsg("ShaftDiameter <= 0")
+	}
+	if k.HeadDiameter <= k.ShaftDiameter {
+		return nil, sdf.ErrMsg("HeadDiameter <= ShaftDiameter")
+	}
+	if k.Length <= 0 {
+		return nil, sdf.ErrMsg("Length <= 0")
+	}
+	head, err := sdf.Circle2D(0.5 * k.HeadDiameter)
+	if err != nil {
+		return nil, err
+	}
+	slot := slot2D(k.ShaftDiameter, k.Length)
+	return sdf.Union2D(head, slot), nil
+}
+
+// slot2D returns a round ended slot from the origin to (0, length).
+func slot2D(width, length float64) sdf.SDF2 {
+	s := sdf.Box2D(v2.Vec{width, length + width}, 0.5*width)
+	return sdf.Transform2D(s, sdf.Translate2d(v2.Vec{0, 0.5 * length}))
+}
+
+// Keyhole3D returns a 3d keyhole cutter from z = 0 (back) to z = Thickness (front).
+// The undercut is a pocket behind the slot so the screw head can slide along it.
+func Keyhole3D(k *KeyholeParms) (sdf.SDF3, error) {
+	if k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("Thickness <= 0")
+	}
+	if k.Undercut < 0 || k.Undercut >= k.Thickness {
+		return nil, sdf.ErrMsg("Undercut must be 0..Thickness")
+	}
+	s2, err := Keyhole2D(k)
+	if err != nil {
+		return nil, err
+	}
+	s := extrudeMarks(s2, k.Thickness)
+	if k.Undercut > 0 {
+		pocket := extrudeMarks(slot2D(k.HeadDiameter, k.Length), k.Undercut)
+		s = sdf.Union3D(s, pocket)
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+// Grommets
+
+// GrommetParms defines the parameters for a cable pass-through grommet.
+type GrommetParms struct {
+	Diameter      float64 // cable hole diameter
+	RingDiameter  float64 // outer diameter of the ring around the hole
+	Height        float64 // height of the ring above the plate
+	ChamferRadius float64 // chamfer of the hole entries
+}
+
+// grommet returns the ring and the hole cutter for a grommet on a plate.
+func grommet(k *GrommetParms, thickness float64) (sdf.SDF3, sdf.SDF3, error) {
+	if k.Diameter <= 0 {
+		return nil, nil, sdf.ErrMsg("grommet Diameter <= 0")
+	}
+	if k.Height < 0 || k.ChamferRadius < 0 {
+		return nil, nil, sdf.ErrMsg("grommet Height/ChamferRadius < 0")
+	}
+	r := 0.5 * k.Diameter
+	l := thickness + k.Height
+	var ring sdf.SDF3
+	if k.Height > 0 {
+		if k.RingDiameter <= k.Diameter {
+			return nil, nil, sdf.ErrMsg("grommet RingDiameter <= Diameter")
+		}
+		s, err := sdf.Cylinder3D(k.Height, 0.5*k.RingDiameter, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		ring = sdf.Transform3D(s, sdf.Translate3d(v3.Vec{0, 0, thickness + 0.5*k.Height}))
+	}
+	// chamfer both entries of the hole
+	hole, err := sdf.Cylinder3D(l, r, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if k.ChamferRadius > 0 {
+		front, err := ChamferedHole3D(l, r, k.ChamferRadius)
+		if err != nil {
+			return nil, nil, err
+		}
+		back := sdf.Transform3D(front, sdf.MirrorXY())
+		hole = sdf.Union3D(front, back)
+	}
+	hole = sdf.Transform3D(hole, sdf.Translate3d(v3.Vec{0, 0, 0.5 * l}))
+	return ring, hole, nil
+}
+
+//-----------------------------------------------------------------------------
+// Wall Mounting Plate
+
+// WallMountParms defines the parameters for a wall mounting plate.
+type WallMountParms struct {
+	Size         v2.Vec         // enclosure footprint
+	Margin       float64        // plate margin around the footprint
+	Thickness    float64        // plate thickness
+	CornerRadius float64        // radius of the plate corners
+	Keyhole      *KeyholeParms  // keyhole slots (nil = none)
+	Keyholes     []v2.Vec       // keyhole positions (default two in the upper half of the plate)
+	Boss         *StandoffParms // enclosure screw bosses (nil = none)
+	Bosses       []v2.Vec       // boss positions
+	Grommet      *GrommetParms  // cable pass-through grommets (nil = none)
+	Grommets     []v2.Vec       // grommet positions
+	Mount        string         // named mounting pattern, e.g. "vesa_100" ("" = none)
+}
+
+// WallMount3D returns a wall mounting plate centered on the origin in x and y.
+func WallMount3D(k *WallMountParms) (sdf.SDF3, error) {
+	if k.Size.X <= 0 || k.Size.Y <= 0 {
+		return nil, sdf.ErrMsg("Size <= 0")
+	}
+	if k.Margin < 0 {
+		return nil, sdf.ErrMsg("Margin < 0")
+	}
+	if k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("Thickness <= 0")
+	}
+	size := k.Size.AddScalar(2 * k.Margin)
+	plate := extrudeMarks(sdf.Box2D(size, k.CornerRadius), k.Thickness)
+
+	var adds, cuts []sdf.SDF3
+
+	// keyholes
+	if k.Keyhole != nil {
+		kh := *k.Keyhole
+		kh.Thickness = k.Thickness
+		s, err := Keyhole3D(&kh)
+		if err != nil {
+			return nil, err
+		}
+		positions := k.Keyholes
+		if len(positions) == 0 {
+			positions = []v2.Vec{{-0.25 * size.X, 0.25 * size.Y}, {0.25 * size.X, 0.25 * size.Y}}
+		}
+		cuts = append(cuts, multi3D(s, positions, 0))
+	}
+
+	// bosses on the front of the plate
+	if k.Boss != nil && len(k.Bosses) != 0 {
+		s, err := Standoff3D(k.Boss)
+		if err != nil {
+			return nil, err
+		}
+		adds = append(adds, multi3D(s, k.Bosses, k.Thickness+0.5*k.Boss.PillarHeight))
+	}
+
+	// grommets
+	if k.Grommet != nil && len(k.Grommets) != 0 {
+		ring, hole, err := grommet(k.Grommet, k.Thickness)
+		if err != nil {
+			return nil, err
+		}
+		if ring != nil {
+			adds = append(adds, multi3D(ring, k.Grommets, 0))
+		}
+		cuts = append(cuts, multi3D(hole, k.Grommets, 0))
+	}
+
+	// mounting pattern
+	if k.Mount != "" {
+		mp, err := MountPatternLookup(k.Mount)
+		if err != nil {
+			return nil, err
+		}
+		s, err := MountPattern3D(mp, k.Thickness)
+		if err != nil {
+			return nil, err
+		}
+		cuts = append(cuts, sdf.Transform3D(s, sdf.Translate3d(v3.Vec{0, 0, 0.5 * k.Thickness})))
+	}
+
+	s := sdf.Union3D(append([]sdf.SDF3{plate}, adds...)...)
+	if len(cuts) != 0 {
+		s = sdf.Difference3D(s, sdf.Union3D(cuts...))
+	}
+	return s, nil
+}
+
+// multi3D returns copies of an SDF3 at 2d positions and a z offset.
+func multi3D(s sdf.SDF3, positions []v2.Vec, z float64) sdf.SDF3 {
+	var ps v3.VecSet
+	for _, p := range positions {
+		ps = append(ps, v3.Vec{p.X, p.Y, z})
+	}
+	return sdf.Multi3D(s, ps)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,264 @@
+//-----------------------------------------------------------------------------
+/*
+
+Batch Rendering
+
+A job file (YAML) lists many models with their parameters and output
+formats. The jobs are run by a pool of workers, each job writes a log file
+next to its outputs and the results are printed as a summary table. This is
+meant for unattended runs, e.g. nightly generation of a parts catalog.
+
+	output: build
+	workers: 4
+	formats: [stl]
+	params:
+	  wall: 2
+	render:
+	  cells: 300
+	jobs:
+	  - name: bolt_m4
+	    model: bolt
+	    params: {size: 4}
+	  - name: bolt_m6
+	    model: bolt
+	    params: {size: 6}
+	    formats: [stl, 3mf, step]
+
+The models are the builders registered with Register. Builders are called
+from several goroutines, so they must not modify shared state.
+
+*/
+//-----------------------------------------------------------------------------
+
+package project
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	"gopkg.in/yaml.v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Job is a model to render in a batch.
+type Job struct {
+	Name    string          `yaml:"name"`              // job name, the base name of the output files
+	Model   string          `yaml:"model"`             // registered model builder name
+	Params  Params          `yaml:"params,omitempty"`  // job parameters (override the batch parameters)
+	Formats []string        `yaml:"formats,omitempty"` // output formats (override the batch formats)
+	Render  *RenderSettings `yaml:"render,omitempty"`  // render settings (override the batch settings)
+}
+
+// Batch is a set of jobs with their common settings.
+type Batch struct {
+	Output  string         `yaml:"output,omitempty"`  // output directory (default ".")
+	Workers int            `yaml:"workers,omitempty"` // number of parallel jobs (default number of CPUs)
+	Params  Params         `yaml:"params,omitempty"`  // batch wide parameters
+	Formats []string       `yaml:"formats,omitempty"` // default output formats (default stl)
+	Render  RenderSettings `yaml:"render,omitempty"`  // default render settings
+	Jobs    []Job          `yaml:"jobs"`
+}
+
+// JobResult is the result of a batch job.
+type JobResult struct {
+	Job       string        // job name
+	Files     []string      // output files
+	Triangles int           // number of triangles
+	Duration  time.Duration // run time
+	Err       error         // job error
+}
+
+// ReadBatch reads a batch job file from a reader.
+func ReadBatch(r io.Reader) (*Batch, error) {
+	b := &Batch{}
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(b); err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for i, j := range b.Jobs {
+		if j.Name == "" {
+			return nil, sdf.ErrMsg(fmt.Sprintf("job %d has no name", i))
+		}
+		if names[j.Name] {
+			return nil, sdf.ErrMsg(fmt.Sprintf("job \"%s\" is repeated", j.Name))
+		}
+		names[j.Name] = true
+	}
+	return b, nil
+}
+
+// LoadBatch loads a batch job file.
+func LoadBatch(path string) (*Batch, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBatch(f)
+}
+
+//-----------------------------------------------------------------------------
+
+// saveMesh writes a mesh to a file with the format given by the file extension.
+func saveMesh(path, name string, mesh []*sdf.Triangle3, opts render.ExportOptions) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".stl":
+		return render.SaveSTLWithOptions(path, mesh, opts)
+	case ".3mf":
+		return render.Save3MFWithOptions(path, []render.Part3MF{{Name: name, Color: color.RGBA{128, 128, 128, 255}, Mesh: mesh}}, opts)
+	case ".obj":
+		return render.SaveOBJ(path, mesh)
+	case ".step", ".stp":
+		return render.SaveSTEPWithOptions(path, mesh, render.STEPOptions{ProductName: name, Fingerprint: opts.Fingerprint, Unit: opts.Unit})
+	}
+	return sdf.ErrMsg(fmt.Sprintf("unknown output file type \"%s\"", path))
+}
+
+// run runs a job, logging to the job log file.
+func (b *Batch) run(j *Job, dir string) (res JobResult) {
+	start := time.Now()
+	res.Job = j.Name
+	defer func() { res.Duration = time.Since(start) }()
+
+	f, err := os.Create(filepath.Join(dir, j.Name+".log"))
+	if err != nil {
+		res.Err = err
+		return
+	}
+	defer f.Close()
+	l := log.New(f, "", log.LstdFlags)
+	defer func() {
+		if res.Err != nil {
+			l.Printf("error: %s", res.Err)
+		} else {
+			l.Printf("done in %s", time.Since(start).Round(time.Millisecond))
+		}
+	}()
+
+	builder, ok := builders[j.Model]
+	if !ok {
+		res.Err = sdf.ErrMsg(fmt.Sprintf("model \"%s\" is not registered", j.Model))
+		return
+	}
+	params := b.Params.merge(j.Params)
+	l.Printf("model %s %v", j.Model, params)
+	s, err := builder(params)
+	if err != nil {
+		res.Err = err
+		return
+	}
+	settings := b.Render.override(j.Render)
+	if settings.Scale != 0 && settings.Scale != 1 {
+		s = sdf.ScaleUniform3D(s, settings.Scale)
+	}
+	r, err := settings.renderer()
+	if err != nil {
+		res.Err = err
+		return
+	}
+	l.Printf("rendering (%s)", r.Info(s))
+	mesh, err := settings.toTriangles(s, r)
+	if err != nil {
+		res.Err = err
+		return
+	}
+	res.Triangles = len(mesh)
+	l.Printf("%d triangles", len(mesh))
+	formats := j.Formats
+	if len(formats) == 0 {
+		formats = b.Formats
+	}
+	if len(formats) == 0 {
+		formats = []string{"stl"}
+	}
+	opts, err := settings.exportOptions()
+	if err != nil {
+		res.Err = err
+		return
+	}
+	for _, format := range formats {
+		name := j.Name + "." + strings.TrimPrefix(format, ".")
+		if err := saveMesh(filepath.Join(dir, name), j.Name, mesh, opts); err != nil {
+			res.Err = err
+			return
+		}
+		l.Printf("wrote %s", name)
+		res.Files = append(res.Files, name)
+	}
+	return
+}
+
+// Run runs the jobs of a batch with a pool of workers. The results are in job order.
+// The outputs and job logs are written to the batch output directory, relative to dir.
+func (b *Batch) Run(dir string) ([]JobResult, error) {
+	dir = filepath.Join(dir, b.Output)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	workers := b.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	results := make([]JobResult, len(b.Jobs))
+	jobs := make(chan int, len(b.Jobs))
+	for i := range b.Jobs {
+		jobs <- i
+	}
+	close(jobs)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = b.run(&b.Jobs[i], dir)
+			}
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// Failed returns the number of failed jobs.
+func Failed(results []JobResult) int {
+	n := 0
+	for _, r := range results {
+		if r.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteSummary writes a table of the job results.
+func WriteSummary(w io.Writer, results []JobResult) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "JOB\tSTATUS\tTRIANGLES\tTIME\tFILES\n")
+	var total time.Duration
+	for _, r := range results {
+		status := "ok"
+		if r.Err != nil {
+			status = "FAILED: " + r.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", r.Job, status, r.Triangles, r.Duration.Round(time.Millisecond), strings.Join(r.Files, " "))
+		total += r.Duration
+	}
+	fmt.Fprintf(tw, "%d jobs, %d failed\t\t\t%s\t\n", len(results), Failed(results), total.Round(time.Millisecond))
+	return tw.Flush()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,101 @@
+//-----------------------------------------------------------------------------
+/*
+
+Batch Rendering Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package project
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+const testBatch = `
+output: build
+workers: 2
+formats: [stl]
+params:
+  radius: 5
+render:
+  cells: 20
+jobs:
+  - name: small
+    model: batch_sphere
+  - name: big
+    model: batch_sphere
+    params: {radius: 10}
+    formats: [stl, 3mf, obj]
+    render: {fingerprint: true}
+  - name: missing
+    model: no_such_model
+`
+
+func Test_Batch(t *testing.T) {
+	Register("batch_sphere", func(p Params) (sdf.SDF3, error) {
+		return sdf.Sphere3D(p.Get("radius", 1))
+	})
+	b, err := ReadBatch(strings.NewReader(testBatch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	results, err := b.Run(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[0].Job != "small" || results[2].Job != "missing" {
+		t.Fatalf("bad results %v", results)
+	}
+	if Failed(results) != 1 || results[2].Err == nil {
+		t.Errorf("expected the missing model to fail")
+	}
+	if len(results[1].Files) != 3 || results[1].Triangles == 0 {
+		t.Errorf("bad result %v", results[1])
+	}
+	for _, name := range []string{"small.stl", "big.stl", "big.3mf", "big.obj", "small.log", "missing.log"} {
+		if _, err := os.Stat(filepath.Join(dir, "build", name)); err != nil {
+			t.Error(err)
+		}
+	}
+	// only the big job has a fingerprint in the STL header
+	for name, fp := range map[string]bool{"small.stl": false, "big.stl": true} {
+		data, err := os.ReadFile(filepath.Join(dir, "build", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(string(data), "sdfx ") != fp {
+			t.Errorf("%s: expected fingerprint %v", name, fp)
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteSummary(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "3 jobs, 1 failed") {
+		t.Errorf("bad summary\n%s", buf.String())
+	}
+}
+
+func Test_BatchErrors(t *testing.T) {
+	for _, s := range []string{
+		"jobs:\n  - model: x\n",
+		"jobs:\n  - {name: a, model: x}\n  - {name: a, model: y}\n",
+		"jobs: []\nunknown: 1\n",
+	} {
+		if _, err := ReadBatch(strings.NewReader(s)); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
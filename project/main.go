@@ -0,0 +1,133 @@
+//-----------------------------------------------------------------------------
+/*
+
+Project Command Line
+
+A program registers its model builders and calls Main to get a command
+line interface for its project files. Projects with only model trees don't
+need a program, "sdfx project" renders them.
+
+	func main() {
+		project.Register("base", base)
+		project.Register("panel", panel)
+		project.Main()
+	}
+
+	$ ./axoloti -p axoloti.json -o build
+	$ ./axoloti -p axoloti.json -part panel
+	$ ./axoloti -list
+	$ ./axoloti -batch catalog.yaml -j 8
+
+*/
+//-----------------------------------------------------------------------------
+
+package project
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// Main is the command line entry point for a project program.
+func Main() {
+	path := flag.String("p", "project.json", "project file")
+	dir := flag.String("o", ".", "output directory")
+	part := flag.String("part", "", "render a single part (default all parts)")
+	list := flag.Bool("list", false, "list the registered models and project parts")
+	update := flag.Bool("u", true, "update the output manifest in the project file")
+	batch := flag.String("batch", "", "run the jobs of a batch job file (YAML)")
+	workers := flag.Int("j", 0, "number of parallel batch jobs (default from the job file)")
+	fingerprint := flag.Bool("fingerprint", false, "embed the sdfx version, commit and model hash in the output files")
+	flag.Parse()
+	if *batch != "" {
+		if err := runBatch(*batch, *dir, *workers, *fingerprint); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := run(*path, *dir, *part, *list, *update, *fingerprint); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(path, dir, part string, list, update, fingerprint bool) error {
+	if list {
+		fmt.Printf("models:\n")
+		for _, name := range Models() {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+	p, err := Load(path)
+	if err != nil {
+		if list {
+			return nil
+		}
+		return err
+	}
+	if list {
+		fmt.Printf("parts:\n")
+		for _, x := range p.Parts {
+			model := x.Model
+			if x.Tree != nil {
+				model = "tree"
+			}
+			fmt.Printf("  %s (%s) -> %s\n", x.Name, model, x.Output)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// the command line fingerprint option is not saved in the project file
+	saved := p.Render.Fingerprint
+	p.Render.Fingerprint = saved || fingerprint
+	if part != "" {
+		err = p.RenderPart(part, dir)
+	} else {
+		err = p.RenderAll(dir)
+	}
+	p.Render.Fingerprint = saved
+	if err != nil {
+		return err
+	}
+	if update {
+		return p.Save(path)
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// runBatch runs a batch job file and prints the summary.
+func runBatch(path, dir string, workers int, fingerprint bool) error {
+	b, err := LoadBatch(path)
+	if err != nil {
+		return err
+	}
+	if workers > 0 {
+		b.Workers = workers
+	}
+	if fingerprint {
+		b.Render.Fingerprint = true
+	}
+	results, err := b.Run(dir)
+	if err != nil {
+		return err
+	}
+	if err := WriteSummary(os.Stdout, results); err != nil {
+		return err
+	}
+	if n := Failed(results); n != 0 {
+		return sdf.ErrMsg(fmt.Sprintf("%d of %d jobs failed", n, len(results)))
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,391 @@
+//-----------------------------------------------------------------------------
+/*
+
+Project Workspace
+
+A project file describes a multi-part project: the parts, the model and
+parameters used to build each part, the render settings, and a manifest
+of the generated output files. The file is JSON so it can be kept under
+version control alongside the code.
+
+The model of a part is stored in the project file as a serialized SDF node
+tree (see sdf.Marshal), so the project can be rendered by any program that
+reads project files (e.g. "sdfx project") without recompiling the model code.
+
+Optionally a part model can be built by a named builder function that is
+registered by the program using the project. The project file refers to the
+builder by name and supplies its parameters. Builders are needed for models
+that are not serializable (e.g. custom blending functions) and to vary the
+parameters per part.
+
+*/
+//-----------------------------------------------------------------------------
+
+package project
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	"github.com/deadsy/sdfx/units"
+)
+
+//-----------------------------------------------------------------------------
+
+// Version is the current project file format version.
+// Version 2 adds the model node trees of the parts.
+const Version = 2
+
+// Params are named model parameters.
+type Params map[string]float64
+
+// Get returns a named parameter, or a default value if it is not set.
+func (p Params) Get(name string, dflt float64) float64 {
+	if x, ok := p[name]; ok {
+		return x
+	}
+	return dflt
+}
+
+// merge returns the parameters overridden by another set of parameters.
+func (p Params) merge(q Params) Params {
+	r := make(Params, len(p)+len(q))
+	for k, v := range p {
+		r[k] = v
+	}
+	for k, v := range q {
+		r[k] = v
+	}
+	return r
+}
+
+//-----------------------------------------------------------------------------
+
+// Builder builds a model from a set of parameters.
+type Builder func(p Params) (sdf.SDF3, error)
+
+var builders = map[string]Builder{}
+
+// Register registers a named model builder.
+func Register(name string, b Builder) {
+	builders[name] = b
+}
+
+// Models returns the names of the registered model builders.
+func Models() []string {
+	names := make([]string, 0, len(builders))
+	for k := range builders {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//-----------------------------------------------------------------------------
+
+// RenderSettings control how a part is rendered.
+type RenderSettings struct {
+	Method string  `json:"method,omitempty"` // "octree" (default) or "uniform"
+	Cells  int     `json:"cells,omitempty"`  // mesh cells on the longest axis (default 200)
+	Scale  float64 `json:"scale,omitempty"`  // uniform scale, e.g. for shrinkage (default 1)
+	// embed the sdfx version, commit and model hash in the output files
+	Fingerprint bool `json:"fingerprint,omitempty"`
+	// fail on NaN/Inf distances instead of writing a broken mesh
+	Guard bool `json:"guard,omitempty"`
+	// unit of the output file coordinates: "mm" (default), "um", "cm", "m", "in" or "ft"
+	Unit string `json:"unit,omitempty"`
+}
+
+// defaultCells is the default number of mesh cells.
+const defaultCells = 200
+
+// override returns the settings overridden by the non-zero fields of another set of settings.
+func (r RenderSettings) override(x *RenderSettings) RenderSettings {
+	if x == nil {
+		return r
+	}
+	if x.Method != "" {
+		r.Method = x.Method
+	}
+	if x.Cells != 0 {
+		r.Cells = x.Cells
+	}
+	if x.Scale != 0 {
+		r.Scale = x.Scale
+	}
+	if x.Fingerprint {
+		r.Fingerprint = true
+	}
+	if x.Guard {
+		r.Guard = true
+	}
+	if x.Unit != "" {
+		r.Unit = x.Unit
+	}
+	return r
+}
+
+// exportOptions returns the mesh file export options for the settings.
+func (r RenderSettings) exportOptions() (render.ExportOptions, error) {
+	u, err := units.ParseUnit(r.Unit)
+	if err != nil {
+		return render.ExportOptions{}, err
+	}
+	return render.ExportOptions{Fingerprint: r.Fingerprint, Unit: u}, nil
+}
+
+// toTriangles renders an SDF3, failing on non-finite distances with the guard setting.
+func (r RenderSettings) toTriangles(s sdf.SDF3, r3 render.Render3) ([]*sdf.Triangle3, error) {
+	if r.Guard {
+		return render.ToTrianglesGuarded(s, r3)
+	}
+	return render.ToTriangles(s, r3), nil
+}
+
+// renderer returns the renderer for the settings.
+func (r RenderSettings) renderer() (render.Render3, error) {
+	cells := r.Cells
+	if cells == 0 {
+		cells = defaultCells
+	}
+	switch r.Method {
+	case "", "octree":
+		return render.NewMarchingCubesOctree(cells), nil
+	case "uniform":
+		return render.NewMarchingCubesUniform(cells), nil
+	}
+	return nil, sdf.ErrMsg(fmt.Sprintf("unknown render method \"%s\"", r.Method))
+}
+
+//-----------------------------------------------------------------------------
+
+// Part is a part of a project.
+type Part struct {
+	Name   string          `json:"name"`             // part name
+	Tree   *sdf.Node       `json:"tree,omitempty"`   // model node tree
+	Model  string          `json:"model,omitempty"`  // registered model builder name (instead of a tree)
+	Params Params          `json:"params,omitempty"` // builder parameters (override the project parameters)
+	Output string          `json:"output"`           // output file (.stl, .3mf, .obj or .step)
+	Render *RenderSettings `json:"render,omitempty"` // render settings (override the project settings)
+}
+
+// SetTree sets the model node tree of a part from an SDF3.
+func (part *Part) SetTree(s sdf.SDF3) error {
+	n, err := sdf.NewNode(s)
+	if err != nil {
+		return err
+	}
+	part.Tree = n
+	part.Model = ""
+	return nil
+}
+
+// model returns the model of a part.
+func (part *Part) model(params Params) (sdf.SDF3, error) {
+	if part.Tree != nil {
+		if part.Model != "" {
+			return nil, sdf.ErrMsg("both a model tree and a builder")
+		}
+		return part.Tree.SDF3()
+	}
+	if part.Model == "" {
+		return nil, sdf.ErrMsg("no model tree or builder")
+	}
+	b, ok := builders[part.Model]
+	if !ok {
+		return nil, sdf.ErrMsg(fmt.Sprintf("model \"%s\" is not registered", part.Model))
+	}
+	return b(params.merge(part.Params))
+}
+
+// Output is a manifest entry for a generated file.
+type Output struct {
+	Part      string `json:"part"`      // part name
+	Path      string `json:"path"`      // output file
+	Triangles int    `json:"triangles"` // number of triangles
+	SHA1      string `json:"sha1"`      // file checksum
+}
+
+// Project is a set of parts with their parameters and render settings.
+type Project struct {
+	Version int            `json:"version"`
+	Name    string         `json:"name"`
+	Params  Params         `json:"params,omitempty"`  // project wide parameters
+	Render  RenderSettings `json:"render"`            // default render settings
+	Parts   []Part         `json:"parts"`             // project parts
+	Outputs []Output       `json:"outputs,omitempty"` // manifest of generated files
+}
+
+// New returns an empty project.
+func New(name string) *Project {
+	return &Project{
+		Version: Version,
+		Name:    name,
+		Params:  Params{},
+	}
+}
+
+// Read reads a project from a reader.
+func Read(r io.Reader) (*Project, error) {
+	p := &Project{}
+	if err := json.NewDecoder(r).Decode(p); err != nil {
+		return nil, err
+	}
+	if p.Version > Version {
+		return nil, sdf.ErrMsg(fmt.Sprintf("project version %d is newer than %d", p.Version, Version))
+	}
+	return p, nil
+}
+
+// Write writes a project to a writer.
+func (p *Project) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// Load loads a project file.
+func Load(path string) (*Project, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Save saves a project file.
+func (p *Project) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := p.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//-----------------------------------------------------------------------------
+
+// AddPart adds a part to the project.
+func (p *Project) AddPart(part Part) error {
+	if part.Name == "" {
+		return sdf.ErrMsg("part has no name")
+	}
+	if _, err := p.Part(part.Name); err == nil {
+		return sdf.ErrMsg(fmt.Sprintf("part \"%s\" already exists", part.Name))
+	}
+	p.Parts = append(p.Parts, part)
+	return nil
+}
+
+// Part returns a named part of the project.
+func (p *Project) Part(name string) (*Part, error) {
+	for i := range p.Parts {
+		if p.Parts[i].Name == name {
+			return &p.Parts[i], nil
+		}
+	}
+	return nil, sdf.ErrMsg(fmt.Sprintf("part \"%s\" not found", name))
+}
+
+// Build builds the model for a named part.
+func (p *Project) Build(name string) (sdf.SDF3, error) {
+	part, err := p.Part(name)
+	if err != nil {
+		return nil, err
+	}
+	s, err := part.model(p.Params)
+	if err != nil {
+		return nil, fmt.Errorf("part \"%s\": %w", name, err)
+	}
+	settings := p.Render.override(part.Render)
+	if settings.Scale != 0 && settings.Scale != 1 {
+		s = sdf.ScaleUniform3D(s, settings.Scale)
+	}
+	return s, nil
+}
+
+// RenderPart renders a named part to its output file in a directory
+// and records the output in the manifest.
+func (p *Project) RenderPart(name, dir string) error {
+	s, err := p.Build(name)
+	if err != nil {
+		return err
+	}
+	part, _ := p.Part(name)
+	if part.Output == "" {
+		return sdf.ErrMsg(fmt.Sprintf("part \"%s\" has no output file", name))
+	}
+	settings := p.Render.override(part.Render)
+	r, err := settings.renderer()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("rendering %s (%s)\n", part.Output, r.Info(s))
+	mesh, err := settings.toTriangles(s, r)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, part.Output)
+	opts, err := settings.exportOptions()
+	if err != nil {
+		return err
+	}
+	if err := saveMesh(path, name, mesh, opts); err != nil {
+		return err
+	}
+	sum, err := fileSHA1(path)
+	if err != nil {
+		return err
+	}
+	p.setOutput(Output{Part: name, Path: part.Output, Triangles: len(mesh), SHA1: sum})
+	return nil
+}
+
+// RenderAll renders all parts of the project to a directory.
+func (p *Project) RenderAll(dir string) error {
+	for _, part := range p.Parts {
+		if err := p.RenderPart(part.Name, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setOutput adds or replaces a manifest entry.
+func (p *Project) setOutput(o Output) {
+	for i := range p.Outputs {
+		if p.Outputs[i].Part == o.Part {
+			p.Outputs[i] = o
+			return
+		}
+	}
+	p.Outputs = append(p.Outputs, o)
+}
+
+// fileSHA1 returns the SHA1 checksum of a file.
+func fileSHA1(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,112 @@
+//-----------------------------------------------------------------------------
+/*
+
+Project Workspace Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package project
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Project(t *testing.T) {
+	Register("test_sphere", func(p Params) (sdf.SDF3, error) {
+		return sdf.Sphere3D(p.Get("radius", 1))
+	})
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.json")
+
+	p := New("test")
+	p.Params["radius"] = 5
+	p.Render.Cells = 20
+	if err := p.AddPart(Part{Name: "small", Model: "test_sphere", Output: "small.stl"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AddPart(Part{Name: "big", Model: "test_sphere", Output: "big.3mf", Params: Params{"radius": 10}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.RenderAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Outputs) != 2 || q.Outputs[0].Triangles == 0 || q.Outputs[0].SHA1 == "" {
+		t.Errorf("bad output manifest %v", q.Outputs)
+	}
+	s, err := q.Build("big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.BoundingBox().Size().X != 20 {
+		t.Errorf("part parameter not applied")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_ProjectTree(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tree.json")
+
+	box, _ := sdf.Box3D(v3.Vec{10, 20, 30}, 1)
+	p := New("tree")
+	p.Render.Cells = 20
+	part := Part{Name: "box", Output: "box.stl"}
+	if err := part.SetTree(box); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AddPart(part); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// the loaded project builds the model without a registered builder
+	q, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := q.Build("box")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt := v3.Vec{3, 4, 5}
+	if s.Evaluate(pt) != box.Evaluate(pt) || s.BoundingBox() != box.BoundingBox() {
+		t.Errorf("model tree not restored")
+	}
+	if err := q.RenderAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Outputs) != 1 || q.Outputs[0].Triangles == 0 {
+		t.Errorf("bad output manifest %v", q.Outputs)
+	}
+
+	// a part needs one of a tree or a builder
+	q.Parts[0].Model = "test_sphere"
+	if _, err := q.Build("box"); err == nil {
+		t.Errorf("expected an error for a tree and a builder")
+	}
+	q.Parts[0].Model = ""
+	q.Parts[0].Tree = nil
+	if _, err := q.Build("box"); err == nil {
+		t.Errorf("expected an error for no tree or builder")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -1,7 +1,7 @@
 //-----------------------------------------------------------------------------
 /*
 
-Output a 3D triangle mesh to a 3MF file.
+Output a 3D triangle mesh to a 3MF file, and load the meshes of a 3MF file.
 
 https://3mf.io/specification/
 
@@ -10,11 +10,12 @@ Notes:
 3D manufacturing files (3mf) generally contain meta data about the 3d object.
 The files produced by this code are very basic. They are the equivalent of an
 STL in 3MF format. That is: just the triangle mesh with a default 1mm unit.
+The Unit export option scales the mesh and sets the model unit.
 
 File sizes for 3MF are around 7x smaller than an STL with the same mesh.
 
-3MF files are not identical from run to run. 3MF files are a zipped archive.
-The contents of the archive *are* the same but the containing zip file differs.
+3MF files are a zipped archive with timestamps, the Clock export option sets
+them for reproducible files (see reproducible.go).
 
 */
 //-----------------------------------------------------------------------------
@@ -22,10 +23,17 @@ The contents of the archive *are* the same but the containing zip file differs.
 package render
 
 import (
+	"bytes"
+	"encoding/xml"
 	"fmt"
+	"image/color"
+	"io"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/deadsy/sdfx/sdf"
+	"github.com/deadsy/sdfx/units"
 	v3 "github.com/deadsy/sdfx/vec/v3"
 	"github.com/hpinc/go3mf"
 )
@@ -37,12 +45,46 @@ func toPoint3D(a v3.Vec) go3mf.Point3D {
 	return go3mf.Point3D{float32(a.X), float32(a.Y), float32(a.Z)}
 }
 
+// units3MF returns the 3MF model unit for a length unit.
+func units3MF(u units.Unit) go3mf.Units {
+	switch u {
+	case units.Micrometer:
+		return go3mf.UnitMicrometer
+	case units.Centimeter:
+		return go3mf.UnitCentimeter
+	case units.Meter:
+		return go3mf.UnitMeter
+	case units.Inch:
+		return go3mf.UnitInch
+	case units.Foot:
+		return go3mf.UnitFoot
+	}
+	return go3mf.UnitMillimeter
+}
+
+// fingerprint3MF adds a fingerprint to the metadata of a 3MF model.
+func fingerprint3MF(model *go3mf.Model, f *Fingerprint) {
+	model.Metadata = append(model.Metadata,
+		go3mf.Metadata{Name: xml.Name{Local: "Application"}, Value: "sdfx " + f.Version},
+		go3mf.Metadata{Name: xml.Name{Local: "Description"}, Value: f.String()},
+	)
+}
+
+// encode3MF writes a 3MF model to a writer, as a reproducible archive.
+func encode3MF(w io.Writer, model *go3mf.Model, clock func() time.Time) error {
+	var buf bytes.Buffer
+	if err := go3mf.NewEncoder(&buf).Encode(model); err != nil {
+		return err
+	}
+	return reproducible3MF(w, buf.Bytes(), now(clock))
+}
+
 //-----------------------------------------------------------------------------
 
 // write3MF writes a stream of triangles to a 3MF file.
-func write3MF(wg *sync.WaitGroup, path string) (chan<- []*sdf.Triangle3, error) {
+func write3MF(wg *sync.WaitGroup, path string, opts ExportOptions) (chan<- []*sdf.Triangle3, error) {
 
-	f, err := go3mf.CreateWriter(path)
+	f, err := os.Create(path)
 	if err != nil {
 		return nil, err
 	}
@@ -51,33 +93,27 @@ func write3MF(wg *sync.WaitGroup, path string) (chan<- []*sdf.Triangle3, error)
 	// This goroutine reads the channel and writes triangles to the file.
 	c := make(chan []*sdf.Triangle3)
 
-	var model go3mf.Model
-	var mesh go3mf.Mesh
-
-	// add the mesh to the model
-	obj := &go3mf.Object{Mesh: &mesh}
-	obj.ID = model.Resources.UnusedID()
-	model.Resources.Objects = append(model.Resources.Objects, obj)
-	model.Build.Items = append(model.Build.Items, &go3mf.Item{ObjectID: obj.ID})
-
-	// use the mesh builder to de-dup the vertices
-	mb := go3mf.NewMeshBuilder(&mesh)
-
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		defer f.Close()
-		// read triangles from the channel and add them to the model
+		// read triangles from the channel
+		var triangles []*sdf.Triangle3
 		for ts := range c {
-			for _, t := range ts {
-				v1 := mb.AddVertex(toPoint3D(t[0]))
-				v2 := mb.AddVertex(toPoint3D(t[1]))
-				v3 := mb.AddVertex(toPoint3D(t[2]))
-				mesh.Triangles.Triangle = append(mesh.Triangles.Triangle, go3mf.Triangle{V1: v1, V2: v2, V3: v3})
-			}
+			triangles = append(triangles, ts...)
+		}
+		triangles = opts.facets(triangles)
+		// de-dup the vertices and add the mesh to the model
+		m, _ := NewMesh(scaleMesh(triangles, opts.Unit), 0)
+		model := go3mf.Model{Units: units3MF(opts.Unit)}
+		obj := &go3mf.Object{ID: model.Resources.UnusedID(), Mesh: m.to3MF()}
+		model.Resources.Objects = append(model.Resources.Objects, obj)
+		model.Build.Items = append(model.Build.Items, &go3mf.Item{ObjectID: obj.ID})
+		if opts.Fingerprint {
+			fingerprint3MF(&model, NewFingerprint(triangles))
 		}
 		// encode and write out the file
-		if err := f.Encode(&model); err != nil {
+		if err := encode3MF(f, &model, opts.Clock); err != nil {
 			fmt.Printf("%s\n", err)
 			return
 		}
@@ -87,3 +123,175 @@ func write3MF(wg *sync.WaitGroup, path string) (chan<- []*sdf.Triangle3, error)
 }
 
 //-----------------------------------------------------------------------------
+
+// Part3MF is a named and colored triangle mesh for a multi-part 3MF file.
+type Part3MF struct {
+	Name  string           // part name
+	Color color.RGBA       // display color
+	Mesh  []*sdf.Triangle3 // triangle mesh
+}
+
+// Save3MF writes a set of colored parts to a 3MF file.
+// Parts with empty meshes are skipped.
+func Save3MF(path string, parts []Part3MF) error {
+	return Save3MFWithOptions(path, parts, ExportOptions{})
+}
+
+// Save3MFWithOptions writes a set of colored parts to a 3MF file with export options.
+func Save3MFWithOptions(path string, parts []Part3MF, opts ExportOptions) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write3MF(f, parts, opts); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// Write3MF writes a set of colored parts as a 3MF file to a writer.
+// Parts with empty meshes are skipped.
+func Write3MF(w io.Writer, parts []Part3MF, opts ExportOptions) error {
+	model := go3mf.Model{Units: units3MF(opts.Unit)}
+	// each part gets a base material for its color
+	materials := &go3mf.BaseMaterials{ID: model.Resources.UnusedID()}
+	model.Resources.Assets = append(model.Resources.Assets, materials)
+	for _, p := range parts {
+		if len(p.Mesh) == 0 {
+			continue
+		}
+		m, err := NewMesh(scaleMesh(opts.facets(p.Mesh), opts.Unit), 0)
+		if err != nil {
+			return err
+		}
+		obj := &go3mf.Object{
+			ID:     model.Resources.UnusedID(),
+			Name:   p.Name,
+			PID:    materials.ID,
+			PIndex: uint32(len(materials.Materials)),
+			Mesh:   m.to3MF(),
+		}
+		materials.Materials = append(materials.Materials, go3mf.Base{Name: p.Name, Color: p.Color})
+		model.Resources.Objects = append(model.Resources.Objects, obj)
+		model.Build.Items = append(model.Build.Items, &go3mf.Item{ObjectID: obj.ID})
+	}
+	if len(model.Build.Items) == 0 {
+		return sdf.ErrMsg("no parts to write")
+	}
+	if opts.Fingerprint {
+		h := newModelHash()
+		for _, p := range parts {
+			h.add(opts.facets(p.Mesh))
+		}
+		fingerprint3MF(&model, h.fingerprint())
+	}
+	return encode3MF(w, &model, opts.Clock)
+}
+
+//-----------------------------------------------------------------------------
+
+// unitsScale returns the number of millimeters per 3MF model unit.
+func unitsScale(u go3mf.Units) float64 {
+	switch u {
+	case go3mf.UnitMicrometer:
+		return units.Micrometer.Scale()
+	case go3mf.UnitCentimeter:
+		return units.Centimeter.Scale()
+	case go3mf.UnitInch:
+		return units.Inch.Scale()
+	case go3mf.UnitFoot:
+		return units.Foot.Scale()
+	case go3mf.UnitMeter:
+		return units.Meter.Scale()
+	}
+	return units.Millimeter.Scale()
+}
+
+// load3MFObject adds the triangles of a 3MF object (and its components) to a mesh.
+// The transforms are applied innermost first.
+func load3MFObject(model *go3mf.Model, path string, id uint32, xf []go3mf.Matrix, depth int, mesh []*sdf.Triangle3) ([]*sdf.Triangle3, error) {
+	if depth > 32 {
+		return nil, sdf.ErrMsg("3mf components are nested too deeply")
+	}
+	obj, ok := model.FindObject(path, id)
+	if !ok {
+		return nil, sdf.ErrMsg(fmt.Sprintf("3mf object %d not found", id))
+	}
+	if obj.Mesh != nil {
+		vertex := obj.Mesh.Vertices.Vertex
+		for _, t := range obj.Mesh.Triangles.Triangle {
+			idx := [3]uint32{t.V1, t.V2, t.V3}
+			var tri sdf.Triangle3
+			for i, k := range idx {
+				if int(k) >= len(vertex) {
+					return nil, sdf.ErrMsg(fmt.Sprintf("3mf object %d has a bad vertex index", id))
+				}
+				p := vertex[k]
+				for _, m := range xf {
+					p = m.Mul3D(p)
+				}
+				tri[i] = v3.Vec{X: float64(p[0]), Y: float64(p[1]), Z: float64(p[2])}
+			}
+			mesh = append(mesh, &tri)
+		}
+	}
+	if obj.Components != nil {
+		for _, c := range obj.Components.Component {
+			cxf := xf
+			if c.HasTransform() {
+				cxf = append([]go3mf.Matrix{c.Transform}, xf...)
+			}
+			var err error
+			if mesh, err = load3MFObject(model, c.ObjectPath(path), c.ObjectID, cxf, depth+1, mesh); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return mesh, nil
+}
+
+// Load3MF loads the build items of a 3MF file as a single triangle mesh in millimeters.
+func Load3MF(path string) ([]*sdf.Triangle3, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Read3MF(f, info.Size())
+}
+
+// Read3MF reads the build items of a 3MF file of a given size as a single
+// triangle mesh in millimeters.
+func Read3MF(r io.ReaderAt, size int64) ([]*sdf.Triangle3, error) {
+	var model go3mf.Model
+	if err := go3mf.NewDecoder(r, size).Decode(&model); err != nil {
+		return nil, err
+	}
+	var err error
+	var mesh []*sdf.Triangle3
+	for _, item := range model.Build.Items {
+		var xf []go3mf.Matrix
+		if item.HasTransform() {
+			xf = []go3mf.Matrix{item.Transform}
+		}
+		if mesh, err = load3MFObject(&model, item.ObjectPath(), item.ObjectID, xf, 0, mesh); err != nil {
+			return nil, err
+		}
+	}
+	if k := unitsScale(model.Units); k != 1 {
+		for _, t := range mesh {
+			for i := range t {
+				t[i] = t[i].MulScalar(k)
+			}
+		}
+	}
+	return mesh, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,138 @@
+//-----------------------------------------------------------------------------
+/*
+
+Annotations
+
+Notes attached to points of a model for design review: labels, leader lines
+and measurement callouts (dimensions). Annotations are written to glTF files
+as separate nodes and shown by the live preview viewer.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// AnnotationKind is the kind of an annotation.
+type AnnotationKind int
+
+// Annotation kinds.
+const (
+	AnnotationLabel     AnnotationKind = iota // text at a point
+	AnnotationLeader                          // text with a leader line to a point
+	AnnotationDimension                       // distance between two points
+)
+
+func (k AnnotationKind) String() string {
+	switch k {
+	case AnnotationLabel:
+		return "label"
+	case AnnotationLeader:
+		return "leader"
+	case AnnotationDimension:
+		return "dimension"
+	}
+	return "unknown"
+}
+
+// Annotation is a note attached to points of a model.
+type Annotation struct {
+	Kind   AnnotationKind
+	Text   string
+	Points []v3.Vec // model points the annotation is attached to
+	At     v3.Vec   // text position
+	Offset v3.Vec   // dimension line offset from the measured points
+}
+
+// NewLabel returns a label at a model point.
+func NewLabel(p v3.Vec, text string) *Annotation {
+	return &Annotation{
+		Kind:   AnnotationLabel,
+		Text:   text,
+		Points: []v3.Vec{p},
+		At:     p,
+	}
+}
+
+// NewLeader returns a label at a text position with a leader line to a model point.
+func NewLeader(p, at v3.Vec, text string) *Annotation {
+	return &Annotation{
+		Kind:   AnnotationLeader,
+		Text:   text,
+		Points: []v3.Vec{p},
+		At:     at,
+	}
+}
+
+// NewDimension returns a measurement callout for the distance between two model
+// points. The dimension line is offset from the points. An empty text is replaced
+// with the distance.
+func NewDimension(a, b, offset v3.Vec, text string) *Annotation {
+	if text == "" {
+		text = fmt.Sprintf("%.2f", b.Sub(a).Length())
+	}
+	return &Annotation{
+		Kind:   AnnotationDimension,
+		Text:   text,
+		Points: []v3.Vec{a, b},
+		At:     a.Add(b).MulScalar(0.5).Add(offset),
+		Offset: offset,
+	}
+}
+
+// Lines returns the line segments drawn for an annotation.
+func (a *Annotation) Lines() [][2]v3.Vec {
+	switch a.Kind {
+	case AnnotationLeader:
+		return [][2]v3.Vec{{a.Points[0], a.At}}
+	case AnnotationDimension:
+		p0, p1 := a.Points[0], a.Points[1]
+		q0, q1 := p0.Add(a.Offset), p1.Add(a.Offset)
+		lines := [][2]v3.Vec{{q0, q1}}
+		if a.Offset.Length() != 0 {
+			// extension lines
+			lines = append(lines, [2]v3.Vec{p0, q0}, [2]v3.Vec{p1, q1})
+		}
+		return lines
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// annotationJSON is the JSON form of an annotation for the viewer and glTF extras.
+type annotationJSON struct {
+	Kind   string       `json:"kind"`
+	Text   string       `json:"text"`
+	Points [][3]float64 `json:"points"`
+	At     [3]float64   `json:"at"`
+	Lines  [][6]float64 `json:"lines,omitempty"`
+}
+
+func toArray(v v3.Vec) [3]float64 {
+	return [3]float64{v.X, v.Y, v.Z}
+}
+
+func (a *Annotation) toJSON() annotationJSON {
+	j := annotationJSON{
+		Kind: a.Kind.String(),
+		Text: a.Text,
+		At:   toArray(a.At),
+	}
+	for _, p := range a.Points {
+		j.Points = append(j.Points, toArray(p))
+	}
+	for _, l := range a.Lines() {
+		j.Lines = append(j.Lines, [6]float64{l[0].X, l[0].Y, l[0].Z, l[1].X, l[1].Y, l[1].Z})
+	}
+	return j
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,119 @@
+//-----------------------------------------------------------------------------
+/*
+
+Rendering Benchmarks
+
+Benchmarks for the renderers at standard resolutions and the mesh export:
+
+	go test -run XXX -bench . -count 5 ./render > new.txt
+	sdfxbench old.txt new.txt
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// benchCells are the standard render resolutions (cells on the longest axis).
+var benchCells = []int{50, 100, 200}
+
+// benchModel3 returns the benchmark model: a box with a hole and a rounded boss.
+func benchModel3() sdf.SDF3 {
+	box, _ := sdf.Box3D(v3.Vec{40, 30, 10}, 2)
+	hole, _ := sdf.Cylinder3D(12, 6, 0)
+	boss, _ := sdf.Sphere3D(8)
+	boss = sdf.Transform3D(boss, sdf.Translate3d(v3.Vec{12, 0, 5}))
+	return sdf.Union3D(sdf.Difference3D(box, hole), boss)
+}
+
+// benchModel2 returns the 2d benchmark model: a rounded plate with a hole.
+func benchModel2() sdf.SDF2 {
+	hole, _ := sdf.Circle2D(6)
+	return sdf.Difference2D(sdf.Box2D(v2.Vec{40, 30}, 4), hole)
+}
+
+// lineCounter is a line writer that counts the lines.
+type lineCounter struct {
+	n int
+}
+
+func (c *lineCounter) Write(in []*sdf.Line2) error {
+	c.n += len(in)
+	return nil
+}
+
+func (c *lineCounter) Close() error {
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func Benchmark_Render3(b *testing.B) {
+	s := benchModel3()
+	renderers := []struct {
+		name string
+		new  func(cells int) Render3
+	}{
+		{"MarchingCubesUniform", func(n int) Render3 { return NewMarchingCubesUniform(n) }},
+		{"MarchingCubesOctree", func(n int) Render3 { return NewMarchingCubesOctree(n) }},
+	}
+	for _, r := range renderers {
+		for _, n := range benchCells {
+			b.Run(fmt.Sprintf("%s/%d", r.name, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					ToTriangles(s, r.new(n))
+				}
+			})
+		}
+	}
+}
+
+func Benchmark_Render2(b *testing.B) {
+	s := benchModel2()
+	renderers := []struct {
+		name string
+		new  func(cells int) Render2
+	}{
+		{"MarchingSquaresUniform", func(n int) Render2 { return NewMarchingSquaresUniform(n) }},
+		{"MarchingSquaresQuadtree", func(n int) Render2 { return NewMarchingSquaresQuadtree(n) }},
+		{"DualContouring2D", func(n int) Render2 { return NewDualContouring2D(n) }},
+	}
+	for _, r := range renderers {
+		for _, n := range benchCells {
+			b.Run(fmt.Sprintf("%s/%d", r.name, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					r.new(n).Render(s, &lineCounter{})
+				}
+			})
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Benchmark_Export(b *testing.B) {
+	mesh := ToTriangles(benchModel3(), NewMarchingCubesOctree(100))
+	b.Run("NewMesh", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewMesh(mesh, 0)
+		}
+	})
+	b.Run("STL", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			writeSTLBinary(io.Discard, mesh, ExportOptions{})
+		}
+	})
+}
+
+//-----------------------------------------------------------------------------
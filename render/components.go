@@ -0,0 +1,311 @@
+//-----------------------------------------------------------------------------
+/*
+
+Multi-Body Components
+
+Find the disconnected solids of an SDF3 (e.g. the parts of a union used for
+print plating) so they can be exported as separate meshes, files or 3MF
+objects.
+
+The SDF3 is sampled at the centers of the cells of a coarse grid. A cell is
+solid if its center is inside, or for a cell the surface passes through,
+if a center of its subdivided cells is inside (so thin walls aren't lost).
+The solid cells are flood filled into components and every cell of the grid
+is labelled with the nearest component.
+
+A component is the SDF3 where the grid has its label, and the absolute
+distance elsewhere, so the other solids have no surface. Solids closer than
+about a cell are one component.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// componentCells is the default number of grid cells on the longest side of the bounding box.
+const componentCells = 100
+
+// componentRefine is the number of times a cell on the surface is subdivided.
+const componentRefine = 2
+
+// componentGrid is the grid of component labels of an SDF3.
+type componentGrid struct {
+	origin v3.Vec  // center of the first cell
+	h      float64 // cell size
+	n      v3i.Vec // number of cells
+	label  []int32 // nearest component of each cell
+	boxes  []sdf.Box3
+}
+
+// index returns the cell index of a point, clamped to the grid.
+func (g *componentGrid) index(p v3.Vec) int {
+	q := p.Sub(g.origin).DivScalar(g.h)
+	i := clampInt(int(math.Round(q.X)), 0, g.n.X-1)
+	j := clampInt(int(math.Round(q.Y)), 0, g.n.Y-1)
+	k := clampInt(int(math.Round(q.Z)), 0, g.n.Z-1)
+	return i + g.n.X*(j+g.n.Y*k)
+}
+
+// at returns the component label of a point.
+func (g *componentGrid) at(p v3.Vec) int {
+	return int(g.label[g.index(p)])
+}
+
+func clampInt(x, a, b int) int {
+	if x < a {
+		return a
+	}
+	if x > b {
+		return b
+	}
+	return x
+}
+
+// solid returns true if a cell (center, size) has an inside point on its
+// subdivided grid.
+func solid(s sdf.SDF3, c v3.Vec, h float64, level int) bool {
+	d := s.Evaluate(c)
+	if d < 0 {
+		return true
+	}
+	// half diagonal
+	if level == 0 || d >= 0.5*math.Sqrt(3)*h {
+		return false
+	}
+	q := 0.25 * h
+	for _, o := range []v3.Vec{
+		{-q, -q, -q}, {q, -q, -q}, {-q, q, -q}, {q, q, -q},
+		{-q, -q, q}, {q, -q, q}, {-q, q, q}, {q, q, q},
+	} {
+		if solid(s, c.Add(o), 0.5*h, level-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// newComponentGrid finds the components of an SDF3 on a grid.
+func newComponentGrid(s sdf.SDF3, cells int) (*componentGrid, error) {
+	if cells <= 0 {
+		return nil, sdf.ErrMsg("cells <= 0")
+	}
+	bb := s.BoundingBox()
+	h := bb.Size().MaxComponent() / float64(cells)
+	if h <= 0 {
+		return nil, sdf.ErrMsg("empty bounding box")
+	}
+	// a layer of cells around the bounding box
+	bb = bb.Enlarge(v3.Vec{X: 2 * h, Y: 2 * h, Z: 2 * h})
+	size := bb.Size()
+	g := &componentGrid{
+		origin: bb.Min.AddScalar(0.5 * h),
+		h:      h,
+		n: v3i.Vec{
+			int(math.Ceil(size.X / h)),
+			int(math.Ceil(size.Y / h)),
+			int(math.Ceil(size.Z / h)),
+		},
+	}
+	n := g.n
+	index := func(i, j, k int) int { return i + n.X*(j+n.Y*k) }
+	center := func(i, j, k int) v3.Vec {
+		return g.origin.Add(v3.Vec{X: float64(i), Y: float64(j), Z: float64(k)}.MulScalar(h))
+	}
+
+	// -2 is empty, -1 is solid and not labelled
+	const (
+		empty    = -2
+		unfilled = -1
+	)
+	g.label = make([]int32, n.X*n.Y*n.Z)
+	for k := 0; k < n.Z; k++ {
+		for j := 0; j < n.Y; j++ {
+			for i := 0; i < n.X; i++ {
+				g.label[index(i, j, k)] = empty
+				if solid(s, center(i, j, k), h, componentRefine) {
+					g.label[index(i, j, k)] = unfilled
+				}
+			}
+		}
+	}
+
+	neighbors := []v3i.Vec{{1, 0, 0}, {-1, 0, 0}, {0, 1, 0}, {0, -1, 0}, {0, 0, 1}, {0, 0, -1}}
+	// flood fill the solid cells, the labelled cells are the seeds for the nearest labels
+	var queue []v3i.Vec
+	for k := 0; k < n.Z; k++ {
+		for j := 0; j < n.Y; j++ {
+			for i := 0; i < n.X; i++ {
+				if g.label[index(i, j, k)] != unfilled {
+					continue
+				}
+				id := int32(len(g.boxes))
+				box := sdf.Box3{Min: center(i, j, k), Max: center(i, j, k)}
+				g.label[index(i, j, k)] = id
+				stack := []v3i.Vec{{i, j, k}}
+				for len(stack) > 0 {
+					p := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					queue = append(queue, p)
+					box = box.Include(center(p.X, p.Y, p.Z))
+					for _, d := range neighbors {
+						q := p.Add(d)
+						if q.X < 0 || q.Y < 0 || q.Z < 0 || q.X >= n.X || q.Y >= n.Y || q.Z >= n.Z {
+							continue
+						}
+						if x := index(q.X, q.Y, q.Z); g.label[x] == unfilled {
+							g.label[x] = id
+							stack = append(stack, q)
+						}
+					}
+				}
+				// the cells are a cell size apart
+				g.boxes = append(g.boxes, box.Enlarge(v3.Vec{X: 2 * h, Y: 2 * h, Z: 2 * h}))
+			}
+		}
+	}
+	if len(g.boxes) == 0 {
+		return nil, sdf.ErrMsg("no solid found")
+	}
+
+	// label the empty cells with the nearest component
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		id := g.label[index(p.X, p.Y, p.Z)]
+		for _, d := range neighbors {
+			q := p.Add(d)
+			if q.X < 0 || q.Y < 0 || q.Z < 0 || q.X >= n.X || q.Y >= n.Y || q.Z >= n.Z {
+				continue
+			}
+			if x := index(q.X, q.Y, q.Z); g.label[x] == empty {
+				g.label[x] = id
+				queue = append(queue, q)
+			}
+		}
+	}
+	return g, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// ComponentSDF3 is a connected solid of an SDF3.
+type ComponentSDF3 struct {
+	sdf  sdf.SDF3
+	grid *componentGrid
+	id   int
+	bb   sdf.Box3
+}
+
+// Evaluate returns the minimum distance to a component.
+func (s *ComponentSDF3) Evaluate(p v3.Vec) float64 {
+	d := s.sdf.Evaluate(p)
+	if s.grid.at(p) != s.id {
+		return math.Abs(d)
+	}
+	return d
+}
+
+// BoundingBox returns the bounding box of a component.
+func (s *ComponentSDF3) BoundingBox() sdf.Box3 {
+	return s.bb
+}
+
+// Components returns the disconnected solids of an SDF3.
+func Components(s sdf.SDF3) ([]sdf.SDF3, error) {
+	return ComponentsCells(s, componentCells)
+}
+
+// ComponentsCells returns the disconnected solids of an SDF3, found on a grid
+// with a number of cells on the longest side of the bounding box.
+func ComponentsCells(s sdf.SDF3, cells int) ([]sdf.SDF3, error) {
+	g, err := newComponentGrid(s, cells)
+	if err != nil {
+		return nil, err
+	}
+	components := make([]sdf.SDF3, len(g.boxes))
+	for i, bb := range g.boxes {
+		components[i] = &ComponentSDF3{sdf: s, grid: g, id: i, bb: bb}
+	}
+	return components, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// ToComponents renders an SDF3 and splits the mesh into the meshes of its
+// disconnected solids.
+func ToComponents(s sdf.SDF3, r Render3) ([][]*sdf.Triangle3, error) {
+	g, err := newComponentGrid(s, componentCells)
+	if err != nil {
+		return nil, err
+	}
+	meshes := make([][]*sdf.Triangle3, len(g.boxes))
+	for _, t := range ToTriangles(s, r) {
+		k := g.at(t[0].Add(t[1]).Add(t[2]).DivScalar(3))
+		meshes[k] = append(meshes[k], t)
+	}
+	// drop any components without faces
+	out := meshes[:0]
+	for _, m := range meshes {
+		if len(m) != 0 {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// componentPath returns the file name of a component, e.g. part_2.stl.
+func componentPath(path string, i int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(path, ext), i+1, ext)
+}
+
+// ToSTLComponents renders the disconnected solids of an SDF3 to STL files,
+// numbered from 1 (e.g. part_1.stl, part_2.stl). It returns the file names.
+func ToSTLComponents(s sdf.SDF3, path string, r Render3, opts ExportOptions) ([]string, error) {
+	meshes, err := ToComponents(s, r)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(meshes))
+	for i, m := range meshes {
+		paths[i] = componentPath(path, i)
+		if err := SaveSTLWithOptions(paths[i], m, opts); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// To3MFComponents renders the disconnected solids of an SDF3 to a 3MF file
+// with an object for each.
+func To3MFComponents(s sdf.SDF3, path string, r Render3, opts ExportOptions) error {
+	meshes, err := ToComponents(s, r)
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	parts := make([]Part3MF, len(meshes))
+	for i, m := range meshes {
+		parts[i] = Part3MF{
+			Name:  fmt.Sprintf("%s %d", name, i+1),
+			Color: DefaultMaterial().Color,
+			Mesh:  m,
+		}
+	}
+	return Save3MFWithOptions(path, parts, opts)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,127 @@
+//-----------------------------------------------------------------------------
+/*
+
+Multi-Body Component Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// ringAndPeg returns a peg through a ring that doesn't touch it.
+func ringAndPeg() sdf.SDF3 {
+	peg, _ := sdf.Cylinder3D(20, 2, 0)
+	outer, _ := sdf.Cylinder3D(4, 6, 0)
+	inner, _ := sdf.Cylinder3D(6, 4, 0)
+	return sdf.Union3D(peg, sdf.Difference3D(outer, inner))
+}
+
+func Test_Components(t *testing.T) {
+	box, _ := sdf.Box3D(v3.Vec{X: 10, Y: 10, Z: 10}, 0)
+	twoBoxes := sdf.Union3D(box, sdf.Transform3D(box, sdf.Translate3d(v3.Vec{X: 15})))
+	ball, _ := sdf.Sphere3D(5)
+	joined := sdf.Union3D(box, sdf.Transform3D(ball, sdf.Translate3d(v3.Vec{X: 8})))
+
+	tests := []struct {
+		name string
+		s    sdf.SDF3
+		n    int
+	}{
+		{"two boxes", twoBoxes, 2},
+		{"ring and peg", ringAndPeg(), 2},
+		{"box", box, 1},
+		{"joined", joined, 1},
+	}
+	for _, test := range tests {
+		components, err := ComponentsCells(test.s, 50)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(components) != test.n {
+			t.Errorf("%s: expected %d components, got %d", test.name, test.n, len(components))
+		}
+	}
+
+	// a component has the surface of its solid only
+	components, _ := ComponentsCells(twoBoxes, 50)
+	a, b := components[0], components[1]
+	if a.Evaluate(v3.Vec{}) >= 0 || a.Evaluate(v3.Vec{X: 15}) <= 0 {
+		t.Error("bad first component")
+	}
+	if b.Evaluate(v3.Vec{X: 15}) >= 0 || b.Evaluate(v3.Vec{}) <= 0 {
+		t.Error("bad second component")
+	}
+	if a.BoundingBox().Contains(v3.Vec{X: 15}) {
+		t.Error("bad first component bounding box")
+	}
+
+	if _, err := Components(sdf.Transform3D(box, sdf.Translate3d(v3.Vec{X: 1e3}))); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_ToComponents(t *testing.T) {
+	s := ringAndPeg()
+	r := NewMarchingCubesOctree(60)
+	meshes, err := ToComponents(s, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meshes) != 2 {
+		t.Fatalf("expected 2 meshes, got %d", len(meshes))
+	}
+	if n := len(meshes[0]) + len(meshes[1]); n != len(ToTriangles(s, r)) {
+		t.Errorf("split meshes have %d faces, expected %d", n, len(ToTriangles(s, r)))
+	}
+
+	dir := t.TempDir()
+	paths, err := ToSTLComponents(s, filepath.Join(dir, "part.stl"), r, ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, path := range paths {
+		if filepath.Base(path) != []string{"part_1.stl", "part_2.stl"}[i] {
+			t.Errorf("bad file name %s", path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Error(err)
+		}
+	}
+
+	path := filepath.Join(dir, "part.3mf")
+	if err := To3MFComponents(s, path, r, ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+	for _, f := range z.File {
+		if !strings.HasSuffix(f.Name, ".model") {
+			continue
+		}
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if n := strings.Count(string(data), "<object "); n != 2 {
+			t.Errorf("expected 2 3mf objects, got %d", n)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
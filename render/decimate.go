@@ -0,0 +1,392 @@
+//-----------------------------------------------------------------------------
+/*
+
+Mesh Decimation
+
+Reduce the triangle count of a mesh with quadric error metric edge collapse
+(Garland and Heckbert, "Surface Simplification Using Quadric Error Metrics").
+
+Each vertex has a quadric that measures the squared distance to the planes
+of its original faces. Collapsing an edge merges the quadrics of its two
+vertices and moves the merged vertex to the point of least error. Edges are
+collapsed cheapest first. Sharp features have a high collapse cost, so they
+are kept while flat and smoothly curved areas are reduced.
+
+The boundary edges of an open mesh get extra planes perpendicular to their
+faces, so the boundary is kept. Collapses that would flip a face or make the
+mesh non-manifold are skipped.
+
+The vertices of the input mesh are welded, so meshes with nearly coincident
+vertices (e.g. from marching cubes) are decimated as connected surfaces.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"container/heap"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// DecimateParms defines the parameters for mesh decimation.
+type DecimateParms struct {
+	Target   int     // target number of triangles (0 = no limit)
+	MaxError float64 // maximum distance from the original surface (0 = no limit)
+}
+
+// boundaryWeight is the weight of the boundary edge planes.
+const boundaryWeight = 1e3
+
+// minFlipCos is the minimum cosine of the angle a face normal may turn in a collapse.
+const minFlipCos = 0.2
+
+//-----------------------------------------------------------------------------
+
+// quadric is a symmetric 4x4 matrix (a2 ab ac ad b2 bc bd c2 cd d2)
+// and the total weight of its surface planes.
+type quadric [11]float64
+
+// planeQuadric returns the weighted quadric for the plane n.p + d = 0.
+func planeQuadric(n v3.Vec, d, w float64) quadric {
+	a, b, c := n.X, n.Y, n.Z
+	return quadric{
+		w * a * a, w * a * b, w * a * c, w * a * d,
+		w * b * b, w * b * c, w * b * d,
+		w * c * c, w * c * d,
+		w * d * d,
+		w,
+	}
+}
+
+func (q *quadric) add(r *quadric) {
+	for i := range q {
+		q[i] += r[i]
+	}
+}
+
+// eval returns the quadric error at a point.
+func (q *quadric) eval(p v3.Vec) float64 {
+	x, y, z := p.X, p.Y, p.Z
+	return q[0]*x*x + 2*q[1]*x*y + 2*q[2]*x*z + 2*q[3]*x +
+		q[4]*y*y + 2*q[5]*y*z + 2*q[6]*y +
+		q[7]*z*z + 2*q[8]*z +
+		q[9]
+}
+
+// optimal returns the point of least error, and false if it is not unique.
+func (q *quadric) optimal() (v3.Vec, bool) {
+	// solve A.p = -b with Cramer's rule
+	det3 := func(c0, c1, c2 v3.Vec) float64 {
+		return c0.Dot(c1.Cross(c2))
+	}
+	c0 := v3.Vec{q[0], q[1], q[2]}
+	c1 := v3.Vec{q[1], q[4], q[5]}
+	c2 := v3.Vec{q[2], q[5], q[7]}
+	b := v3.Vec{-q[3], -q[6], -q[8]}
+	det := det3(c0, c1, c2)
+	scale := q[0] + q[4] + q[7]
+	if math.Abs(det) <= 1e-9*scale*scale*scale {
+		return v3.Vec{}, false
+	}
+	return v3.Vec{det3(b, c1, c2), det3(c0, b, c2), det3(c0, c1, b)}.DivScalar(det), true
+}
+
+// err returns the mean squared distance from a point to the surface planes.
+func (q *quadric) err(p v3.Vec) float64 {
+	if q[10] == 0 {
+		return 0
+	}
+	return q.eval(p) / q[10]
+}
+
+//-----------------------------------------------------------------------------
+
+// collapse is a candidate edge collapse.
+type collapse struct {
+	a, b   int     // vertices
+	sa, sb int     // vertex stamps when the collapse was computed
+	cost   float64 // quadric error
+	err    float64 // mean squared distance to the surface planes
+	p      v3.Vec  // position of the merged vertex
+}
+
+type collapseHeap []collapse
+
+func (h collapseHeap) Len() int            { return len(h) }
+func (h collapseHeap) Less(i, j int) bool  { return h[i].cost < h[j].cost }
+func (h collapseHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *collapseHeap) Push(x interface{}) { *h = append(*h, x.(collapse)) }
+func (h *collapseHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// decimator is the state of a mesh decimation.
+type decimator struct {
+	pos     []v3.Vec  // vertex positions
+	q       []quadric // vertex quadrics
+	stamp   []int     // vertex stamps, incremented when a vertex changes
+	removed []bool    // removed vertices
+	vfaces  [][]int   // faces of each vertex
+	faces   [][3]int  // triangles
+	alive   []bool    // live faces
+	nfaces  int       // number of live faces
+	heap    collapseHeap
+}
+
+func newDecimator(mesh []*sdf.Triangle3) *decimator {
+	m, _ := NewMesh(mesh, weldTolerance(mesh))
+	pos, faces := m.Vertices, m.Faces
+	d := &decimator{
+		pos:     pos,
+		q:       make([]quadric, len(pos)),
+		stamp:   make([]int, len(pos)),
+		removed: make([]bool, len(pos)),
+		vfaces:  make([][]int, len(pos)),
+		faces:   faces,
+		alive:   make([]bool, len(faces)),
+		nfaces:  len(faces),
+	}
+	// face quadrics, weighted by area
+	edges := make(map[[2]int]int)
+	for i, f := range faces {
+		d.alive[i] = true
+		n, area := d.normal(f)
+		if area > 0 {
+			q := planeQuadric(n, -n.Dot(pos[f[0]]), area)
+			for _, v := range f {
+				d.q[v].add(&q)
+			}
+		}
+		for j, v := range f {
+			d.vfaces[v] = append(d.vfaces[v], i)
+			e := [2]int{v, f[(j+1)%3]}
+			if e[0] > e[1] {
+				e[0], e[1] = e[1], e[0]
+			}
+			edges[e]++
+		}
+	}
+	// boundary edge quadrics
+	for _, f := range faces {
+		n, _ := d.normal(f)
+		for j := range f {
+			a, b := f[j], f[(j+1)%3]
+			e := [2]int{a, b}
+			if e[0] > e[1] {
+				e[0], e[1] = e[1], e[0]
+			}
+			if edges[e] != 1 {
+				continue
+			}
+			edge := pos[b].Sub(pos[a])
+			m := edge.Cross(n)
+			if m.Length() == 0 {
+				continue
+			}
+			m = m.Normalize()
+			q := planeQuadric(m, -m.Dot(pos[a]), boundaryWeight*edge.Length2())
+			// not a surface plane
+			q[10] = 0
+			d.q[a].add(&q)
+			d.q[b].add(&q)
+		}
+	}
+	for e := range edges {
+		d.push(e[0], e[1])
+	}
+	return d
+}
+
+// normal returns the unit normal and the area of a face.
+func (d *decimator) normal(f [3]int) (v3.Vec, float64) {
+	n := d.pos[f[1]].Sub(d.pos[f[0]]).Cross(d.pos[f[2]].Sub(d.pos[f[0]]))
+	l := n.Length()
+	if l == 0 {
+		return v3.Vec{}, 0
+	}
+	return n.DivScalar(l), 0.5 * l
+}
+
+// push adds the collapse of an edge to the heap.
+func (d *decimator) push(a, b int) {
+	q := d.q[a]
+	q.add(&d.q[b])
+	p, ok := q.optimal()
+	cost := 0.0
+	if ok {
+		cost = q.eval(p)
+	}
+	if !ok || cost > q.eval(d.pos[a]) || cost > q.eval(d.pos[b]) {
+		// pick the best of the end points and the midpoint
+		p, cost = d.pos[a], q.eval(d.pos[a])
+		for _, x := range []v3.Vec{d.pos[b], d.pos[a].Add(d.pos[b]).MulScalar(0.5)} {
+			if c := q.eval(x); c < cost {
+				p, cost = x, c
+			}
+		}
+	}
+	heap.Push(&d.heap, collapse{a, b, d.stamp[a], d.stamp[b], math.Max(cost, 0), q.err(p), p})
+}
+
+// neighbors returns the neighboring vertices of a vertex.
+func (d *decimator) neighbors(v int) map[int]bool {
+	n := make(map[int]bool)
+	for _, i := range d.vfaces[v] {
+		for _, u := range d.faces[i] {
+			if u != v {
+				n[u] = true
+			}
+		}
+	}
+	return n
+}
+
+// valid returns true if the collapse of edge a-b to p keeps the mesh manifold and doesn't flip faces.
+func (d *decimator) valid(a, b int, p v3.Vec) bool {
+	// link condition: the common neighbors are the opposite vertices of the shared faces
+	shared := 0
+	for _, i := range d.vfaces[a] {
+		f := d.faces[i]
+		if f[0] == b || f[1] == b || f[2] == b {
+			shared++
+		}
+	}
+	na := d.neighbors(a)
+	common := 0
+	for u := range d.neighbors(b) {
+		if na[u] {
+			common++
+		}
+	}
+	if common != shared {
+		return false
+	}
+	// face flips
+	for _, v := range []int{a, b} {
+		for _, i := range d.vfaces[v] {
+			f := d.faces[i]
+			if (f[0] == a || f[1] == a || f[2] == a) && (f[0] == b || f[1] == b || f[2] == b) {
+				// removed by the collapse
+				continue
+			}
+			n0, _ := d.normal(f)
+			old := d.pos[v]
+			d.pos[v] = p
+			n1, area := d.normal(f)
+			d.pos[v] = old
+			if area == 0 || n0.Dot(n1) < minFlipCos {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// collapse merges vertex b into vertex a at position p.
+func (d *decimator) collapse(a, b int, p v3.Vec) {
+	var faces []int
+	for _, i := range d.vfaces[a] {
+		f := d.faces[i]
+		if f[0] == b || f[1] == b || f[2] == b {
+			// remove the shared face from its third vertex
+			d.alive[i] = false
+			d.nfaces--
+			for _, u := range f {
+				if u != a && u != b {
+					d.vfaces[u] = remove(d.vfaces[u], i)
+				}
+			}
+			continue
+		}
+		faces = append(faces, i)
+	}
+	for _, i := range d.vfaces[b] {
+		if !d.alive[i] {
+			continue
+		}
+		f := &d.faces[i]
+		for j := range f {
+			if f[j] == b {
+				f[j] = a
+			}
+		}
+		faces = append(faces, i)
+	}
+	d.vfaces[a] = faces
+	d.vfaces[b] = nil
+	d.removed[b] = true
+	d.pos[a] = p
+	d.q[a].add(&d.q[b])
+	d.stamp[a]++
+	for u := range d.neighbors(a) {
+		d.push(a, u)
+	}
+}
+
+// remove removes a value from a slice.
+func remove(s []int, x int) []int {
+	for i, y := range s {
+		if y == x {
+			return append(s[:i], s[i+1:]...)
+		}
+	}
+	return s
+}
+
+//-----------------------------------------------------------------------------
+
+// Decimate reduces the number of triangles of a mesh until the target count is
+// reached or every remaining collapse would exceed the maximum error. The error
+// of a collapse is the RMS distance of the merged vertex from the original
+// surface planes around it.
+func Decimate(mesh []*sdf.Triangle3, k *DecimateParms) ([]*sdf.Triangle3, error) {
+	if k.Target < 0 {
+		return nil, sdf.ErrMsg("Target < 0")
+	}
+	if k.MaxError < 0 {
+		return nil, sdf.ErrMsg("MaxError < 0")
+	}
+	if k.Target == 0 && k.MaxError == 0 {
+		return nil, sdf.ErrMsg("no Target or MaxError")
+	}
+	if len(mesh) == 0 {
+		return nil, sdf.ErrMsg("empty mesh")
+	}
+	d := newDecimator(mesh)
+	maxErr := math.Inf(1)
+	if k.MaxError > 0 {
+		maxErr = k.MaxError * k.MaxError
+	}
+	for d.nfaces > k.Target && d.heap.Len() > 0 {
+		c := heap.Pop(&d.heap).(collapse)
+		if d.removed[c.a] || d.removed[c.b] || d.stamp[c.a] != c.sa || d.stamp[c.b] != c.sb {
+			// stale
+			continue
+		}
+		if c.err > maxErr {
+			continue
+		}
+		if !d.valid(c.a, c.b, c.p) {
+			continue
+		}
+		d.collapse(c.a, c.b, c.p)
+	}
+	out := make([]*sdf.Triangle3, 0, d.nfaces)
+	for i, f := range d.faces {
+		if d.alive[i] {
+			out = append(out, &sdf.Triangle3{d.pos[f[0]], d.pos[f[1]], d.pos[f[2]]})
+		}
+	}
+	return out, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,93 @@
+//-----------------------------------------------------------------------------
+/*
+
+Mesh Decimation Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// closedMesh returns true if every edge of a mesh is shared by two faces with opposite directions.
+func closedMesh(mesh []*sdf.Triangle3) bool {
+	edges := make(map[[2]v3.Vec]int)
+	for _, t := range mesh {
+		for i := range t {
+			edges[[2]v3.Vec{t[i], t[(i+1)%3]}]++
+		}
+	}
+	for e, n := range edges {
+		if n != 1 || edges[[2]v3.Vec{e[1], e[0]}] != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+func Test_Decimate_Target(t *testing.T) {
+	s, _ := sdf.Sphere3D(10)
+	mesh, err := Decimate(ToTriangles(s, NewMarchingCubesUniform(50)), &DecimateParms{Target: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mesh) > 1000 || len(mesh) < 900 {
+		t.Errorf("expected about 1000 triangles, got %d", len(mesh))
+	}
+	if !closedMesh(mesh) {
+		t.Error("mesh is not closed")
+	}
+	for _, tri := range mesh {
+		for _, p := range tri {
+			if d := math.Abs(p.Length() - 10); d > 0.2 {
+				t.Fatalf("vertex %v is %f from the surface", p, d)
+			}
+		}
+		if tri.Normal().Dot(tri[0]) <= 0 {
+			t.Fatal("flipped triangle")
+		}
+	}
+}
+
+func Test_Decimate_MaxError(t *testing.T) {
+	s, _ := sdf.Box3D(v3.Vec{20, 10, 5}, 0)
+	in := ToTriangles(s, NewMarchingCubesUniform(60))
+	mesh, err := Decimate(in, &DecimateParms{MaxError: 0.01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mesh) > len(in)/10 {
+		t.Errorf("expected a large reduction, got %d of %d triangles", len(mesh), len(in))
+	}
+	if !closedMesh(mesh) {
+		t.Error("mesh is not closed")
+	}
+	bb := mesh[0].BoundingBox()
+	for _, tri := range mesh[1:] {
+		bb = bb.Extend(tri.BoundingBox())
+	}
+	if !bb.Equals(s.BoundingBox(), 0.5) {
+		t.Errorf("bounding box %v not kept", bb)
+	}
+}
+
+func Test_Decimate_Errors(t *testing.T) {
+	s, _ := sdf.Sphere3D(10)
+	mesh := ToTriangles(s, NewMarchingCubesUniform(10))
+	for _, k := range []DecimateParms{{}, {Target: -1}, {MaxError: -1}} {
+		if _, err := Decimate(mesh, &k); err == nil {
+			t.Errorf("expected an error for %v", k)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
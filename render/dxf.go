@@ -11,6 +11,7 @@ package render
 import (
 	"errors"
 	"fmt"
+	"io"
 	"sync"
 
 	"github.com/deadsy/sdfx/sdf"
@@ -92,12 +93,7 @@ func (d *DXF) Save() error {
 // SaveDXF writes line segments to a DXF file.
 func SaveDXF(path string, mesh []*sdf.Line2) error {
 	d := NewDXF(path)
-	d.drawing.ChangeLayer("Lines")
-	for i := range mesh {
-		p0 := mesh[i][0]
-		p1 := mesh[i][1]
-		d.drawing.Line(p0.X, p0.Y, 0, p1.X, p1.Y, 0)
-	}
+	d.Lines(mesh)
 	err := d.Save()
 	if err != nil {
 		return err
@@ -105,6 +101,14 @@ func SaveDXF(path string, mesh []*sdf.Line2) error {
 	return nil
 }
 
+// WriteDXF writes line segments as a DXF file to a writer.
+func WriteDXF(w io.Writer, mesh []*sdf.Line2) error {
+	d := NewDXF("")
+	d.Lines(mesh)
+	_, err := d.drawing.WriteTo(w)
+	return err
+}
+
 //-----------------------------------------------------------------------------
 
 // writeDXF writes a stream of line segments to a DXF file.
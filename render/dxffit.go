@@ -0,0 +1,337 @@
+//-----------------------------------------------------------------------------
+/*
+
+DXF Curve Fitting and Layers
+
+Marching squares produces dense polylines. CNC and laser CAM software
+prefers clean geometry, so the contours are fitted with lines, arcs,
+circles and (optionally) splines before they are written to the DXF file.
+
+The fit is greedy: starting at a corner of the contour, each run of points
+is extended as a line and as an arc while all its points are within the
+tolerance of the fitted curve, and the longer fit wins. Short lines that
+are left over on free-form curves can be merged into cubic B-splines.
+
+Each SDF2 can be written to its own named layer, e.g. cut/engrave/outline.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/yofu/dxf"
+	"github.com/yofu/dxf/color"
+	"github.com/yofu/dxf/entity"
+)
+
+//-----------------------------------------------------------------------------
+
+// minArcPoints is the minimum number of contour points fitted by an arc.
+const minArcPoints = 5
+
+// maxSplineSegments is the maximum number of segments of a line to merge it into a spline.
+const maxSplineSegments = 2
+
+type fitKind int
+
+const (
+	fitLine fitKind = iota
+	fitArc
+	fitCircle
+	fitSpline
+)
+
+// fitPrimitive is a curve fitted to a run of contour points.
+type fitPrimitive struct {
+	kind   fitKind
+	pts    []v2.Vec // end points (line/arc) or control points (spline)
+	center v2.Vec   // arc/circle center
+	radius float64  // arc/circle radius
+	ccw    bool     // arc direction
+	n      int      // number of contour segments covered
+}
+
+// circle3 returns the circle through 3 points.
+func circle3(a, b, c v2.Vec) (v2.Vec, float64, bool) {
+	d := 2 * (a.X*(b.Y-c.Y) + b.X*(c.Y-a.Y) + c.X*(a.Y-b.Y))
+	if math.Abs(d) < tolerance {
+		return v2.Vec{}, 0, false
+	}
+	a2, b2, c2 := a.Length2(), b.Length2(), c.Length2()
+	center := v2.Vec{
+		(a2*(b.Y-c.Y) + b2*(c.Y-a.Y) + c2*(a.Y-b.Y)) / d,
+		(a2*(c.X-b.X) + b2*(a.X-c.X) + c2*(b.X-a.X)) / d,
+	}
+	return center, center.Sub(a).Length(), true
+}
+
+// lineFits returns true if the points from i to j are within tol of the line from i to j.
+func lineFits(pts []v2.Vec, i, j int, tol float64) bool {
+	a, b := pts[i], pts[j]
+	ab := b.Sub(a)
+	l := ab.Length()
+	if l < tolerance {
+		return false
+	}
+	for k := i + 1; k < j; k++ {
+		if math.Abs(ab.Cross(pts[k].Sub(a)))/l > tol {
+			return false
+		}
+	}
+	return true
+}
+
+// arcFit returns the arc through the points from i to j if all the points are within tol of it.
+func arcFit(pts []v2.Vec, i, j int, tol, maxRadius float64) (fitPrimitive, bool) {
+	m := (i + j) / 2
+	c, r, ok := circle3(pts[i], pts[m], pts[j])
+	if !ok || r > maxRadius {
+		return fitPrimitive{}, false
+	}
+	ccw := pts[m].Sub(pts[i]).Cross(pts[j].Sub(pts[m])) > 0
+	for k := i; k <= j; k++ {
+		if math.Abs(pts[k].Sub(c).Length()-r) > tol {
+			return fitPrimitive{}, false
+		}
+		// the points must turn consistently around the center
+		if k > i && (pts[k-1].Sub(c).Cross(pts[k].Sub(c)) > 0) != ccw {
+			return fitPrimitive{}, false
+		}
+	}
+	return fitPrimitive{kind: fitArc, pts: []v2.Vec{pts[i], pts[j]}, center: c, radius: r, ccw: ccw, n: j - i}, true
+}
+
+// fitPolyline fits lines and arcs to an open polyline.
+func fitPolyline(pts []v2.Vec, tol, maxRadius float64) []fitPrimitive {
+	var prims []fitPrimitive
+	for i := 0; i < len(pts)-1; {
+		// longest line
+		jl := i + 1
+		for jl+1 < len(pts) && lineFits(pts, i, jl+1, tol) {
+			jl++
+		}
+		// longest arc
+		var arc fitPrimitive
+		ja := i
+		for j := i + minArcPoints - 1; j < len(pts); j++ {
+			a, ok := arcFit(pts, i, j, tol, maxRadius)
+			if !ok {
+				break
+			}
+			arc, ja = a, j
+		}
+		if ja > jl {
+			prims = append(prims, arc)
+			i = ja
+		} else {
+			prims = append(prims, fitPrimitive{kind: fitLine, pts: []v2.Vec{pts[i], pts[jl]}, n: jl - i})
+			i = jl
+		}
+	}
+	return prims
+}
+
+// mergeSplines merges runs of short lines into cubic B-splines.
+func mergeSplines(prims []fitPrimitive) []fitPrimitive {
+	var out []fitPrimitive
+	for i := 0; i < len(prims); {
+		j := i
+		for j < len(prims) && prims[j].kind == fitLine && prims[j].n <= maxSplineSegments {
+			j++
+		}
+		if j-i >= 3 {
+			cp := []v2.Vec{prims[i].pts[0]}
+			for _, p := range prims[i:j] {
+				cp = append(cp, p.pts[1])
+			}
+			out = append(out, fitPrimitive{kind: fitSpline, pts: cp, n: j - i})
+			i = j
+			continue
+		}
+		out = append(out, prims[i])
+		i++
+	}
+	return out
+}
+
+// fitContour fits lines, arcs, circles and splines to a closed contour.
+func fitContour(c Contour, tol float64, splines bool) []fitPrimitive {
+	n := len(c)
+	if n < 3 {
+		return nil
+	}
+	// start at the sharpest corner so a curve isn't split at the start
+	start, best := 0, 2.0
+	for i := range c {
+		d0 := c[i].Sub(c[(i+n-1)%n]).Normalize()
+		d1 := c[(i+1)%n].Sub(c[i]).Normalize()
+		if d := d0.Dot(d1); d < best {
+			start, best = i, d
+		}
+	}
+	pts := make([]v2.Vec, 0, n+1)
+	pts = append(pts, c[start:]...)
+	pts = append(pts, c[:start]...)
+	pts = append(pts, c[start])
+
+	// the whole contour may be a circle
+	bb := sdf.Box2{Min: pts[0], Max: pts[0]}
+	for _, p := range pts {
+		bb = bb.Include(p)
+	}
+	maxRadius := 1e3 * bb.Size().MaxComponent()
+	if cc, r, ok := circle3(pts[0], pts[n/3], pts[2*n/3]); ok {
+		fits := true
+		for _, p := range pts {
+			if math.Abs(p.Sub(cc).Length()-r) > tol {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return []fitPrimitive{{kind: fitCircle, center: cc, radius: r, n: n}}
+		}
+	}
+
+	prims := fitPolyline(pts, tol, maxRadius)
+	if splines {
+		prims = mergeSplines(prims)
+	}
+	return prims
+}
+
+//-----------------------------------------------------------------------------
+
+// angle returns the angle of a point about a center in degrees (0..360).
+func angle(p, c v2.Vec) float64 {
+	a := sdf.RtoD(math.Atan2(p.Y-c.Y, p.X-c.X))
+	if a < 0 {
+		a += 360
+	}
+	return a
+}
+
+// dxfSpline adds the bounding box method missing from the dxf spline entity.
+type dxfSpline struct {
+	*entity.Spline
+}
+
+// BBox returns the bounding box of the spline control points.
+func (s dxfSpline) BBox() ([]float64, []float64) {
+	mins := []float64{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64}
+	maxs := []float64{-math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}
+	for _, c := range s.Controls {
+		for i := range mins {
+			mins[i] = math.Min(mins[i], c[i])
+			maxs[i] = math.Max(maxs[i], c[i])
+		}
+	}
+	return mins, maxs
+}
+
+// spline adds a clamped cubic B-spline to a dxf drawing object.
+func (d *DXF) spline(cp []v2.Vec) {
+	const degree = 3
+	s := entity.NewSpline()
+	s.Flag = 8 // planar
+	s.Degree = degree
+	n := len(cp)
+	// clamped uniform knot vector
+	for i := 0; i < n+degree+1; i++ {
+		k := float64(i - degree)
+		s.Knots = append(s.Knots, math.Max(0, math.Min(k, float64(n-degree))))
+	}
+	for _, p := range cp {
+		s.Controls = append(s.Controls, []float64{p.X, p.Y, 0})
+	}
+	s.SetLayer(d.drawing.CurrentLayer)
+	d.drawing.AddEntity(dxfSpline{s})
+}
+
+// fitted adds fitted primitives to a dxf drawing object.
+func (d *DXF) fitted(prims []fitPrimitive) {
+	for _, p := range prims {
+		switch p.kind {
+		case fitLine:
+			d.drawing.Line(p.pts[0].X, p.pts[0].Y, 0, p.pts[1].X, p.pts[1].Y, 0)
+		case fitArc:
+			// dxf arcs are anticlockwise from the start angle to the end angle
+			a0, a1 := angle(p.pts[0], p.center), angle(p.pts[1], p.center)
+			if !p.ccw {
+				a0, a1 = a1, a0
+			}
+			d.drawing.Arc(p.center.X, p.center.Y, 0, p.radius, a0, a1)
+		case fitCircle:
+			d.drawing.Circle(p.center.X, p.center.Y, 0, p.radius)
+		case fitSpline:
+			d.spline(p.pts)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// DXFLayer is an SDF2 written to a named DXF layer.
+type DXFLayer struct {
+	Name  string            // layer name, e.g. "cut", "engrave", "outline"
+	Color color.ColorNumber // layer color (0 = default)
+	SDF   sdf.SDF2
+}
+
+// DXFOptions configures fitted DXF export.
+type DXFOptions struct {
+	Tolerance float64 // maximum distance of the contour points from the fitted curves (0 = 0.1% of the size)
+	Splines   bool    // fit splines to free-form curves
+}
+
+// ToDXFLayers renders SDF2s to named layers of a DXF file. The contours are fitted
+// with lines, arcs, circles and splines.
+func ToDXFLayers(
+	path string, // path to filename
+	layers []DXFLayer, // sdf2s to render
+	r Render2, // rendering method
+	opts DXFOptions,
+) error {
+	if len(layers) == 0 {
+		return sdf.ErrMsg("no layers")
+	}
+	if opts.Tolerance < 0 {
+		return sdf.ErrMsg("Tolerance < 0")
+	}
+	d := &DXF{
+		name:    path,
+		drawing: dxf.NewDrawing(),
+	}
+	for _, l := range layers {
+		if l.SDF == nil {
+			return fmt.Errorf("layer \"%s\" has no sdf", l.Name)
+		}
+		fmt.Printf("rendering %s layer %s (%s)\n", path, l.Name, r.Info(l.SDF))
+		c := l.Color
+		if c == 0 {
+			c = dxf.DefaultColor
+		}
+		if _, err := d.drawing.AddLayer(l.Name, c, dxf.DefaultLineType, true); err != nil {
+			return err
+		}
+		lc := &lineCollector{}
+		r.Render(l.SDF, lc)
+		size := l.SDF.BoundingBox().Size().MaxComponent()
+		tol := opts.Tolerance
+		if tol == 0 {
+			tol = 1e-3 * size
+		}
+		for _, contour := range joinLines(lc.lines, 1e-6*size) {
+			d.fitted(fitContour(contour, tol, opts.Splines))
+		}
+	}
+	return d.Save()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,55 @@
+//-----------------------------------------------------------------------------
+/*
+
+DXF Curve Fitting Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_FitContour(t *testing.T) {
+	circle, err := sdf.Circle2D(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	box := sdf.Box2D(v2.Vec{20, 20}, 3)
+
+	tests := []struct {
+		s     sdf.SDF2
+		kinds map[fitKind]int
+	}{
+		{circle, map[fitKind]int{fitCircle: 1}},
+		// 4 sides and 4 corners, one corner may be split at the contour start
+		{box, map[fitKind]int{fitLine: 4, fitArc: 4}},
+	}
+
+	for i, test := range tests {
+		c := &lineCollector{}
+		NewMarchingSquaresUniform(300).Render(test.s, c)
+		contours := joinLines(c.lines, 1e-6)
+		if len(contours) != 1 {
+			t.Fatalf("test %d: expected 1 contour, got %d", i, len(contours))
+		}
+		kinds := make(map[fitKind]int)
+		for _, p := range fitContour(contours[0], 0.02, false) {
+			kinds[p.kind]++
+		}
+		for k, n := range test.kinds {
+			if kinds[k] < n || kinds[k] > n+1 {
+				t.Errorf("test %d: expected %d of kind %d, got %d", i, n, k, kinds[k])
+			}
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,212 @@
+//-----------------------------------------------------------------------------
+/*
+
+FE Result Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package fe
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+const testFRD = `    1C
+    1UUSER
+    2C                             2                                     1
+ -1         1 0.00000E+00 0.00000E+00 0.00000E+00
+ -1         2 1.00000E+01 0.00000E+00 0.00000E+00
+ -3
+    3C                             1                                     1
+ -1         1    1    0    1
+ -2         1         2
+ -3
+  1PSTEP                         1           1           1
+  100CL  101 1.000000000           2                     0    1           1
+ -4  DISP        4    1
+ -5  D1          1    2    1    0
+ -5  D2          1    2    2    0
+ -5  D3          1    2    3    0
+ -5  ALL         1    2    0    0    1ALL
+ -1         1 0.00000E+00 0.00000E+00 0.00000E+00
+ -1         2 3.00000E-01-4.00000E-01 0.00000E+00
+ -3
+ -4  STRESS      6    1
+ -5  SXX         1    4    1    1
+ -5  SYY         1    4    2    2
+ -5  SZZ         1    4    3    3
+ -5  SXY         1    4    1    2
+ -5  SYZ         1    4    2    3
+ -5  SZX         1    4    3    1
+ -1         1 1.00000E+02 0.00000E+00 0.00000E+00 0.00000E+00 0.00000E+00 0.00000E+00
+ -1         2 0.00000E+00 0.00000E+00 0.00000E+00 1.00000E+01 0.00000E+00 0.00000E+00
+ -3
+ 9999
+`
+
+const testVTK = `# vtk DataFile Version 3.0
+test
+ASCII
+DATASET UNSTRUCTURED_GRID
+POINTS 2 float
+0 0 0 10 0 0
+CELLS 1 3
+2 0 1
+CELL_TYPES 1
+3
+CELL_DATA 1
+SCALARS id int 1
+LOOKUP_TABLE default
+7
+POINT_DATA 2
+SCALARS temp float 1
+LOOKUP_TABLE default
+20 40
+VECTORS disp float
+0 0 0 0.3 -0.4 0
+`
+
+func Test_ReadFRD(t *testing.T) {
+	r, err := ReadFRD(strings.NewReader(testFRD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Nodes) != 2 || !r.Nodes[1].Equals(v3.Vec{10, 0, 0}, 0) {
+		t.Fatalf("bad nodes %v", r.Nodes)
+	}
+	check := func(name string, i int, v float64) {
+		x, ok := r.Fields[name]
+		if !ok {
+			t.Errorf("%s: not found in %v", name, r.Names())
+			return
+		}
+		if math.Abs(x[i]-v) > 1e-9 {
+			t.Errorf("%s[%d]: expected %f, got %f", name, i, v, x[i])
+		}
+	}
+	check("DISP", 1, 0.5)
+	check("DISP.Y", 1, -0.4)
+	check("STRESS", 0, 100)
+	check("STRESS", 1, 10*math.Sqrt(3))
+	check("STRESS.SXY", 1, 10)
+
+	f, err := r.Field("DISP", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := f(v3.Vec{5, 0, 0}); math.Abs(x-0.25) > 1e-9 {
+		t.Errorf("field: expected 0.25, got %f", x)
+	}
+}
+
+func Test_ReadVTK(t *testing.T) {
+	r, err := ReadVTK(strings.NewReader(testVTK))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Nodes) != 2 {
+		t.Fatalf("bad nodes %v", r.Nodes)
+	}
+	if _, ok := r.Fields["id"]; ok {
+		t.Error("cell data should be skipped")
+	}
+	if x := r.Fields["temp"]; x[1] != 40 {
+		t.Errorf("temp: expected 40, got %v", x)
+	}
+	if x := r.Fields["disp"]; math.Abs(x[1]-0.5) > 1e-9 {
+		t.Errorf("disp: expected 0.5, got %v", x)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Mesh(t *testing.T) {
+	box, _ := sdf.Box3D(v3.Vec{10, 10, 10}, 0)
+	base, _ := sdf.Box3D(v3.Vec{12, 12, 1}, 0)
+	base = sdf.Transform3D(base, sdf.Translate3d(v3.Vec{0, 0, -5}))
+
+	m, err := NewMesh(box, &MeshParms{Cells: 10, Regions: []Region{{"FIXED", base}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Type != "C3D8" || len(m.Elements) != 1000 || len(m.Nodes) != 11*11*11 {
+		t.Fatalf("bad hex mesh: %d nodes, %d elements", len(m.Nodes), len(m.Elements))
+	}
+	if v := m.Volume(); math.Abs(v-1000) > 1e-6 {
+		t.Errorf("hex volume: expected 1000, got %f", v)
+	}
+	if len(m.Sets) != 1 || len(m.Sets[0].Nodes) != 11*11 {
+		t.Errorf("bad node set %v", m.Sets)
+	}
+	var sb strings.Builder
+	if err := m.WriteINP(&sb); err != nil {
+		t.Fatal(err)
+	}
+	inp := sb.String()
+	for _, s := range []string{"*NODE, NSET=NALL\n1, -5, -5, -5\n", "*ELEMENT, TYPE=C3D8, ELSET=EALL\n1, 1, 2, 3, 4, 5, 6, 7, 8\n", "*NSET, NSET=FIXED\n"} {
+		if !strings.Contains(inp, s) {
+			t.Errorf("missing \"%s\"", s)
+		}
+	}
+
+	m, err = NewMesh(box, &MeshParms{Cells: 4, Tet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Type != "C3D4" || len(m.Elements) != 12*64 {
+		t.Fatalf("bad tet mesh: %d elements", len(m.Elements))
+	}
+	if v := m.Volume(); math.Abs(v-1000) > 1e-6 {
+		t.Errorf("tet volume: expected 1000, got %f", v)
+	}
+	// conforming: interior faces are shared by 2 tets, each boundary cell face has 2 faces
+	faces := make(map[[3]int]int)
+	for _, e := range m.Elements {
+		if m.tetVolume(e[0], e[1], e[2], e[3]) <= 0 {
+			t.Fatalf("inverted tet %v", e)
+		}
+		for i := range e {
+			f := []int{e[i], e[(i+1)%4], e[(i+2)%4]}
+			sort.Ints(f)
+			faces[[3]int{f[0], f[1], f[2]}]++
+		}
+	}
+	boundary := 0
+	for _, n := range faces {
+		if n == 1 {
+			boundary++
+		} else if n != 2 {
+			t.Fatalf("face shared by %d tets", n)
+		}
+	}
+	if boundary != 2*6*16 {
+		t.Errorf("expected %d boundary faces, got %d", 2*6*16, boundary)
+	}
+	if n := m.Components(); n != 1 {
+		t.Errorf("expected 1 component, got %d", n)
+	}
+
+	other := sdf.Transform3D(box, sdf.Translate3d(v3.Vec{20, 0, 0}))
+	m, err = NewMesh(sdf.Union3D(box, other), &MeshParms{Cells: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := m.Components(); n != 2 {
+		t.Errorf("expected 2 components, got %d", n)
+	}
+
+	if _, err := NewMesh(box, &MeshParms{Cells: 4, Regions: []Region{{"1 bad", base}}}); err == nil {
+		t.Error("expected an error for a bad set name")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,205 @@
+//-----------------------------------------------------------------------------
+/*
+
+CalculiX .frd Result Files
+
+Reads the ASCII nodal coordinates and nodal result blocks of a CalculiX
+result file. The records are fixed width: a key, a node number (5 or 10
+digits) and 12 character values. Results from later steps overwrite
+earlier ones, so the final step is returned.
+
+*/
+//-----------------------------------------------------------------------------
+
+package fe
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// frdValueWidth is the width of a value in an frd record.
+const frdValueWidth = 12
+
+// frdRecord parses a " -1" record into a node number and values.
+func frdRecord(line string) (int, []float64, error) {
+	// the node number is 10 digits (long format) or 5 digits (short format)
+	w := 5
+	if len(line) >= 13 {
+		if _, err := strconv.Atoi(strings.TrimSpace(line[3:13])); err == nil {
+			w = 10
+		}
+	}
+	if len(line) < 3+w {
+		return 0, nil, fmt.Errorf("short record \"%s\"", line)
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(line[3 : 3+w]))
+	if err != nil {
+		return 0, nil, err
+	}
+	var values []float64
+	for i := 3 + w; i < len(line); i += frdValueWidth {
+		s := strings.TrimSpace(line[i:minInt(i+frdValueWidth, len(line))])
+		if s == "" {
+			continue
+		}
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, nil, err
+		}
+		values = append(values, x)
+	}
+	return id, values, nil
+}
+
+// minInt returns the minimum of two integers.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// frdBlock is a nodal result block.
+type frdBlock struct {
+	name   string
+	comps  []string
+	values map[int][]float64 // node number to values
+}
+
+// ReadFRD reads nodal results from a CalculiX .frd file.
+func ReadFRD(rd io.Reader) (*Result, error) {
+	r := newResult()
+	index := make(map[int]int) // node number to node index
+	const (
+		stateNone = iota
+		stateNodes
+		stateResult
+		stateSkip
+	)
+	state := stateNone
+	var block *frdBlock
+
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		key := fields[0]
+		if strings.HasPrefix(line, " -") && len(line) >= 3 {
+			// the node number may run into the record key
+			key = line[1:3]
+		}
+		switch key {
+		case "2C":
+			state = stateNodes
+		case "3C":
+			state = stateSkip
+		case "-4":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("bad result header at line %d", lineNum)
+			}
+			state = stateResult
+			block = &frdBlock{name: fields[1], values: make(map[int][]float64)}
+		case "-5":
+			if block != nil && len(fields) > 1 && fields[1] != "ALL" {
+				block.comps = append(block.comps, fields[1])
+			}
+		case "-1":
+			if state != stateNodes && state != stateResult {
+				continue
+			}
+			id, values, err := frdRecord(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNum, err)
+			}
+			if state == stateNodes {
+				if len(values) < 3 {
+					return nil, fmt.Errorf("bad node at line %d", lineNum)
+				}
+				index[id] = len(r.Nodes)
+				r.Nodes = append(r.Nodes, v3.Vec{values[0], values[1], values[2]})
+			} else {
+				block.values[id] = values
+			}
+		case "-3":
+			if state == stateResult {
+				r.addFRD(block, index)
+				block = nil
+			}
+			state = stateNone
+		case "9999":
+			return r, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// addFRD adds the results of an frd block.
+func (r *Result) addFRD(b *frdBlock, index map[int]int) {
+	n := len(r.Nodes)
+	// component values
+	comps := make([][]float64, len(b.comps))
+	for i := range comps {
+		comps[i] = make([]float64, n)
+	}
+	for id, values := range b.values {
+		j, ok := index[id]
+		if !ok {
+			continue
+		}
+		for i := range comps {
+			if i < len(values) {
+				comps[i][j] = values[i]
+			}
+		}
+	}
+	switch {
+	case len(comps) == 1:
+		r.Fields[b.name] = comps[0]
+		return
+	case len(comps) == 3:
+		v := make([]v3.Vec, n)
+		for j := range v {
+			v[j] = v3.Vec{comps[0][j], comps[1][j], comps[2][j]}
+		}
+		r.addVector(b.name, v)
+		return
+	case len(comps) == 6:
+		// symmetric tensor: xx, yy, zz, xy, yz, zx
+		m := make([]float64, n)
+		for j := range m {
+			m[j] = vonMises(comps[0][j], comps[1][j], comps[2][j], comps[3][j], comps[4][j], comps[5][j])
+		}
+		r.Fields[b.name] = m
+	}
+	for i, c := range b.comps {
+		r.Fields[b.name+"."+c] = comps[i]
+	}
+}
+
+// LoadFRD reads nodal results from a CalculiX .frd file.
+func LoadFRD(path string) (*Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadFRD(f)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,290 @@
+//-----------------------------------------------------------------------------
+/*
+
+Finite Element Meshes and CalculiX/Abaqus .inp Files
+
+Voxel mesh an SDF3 into hex8 (C3D8) elements: the bounding box is divided
+into cubic cells and the cells with their center inside the object are the
+elements. The hex elements can be split into tet4 (C3D4) elements around a
+node at the cell center. Each cell face is split along the diagonal through
+the corner with the lowest grid index, so neighboring cells split their
+shared face the same way and the tet mesh is conforming.
+
+Boundary regions (fixed faces, load surfaces) are given as SDF3s, the nodes
+inside a region are written as a named node set for the analysis deck.
+
+*/
+//-----------------------------------------------------------------------------
+
+package fe
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Region is a named boundary region. The nodes within the region SDF are a node set.
+type Region struct {
+	Name string
+	SDF  sdf.SDF3
+}
+
+// MeshParms defines the parameters for a finite element mesh.
+type MeshParms struct {
+	Cells   int      // number of cells on the longest axis of the bounding box
+	Tet     bool     // split the hex8 elements into tet4 elements
+	Regions []Region // boundary regions written as node sets
+}
+
+// NodeSet is a named set of nodes.
+type NodeSet struct {
+	Name  string
+	Nodes []int // node indices
+}
+
+// Mesh is a finite element mesh.
+type Mesh struct {
+	Type     string   // element type: C3D8 or C3D4
+	Nodes    []v3.Vec // node positions
+	Elements [][]int  // element node indices
+	Sets     []NodeSet
+}
+
+//-----------------------------------------------------------------------------
+
+// hexFaces are the faces of a hex8 element, counterclockwise seen from outside or inside.
+var hexFaces = [6][4]int{
+	{0, 1, 2, 3}, {4, 5, 6, 7}, {0, 1, 5, 4}, {1, 2, 6, 5}, {2, 3, 7, 6}, {3, 0, 4, 7},
+}
+
+// hexCorners are the grid offsets of the corners of a hex8 element in C3D8 order.
+var hexCorners = [8][3]int{
+	{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}, {0, 0, 1}, {1, 0, 1}, {1, 1, 1}, {0, 1, 1},
+}
+
+// checkName returns an error if a name is not a valid set name.
+func checkName(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty set name")
+	}
+	for i, c := range name {
+		letter := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !letter && (i == 0 || !((c >= '0' && c <= '9') || c == '_' || c == '-')) {
+			return fmt.Errorf("bad set name \"%s\"", name)
+		}
+	}
+	return nil
+}
+
+// NewMesh returns a finite element mesh for an SDF3.
+func NewMesh(s sdf.SDF3, k *MeshParms) (*Mesh, error) {
+	if k.Cells <= 0 {
+		return nil, fmt.Errorf("Cells <= 0")
+	}
+	for _, r := range k.Regions {
+		if err := checkName(r.Name); err != nil {
+			return nil, err
+		}
+		if r.SDF == nil {
+			return nil, fmt.Errorf("region %s has no SDF", r.Name)
+		}
+	}
+
+	bb := s.BoundingBox()
+	size := bb.Size()
+	h := size.MaxComponent() / float64(k.Cells)
+	n := [3]int{
+		int(math.Max(1, math.Ceil(size.X/h))),
+		int(math.Max(1, math.Ceil(size.Y/h))),
+		int(math.Max(1, math.Ceil(size.Z/h))),
+	}
+	// center the grid on the bounding box
+	base := bb.Center().Sub(v3.Vec{float64(n[0]), float64(n[1]), float64(n[2])}.MulScalar(h / 2))
+
+	// grid node index to mesh node index
+	key := func(i, j, k int) int { return i + (n[0]+1)*(j+(n[1]+1)*k) }
+	nodeIndex := make([]int, (n[0]+1)*(n[1]+1)*(n[2]+1))
+	for i := range nodeIndex {
+		nodeIndex[i] = -1
+	}
+
+	m := &Mesh{Type: "C3D8"}
+	if k.Tet {
+		m.Type = "C3D4"
+	}
+	node := func(i, j, k int) int {
+		x := key(i, j, k)
+		if nodeIndex[x] < 0 {
+			nodeIndex[x] = len(m.Nodes)
+			m.Nodes = append(m.Nodes, base.Add(v3.Vec{float64(i), float64(j), float64(k)}.MulScalar(h)))
+		}
+		return nodeIndex[x]
+	}
+
+	for z := 0; z < n[2]; z++ {
+		for y := 0; y < n[1]; y++ {
+			for x := 0; x < n[0]; x++ {
+				center := base.Add(v3.Vec{float64(x) + 0.5, float64(y) + 0.5, float64(z) + 0.5}.MulScalar(h))
+				if s.Evaluate(center) > 0 {
+					continue
+				}
+				var hex [8]int
+				var keys [8]int
+				for i, c := range hexCorners {
+					hex[i] = node(x+c[0], y+c[1], z+c[2])
+					keys[i] = key(x+c[0], y+c[1], z+c[2])
+				}
+				if !k.Tet {
+					m.Elements = append(m.Elements, hex[:])
+					continue
+				}
+				mid := len(m.Nodes)
+				m.Nodes = append(m.Nodes, center)
+				for _, f := range hexFaces {
+					// split along the diagonal through the lowest grid index corner
+					lo := 0
+					for i := 1; i < 4; i++ {
+						if keys[f[i]] < keys[f[lo]] {
+							lo = i
+						}
+					}
+					a, b, c, d := hex[f[lo]], hex[f[(lo+1)%4]], hex[f[(lo+2)%4]], hex[f[(lo+3)%4]]
+					m.Elements = append(m.Elements, m.tet(a, b, c, mid), m.tet(a, c, d, mid))
+				}
+			}
+		}
+	}
+	if len(m.Elements) == 0 {
+		return nil, fmt.Errorf("no elements, the object is thinner than the cell size %g", h)
+	}
+
+	for _, r := range k.Regions {
+		set := NodeSet{Name: r.Name}
+		for i, p := range m.Nodes {
+			if r.SDF.Evaluate(p) <= 0 {
+				set.Nodes = append(set.Nodes, i)
+			}
+		}
+		m.Sets = append(m.Sets, set)
+	}
+	return m, nil
+}
+
+// tet returns a tet4 element with a positive volume.
+func (m *Mesh) tet(a, b, c, d int) []int {
+	if m.tetVolume(a, b, c, d) < 0 {
+		b, c = c, b
+	}
+	return []int{a, b, c, d}
+}
+
+// tetVolume returns the signed volume of a tet.
+func (m *Mesh) tetVolume(a, b, c, d int) float64 {
+	p := m.Nodes[a]
+	return m.Nodes[b].Sub(p).Cross(m.Nodes[c].Sub(p)).Dot(m.Nodes[d].Sub(p)) / 6
+}
+
+//-----------------------------------------------------------------------------
+
+// Volume returns the total volume of the elements.
+func (m *Mesh) Volume() float64 {
+	v := 0.0
+	for _, e := range m.Elements {
+		if len(e) == 4 {
+			v += m.tetVolume(e[0], e[1], e[2], e[3])
+			continue
+		}
+		// voxel hex: the product of the edge lengths
+		p := m.Nodes[e[0]]
+		v += m.Nodes[e[1]].Sub(p).Length() * m.Nodes[e[3]].Sub(p).Length() * m.Nodes[e[4]].Sub(p).Length()
+	}
+	return v
+}
+
+// Components returns the number of connected pieces of the mesh.
+// Elements sharing a node are connected, a part should have a single component.
+func (m *Mesh) Components() int {
+	parent := make([]int, len(m.Nodes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	used := make([]bool, len(m.Nodes))
+	for _, e := range m.Elements {
+		r := find(e[0])
+		for _, x := range e {
+			used[x] = true
+			parent[find(x)] = r
+		}
+	}
+	n := 0
+	for i := range parent {
+		if used[i] && find(i) == i {
+			n++
+		}
+	}
+	return n
+}
+
+//-----------------------------------------------------------------------------
+
+// WriteINP writes the mesh as a CalculiX/Abaqus .inp file. The nodes are in
+// node set NALL, the elements in element set EALL. Numbering starts at 1.
+func (m *Mesh) WriteINP(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "*HEADING\nsdfx %s mesh, %d nodes, %d elements\n", m.Type, len(m.Nodes), len(m.Elements))
+	fmt.Fprintf(b, "*NODE, NSET=NALL\n")
+	for i, p := range m.Nodes {
+		fmt.Fprintf(b, "%d, %.9g, %.9g, %.9g\n", i+1, p.X, p.Y, p.Z)
+	}
+	fmt.Fprintf(b, "*ELEMENT, TYPE=%s, ELSET=EALL\n", m.Type)
+	for i, e := range m.Elements {
+		fmt.Fprintf(b, "%d", i+1)
+		for _, x := range e {
+			fmt.Fprintf(b, ", %d", x+1)
+		}
+		fmt.Fprintf(b, "\n")
+	}
+	for _, s := range m.Sets {
+		fmt.Fprintf(b, "*NSET, NSET=%s\n", s.Name)
+		// at most 16 entries per line
+		for i, x := range s.Nodes {
+			sep := ", "
+			if i%16 == 15 || i == len(s.Nodes)-1 {
+				sep = "\n"
+			}
+			fmt.Fprintf(b, "%d%s", x+1, sep)
+		}
+	}
+	return b.Flush()
+}
+
+// SaveINP writes the mesh to a CalculiX/Abaqus .inp file.
+func (m *Mesh) SaveINP(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := m.WriteINP(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,140 @@
+//-----------------------------------------------------------------------------
+/*
+
+Finite Element Results
+
+Nodal results (stress, displacement, ...) from an FE analysis, read from
+CalculiX .frd or legacy VTK files. Each result is mapped to a scalar per
+node and can be turned into a ScalarField over the model to drive field
+driven operators, e.g. stress-driven lattice grading or reinforcement.
+
+Vector results give a magnitude and components (name.X, name.Y, name.Z).
+Stress tensors give the von Mises stress.
+
+*/
+//-----------------------------------------------------------------------------
+
+package fe
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Result is a set of nodal results.
+type Result struct {
+	Nodes  v3.VecSet            // node positions
+	Fields map[string][]float64 // nodal values for each named result
+}
+
+// newResult returns an empty result.
+func newResult() *Result {
+	return &Result{Fields: make(map[string][]float64)}
+}
+
+// Names returns the sorted names of the results.
+func (r *Result) Names() []string {
+	var names []string
+	for k := range r.Fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Range returns the minimum and maximum values of a result.
+func (r *Result) Range(name string) (float64, float64, error) {
+	v, ok := r.Fields[name]
+	if !ok {
+		return 0, 0, fmt.Errorf("result \"%s\" not found", name)
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, x := range v {
+		lo = math.Min(lo, x)
+		hi = math.Max(hi, x)
+	}
+	return lo, hi, nil
+}
+
+// Field returns a scalar field interpolated from the nodal values of a result.
+// Nodes within the radius of a point contribute to its value (0 = 2x the mean node spacing).
+func (r *Result) Field(name string, radius float64) (sdf.ScalarField, error) {
+	v, ok := r.Fields[name]
+	if !ok {
+		return nil, fmt.Errorf("result \"%s\" not found", name)
+	}
+	if radius == 0 {
+		radius = 2 * r.spacing()
+	}
+	return sdf.FieldPoints(r.Nodes, v, radius)
+}
+
+// spacing returns an estimate of the mean node spacing.
+func (r *Result) spacing() float64 {
+	if len(r.Nodes) < 2 {
+		return 1
+	}
+	bb := sdf.Box3{Min: r.Nodes[0], Max: r.Nodes[0]}
+	for _, p := range r.Nodes {
+		bb = bb.Include(p)
+	}
+	// assume the nodes fill the volume (or a plane, or a line) of the bounding box
+	size := bb.Size()
+	vol, dims := 1.0, 0
+	for _, x := range []float64{size.X, size.Y, size.Z} {
+		if x > 0 {
+			vol *= x
+			dims++
+		}
+	}
+	if dims == 0 {
+		return 1
+	}
+	return math.Pow(vol/float64(len(r.Nodes)), 1/float64(dims))
+}
+
+// addVector adds the magnitude and components of a vector result.
+func (r *Result) addVector(name string, v []v3.Vec) {
+	m := make([]float64, len(v))
+	x := make([]float64, len(v))
+	y := make([]float64, len(v))
+	z := make([]float64, len(v))
+	for i, a := range v {
+		m[i], x[i], y[i], z[i] = a.Length(), a.X, a.Y, a.Z
+	}
+	r.Fields[name] = m
+	r.Fields[name+".X"] = x
+	r.Fields[name+".Y"] = y
+	r.Fields[name+".Z"] = z
+}
+
+// vonMises returns the von Mises stress of a symmetric stress tensor.
+func vonMises(sxx, syy, szz, sxy, syz, szx float64) float64 {
+	a := (sxx-syy)*(sxx-syy) + (syy-szz)*(syy-szz) + (szz-sxx)*(szz-sxx)
+	b := sxy*sxy + syz*syz + szx*szx
+	return math.Sqrt(0.5*a + 3*b)
+}
+
+//-----------------------------------------------------------------------------
+
+// Load reads FE results from a file. The format is given by the file extension
+// (.frd for CalculiX, .vtk for legacy VTK).
+func Load(path string) (*Result, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".frd":
+		return LoadFRD(path)
+	case ".vtk":
+		return LoadVTK(path)
+	}
+	return nil, fmt.Errorf("unknown FE result format \"%s\"", path)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,391 @@
+//-----------------------------------------------------------------------------
+/*
+
+Legacy VTK Result Files
+
+Reads the points and point data of an ASCII legacy VTK file (as written by
+FE codes and ParaView). Cell data is skipped. Binary files are not supported.
+
+*/
+//-----------------------------------------------------------------------------
+
+package fe
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// vtkReader reads the tokens of a legacy VTK file.
+type vtkReader struct {
+	scanner *bufio.Scanner
+	peeked  string
+}
+
+// next returns the next token.
+func (v *vtkReader) next() (string, error) {
+	if v.peeked != "" {
+		t := v.peeked
+		v.peeked = ""
+		return t, nil
+	}
+	if !v.scanner.Scan() {
+		if err := v.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return v.scanner.Text(), nil
+}
+
+// peek returns the next token without consuming it.
+func (v *vtkReader) peek() (string, error) {
+	if v.peeked == "" {
+		t, err := v.next()
+		if err != nil {
+			return "", err
+		}
+		v.peeked = t
+	}
+	return v.peeked, nil
+}
+
+// int returns the next token as an integer.
+func (v *vtkReader) int() (int, error) {
+	t, err := v.next()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(t)
+}
+
+// floats returns the next n tokens as floats.
+func (v *vtkReader) floats(n int) ([]float64, error) {
+	x := make([]float64, n)
+	for i := range x {
+		t, err := v.next()
+		if err != nil {
+			return nil, err
+		}
+		x[i], err = strconv.ParseFloat(t, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+// skip skips n tokens.
+func (v *vtkReader) skip(n int) error {
+	for i := 0; i < n; i++ {
+		if _, err := v.next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// attribute reads a data attribute for n points (or cells).
+// The values are added to the result if it is not nil.
+func (v *vtkReader) attribute(key string, n int, r *Result) error {
+	name, err := v.next()
+	if err != nil {
+		return err
+	}
+	switch key {
+	case "SCALARS":
+		// SCALARS name type [ncomp] [LOOKUP_TABLE table]
+		if _, err := v.next(); err != nil {
+			return err
+		}
+		ncomp := 1
+		if t, err := v.peek(); err == nil {
+			if x, err := strconv.Atoi(t); err == nil {
+				ncomp = x
+				v.next()
+			}
+		}
+		if t, _ := v.peek(); t == "LOOKUP_TABLE" {
+			if err := v.skip(2); err != nil {
+				return err
+			}
+		}
+		x, err := v.floats(n * ncomp)
+		if err != nil {
+			return err
+		}
+		if r != nil {
+			r.addComponents(name, x, ncomp)
+		}
+	case "VECTORS", "NORMALS":
+		if _, err := v.next(); err != nil {
+			return err
+		}
+		x, err := v.floats(3 * n)
+		if err != nil {
+			return err
+		}
+		if r != nil {
+			vec := make([]v3.Vec, n)
+			for i := range vec {
+				vec[i] = v3.Vec{x[3*i], x[3*i+1], x[3*i+2]}
+			}
+			r.addVector(name, vec)
+		}
+	case "TENSORS":
+		if _, err := v.next(); err != nil {
+			return err
+		}
+		x, err := v.floats(9 * n)
+		if err != nil {
+			return err
+		}
+		if r != nil {
+			m := make([]float64, n)
+			for i := range m {
+				t := x[9*i : 9*i+9]
+				m[i] = vonMises(t[0], t[4], t[8], t[1], t[5], t[2])
+			}
+			r.Fields[name] = m
+		}
+	case "FIELD":
+		// FIELD name narrays, each array: name ncomp ntuples type values
+		narrays, err := v.int()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < narrays; i++ {
+			array, err := v.next()
+			if err != nil {
+				return err
+			}
+			ncomp, err := v.int()
+			if err != nil {
+				return err
+			}
+			ntuples, err := v.int()
+			if err != nil {
+				return err
+			}
+			if _, err := v.next(); err != nil {
+				return err
+			}
+			x, err := v.floats(ncomp * ntuples)
+			if err != nil {
+				return err
+			}
+			if r != nil && ntuples == n {
+				r.addComponents(array, x, ncomp)
+			}
+		}
+	case "LOOKUP_TABLE":
+		size, err := v.int()
+		if err != nil {
+			return err
+		}
+		return v.skip(4 * size)
+	case "COLOR_SCALARS", "TEXTURE_COORDINATES":
+		dim, err := v.int()
+		if err != nil {
+			return err
+		}
+		if key == "TEXTURE_COORDINATES" {
+			if _, err := v.next(); err != nil {
+				return err
+			}
+		}
+		return v.skip(dim * n)
+	default:
+		return fmt.Errorf("unknown attribute \"%s\"", key)
+	}
+	return nil
+}
+
+// addComponents adds a result with one or more components per node.
+func (r *Result) addComponents(name string, x []float64, ncomp int) {
+	switch ncomp {
+	case 1:
+		r.Fields[name] = x
+	case 3:
+		vec := make([]v3.Vec, len(x)/3)
+		for i := range vec {
+			vec[i] = v3.Vec{x[3*i], x[3*i+1], x[3*i+2]}
+		}
+		r.addVector(name, vec)
+	default:
+		for c := 0; c < ncomp; c++ {
+			y := make([]float64, len(x)/ncomp)
+			for i := range y {
+				y[i] = x[i*ncomp+c]
+			}
+			r.Fields[fmt.Sprintf("%s.%d", name, c)] = y
+		}
+	}
+}
+
+// ReadVTK reads the points and point data from an ASCII legacy VTK file.
+func ReadVTK(rd io.Reader) (*Result, error) {
+	br := bufio.NewReader(rd)
+	// header: version, title, format
+	var header [3]string
+	for i := range header {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("bad vtk header")
+		}
+		header[i] = strings.TrimSpace(line)
+	}
+	if !strings.HasPrefix(header[0], "# vtk DataFile") {
+		return nil, fmt.Errorf("not a vtk file")
+	}
+	if !strings.EqualFold(header[2], "ASCII") {
+		return nil, fmt.Errorf("vtk format \"%s\" is not supported", header[2])
+	}
+
+	v := &vtkReader{scanner: bufio.NewScanner(br)}
+	v.scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	v.scanner.Split(bufio.ScanWords)
+	r := newResult()
+	var dims [3]int
+	var origin, spacing v3.Vec
+	pointData := false
+	nData := 0
+	for {
+		key, err := v.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToUpper(key) {
+		case "DATASET":
+			if _, err := v.next(); err != nil {
+				return nil, err
+			}
+		case "POINTS":
+			n, err := v.int()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := v.next(); err != nil {
+				return nil, err
+			}
+			x, err := v.floats(3 * n)
+			if err != nil {
+				return nil, err
+			}
+			r.Nodes = make(v3.VecSet, n)
+			for i := range r.Nodes {
+				r.Nodes[i] = v3.Vec{x[3*i], x[3*i+1], x[3*i+2]}
+			}
+		case "DIMENSIONS":
+			for i := range dims {
+				if dims[i], err = v.int(); err != nil {
+					return nil, err
+				}
+			}
+		case "ORIGIN", "SPACING", "ASPECT_RATIO":
+			x, err := v.floats(3)
+			if err != nil {
+				return nil, err
+			}
+			if strings.ToUpper(key) == "ORIGIN" {
+				origin = v3.Vec{x[0], x[1], x[2]}
+			} else {
+				spacing = v3.Vec{x[0], x[1], x[2]}
+			}
+		case "CELLS", "POLYGONS", "LINES", "VERTICES", "TRIANGLE_STRIPS":
+			n, err := v.int()
+			if err != nil {
+				return nil, err
+			}
+			size, err := v.int()
+			if err != nil {
+				return nil, err
+			}
+			if t, _ := v.peek(); t == "OFFSETS" {
+				// version 5 offsets and connectivity arrays
+				if err := v.skip(2 + n); err != nil {
+					return nil, err
+				}
+				if err := v.skip(2 + size); err != nil {
+					return nil, err
+				}
+			} else if err := v.skip(size); err != nil {
+				return nil, err
+			}
+		case "CELL_TYPES":
+			n, err := v.int()
+			if err != nil {
+				return nil, err
+			}
+			if err := v.skip(n); err != nil {
+				return nil, err
+			}
+		case "POINT_DATA", "CELL_DATA":
+			if nData, err = v.int(); err != nil {
+				return nil, err
+			}
+			pointData = strings.ToUpper(key) == "POINT_DATA"
+		case "METADATA":
+			// skip to the next data section
+			for {
+				t, err := v.peek()
+				if err != nil || t == "POINT_DATA" || t == "CELL_DATA" {
+					break
+				}
+				v.next()
+			}
+		default:
+			if nData == 0 {
+				return nil, fmt.Errorf("unknown vtk keyword \"%s\"", key)
+			}
+			var dst *Result
+			if pointData {
+				dst = r
+			}
+			if err := v.attribute(strings.ToUpper(key), nData, dst); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// structured points
+	if r.Nodes == nil && dims[0] > 0 {
+		for k := 0; k < dims[2]; k++ {
+			for j := 0; j < dims[1]; j++ {
+				for i := 0; i < dims[0]; i++ {
+					r.Nodes = append(r.Nodes, origin.Add(v3.Vec{float64(i), float64(j), float64(k)}.Mul(spacing)))
+				}
+			}
+		}
+	}
+	for name, x := range r.Fields {
+		if len(x) != len(r.Nodes) {
+			return nil, fmt.Errorf("result \"%s\" has %d values for %d points", name, len(x), len(r.Nodes))
+		}
+	}
+	return r, nil
+}
+
+// LoadVTK reads the points and point data from an ASCII legacy VTK file.
+func LoadVTK(path string) (*Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadVTK(f)
+}
+
+//-----------------------------------------------------------------------------
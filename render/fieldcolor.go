@@ -0,0 +1,93 @@
+//-----------------------------------------------------------------------------
+/*
+
+Color by Field
+
+Color the vertices of a mesh by a scalar field (e.g. wall thickness,
+curvature or FE stress) to share analysis results as PLY or glTF files.
+
+The field values are mapped to [0,1] over a range, either given or the
+range of the values at the vertices, and then to a color with a color map.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// ColorField colors the vertices of a mesh by a scalar field.
+type ColorField struct {
+	Field    sdf.ScalarField            // field sampled at the vertices
+	Min, Max float64                    // value range (Min == Max uses the range of the vertex values)
+	Map      func(t float64) color.RGBA // color of a value in [0,1] (default ColorRamp)
+}
+
+// rampColors are the colors of ColorRamp, evenly spaced from 0 to 1.
+var rampColors = [][3]float64{
+	{0.19, 0.21, 0.58}, // blue
+	{0.17, 0.63, 0.82}, // cyan
+	{0.40, 0.76, 0.35}, // green
+	{0.99, 0.85, 0.22}, // yellow
+	{0.84, 0.15, 0.16}, // red
+}
+
+// ColorRamp returns a blue (0) to red (1) color for a value.
+func ColorRamp(t float64) color.RGBA {
+	t = sdf.Clamp(t, 0, 1) * float64(len(rampColors)-1)
+	i := int(math.Min(t, float64(len(rampColors)-2)))
+	f := t - float64(i)
+	var c [3]uint8
+	for k := range c {
+		c[k] = uint8(math.Round(255 * sdf.Mix(rampColors[i][k], rampColors[i+1][k], f)))
+	}
+	return color.RGBA{c[0], c[1], c[2], 255}
+}
+
+// Values returns the field values at a set of points, and the value range.
+func (cf *ColorField) Values(points []v3.Vec) ([]float64, float64, float64) {
+	values := make([]float64, len(points))
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i, p := range points {
+		v := cf.Field(p)
+		values[i] = v
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	if cf.Min != cf.Max {
+		lo, hi = cf.Min, cf.Max
+	}
+	return values, lo, hi
+}
+
+// Colors returns the colors of a set of points.
+func (cf *ColorField) Colors(points []v3.Vec) []color.RGBA {
+	return cf.colors(cf.Values(points))
+}
+
+// colors returns the colors of a set of values over a range.
+func (cf *ColorField) colors(values []float64, lo, hi float64) []color.RGBA {
+	m := cf.Map
+	if m == nil {
+		m = ColorRamp
+	}
+	colors := make([]color.RGBA, len(values))
+	for i, v := range values {
+		t := 0.5
+		if hi != lo {
+			t = (v - lo) / (hi - lo)
+		}
+		colors[i] = m(t)
+	}
+	return colors
+}
+
+//-----------------------------------------------------------------------------
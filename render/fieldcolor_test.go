@@ -0,0 +1,105 @@
+//-----------------------------------------------------------------------------
+/*
+
+Color by Field Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_ColorRamp(t *testing.T) {
+	if c := ColorRamp(0); c.B <= c.R {
+		t.Errorf("expected blue at 0, got %v", c)
+	}
+	if c := ColorRamp(1); c.R <= c.B {
+		t.Errorf("expected red at 1, got %v", c)
+	}
+	if ColorRamp(-1) != ColorRamp(0) || ColorRamp(2) != ColorRamp(1) {
+		t.Error("expected the values to be clamped")
+	}
+}
+
+func Test_PLY(t *testing.T) {
+	s, _ := sdf.Box3D(v3.Vec{X: 10, Y: 10, Z: 10}, 0)
+	mesh := ToTriangles(s, NewMarchingCubesOctree(10))
+	m, _ := NewMesh(mesh, 0)
+	// color by height
+	cf := &ColorField{Field: func(p v3.Vec) float64 { return p.Z }}
+
+	for _, field := range []*ColorField{nil, cf} {
+		var buf bytes.Buffer
+		if err := WritePLY(&buf, mesh, field); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()
+		end := bytes.Index(data, []byte("end_header\n")) + len("end_header\n")
+		header := string(data[:end])
+		if !strings.Contains(header, "element face") {
+			t.Fatalf("bad header %q", header)
+		}
+		// 6 floats per vertex, 3 bytes of color and a float value
+		vsize := 24
+		if field != nil {
+			vsize += 7
+			if !strings.Contains(header, "property uchar red") {
+				t.Fatal("expected vertex colors")
+			}
+		}
+		size := end + vsize*len(m.Vertices) + 13*len(m.Faces)
+		if len(data) != size {
+			t.Fatalf("expected %d bytes, got %d", size, len(data))
+		}
+		if field == nil {
+			continue
+		}
+		// the lowest vertex is blue, the highest is red
+		for i, v := range m.Vertices {
+			x := data[end+i*vsize:]
+			c := color.RGBA{x[24], x[25], x[26], 255}
+			value := math.Float32frombits(binary.LittleEndian.Uint32(x[27:]))
+			if float64(value) != float64(float32(v.Z)) {
+				t.Fatalf("expected a value of %g, got %g", v.Z, value)
+			}
+			if v.Z == -5 && c != ColorRamp(0) || v.Z == 5 && c != ColorRamp(1) {
+				t.Fatalf("bad color %v at %v", c, v)
+			}
+		}
+	}
+}
+
+func Test_GLTFColors(t *testing.T) {
+	s, _ := sdf.Sphere3D(10)
+	mesh := ToTriangles(s, NewMarchingCubesOctree(20))
+	cf := &ColorField{Field: func(p v3.Vec) float64 { return p.X }, Min: -10, Max: 10}
+	d, err := gltfParts([]GLTFPart{{Name: "sphere", Mesh: mesh, Colors: cf}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, ok := d.Meshes[0].Primitives[0].Attributes["COLOR_0"]
+	if !ok {
+		t.Fatal("glTF mesh has no vertex colors")
+	}
+	if a := d.Accessors[k]; a.Type != "VEC3" || a.Count != 3*len(mesh) {
+		t.Errorf("bad vertex color accessor %+v", a)
+	}
+	if d.Materials[0].PbrMetallicRoughness.BaseColorFactor != [4]float64{1, 1, 1, 1} {
+		t.Error("expected a white base color")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,144 @@
+//-----------------------------------------------------------------------------
+/*
+
+Model Fingerprints
+
+A fingerprint identifies the code and the model that produced an exported
+file: the sdfx version, the VCS commit of the program (when it was built
+from a repository checkout) and a hash of the mesh. With the Fingerprint
+export option set the STL, 3MF and STEP writers put it in the file
+metadata, so a physical part can be traced back to the code that produced
+it.
+
+	STL (binary)  the 80 byte header (with a shortened hash)
+	STL (ASCII)   the solid name
+	3MF           the Application and Description metadata
+	STEP          the FILE_DESCRIPTION header
+
+The model hash is the SHA-256 of the triangle vertices as little endian
+float32 values (the precision of an STL), in mesh order.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math"
+	"runtime/debug"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// sdfxModule is the module path of sdfx.
+const sdfxModule = "github.com/deadsy/sdfx"
+
+// Fingerprint identifies the code and model that produced an export.
+type Fingerprint struct {
+	Version  string // sdfx module version
+	Commit   string // VCS revision of the program ("" if not known)
+	Modified bool   // the program was built from a modified working tree
+	Hash     string // hex SHA-256 of the mesh
+}
+
+// buildInfo returns the sdfx version and the VCS state of the running program.
+func buildInfo() (version, commit string, modified bool) {
+	version = "unknown"
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	if info.Main.Path == sdfxModule {
+		version = info.Main.Version
+	}
+	for _, d := range info.Deps {
+		if d.Path == sdfxModule {
+			version = d.Version
+		}
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			commit = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	return
+}
+
+// NewFingerprint returns the fingerprint of a mesh produced by the running program.
+func NewFingerprint(mesh []*sdf.Triangle3) *Fingerprint {
+	h := newModelHash()
+	h.add(mesh)
+	return h.fingerprint()
+}
+
+// String returns the fingerprint as text.
+func (f *Fingerprint) String() string {
+	return fmt.Sprintf("sdfx %s%s model sha256:%s", f.Version, f.commit(12), f.Hash)
+}
+
+// short returns the fingerprint as text that fits in an STL header.
+func (f *Fingerprint) short() string {
+	s := fmt.Sprintf("sdfx %s%s model %s", f.Version, f.commit(12), f.Hash[:16])
+	if len(s) > 80 {
+		s = s[:80]
+	}
+	return s
+}
+
+// commit returns the shortened commit, with a "+" for a modified tree.
+func (f *Fingerprint) commit(n int) string {
+	if f.Commit == "" {
+		return ""
+	}
+	c := f.Commit
+	if len(c) > n {
+		c = c[:n]
+	}
+	if f.Modified {
+		c += "+"
+	}
+	return " commit " + c
+}
+
+//-----------------------------------------------------------------------------
+
+// modelHash accumulates the hash of a mesh written in batches.
+type modelHash struct {
+	h   hash.Hash
+	buf [36]byte
+}
+
+func newModelHash() *modelHash {
+	return &modelHash{h: sha256.New()}
+}
+
+// add adds triangles to the hash.
+func (m *modelHash) add(mesh []*sdf.Triangle3) {
+	for _, t := range mesh {
+		for i, v := range t {
+			binary.LittleEndian.PutUint32(m.buf[12*i:], math.Float32bits(float32(v.X)))
+			binary.LittleEndian.PutUint32(m.buf[12*i+4:], math.Float32bits(float32(v.Y)))
+			binary.LittleEndian.PutUint32(m.buf[12*i+8:], math.Float32bits(float32(v.Z)))
+		}
+		m.h.Write(m.buf[:])
+	}
+}
+
+// fingerprint returns the fingerprint for the hashed mesh.
+func (m *modelHash) fingerprint() *Fingerprint {
+	f := &Fingerprint{Hash: hex.EncodeToString(m.h.Sum(nil))}
+	f.Version, f.Commit, f.Modified = buildInfo()
+	return f
+}
+
+//-----------------------------------------------------------------------------
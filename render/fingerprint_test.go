@@ -0,0 +1,114 @@
+//-----------------------------------------------------------------------------
+/*
+
+Model Fingerprint Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	"github.com/hpinc/go3mf"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Fingerprint(t *testing.T) {
+	s, _ := sdf.Sphere3D(5)
+	mesh := ToTriangles(s, NewMarchingCubesUniform(20))
+	f := NewFingerprint(mesh)
+	if len(f.Hash) != 64 || f.Version == "" {
+		t.Fatalf("bad fingerprint %+v", f)
+	}
+	if g := NewFingerprint(mesh); g.Hash != f.Hash {
+		t.Error("the hash is not repeatable")
+	}
+	moved := append([]*sdf.Triangle3{}, mesh...)
+	moved[0] = &sdf.Triangle3{mesh[0][1], mesh[0][2], mesh[0][0]}
+	if g := NewFingerprint(moved); g.Hash == f.Hash {
+		t.Error("the hash should change with the mesh")
+	}
+	if x := (&Fingerprint{Version: "v1.0.0", Commit: "0123456789abcdef", Modified: true, Hash: f.Hash}).String(); !strings.HasPrefix(x, "sdfx v1.0.0 commit 0123456789ab+ model sha256:") {
+		t.Errorf("bad fingerprint string %s", x)
+	}
+
+	opts := ExportOptions{Fingerprint: true}
+	dir := t.TempDir()
+	contains := func(path, s string) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(b), s) {
+			t.Errorf("%s: fingerprint not found", path)
+		}
+	}
+
+	// binary STL, the same hash when saved and streamed
+	path := filepath.Join(dir, "a.stl")
+	if err := SaveSTLWithOptions(path, mesh, opts); err != nil {
+		t.Fatal(err)
+	}
+	contains(path, f.short())
+	plain := filepath.Join(dir, "plain.stl")
+	if err := SaveSTL(plain, mesh); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(plain); strings.Contains(string(b), "sdfx") {
+		t.Error("unexpected fingerprint without the option")
+	}
+	path = filepath.Join(dir, "b.stl")
+	ToSTLWithOptions(s, path, NewMarchingCubesUniform(20), opts)
+	contains(path, f.short())
+	if m, err := LoadSTL(path); err != nil || len(m) != len(mesh) {
+		t.Errorf("bad STL reload %d %v", len(m), err)
+	}
+
+	// ASCII STL
+	path = filepath.Join(dir, "c.stl")
+	if err := SaveSTLASCII(path, mesh, opts); err != nil {
+		t.Fatal(err)
+	}
+	contains(path, "solid "+f.String()+"\n")
+	if m, err := LoadSTL(path); err != nil || len(m) != len(mesh) {
+		t.Errorf("bad ASCII STL reload %d %v", len(m), err)
+	}
+
+	// 3MF
+	path = filepath.Join(dir, "d.3mf")
+	if err := Save3MFWithOptions(path, []Part3MF{{Name: "sphere", Mesh: mesh}}, opts); err != nil {
+		t.Fatal(err)
+	}
+	r, err := go3mf.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var model go3mf.Model
+	if err := r.Decode(&model); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	found := false
+	for _, m := range model.Metadata {
+		found = found || (m.Name.Local == "Description" && m.Value == f.String())
+	}
+	if !found {
+		t.Errorf("3MF: fingerprint not found in %v", model.Metadata)
+	}
+
+	// STEP
+	path = filepath.Join(dir, "e.step")
+	if err := SaveSTEPWithOptions(path, mesh[:20], STEPOptions{Fingerprint: true}); err != nil {
+		t.Fatal(err)
+	}
+	contains(path, "FILE_DESCRIPTION(('STEP AP214','"+NewFingerprint(mesh[:20]).String()+"'),'1');")
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,390 @@
+//-----------------------------------------------------------------------------
+/*
+
+glTF Output
+
+Write named and colored triangle meshes and annotations to a glTF 2.0 file,
+either binary (.glb) or JSON with an embedded buffer (.gltf).
+
+Each part is a node with a flat shaded mesh, optionally with texture
+coordinates (see uv.go) and vertex colors (see fieldcolor.go). Annotations are separate nodes
+under an "annotations" node: each is placed at its text position, carries
+the annotation (kind, text, attached points) in its extras and has a line
+mesh for any leader or dimension lines.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// glTF constants.
+const (
+	gltfFloat        = 5126
+	gltfArrayBuffer  = 34962
+	gltfModeLines    = 1
+	gltfModeTriangle = 4
+)
+
+type gltfAsset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator,omitempty"`
+}
+
+type gltfScene struct {
+	Nodes []int `json:"nodes"`
+}
+
+type gltfNode struct {
+	Name        string      `json:"name,omitempty"`
+	Mesh        *int        `json:"mesh,omitempty"`
+	Translation *[3]float64 `json:"translation,omitempty"`
+	Children    []int       `json:"children,omitempty"`
+	Extras      interface{} `json:"extras,omitempty"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Material   *int           `json:"material,omitempty"`
+	Mode       int            `json:"mode"`
+}
+
+type gltfMesh struct {
+	Name       string          `json:"name,omitempty"`
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPBR struct {
+	BaseColorFactor [4]float64 `json:"baseColorFactor"`
+	MetallicFactor  float64    `json:"metallicFactor"`
+	RoughnessFactor float64    `json:"roughnessFactor"`
+}
+
+type gltfMaterial struct {
+	Name                 string  `json:"name,omitempty"`
+	PbrMetallicRoughness gltfPBR `json:"pbrMetallicRoughness"`
+	DoubleSided          bool    `json:"doubleSided,omitempty"`
+}
+
+type gltfAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float64 `json:"min,omitempty"`
+	Max           []float64 `json:"max,omitempty"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target,omitempty"`
+}
+
+type gltfBuffer struct {
+	ByteLength int    `json:"byteLength"`
+	URI        string `json:"uri,omitempty"`
+}
+
+// gltfDoc is a glTF document being built.
+type gltfDoc struct {
+	Asset       gltfAsset        `json:"asset"`
+	Scene       int              `json:"scene"`
+	Scenes      []gltfScene      `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes"`
+	Meshes      []gltfMesh       `json:"meshes,omitempty"`
+	Materials   []gltfMaterial   `json:"materials,omitempty"`
+	Accessors   []gltfAccessor   `json:"accessors,omitempty"`
+	BufferViews []gltfBufferView `json:"bufferViews,omitempty"`
+	Buffers     []gltfBuffer     `json:"buffers,omitempty"`
+	bin         bytes.Buffer
+}
+
+func newGLTFDoc() *gltfDoc {
+	return &gltfDoc{
+		Asset:  gltfAsset{Version: "2.0", Generator: "sdfx"},
+		Scenes: []gltfScene{{Nodes: []int{}}},
+	}
+}
+
+// addVec3 adds an accessor for a set of 3d vectors and returns its index.
+func (d *gltfDoc) addVec3(vs []v3.Vec) int {
+	offset := d.bin.Len()
+	lo := []float64{math.MaxFloat32, math.MaxFloat32, math.MaxFloat32}
+	hi := []float64{-math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32}
+	for _, v := range vs {
+		for i, x := range []float64{v.X, v.Y, v.Z} {
+			f := float32(x)
+			binary.Write(&d.bin, binary.LittleEndian, f)
+			lo[i] = math.Min(lo[i], float64(f))
+			hi[i] = math.Max(hi[i], float64(f))
+		}
+	}
+	d.BufferViews = append(d.BufferViews, gltfBufferView{
+		ByteOffset: offset,
+		ByteLength: d.bin.Len() - offset,
+		Target:     gltfArrayBuffer,
+	})
+	d.Accessors = append(d.Accessors, gltfAccessor{
+		BufferView:    len(d.BufferViews) - 1,
+		ComponentType: gltfFloat,
+		Count:         len(vs),
+		Type:          "VEC3",
+		Min:           lo,
+		Max:           hi,
+	})
+	return len(d.Accessors) - 1
+}
+
+// addVec2 adds an accessor for a set of 2d vectors and returns its index.
+func (d *gltfDoc) addVec2(vs []v2.Vec) int {
+	offset := d.bin.Len()
+	for _, v := range vs {
+		binary.Write(&d.bin, binary.LittleEndian, [2]float32{float32(v.X), float32(v.Y)})
+	}
+	d.BufferViews = append(d.BufferViews, gltfBufferView{
+		ByteOffset: offset,
+		ByteLength: d.bin.Len() - offset,
+		Target:     gltfArrayBuffer,
+	})
+	d.Accessors = append(d.Accessors, gltfAccessor{
+		BufferView:    len(d.BufferViews) - 1,
+		ComponentType: gltfFloat,
+		Count:         len(vs),
+		Type:          "VEC2",
+	})
+	return len(d.Accessors) - 1
+}
+
+// addMaterial adds a material with a base color and returns its index.
+func (d *gltfDoc) addMaterial(name string, c color.RGBA) int {
+	d.Materials = append(d.Materials, gltfMaterial{
+		Name: name,
+		PbrMetallicRoughness: gltfPBR{
+			BaseColorFactor: [4]float64{float64(c.R) / 255, float64(c.G) / 255, float64(c.B) / 255, float64(c.A) / 255},
+			RoughnessFactor: 0.8,
+		},
+		DoubleSided: true,
+	})
+	return len(d.Materials) - 1
+}
+
+// addMesh adds a mesh with a single primitive and returns its index.
+func (d *gltfDoc) addMesh(name string, p gltfPrimitive) int {
+	d.Meshes = append(d.Meshes, gltfMesh{Name: name, Primitives: []gltfPrimitive{p}})
+	return len(d.Meshes) - 1
+}
+
+// addNode adds a node and returns its index.
+func (d *gltfDoc) addNode(n gltfNode) int {
+	d.Nodes = append(d.Nodes, n)
+	return len(d.Nodes) - 1
+}
+
+// linear returns the linear value of an sRGB color component.
+func linear(c uint8) float64 {
+	x := float64(c) / 255
+	if x <= 0.04045 {
+		return x / 12.92
+	}
+	return math.Pow((x+0.055)/1.055, 2.4)
+}
+
+// addTriangles adds the flat shaded triangle mesh of a part, with texture
+// coordinates and vertex colors if the part has them, and returns its index.
+func (d *gltfDoc) addTriangles(p GLTFPart, material int) int {
+	mesh := p.Mesh
+	positions := make([]v3.Vec, 0, 3*len(mesh))
+	normals := make([]v3.Vec, 0, 3*len(mesh))
+	for _, t := range mesh {
+		n := t.Normal()
+		positions = append(positions, t[0], t[1], t[2])
+		normals = append(normals, n, n, n)
+	}
+	attributes := map[string]int{"POSITION": d.addVec3(positions), "NORMAL": d.addVec3(normals)}
+	if p.UV {
+		coords := make([]v2.Vec, 0, 3*len(mesh))
+		for _, t := range UnwrapUV(mesh, uvPadding) {
+			// glTF textures have v = 0 at the top
+			for _, c := range t {
+				coords = append(coords, v2.Vec{X: c.X, Y: 1 - c.Y})
+			}
+		}
+		attributes["TEXCOORD_0"] = d.addVec2(coords)
+	}
+	if p.Colors != nil {
+		// glTF vertex colors are linear
+		colors := make([]v3.Vec, len(positions))
+		for i, c := range p.Colors.Colors(positions) {
+			colors[i] = v3.Vec{X: linear(c.R), Y: linear(c.G), Z: linear(c.B)}
+		}
+		attributes["COLOR_0"] = d.addVec3(colors)
+	}
+	return d.addMesh(p.Name, gltfPrimitive{
+		Attributes: attributes,
+		Material:   &material,
+		Mode:       gltfModeTriangle,
+	})
+}
+
+// addLines adds a line segment mesh and returns its index.
+func (d *gltfDoc) addLines(name string, lines [][2]v3.Vec, material int) int {
+	positions := make([]v3.Vec, 0, 2*len(lines))
+	for _, l := range lines {
+		positions = append(positions, l[0], l[1])
+	}
+	return d.addMesh(name, gltfPrimitive{
+		Attributes: map[string]int{"POSITION": d.addVec3(positions)},
+		Material:   &material,
+		Mode:       gltfModeLines,
+	})
+}
+
+// addAnnotations adds the annotation nodes under an "annotations" node.
+func (d *gltfDoc) addAnnotations(notes []*Annotation) {
+	if len(notes) == 0 {
+		return
+	}
+	material := d.addMaterial("annotation", color.RGBA{255, 200, 0, 255})
+	var children []int
+	for _, a := range notes {
+		at := toArray(a.At)
+		n := gltfNode{Name: a.Text, Translation: &at, Extras: a.toJSON()}
+		if lines := a.Lines(); len(lines) != 0 {
+			// the lines are relative to the node position
+			for i := range lines {
+				lines[i] = [2]v3.Vec{lines[i][0].Sub(a.At), lines[i][1].Sub(a.At)}
+			}
+			mesh := d.addLines(a.Text, lines, material)
+			n.Mesh = &mesh
+		}
+		children = append(children, d.addNode(n))
+	}
+	root := d.addNode(gltfNode{Name: "annotations", Children: children})
+	d.Scenes[0].Nodes = append(d.Scenes[0].Nodes, root)
+}
+
+// pad pads a buffer to a multiple of 4 bytes.
+func pad(b []byte, c byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, c)
+	}
+	return b
+}
+
+// write writes a glTF document as a binary (glb) or a JSON file.
+func (d *gltfDoc) write(w io.Writer, glb bool) error {
+	bin := pad(d.bin.Bytes(), 0)
+	if len(bin) != 0 {
+		d.Buffers = []gltfBuffer{{ByteLength: len(bin)}}
+		if !glb {
+			d.Buffers[0].URI = "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(bin)
+		}
+	}
+	js, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	if !glb {
+		_, err := w.Write(js)
+		return err
+	}
+	js = pad(js, ' ')
+	length := 12 + 8 + len(js)
+	if len(bin) != 0 {
+		length += 8 + len(bin)
+	}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint32{0x46546c67, 2, uint32(length)})
+	binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(js)), 0x4e4f534a})
+	buf.Write(js)
+	if len(bin) != 0 {
+		binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(bin)), 0x004e4942})
+		buf.Write(bin)
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+//-----------------------------------------------------------------------------
+
+// GLTFPart is a named and colored triangle mesh for a glTF file.
+type GLTFPart struct {
+	Name   string           // part name
+	Color  color.RGBA       // display color
+	Mesh   []*sdf.Triangle3 // triangle mesh
+	UV     bool             // add texture coordinates
+	Colors *ColorField      // color the vertices by a field (the part color is not used)
+}
+
+// gltfParts returns a glTF document for a set of colored parts and annotations.
+func gltfParts(parts []GLTFPart, notes []*Annotation) (*gltfDoc, error) {
+	d := newGLTFDoc()
+	for _, p := range parts {
+		if len(p.Mesh) == 0 {
+			continue
+		}
+		c := p.Color
+		if p.Colors != nil {
+			// the vertex colors are multiplied by the base color
+			c = color.RGBA{255, 255, 255, 255}
+		}
+		mesh := d.addTriangles(p, d.addMaterial(p.Name, c))
+		node := d.addNode(gltfNode{Name: p.Name, Mesh: &mesh})
+		d.Scenes[0].Nodes = append(d.Scenes[0].Nodes, node)
+	}
+	d.addAnnotations(notes)
+	if len(d.Nodes) == 0 {
+		return nil, sdf.ErrMsg("nothing to write")
+	}
+	return d, nil
+}
+
+// WriteGLTF writes a set of colored parts and annotations as binary (glb)
+// or JSON glTF to a writer.
+func WriteGLTF(w io.Writer, parts []GLTFPart, notes []*Annotation, glb bool) error {
+	d, err := gltfParts(parts, notes)
+	if err != nil {
+		return err
+	}
+	return d.write(w, glb)
+}
+
+// SaveGLTF writes a set of colored parts and annotations to a glTF file.
+// A ".glb" path is written as binary glTF, otherwise as JSON glTF.
+func SaveGLTF(path string, parts []GLTFPart, notes []*Annotation) error {
+	d, err := gltfParts(parts, notes)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := d.write(f, strings.EqualFold(filepath.Ext(path), ".glb")); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//-----------------------------------------------------------------------------
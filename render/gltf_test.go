@@ -0,0 +1,79 @@
+//-----------------------------------------------------------------------------
+/*
+
+glTF Output Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_GLTF(t *testing.T) {
+	s, _ := sdf.Box3D(v3.Vec{20, 10, 5}, 0)
+	mesh := ToTriangles(s, NewMarchingCubesUniform(20))
+	parts := []GLTFPart{{Name: "box", Color: color.RGBA{200, 0, 0, 255}, Mesh: mesh}}
+	notes := []*Annotation{
+		NewLabel(v3.Vec{0, 0, 2.5}, "top"),
+		NewLeader(v3.Vec{10, 0, 0}, v3.Vec{15, 0, 5}, "end face"),
+		NewDimension(v3.Vec{-10, -5, 2.5}, v3.Vec{10, -5, 2.5}, v3.Vec{0, -3, 0}, ""),
+	}
+	if notes[2].Text != "20.00" {
+		t.Errorf("expected a dimension of 20.00, got %s", notes[2].Text)
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"model.glb", "model.gltf"} {
+		path := filepath.Join(dir, name)
+		if err := SaveGLTF(path, parts, notes); err != nil {
+			t.Fatal(err)
+		}
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		js := buf
+		if filepath.Ext(name) == ".glb" {
+			if string(buf[0:4]) != "glTF" || int(binary.LittleEndian.Uint32(buf[8:12])) != len(buf) {
+				t.Fatal("bad glb header")
+			}
+			n := binary.LittleEndian.Uint32(buf[12:16])
+			js = buf[20 : 20+n]
+		}
+		var doc gltfDoc
+		if err := json.Unmarshal(js, &doc); err != nil {
+			t.Fatal(err)
+		}
+		// a part node, 3 annotation nodes and the annotations node
+		if len(doc.Nodes) != 5 || len(doc.Scenes[0].Nodes) != 2 {
+			t.Fatalf("%s: bad nodes %+v", name, doc.Nodes)
+		}
+		if doc.Accessors[0].Count != 3*len(mesh) {
+			t.Errorf("%s: expected %d vertices, got %d", name, 3*len(mesh), doc.Accessors[0].Count)
+		}
+		leader := doc.Nodes[2]
+		extras, _ := leader.Extras.(map[string]interface{})
+		if leader.Name != "end face" || leader.Mesh == nil || extras["kind"] != "leader" {
+			t.Errorf("%s: bad leader node %+v", name, leader)
+		}
+	}
+
+	if err := SaveGLTF(filepath.Join(dir, "empty.glb"), nil, nil); err == nil {
+		t.Error("expected an error for an empty file")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,111 @@
+//-----------------------------------------------------------------------------
+/*
+
+Guarded Rendering
+
+Render with checks for NaN and Inf distances. The mesh is only passed on
+when every distance sampled by the renderer was finite, otherwise the
+render fails with the sample point and the SDF node path that produced it.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Guard is a Render3 that detects non-finite distances.
+type Guard struct {
+	r   Render3
+	mu  sync.Mutex
+	err error
+}
+
+// NewGuard returns a Render3 that checks the distances sampled by a renderer.
+func NewGuard(r Render3) *Guard {
+	return &Guard{r: r}
+}
+
+// Info returns a string describing the rendered volume.
+func (g *Guard) Info(s sdf.SDF3) string {
+	return g.r.Info(s)
+}
+
+// meshCollector keeps the triangles written by a renderer.
+type meshCollector struct {
+	mu   sync.Mutex
+	mesh []*sdf.Triangle3
+}
+
+func (m *meshCollector) Write(in []*sdf.Triangle3) error {
+	m.mu.Lock()
+	m.mesh = append(m.mesh, in...)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *meshCollector) Close() error {
+	return nil
+}
+
+// finite returns true if the components of a vector are not NaN or Inf.
+func finite(v v3.Vec) bool {
+	for _, x := range []float64{v.X, v.Y, v.Z} {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// Render renders an SDF3. The triangles are written to the output if all
+// distances were finite. Otherwise the output is closed without triangles
+// and Err returns the diagnostic.
+func (g *Guard) Render(s sdf.SDF3, output sdf.Triangle3Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.err = nil
+	bb := s.BoundingBox()
+	if !finite(bb.Min) || !finite(bb.Max) {
+		g.err = sdf.ErrMsg(fmt.Sprintf("non-finite bounding box %v %v", bb.Min, bb.Max))
+		output.Close()
+		return
+	}
+	gs := sdf.Guard3D(s)
+	var m meshCollector
+	g.r.Render(gs, &m)
+	if g.err = gs.Err(); g.err == nil {
+		output.Write(m.mesh)
+	}
+	output.Close()
+}
+
+// Err returns the error of the last render, a *sdf.NonFiniteError for a non-finite distance.
+func (g *Guard) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+//-----------------------------------------------------------------------------
+
+// ToTrianglesGuarded renders an SDF3 to a triangle mesh, failing on non-finite distances.
+func ToTrianglesGuarded(s sdf.SDF3, r Render3) ([]*sdf.Triangle3, error) {
+	g := NewGuard(r)
+	mesh := ToTriangles(s, g)
+	if err := g.Err(); err != nil {
+		return nil, err
+	}
+	return mesh, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,83 @@
+//-----------------------------------------------------------------------------
+/*
+
+Guarded Rendering Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// nanSDF3 is a sphere with NaN distances for x > 0.
+type nanSDF3 struct {
+	sdf.SDF3
+}
+
+func (s *nanSDF3) Evaluate(p v3.Vec) float64 {
+	if p.X > 0 {
+		return math.NaN()
+	}
+	return s.SDF3.Evaluate(p)
+}
+
+func Test_Guard(t *testing.T) {
+	box, _ := sdf.Box3D(v3.Vec{10, 10, 10}, 0)
+	sphere, _ := sdf.Sphere3D(4)
+	good := sdf.Union3D(box, sdf.Transform3D(sphere, sdf.Translate3d(v3.Vec{10, 0, 0})))
+	bad := sdf.Union3D(box, sdf.Transform3D(&nanSDF3{sphere}, sdf.Translate3d(v3.Vec{10, 0, 0})))
+
+	// finite distances give the same mesh
+	r := NewMarchingCubesUniform(30)
+	mesh, err := ToTrianglesGuarded(good, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mesh) == 0 || len(mesh) != len(ToTriangles(good, r)) {
+		t.Errorf("guarded mesh has %d triangles", len(mesh))
+	}
+
+	// non-finite distances fail with the node path
+	mesh, err = ToTrianglesGuarded(bad, r)
+	var nf *sdf.NonFiniteError
+	if !errors.As(err, &nf) {
+		t.Fatalf("expected a non-finite error, got %v", err)
+	}
+	if mesh != nil {
+		t.Error("expected no mesh")
+	}
+	if !math.IsNaN(nf.Value) || nf.Point.X <= 10 {
+		t.Errorf("bad sample %g at %v", nf.Value, nf.Point)
+	}
+	path := []string{"UnionSDF3", "[1]TransformSDF3", "[0]nanSDF3"}
+	if !reflect.DeepEqual(nf.Path, path) {
+		t.Errorf("got path %v, expected %v", nf.Path, path)
+	}
+
+	// the export option removes the broken file
+	file := filepath.Join(t.TempDir(), "bad.stl")
+	ToSTLWithOptions(bad, file, r, ExportOptions{Guard: true})
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Error("expected no file for a non-finite render")
+	}
+	file = filepath.Join(t.TempDir(), "good.stl")
+	ToSTLWithOptions(good, file, r, ExportOptions{Guard: true})
+	if _, err := os.Stat(file); err != nil {
+		t.Error(err)
+	}
+}
+
+//-----------------------------------------------------------------------------
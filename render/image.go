@@ -0,0 +1,193 @@
+//-----------------------------------------------------------------------------
+/*
+
+Raster Rendering of an SDF2
+
+Each pixel is evaluated once at its center. The distance to the boundary is
+used to compute the pixel coverage of the shape and outline, which gives
+anti-aliased edges without supersampling. This works best for SDF2s that
+are exact distance functions near the boundary.
+
+The optional heatmap colors the distance field with contour bands. It is
+useful for checking the distance field of new SDF2s.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// defaultDPI is the default image resolution.
+const defaultDPI = 96.0
+
+// ImageOptions configures raster rendering of an SDF2.
+type ImageOptions struct {
+	DPI          float64     // pixels per inch, the model units are mm (0 = 96)
+	Width        int         // image width in pixels, overrides DPI (0 = use DPI)
+	Margin       float64     // margin around the shape in model units
+	Background   color.Color // background color (nil = white)
+	Fill         color.Color // fill color (nil = black)
+	Outline      color.Color // outline color (nil = no outline)
+	OutlineWidth float64     // outline width in pixels (0 = 1)
+	Heatmap      bool        // color the distance field instead of the fill and background
+	Bands        float64     // heatmap contour band spacing in model units (0 = 1/20 of the size)
+}
+
+// rgba returns the color components as floats (0..1).
+func rgba(c color.Color) [4]float64 {
+	r, g, b, a := c.RGBA()
+	return [4]float64{float64(r) / 0xffff, float64(g) / 0xffff, float64(b) / 0xffff, float64(a) / 0xffff}
+}
+
+// blend blends color c over color dst with coverage k.
+func blend(dst, c [4]float64, k float64) [4]float64 {
+	k *= c[3]
+	for i := range dst {
+		dst[i] += (c[i] - dst[i]) * k
+	}
+	return dst
+}
+
+// clamp01 clamps x to the range 0..1.
+func clamp01(x float64) float64 {
+	return math.Max(0, math.Min(1, x))
+}
+
+// heatmap returns the color of a distance value.
+func heatmap(d, bands float64) [4]float64 {
+	var c [4]float64
+	if d > 0 {
+		c = [4]float64{0.9, 0.6, 0.3, 1}
+	} else {
+		c = [4]float64{0.65, 0.85, 1.0, 1}
+	}
+	// darken away from the surface, with contour bands
+	k := (1 - math.Exp(-4*math.Abs(d)/(20*bands))) * (0.8 + 0.2*math.Cos(sdf.Tau*d/bands))
+	for i := 0; i < 3; i++ {
+		c[i] *= k
+	}
+	return c
+}
+
+// ToImage renders an SDF2 to an anti-aliased image.
+func ToImage(s sdf.SDF2, opts ImageOptions) (*image.RGBA, error) {
+	if opts.DPI < 0 || opts.Width < 0 || opts.Margin < 0 || opts.OutlineWidth < 0 || opts.Bands < 0 {
+		return nil, sdf.ErrMsg("DPI/Width/Margin/OutlineWidth/Bands < 0")
+	}
+	bb := s.BoundingBox().Enlarge(v2.Vec{2 * opts.Margin, 2 * opts.Margin})
+	size := bb.Size()
+	if size.X <= 0 || size.Y <= 0 {
+		return nil, sdf.ErrMsg("empty bounding box")
+	}
+	// model units per pixel
+	var pixel float64
+	if opts.Width > 0 {
+		pixel = size.X / float64(opts.Width)
+	} else {
+		dpi := opts.DPI
+		if dpi == 0 {
+			dpi = defaultDPI
+		}
+		pixel = sdf.MillimetresPerInch / dpi
+	}
+	nx := int(math.Ceil(size.X / pixel))
+	ny := int(math.Ceil(size.Y / pixel))
+	img := image.NewRGBA(image.Rect(0, 0, nx, ny))
+
+	background := rgba(color.White)
+	if opts.Background != nil {
+		background = rgba(opts.Background)
+	}
+	fill := rgba(color.Black)
+	if opts.Fill != nil {
+		fill = rgba(opts.Fill)
+	}
+	w := opts.OutlineWidth
+	if w == 0 {
+		w = 1
+	}
+	bands := opts.Bands
+	if bands == 0 {
+		bands = size.MaxComponent() / 20
+	}
+
+	// render the rows in parallel
+	rows := make(chan int, ny)
+	for y := 0; y < ny; y++ {
+		rows <- y
+	}
+	close(rows)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for y := range rows {
+				py := bb.Max.Y - (float64(y)+0.5)*pixel
+				for x := 0; x < nx; x++ {
+					d := s.Evaluate(v2.Vec{bb.Min.X + (float64(x)+0.5)*pixel, py})
+					var c [4]float64
+					if opts.Heatmap {
+						c = heatmap(d, bands)
+					} else {
+						c = blend(background, fill, clamp01(0.5-d/pixel))
+					}
+					if opts.Outline != nil {
+						c = blend(c, rgba(opts.Outline), clamp01(0.5*w+0.5-math.Abs(d)/pixel))
+					}
+					img.SetRGBA(x, y, color.RGBA{
+						uint8(255*c[0] + 0.5),
+						uint8(255*c[1] + 0.5),
+						uint8(255*c[2] + 0.5),
+						uint8(255*c[3] + 0.5),
+					})
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return img, nil
+}
+
+// ToPNG renders an SDF2 to an anti-aliased PNG file.
+func ToPNG(s sdf.SDF2, path string, opts ImageOptions) error {
+	img, err := ToImage(s, opts)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ToPNGWriter renders an SDF2 to an anti-aliased PNG written to a writer.
+func ToPNGWriter(s sdf.SDF2, w io.Writer, opts ImageOptions) error {
+	img, err := ToImage(s, opts)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+//-----------------------------------------------------------------------------
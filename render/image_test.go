@@ -0,0 +1,47 @@
+//-----------------------------------------------------------------------------
+/*
+
+Raster Rendering Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_ToImage(t *testing.T) {
+	s, err := sdf.Circle2D(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := ToImage(s, ImageOptions{Width: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := img.Bounds()
+	if b.Dx() != 200 || b.Dy() != 200 {
+		t.Fatalf("image size %v", b)
+	}
+	// the coverage of the dark pixels is the area of the circle
+	pixel := 20.0 / 200
+	var area float64
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			area += (1 - float64(img.RGBAAt(x, y).R)/255) * pixel * pixel
+		}
+	}
+	expected := sdf.Pi * 10 * 10
+	if math.Abs(area-expected)/expected > 1e-3 {
+		t.Errorf("area %f expected %f", area, expected)
+	}
+}
+
+//-----------------------------------------------------------------------------
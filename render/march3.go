@@ -25,11 +25,11 @@ import (
 //-----------------------------------------------------------------------------
 
 // evalReq is used for processing evaluations in parallel.
-// A slice of V3 is evaluated with fn, the result is stored in out.
+// A slice of V3 is evaluated with s (as a batch), the result is stored in out.
 type evalReq struct {
 	out []float64
 	p   []v3.Vec
-	fn  func(v3.Vec) float64
+	s   sdf.SDF3
 	wg  *sync.WaitGroup
 }
 
@@ -39,12 +39,8 @@ var evalProcessCh = make(chan evalReq, 100)
 func evalRoutines() {
 	for i := 0; i < runtime.NumCPU(); i++ {
 		go func() {
-			var i int
-			var p v3.Vec
 			for r := range evalProcessCh {
-				for i, p = range r.p {
-					r.out[i] = r.fn(p)
-				}
+				sdf.EvaluateN(r.s, r.p, r.out)
 				r.wg.Done()
 			}
 		}()
@@ -86,7 +82,7 @@ func (l *layerYZ) Evaluate(s sdf.SDF3, x int) {
 	// define the base struct for requesting evaluation
 	eReq := evalReq{
 		wg:  new(sync.WaitGroup),
-		fn:  s.Evaluate,
+		s:   s,
 		out: l.val1,
 	}
 
@@ -132,7 +128,7 @@ func (l *layerYZ) Get(x, y, z int) float64 {
 
 //-----------------------------------------------------------------------------
 
-func marchingCubes(s sdf.SDF3, box sdf.Box3, step float64, output sdf.Triangle3Writer) {
+func marchingCubes(s sdf.SDF3, box sdf.Box3, step float64, opts *MeshOptions, output sdf.Triangle3Writer) {
 
 	size := box.Size()
 	base := box.Min
@@ -180,7 +176,7 @@ func marchingCubes(s sdf.SDF3, box sdf.Box3, step float64, output sdf.Triangle3W
 					l.Get(1, y, z+1),
 					l.Get(1, y+1, z+1),
 					l.Get(0, y+1, z+1)}
-				output.Write(mcToTriangles(corners, values, 0))
+				output.Write(mcToTriangles(corners, values, 0, opts))
 				p.Z += dz
 			}
 			p.Y += dy
@@ -191,7 +187,23 @@ func marchingCubes(s sdf.SDF3, box sdf.Box3, step float64, output sdf.Triangle3W
 
 //-----------------------------------------------------------------------------
 
-func mcToTriangles(p [8]v3.Vec, v [8]float64, x float64) []*sdf.Triangle3 {
+// MeshOptions are the facet tolerances of the marching cubes renderers.
+// The zero value gives the defaults.
+type MeshOptions struct {
+	Epsilon   float64 // edge crossings closer than this to a cube corner are on the corner (0 = 1e-12)
+	Tolerance float64 // facets with vertices closer than this are dropped (0 = coincident vertices)
+}
+
+func (o *MeshOptions) epsilon() float64 {
+	if o.Epsilon <= 0 {
+		return epsilon
+	}
+	return o.Epsilon
+}
+
+//-----------------------------------------------------------------------------
+
+func mcToTriangles(p [8]v3.Vec, v [8]float64, x float64, opts *MeshOptions) []*sdf.Triangle3 {
 	// which of the 0..255 patterns do we have?
 	index := 0
 	for i := 0; i < 8; i++ {
@@ -210,7 +222,7 @@ func mcToTriangles(p [8]v3.Vec, v [8]float64, x float64) []*sdf.Triangle3 {
 		if mcEdgeTable[index]&bit != 0 {
 			a := mcPairTable[i][0]
 			b := mcPairTable[i][1]
-			points[i] = mcInterpolate(p[a], p[b], v[a], v[b], x)
+			points[i] = mcInterpolate(p[a], p[b], v[a], v[b], x, opts.epsilon())
 		}
 	}
 	// create the triangles
@@ -222,7 +234,7 @@ func mcToTriangles(p [8]v3.Vec, v [8]float64, x float64) []*sdf.Triangle3 {
 		t[2] = points[table[i*3+0]]
 		t[1] = points[table[i*3+1]]
 		t[0] = points[table[i*3+2]]
-		if !t.Degenerate(0) {
+		if !t.Degenerate(opts.Tolerance) {
 			result = append(result, &t)
 		}
 	}
@@ -231,7 +243,7 @@ func mcToTriangles(p [8]v3.Vec, v [8]float64, x float64) []*sdf.Triangle3 {
 
 //-----------------------------------------------------------------------------
 
-func mcInterpolate(p1, p2 v3.Vec, v1, v2, x float64) v3.Vec {
+func mcInterpolate(p1, p2 v3.Vec, v1, v2, x, epsilon float64) v3.Vec {
 
 	closeToV1 := math.Abs(x-v1) < epsilon
 	closeToV2 := math.Abs(x-v2) < epsilon
@@ -265,6 +277,7 @@ func mcInterpolate(p1, p2 v3.Vec, v1, v2, x float64) v3.Vec {
 // MarchingCubesUniform renders using marching cubes with uniform space sampling.
 type MarchingCubesUniform struct {
 	meshCells int // number of cells on the longest axis of bounding box. e.g 200
+	opts      MeshOptions
 }
 
 // NewMarchingCubesUniform returns a Render3 object.
@@ -274,6 +287,14 @@ func NewMarchingCubesUniform(meshCells int) *MarchingCubesUniform {
 	}
 }
 
+// NewMarchingCubesUniformWithOptions returns a Render3 object with the given facet tolerances.
+func NewMarchingCubesUniformWithOptions(meshCells int, opts MeshOptions) *MarchingCubesUniform {
+	return &MarchingCubesUniform{
+		meshCells: meshCells,
+		opts:      opts,
+	}
+}
+
 // Info returns a string describing the rendered volume.
 func (r *MarchingCubesUniform) Info(s sdf.SDF3) string {
 	bb0 := s.BoundingBox()
@@ -291,11 +312,16 @@ func (r *MarchingCubesUniform) Render(s sdf.SDF3, output sdf.Triangle3Writer) {
 	bb0 := s.BoundingBox()
 	bb0Size := bb0.Size()
 	meshInc := bb0Size.MaxComponent() / float64(r.meshCells)
+	if !(meshInc > 0) {
+		// an empty (or degenerate) bounding box has no surface
+		output.Close()
+		return
+	}
 	bb1Size := bb0Size.DivScalar(meshInc)
 	bb1Size = bb1Size.Ceil().AddScalar(1)
 	bb1Size = bb1Size.MulScalar(meshInc)
 	bb := sdf.NewBox3(bb0.Center(), bb1Size)
-	marchingCubes(s, bb, meshInc, output)
+	marchingCubes(s, bb, meshInc, &r.opts, output)
 	output.Close()
 }
 
@@ -0,0 +1,651 @@
+//-----------------------------------------------------------------------------
+/*
+
+Marching Cubes Octree with Detail Hints
+
+A model with detail hints (see sdf.DetailHint) is rendered with smaller
+leaf cubes within the hinted regions, and the usual leaf cubes elsewhere.
+
+Where a large leaf cube meets smaller ones the surface is sampled at
+different points on each side of the shared face, which leaves cracks. To
+close them the samples on the faces and edges of a larger leaf are
+interpolated from its corners, so the contour of the smaller cubes on the
+face runs between the same edge crossings as the contour of the larger
+cube. The triangles of the larger cube are then split along the contour
+of the smaller cubes. The interpolated samples may put the surface into
+cubes that were found to be empty, and these are added as leaves. If the
+contours still don't match (e.g. a feature crosses the face) the larger
+cube is subdivided, and the mesh is built again.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"math"
+	"sort"
+
+	"github.com/deadsy/sdfx/sdf"
+	"github.com/deadsy/sdfx/vec/conv"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// maxHintLevels is the maximum number of extra octree levels for the detail hints.
+const maxHintLevels = 8
+
+// maxStitchPasses is the maximum number of times the mesh is built.
+const maxStitchPasses = 16
+
+// mcCorners are the corner offsets of a cube in marching cubes order.
+var mcCorners = [8]v3i.Vec{
+	{X: 0, Y: 0, Z: 0}, {X: 1, Y: 0, Z: 0}, {X: 1, Y: 1, Z: 0}, {X: 0, Y: 1, Z: 0},
+	{X: 0, Y: 0, Z: 1}, {X: 1, Y: 0, Z: 1}, {X: 1, Y: 1, Z: 1}, {X: 0, Y: 1, Z: 1},
+}
+
+// hintRegion is a detail region on the octree lattice.
+type hintRegion struct {
+	lo, hi v3i.Vec // lattice bounds
+	n      uint    // leaf level
+}
+
+// hintOctree is an octree with smaller leaves in the detail regions.
+type hintOctree struct {
+	dc      *dcache3
+	top     *cube
+	base    uint                      // leaf level outside the detail regions
+	regions []hintRegion              // detail regions
+	leaves  []map[v3i.Vec]bool        // leaf cubes, by level
+	empty   []map[v3i.Vec]bool        // empty cubes, by level
+	grow    []cube                    // empty cubes the surface runs into
+	values  map[v3i.Vec]float64       // sampled values
+	mesh    map[cube][]*sdf.Triangle3 // triangles of each leaf
+}
+
+// getAxis returns a component of a lattice point.
+func getAxis(v v3i.Vec, a int) int {
+	switch a {
+	case 0:
+		return v.X
+	case 1:
+		return v.Y
+	}
+	return v.Z
+}
+
+// setAxis sets a component of a lattice point.
+func setAxis(v v3i.Vec, a, x int) v3i.Vec {
+	switch a {
+	case 0:
+		v.X = x
+	case 1:
+		v.Y = x
+	default:
+		v.Z = x
+	}
+	return v
+}
+
+// floorMul rounds x down to a multiple of s.
+func floorMul(x, s int) int {
+	if x >= 0 {
+		return x - x%s
+	}
+	return -((-x + s - 1) / s * s)
+}
+
+// newHintOctree returns the octree for a model with detail regions, or nil
+// if the regions don't need smaller leaves than the render resolution.
+func newHintOctree(s sdf.SDF3, resolution float64, regions []sdf.DetailRegion) *hintOctree {
+	// a feature is at least 2 leaf cubes across
+	k := make([]uint, len(regions))
+	var kMax uint
+	for i, r := range regions {
+		for resolution/float64(int(1)<<k[i]) > 0.5*r.Size && k[i] < maxHintLevels {
+			k[i]++
+		}
+		if k[i] > kMax {
+			kMax = k[i]
+		}
+	}
+	if kMax == 0 {
+		return nil
+	}
+	// the octree of the renderer with kMax more levels
+	dc, top := newOctree(s, resolution)
+	res := dc.resolution / float64(int(1)<<kMax)
+	levels := top.n + 1 + kMax
+	h := &hintOctree{
+		dc:     newDcache3(s, dc.origin, res, levels),
+		top:    &cube{v: v3i.Vec{X: 0, Y: 0, Z: 0}, n: levels - 1},
+		base:   1 + kMax,
+		leaves: make([]map[v3i.Vec]bool, levels),
+		empty:  make([]map[v3i.Vec]bool, levels),
+	}
+	for i := range h.leaves {
+		h.leaves[i] = make(map[v3i.Vec]bool)
+		h.empty[i] = make(map[v3i.Vec]bool)
+	}
+	for i, r := range regions {
+		if k[i] == 0 {
+			continue
+		}
+		lo := r.Box.Min.Sub(dc.origin).DivScalar(res)
+		hi := r.Box.Max.Sub(dc.origin).DivScalar(res)
+		h.regions = append(h.regions, hintRegion{
+			lo: v3i.Vec{X: int(math.Floor(lo.X)), Y: int(math.Floor(lo.Y)), Z: int(math.Floor(lo.Z))},
+			hi: v3i.Vec{X: int(math.Ceil(hi.X)), Y: int(math.Ceil(hi.Y)), Z: int(math.Ceil(hi.Z))},
+			n:  1 + kMax - k[i],
+		})
+	}
+	return h
+}
+
+//-----------------------------------------------------------------------------
+
+// leafLevel returns the level of the leaf cubes for a cube.
+func (h *hintOctree) leafLevel(c *cube) uint {
+	n := h.base
+	s := 1 << c.n
+	for _, r := range h.regions {
+		if r.n < n &&
+			c.v.X < r.hi.X && c.v.X+s > r.lo.X &&
+			c.v.Y < r.hi.Y && c.v.Y+s > r.lo.Y &&
+			c.v.Z < r.hi.Z && c.v.Z+s > r.lo.Z {
+			n = r.n
+		}
+	}
+	return n
+}
+
+// build finds the leaf cubes with a surface.
+func (h *hintOctree) build(c *cube) {
+	if h.dc.isEmpty(c) {
+		h.empty[c.n][c.v] = true
+		return
+	}
+	if c.n <= h.leafLevel(c) {
+		h.leaves[c.n][c.v] = true
+		return
+	}
+	h.split(c)
+}
+
+// split processes the sub cubes of a cube.
+func (h *hintOctree) split(c *cube) {
+	n := c.n - 1
+	s := 1 << n
+	for _, o := range mcCorners {
+		h.build(&cube{c.v.Add(o.MulScalar(s)), n})
+	}
+}
+
+// isLeaf returns true if a point is on a level n leaf cube.
+func (h *hintOctree) isLeaf(p v3i.Vec, n uint) bool {
+	if len(h.leaves[n]) == 0 {
+		return false
+	}
+	s := 1 << n
+	var lo, hi v3i.Vec
+	for a := 0; a < 3; a++ {
+		x := getAxis(p, a)
+		l := floorMul(x, s)
+		hi = setAxis(hi, a, l)
+		if l == x {
+			// on the lattice, the cube may be on either side
+			l -= s
+		}
+		lo = setAxis(lo, a, l)
+	}
+	for x := lo.X; x <= hi.X; x += s {
+		for y := lo.Y; y <= hi.Y; y += s {
+			for z := lo.Z; z <= hi.Z; z += s {
+				if h.leaves[n][v3i.Vec{X: x, Y: y, Z: z}] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// value returns the sampled value at a lattice point.
+func (h *hintOctree) value(p v3i.Vec) float64 {
+	if d, ok := h.values[p]; ok {
+		return d
+	}
+	d := h.sample(p)
+	h.values[p] = d
+	return d
+}
+
+// sample returns the value at a lattice point. A point on a face or an edge
+// of the largest leaf it touches is interpolated from the corners of the leaf.
+func (h *hintOctree) sample(p v3i.Vec) float64 {
+	for n := uint(len(h.leaves) - 1); n >= 1; n-- {
+		if !h.isLeaf(p, n) {
+			continue
+		}
+		s := 1 << n
+		lo := v3i.Vec{X: floorMul(p.X, s), Y: floorMul(p.Y, s), Z: floorMul(p.Z, s)}
+		f := [3]float64{
+			float64(p.X-lo.X) / float64(s),
+			float64(p.Y-lo.Y) / float64(s),
+			float64(p.Z-lo.Z) / float64(s),
+		}
+		if f[0] == 0 && f[1] == 0 && f[2] == 0 {
+			// a corner
+			break
+		}
+		d := 0.0
+		for _, o := range mcCorners {
+			w := 1.0
+			for a := 0; a < 3; a++ {
+				if getAxis(o, a) == 1 {
+					w *= f[a]
+				} else {
+					w *= 1 - f[a]
+				}
+			}
+			if w != 0 {
+				d += w * h.value(lo.Add(o.MulScalar(s)))
+			}
+		}
+		return d
+	}
+	_, d := h.dc.evaluate(p)
+	return d
+}
+
+// position returns the position of a lattice point.
+func (h *hintOctree) position(p v3i.Vec) v3.Vec {
+	return h.dc.origin.Add(conv.V3iToV3(p).MulScalar(h.dc.resolution))
+}
+
+// triangles returns the triangles of a leaf cube.
+func (h *hintOctree) triangles(c cube) []*sdf.Triangle3 {
+	s := 1 << c.n
+	var corners [8]v3.Vec
+	var values [8]float64
+	for i, o := range mcCorners {
+		p := c.v.Add(o.MulScalar(s))
+		corners[i] = h.position(p)
+		values[i] = h.value(p)
+	}
+	return mcToTriangles(corners, values, 0, &h.dc.opts)
+}
+
+//-----------------------------------------------------------------------------
+// stitching
+
+// faceEdge is the edge of a leaf triangle on a face of the leaf.
+type faceEdge struct {
+	t    int // triangle index
+	e    int // edge index, from vertex e to e+1
+	a, b v3.Vec
+}
+
+// inBox returns true if a point is within a box, with a tolerance.
+func inBox(p v3.Vec, b sdf.Box3, tol float64) bool {
+	return p.X >= b.Min.X-tol && p.X <= b.Max.X+tol &&
+		p.Y >= b.Min.Y-tol && p.Y <= b.Max.Y+tol &&
+		p.Z >= b.Min.Z-tol && p.Z <= b.Max.Z+tol
+}
+
+// onFace returns the triangle edges on a face (a flat box) of a cube.
+func onFace(mesh []*sdf.Triangle3, axis int, face sdf.Box3, tol float64) []faceEdge {
+	k := face.Min.Get(axis)
+	var edges []faceEdge
+	for i, t := range mesh {
+		for e := 0; e < 3; e++ {
+			a, b := t[e], t[(e+1)%3]
+			if a.Get(axis) == k && b.Get(axis) == k && inBox(a, face, tol) && inBox(b, face, tol) {
+				edges = append(edges, faceEdge{i, e, a, b})
+			}
+		}
+	}
+	return edges
+}
+
+// onBorder returns true if a polyline runs along an edge of a face.
+func onBorder(l polyline, axis int, face sdf.Box3, tol float64) bool {
+	for _, a := range []int{(axis + 1) % 3, (axis + 2) % 3} {
+		for _, k := range []float64{face.Min.Get(a), face.Max.Get(a)} {
+			border := true
+			for _, p := range l {
+				if math.Abs(p.Get(a)-k) > tol {
+					border = false
+					break
+				}
+			}
+			if border {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// polyline is a chain of points.
+type polyline []v3.Vec
+
+// polylines joins the edges of the smaller leaves on a face into polylines.
+// It returns false if there are closed loops.
+func polylines(edges []faceEdge, tol float64) ([]polyline, bool) {
+	// weld the end points
+	var points []v3.Vec
+	index := func(p v3.Vec) int {
+		for i, q := range points {
+			if p.Sub(q).Length() <= tol {
+				return i
+			}
+		}
+		points = append(points, p)
+		return len(points) - 1
+	}
+	adj := make(map[int][]int)
+	seen := make(map[[2]int]bool)
+	for _, e := range edges {
+		a, b := index(e.a), index(e.b)
+		if a == b || seen[[2]int{a, b}] || seen[[2]int{b, a}] {
+			// degenerate, or on the face of two cubes
+			continue
+		}
+		seen[[2]int{a, b}] = true
+		adj[a] = append(adj[a], b)
+		adj[b] = append(adj[b], a)
+	}
+	used := make(map[[2]int]bool)
+	key := func(a, b int) [2]int {
+		if a > b {
+			a, b = b, a
+		}
+		return [2]int{a, b}
+	}
+	var lines []polyline
+	count := 0
+	for i := range points {
+		if len(adj[i]) != 1 || used[key(i, adj[i][0])] {
+			continue
+		}
+		// walk from an end point
+		line := polyline{points[i]}
+		prev, cur := -1, i
+		for {
+			next := -1
+			for _, j := range adj[cur] {
+				if j != prev && !used[key(cur, j)] {
+					next = j
+					break
+				}
+			}
+			if next < 0 {
+				break
+			}
+			used[key(cur, next)] = true
+			count++
+			line = append(line, points[next])
+			prev, cur = cur, next
+		}
+		lines = append(lines, line)
+	}
+	total := 0
+	for _, a := range adj {
+		total += len(a)
+	}
+	return lines, 2*count == total
+}
+
+// faceLeaves finds the leaves within a cube on the other side of a face of
+// a leaf that touch the face, and the smaller empty cubes on the face.
+func (h *hintOctree) faceLeaves(c, leaf cube, axis, side int, leaves, empty *[]cube) {
+	s, k := 1<<c.n, 1<<leaf.n
+	plane := getAxis(leaf.v, axis) + side*k
+	x := getAxis(c.v, axis)
+	if (side == 1 && (x > plane || x+s <= plane)) || (side == 0 && (x >= plane || x+s < plane)) {
+		return
+	}
+	inside := true
+	for _, a := range []int{(axis + 1) % 3, (axis + 2) % 3} {
+		lo, hi := getAxis(leaf.v, a), getAxis(leaf.v, a)+k
+		x := getAxis(c.v, a)
+		if x > hi || x+s < lo {
+			return
+		}
+		inside = inside && x < hi && x+s > lo
+	}
+	if h.leaves[c.n][c.v] {
+		*leaves = append(*leaves, c)
+		return
+	}
+	if h.empty[c.n][c.v] {
+		if inside && c.n < leaf.n {
+			*empty = append(*empty, c)
+		}
+		return
+	}
+	if c.n <= 1 {
+		return
+	}
+	for _, o := range mcCorners {
+		h.faceLeaves(cube{c.v.Add(o.MulScalar(s / 2)), c.n - 1}, leaf, axis, side, leaves, empty)
+	}
+}
+
+// matchFace matches the edges of the triangles of a leaf on a face with
+// the contour of the smaller leaves on the face, and adds the points of the
+// contour to be inserted into the edges. It returns false if they don't match.
+func matchFace(mesh, fine []*sdf.Triangle3, axis int, face sdf.Box3, tol float64, inserts map[[2]int]polyline) bool {
+	lines, ok := polylines(onFace(fine, axis, face, tol), tol)
+	if !ok {
+		return false
+	}
+	matched := make([]bool, len(lines))
+	for _, e := range onFace(mesh, axis, face, tol) {
+		found := false
+		for i, l := range lines {
+			if matched[i] {
+				continue
+			}
+			first, last := l[0], l[len(l)-1]
+			if e.a.Sub(first).Length() <= tol && e.b.Sub(last).Length() <= tol {
+				inserts[[2]int{e.t, e.e}] = l[1 : len(l)-1]
+			} else if e.a.Sub(last).Length() <= tol && e.b.Sub(first).Length() <= tol {
+				r := make(polyline, 0, len(l)-2)
+				for j := len(l) - 2; j >= 1; j-- {
+					r = append(r, l[j])
+				}
+				inserts[[2]int{e.t, e.e}] = r
+			} else {
+				continue
+			}
+			matched[i], found = true, true
+			break
+		}
+		if !found && !onBorder(polyline{e.a, e.b}, axis, face, tol) {
+			// an edge on the border of the face may be on the other face
+			return false
+		}
+	}
+	for i, m := range matched {
+		if !m && !onBorder(lines[i], axis, face, tol) {
+			return false
+		}
+	}
+	return true
+}
+
+// stitch splits the triangles of a leaf along the contours of the smaller
+// leaves on its faces. It returns false if the contours don't match.
+func (h *hintOctree) stitch(c cube) bool {
+	mesh := h.mesh[c]
+	if len(mesh) == 0 {
+		return true
+	}
+	s := 1 << c.n
+	tol := 1e-6 * h.dc.resolution
+	inserts := make(map[[2]int]polyline)
+	for axis := 0; axis < 3; axis++ {
+		for side := 0; side < 2; side++ {
+			plane := getAxis(c.v, axis) + side*s
+			// the cube on the other side of the face
+			nv := setAxis(c.v, axis, getAxis(c.v, axis)+(2*side-1)*s)
+			coarse := false
+			for n := c.n; n < uint(len(h.leaves)); n++ {
+				m := 1 << n
+				if h.leaves[n][v3i.Vec{X: floorMul(nv.X, m), Y: floorMul(nv.Y, m), Z: floorMul(nv.Z, m)}] {
+					coarse = true
+					break
+				}
+			}
+			if coarse {
+				// the same size or larger, the larger leaf is stitched
+				continue
+			}
+			// The smaller leaves on the other side of the face. A surface on
+			// the face may have edges on the edges of the face, which are
+			// shared with the leaves next to the face.
+			var leaves, empty []cube
+			h.faceLeaves(*h.top, c, axis, side, &leaves, &empty)
+			if h.emptyLevel(nv, c.n) != 0 {
+				empty = append(empty, cube{nv, c.n})
+			}
+			var fine []*sdf.Triangle3
+			for _, f := range leaves {
+				if f.n < c.n {
+					fine = append(fine, h.mesh[f]...)
+				}
+			}
+			face := sdf.Box3{
+				Min: h.position(setAxis(c.v, axis, plane)),
+				Max: h.position(setAxis(c.v.AddScalar(s), axis, plane)),
+			}
+			if !matchFace(mesh, fine, axis, face, tol, inserts) {
+				if len(empty) == 0 {
+					return false
+				}
+				// The samples on the face of a larger leaf are interpolated,
+				// so the surface may run into cubes found to be empty.
+				h.grow = append(h.grow, empty...)
+			}
+		}
+	}
+	if len(inserts) == 0 {
+		return true
+	}
+	// split the triangles with a fan from the centroid
+	var out []*sdf.Triangle3
+	for i, t := range mesh {
+		var poly polyline
+		for e := 0; e < 3; e++ {
+			poly = append(poly, t[e])
+			poly = append(poly, inserts[[2]int{i, e}]...)
+		}
+		if len(poly) == 3 {
+			out = append(out, t)
+			continue
+		}
+		center := t[0].Add(t[1]).Add(t[2]).DivScalar(3)
+		for j := range poly {
+			out = append(out, &sdf.Triangle3{center, poly[j], poly[(j+1)%len(poly)]})
+		}
+	}
+	h.mesh[c] = out
+	return true
+}
+
+// sortedLeaves returns the leaf cubes, smallest first, in a repeatable order.
+func (h *hintOctree) sortedLeaves() []cube {
+	var leaves []cube
+	for n := range h.leaves {
+		for v := range h.leaves[n] {
+			leaves = append(leaves, cube{v, uint(n)})
+		}
+	}
+	sort.Slice(leaves, func(i, j int) bool {
+		a, b := leaves[i], leaves[j]
+		if a.n != b.n {
+			return a.n < b.n
+		}
+		if a.v.X != b.v.X {
+			return a.v.X < b.v.X
+		}
+		if a.v.Y != b.v.Y {
+			return a.v.Y < b.v.Y
+		}
+		return a.v.Z < b.v.Z
+	})
+	return leaves
+}
+
+// emptyLevel returns the level of the empty cube containing a level n cube, or 0.
+func (h *hintOctree) emptyLevel(v v3i.Vec, n uint) uint {
+	for m := n; m < uint(len(h.empty)); m++ {
+		s := 1 << m
+		if h.empty[m][v3i.Vec{X: floorMul(v.X, s), Y: floorMul(v.Y, s), Z: floorMul(v.Z, s)}] {
+			return m
+		}
+	}
+	return 0
+}
+
+// fill makes a cube within an empty cube a leaf.
+func (h *hintOctree) fill(c cube) {
+	m := h.emptyLevel(c.v, c.n)
+	if m == 0 {
+		return
+	}
+	// split the empty cube down to the level of the leaf
+	for ; m > c.n; m-- {
+		s := 1 << m
+		v := v3i.Vec{X: floorMul(c.v.X, s), Y: floorMul(c.v.Y, s), Z: floorMul(c.v.Z, s)}
+		delete(h.empty[m], v)
+		for _, o := range mcCorners {
+			h.empty[m-1][v.Add(o.MulScalar(s/2))] = true
+		}
+	}
+	delete(h.empty[c.n], c.v)
+	h.leaves[c.n][c.v] = true
+}
+
+// render builds the stitched mesh of the leaves.
+func (h *hintOctree) render() []*sdf.Triangle3 {
+	for pass := 0; ; pass++ {
+		leaves := h.sortedLeaves()
+		h.values = make(map[v3i.Vec]float64)
+		h.mesh = make(map[cube][]*sdf.Triangle3)
+		for _, c := range leaves {
+			h.mesh[c] = h.triangles(c)
+		}
+		// the smaller leaves are stitched first
+		var split []cube
+		h.grow = nil
+		for _, c := range leaves {
+			if !h.stitch(c) && c.n > 1 {
+				split = append(split, c)
+			}
+		}
+		if (len(split) == 0 && len(h.grow) == 0) || pass == maxStitchPasses-1 {
+			break
+		}
+		// subdivide the leaves with unmatched contours
+		for _, c := range split {
+			delete(h.leaves[c.n], c.v)
+			h.split(&cube{c.v, c.n})
+		}
+		// add the empty cubes the surface runs into
+		for _, c := range h.grow {
+			h.fill(c)
+		}
+	}
+	var mesh []*sdf.Triangle3
+	for _, c := range h.sortedLeaves() {
+		mesh = append(mesh, h.mesh[c]...)
+	}
+	return mesh
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,98 @@
+//-----------------------------------------------------------------------------
+/*
+
+Detail Hint Rendering Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// hintPlate returns a plate with a small engraved logo (a slot and a ring).
+func hintPlate(t *testing.T, size float64) sdf.SDF3 {
+	plate, err := sdf.Box3D(v3.Vec{X: 40, Y: 40, Z: 4}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slot, err := sdf.Box3D(v3.Vec{X: 6, Y: 0.6, Z: 2}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := sdf.Cylinder3D(2, 1.4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hole, err := sdf.Cylinder3D(2, 0.8, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logo := sdf.Union3D(slot, sdf.Transform3D(sdf.Difference3D(ring, hole), sdf.Translate3d(v3.Vec{X: 5})))
+	logo = sdf.Transform3D(sdf.DetailHint(logo, size), sdf.Translate3d(v3.Vec{X: 5, Y: 7, Z: 2}))
+	return sdf.Difference3D(plate, logo)
+}
+
+// slotFloor returns the number of vertices on the floor of the slot.
+func slotFloor(mesh []*sdf.Triangle3) int {
+	n := 0
+	for _, t := range mesh {
+		for _, p := range t {
+			if p.X > 3 && p.X < 7 && math.Abs(p.Y-7) < 0.3 && math.Abs(p.Z-1) < 0.1 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func Test_DetailHint(t *testing.T) {
+	// the slot is smaller than the cells
+	plain := ToTriangles(hintPlate(t, 0), NewMarchingCubesOctree(20))
+	if n := slotFloor(plain); n != 0 {
+		t.Errorf("expected no slot at 20 cells, got %d vertices", n)
+	}
+
+	s := hintPlate(t, 0.3)
+	mesh := ToTriangles(s, NewMarchingCubesOctree(20))
+	if r := Validate(mesh); !r.Watertight() {
+		t.Errorf("hinted mesh is not watertight: %s", r)
+	}
+	if slotFloor(mesh) == 0 {
+		t.Error("the hinted slot is not rendered")
+	}
+	// the same detail everywhere needs 8x the cells
+	fine := ToTriangles(hintPlate(t, 0), NewMarchingCubesOctree(160))
+	if len(mesh) > len(fine)/4 {
+		t.Errorf("expected far fewer than %d triangles, got %d", len(fine), len(mesh))
+	}
+
+	// the mesh is closed at other resolutions and orientations
+	s = sdf.Transform3D(s, sdf.RotateX(0.3).Mul(sdf.RotateZ(0.2)))
+	for _, cells := range []int{10, 33} {
+		if r := Validate(ToTriangles(s, NewMarchingCubesOctree(cells))); !r.Watertight() {
+			t.Errorf("%d cells: hinted mesh is not watertight: %s", cells, r)
+		}
+	}
+
+	// a hint coarser than the cells changes nothing
+	coarse := ToTriangles(hintPlate(t, 5), NewMarchingCubesOctree(20))
+	if len(coarse) != len(plain) {
+		t.Fatalf("expected %d triangles, got %d", len(plain), len(coarse))
+	}
+	for i := range coarse {
+		if *coarse[i] != *plain[i] {
+			t.Fatalf("triangle %d differs", i)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
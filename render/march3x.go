@@ -35,12 +35,14 @@ type cube struct {
 // is about 2x a non-cached evaluation.
 
 type dcache3 struct {
-	origin     v3.Vec              // origin of the overall bounding cube
-	resolution float64             // size of smallest octree cube
-	hdiag      []float64           // lookup table of cube half diagonals
-	s          sdf.SDF3            // the SDF3 to be rendered
-	cache      map[v3i.Vec]float64 // cache of distances
-	lock       sync.RWMutex        // lock the the cache during reads/writes
+	origin     v3.Vec                    // origin of the overall bounding cube
+	resolution float64                   // size of smallest octree cube
+	hdiag      []float64                 // lookup table of cube half diagonals
+	s          sdf.SDF3                  // the SDF3 to be rendered
+	cache      map[v3i.Vec]float64       // cache of distances
+	lock       sync.RWMutex              // lock the the cache during reads/writes
+	visit      func(c *cube, empty bool) // called for each cube processed (debug)
+	opts       MeshOptions               // facet tolerances
 }
 
 func newDcache3(s sdf.SDF3, origin v3.Vec, resolution float64, n uint) *dcache3 {
@@ -102,7 +104,11 @@ func (dc *dcache3) isEmpty(c *cube) bool {
 
 // Process a cube. Generate triangles, or more cubes.
 func (dc *dcache3) processCube(c *cube, output sdf.Triangle3Writer) {
-	if !dc.isEmpty(c) {
+	empty := dc.isEmpty(c)
+	if dc.visit != nil {
+		dc.visit(c, empty)
+	}
+	if !empty {
 		if c.n == 1 {
 			// this cube is at the required resolution
 			c0, d0 := dc.evaluate(c.v.Add(v3i.Vec{X: 0, Y: 0, Z: 0}))
@@ -116,7 +122,7 @@ func (dc *dcache3) processCube(c *cube, output sdf.Triangle3Writer) {
 			corners := [8]v3.Vec{c0, c1, c2, c3, c4, c5, c6, c7}
 			values := [8]float64{d0, d1, d2, d3, d4, d5, d6, d7}
 			// output the triangle(s) for this cube
-			output.Write(mcToTriangles(corners, values, 0))
+			output.Write(mcToTriangles(corners, values, 0, &dc.opts))
 		} else {
 			// process the sub cubes
 			n := c.n - 1
@@ -136,8 +142,8 @@ func (dc *dcache3) processCube(c *cube, output sdf.Triangle3Writer) {
 
 //-----------------------------------------------------------------------------
 
-// marchingCubesOctree generates a triangle mesh for an SDF3 using octree subdivision.
-func marchingCubesOctree(s sdf.SDF3, resolution float64, output sdf.Triangle3Writer) {
+// newOctree returns the distance cache and the top level cube of the octree for an SDF3.
+func newOctree(s sdf.SDF3, resolution float64) (*dcache3, *cube) {
 	// Scale the bounding box about the center to make sure the boundaries
 	// aren't on the object surface.
 	bb := s.BoundingBox()
@@ -150,16 +156,35 @@ func marchingCubesOctree(s sdf.SDF3, resolution float64, output sdf.Triangle3Wri
 	levels := uint(math.Ceil(math.Log2(longAxis/resolution))) + 1
 	// create the distance cache
 	dc := newDcache3(s, bb.Min, resolution, levels)
+	return dc, &cube{v: v3i.Vec{X: 0, Y: 0, Z: 0}, n: levels - 1}
+}
+
+// marchingCubesOctree generates a triangle mesh for an SDF3 using octree subdivision.
+func marchingCubesOctree(s sdf.SDF3, resolution float64, opts MeshOptions, output sdf.Triangle3Writer) {
+	if regions := sdf.DetailRegions(s); len(regions) != 0 {
+		// smaller cubes in the detail regions (see march3h.go)
+		if h := newHintOctree(s, resolution, regions); h != nil {
+			h.dc.opts = opts
+			h.build(h.top)
+			output.Write(h.render())
+			output.Close()
+			return
+		}
+	}
+	dc, top := newOctree(s, resolution)
+	dc.opts = opts
 	// process the octree, start at the top level
-	dc.processCube(&cube{v: v3i.Vec{X: 0, Y: 0, Z: 0}, n: levels - 1}, output)
+	dc.processCube(top, output)
 	output.Close()
 }
 
 //-----------------------------------------------------------------------------
 
 // MarchingCubesOctree renders using marching cubes with octree space sampling.
+// The cubes are smaller within the subtrees marked with sdf.DetailHint.
 type MarchingCubesOctree struct {
 	meshCells int // number of cells on the longest axis of bounding box. e.g 200
+	opts      MeshOptions
 }
 
 // NewMarchingCubesOctree returns a Render3 object.
@@ -169,6 +194,14 @@ func NewMarchingCubesOctree(meshCells int) *MarchingCubesOctree {
 	}
 }
 
+// NewMarchingCubesOctreeWithOptions returns a Render3 object with the given facet tolerances.
+func NewMarchingCubesOctreeWithOptions(meshCells int, opts MeshOptions) *MarchingCubesOctree {
+	return &MarchingCubesOctree{
+		meshCells: meshCells,
+		opts:      opts,
+	}
+}
+
 // Info returns a string describing the rendered volume.
 func (r *MarchingCubesOctree) Info(s sdf.SDF3) string {
 	bbSize := s.BoundingBox().Size()
@@ -182,7 +215,12 @@ func (r *MarchingCubesOctree) Render(s sdf.SDF3, output sdf.Triangle3Writer) {
 	// work out the sampling resolution to use
 	bbSize := s.BoundingBox().Size()
 	resolution := bbSize.MaxComponent() / float64(r.meshCells)
-	marchingCubesOctree(s, resolution, output)
+	if !(resolution > 0) {
+		// an empty (or degenerate) bounding box has no surface
+		output.Close()
+		return
+	}
+	marchingCubesOctree(s, resolution, r.opts, output)
 }
 
 //-----------------------------------------------------------------------------
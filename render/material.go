@@ -0,0 +1,288 @@
+//-----------------------------------------------------------------------------
+/*
+
+Material Export
+
+Split the mesh of a model by the materials of its tagged subtrees (see
+sdf.WithMaterial) and write the parts with their colors:
+
+* 3MF and glTF: a colored part per material (Parts3MF, PartsGLTF).
+* OBJ: the faces of each material use a material of an MTL file.
+* AMF: a volume per material, with the material colors.
+* STEP: the faces are colored with STYLED_ITEMs.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+	"github.com/deadsy/sdfx/step"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// MaterialMesh is the part of a mesh with a material.
+type MaterialMesh struct {
+	Material sdf.Material
+	Mesh     []*sdf.Triangle3
+}
+
+// DefaultMaterial returns the material of the faces outside the tagged subtrees.
+func DefaultMaterial() sdf.Material {
+	return sdf.Material{Name: "default", Color: color.RGBA{128, 128, 128, 255}}
+}
+
+// SplitMaterials splits the mesh of a model by the materials of the tagged
+// subtrees nearest to the faces, in the order the materials are found.
+func SplitMaterials(s sdf.SDF3, mesh []*sdf.Triangle3) []MaterialMesh {
+	m := sdf.NewMaterialMap(s)
+	var parts []MaterialMesh
+	index := make(map[int]int)
+	for _, t := range mesh {
+		// faces within a quarter of their size of the nearest are on the same surface
+		tol := 0.25 * math.Max(t[1].Sub(t[0]).Length(), math.Max(t[2].Sub(t[1]).Length(), t[0].Sub(t[2]).Length()))
+		k := m.At(t[0].Add(t[1]).Add(t[2]).DivScalar(3), tol)
+		i, ok := index[k]
+		if !ok {
+			i = len(parts)
+			index[k] = i
+			mat := DefaultMaterial()
+			if k >= 0 {
+				mat = m.Materials[k]
+			}
+			parts = append(parts, MaterialMesh{Material: mat})
+		}
+		parts[i].Mesh = append(parts[i].Mesh, t)
+	}
+	return parts
+}
+
+// ToMaterials renders an SDF3 and splits the mesh by material.
+func ToMaterials(s sdf.SDF3, r Render3) []MaterialMesh {
+	return SplitMaterials(s, ToTriangles(s, r))
+}
+
+// Parts3MF returns the 3MF parts of the material meshes.
+func Parts3MF(parts []MaterialMesh) []Part3MF {
+	out := make([]Part3MF, len(parts))
+	for i, p := range parts {
+		out[i] = Part3MF{Name: p.Material.Name, Color: p.Material.Color, Mesh: p.Mesh}
+	}
+	return out
+}
+
+// PartsGLTF returns the glTF parts of the material meshes.
+func PartsGLTF(parts []MaterialMesh) []GLTFPart {
+	out := make([]GLTFPart, len(parts))
+	for i, p := range parts {
+		out[i] = GLTFPart{Name: p.Material.Name, Color: p.Material.Color, Mesh: p.Mesh}
+	}
+	return out
+}
+
+//-----------------------------------------------------------------------------
+
+// vertexIndex returns the indices of the welded vertices of the material meshes.
+func vertexIndex(parts []MaterialMesh) ([]v3.Vec, [][][3]int) {
+	index := make(map[v3.Vec]int)
+	var vertices []v3.Vec
+	faces := make([][][3]int, len(parts))
+	for i, p := range parts {
+		faces[i] = make([][3]int, len(p.Mesh))
+		for j, t := range p.Mesh {
+			for k, v := range t {
+				n, ok := index[v]
+				if !ok {
+					n = len(vertices)
+					index[v] = n
+					vertices = append(vertices, v)
+				}
+				faces[i][j][k] = n
+			}
+		}
+	}
+	return vertices, faces
+}
+
+// materialName returns a material name without white space.
+func materialName(m sdf.Material, i int) string {
+	name := strings.Join(strings.Fields(m.Name), "_")
+	if name == "" {
+		name = fmt.Sprintf("material%d", i)
+	}
+	return name
+}
+
+// rgb returns the color components in [0,1].
+func rgb(c color.RGBA) (float64, float64, float64) {
+	return float64(c.R) / 255, float64(c.G) / 255, float64(c.B) / 255
+}
+
+//-----------------------------------------------------------------------------
+// OBJ + MTL
+
+// WriteOBJMaterials writes material meshes as a Wavefront OBJ file, using
+// the materials of an MTL file, and writes the MTL file.
+func WriteOBJMaterials(obj, mtl io.Writer, mtlName string, parts []MaterialMesh) error {
+	vertices, faces := vertexIndex(parts)
+	w := bufio.NewWriter(obj)
+	fmt.Fprintf(w, "# sdfx: %d vertices, %d materials\n", len(vertices), len(parts))
+	fmt.Fprintf(w, "mtllib %s\n", mtlName)
+	for _, v := range vertices {
+		fmt.Fprintf(w, "v %g %g %g\n", v.X, v.Y, v.Z)
+	}
+	m := bufio.NewWriter(mtl)
+	for i, p := range parts {
+		name := materialName(p.Material, i)
+		r, g, b := rgb(p.Material.Color)
+		fmt.Fprintf(m, "newmtl %s\nKd %g %g %g\n", name, r, g, b)
+		fmt.Fprintf(w, "usemtl %s\n", name)
+		for _, f := range faces[i] {
+			// OBJ indices start at 1
+			fmt.Fprintf(w, "f %d %d %d\n", f[0]+1, f[1]+1, f[2]+1)
+		}
+	}
+	if err := m.Flush(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// SaveOBJMaterials writes material meshes to a Wavefront OBJ file and an
+// MTL file with the same name.
+func SaveOBJMaterials(path string, parts []MaterialMesh) error {
+	mtlPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".mtl"
+	obj, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+	mtl, err := os.Create(mtlPath)
+	if err != nil {
+		return err
+	}
+	defer mtl.Close()
+	if err := WriteOBJMaterials(obj, mtl, filepath.Base(mtlPath), parts); err != nil {
+		return err
+	}
+	if err := mtl.Close(); err != nil {
+		return err
+	}
+	return obj.Close()
+}
+
+//-----------------------------------------------------------------------------
+// AMF
+
+// xmlText returns text escaped for XML.
+func xmlText(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// WriteAMF writes material meshes as an AMF file (a single object with a
+// volume per material) to a writer.
+func WriteAMF(out io.Writer, parts []MaterialMesh) error {
+	vertices, faces := vertexIndex(parts)
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(w, "<amf unit=\"millimeter\" version=\"1.1\">\n")
+	fmt.Fprintf(w, "<metadata type=\"producer\">sdfx</metadata>\n")
+	for i, p := range parts {
+		r, g, b := rgb(p.Material.Color)
+		// material ids start at 1
+		fmt.Fprintf(w, "<material id=\"%d\"><metadata type=\"name\">%s</metadata>", i+1, xmlText(p.Material.Name))
+		fmt.Fprintf(w, "<color><r>%g</r><g>%g</g><b>%g</b></color></material>\n", r, g, b)
+	}
+	fmt.Fprintf(w, "<object id=\"0\"><mesh>\n<vertices>\n")
+	for _, v := range vertices {
+		fmt.Fprintf(w, "<vertex><coordinates><x>%g</x><y>%g</y><z>%g</z></coordinates></vertex>\n", v.X, v.Y, v.Z)
+	}
+	fmt.Fprintf(w, "</vertices>\n")
+	for i := range parts {
+		fmt.Fprintf(w, "<volume materialid=\"%d\">\n", i+1)
+		for _, f := range faces[i] {
+			fmt.Fprintf(w, "<triangle><v1>%d</v1><v2>%d</v2><v3>%d</v3></triangle>\n", f[0], f[1], f[2])
+		}
+		fmt.Fprintf(w, "</volume>\n")
+	}
+	fmt.Fprintf(w, "</mesh></object>\n</amf>\n")
+	return w.Flush()
+}
+
+// SaveAMF writes material meshes to an AMF file.
+func SaveAMF(path string, parts []MaterialMesh) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteAMF(f, parts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//-----------------------------------------------------------------------------
+// STEP
+
+// writeSTEPMaterials writes material meshes with colored faces to a STEP writer.
+func writeSTEPMaterials(writer *step.Writer, parts []MaterialMesh, opts STEPOptions) error {
+	var mesh []*sdf.Triangle3
+	var colors []int
+	palette := make([]step.Color, len(parts))
+	for i, p := range parts {
+		r, g, b := rgb(p.Material.Color)
+		palette[i] = step.Color{Name: p.Material.Name, R: r, G: g, B: b}
+		m := p.Mesh
+		if opts.SortFacets {
+			m = sortFacets(m)
+		}
+		mesh = append(mesh, m...)
+		for range m {
+			colors = append(colors, i)
+		}
+	}
+	writer.SetFaceColors(colors, palette)
+	// the facets of each material are sorted
+	opts.SortFacets = false
+	return writeSTEPMesh(writer, mesh, opts)
+}
+
+// WriteSTEPMaterials writes material meshes as a STEP file with colored faces to a writer.
+func WriteSTEPMaterials(w io.Writer, parts []MaterialMesh, opts STEPOptions) error {
+	writer := step.NewWriterFor(w, stepProductName(opts)+".step")
+	if err := writeSTEPMaterials(writer, parts, opts); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// SaveSTEPMaterials writes material meshes to a STEP file with colored faces.
+func SaveSTEPMaterials(path string, parts []MaterialMesh, opts STEPOptions) error {
+	writer, err := step.NewWriter(path)
+	if err != nil {
+		return err
+	}
+	if err := writeSTEPMaterials(writer, parts, opts); err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+//-----------------------------------------------------------------------------
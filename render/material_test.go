@@ -0,0 +1,130 @@
+//-----------------------------------------------------------------------------
+/*
+
+Material Export Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// twoColor returns a red box with a blue ball on top.
+func twoColor() sdf.SDF3 {
+	box, _ := sdf.Box3D(v3.Vec{X: 10, Y: 10, Z: 10}, 0)
+	ball, _ := sdf.Sphere3D(4)
+	ball = sdf.Transform3D(ball, sdf.Translate3d(v3.Vec{Z: 7}))
+	return sdf.Union3D(
+		sdf.WithMaterial(box, sdf.Material{Name: "red", Color: color.RGBA{255, 0, 0, 255}}),
+		sdf.WithMaterial(ball, sdf.Material{Name: "blue", Color: color.RGBA{0, 0, 255, 255}}),
+	)
+}
+
+func Test_SplitMaterials(t *testing.T) {
+	s := twoColor()
+	mesh := ToTriangles(s, NewMarchingCubesOctree(60))
+	parts := SplitMaterials(s, mesh)
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 materials, got %d", len(parts))
+	}
+	n := 0
+	for _, p := range parts {
+		n += len(p.Mesh)
+		for _, tri := range p.Mesh {
+			// the ball is above the box (the top of the box is at z = 5)
+			c := tri[0].Add(tri[1]).Add(tri[2]).DivScalar(3)
+			if c.Z < 4.8 && p.Material.Name != "red" || c.Z > 5.2 && p.Material.Name != "blue" {
+				t.Fatalf("%s face at %v", p.Material.Name, c)
+			}
+		}
+	}
+	if n != len(mesh) {
+		t.Errorf("expected %d faces, got %d", len(mesh), n)
+	}
+
+	// untagged models have the default material
+	box, _ := sdf.Box3D(v3.Vec{X: 1, Y: 1, Z: 1}, 0)
+	parts = ToMaterials(box, NewMarchingCubesOctree(10))
+	if len(parts) != 1 || parts[0].Material != DefaultMaterial() {
+		t.Errorf("expected the default material, got %v", parts)
+	}
+}
+
+func Test_MaterialWriters(t *testing.T) {
+	parts := ToMaterials(twoColor(), NewMarchingCubesOctree(30))
+	faces := len(parts[0].Mesh) + len(parts[1].Mesh)
+
+	// OBJ + MTL
+	var obj, mtl bytes.Buffer
+	if err := WriteOBJMaterials(&obj, &mtl, "model.mtl", parts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(obj.String(), "usemtl blue") || !strings.Contains(mtl.String(), "newmtl blue\nKd 0 0 1\n") {
+		t.Error("bad obj materials")
+	}
+	data := obj.Bytes()
+	if mesh, err := ReadOBJ(bytes.NewReader(data), int64(len(data))); err != nil || len(mesh) != faces {
+		t.Errorf("expected %d obj faces, got %d (%v)", faces, len(mesh), err)
+	}
+
+	// AMF
+	var amf bytes.Buffer
+	if err := WriteAMF(&amf, parts); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Materials []struct {
+			ID int `xml:"id,attr"`
+		} `xml:"material"`
+		Volumes []struct {
+			Triangles []struct{} `xml:"triangle"`
+		} `xml:"object>mesh>volume"`
+	}
+	if err := xml.Unmarshal(amf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Materials) != 2 || len(doc.Volumes) != 2 ||
+		len(doc.Volumes[0].Triangles)+len(doc.Volumes[1].Triangles) != faces {
+		t.Errorf("bad amf file")
+	}
+
+	// STEP
+	var stp bytes.Buffer
+	if err := WriteSTEPMaterials(&stp, parts, STEPOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(stp.String(), "=STYLED_ITEM("); n != faces {
+		t.Errorf("expected %d styled faces, got %d", faces, n)
+	}
+	if n := strings.Count(stp.String(), "=COLOUR_RGB("); n != 2 {
+		t.Errorf("expected 2 colors, got %d", n)
+	}
+	data = stp.Bytes()
+	if mesh, err := ReadSTEP(bytes.NewReader(data), int64(len(data))); err != nil || len(mesh) != faces {
+		t.Errorf("expected %d step faces, got %d (%v)", faces, len(mesh), err)
+	}
+
+	// 3MF
+	var buf bytes.Buffer
+	if err := Write3MF(&buf, Parts3MF(parts), ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data = buf.Bytes()
+	if mesh, err := Read3MF(bytes.NewReader(data), int64(len(data))); err != nil || len(mesh) != faces {
+		t.Errorf("expected %d 3mf faces, got %d (%v)", faces, len(mesh), err)
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,412 @@
+//-----------------------------------------------------------------------------
+/*
+
+Indexed Triangle Mesh
+
+A mesh with shared vertices: a vertex list, faces that index it and smooth
+vertex normals. The renderers produce triangle soups (each triangle has its
+own copy of its vertices), NewMesh welds the coincident vertices of a soup.
+
+Welding with a tolerance merges vertices closer than the tolerance. The
+vertices are hashed on a grid with the tolerance as the cell size, so only
+the neighboring cells are searched. Faces that lose a vertex to welding are
+removed.
+
+The mesh is the common form for the file writers: 3MF and OBJ write the
+indexed vertices, STL and STEP write the welded triangles. LoadMesh reads a
+triangle soup back from any of the formats, ReadMesh does the same from a
+reader (e.g. an upload) instead of a file.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+	"github.com/hpinc/go3mf"
+)
+
+//-----------------------------------------------------------------------------
+
+// Mesh is an indexed triangle mesh.
+type Mesh struct {
+	Vertices []v3.Vec // vertex positions
+	Faces    [][3]int // triangles, anticlockwise vertex indices
+	Normals  []v3.Vec // vertex normals, the area weighted average of the face normals
+}
+
+// NewMesh returns the indexed form of a triangle mesh. Vertices closer than the
+// tolerance are welded, a zero tolerance welds identical vertices.
+func NewMesh(triangles []*sdf.Triangle3, tolerance float64) (*Mesh, error) {
+	if tolerance < 0 {
+		return nil, sdf.ErrMsg("tolerance < 0")
+	}
+	m := &Mesh{}
+	var find func(p v3.Vec) int
+	if tolerance == 0 {
+		index := make(map[v3.Vec]int)
+		find = func(p v3.Vec) int {
+			i, ok := index[p]
+			if !ok {
+				i = len(m.Vertices)
+				index[p] = i
+				m.Vertices = append(m.Vertices, p)
+			}
+			return i
+		}
+	} else {
+		toKey := func(p v3.Vec) v3i.Vec {
+			return v3i.Vec{
+				int(math.Floor(p.X / tolerance)),
+				int(math.Floor(p.Y / tolerance)),
+				int(math.Floor(p.Z / tolerance)),
+			}
+		}
+		index := make(map[v3i.Vec][]int)
+		find = func(p v3.Vec) int {
+			k := toKey(p)
+			for dx := -1; dx <= 1; dx++ {
+				for dy := -1; dy <= 1; dy++ {
+					for dz := -1; dz <= 1; dz++ {
+						for _, i := range index[k.Add(v3i.Vec{dx, dy, dz})] {
+							if m.Vertices[i].Sub(p).Length() <= tolerance {
+								return i
+							}
+						}
+					}
+				}
+			}
+			i := len(m.Vertices)
+			index[k] = append(index[k], i)
+			m.Vertices = append(m.Vertices, p)
+			return i
+		}
+	}
+	m.Faces = make([][3]int, 0, len(triangles))
+	for _, t := range triangles {
+		f := [3]int{find(t[0]), find(t[1]), find(t[2])}
+		if f[0] != f[1] && f[1] != f[2] && f[2] != f[0] {
+			m.Faces = append(m.Faces, f)
+		}
+	}
+	m.Normals = make([]v3.Vec, len(m.Vertices))
+	for _, f := range m.Faces {
+		// the cross product length is twice the face area
+		n := m.Vertices[f[1]].Sub(m.Vertices[f[0]]).Cross(m.Vertices[f[2]].Sub(m.Vertices[f[0]]))
+		for _, i := range f {
+			m.Normals[i] = m.Normals[i].Add(n)
+		}
+	}
+	for i, n := range m.Normals {
+		if l := n.Length(); l != 0 {
+			m.Normals[i] = n.DivScalar(l)
+		}
+	}
+	return m, nil
+}
+
+// Triangle returns a face of the mesh as a triangle.
+func (m *Mesh) Triangle(i int) *sdf.Triangle3 {
+	f := m.Faces[i]
+	return &sdf.Triangle3{m.Vertices[f[0]], m.Vertices[f[1]], m.Vertices[f[2]]}
+}
+
+// Triangles returns the faces of the mesh as triangles.
+func (m *Mesh) Triangles() []*sdf.Triangle3 {
+	t := make([]*sdf.Triangle3, len(m.Faces))
+	for i := range m.Faces {
+		t[i] = m.Triangle(i)
+	}
+	return t
+}
+
+// BoundingBox returns the bounding box of the mesh vertices.
+func (m *Mesh) BoundingBox() sdf.Box3 {
+	if len(m.Vertices) == 0 {
+		return sdf.Box3{}
+	}
+	bb := sdf.Box3{Min: m.Vertices[0], Max: m.Vertices[0]}
+	for _, v := range m.Vertices[1:] {
+		bb = bb.Include(v)
+	}
+	return bb
+}
+
+//-----------------------------------------------------------------------------
+
+// to3MF returns the 3MF form of the mesh.
+func (m *Mesh) to3MF() *go3mf.Mesh {
+	mesh := &go3mf.Mesh{}
+	mesh.Vertices.Vertex = make([]go3mf.Point3D, len(m.Vertices))
+	for i, v := range m.Vertices {
+		mesh.Vertices.Vertex[i] = toPoint3D(v)
+	}
+	mesh.Triangles.Triangle = make([]go3mf.Triangle, len(m.Faces))
+	for i, f := range m.Faces {
+		mesh.Triangles.Triangle[i] = go3mf.Triangle{V1: uint32(f[0]), V2: uint32(f[1]), V3: uint32(f[2])}
+	}
+	return mesh
+}
+
+// SaveSTL writes the mesh to a binary STL file.
+func (m *Mesh) SaveSTL(path string) error {
+	return SaveSTL(path, m.Triangles())
+}
+
+// Save3MF writes the mesh to a 3MF file.
+func (m *Mesh) Save3MF(path string) error {
+	var model go3mf.Model
+	obj := &go3mf.Object{ID: model.Resources.UnusedID(), Mesh: m.to3MF()}
+	model.Resources.Objects = append(model.Resources.Objects, obj)
+	model.Build.Items = append(model.Build.Items, &go3mf.Item{ObjectID: obj.ID})
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := encode3MF(f, &model, nil); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// SaveOBJ writes the mesh to a Wavefront OBJ file with vertex normals.
+func (m *Mesh) SaveOBJ(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := m.WriteOBJ(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteOBJ writes the mesh as a Wavefront OBJ file with vertex normals to a writer.
+func (m *Mesh) WriteOBJ(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "# sdfx: %d vertices, %d faces\n", len(m.Vertices), len(m.Faces))
+	for _, v := range m.Vertices {
+		fmt.Fprintf(w, "v %g %g %g\n", v.X, v.Y, v.Z)
+	}
+	for _, n := range m.Normals {
+		fmt.Fprintf(w, "vn %g %g %g\n", n.X, n.Y, n.Z)
+	}
+	for _, x := range m.Faces {
+		// OBJ indices start at 1
+		a, b, c := x[0]+1, x[1]+1, x[2]+1
+		fmt.Fprintf(w, "f %d//%d %d//%d %d//%d\n", a, a, b, b, c, c)
+	}
+	return w.Flush()
+}
+
+// SaveSTEP writes the mesh to a STEP file.
+func (m *Mesh) SaveSTEP(path string, opts STEPOptions) error {
+	return SaveSTEPWithOptions(path, m.Triangles(), opts)
+}
+
+//-----------------------------------------------------------------------------
+
+// SaveOBJ writes a triangle mesh to a Wavefront OBJ file.
+func SaveOBJ(path string, mesh []*sdf.Triangle3) error {
+	m, err := NewMesh(mesh, 0)
+	if err != nil {
+		return err
+	}
+	return m.SaveOBJ(path)
+}
+
+// WriteOBJ writes a triangle mesh as a Wavefront OBJ file to a writer.
+func WriteOBJ(w io.Writer, mesh []*sdf.Triangle3) error {
+	m, err := NewMesh(mesh, 0)
+	if err != nil {
+		return err
+	}
+	return m.WriteOBJ(w)
+}
+
+// SaveOBJWithUV writes a triangle mesh to a Wavefront OBJ file with texture
+// coordinates (see UnwrapUV).
+func SaveOBJWithUV(path string, mesh []*sdf.Triangle3) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteOBJWithUV(f, mesh); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteOBJWithUV writes a triangle mesh as a Wavefront OBJ file with texture
+// coordinates to a writer.
+func WriteOBJWithUV(out io.Writer, mesh []*sdf.Triangle3) error {
+	m, err := NewMesh(mesh, 0)
+	if err != nil {
+		return err
+	}
+	// the vertices on chart seams have more than one texture coordinate
+	uvIndex := make(map[v2.Vec]int)
+	coords := UnwrapUV(m.Triangles(), uvPadding)
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "# sdfx: %d vertices, %d faces\n", len(m.Vertices), len(m.Faces))
+	for _, v := range m.Vertices {
+		fmt.Fprintf(w, "v %g %g %g\n", v.X, v.Y, v.Z)
+	}
+	for _, n := range m.Normals {
+		fmt.Fprintf(w, "vn %g %g %g\n", n.X, n.Y, n.Z)
+	}
+	faces := make([][3]int, len(m.Faces))
+	for i, t := range coords {
+		for j, c := range t {
+			k, ok := uvIndex[c]
+			if !ok {
+				k = len(uvIndex)
+				uvIndex[c] = k
+				fmt.Fprintf(w, "vt %g %g\n", c.X, c.Y)
+			}
+			faces[i][j] = k
+		}
+	}
+	for i, x := range m.Faces {
+		// OBJ indices start at 1
+		fmt.Fprintf(w, "f")
+		for j := range x {
+			fmt.Fprintf(w, " %d/%d/%d", x[j]+1, faces[i][j]+1, x[j]+1)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	return w.Flush()
+}
+
+//-----------------------------------------------------------------------------
+
+// objIndex returns the vertex index of an OBJ face vertex ("v", "v/vt", "v//vn" or "v/vt/vn").
+// Negative indices are relative to the end of the vertex list.
+func objIndex(s string, n int) (int, error) {
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		s = s[:i]
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil || i == 0 {
+		return 0, sdf.ErrMsg(fmt.Sprintf("bad face vertex \"%s\"", s))
+	}
+	if i < 0 {
+		i += n
+	} else {
+		i--
+	}
+	if i < 0 || i >= n {
+		return 0, sdf.ErrMsg(fmt.Sprintf("face vertex %s is out of range", s))
+	}
+	return i, nil
+}
+
+// LoadOBJ loads the faces of a Wavefront OBJ file as a triangle mesh.
+// Polygons are triangulated as fans, so they should be convex.
+func LoadOBJ(path string) ([]*sdf.Triangle3, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readOBJ(f, path)
+}
+
+// ReadOBJ reads the faces of a Wavefront OBJ file of a given size as a triangle mesh.
+func ReadOBJ(r io.ReaderAt, size int64) ([]*sdf.Triangle3, error) {
+	return readOBJ(io.NewSectionReader(r, 0, size), "obj")
+}
+
+// readOBJ reads the faces of a Wavefront OBJ file, the errors are prefixed with the file name.
+func readOBJ(f io.Reader, path string) ([]*sdf.Triangle3, error) {
+	var err error
+	var vertices []v3.Vec
+	var mesh []*sdf.Triangle3
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "v":
+			if len(fields) < 4 {
+				return nil, sdf.ErrMsg(fmt.Sprintf("%s:%d: bad vertex", path, n))
+			}
+			var p [3]float64
+			for i := range p {
+				if p[i], err = strconv.ParseFloat(fields[i+1], 64); err != nil {
+					return nil, sdf.ErrMsg(fmt.Sprintf("%s:%d: bad vertex", path, n))
+				}
+			}
+			vertices = append(vertices, v3.Vec{X: p[0], Y: p[1], Z: p[2]})
+		case "f":
+			if len(fields) < 4 {
+				return nil, sdf.ErrMsg(fmt.Sprintf("%s:%d: face has less than 3 vertices", path, n))
+			}
+			idx := make([]int, len(fields)-1)
+			for i, x := range fields[1:] {
+				if idx[i], err = objIndex(x, len(vertices)); err != nil {
+					return nil, sdf.ErrMsg(fmt.Sprintf("%s:%d: %s", path, n, err))
+				}
+			}
+			for i := 1; i < len(idx)-1; i++ {
+				mesh = append(mesh, &sdf.Triangle3{vertices[idx[0]], vertices[idx[i]], vertices[idx[i+1]]})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return mesh, nil
+}
+
+// LoadMesh loads a triangle mesh from an STL, 3MF, OBJ or STEP file, by file extension.
+func LoadMesh(path string) ([]*sdf.Triangle3, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".stl":
+		return LoadSTL(path)
+	case ".3mf":
+		return Load3MF(path)
+	case ".obj":
+		return LoadOBJ(path)
+	case ".step", ".stp":
+		return LoadSTEP(path)
+	}
+	return nil, sdf.ErrMsg(fmt.Sprintf("unknown mesh file type \"%s\"", path))
+}
+
+// ReadMesh reads a triangle mesh of a given size from an STL, 3MF, OBJ or
+// STEP file, by file extension (e.g. ".stl").
+func ReadMesh(r io.ReaderAt, size int64, ext string) ([]*sdf.Triangle3, error) {
+	switch strings.ToLower(ext) {
+	case ".stl":
+		return ReadSTL(r, size)
+	case ".3mf":
+		return Read3MF(r, size)
+	case ".obj":
+		return ReadOBJ(r, size)
+	case ".step", ".stp":
+		return ReadSTEP(r, size)
+	}
+	return nil, sdf.ErrMsg(fmt.Sprintf("unknown mesh file type \"%s\"", ext))
+}
+
+//-----------------------------------------------------------------------------
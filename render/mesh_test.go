@@ -0,0 +1,208 @@
+//-----------------------------------------------------------------------------
+/*
+
+Indexed Mesh Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	"github.com/deadsy/sdfx/units"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_NewMesh(t *testing.T) {
+	s, _ := sdf.Box3D(v3.Vec{10, 10, 10}, 0)
+	triangles := ToTriangles(s, NewMarchingCubesUniform(20))
+	m, err := NewMesh(triangles, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Faces) != len(triangles) {
+		t.Fatalf("expected %d faces, got %d", len(triangles), len(m.Faces))
+	}
+	// closed mesh: V - E + F = 2
+	edges := make(map[[2]int]bool)
+	for _, f := range m.Faces {
+		for i := range f {
+			a, b := f[i], f[(i+1)%3]
+			if a > b {
+				a, b = b, a
+			}
+			edges[[2]int{a, b}] = true
+		}
+	}
+	if chi := len(m.Vertices) - len(edges) + len(m.Faces); chi != 2 {
+		t.Errorf("euler characteristic %d", chi)
+	}
+	for i, tri := range m.Triangles() {
+		if *tri != *triangles[i] {
+			t.Fatalf("triangle %d changed", i)
+		}
+	}
+	for i, n := range m.Normals {
+		if n.Dot(m.Vertices[i]) <= 0 {
+			t.Fatalf("vertex normal %v points inwards", n)
+		}
+	}
+	if !m.BoundingBox().Equals(s.BoundingBox(), 1e-9) {
+		t.Errorf("bounding box %v", m.BoundingBox())
+	}
+}
+
+func Test_NewMesh_Tolerance(t *testing.T) {
+	// two triangles sharing an edge with slightly different vertices
+	triangles := []*sdf.Triangle3{
+		{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+		{{1, 1e-7, 0}, {1, 1, 0}, {0, 1 - 1e-7, 0}},
+		// collapses to a line when welded
+		{{0, 0, 0}, {1e-7, 0, 0}, {0, 0, 1}},
+	}
+	m, err := NewMesh(triangles, 1e-6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Vertices) != 5 || len(m.Faces) != 2 {
+		t.Errorf("expected 5 vertices and 2 faces, got %d and %d", len(m.Vertices), len(m.Faces))
+	}
+	m, _ = NewMesh(triangles, 0)
+	if len(m.Vertices) != 8 || len(m.Faces) != 3 {
+		t.Errorf("expected 8 vertices and 3 faces, got %d and %d", len(m.Vertices), len(m.Faces))
+	}
+	if _, err := NewMesh(triangles, -1); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func Test_SaveOBJ(t *testing.T) {
+	s, _ := sdf.Sphere3D(5)
+	m, _ := NewMesh(ToTriangles(s, NewMarchingCubesUniform(10)), 0)
+	path := filepath.Join(t.TempDir(), "sphere.obj")
+	if err := m.SaveOBJ(path); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	count := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		count[strings.Fields(scanner.Text())[0]]++
+	}
+	if count["v"] != len(m.Vertices) || count["vn"] != len(m.Normals) || count["f"] != len(m.Faces) {
+		t.Errorf("obj counts %v", count)
+	}
+	if err := m.SaveSTL(filepath.Join(t.TempDir(), "sphere.stl")); err != nil {
+		t.Error(err)
+	}
+	if err := m.Save3MF(filepath.Join(t.TempDir(), "sphere.3mf")); err != nil {
+		t.Error(err)
+	}
+}
+
+// meshVolume returns the signed volume of a closed mesh.
+func meshVolume(mesh []*sdf.Triangle3) float64 {
+	v := 0.0
+	for _, t := range mesh {
+		v += t[0].Dot(t[1].Cross(t[2]))
+	}
+	return v / 6
+}
+
+func Test_LoadMesh(t *testing.T) {
+	s, _ := sdf.Sphere3D(5)
+	mesh := ToTriangles(s, NewMarchingCubesUniform(10))
+	vol := meshVolume(mesh)
+	dir := t.TempDir()
+	for _, name := range []string{"a.stl", "a.3mf", "a.obj", "a.step", "inch.3mf", "inch.step"} {
+		path := filepath.Join(dir, name)
+		var err error
+		switch name {
+		case "a.stl":
+			err = SaveSTL(path, mesh)
+		case "a.3mf":
+			err = Save3MF(path, []Part3MF{{Name: "a", Mesh: mesh}})
+		case "a.obj":
+			err = SaveOBJ(path, mesh)
+		case "a.step":
+			err = SaveSTEP(path, mesh)
+		case "inch.3mf":
+			err = Save3MFWithOptions(path, []Part3MF{{Name: "a", Mesh: mesh}}, ExportOptions{Unit: units.Inch})
+		case "inch.step":
+			err = SaveSTEPWithOptions(path, mesh, STEPOptions{Unit: units.Inch})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := LoadMesh(path)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		// the volume is signed, so this also checks the winding
+		if v := meshVolume(m); math.Abs(v-vol) > 1e-3*vol {
+			t.Errorf("%s: expected volume %f, got %f", name, vol, v)
+		}
+	}
+	if _, err := LoadMesh(filepath.Join(dir, "a.xyz")); err == nil {
+		t.Error("expected an error for an unknown file type")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_RenderEmpty(t *testing.T) {
+	for _, r := range []Render3{NewMarchingCubesUniform(20), NewMarchingCubesOctree(20)} {
+		if mesh := ToTriangles(sdf.Empty3D(), r); len(mesh) != 0 {
+			t.Errorf("expected no triangles, got %d", len(mesh))
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_MeshOptions(t *testing.T) {
+	s, _ := sdf.Sphere3D(10)
+	for _, x := range []struct {
+		r0, r1, r2 Render3
+	}{
+		{NewMarchingCubesUniform(30), NewMarchingCubesUniformWithOptions(30, MeshOptions{}), NewMarchingCubesUniformWithOptions(30, MeshOptions{Tolerance: 0.2})},
+		{NewMarchingCubesOctree(30), NewMarchingCubesOctreeWithOptions(30, MeshOptions{}), NewMarchingCubesOctreeWithOptions(30, MeshOptions{Tolerance: 0.2})},
+	} {
+		// the zero options are the defaults
+		m0, m1 := ToTriangles(s, x.r0), ToTriangles(s, x.r1)
+		if len(m0) != len(m1) {
+			t.Fatalf("%d triangles with zero options, expected %d", len(m1), len(m0))
+		}
+		for i := range m0 {
+			if *m0[i] != *m1[i] {
+				t.Fatalf("triangle %d differs with zero options", i)
+			}
+		}
+		// small facets are dropped
+		m2 := ToTriangles(s, x.r2)
+		if len(m2) >= len(m0) {
+			t.Errorf("%d triangles with a facet tolerance, expected fewer than %d", len(m2), len(m0))
+		}
+		for _, tri := range m2 {
+			if tri.Degenerate(0.2) {
+				t.Fatal("small facet in the mesh")
+			}
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,147 @@
+//-----------------------------------------------------------------------------
+/*
+
+Octree Debug Export
+
+The octree renderer (march3x.go) only subdivides a cube if the distance at
+its center says the surface may pass through it. A thin feature that fits
+between the samples of a large cube is culled and never meshed.
+
+This records the cubes where the octree stopped subdividing: culled cubes
+at every depth and the surface cubes at full resolution. They are written
+to a glTF file as wireframe boxes colored by depth (blue is shallow, red is
+deep), one node per depth, along with the rendered mesh. A missed feature
+shows up as a shallow box around it, which tells you how far to raise the
+mesh resolution.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+	"github.com/deadsy/sdfx/vec/conv"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// OctreeCell is a cube where the octree renderer stopped subdividing.
+type OctreeCell struct {
+	Box     sdf.Box3 // cube bounds
+	Depth   int      // depth in the octree (0 == the top level cube)
+	Surface bool     // a full resolution cube that was meshed, else a culled cube
+}
+
+// OctreeDebug renders an SDF3 with the octree renderer (see NewMarchingCubesOctree)
+// and returns the mesh and the cubes where the octree stopped subdividing.
+func OctreeDebug(s sdf.SDF3, meshCells int) ([]*sdf.Triangle3, []OctreeCell) {
+	resolution := s.BoundingBox().Size().MaxComponent() / float64(meshCells)
+	if !(resolution > 0) {
+		return nil, nil
+	}
+	dc, top := newOctree(s, resolution)
+	var cells []OctreeCell
+	dc.visit = func(c *cube, empty bool) {
+		if !empty && c.n != 1 {
+			// subdivided
+			return
+		}
+		min := dc.origin.Add(conv.V3iToV3(c.v).MulScalar(dc.resolution))
+		size := float64(int(1)<<c.n) * dc.resolution
+		cells = append(cells, OctreeCell{
+			Box:     sdf.Box3{Min: min, Max: min.AddScalar(size)},
+			Depth:   int(top.n - c.n),
+			Surface: !empty,
+		})
+	}
+	output := &meshCollector{}
+	dc.processCube(top, output)
+	return output.mesh, cells
+}
+
+//-----------------------------------------------------------------------------
+
+// boxEdges returns the 12 edges of a box.
+func boxEdges(b sdf.Box3) [][2]v3.Vec {
+	// vertex i has x, y, z = bits 2, 1, 0 of i
+	v := b.Vertices()
+	return [][2]v3.Vec{
+		{v[0], v[1]}, {v[2], v[3]}, {v[4], v[5]}, {v[6], v[7]},
+		{v[0], v[2]}, {v[1], v[3]}, {v[4], v[6]}, {v[5], v[7]},
+		{v[0], v[4]}, {v[1], v[5]}, {v[2], v[6]}, {v[3], v[7]},
+	}
+}
+
+// octreeGLTF returns a glTF document for the mesh and the octree cells of an SDF3.
+func octreeGLTF(s sdf.SDF3, meshCells int) (*gltfDoc, error) {
+	mesh, cells := OctreeDebug(s, meshCells)
+	if len(cells) == 0 {
+		return nil, sdf.ErrMsg("nothing to write")
+	}
+	d := newGLTFDoc()
+	if len(mesh) != 0 {
+		part := GLTFPart{Name: "mesh", Color: DefaultMaterial().Color, Mesh: mesh}
+		m := d.addTriangles(part, d.addMaterial(part.Name, part.Color))
+		d.Scenes[0].Nodes = append(d.Scenes[0].Nodes, d.addNode(gltfNode{Name: part.Name, Mesh: &m}))
+	}
+	// group the cell edges by depth
+	maxDepth := 0
+	for _, c := range cells {
+		if c.Depth > maxDepth {
+			maxDepth = c.Depth
+		}
+	}
+	lines := make([][][2]v3.Vec, maxDepth+1)
+	count := make([]int, maxDepth+1)
+	for _, c := range cells {
+		lines[c.Depth] = append(lines[c.Depth], boxEdges(c.Box)...)
+		count[c.Depth]++
+	}
+	var children []int
+	for i := range lines {
+		if len(lines[i]) == 0 {
+			continue
+		}
+		var c color.RGBA
+		if maxDepth == 0 {
+			c = ColorRamp(1)
+		} else {
+			c = ColorRamp(float64(i) / float64(maxDepth))
+		}
+		name := fmt.Sprintf("depth %d (%d cells)", i, count[i])
+		m := d.addLines(name, lines[i], d.addMaterial(name, c))
+		children = append(children, d.addNode(gltfNode{Name: name, Mesh: &m}))
+	}
+	root := d.addNode(gltfNode{Name: "octree", Children: children})
+	d.Scenes[0].Nodes = append(d.Scenes[0].Nodes, root)
+	return d, nil
+}
+
+// SaveOctreeGLTF renders an SDF3 with the octree renderer and writes the
+// mesh and the octree cells as wireframe boxes colored by depth to a glTF
+// file. A ".glb" path is written as binary glTF, otherwise as JSON glTF.
+func SaveOctreeGLTF(path string, s sdf.SDF3, meshCells int) error {
+	d, err := octreeGLTF(s, meshCells)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := d.write(f, strings.EqualFold(filepath.Ext(path), ".glb")); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,82 @@
+//-----------------------------------------------------------------------------
+/*
+
+Octree Debug Export Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_OctreeDebug(t *testing.T) {
+	s, _ := sdf.Sphere3D(10)
+	mesh, cells := OctreeDebug(s, 30)
+	if n := len(ToTriangles(s, NewMarchingCubesOctree(30))); len(mesh) != n {
+		t.Errorf("expected %d triangles, got %d", n, len(mesh))
+	}
+	// the cells partition the top level cube
+	var volume, top float64
+	maxDepth := 0
+	for _, c := range cells {
+		size := c.Box.Size()
+		volume += size.X * size.Y * size.Z
+		if c.Depth == 0 {
+			t.Error("the top level cube is not empty")
+		}
+		if c.Depth > maxDepth {
+			maxDepth = c.Depth
+		}
+	}
+	for _, c := range cells {
+		if c.Surface && c.Depth != maxDepth {
+			t.Fatalf("surface cell at depth %d", c.Depth)
+		}
+		size := c.Box.Size().X * float64(int(1)<<uint(c.Depth))
+		top = size * size * size
+	}
+	if math.Abs(volume-top) > 1e-6*top {
+		t.Errorf("expected a cell volume of %g, got %g", top, volume)
+	}
+
+	path := filepath.Join(t.TempDir(), "octree.gltf")
+	if err := SaveOctreeGLTF(path, s, 30); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc gltfDoc
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		t.Fatal(err)
+	}
+	var depths int
+	for _, n := range doc.Nodes {
+		if strings.HasPrefix(n.Name, "depth ") {
+			depths++
+		}
+	}
+	if depths == 0 || doc.Nodes[0].Name != "mesh" {
+		t.Errorf("unexpected nodes %v", doc.Nodes)
+	}
+
+	if err := SaveOctreeGLTF(path, sdf.Transform3D(s, sdf.Scale3d(v3.Vec{})), 30); err == nil {
+		t.Error("expected an error for an empty bounding box")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,88 @@
+//-----------------------------------------------------------------------------
+/*
+
+PLY Output
+
+Write a triangle mesh to a binary PLY file: the welded vertices with their
+normals and the faces. With a color field the vertices also have a color
+and the field value (as a "value" property), so analysis results can be
+viewed in tools like MeshLab or CloudCompare.
+
+http://paulbourke.net/dataformats/ply/
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// WritePLY writes a triangle mesh as a binary PLY file to a writer. The
+// vertices are colored by a field if it is not nil.
+func WritePLY(out io.Writer, mesh []*sdf.Triangle3, cf *ColorField) error {
+	m, err := NewMesh(mesh, 0)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "ply\nformat binary_little_endian 1.0\ncomment sdfx\n")
+	fmt.Fprintf(w, "element vertex %d\n", len(m.Vertices))
+	fmt.Fprintf(w, "property float x\nproperty float y\nproperty float z\n")
+	fmt.Fprintf(w, "property float nx\nproperty float ny\nproperty float nz\n")
+	var values []float64
+	var colors []color.RGBA
+	if cf != nil {
+		var lo, hi float64
+		values, lo, hi = cf.Values(m.Vertices)
+		colors = cf.colors(values, lo, hi)
+		fmt.Fprintf(w, "property uchar red\nproperty uchar green\nproperty uchar blue\n")
+		fmt.Fprintf(w, "property float value\n")
+	}
+	fmt.Fprintf(w, "element face %d\n", len(m.Faces))
+	fmt.Fprintf(w, "property list uchar int vertex_indices\nend_header\n")
+
+	for i, v := range m.Vertices {
+		n := m.Normals[i]
+		binary.Write(w, binary.LittleEndian, [6]float32{
+			float32(v.X), float32(v.Y), float32(v.Z),
+			float32(n.X), float32(n.Y), float32(n.Z),
+		})
+		if cf != nil {
+			c := colors[i]
+			w.Write([]byte{c.R, c.G, c.B})
+			binary.Write(w, binary.LittleEndian, float32(values[i]))
+		}
+	}
+	for _, f := range m.Faces {
+		w.WriteByte(3)
+		binary.Write(w, binary.LittleEndian, [3]int32{int32(f[0]), int32(f[1]), int32(f[2])})
+	}
+	return w.Flush()
+}
+
+// SavePLY writes a triangle mesh to a binary PLY file. The vertices are
+// colored by a field if it is not nil.
+func SavePLY(path string, mesh []*sdf.Triangle3, cf *ColorField) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WritePLY(f, mesh, cf); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,252 @@
+//-----------------------------------------------------------------------------
+/*
+
+Raymarched Preview
+
+Render an SDF3 directly (no meshing) by sphere tracing a ray for each pixel.
+The surface is shaded with normals from the distance gradient, a diffuse
+key light, soft shadows and ambient occlusion.
+
+This is a quick visual check, not a photorealistic renderer. Distance bounds
+that overestimate the distance will show holes, reduce Lighting.StepScale
+for those.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// maxMarchSteps is the maximum number of sphere tracing steps per ray.
+const maxMarchSteps = 500
+
+// Camera defines the view of a preview. The zero value views the object from the front right.
+type Camera struct {
+	Position v3.Vec  // camera position (Position == Target: fit the object in the view)
+	Target   v3.Vec  // point at the center of the view
+	Up       v3.Vec  // up direction (0 = +z)
+	FOV      float64 // vertical field of view in degrees (0 = 30)
+	Width    int     // image width in pixels (0 = 800)
+	Height   int     // image height in pixels (0 = 600)
+}
+
+// Lighting defines the shading of a preview.
+type Lighting struct {
+	Direction  v3.Vec      // direction towards the key light (0 = over the left shoulder)
+	Color      color.Color // surface color (nil = light gray)
+	Background color.Color // background color (nil = dark gray)
+	Ambient    float64     // ambient light level (0 = 0.2)
+	Shadows    bool        // cast soft shadows
+	AO         bool        // ambient occlusion
+	StepScale  float64     // sphere tracing step scale (0 = 1)
+}
+
+// defaults fills in the defaults for a camera.
+func (c Camera) defaults(bb sdf.Box3) Camera {
+	if c.FOV == 0 {
+		c.FOV = 30
+	}
+	if c.Width == 0 {
+		c.Width = 800
+	}
+	if c.Height == 0 {
+		c.Height = 600
+	}
+	if c.Up.Equals(v3.Vec{}, 0) {
+		c.Up = v3.Vec{0, 0, 1}
+	}
+	if c.Position.Equals(c.Target, 0) {
+		// fit the bounding sphere in the view
+		c.Target = bb.Center()
+		r := 0.5 * bb.Size().Length()
+		fov := sdf.DtoR(c.FOV) * math.Min(1, float64(c.Width)/float64(c.Height))
+		d := 1.1 * r / math.Sin(0.5*fov)
+		c.Position = c.Target.Add(v3.Vec{1, -1.6, 1}.Normalize().MulScalar(d))
+	}
+	return c
+}
+
+// defaults fills in the defaults for lighting.
+func (l Lighting) defaults(c Camera) Lighting {
+	if l.Direction.Equals(v3.Vec{}, 0) {
+		// from behind and to the left of the camera
+		l.Direction = c.Position.Sub(c.Target).Normalize().Add(v3.Vec{-0.5, 0, 0.8})
+	}
+	l.Direction = l.Direction.Normalize()
+	if l.Color == nil {
+		l.Color = color.RGBA{200, 200, 200, 255}
+	}
+	if l.Background == nil {
+		l.Background = color.RGBA{48, 48, 48, 255}
+	}
+	if l.Ambient == 0 {
+		l.Ambient = 0.2
+	}
+	if l.StepScale == 0 {
+		l.StepScale = 1
+	}
+	return l
+}
+
+// marcher traces rays against an SDF3.
+type marcher struct {
+	s         sdf.SDF3
+	center    v3.Vec  // bounding sphere center
+	radius    float64 // bounding sphere radius
+	eps       float64 // surface hit distance
+	stepScale float64
+}
+
+// march returns the distance along a ray to the surface (or -1 for a miss).
+func (m *marcher) march(o, d v3.Vec) float64 {
+	// clip the ray to the bounding sphere
+	oc := o.Sub(m.center)
+	b := oc.Dot(d)
+	c := oc.Length2() - m.radius*m.radius
+	h := b*b - c
+	if h < 0 {
+		return -1
+	}
+	h = math.Sqrt(h)
+	t, tMax := math.Max(0, -b-h), -b+h
+	for i := 0; i < maxMarchSteps && t < tMax; i++ {
+		dist := m.s.Evaluate(o.Add(d.MulScalar(t)))
+		if dist < m.eps {
+			return t
+		}
+		t += dist * m.stepScale
+	}
+	return -1
+}
+
+// shadow returns the soft shadow factor (0..1) towards a light.
+func (m *marcher) shadow(p, l v3.Vec) float64 {
+	const k = 8.0 // shadow sharpness
+	res := 1.0
+	t := 10 * m.eps
+	for i := 0; i < maxMarchSteps/4 && t < 2*m.radius; i++ {
+		dist := m.s.Evaluate(p.Add(l.MulScalar(t)))
+		if dist < m.eps {
+			return 0
+		}
+		res = math.Min(res, k*dist/t)
+		t += dist * m.stepScale
+	}
+	return sdf.Clamp(res, 0, 1)
+}
+
+// occlusion returns the ambient occlusion factor (0..1) at a surface point.
+func (m *marcher) occlusion(p, n v3.Vec) float64 {
+	const samples = 5
+	occ, w := 0.0, 1.0
+	step := 0.02 * m.radius
+	for i := 1; i <= samples; i++ {
+		h := float64(i) * step
+		occ += w * (h - m.s.Evaluate(p.Add(n.MulScalar(h)))) / step
+		w *= 0.5
+	}
+	return sdf.Clamp(1-0.3*occ, 0, 1)
+}
+
+// Preview renders a shaded image of an SDF3 by raymarching.
+func Preview(s sdf.SDF3, camera Camera, lighting Lighting) image.Image {
+	bb := s.BoundingBox()
+	c := camera.defaults(bb)
+	l := lighting.defaults(c)
+	m := &marcher{
+		s:         s,
+		center:    bb.Center(),
+		radius:    0.5 * bb.Size().Length(),
+		stepScale: l.StepScale,
+	}
+	m.eps = 1e-4 * m.radius
+
+	// camera basis
+	fwd := c.Target.Sub(c.Position).Normalize()
+	right := fwd.Cross(c.Up).Normalize()
+	up := right.Cross(fwd)
+	scale := math.Tan(0.5*sdf.DtoR(c.FOV)) / (0.5 * float64(c.Height))
+
+	surface := rgba(l.Color)
+	background := rgba(l.Background)
+	img := image.NewRGBA(image.Rect(0, 0, c.Width, c.Height))
+
+	// render the rows in parallel
+	rows := make(chan int, c.Height)
+	for y := 0; y < c.Height; y++ {
+		rows <- y
+	}
+	close(rows)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for y := range rows {
+				for x := 0; x < c.Width; x++ {
+					u := (float64(x) + 0.5 - 0.5*float64(c.Width)) * scale
+					v := (0.5*float64(c.Height) - float64(y) - 0.5) * scale
+					d := fwd.Add(right.MulScalar(u)).Add(up.MulScalar(v)).Normalize()
+					col := background
+					if t := m.march(c.Position, d); t >= 0 {
+						p := c.Position.Add(d.MulScalar(t))
+						n := sdf.Normal3(s, p, m.eps)
+						// face the normal towards the camera
+						if n.Dot(d) > 0 {
+							n = n.Neg()
+						}
+						diffuse := math.Max(0, n.Dot(l.Direction))
+						if l.Shadows && diffuse > 0 {
+							diffuse *= m.shadow(p.Add(n.MulScalar(2*m.eps)), l.Direction)
+						}
+						ambient := l.Ambient
+						if l.AO {
+							ambient *= m.occlusion(p, n)
+						}
+						// a little fill light from the camera
+						fill := 0.15 * math.Max(0, -n.Dot(d))
+						k := math.Min(1, ambient+(1-l.Ambient)*diffuse+fill)
+						col = [4]float64{surface[0] * k, surface[1] * k, surface[2] * k, 1}
+					}
+					img.SetRGBA(x, y, color.RGBA{
+						uint8(255*col[0] + 0.5),
+						uint8(255*col[1] + 0.5),
+						uint8(255*col[2] + 0.5),
+						uint8(255*col[3] + 0.5),
+					})
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return img
+}
+
+// PreviewPNG renders a shaded image of an SDF3 to a PNG file.
+func PreviewPNG(s sdf.SDF3, path string, camera Camera, lighting Lighting) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, Preview(s, camera, lighting)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//-----------------------------------------------------------------------------
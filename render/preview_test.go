@@ -0,0 +1,35 @@
+//-----------------------------------------------------------------------------
+/*
+
+Raymarched Preview Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Preview(t *testing.T) {
+	s, err := sdf.Sphere3D(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bg := color.RGBA{0, 0, 255, 255}
+	img := Preview(s, Camera{Width: 80, Height: 60}, Lighting{Background: bg, Shadows: true, AO: true})
+	if c := img.At(0, 0); c != bg {
+		t.Errorf("corner: expected background, got %v", c)
+	}
+	if r, g, b, _ := img.At(40, 30).RGBA(); r == 0 || r != g || g != b {
+		t.Errorf("center: expected a lit gray surface, got %d %d %d", r, g, b)
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,175 @@
+//-----------------------------------------------------------------------------
+/*
+
+Progressive Rendering
+
+Render a coarse mesh first and then successively finer meshes, so a viewer
+can show the shape quickly and the detail over time. Each mesh is a complete
+mesh of the object at a resolution that doubles from one level to the next.
+
+The levels are the layers of a marching cubes octree. The cubes of a level
+that contain the surface are split into the cubes of the next level, empty
+cubes are dropped, and the distance evaluations are cached across levels.
+The number of surface cubes grows about 4x per level, so the coarser levels
+together cost about a third of the finest level.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// MeshUpdate is a complete mesh at one level of a progressive render.
+type MeshUpdate struct {
+	Level  int              // level number (0 is the coarsest)
+	Levels int              // total number of levels
+	Cells  int              // mesh cells on the longest axis
+	Mesh   []*sdf.Triangle3 // triangle mesh
+}
+
+// Final returns true if this is the final (finest) level of a progressive render.
+func (m *MeshUpdate) Final() bool {
+	return m.Level == m.Levels-1
+}
+
+// MarchingCubesProgressive renders using marching cubes at increasing resolutions.
+type MarchingCubesProgressive struct {
+	minCells int               // minimum mesh cells on the longest axis for the coarsest level
+	maxCells int               // mesh cells on the longest axis for the finest level
+	update   func(*MeshUpdate) // called with each level
+}
+
+// NewMarchingCubesProgressive returns a Render3 object that renders meshes with
+// maxCells on the longest axis, and with half as many cells for each coarser level
+// down to minCells. The update function (which may be nil) is called with the
+// mesh for each level, and the final level is written to the render output.
+func NewMarchingCubesProgressive(minCells, maxCells int, update func(m *MeshUpdate)) *MarchingCubesProgressive {
+	if minCells < 1 {
+		minCells = 1
+	}
+	if maxCells < minCells {
+		maxCells = minCells
+	}
+	return &MarchingCubesProgressive{
+		minCells: minCells,
+		maxCells: maxCells,
+		update:   update,
+	}
+}
+
+// levels returns the mesh cells for each level.
+func (r *MarchingCubesProgressive) levels() []int {
+	cells := []int{r.maxCells}
+	for n := (r.maxCells + 1) / 2; n >= r.minCells && n < cells[0]; n = (n + 1) / 2 {
+		cells = append([]int{n}, cells...)
+	}
+	return cells
+}
+
+// Info returns a string describing the rendered volume.
+func (r *MarchingCubesProgressive) Info(s sdf.SDF3) string {
+	return fmt.Sprintf("%d levels, %s", len(r.levels()), NewMarchingCubesOctree(r.maxCells).Info(s))
+}
+
+// Render produces a 3d triangle mesh over the bounding volume of an sdf3.
+func (r *MarchingCubesProgressive) Render(s sdf.SDF3, output sdf.Triangle3Writer) {
+	var mesh []*sdf.Triangle3
+	r.RenderLevels(s, func(m *MeshUpdate) {
+		if r.update != nil {
+			r.update(m)
+		}
+		mesh = m.Mesh
+	})
+	output.Write(mesh)
+	output.Close()
+}
+
+// RenderLevels renders the mesh for each level and calls fn with it, coarsest first.
+func (r *MarchingCubesProgressive) RenderLevels(s sdf.SDF3, fn func(m *MeshUpdate)) {
+	levels := r.levels()
+	resolution := s.BoundingBox().Size().MaxComponent() / float64(r.maxCells)
+	if !(resolution > 0) {
+		// an empty (or degenerate) bounding box has no surface
+		for i, cells := range levels {
+			fn(&MeshUpdate{Level: i, Levels: len(levels), Cells: cells})
+		}
+		return
+	}
+	dc, top := newOctree(s, resolution)
+	// the finest level has cubes of size 2 (n = 1), each coarser level doubles that
+	n := uint(len(levels))
+	if n > top.n {
+		n = top.n
+		levels = levels[len(levels)-int(n):]
+	}
+	cubes := dc.surfaceCubes([]*cube{top}, n)
+	for i, cells := range levels {
+		fn(&MeshUpdate{
+			Level:  i,
+			Levels: len(levels),
+			Cells:  cells,
+			Mesh:   dc.cubeTriangles(cubes),
+		})
+		if i < len(levels)-1 {
+			n--
+			cubes = dc.surfaceCubes(cubes, n)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// cubeOffsets returns the corner offsets of a cube of side s in marching cubes order.
+func cubeOffsets(s int) [8]v3i.Vec {
+	return [8]v3i.Vec{
+		{X: 0, Y: 0, Z: 0}, {X: s, Y: 0, Z: 0}, {X: s, Y: s, Z: 0}, {X: 0, Y: s, Z: 0},
+		{X: 0, Y: 0, Z: s}, {X: s, Y: 0, Z: s}, {X: s, Y: s, Z: s}, {X: 0, Y: s, Z: s},
+	}
+}
+
+// surfaceCubes splits a set of cubes down to level n and returns the cubes containing the surface.
+func (dc *dcache3) surfaceCubes(cubes []*cube, n uint) []*cube {
+	var out []*cube
+	var split func(c *cube)
+	split = func(c *cube) {
+		if dc.isEmpty(c) {
+			return
+		}
+		if c.n == n {
+			out = append(out, c)
+			return
+		}
+		for _, d := range cubeOffsets(1 << (c.n - 1)) {
+			split(&cube{c.v.Add(d), c.n - 1})
+		}
+	}
+	for _, c := range cubes {
+		split(c)
+	}
+	return out
+}
+
+// cubeTriangles returns the marching cubes triangles for a set of cubes.
+func (dc *dcache3) cubeTriangles(cubes []*cube) []*sdf.Triangle3 {
+	var mesh []*sdf.Triangle3
+	for _, c := range cubes {
+		var corners [8]v3.Vec
+		var values [8]float64
+		for i, d := range cubeOffsets(1 << c.n) {
+			corners[i], values[i] = dc.evaluate(c.v.Add(d))
+		}
+		mesh = append(mesh, mcToTriangles(corners, values, 0, &dc.opts)...)
+	}
+	return mesh
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,105 @@
+//-----------------------------------------------------------------------------
+/*
+
+Progressive Rendering Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"math"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// countSDF3 counts the evaluations of an SDF3.
+type countSDF3 struct {
+	sdf.SDF3
+	n int64
+}
+
+func (s *countSDF3) Evaluate(p v3.Vec) float64 {
+	atomic.AddInt64(&s.n, 1)
+	return s.SDF3.Evaluate(p)
+}
+
+func Test_Progressive(t *testing.T) {
+	box, _ := sdf.Box3D(v3.Vec{30, 20, 10}, 2)
+	hole, _ := sdf.Cylinder3D(12, 4, 0)
+	s := sdf.Difference3D(box, hole)
+
+	var updates []*MeshUpdate
+	c := &countSDF3{SDF3: s}
+	mesh := ToTriangles(c, NewMarchingCubesProgressive(10, 80, func(m *MeshUpdate) {
+		updates = append(updates, m)
+	}))
+	progressive := c.n
+
+	if len(updates) != 4 {
+		t.Fatalf("%d levels, expected 4", len(updates))
+	}
+	for i, m := range updates {
+		if m.Level != i || m.Levels != 4 || m.Cells != 80>>uint(3-i) || m.Final() != (i == 3) {
+			t.Errorf("bad level %d: %+v", i, m)
+		}
+		if r := Validate(m.Mesh); !r.Watertight() {
+			t.Errorf("level %d is not watertight", i)
+		}
+		if i > 0 && len(m.Mesh) <= len(updates[i-1].Mesh) {
+			t.Errorf("level %d has %d triangles, level %d has %d", i, len(m.Mesh), i-1, len(updates[i-1].Mesh))
+		}
+	}
+
+	// the final level is the octree mesh
+	c.n = 0
+	octree := ToTriangles(c, NewMarchingCubesOctree(80))
+	if len(mesh) != len(octree) || len(updates[3].Mesh) != len(octree) {
+		t.Fatalf("%d triangles, expected %d", len(mesh), len(octree))
+	}
+	if v0, v1 := meshVolume(mesh), meshVolume(octree); math.Abs(v0-v1) > 1e-9*v1 {
+		t.Errorf("volume %g, expected %g", v0, v1)
+	}
+
+	// the coarser levels refine the finest level, they don't re-render it
+	if float64(progressive) > 1.2*float64(c.n) {
+		t.Errorf("%d evaluations, the octree takes %d", progressive, c.n)
+	}
+
+	// the levels stop at the octree depth
+	updates = nil
+	ToTriangles(s, NewMarchingCubesProgressive(1, 8, func(m *MeshUpdate) {
+		updates = append(updates, m)
+	}))
+	if n := len(updates); n == 0 || updates[n-1].Cells != 8 || !updates[n-1].Final() {
+		t.Errorf("bad levels for 8 cells")
+	}
+
+	// no update function
+	if mesh := ToTriangles(s, NewMarchingCubesProgressive(10, 40, nil)); len(mesh) == 0 {
+		t.Error("no mesh")
+	}
+}
+
+func Test_ProgressiveViewer(t *testing.T) {
+	s, _ := sdf.Sphere3D(10)
+	levels := 0
+	v := NewViewer(NewMarchingCubesProgressive(10, 40, func(m *MeshUpdate) { levels++ }))
+	srv := httptest.NewServer(v)
+	defer srv.Close()
+	if err := v.Update(s); err != nil {
+		t.Fatal(err)
+	}
+	if levels != 3 || v.version != 3 {
+		t.Errorf("%d levels and %d mesh versions, expected 3", levels, v.version)
+	}
+}
+
+//-----------------------------------------------------------------------------
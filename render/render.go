@@ -10,9 +10,14 @@ package render
 
 import (
 	"fmt"
+	"image/color"
+	"io"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/deadsy/sdfx/sdf"
+	"github.com/deadsy/sdfx/units"
 )
 
 //-----------------------------------------------------------------------------
@@ -52,16 +57,75 @@ func ToTriangles(
 
 //-----------------------------------------------------------------------------
 
+// ExportOptions are the options for writing mesh files.
+// The zero value gives the default behavior.
+type ExportOptions struct {
+	Fingerprint bool // embed the sdfx version, commit and model hash in the file metadata
+	Guard       bool // fail on NaN/Inf distances instead of writing a broken mesh
+	SortFacets  bool // write the facets in a canonical order instead of the render order
+	// unit of the file coordinates, the model is in millimeters (default)
+	Unit units.Unit
+	// time of the file timestamps (default time.Now), set it for reproducible files
+	Clock func() time.Time
+}
+
+// scaleMesh returns a mesh scaled from model units (millimeters) to a file unit.
+func scaleMesh(mesh []*sdf.Triangle3, u units.Unit) []*sdf.Triangle3 {
+	if u.Scale() == 1 {
+		return mesh
+	}
+	k := 1 / u.Scale()
+	scaled := make([]*sdf.Triangle3, len(mesh))
+	for i, t := range mesh {
+		scaled[i] = &sdf.Triangle3{t[0].MulScalar(k), t[1].MulScalar(k), t[2].MulScalar(k)}
+	}
+	return scaled
+}
+
+// guard wraps a renderer with a Guard if the option is set.
+// The check function removes the output file of a failed render.
+func (opts ExportOptions) guard(r Render3) (Render3, func(path string)) {
+	if !opts.Guard {
+		return r, func(string) {}
+	}
+	g := NewGuard(r)
+	return g, func(path string) {
+		if err := g.Err(); err != nil {
+			fmt.Printf("%s: %s\n", path, err)
+			os.Remove(path)
+		}
+	}
+}
+
+// triangles renders an SDF3 to a triangle mesh, with a Guard if the option is set.
+func (opts ExportOptions) triangles(s sdf.SDF3, r Render3) ([]*sdf.Triangle3, error) {
+	if opts.Guard {
+		return ToTrianglesGuarded(s, r)
+	}
+	return ToTriangles(s, r), nil
+}
+
 // ToSTL renders an SDF3 to an STL file.
 func ToSTL(
 	s sdf.SDF3, // sdf3 to render
 	path string, // path to filename
 	r Render3, // rendering method
 ) {
+	ToSTLWithOptions(s, path, r, ExportOptions{})
+}
+
+// ToSTLWithOptions renders an SDF3 to an STL file with export options.
+func ToSTLWithOptions(
+	s sdf.SDF3, // sdf3 to render
+	path string, // path to filename
+	r Render3, // rendering method
+	opts ExportOptions, // export options
+) {
+	r, check := opts.guard(r)
 	fmt.Printf("rendering %s (%s)\n", path, r.Info(s))
 	// write the triangles to an STL file
 	var wg sync.WaitGroup
-	output, err := writeSTL(&wg, path)
+	output, err := writeSTL(&wg, path, opts)
 	if err != nil {
 		fmt.Printf("%s", err)
 		return
@@ -72,6 +136,21 @@ func ToSTL(
 	close(output)
 	// wait for the file write to complete
 	wg.Wait()
+	check(path)
+}
+
+// ToSTLWriter renders an SDF3 to a binary STL written to a writer.
+func ToSTLWriter(
+	s sdf.SDF3, // sdf3 to render
+	w io.Writer, // output
+	r Render3, // rendering method
+	opts ExportOptions, // export options
+) error {
+	mesh, err := opts.triangles(s, r)
+	if err != nil {
+		return err
+	}
+	return WriteSTL(w, mesh, opts)
 }
 
 //-----------------------------------------------------------------------------
@@ -82,10 +161,21 @@ func To3MF(
 	path string, // path to filename
 	r Render3, // rendering method
 ) {
+	To3MFWithOptions(s, path, r, ExportOptions{})
+}
+
+// To3MFWithOptions renders an SDF3 to a 3MF file with export options.
+func To3MFWithOptions(
+	s sdf.SDF3, // sdf3 to render
+	path string, // path to filename
+	r Render3, // rendering method
+	opts ExportOptions, // export options
+) {
+	r, check := opts.guard(r)
 	fmt.Printf("rendering %s (%s)\n", path, r.Info(s))
 	// write the triangles to a 3MF file
 	var wg sync.WaitGroup
-	output, err := write3MF(&wg, path)
+	output, err := write3MF(&wg, path, opts)
 	if err != nil {
 		fmt.Printf("%s", err)
 		return
@@ -96,6 +186,21 @@ func To3MF(
 	close(output)
 	// wait for the file write to complete
 	wg.Wait()
+	check(path)
+}
+
+// To3MFWriter renders an SDF3 to a 3MF file written to a writer.
+func To3MFWriter(
+	s sdf.SDF3, // sdf3 to render
+	w io.Writer, // output
+	r Render3, // rendering method
+	opts ExportOptions, // export options
+) error {
+	mesh, err := opts.triangles(s, r)
+	if err != nil {
+		return err
+	}
+	return Write3MF(w, []Part3MF{{Name: "sdfx", Color: color.RGBA{128, 128, 128, 255}, Mesh: mesh}}, opts)
 }
 
 //-----------------------------------------------------------------------------
@@ -122,6 +227,17 @@ func ToDXF(
 	wg.Wait()
 }
 
+// ToDXFWriter renders an SDF2 to a DXF file written to a writer.
+func ToDXFWriter(
+	s sdf.SDF2, // sdf2 to render
+	w io.Writer, // output
+	r Render2, // rendering method
+) error {
+	c := &lineCollector{}
+	r.Render(s, c)
+	return WriteDXF(w, c.lines)
+}
+
 //-----------------------------------------------------------------------------
 
 const svgLineStyle = "fill:none;stroke:black;stroke-width:0.1"
@@ -147,4 +263,15 @@ func ToSVG(
 	wg.Wait()
 }
 
+// ToSVGWriter renders an SDF2 to an SVG file written to a writer.
+func ToSVGWriter(
+	s sdf.SDF2, // sdf2 to render
+	w io.Writer, // output
+	r Render2, // rendering method
+) error {
+	c := &lineCollector{}
+	r.Render(s, c)
+	return WriteSVG(w, svgLineStyle, c.lines)
+}
+
 //-----------------------------------------------------------------------------
@@ -0,0 +1,159 @@
+//-----------------------------------------------------------------------------
+/*
+
+Reproducible Files
+
+The writers produce the same file for the same mesh and options, so that
+generated files can be checked in and diffed. The things that could vary
+are:
+
+- the facet order: the writers keep the order of the renderer. The built-in
+  renderers are deterministic, but a parallel renderer may not be. With the
+  SortFacets option the facets are written in a canonical order.
+
+  The vertices themselves don't vary: each distance is evaluated on a single
+  goroutine and the union of SDFs is a minimum, which is exact in any order
+  (BVH or not). Blended unions (SetMin) always combine their children in the
+  given order. So there is no floating point reduction whose order could
+  change the mesh between parallel renders.
+
+- timestamps: the STEP header and the 3MF archive entries have a time. The
+  Clock option sets it, e.g. to a fixed time or the commit time.
+
+- 3MF archives: the content types are written in map order by the 3MF
+  package, so the archive is rewritten with the entries in a fixed order.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// now returns the time for file timestamps.
+func now(clock func() time.Time) time.Time {
+	if clock == nil {
+		return time.Now()
+	}
+	return clock()
+}
+
+// vecLess orders vectors by x, then y, then z.
+func vecLess(a, b v3.Vec) bool {
+	if a.X != b.X {
+		return a.X < b.X
+	}
+	if a.Y != b.Y {
+		return a.Y < b.Y
+	}
+	return a.Z < b.Z
+}
+
+// sortFacets returns the triangles of a mesh in a canonical order. Each
+// triangle starts at its least vertex (keeping the winding) and the
+// triangles are sorted by their vertices.
+func sortFacets(mesh []*sdf.Triangle3) []*sdf.Triangle3 {
+	out := make([]*sdf.Triangle3, len(mesh))
+	for i, t := range mesh {
+		k := 0
+		for j := 1; j < 3; j++ {
+			if vecLess(t[j], t[k]) {
+				k = j
+			}
+		}
+		out[i] = &sdf.Triangle3{t[k], t[(k+1)%3], t[(k+2)%3]}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		for k := range a {
+			if a[k] != b[k] {
+				return vecLess(a[k], b[k])
+			}
+		}
+		return false
+	})
+	return out
+}
+
+// facets returns a mesh in canonical order if the SortFacets option is set.
+func (opts ExportOptions) facets(mesh []*sdf.Triangle3) []*sdf.Triangle3 {
+	if opts.SortFacets {
+		return sortFacets(mesh)
+	}
+	return mesh
+}
+
+//-----------------------------------------------------------------------------
+
+// contentTypeRe matches the entries of an OPC content types part.
+var contentTypeRe = regexp.MustCompile(`<(Default|Override) [^>]*>`)
+
+// sortContentTypes sorts the entries of an OPC content types part.
+func sortContentTypes(data []byte) []byte {
+	idx := contentTypeRe.FindAllIndex(data, -1)
+	if len(idx) < 2 {
+		return data
+	}
+	entries := make([]string, len(idx))
+	for i, x := range idx {
+		entries[i] = string(data[x[0]:x[1]])
+	}
+	sort.Strings(entries)
+	var out bytes.Buffer
+	out.Write(data[:idx[0][0]])
+	for i, e := range entries {
+		out.WriteString(e)
+		if i < len(idx)-1 {
+			// keep anything between the entries
+			out.Write(data[idx[i][1]:idx[i+1][0]])
+		}
+	}
+	out.Write(data[idx[len(idx)-1][1]:])
+	return out.Bytes()
+}
+
+// reproducible3MF rewrites a 3MF archive with the entry times set to a time
+// and the content types sorted.
+func reproducible3MF(w io.Writer, data []byte, t time.Time) error {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if f.Name == "[Content_Types].xml" {
+			content = sortContentTypes(content)
+		}
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name, Method: f.Method, Modified: t})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+//-----------------------------------------------------------------------------
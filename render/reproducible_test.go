@@ -0,0 +1,111 @@
+//-----------------------------------------------------------------------------
+/*
+
+Reproducible File Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// shuffleMesh returns a mesh with the triangles and their vertices in a random order.
+func shuffleMesh(mesh []*sdf.Triangle3, rng *rand.Rand) []*sdf.Triangle3 {
+	out := make([]*sdf.Triangle3, len(mesh))
+	for i, j := range rng.Perm(len(mesh)) {
+		t := mesh[j]
+		k := rng.Intn(3)
+		out[i] = &sdf.Triangle3{t[k], t[(k+1)%3], t[(k+2)%3]}
+	}
+	return out
+}
+
+func Test_Reproducible(t *testing.T) {
+	s, _ := sdf.Box3D(v3.Vec{X: 10, Y: 20, Z: 30}, 2)
+	mesh := ToTriangles(s, NewMarchingCubesOctree(20))
+	shuffled := shuffleMesh(mesh, rand.New(rand.NewSource(1)))
+	clock := func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC) }
+
+	tests := []struct {
+		name  string
+		write func(w *bytes.Buffer, mesh []*sdf.Triangle3) error
+	}{
+		{"stl", func(w *bytes.Buffer, mesh []*sdf.Triangle3) error {
+			return WriteSTL(w, mesh, ExportOptions{SortFacets: true})
+		}},
+		{"stl ascii", func(w *bytes.Buffer, mesh []*sdf.Triangle3) error {
+			return WriteSTLASCII(w, mesh, ExportOptions{SortFacets: true})
+		}},
+		{"3mf", func(w *bytes.Buffer, mesh []*sdf.Triangle3) error {
+			return Write3MF(w, []Part3MF{{Name: "box", Mesh: mesh}}, ExportOptions{SortFacets: true, Clock: clock})
+		}},
+		{"step", func(w *bytes.Buffer, mesh []*sdf.Triangle3) error {
+			return WriteSTEP(w, mesh, STEPOptions{SortFacets: true, Clock: clock})
+		}},
+	}
+	for _, test := range tests {
+		var a, b bytes.Buffer
+		if err := test.write(&a, mesh); err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		// the same mesh with the facets in another order
+		if err := test.write(&b, shuffled); err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		if !bytes.Equal(a.Bytes(), b.Bytes()) {
+			t.Errorf("%s: files are not identical", test.name)
+		}
+	}
+
+	// the STEP header has the clock time
+	var buf bytes.Buffer
+	if err := WriteSTEP(&buf, mesh, STEPOptions{Clock: clock}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("2024-05-06T07:08:09")) {
+		t.Error("STEP header does not have the clock time")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_ReproducibleUnion(t *testing.T) {
+	// large unions (with a BVH) and blended unions rendered in parallel
+	ball, _ := sdf.Sphere3D(3)
+	rng := rand.New(rand.NewSource(1))
+	var balls []sdf.SDF3
+	for i := 0; i < 200; i++ {
+		v := v3.Vec{X: rng.Float64() * 50, Y: rng.Float64() * 50, Z: rng.Float64() * 50}
+		balls = append(balls, sdf.Transform3D(ball, sdf.Translate3d(v)))
+	}
+	blended := sdf.Union3D(balls[:20]...)
+	blended.(*sdf.UnionSDF3).SetMin(sdf.PolyMin(2))
+
+	for _, s := range []sdf.SDF3{sdf.Union3D(balls...), blended} {
+		for _, r := range []Render3{NewMarchingCubesUniform(50), NewMarchingCubesOctree(50)} {
+			var a, b bytes.Buffer
+			if err := WriteSTL(&a, ToTriangles(s, r), ExportOptions{SortFacets: true}); err != nil {
+				t.Fatal(err)
+			}
+			if err := WriteSTL(&b, ToTriangles(s, r), ExportOptions{SortFacets: true}); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(a.Bytes(), b.Bytes()) {
+				t.Errorf("%s: renders are not identical", r.Info(s))
+			}
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,297 @@
+//-----------------------------------------------------------------------------
+/*
+
+Hatched Cross Sections
+
+Cut an SDF3 or a triangle mesh with a plane and draw the cross section with
+hatched solid regions, as used for section views in documentation and
+patent-style figures.
+
+SDF3s are cut with Slice2D and contoured with marching squares. Meshes are
+cut exactly: each triangle that crosses the plane gives a segment of the
+section outline. The plane has the same 2D axes as Slice2D, so the two
+produce the same drawing for the same part.
+
+The hatch lines are clipped to the solid regions with the even-odd rule, so
+holes in the section are left open.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	svg "github.com/ajstarks/svgo/float"
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/yofu/dxf"
+	"github.com/yofu/dxf/color"
+)
+
+//-----------------------------------------------------------------------------
+
+// SectionOptions configures the hatching of a cross section.
+type SectionOptions struct {
+	Spacing float64 // hatch line spacing (0 = 2% of the section size)
+	Angle   float64 // hatch line angle from the x-axis in degrees (0 = 45)
+}
+
+// Section is a hatched cross section.
+type Section struct {
+	Contours []Contour    // section outline
+	Hatch    []*sdf.Line2 // hatch lines within the solid regions
+	bb       sdf.Box2     // bounding box of the outline
+}
+
+//-----------------------------------------------------------------------------
+
+// planeAxes returns the 2D axes of a plane with normal n, as used by sdf.Slice2D.
+func planeAxes(n v3.Vec) (v3.Vec, v3.Vec) {
+	var u v3.Vec
+	if n.X == 0 {
+		u = v3.Vec{1, 0, 0}
+	} else if n.Y == 0 {
+		u = v3.Vec{0, 1, 0}
+	} else if n.Z == 0 {
+		u = v3.Vec{0, 0, 1}
+	} else {
+		u = v3.Vec{n.Y, -n.X, 0}
+	}
+	v := n.Cross(u)
+	return u.Normalize(), v.Normalize()
+}
+
+// CrossSection cuts an SDF3 with the plane through a with normal n. The section
+// is rendered with the given number of marching squares cells on its longest axis.
+func CrossSection(s sdf.SDF3, a, n v3.Vec, cells int, opts *SectionOptions) (*Section, error) {
+	if s == nil {
+		return nil, sdf.ErrMsg("s == nil")
+	}
+	if n.Length() == 0 {
+		return nil, sdf.ErrMsg("n == 0")
+	}
+	if cells <= 0 {
+		return nil, sdf.ErrMsg("cells <= 0")
+	}
+	s2 := sdf.Slice2D(s, a, n)
+	resolution := s2.BoundingBox().Size().MaxComponent() / float64(cells)
+	return newSection(Contours(s2, resolution), opts)
+}
+
+// MeshCrossSection cuts a closed triangle mesh with the plane through a with normal n.
+func MeshCrossSection(mesh []*sdf.Triangle3, a, n v3.Vec, opts *SectionOptions) (*Section, error) {
+	if len(mesh) == 0 {
+		return nil, sdf.ErrMsg("no triangles")
+	}
+	if n.Length() == 0 {
+		return nil, sdf.ErrMsg("n == 0")
+	}
+	n = n.Normalize()
+	u, v := planeAxes(n)
+	project := func(p v3.Vec) v2.Vec {
+		d := p.Sub(a)
+		return v2.Vec{d.Dot(u), d.Dot(v)}
+	}
+	var lines []*sdf.Line2
+	for _, t := range mesh {
+		var d [3]float64
+		for i := range t {
+			d[i] = t[i].Sub(a).Dot(n)
+		}
+		// the edge crossings of the plane, vertices on the plane count as above
+		var pts []v2.Vec
+		for i := range t {
+			j := (i + 1) % 3
+			if (d[i] < 0) != (d[j] < 0) {
+				k := d[i] / (d[i] - d[j])
+				pts = append(pts, project(t[i].Add(t[j].Sub(t[i]).MulScalar(k))))
+			}
+		}
+		if len(pts) == 2 && pts[0] != pts[1] {
+			lines = append(lines, &sdf.Line2{pts[0], pts[1]})
+		}
+	}
+	bb := mesh[0].BoundingBox()
+	for _, t := range mesh[1:] {
+		bb = bb.Extend(t.BoundingBox())
+	}
+	contours := joinLines(lines, 1e-9*bb.Size().MaxComponent())
+	orientNested(contours)
+	return newSection(contours, opts)
+}
+
+// orientNested winds contours anticlockwise around the material by their nesting depth.
+func orientNested(contours []Contour) {
+	for i, c := range contours {
+		depth := 0
+		for j, x := range contours {
+			if j != i && x.inside(c[0]) {
+				depth++
+			}
+		}
+		if (c.Area() < 0) == (depth%2 == 0) {
+			for j, k := 0, len(c)-1; j < k; j, k = j+1, k-1 {
+				c[j], c[k] = c[k], c[j]
+			}
+		}
+	}
+}
+
+// inside returns true if a point is inside a contour (even-odd rule).
+func (c Contour) inside(p v2.Vec) bool {
+	in := false
+	for i := range c {
+		a, b := c[i], c[(i+1)%len(c)]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < a.X+(p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+			in = !in
+		}
+	}
+	return in
+}
+
+//-----------------------------------------------------------------------------
+
+// newSection hatches the solid regions of a set of contours.
+func newSection(contours []Contour, opts *SectionOptions) (*Section, error) {
+	if len(contours) == 0 {
+		return nil, sdf.ErrMsg("the plane does not cut the part")
+	}
+	s := &Section{Contours: contours}
+	s.bb = sdf.Box2{Min: contours[0][0], Max: contours[0][0]}
+	for _, c := range contours {
+		for _, p := range c {
+			s.bb = s.bb.Include(p)
+		}
+	}
+	var k SectionOptions
+	if opts != nil {
+		k = *opts
+	}
+	if k.Spacing < 0 {
+		return nil, sdf.ErrMsg("Spacing < 0")
+	}
+	if k.Spacing == 0 {
+		k.Spacing = 0.02 * s.bb.Size().MaxComponent()
+	}
+	if k.Angle == 0 {
+		k.Angle = 45
+	}
+	s.hatch(k.Spacing, sdf.DtoR(k.Angle))
+	return s, nil
+}
+
+// hatch adds hatch lines at an angle to the solid regions.
+func (s *Section) hatch(spacing, angle float64) {
+	// work in a frame with the hatch lines along the x-axis
+	cos, sin := math.Cos(angle), math.Sin(angle)
+	toHatch := func(p v2.Vec) v2.Vec { return v2.Vec{p.X*cos + p.Y*sin, -p.X*sin + p.Y*cos} }
+	fromHatch := func(p v2.Vec) v2.Vec { return v2.Vec{p.X*cos - p.Y*sin, p.X*sin + p.Y*cos} }
+	var edges [][2]v2.Vec
+	lo, hi := math.MaxFloat64, -math.MaxFloat64
+	for _, c := range s.Contours {
+		for i := range c {
+			a, b := toHatch(c[i]), toHatch(c[(i+1)%len(c)])
+			edges = append(edges, [2]v2.Vec{a, b})
+			lo = math.Min(lo, a.Y)
+			hi = math.Max(hi, a.Y)
+		}
+	}
+	// hatch lines at multiples of the spacing, so they line up across sections
+	var xs []float64
+	for i := math.Floor(lo/spacing) + 1; i*spacing < hi; i++ {
+		y := i * spacing
+		xs = xs[:0]
+		for _, e := range edges {
+			a, b := e[0], e[1]
+			if (a.Y > y) != (b.Y > y) {
+				xs = append(xs, a.X+(y-a.Y)*(b.X-a.X)/(b.Y-a.Y))
+			}
+		}
+		sort.Float64s(xs)
+		for j := 0; j+1 < len(xs); j += 2 {
+			s.Hatch = append(s.Hatch, &sdf.Line2{fromHatch(v2.Vec{xs[j], y}), fromHatch(v2.Vec{xs[j+1], y})})
+		}
+	}
+}
+
+// Lines returns the outline and hatch line segments of a section.
+func (s *Section) Lines() []*sdf.Line2 {
+	var lines []*sdf.Line2
+	for _, c := range s.Contours {
+		lines = append(lines, c.Lines()...)
+	}
+	return append(lines, s.Hatch...)
+}
+
+//-----------------------------------------------------------------------------
+
+// SaveSVG writes a section to an SVG file. The outline is drawn with the stroke
+// (default black) and the hatch lines with half the stroke width.
+func (s *Section) SaveSVG(path string, opts SVGOptions) error {
+	page, xf, err := svgLayout(s.bb, &opts)
+	if err != nil {
+		return err
+	}
+	fill := opts.Fill
+	if fill == "" {
+		fill = "none"
+	}
+	stroke := opts.Stroke
+	if stroke == "" {
+		stroke = "black"
+	}
+	strokeWidth := opts.StrokeWidth
+	if strokeWidth == 0 {
+		strokeWidth = 0.1
+	}
+	var sb strings.Builder
+	for _, l := range s.Hatch {
+		p0, p1 := xf(l[0]), xf(l[1])
+		fmt.Fprintf(&sb, "M%.4f %.4f L%.4f %.4f ", p0.X, p0.Y, p1.X, p1.Y)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	canvas := svg.New(f)
+	svgStart(canvas, page, &opts)
+	canvas.Path(svgPath(s.Contours, xf), fmt.Sprintf("fill:%s;fill-rule:evenodd;stroke:%s;stroke-width:%g", fill, stroke, strokeWidth))
+	if len(s.Hatch) != 0 {
+		canvas.Path(strings.TrimSpace(sb.String()), fmt.Sprintf("fill:none;stroke:%s;stroke-width:%g", stroke, 0.5*strokeWidth))
+	}
+	canvas.End()
+	return f.Close()
+}
+
+// SaveDXF writes a section to a DXF file with the outline and the hatch lines
+// on the "Section" and "Hatch" layers.
+func (s *Section) SaveDXF(path string) error {
+	d := &DXF{
+		name:    path,
+		drawing: dxf.NewDrawing(),
+	}
+	if _, err := d.drawing.AddLayer("Section", dxf.DefaultColor, dxf.DefaultLineType, true); err != nil {
+		return err
+	}
+	for _, c := range s.Contours {
+		for _, l := range c.Lines() {
+			d.drawing.Line(l[0].X, l[0].Y, 0, l[1].X, l[1].Y, 0)
+		}
+	}
+	if _, err := d.drawing.AddLayer("Hatch", color.Red, dxf.DefaultLineType, true); err != nil {
+		return err
+	}
+	for _, l := range s.Hatch {
+		d.drawing.Line(l[0].X, l[0].Y, 0, l[1].X, l[1].Y, 0)
+	}
+	return d.Save()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,76 @@
+//-----------------------------------------------------------------------------
+/*
+
+Hatched Cross Section Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Section(t *testing.T) {
+	box, _ := sdf.Box3D(v3.Vec{20, 20, 10}, 0)
+	hole, _ := sdf.Cylinder3D(20, 5, 0)
+	s := sdf.Difference3D(box, hole)
+	mesh := ToTriangles(s, NewMarchingCubesUniform(100))
+
+	sdfSection, err := CrossSection(s, v3.Vec{}, v3.Vec{0, 0, 1}, 200, &SectionOptions{Spacing: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meshSection, err := MeshCrossSection(mesh, v3.Vec{}, v3.Vec{0, 0, 1}, &SectionOptions{Spacing: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2 := sdf.Slice2D(s, v3.Vec{}, v3.Vec{0, 0, 1})
+	for _, x := range []*Section{sdfSection, meshSection} {
+		if len(x.Contours) != 2 {
+			t.Fatalf("expected 2 contours, got %d", len(x.Contours))
+		}
+		area := x.Contours[0].Area() + x.Contours[1].Area()
+		if math.Abs(area-(400-25*sdf.Pi)) > 2 {
+			t.Errorf("expected area %f, got %f", 400-25*sdf.Pi, area)
+		}
+		if len(x.Hatch) == 0 {
+			t.Fatal("no hatch lines")
+		}
+		// the hatch lines are in the material
+		for _, l := range x.Hatch {
+			mid := l[0].Add(l[1]).MulScalar(0.5)
+			if d := s2.Evaluate(mid); d > 0.1 {
+				t.Fatalf("hatch line %v is outside the material", l)
+			}
+		}
+	}
+
+	dir := t.TempDir()
+	if err := sdfSection.SaveSVG(filepath.Join(dir, "section.svg"), SVGOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := meshSection.SaveDXF(filepath.Join(dir, "section.dxf")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"section.svg", "section.dxf"} {
+		if fi, err := os.Stat(filepath.Join(dir, name)); err != nil || fi.Size() == 0 {
+			t.Errorf("%s was not written", name)
+		}
+	}
+
+	if _, err := CrossSection(s, v3.Vec{0, 0, 20}, v3.Vec{0, 0, 1}, 100, nil); err == nil {
+		t.Error("expected an error for a plane that misses the part")
+	}
+}
+
+//-----------------------------------------------------------------------------
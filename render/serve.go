@@ -0,0 +1,330 @@
+//-----------------------------------------------------------------------------
+/*
+
+Live Preview Server
+
+Serve a WebGL (three.js) viewer for an SDF3 over HTTP for an edit/preview
+loop. The model is meshed at a low resolution and sent to the browser as a
+binary STL. The browser listens for mesh updates on an event stream. With a
+progressive renderer each level is sent as it is done, so a coarse preview
+shows up quickly and is refined in place.
+Annotations (labels, leaders, dimensions) are sent as JSON and drawn over
+the mesh.
+
+Hot reload: re-run the Go program after an edit. The event stream of the
+open browser page reconnects to the new server and the new mesh is loaded,
+keeping the current view.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// DefaultPreviewCells is the default meshing resolution for the live preview.
+const DefaultPreviewCells = 100
+
+// Viewer is a live preview web server.
+type Viewer struct {
+	r       Render3
+	mu      sync.Mutex
+	mesh    []byte            // binary stl
+	notes   []byte            // annotations json
+	version int               // mesh version
+	clients map[chan int]bool // event stream clients
+}
+
+// NewViewer returns a live preview web server. The SDF3s are rendered with
+// r (nil = progressive marching cubes up to DefaultPreviewCells).
+func NewViewer(r Render3) *Viewer {
+	if r == nil {
+		r = NewMarchingCubesProgressive(DefaultPreviewCells/4, DefaultPreviewCells, nil)
+	}
+	return &Viewer{
+		r:       r,
+		clients: make(map[chan int]bool),
+	}
+}
+
+// Update meshes an SDF3 and sends it to the viewers.
+// A progressive renderer sends each level of the mesh.
+func (v *Viewer) Update(s sdf.SDF3) error {
+	log.Printf("meshing %s", v.r.Info(s))
+	if p, ok := v.r.(*MarchingCubesProgressive); ok {
+		var err error
+		p.RenderLevels(s, func(m *MeshUpdate) {
+			if p.update != nil {
+				p.update(m)
+			}
+			if err == nil {
+				err = v.UpdateMesh(m.Mesh)
+			}
+		})
+		return err
+	}
+	return v.UpdateMesh(ToTriangles(s, v.r))
+}
+
+// UpdateMesh sends a triangle mesh to the viewers.
+func (v *Viewer) UpdateMesh(mesh []*sdf.Triangle3) error {
+	var buf bytes.Buffer
+	if err := writeSTLBinary(&buf, mesh, ExportOptions{}); err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.mesh = buf.Bytes()
+	v.notify()
+	return nil
+}
+
+// Annotate sends a set of annotations to the viewers, replacing any previous annotations.
+func (v *Viewer) Annotate(notes []*Annotation) error {
+	js := make([]annotationJSON, len(notes))
+	for i, a := range notes {
+		js[i] = a.toJSON()
+	}
+	buf, err := json.Marshal(js)
+	if err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.notes = buf
+	v.notify()
+	return nil
+}
+
+// notify sends a new version to the viewers. The caller must hold the lock.
+func (v *Viewer) notify() {
+	v.version++
+	for c := range v.clients {
+		select {
+		case c <- v.version:
+		default:
+			// the client has an update pending
+		}
+	}
+}
+
+// events streams mesh updates to a browser.
+func (v *Viewer) events(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	c := make(chan int, 1)
+	v.mu.Lock()
+	v.clients[c] = true
+	// send the current mesh on (re)connection
+	if v.mesh != nil {
+		c <- v.version
+	}
+	v.mu.Unlock()
+	defer func() {
+		v.mu.Lock()
+		delete(v.clients, c)
+		v.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case version := <-c:
+			fmt.Fprintf(w, "event: mesh\ndata: %d\n\n", version)
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+// ServeHTTP serves the viewer page, the mesh and the event stream.
+func (v *Viewer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.URL.Path {
+	case "/":
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(viewerHTML))
+	case "/mesh.stl":
+		v.mu.Lock()
+		mesh := v.mesh
+		v.mu.Unlock()
+		if mesh == nil {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "model/stl")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(mesh)
+	case "/annotations.json":
+		v.mu.Lock()
+		notes := v.notes
+		v.mu.Unlock()
+		if notes == nil {
+			notes = []byte("[]")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(notes)
+	case "/events":
+		v.events(w, req)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// Serve meshes an SDF3 and serves a live preview on an address (e.g. "localhost:8080").
+// It blocks until the server fails.
+func Serve(addr string, s sdf.SDF3, r Render3) error {
+	v := NewViewer(r)
+	if err := v.Update(s); err != nil {
+		return err
+	}
+	log.Printf("preview at http://%s/", addr)
+	return http.ListenAndServe(addr, v)
+}
+
+//-----------------------------------------------------------------------------
+
+const viewerHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>sdfx preview</title>
+<style>
+body { margin: 0; overflow: hidden; background: #303030; font-family: sans-serif; }
+#status { position: absolute; top: 8px; left: 8px; color: #c0c0c0; font-size: 12px; }
+.note { color: #ffc800; font-size: 12px; background: rgba(0, 0, 0, 0.6); padding: 1px 4px; border-radius: 3px; }
+</style>
+<script type="importmap">
+{ "imports": {
+  "three": "https://unpkg.com/three@0.160.0/build/three.module.js",
+  "three/addons/": "https://unpkg.com/three@0.160.0/examples/jsm/"
+} }
+</script>
+</head>
+<body>
+<div id="status">connecting...</div>
+<script type="module">
+import * as THREE from 'three';
+import { OrbitControls } from 'three/addons/controls/OrbitControls.js';
+import { STLLoader } from 'three/addons/loaders/STLLoader.js';
+import { CSS2DRenderer, CSS2DObject } from 'three/addons/renderers/CSS2DRenderer.js';
+
+const status = document.getElementById('status');
+const renderer = new THREE.WebGLRenderer({ antialias: true });
+renderer.setPixelRatio(window.devicePixelRatio);
+renderer.setSize(window.innerWidth, window.innerHeight);
+document.body.appendChild(renderer.domElement);
+const labelRenderer = new CSS2DRenderer();
+labelRenderer.setSize(window.innerWidth, window.innerHeight);
+labelRenderer.domElement.style.position = 'absolute';
+labelRenderer.domElement.style.top = '0px';
+labelRenderer.domElement.style.pointerEvents = 'none';
+document.body.appendChild(labelRenderer.domElement);
+
+const scene = new THREE.Scene();
+scene.background = new THREE.Color(0x303030);
+scene.add(new THREE.HemisphereLight(0xffffff, 0x404040, 1.5));
+const light = new THREE.DirectionalLight(0xffffff, 1.5);
+scene.add(light);
+
+const camera = new THREE.PerspectiveCamera(30, window.innerWidth / window.innerHeight, 0.1, 1e6);
+camera.up.set(0, 0, 1);
+const controls = new OrbitControls(camera, renderer.domElement);
+const material = new THREE.MeshStandardMaterial({ color: 0xc8c8c8, flatShading: true, side: THREE.DoubleSide });
+let mesh = null;
+let notes = null;
+let fitted = false;
+
+function fit(geometry) {
+  geometry.computeBoundingSphere();
+  const s = geometry.boundingSphere;
+  const d = 1.1 * s.radius / Math.sin(THREE.MathUtils.degToRad(camera.fov / 2));
+  controls.target.copy(s.center);
+  camera.position.copy(s.center).add(new THREE.Vector3(1, -1.6, 1).normalize().multiplyScalar(d));
+  camera.near = d / 100;
+  camera.far = d * 100;
+  camera.updateProjectionMatrix();
+}
+
+function load(version) {
+  new STLLoader().load('mesh.stl?v=' + version, (geometry) => {
+    if (mesh) {
+      scene.remove(mesh);
+      mesh.geometry.dispose();
+    }
+    mesh = new THREE.Mesh(geometry, material);
+    scene.add(mesh);
+    if (!fitted) {
+      fit(geometry);
+      fitted = true;
+    }
+    status.textContent = 'mesh ' + version + ': ' + geometry.attributes.position.count / 3 + ' triangles';
+  });
+}
+
+function annotate(version) {
+  fetch('annotations.json?v=' + version).then((r) => r.json()).then((list) => {
+    if (notes) {
+      scene.remove(notes);
+      notes.traverse((o) => { if (o.geometry) o.geometry.dispose(); if (o.element) o.element.remove(); });
+    }
+    notes = new THREE.Group();
+    const lines = [];
+    for (const a of list) {
+      for (const l of a.lines || []) lines.push(...l);
+      const div = document.createElement('div');
+      div.className = 'note';
+      div.textContent = a.text;
+      const label = new CSS2DObject(div);
+      label.position.set(...a.at);
+      notes.add(label);
+    }
+    if (lines.length) {
+      const geometry = new THREE.BufferGeometry();
+      geometry.setAttribute('position', new THREE.Float32BufferAttribute(lines, 3));
+      notes.add(new THREE.LineSegments(geometry, new THREE.LineBasicMaterial({ color: 0xffc800, depthTest: false })));
+    }
+    scene.add(notes);
+  });
+}
+
+const events = new EventSource('events');
+events.addEventListener('mesh', (e) => { load(e.data); annotate(e.data); });
+events.onerror = () => { status.textContent = 'waiting for server...'; };
+
+window.addEventListener('resize', () => {
+  camera.aspect = window.innerWidth / window.innerHeight;
+  camera.updateProjectionMatrix();
+  renderer.setSize(window.innerWidth, window.innerHeight);
+  labelRenderer.setSize(window.innerWidth, window.innerHeight);
+});
+
+renderer.setAnimationLoop(() => {
+  controls.update();
+  light.position.copy(camera.position);
+  renderer.render(scene, camera);
+  labelRenderer.render(scene, camera);
+});
+</script>
+</body>
+</html>
+`
+
+//-----------------------------------------------------------------------------
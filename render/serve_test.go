@@ -0,0 +1,80 @@
+//-----------------------------------------------------------------------------
+/*
+
+Live Preview Server Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Viewer(t *testing.T) {
+	s, err := sdf.Sphere3D(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewViewer(NewMarchingCubesUniform(20))
+	if err := v.Update(s); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(v)
+	defer srv.Close()
+
+	// the mesh is a binary stl
+	resp, err := http.Get(srv.URL + "/mesh.stl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mesh, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if len(mesh) < 84 || (len(mesh)-84)%50 != 0 {
+		t.Fatalf("bad stl size %d", len(mesh))
+	}
+
+	// annotations are json
+	if err := v.Annotate([]*Annotation{NewLabel(v3.Vec{0, 0, 10}, "top")}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = http.Get(srv.URL + "/annotations.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notes []annotationJSON
+	err = json.NewDecoder(resp.Body).Decode(&notes)
+	resp.Body.Close()
+	if err != nil || len(notes) != 1 || notes[0].Text != "top" {
+		t.Fatalf("bad annotations %v %v", notes, err)
+	}
+
+	// a new client gets the current mesh version
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/events", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "event: mesh") {
+		t.Fatalf("bad event \"%s\" %v", line, err)
+	}
+}
+
+//-----------------------------------------------------------------------------
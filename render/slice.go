@@ -0,0 +1,320 @@
+//-----------------------------------------------------------------------------
+/*
+
+Slicing
+
+Cut an SDF3 into layers of closed 2D contours. Each layer is a plane at a
+constant z. The SDF3 is sliced with Slice2D, the slice is rendered with
+marching squares and the line segments are joined into closed contours.
+
+Contours are wound anticlockwise around material, so outer boundaries are
+anticlockwise (positive area) and holes are clockwise (negative area).
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// DefaultSliceCells is the default number of marching squares cells on the longest xy axis.
+const DefaultSliceCells = 400
+
+// Contour is a closed 2D polygon. The last vertex connects to the first vertex.
+type Contour []v2.Vec
+
+// Area returns the signed area of a contour (positive for anticlockwise).
+func (c Contour) Area() float64 {
+	a := 0.0
+	for i := range c {
+		p0 := c[i]
+		p1 := c[(i+1)%len(c)]
+		a += p0.X*p1.Y - p1.X*p0.Y
+	}
+	return 0.5 * a
+}
+
+// Lines returns the line segments of a contour.
+func (c Contour) Lines() []*sdf.Line2 {
+	lines := make([]*sdf.Line2, len(c))
+	for i := range c {
+		lines[i] = &sdf.Line2{c[i], c[(i+1)%len(c)]}
+	}
+	return lines
+}
+
+// Layer is a cross section of an SDF3 at a z height.
+type Layer struct {
+	Z        float64   // z height of the slicing plane
+	Contours []Contour // closed contours of the cross section
+}
+
+// Lines returns the line segments of all the contours in a layer.
+func (l *Layer) Lines() []*sdf.Line2 {
+	var lines []*sdf.Line2
+	for _, c := range l.Contours {
+		lines = append(lines, c.Lines()...)
+	}
+	return lines
+}
+
+// Area returns the material area of a layer.
+func (l *Layer) Area() float64 {
+	a := 0.0
+	for _, c := range l.Contours {
+		a += c.Area()
+	}
+	return a
+}
+
+//-----------------------------------------------------------------------------
+
+// lineCollector is a Line2Writer that collects the line segments.
+type lineCollector struct {
+	lines []*sdf.Line2
+}
+
+func (c *lineCollector) Write(in []*sdf.Line2) error {
+	c.lines = append(c.lines, in...)
+	return nil
+}
+
+func (c *lineCollector) Close() error {
+	return nil
+}
+
+// joinLines joins line segments into closed contours.
+// The marching squares segments are not consistently oriented, so segments
+// are joined at either end. Segment endpoints closer than tolerance are joined.
+func joinLines(lines []*sdf.Line2, tolerance float64) []Contour {
+	type key struct{ x, y int64 }
+	toKey := func(p v2.Vec) key {
+		return key{int64(math.Floor(p.X / tolerance)), int64(math.Floor(p.Y / tolerance))}
+	}
+	// index the segments by their end points
+	ends := make(map[key][]int, 2*len(lines))
+	for i, l := range lines {
+		for _, p := range l {
+			k := toKey(p)
+			ends[k] = append(ends[k], i)
+		}
+	}
+	used := make([]bool, len(lines))
+	// next returns an unused segment with an end point at p and its other end point.
+	// The neighbouring keys are searched so points either side of a key boundary are joined.
+	next := func(p v2.Vec) (int, v2.Vec) {
+		k := toKey(p)
+		for dx := int64(-1); dx <= 1; dx++ {
+			for dy := int64(-1); dy <= 1; dy++ {
+				for _, i := range ends[key{k.x + dx, k.y + dy}] {
+					if used[i] {
+						continue
+					}
+					if lines[i][0].Sub(p).Length() < tolerance {
+						return i, lines[i][1]
+					}
+					if lines[i][1].Sub(p).Length() < tolerance {
+						return i, lines[i][0]
+					}
+				}
+			}
+		}
+		return -1, v2.Vec{}
+	}
+	var contours []Contour
+	for i := range lines {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		first := lines[i][0]
+		c := Contour{first}
+		end := lines[i][1]
+		for end.Sub(first).Length() >= tolerance {
+			j, p := next(end)
+			if j < 0 {
+				// open contour (shouldn't happen)
+				break
+			}
+			used[j] = true
+			c = append(c, end)
+			end = p
+		}
+		if len(c) >= 3 {
+			contours = append(contours, c)
+		}
+	}
+	return contours
+}
+
+// orient winds a contour anticlockwise around the material of s.
+func (c Contour) orient(s sdf.SDF2, resolution float64) {
+	// test the material on the left of the longest segment
+	i, length := 0, 0.0
+	for j := range c {
+		l := c[(j+1)%len(c)].Sub(c[j]).Length()
+		if l > length {
+			i, length = j, l
+		}
+	}
+	if length == 0 {
+		return
+	}
+	p0, p1 := c[i], c[(i+1)%len(c)]
+	d := p1.Sub(p0).DivScalar(length)
+	left := p0.Add(p1).MulScalar(0.5).Add(v2.Vec{-d.Y, d.X}.MulScalar(0.25 * resolution))
+	if s.Evaluate(left) > 0 {
+		// reverse the contour
+		for j, k := 0, len(c)-1; j < k; j, k = j+1, k-1 {
+			c[j], c[k] = c[k], c[j]
+		}
+	}
+}
+
+// Contours returns the closed contours of an SDF2 rendered with marching squares
+// at the given resolution. The contours are wound anticlockwise around the material.
+func Contours(s sdf.SDF2, resolution float64) []Contour {
+	c := &lineCollector{}
+	marchingSquares(s, resolution, c)
+	contours := joinLines(c.lines, resolution*1e-3)
+	for _, c := range contours {
+		c.orient(s, resolution)
+	}
+	return contours
+}
+
+// sliceLayer returns the contours of an SDF3 cross section at height z.
+func sliceLayer(s sdf.SDF3, z, resolution float64) Layer {
+	s2 := sdf.Slice2D(s, v3.Vec{0, 0, z}, v3.Vec{0, 0, 1})
+	return Layer{
+		Z:        z,
+		Contours: Contours(s2, resolution),
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// Slice returns the cross sections of an SDF3 at z heights from zStart to zEnd
+// in steps of layerHeight. For printing, slice through the middle of each layer,
+// i.e. zStart is half a layer above the build plate.
+func Slice(s sdf.SDF3, zStart, zEnd, layerHeight float64) ([]Layer, error) {
+	return SliceCells(s, zStart, zEnd, layerHeight, DefaultSliceCells)
+}
+
+// SliceCells returns the cross sections of an SDF3 at z heights from zStart to zEnd
+// in steps of layerHeight. Each layer is rendered with the given number of marching
+// squares cells on the longest xy axis of the SDF3 bounding box.
+func SliceCells(s sdf.SDF3, zStart, zEnd, layerHeight float64, cells int) ([]Layer, error) {
+	if s == nil {
+		return nil, sdf.ErrMsg("s == nil")
+	}
+	if layerHeight <= 0 {
+		return nil, sdf.ErrMsg("layerHeight <= 0")
+	}
+	if zEnd < zStart {
+		return nil, sdf.ErrMsg("zEnd < zStart")
+	}
+	if cells <= 0 {
+		return nil, sdf.ErrMsg("cells <= 0")
+	}
+	size := s.BoundingBox().Size()
+	resolution := math.Max(size.X, size.Y) / float64(cells)
+
+	n := int(math.Floor((zEnd-zStart)/layerHeight+1e-9)) + 1
+	layers := make([]Layer, n)
+
+	// slice the layers in parallel
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				layers[i] = sliceLayer(s, zStart+float64(i)*layerHeight, resolution)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return layers, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// lessVec3 returns true if a is before b in x, y, z order.
+func lessVec3(a, b v3.Vec) bool {
+	if a.X != b.X {
+		return a.X < b.X
+	}
+	if a.Y != b.Y {
+		return a.Y < b.Y
+	}
+	return a.Z < b.Z
+}
+
+// SliceMesh returns the cross section of a closed triangle mesh at height z.
+// The contours are wound anticlockwise around the material, if the
+// triangles are wound anticlockwise when viewed from outside.
+func SliceMesh(mesh []*sdf.Triangle3, z float64) Layer {
+	var lines []*sdf.Line2
+	// crossing returns the intersection of an edge with the plane. The edge
+	// is ordered, so both triangles of an edge give the same point.
+	crossing := func(a, b v3.Vec) v2.Vec {
+		if lessVec3(b, a) {
+			a, b = b, a
+		}
+		p := a.Add(b.Sub(a).MulScalar((z - a.Z) / (b.Z - a.Z)))
+		return v2.Vec{X: p.X, Y: p.Y}
+	}
+	size := 0.0
+	for _, t := range mesh {
+		// vertices on the plane count as above it
+		var above [3]bool
+		n := 0
+		for i, p := range t {
+			if p.Z >= z {
+				above[i] = true
+				n++
+			}
+		}
+		if n == 0 || n == 3 {
+			continue
+		}
+		var pts []v2.Vec
+		for i := range t {
+			j := (i + 1) % 3
+			if above[i] != above[j] {
+				pts = append(pts, crossing(t[i], t[j]))
+			}
+		}
+		// material on the left: the direction is z cross the normal
+		nrm := t.Normal()
+		dir := v2.Vec{X: -nrm.Y, Y: nrm.X}
+		if pts[1].Sub(pts[0]).Dot(dir) < 0 {
+			pts[0], pts[1] = pts[1], pts[0]
+		}
+		lines = append(lines, &sdf.Line2{pts[0], pts[1]})
+		size = math.Max(size, t.BoundingBox().Size().MaxComponent())
+	}
+	return Layer{
+		Z:        z,
+		Contours: joinLines(lines, math.Max(size, 1)*1e-9),
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,88 @@
+//-----------------------------------------------------------------------------
+/*
+
+Slicing Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Slice(t *testing.T) {
+	box, err := sdf.Box3D(v3.Vec{20, 20, 10}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hole, err := sdf.Cylinder3D(20, 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := sdf.Difference3D(box, hole)
+
+	layers, err := Slice(s, -4, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 5 {
+		t.Fatalf("expected 5 layers, got %d", len(layers))
+	}
+	for _, l := range layers {
+		if len(l.Contours) != 2 {
+			t.Fatalf("z %f: expected 2 contours, got %d", l.Z, len(l.Contours))
+		}
+		// outer boundary anticlockwise, hole clockwise
+		outer, inner := l.Contours[0].Area(), l.Contours[1].Area()
+		if outer < inner {
+			outer, inner = inner, outer
+		}
+		if math.Abs(outer-400) > 1 || math.Abs(inner+25*sdf.Pi) > 1 {
+			t.Errorf("z %f: bad contour areas %f %f", l.Z, outer, inner)
+		}
+	}
+
+	// above the part
+	layers, err = Slice(s, 6, 6, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 1 || len(layers[0].Contours) != 0 {
+		t.Error("expected an empty layer")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_SliceMesh(t *testing.T) {
+	box, _ := sdf.Box3D(v3.Vec{20, 20, 10}, 0)
+	hole, _ := sdf.Cylinder3D(20, 5, 0)
+	s := sdf.Difference3D(box, hole)
+	mesh := ToTriangles(s, NewMarchingCubesOctree(100))
+
+	l := SliceMesh(mesh, 1.3)
+	if len(l.Contours) != 2 {
+		t.Fatalf("expected 2 contours, got %d", len(l.Contours))
+	}
+	// outer boundary anticlockwise, hole clockwise
+	outer, inner := l.Contours[0].Area(), l.Contours[1].Area()
+	if outer < inner {
+		outer, inner = inner, outer
+	}
+	if math.Abs(outer-400) > 2 || math.Abs(inner+25*sdf.Pi) > 2 {
+		t.Errorf("bad contour areas %f %f", outer, inner)
+	}
+	if l := SliceMesh(mesh, 6); len(l.Contours) != 0 {
+		t.Error("expected an empty layer")
+	}
+}
+
+//-----------------------------------------------------------------------------
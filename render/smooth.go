@@ -0,0 +1,171 @@
+//-----------------------------------------------------------------------------
+/*
+
+Taubin Mesh Smoothing
+
+Remove the staircase artifacts of marching cubes on gently curved surfaces
+without raising the resolution. Each iteration is a Laplacian step that
+moves each vertex towards the average of its neighbors by a factor lambda,
+followed by a step with a negative factor mu that moves it back out. The
+pair acts as a low pass filter that keeps the overall shape, where repeated
+Laplacian steps alone shrink the mesh (Taubin, "A Signal Processing Approach
+to Fair Surface Design").
+
+Sharp features are kept: an edge is a feature edge if the angle between its
+face normals is larger than the feature angle, and boundary edges are
+feature edges. Vertices on two feature edges (creases) are only smoothed
+along the crease, vertices on one or more than two (corners) don't move.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// SmoothParms defines the parameters for Taubin smoothing.
+type SmoothParms struct {
+	Iterations   int     // number of lambda/mu iterations (0 = 10)
+	Lambda       float64 // smoothing factor (0 = 0.5)
+	Mu           float64 // inflation factor, negative with |Mu| > Lambda (0 = -0.53)
+	FeatureAngle float64 // dihedral angle in degrees above which edges are kept sharp (0 = 40)
+}
+
+// defaults returns the parameters with the defaults filled in.
+func (k SmoothParms) defaults() (SmoothParms, error) {
+	if k.Iterations < 0 {
+		return k, sdf.ErrMsg("Iterations < 0")
+	}
+	if k.Iterations == 0 {
+		k.Iterations = 10
+	}
+	if k.Lambda == 0 {
+		k.Lambda = 0.5
+	}
+	if k.Mu == 0 {
+		k.Mu = -0.53
+	}
+	if k.FeatureAngle == 0 {
+		k.FeatureAngle = 40
+	}
+	if k.Lambda <= 0 || k.Lambda >= 1 {
+		return k, sdf.ErrMsg("Lambda must be in (0, 1)")
+	}
+	if k.Mu >= 0 || -k.Mu < k.Lambda {
+		return k, sdf.ErrMsg("Mu must be negative with |Mu| >= Lambda")
+	}
+	return k, nil
+}
+
+// smoothNeighbors returns the neighbors each vertex is smoothed towards.
+// Corner vertices have no neighbors.
+func smoothNeighbors(m *Mesh, featureAngle float64) [][]int {
+	t := newTopology(m)
+	normals := make([]v3.Vec, len(m.Faces))
+	for i := range m.Faces {
+		normals[i] = m.Triangle(i).Normal()
+	}
+	cosLimit := math.Cos(sdf.DtoR(featureAngle))
+	all := make([]map[int]bool, len(m.Vertices))
+	features := make([][]int, len(m.Vertices))
+	for i := range all {
+		all[i] = make(map[int]bool)
+	}
+	for k, faces := range t.edges {
+		a, b := k[0], k[1]
+		all[a][b] = true
+		all[b][a] = true
+		if len(faces) != 2 || normals[faces[0]].Dot(normals[faces[1]]) < cosLimit {
+			features[a] = append(features[a], b)
+			features[b] = append(features[b], a)
+		}
+	}
+	neighbors := make([][]int, len(m.Vertices))
+	for v := range m.Vertices {
+		switch len(features[v]) {
+		case 0:
+			for u := range all[v] {
+				neighbors[v] = append(neighbors[v], u)
+			}
+		case 2:
+			// a crease, smooth along it
+			neighbors[v] = features[v]
+		}
+	}
+	return neighbors
+}
+
+// Smooth returns a triangle mesh smoothed with Taubin lambda/mu smoothing.
+func Smooth(mesh []*sdf.Triangle3, k *SmoothParms) ([]*sdf.Triangle3, error) {
+	parms, err := k.defaults()
+	if err != nil {
+		return nil, err
+	}
+	if len(mesh) == 0 {
+		return nil, nil
+	}
+	m, _ := NewMesh(mesh, weldTolerance(mesh))
+	neighbors := smoothNeighbors(m, parms.FeatureAngle)
+	pos := m.Vertices
+	next := make([]v3.Vec, len(pos))
+	step := func(factor float64) {
+		for v, p := range pos {
+			next[v] = p
+			if len(neighbors[v]) == 0 {
+				continue
+			}
+			var c v3.Vec
+			for _, u := range neighbors[v] {
+				c = c.Add(pos[u])
+			}
+			c = c.DivScalar(float64(len(neighbors[v])))
+			next[v] = p.Add(c.Sub(p).MulScalar(factor))
+		}
+		pos, next = next, pos
+	}
+	for i := 0; i < parms.Iterations; i++ {
+		step(parms.Lambda)
+		step(parms.Mu)
+	}
+	m.Vertices = pos
+	return m.Triangles(), nil
+}
+
+//-----------------------------------------------------------------------------
+
+// SmoothRenderer renders with another renderer and smooths the mesh.
+type SmoothRenderer struct {
+	r Render3
+	k SmoothParms
+}
+
+// NewSmoothRenderer returns a Render3 object that smooths the mesh of another renderer.
+func NewSmoothRenderer(r Render3, k *SmoothParms) (*SmoothRenderer, error) {
+	parms, err := k.defaults()
+	if err != nil {
+		return nil, err
+	}
+	return &SmoothRenderer{r: r, k: parms}, nil
+}
+
+// Info returns a string describing the rendered volume.
+func (r *SmoothRenderer) Info(s sdf.SDF3) string {
+	return fmt.Sprintf("%s, %d smoothing iterations", r.r.Info(s), r.k.Iterations)
+}
+
+// Render produces a smoothed 3d triangle mesh over the bounding volume of an sdf3.
+func (r *SmoothRenderer) Render(s sdf.SDF3, output sdf.Triangle3Writer) {
+	mesh, _ := Smooth(ToTriangles(s, r.r), &r.k)
+	output.Write(mesh)
+	output.Close()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,98 @@
+//-----------------------------------------------------------------------------
+/*
+
+Taubin Mesh Smoothing Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// binarySDF3 has only the sign of an SDF3, which gives marching cubes staircases.
+type binarySDF3 struct {
+	sdf.SDF3
+	step float64
+}
+
+func (s *binarySDF3) Evaluate(p v3.Vec) float64 {
+	return math.Copysign(s.step, s.SDF3.Evaluate(p))
+}
+
+// normalError returns the area weighted RMS angle between the face normals and
+// the radial direction of a sphere.
+func normalError(mesh []*sdf.Triangle3) float64 {
+	var sum, area float64
+	for _, t := range mesh {
+		c := t[0].Add(t[1]).Add(t[2]).DivScalar(3)
+		n := t[1].Sub(t[0]).Cross(t[2].Sub(t[0]))
+		a := math.Acos(sdf.Clamp(n.Normalize().Dot(c.Normalize()), -1, 1))
+		sum += a * a * n.Length()
+		area += n.Length()
+	}
+	return math.Sqrt(sum / area)
+}
+
+// meanRadius returns the mean distance of the vertices from the origin.
+func meanRadius(mesh []*sdf.Triangle3) float64 {
+	var sum float64
+	for _, t := range mesh {
+		for _, p := range t {
+			sum += p.Length()
+		}
+	}
+	return sum / float64(3*len(mesh))
+}
+
+func Test_Smooth(t *testing.T) {
+	s, _ := sdf.Sphere3D(10)
+	mesh := ToTriangles(&binarySDF3{s, 0.1}, NewMarchingCubesUniform(24))
+	smooth, err := Smooth(mesh, &SmoothParms{FeatureAngle: 90, Iterations: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(smooth) != len(mesh) {
+		t.Fatalf("expected %d triangles, got %d", len(mesh), len(smooth))
+	}
+	e0, e1 := normalError(mesh), normalError(smooth)
+	if e1 > 0.7*e0 {
+		t.Errorf("normal error %f -> %f", e0, e1)
+	}
+	// no shrinkage
+	if r0, r1 := meanRadius(mesh), meanRadius(smooth); math.Abs(r1-r0) > 0.01*r0 {
+		t.Errorf("mean radius %f -> %f", r0, r1)
+	}
+	if r := Validate(smooth); !r.OK() {
+		t.Errorf("smoothed mesh: %s", r)
+	}
+}
+
+func Test_Smooth_Features(t *testing.T) {
+	s, _ := sdf.Box3D(v3.Vec{20, 10, 5}, 0)
+	r, err := NewSmoothRenderer(NewMarchingCubesUniform(40), &SmoothParms{Iterations: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mesh := ToTriangles(s, r)
+	bb := mesh[0].BoundingBox()
+	for _, t := range mesh[1:] {
+		bb = bb.Extend(t.BoundingBox())
+	}
+	if !bb.Equals(s.BoundingBox(), 0.3) {
+		t.Errorf("corners moved: bounding box %v", bb)
+	}
+	if _, err := NewSmoothRenderer(r, &SmoothParms{Lambda: 0.5, Mu: -0.1}); err == nil {
+		t.Error("expected an error for |Mu| < Lambda")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,187 @@
+//-----------------------------------------------------------------------------
+/*
+
+Snapshot Comparison
+
+Render a preview image of an SDF3 and compare it against a golden image, so
+tests can catch unintended geometry changes visually.
+
+Pixels are compared with a perceptual color difference: the distance in the
+YIQ color space with the weights used by pixelmatch, which follows the
+sensitivity of the eye to luminance and chrominance changes. A pixel is
+different if its difference exceeds the threshold, and the comparison fails
+if the fraction of different pixels exceeds the allowed fraction.
+
+A failed comparison writes a diff image next to the golden image with the
+different pixels in red over a faded copy of the golden image.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// maxYIQDelta is the YIQ difference between black and white.
+const maxYIQDelta = 35215.0
+
+// SnapshotParms defines the parameters of a snapshot comparison.
+type SnapshotParms struct {
+	Camera    Camera   // preview camera
+	Lighting  Lighting // preview lighting
+	Threshold float64  // per pixel perceptual difference (0..1) allowed (0 = 0.1)
+	MaxDiff   float64  // fraction of different pixels allowed (0 = 0.001)
+	Update    bool     // write the golden image instead of comparing
+}
+
+// ImageDiff is the result of an image comparison.
+type ImageDiff struct {
+	Pixels   int         // number of different pixels
+	Fraction float64     // fraction of different pixels
+	MaxDelta float64     // largest per pixel perceptual difference (0..1)
+	Image    *image.RGBA // different pixels in red over the faded reference image
+}
+
+func (d *ImageDiff) String() string {
+	return fmt.Sprintf("%d pixels (%.3f%%) differ, max difference %.3f", d.Pixels, 100*d.Fraction, d.MaxDelta)
+}
+
+//-----------------------------------------------------------------------------
+
+// yiq returns the YIQ components of a color blended over white.
+func yiq(c color.Color) (float64, float64, float64) {
+	k := rgba(c)
+	r := 255 + (k[0]*255-255)*k[3]
+	g := 255 + (k[1]*255-255)*k[3]
+	b := 255 + (k[2]*255-255)*k[3]
+	y := 0.29889531*r + 0.58662247*g + 0.11448223*b
+	i := 0.59597799*r - 0.27417610*g - 0.32180189*b
+	q := 0.21147017*r - 0.52261711*g + 0.31114694*b
+	return y, i, q
+}
+
+// colorDelta returns the perceptual difference (0..1) between two colors.
+func colorDelta(a, b color.Color) float64 {
+	y0, i0, q0 := yiq(a)
+	y1, i1, q1 := yiq(b)
+	dy, di, dq := y0-y1, i0-i1, q0-q1
+	return math.Sqrt((0.5053*dy*dy + 0.299*di*di + 0.1957*dq*dq) / maxYIQDelta)
+}
+
+// CompareImages compares an image against a reference image. Pixels with a
+// perceptual difference above the threshold (0..1) are counted as different.
+func CompareImages(img, ref image.Image, threshold float64) (*ImageDiff, error) {
+	b := ref.Bounds()
+	if img.Bounds().Size() != b.Size() {
+		return nil, sdf.ErrMsg(fmt.Sprintf("image size %v != reference size %v", img.Bounds().Size(), b.Size()))
+	}
+	if threshold < 0 || threshold > 1 {
+		return nil, sdf.ErrMsg("threshold must be 0..1")
+	}
+	off := img.Bounds().Min.Sub(b.Min)
+	d := &ImageDiff{Image: image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := ref.At(x, y)
+			delta := colorDelta(img.At(x+off.X, y+off.Y), c)
+			d.MaxDelta = math.Max(d.MaxDelta, delta)
+			var out color.RGBA
+			if delta > threshold {
+				d.Pixels++
+				out = color.RGBA{255, 0, 0, 255}
+			} else {
+				// faded gray
+				l, _, _ := yiq(c)
+				v := uint8(255 - 0.25*(255-l))
+				out = color.RGBA{v, v, v, 255}
+			}
+			d.Image.SetRGBA(x-b.Min.X, y-b.Min.Y, out)
+		}
+	}
+	if n := b.Dx() * b.Dy(); n != 0 {
+		d.Fraction = float64(d.Pixels) / float64(n)
+	}
+	return d, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// loadPNG reads a PNG file.
+func loadPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+// savePNG writes an image to a PNG file.
+func savePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// diffPath returns the diff image path for a golden image path.
+func diffPath(golden string) string {
+	return strings.TrimSuffix(golden, ".png") + ".diff.png"
+}
+
+// CompareSnapshot renders a preview of an SDF3 and compares it against a golden
+// PNG image. The golden image is written if it doesn't exist or k.Update is set.
+// An error is returned if the images differ by more than the allowed fraction
+// of pixels, and the diff image is written next to the golden image.
+func CompareSnapshot(s sdf.SDF3, golden string, k *SnapshotParms) (*ImageDiff, error) {
+	threshold := k.Threshold
+	if threshold == 0 {
+		threshold = 0.1
+	}
+	maxDiff := k.MaxDiff
+	if maxDiff == 0 {
+		maxDiff = 0.001
+	}
+	if maxDiff < 0 {
+		return nil, sdf.ErrMsg("MaxDiff < 0")
+	}
+	img := Preview(s, k.Camera, k.Lighting)
+	if _, err := os.Stat(golden); k.Update || os.IsNotExist(err) {
+		return &ImageDiff{}, savePNG(golden, img)
+	}
+	ref, err := loadPNG(golden)
+	if err != nil {
+		return nil, err
+	}
+	d, err := CompareImages(img, ref, threshold)
+	if err != nil {
+		return nil, err
+	}
+	if d.Fraction > maxDiff {
+		path := diffPath(golden)
+		if err := savePNG(path, d.Image); err != nil {
+			return d, err
+		}
+		return d, sdf.ErrMsg(fmt.Sprintf("%s: %s, see %s", golden, d, path))
+	}
+	return d, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,88 @@
+//-----------------------------------------------------------------------------
+/*
+
+Snapshot Comparison Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_CompareImages(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	b := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for i := range a.Pix {
+		a.Pix[i], b.Pix[i] = 255, 255
+	}
+	b.SetRGBA(3, 4, color.RGBA{0, 0, 0, 255})
+	b.SetRGBA(5, 5, color.RGBA{250, 250, 250, 255})
+	d, err := CompareImages(a, b, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Pixels != 1 || d.Fraction != 0.01 {
+		t.Errorf("expected 1 different pixel, got %d (%f)", d.Pixels, d.Fraction)
+	}
+	if d.MaxDelta < 0.9 {
+		t.Errorf("black/white difference %f", d.MaxDelta)
+	}
+	if c := d.Image.RGBAAt(3, 4); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("diff pixel %v", c)
+	}
+	if _, err := CompareImages(a, image.NewRGBA(image.Rect(0, 0, 5, 5)), 0.1); err == nil {
+		t.Error("expected a size error")
+	}
+}
+
+func Test_CompareSnapshot(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "part.png")
+	k := &SnapshotParms{Camera: Camera{Width: 80, Height: 60}}
+	s0, _ := sdf.Box3D(v3.Vec{10, 10, 10}, 1)
+	// the first comparison writes the golden image
+	if _, err := CompareSnapshot(s0, golden, k); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(golden); err != nil {
+		t.Fatal(err)
+	}
+	d, err := CompareSnapshot(s0, golden, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Pixels != 0 {
+		t.Errorf("same part: %s", d)
+	}
+	// a geometry change is caught
+	hole, _ := sdf.Cylinder3D(20, 3, 0)
+	s1 := sdf.Difference3D(s0, hole)
+	if _, err := CompareSnapshot(s1, golden, k); err == nil {
+		t.Error("expected a snapshot mismatch")
+	}
+	if _, err := os.Stat(diffPath(golden)); err != nil {
+		t.Error(err)
+	}
+	// update the golden image
+	k.Update = true
+	if _, err := CompareSnapshot(s1, golden, k); err != nil {
+		t.Fatal(err)
+	}
+	k.Update = false
+	if _, err := CompareSnapshot(s1, golden, k); err != nil {
+		t.Error(err)
+	}
+}
+
+//-----------------------------------------------------------------------------
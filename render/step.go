@@ -3,10 +3,14 @@ package render
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/deadsy/sdfx/sdf"
 	"github.com/deadsy/sdfx/step"
+	"github.com/deadsy/sdfx/units"
 )
 
 // ToSTEP renders an SDF3 to a STEP AP214 file
@@ -20,9 +24,13 @@ func ToSTEP(
 
 // STEPOptions configures STEP export
 type STEPOptions struct {
-	Author       string // Author name
-	Organization string // Organization name
-	ProductName  string // Product name (defaults to filename)
+	Author       string           // Author name
+	Organization string           // Organization name
+	ProductName  string           // Product name (defaults to filename)
+	Fingerprint  bool             // Embed the sdfx version, commit and model hash in the header
+	Unit         units.Unit       // Length unit of the file (the model is in millimeters)
+	SortFacets   bool             // Write the facets in a canonical order (see reproducible.go)
+	Clock        func() time.Time // Time stamp of the file header (defaults to time.Now)
 }
 
 // ToSTEPWithOptions renders an SDF3 to a STEP AP214 file with options
@@ -58,6 +66,16 @@ func ToSTEPWithOptions(
 	return nil
 }
 
+// ToSTEPWriter renders an SDF3 to a STEP AP214 file written to a writer
+func ToSTEPWriter(
+	s sdf.SDF3,
+	w io.Writer,
+	r Render3,
+	opts STEPOptions,
+) error {
+	return WriteSTEP(w, ToTriangles(s, r), opts)
+}
+
 // writeSTEP writes a stream of triangles to a STEP file
 func writeSTEP(wg *sync.WaitGroup, path string, opts STEPOptions) (chan<- []*sdf.Triangle3, error) {
 	writer, err := step.NewWriter(path)
@@ -77,6 +95,11 @@ func writeSTEP(wg *sync.WaitGroup, path string, opts STEPOptions) (chan<- []*sdf
 		}
 		writer.SetAuthor(author, org)
 	}
+	if err := writer.SetLengthUnit(opts.Unit.String()); err != nil {
+		writer.Close()
+		return nil, err
+	}
+	writer.SetTime(now(opts.Clock))
 
 	// External code writes triangles to this channel.
 	// This goroutine reads the channel and writes triangles to the file.
@@ -97,6 +120,9 @@ func writeSTEP(wg *sync.WaitGroup, path string, opts STEPOptions) (chan<- []*sdf
 		}
 
 		fmt.Printf("Writing %d triangles to STEP file\n", len(triangles))
+		if opts.SortFacets {
+			triangles = sortFacets(triangles)
+		}
 
 		// Set default product name if not provided
 		productName := opts.ProductName
@@ -104,8 +130,12 @@ func writeSTEP(wg *sync.WaitGroup, path string, opts STEPOptions) (chan<- []*sdf
 			productName = "sdfx_model"
 		}
 
+		if opts.Fingerprint {
+			writer.SetDescription(NewFingerprint(triangles).String())
+		}
+
 		// Write mesh to STEP file
-		if err := writer.WriteMesh(triangles, productName); err != nil {
+		if err := writer.WriteMesh(scaleMesh(triangles, opts.Unit), productName); err != nil {
 			fmt.Printf("Error writing STEP file: %v\n", err)
 			return
 		}
@@ -128,7 +158,32 @@ func SaveSTEPWithOptions(path string, mesh []*sdf.Triangle3, opts STEPOptions) e
 		return fmt.Errorf("failed to create STEP writer: %w", err)
 	}
 	defer writer.Close()
+	if err := writeSTEPMesh(writer, mesh, opts); err != nil {
+		return err
+	}
+	fmt.Printf("STEP export completed: %s\n", path)
+	return nil
+}
+
+// WriteSTEP writes a pre-computed triangle mesh as a STEP file to a writer
+func WriteSTEP(w io.Writer, mesh []*sdf.Triangle3, opts STEPOptions) error {
+	writer := step.NewWriterFor(w, stepProductName(opts)+".step")
+	if err := writeSTEPMesh(writer, mesh, opts); err != nil {
+		return err
+	}
+	return writer.Close()
+}
 
+// stepProductName returns the product name of the options, or the default name
+func stepProductName(opts STEPOptions) string {
+	if opts.ProductName == "" {
+		return "sdfx_model"
+	}
+	return opts.ProductName
+}
+
+// writeSTEPMesh sets the options of a STEP writer and writes a mesh
+func writeSTEPMesh(writer *step.Writer, mesh []*sdf.Triangle3, opts STEPOptions) error {
 	// Set author information if provided
 	if opts.Author != "" || opts.Organization != "" {
 		author := opts.Author
@@ -141,31 +196,42 @@ func SaveSTEPWithOptions(path string, mesh []*sdf.Triangle3, opts STEPOptions) e
 		}
 		writer.SetAuthor(author, org)
 	}
+	if err := writer.SetLengthUnit(opts.Unit.String()); err != nil {
+		return err
+	}
+	writer.SetTime(now(opts.Clock))
+	if opts.SortFacets {
+		mesh = sortFacets(mesh)
+	}
 
-	// Set default product name if not provided
-	productName := opts.ProductName
-	if productName == "" {
-		productName = "sdfx_model"
+	if opts.Fingerprint {
+		writer.SetDescription(NewFingerprint(mesh).String())
 	}
 
 	// Write mesh to STEP file
-	if err := writer.WriteMesh(mesh, productName); err != nil {
+	if err := writer.WriteMesh(scaleMesh(mesh, opts.Unit), stepProductName(opts)); err != nil {
 		return fmt.Errorf("failed to write mesh: %w", err)
 	}
-
-	fmt.Printf("STEP export completed: %s\n", path)
 	return nil
 }
 
-// LoadSTEP loads a STEP file and converts it to a triangle mesh
-// Note: This is a placeholder for future implementation
+// LoadSTEP loads the planar faces of a STEP file (e.g. a faceted BREP) as a
+// triangle mesh in millimeters.
 func LoadSTEP(path string) ([]*sdf.Triangle3, error) {
-	// TODO: Implement STEP file parsing and conversion to triangle mesh
-	// This would require:
-	// 1. Parse STEP file format
-	// 2. Extract BREP geometry
-	// 3. Tessellate BREP to triangles
-	// 4. Return triangle mesh
-
-	return nil, fmt.Errorf("STEP import not yet implemented")
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	mesh, err := step.ReadMesh(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return mesh, nil
+}
+
+// ReadSTEP reads the planar faces of a STEP file of a given size as a
+// triangle mesh in millimeters.
+func ReadSTEP(r io.ReaderAt, size int64) ([]*sdf.Triangle3, error) {
+	return step.ReadMesh(io.NewSectionReader(r, 0, size))
 }
@@ -12,6 +12,7 @@ import (
 	"bufio"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -25,8 +26,8 @@ import (
 
 // STLHeader defines the STL file header.
 type STLHeader struct {
-	_     [80]uint8 // Header
-	Count uint32    // Number of triangles
+	Header [80]uint8 // Header
+	Count  uint32    // Number of triangles
 }
 
 // STLTriangle defines the triangle data within an STL file.
@@ -51,7 +52,7 @@ func parseFloats(in []string) ([]float64, error) {
 }
 
 // loadSTLAscii loads an STL file created in ASCII format.
-func loadSTLAscii(file *os.File) ([]*sdf.Triangle3, error) {
+func loadSTLAscii(file io.Reader) ([]*sdf.Triangle3, error) {
 	var v []v3.Vec
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
@@ -74,7 +75,7 @@ func loadSTLAscii(file *os.File) ([]*sdf.Triangle3, error) {
 }
 
 // loadSTLBinary loads an STL file created in binary format.
-func loadSTLBinary(file *os.File) ([]*sdf.Triangle3, error) {
+func loadSTLBinary(file io.Reader) ([]*sdf.Triangle3, error) {
 	r := bufio.NewReader(file)
 	header := STLHeader{}
 	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
@@ -94,6 +95,22 @@ func loadSTLBinary(file *os.File) ([]*sdf.Triangle3, error) {
 	return mesh, nil
 }
 
+// ReadSTL reads an STL file (ascii or binary) of a given size and returns the triangle mesh.
+func ReadSTL(r io.ReaderAt, size int64) ([]*sdf.Triangle3, error) {
+	// read header, get expected binary size
+	header := STLHeader{}
+	if err := binary.Read(io.NewSectionReader(r, 0, size), binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	expectedSize := int64(header.Count)*50 + 84
+
+	// parse ascii or binary stl
+	if size == expectedSize {
+		return loadSTLBinary(io.NewSectionReader(r, 0, size))
+	}
+	return loadSTLAscii(io.NewSectionReader(r, 0, size))
+}
+
 // LoadSTL loads an STL file (ascii or binary) and returns the triangle mesh.
 func LoadSTL(path string) ([]*sdf.Triangle3, error) {
 	// open file
@@ -108,61 +125,92 @@ func LoadSTL(path string) ([]*sdf.Triangle3, error) {
 	if err != nil {
 		return nil, err
 	}
-	size := info.Size()
-
-	// read header, get expected binary size
-	header := STLHeader{}
-	if err := binary.Read(file, binary.LittleEndian, &header); err != nil {
-		return nil, err
-	}
-	expectedSize := int64(header.Count)*50 + 84
-
-	// rewind to start of file
-	_, err = file.Seek(0, 0)
-	if err != nil {
-		return nil, err
-	}
-
-	// parse ascii or binary stl
-	if size == expectedSize {
-		return loadSTLBinary(file)
-	}
-	return loadSTLAscii(file)
+	return ReadSTL(file, info.Size())
 }
 
 //-----------------------------------------------------------------------------
 
 // SaveSTL writes a triangle mesh to an STL file.
 func SaveSTL(path string, mesh []*sdf.Triangle3) error {
+	return SaveSTLWithOptions(path, mesh, ExportOptions{})
+}
+
+// SaveSTLWithOptions writes a triangle mesh to an STL file with export options.
+func SaveSTLWithOptions(path string, mesh []*sdf.Triangle3, opts ExportOptions) error {
 	file, err := os.Create(path)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
+	return writeSTLBinary(file, mesh, opts)
+}
+
+// SaveSTLASCII writes a triangle mesh to an ASCII STL file.
+// With the Fingerprint option set the solid name is the model fingerprint.
+func SaveSTLASCII(path string, mesh []*sdf.Triangle3, opts ExportOptions) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteSTLASCII(file, mesh, opts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WriteSTL writes a triangle mesh as a binary STL to a writer.
+func WriteSTL(w io.Writer, mesh []*sdf.Triangle3, opts ExportOptions) error {
+	return writeSTLBinary(w, mesh, opts)
+}
+
+// WriteSTLASCII writes a triangle mesh as an ASCII STL to a writer.
+func WriteSTLASCII(w io.Writer, mesh []*sdf.Triangle3, opts ExportOptions) error {
+	buf := bufio.NewWriter(w)
+	mesh = opts.facets(mesh)
+	name := "sdfx"
+	if opts.Fingerprint {
+		name = NewFingerprint(mesh).String()
+	}
+	fmt.Fprintf(buf, "solid %s\n", name)
+	for _, t := range scaleMesh(mesh, opts.Unit) {
+		n := t.Normal()
+		fmt.Fprintf(buf, "facet normal %g %g %g\nouter loop\n", n.X, n.Y, n.Z)
+		for _, v := range t {
+			fmt.Fprintf(buf, "vertex %g %g %g\n", float32(v.X), float32(v.Y), float32(v.Z))
+		}
+		fmt.Fprintf(buf, "endloop\nendfacet\n")
+	}
+	fmt.Fprintf(buf, "endsolid %s\n", name)
+	return buf.Flush()
+}
 
-	buf := bufio.NewWriter(file)
+// stlTriangle returns the STL record for a triangle.
+func stlTriangle(t *sdf.Triangle3) *STLTriangle {
+	n := t.Normal()
+	return &STLTriangle{
+		Normal:  [3]float32{float32(n.X), float32(n.Y), float32(n.Z)},
+		Vertex1: [3]float32{float32(t[0].X), float32(t[0].Y), float32(t[0].Z)},
+		Vertex2: [3]float32{float32(t[1].X), float32(t[1].Y), float32(t[1].Z)},
+		Vertex3: [3]float32{float32(t[2].X), float32(t[2].Y), float32(t[2].Z)},
+	}
+}
+
+// writeSTLBinary writes a triangle mesh as a binary STL.
+func writeSTLBinary(w io.Writer, mesh []*sdf.Triangle3, opts ExportOptions) error {
+	buf := bufio.NewWriter(w)
+	mesh = opts.facets(mesh)
 	header := STLHeader{}
 	header.Count = uint32(len(mesh))
+	if opts.Fingerprint {
+		copy(header.Header[:], NewFingerprint(mesh).short())
+	}
 	if err := binary.Write(buf, binary.LittleEndian, &header); err != nil {
 		return err
 	}
 
-	var d STLTriangle
-	for _, triangle := range mesh {
-		n := triangle.Normal()
-		d.Normal[0] = float32(n.X)
-		d.Normal[1] = float32(n.Y)
-		d.Normal[2] = float32(n.Z)
-		d.Vertex1[0] = float32(triangle[0].X)
-		d.Vertex1[1] = float32(triangle[0].Y)
-		d.Vertex1[2] = float32(triangle[0].Z)
-		d.Vertex2[0] = float32(triangle[1].X)
-		d.Vertex2[1] = float32(triangle[1].Y)
-		d.Vertex2[2] = float32(triangle[1].Z)
-		d.Vertex3[0] = float32(triangle[2].X)
-		d.Vertex3[1] = float32(triangle[2].Y)
-		d.Vertex3[2] = float32(triangle[2].Z)
-		if err := binary.Write(buf, binary.LittleEndian, &d); err != nil {
+	for _, triangle := range scaleMesh(mesh, opts.Unit) {
+		if err := binary.Write(buf, binary.LittleEndian, stlTriangle(triangle)); err != nil {
 			return err
 		}
 	}
@@ -173,7 +221,7 @@ func SaveSTL(path string, mesh []*sdf.Triangle3) error {
 //-----------------------------------------------------------------------------
 
 // writeSTL writes a stream of triangles to an STL file.
-func writeSTL(wg *sync.WaitGroup, path string) (chan<- []*sdf.Triangle3, error) {
+func writeSTL(wg *sync.WaitGroup, path string, opts ExportOptions) (chan<- []*sdf.Triangle3, error) {
 
 	f, err := os.Create(path)
 	if err != nil {
@@ -200,29 +248,35 @@ func writeSTL(wg *sync.WaitGroup, path string) (chan<- []*sdf.Triangle3, error)
 		defer f.Close()
 
 		var count uint32
-		var d STLTriangle
-		// read triangles from the channel and write them to the file
-		for ts := range c {
-			for _, t := range ts {
-				n := t.Normal()
-				d.Normal[0] = float32(n.X)
-				d.Normal[1] = float32(n.Y)
-				d.Normal[2] = float32(n.Z)
-				d.Vertex1[0] = float32(t[0].X)
-				d.Vertex1[1] = float32(t[0].Y)
-				d.Vertex1[2] = float32(t[0].Z)
-				d.Vertex2[0] = float32(t[1].X)
-				d.Vertex2[1] = float32(t[1].Y)
-				d.Vertex2[2] = float32(t[1].Z)
-				d.Vertex3[0] = float32(t[2].X)
-				d.Vertex3[1] = float32(t[2].Y)
-				d.Vertex3[2] = float32(t[2].Z)
-				if err := binary.Write(buf, binary.LittleEndian, &d); err != nil {
-					fmt.Printf("%s\n", err)
-					return
+		h := newModelHash()
+		write := func(ts []*sdf.Triangle3) error {
+			h.add(ts)
+			for _, t := range scaleMesh(ts, opts.Unit) {
+				if err := binary.Write(buf, binary.LittleEndian, stlTriangle(t)); err != nil {
+					return err
 				}
 				count++
 			}
+			return nil
+		}
+		// read triangles from the channel and write them to the file
+		var all []*sdf.Triangle3
+		for ts := range c {
+			if opts.SortFacets {
+				// the order is known once all the triangles are in
+				all = append(all, ts...)
+				continue
+			}
+			if err := write(ts); err != nil {
+				fmt.Printf("%s\n", err)
+				return
+			}
+		}
+		if opts.SortFacets {
+			if err := write(sortFacets(all)); err != nil {
+				fmt.Printf("%s\n", err)
+				return
+			}
 		}
 		// flush the triangles
 		buf.Flush()
@@ -234,6 +288,9 @@ func writeSTL(wg *sync.WaitGroup, path string) (chan<- []*sdf.Triangle3, error)
 		}
 		// rewrite the header with the correct mesh count
 		hdr.Count = count
+		if opts.Fingerprint {
+			copy(hdr.Header[:], h.fingerprint().short())
+		}
 		if err := binary.Write(f, binary.LittleEndian, &hdr); err != nil {
 			fmt.Printf("%s\n", err)
 			return
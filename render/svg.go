@@ -3,6 +3,9 @@
 
 Output a 2D line set to an SVG file.
 
+Output an SDF2 as a filled SVG path, with options for laser cutting
+(stroke only) and documentation figures (filled, fit to a page).
+
 */
 //-----------------------------------------------------------------------------
 
@@ -10,12 +13,16 @@ package render
 
 import (
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"strings"
 	"sync"
 
 	svg "github.com/ajstarks/svgo/float"
 	"github.com/deadsy/sdfx/sdf"
 	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
 )
 
 //-----------------------------------------------------------------------------
@@ -57,7 +64,12 @@ func (s *SVG) Save() error {
 	if err != nil {
 		return err
 	}
+	s.write(f)
+	return f.Close()
+}
 
+// write writes the SVG lines to a writer.
+func (s *SVG) write(f io.Writer) {
 	width := s.max.X - s.min.X
 	height := s.max.Y - s.min.Y
 	canvas := svg.New(f)
@@ -67,7 +79,6 @@ func (s *SVG) Save() error {
 		canvas.Line(p0.X-s.min.X, s.max.Y-p0.Y, p1.X-s.min.X, s.max.Y-p1.Y, s.lineStyle)
 	}
 	canvas.End()
-	return f.Close()
 }
 
 //-----------------------------------------------------------------------------
@@ -84,6 +95,16 @@ func SaveSVG(path, lineStyle string, mesh []*sdf.Line2) error {
 	return nil
 }
 
+// WriteSVG writes line segments as an SVG file to a writer.
+func WriteSVG(w io.Writer, lineStyle string, mesh []*sdf.Line2) error {
+	s := NewSVG("", lineStyle)
+	for _, v := range mesh {
+		s.Line(v[0], v[1])
+	}
+	s.write(w)
+	return nil
+}
+
 //-----------------------------------------------------------------------------
 
 // writeSVG writes a stream of line segments to an SVG file.
@@ -114,3 +135,154 @@ func writeSVG(wg *sync.WaitGroup, path, lineStyle string) (chan<- []*sdf.Line2,
 }
 
 //-----------------------------------------------------------------------------
+
+// SVGOptions configures filled SVG export.
+type SVGOptions struct {
+	Fill        string  // fill color ("" = black, "none" = no fill)
+	Stroke      string  // stroke color ("" = none)
+	StrokeWidth float64 // stroke width in model units (0 = 0.1)
+	Units       string  // units of the model: "mm" (default), "cm", "in" or "px"
+	Page        v2.Vec  // page size in model units (0 = fit the page to the drawing)
+	Margin      float64 // page margin in model units
+	FitToPage   bool    // scale the drawing to fill the page (otherwise 1:1 centered on the page)
+}
+
+// svgPath returns the SVG path data for a set of contours.
+func svgPath(contours []Contour, xf func(v2.Vec) v2.Vec) string {
+	var sb strings.Builder
+	for _, c := range contours {
+		for i, p := range c {
+			p = xf(p)
+			cmd := "L"
+			if i == 0 {
+				cmd = "M"
+			}
+			fmt.Fprintf(&sb, "%s%.4f %.4f ", cmd, p.X, p.Y)
+		}
+		sb.WriteString("Z ")
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// svgUnits is the size of the SVG units in model units.
+var svgUnits = map[string]float64{"": 1, "mm": 1, "cm": 10, "in": 25.4, "px": 25.4 / 96}
+
+// svgLayout returns the page size and the page transform for a drawing with a bounding box.
+func svgLayout(bb sdf.Box2, opts *SVGOptions) (v2.Vec, func(v2.Vec) v2.Vec, error) {
+	if _, ok := svgUnits[opts.Units]; !ok {
+		return v2.Vec{}, nil, fmt.Errorf("unknown units \"%s\"", opts.Units)
+	}
+	if opts.Page.X < 0 || opts.Page.Y < 0 || opts.Margin < 0 {
+		return v2.Vec{}, nil, sdf.ErrMsg("Page/Margin < 0")
+	}
+	size := bb.Size()
+	page := opts.Page
+	if page.X == 0 || page.Y == 0 {
+		page = size.AddScalar(2 * opts.Margin)
+	}
+	scale := 1.0
+	if opts.FitToPage && size.X > 0 && size.Y > 0 {
+		avail := page.SubScalar(2 * opts.Margin)
+		scale = math.Min(avail.X/size.X, avail.Y/size.Y)
+	}
+	ofs := page.Sub(size.MulScalar(scale)).MulScalar(0.5)
+	xf := func(p v2.Vec) v2.Vec {
+		// flip y, the svg y-axis is down the page
+		return v2.Vec{(p.X-bb.Min.X)*scale + ofs.X, (bb.Max.Y-p.Y)*scale + ofs.Y}
+	}
+	return page, xf, nil
+}
+
+// svgStart starts an SVG document with a page size in model units.
+func svgStart(canvas *svg.SVG, page v2.Vec, opts *SVGOptions) {
+	units := opts.Units
+	if units == "" {
+		units = "mm"
+	}
+	unit := svgUnits[opts.Units]
+	canvas.StartviewUnit(page.X/unit, page.Y/unit, units, 0, 0, page.X, page.Y)
+}
+
+// ToSVGWithOptions renders an SDF2 to an SVG file as a filled path.
+// Holes are handled with the even-odd fill rule.
+func ToSVGWithOptions(
+	s sdf.SDF2, // sdf2 to render
+	path string, // path to filename
+	r Render2, // rendering method
+	opts SVGOptions,
+) error {
+	fmt.Printf("rendering %s (%s)\n", path, r.Info(s))
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := ToSVGWriterWithOptions(s, f, r, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ToSVGWriterWithOptions renders an SDF2 as a filled path to an SVG file
+// written to a writer.
+func ToSVGWriterWithOptions(
+	s sdf.SDF2, // sdf2 to render
+	w io.Writer, // output
+	r Render2, // rendering method
+	opts SVGOptions,
+) error {
+	// render the contours
+	c := &lineCollector{}
+	r.Render(s, c)
+	bb := s.BoundingBox()
+	contours := joinLines(c.lines, bb.Size().MaxComponent()*1e-6)
+	if len(contours) != 0 {
+		bb = sdf.Box2{Min: contours[0][0], Max: contours[0][0]}
+		for _, c := range contours {
+			for _, p := range c {
+				bb = bb.Include(p)
+			}
+		}
+	}
+	page, xf, err := svgLayout(bb, &opts)
+	if err != nil {
+		return err
+	}
+
+	// style
+	fill := opts.Fill
+	if fill == "" {
+		fill = "black"
+	}
+	stroke := opts.Stroke
+	if stroke == "" {
+		stroke = "none"
+	}
+	strokeWidth := opts.StrokeWidth
+	if strokeWidth == 0 {
+		strokeWidth = 0.1
+	}
+	style := fmt.Sprintf("fill:%s;fill-rule:evenodd;stroke:%s;stroke-width:%g", fill, stroke, strokeWidth)
+
+	canvas := svg.New(w)
+	svgStart(canvas, page, &opts)
+	if len(contours) != 0 {
+		canvas.Path(svgPath(contours, xf), style)
+	}
+	canvas.End()
+	return nil
+}
+
+// SliceToSVG renders the cross section of an SDF3 at height z to an SVG file.
+func SliceToSVG(
+	s sdf.SDF3, // sdf3 to slice
+	z float64, // z height of the slice
+	path string, // path to filename
+	r Render2, // rendering method
+	opts SVGOptions,
+) error {
+	s2 := sdf.Slice2D(s, v3.Vec{0, 0, z}, v3.Vec{0, 0, 1})
+	return ToSVGWithOptions(s2, path, r, opts)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,83 @@
+//-----------------------------------------------------------------------------
+/*
+
+Export Unit Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	"github.com/deadsy/sdfx/units"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/hpinc/go3mf"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_ExportUnits(t *testing.T) {
+	// a 1 x 2 x 0.5 inch box
+	s, _ := sdf.Box3D(v3.Vec{25.4, 50.8, 12.7}, 0)
+	mesh := ToTriangles(s, NewMarchingCubesUniform(20))
+	dir := t.TempDir()
+	opts := ExportOptions{Unit: units.Inch}
+
+	// STL coordinates are in inches
+	path := filepath.Join(dir, "box.stl")
+	if err := SaveSTLWithOptions(path, mesh, opts); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSTL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bb := sdf.Box3{Min: loaded[0][0], Max: loaded[0][0]}
+	for _, tri := range loaded {
+		for _, v := range tri {
+			bb = bb.Include(v)
+		}
+	}
+	if !bb.Size().Equals(v3.Vec{1, 2, 0.5}, 1e-4) {
+		t.Errorf("STL size %v, expected 1 x 2 x 0.5 inches", bb.Size())
+	}
+
+	// 3MF records the unit
+	path = filepath.Join(dir, "box.3mf")
+	if err := Save3MFWithOptions(path, []Part3MF{{Name: "box", Mesh: mesh}}, opts); err != nil {
+		t.Fatal(err)
+	}
+	r, err := go3mf.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var model go3mf.Model
+	if err := r.Decode(&model); err != nil {
+		t.Fatal(err)
+	}
+	if model.Units != go3mf.UnitInch {
+		t.Errorf("3MF unit %v, expected inch", model.Units)
+	}
+
+	// STEP has an inch unit entity
+	path = filepath.Join(dir, "box.step")
+	if err := SaveSTEPWithOptions(path, mesh, STEPOptions{Unit: units.Inch}); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(buf), "CONVERSION_BASED_UNIT('INCH'") {
+		t.Error("no inch unit in the STEP file")
+	}
+}
+
+//-----------------------------------------------------------------------------
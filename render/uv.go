@@ -0,0 +1,206 @@
+//-----------------------------------------------------------------------------
+/*
+
+UV Unwrapping
+
+Texture coordinates for a triangle mesh by box projection: each triangle is
+projected onto the axis plane it faces most directly, the connected
+triangles facing the same way form a chart, and the charts are packed into
+the unit square.
+
+All the charts have the same scale, so a texture has the same density over
+the whole mesh (the texel size is only stretched by the angle between a
+triangle and its projection plane, at most 1/cos(54.7) = 1.73).
+
+The charts are height fields over their plane, but a chart that wraps
+around on itself (e.g. a helical ramp) can overlap in the UV space.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"math"
+	"sort"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// uvPadding is the padding around the charts for the file writers.
+const uvPadding = 0.005
+
+// uvAxis returns the axis plane (0..5 for +x, -x, +y, -y, +z, -z) that a normal faces.
+func uvAxis(n v3.Vec) int {
+	a := n.Abs()
+	switch {
+	case a.X >= a.Y && a.X >= a.Z:
+		if n.X >= 0 {
+			return 0
+		}
+		return 1
+	case a.Y >= a.Z:
+		if n.Y >= 0 {
+			return 2
+		}
+		return 3
+	}
+	if n.Z >= 0 {
+		return 4
+	}
+	return 5
+}
+
+// uvProject projects a point onto an axis plane. The (u, v) axes are chosen
+// so that u x v is the plane normal, so the triangles keep their winding.
+func uvProject(p v3.Vec, axis int) v2.Vec {
+	switch axis {
+	case 0:
+		return v2.Vec{X: p.Y, Y: p.Z}
+	case 1:
+		return v2.Vec{X: -p.Y, Y: p.Z}
+	case 2:
+		return v2.Vec{X: -p.X, Y: p.Z}
+	case 3:
+		return v2.Vec{X: p.X, Y: p.Z}
+	case 4:
+		return v2.Vec{X: p.X, Y: p.Y}
+	}
+	return v2.Vec{X: -p.X, Y: p.Y}
+}
+
+//-----------------------------------------------------------------------------
+
+// uvChart is a set of connected triangles facing the same axis plane.
+type uvChart struct {
+	faces    []int   // triangle indices
+	min, max v2.Vec  // projected bounding box
+	offset   v2.Vec  // packed position (in model units)
+	size     v2.Vec  // padded size (in model units)
+	area     float64 // padded area
+}
+
+// uvCharts groups the triangles of a mesh into charts.
+func uvCharts(mesh []*sdf.Triangle3) ([]*uvChart, []int) {
+	axis := make([]int, len(mesh))
+	for i, t := range mesh {
+		axis[i] = uvAxis(t.Normal())
+	}
+	// union the triangles sharing a vertex and an axis
+	parent := make([]int, len(mesh))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	type key struct {
+		v    v3.Vec
+		axis int
+	}
+	first := make(map[key]int)
+	for i, t := range mesh {
+		for _, v := range t {
+			k := key{v, axis[i]}
+			if j, ok := first[k]; ok {
+				parent[find(i)] = find(j)
+			} else {
+				first[k] = i
+			}
+		}
+	}
+	// collect the charts in triangle order
+	index := make(map[int]*uvChart)
+	var charts []*uvChart
+	for i := range mesh {
+		r := find(i)
+		c, ok := index[r]
+		if !ok {
+			c = &uvChart{}
+			index[r] = c
+			charts = append(charts, c)
+		}
+		c.faces = append(c.faces, i)
+	}
+	return charts, axis
+}
+
+// uvPack packs the charts into a square with shelves and returns its size.
+func uvPack(charts []*uvChart, padding float64) float64 {
+	total := 0.0
+	for _, c := range charts {
+		total += (c.max.X - c.min.X) * (c.max.Y - c.min.Y)
+	}
+	gap := padding * math.Sqrt(total)
+	total = 0.0
+	width := 0.0
+	for _, c := range charts {
+		c.size = c.max.Sub(c.min).AddScalar(2 * gap)
+		c.area = c.size.X * c.size.Y
+		total += c.area
+		width = math.Max(width, c.size.X)
+	}
+	width = math.Max(width, math.Sqrt(total))
+	// tallest charts first
+	order := make([]*uvChart, len(charts))
+	copy(order, charts)
+	sort.SliceStable(order, func(i, j int) bool { return order[i].size.Y > order[j].size.Y })
+	var x, y, shelf, used float64
+	for _, c := range order {
+		if x+c.size.X > width {
+			x, y, shelf = 0, y+shelf, 0
+		}
+		c.offset = v2.Vec{X: x + gap, Y: y + gap}
+		x += c.size.X
+		shelf = math.Max(shelf, c.size.Y)
+		used = math.Max(used, x)
+	}
+	return math.Max(used, y+shelf)
+}
+
+// UnwrapUV returns texture coordinates for the vertices of each triangle of
+// a mesh, in the unit square. The padding around the charts is at most a
+// fraction of the texture size.
+func UnwrapUV(mesh []*sdf.Triangle3, padding float64) [][3]v2.Vec {
+	uv := make([][3]v2.Vec, len(mesh))
+	if len(mesh) == 0 {
+		return uv
+	}
+	charts, axis := uvCharts(mesh)
+	for _, c := range charts {
+		c.min = v2.Vec{X: math.Inf(1), Y: math.Inf(1)}
+		c.max = v2.Vec{X: math.Inf(-1), Y: math.Inf(-1)}
+		for _, i := range c.faces {
+			for j, v := range mesh[i] {
+				p := uvProject(v, axis[i])
+				uv[i][j] = p
+				c.min = c.min.Min(p)
+				c.max = c.max.Max(p)
+			}
+		}
+	}
+	size := uvPack(charts, padding)
+	if size == 0 {
+		return uv
+	}
+	k := 1 / size
+	for _, c := range charts {
+		for _, i := range c.faces {
+			for j := range uv[i] {
+				uv[i][j] = uv[i][j].Sub(c.min).Add(c.offset).MulScalar(k)
+			}
+		}
+	}
+	return uv
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,122 @@
+//-----------------------------------------------------------------------------
+/*
+
+UV Unwrapping Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// uvArea returns the signed area of a triangle in the UV space.
+func uvArea(t [3]v2.Vec) float64 {
+	return 0.5 * t[1].Sub(t[0]).Cross(t[2].Sub(t[0]))
+}
+
+func Test_UnwrapUV(t *testing.T) {
+	s, _ := sdf.Box3D(v3.Vec{X: 20, Y: 10, Z: 5}, 2)
+	mesh := ToTriangles(s, NewMarchingCubesOctree(40))
+	uv := UnwrapUV(mesh, 0.01)
+	if len(uv) != len(mesh) {
+		t.Fatalf("expected %d triangles, got %d", len(mesh), len(uv))
+	}
+
+	// the texture scale is the same for all triangles
+	scale := -1.0
+	for i, x := range uv {
+		for _, c := range x {
+			if c.X < 0 || c.X > 1 || c.Y < 0 || c.Y > 1 {
+				t.Fatalf("uv %v is outside the unit square", c)
+			}
+		}
+		tri := mesh[i]
+		n := tri.Normal()
+		area := 0.5 * tri[1].Sub(tri[0]).Cross(tri[2].Sub(tri[0])).Length()
+		projected := area * n.Abs().MaxComponent()
+		if projected < 1e-9 {
+			continue
+		}
+		a := uvArea(x)
+		if a <= 0 {
+			t.Fatalf("triangle %d is flipped in the uv space", i)
+		}
+		k := a / projected
+		if scale < 0 {
+			scale = k
+		} else if math.Abs(k-scale) > 1e-6*scale {
+			t.Fatalf("triangle %d has a scale of %g, expected %g", i, k, scale)
+		}
+	}
+
+	// the charts don't overlap
+	charts, _ := uvCharts(mesh)
+	if len(charts) < 6 {
+		t.Fatalf("expected at least 6 charts, got %d", len(charts))
+	}
+	boxes := make([]sdf.Box2, len(charts))
+	for i, c := range charts {
+		b := sdf.Box2{Min: uv[c.faces[0]][0], Max: uv[c.faces[0]][0]}
+		for _, f := range c.faces {
+			for _, p := range uv[f] {
+				b = b.Include(p)
+			}
+		}
+		boxes[i] = b
+	}
+	for i := range boxes {
+		for j := i + 1; j < len(boxes); j++ {
+			a, b := boxes[i], boxes[j]
+			if a.Min.X < b.Max.X && b.Min.X < a.Max.X && a.Min.Y < b.Max.Y && b.Min.Y < a.Max.Y {
+				t.Fatalf("charts %d and %d overlap", i, j)
+			}
+		}
+	}
+}
+
+func Test_UVWriters(t *testing.T) {
+	s, _ := sdf.Sphere3D(10)
+	mesh := ToTriangles(s, NewMarchingCubesOctree(30))
+
+	var obj bytes.Buffer
+	if err := WriteOBJWithUV(&obj, mesh); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(obj.String(), "\nvt ") {
+		t.Error("obj file has no texture coordinates")
+	}
+	data := obj.Bytes()
+	out, err := ReadOBJ(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != len(mesh) {
+		t.Errorf("expected %d triangles, got %d", len(mesh), len(out))
+	}
+
+	d, err := gltfParts([]GLTFPart{{Name: "sphere", Mesh: mesh, UV: true}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, ok := d.Meshes[0].Primitives[0].Attributes["TEXCOORD_0"]
+	if !ok {
+		t.Fatal("glTF mesh has no texture coordinates")
+	}
+	if a := d.Accessors[k]; a.Type != "VEC2" || a.Count != 3*len(mesh) {
+		t.Errorf("bad texture coordinate accessor %+v", a)
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,476 @@
+//-----------------------------------------------------------------------------
+/*
+
+Mesh Validation and Repair
+
+Check a triangle mesh for the problems that make it unprintable: degenerate
+faces, non-manifold edges (shared by more than two faces), holes (loops of
+edges with a single face), flipped normals and self-intersections. These
+usually come from rendering at too low a resolution, where thin walls and
+close surfaces fall between the sample points.
+
+Flipped faces are found by walking the faces of each connected component
+across its manifold edges: neighboring faces should use a shared edge in
+opposite directions. The smaller set of faces that disagree is reported as
+flipped, and a closed component with a negative volume is inside out.
+
+Self-intersections are found by testing the edges of each face against the
+faces that are near it and share no vertex with it. Coplanar overlaps are
+not detected.
+
+Repair is best effort: it welds the vertices, removes degenerate and repeated
+faces, makes the face orientation consistent and fills the holes with fans.
+Non-manifold edges and self-intersections are left as they are.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// MeshReport is the result of a mesh validation.
+type MeshReport struct {
+	Triangles         int         // number of triangles
+	Vertices          int         // number of welded vertices
+	Degenerate        int         // number of faces with no area
+	Repeated          int         // number of faces that repeat another face
+	NonManifoldEdges  [][2]v3.Vec // edges shared by more than two faces
+	BoundaryEdges     [][2]v3.Vec // edges with a single face
+	Holes             int         // number of boundary edge loops
+	Flipped           []int       // faces with the wrong orientation
+	SelfIntersections [][2]int    // pairs of intersecting faces
+}
+
+// Watertight returns true if the mesh is closed and manifold.
+func (r *MeshReport) Watertight() bool {
+	return len(r.NonManifoldEdges) == 0 && len(r.BoundaryEdges) == 0
+}
+
+// OK returns true if no problems were found.
+func (r *MeshReport) OK() bool {
+	return r.Watertight() && r.Degenerate == 0 && r.Repeated == 0 && len(r.Flipped) == 0 && len(r.SelfIntersections) == 0
+}
+
+func (r *MeshReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d triangles, %d vertices", r.Triangles, r.Vertices)
+	if r.OK() {
+		sb.WriteString(", ok")
+		return sb.String()
+	}
+	if r.Degenerate != 0 {
+		fmt.Fprintf(&sb, ", %d degenerate faces", r.Degenerate)
+	}
+	if r.Repeated != 0 {
+		fmt.Fprintf(&sb, ", %d repeated faces", r.Repeated)
+	}
+	if n := len(r.NonManifoldEdges); n != 0 {
+		fmt.Fprintf(&sb, ", %d non-manifold edges", n)
+	}
+	if r.Holes != 0 {
+		fmt.Fprintf(&sb, ", %d holes (%d boundary edges)", r.Holes, len(r.BoundaryEdges))
+	}
+	if n := len(r.Flipped); n != 0 {
+		fmt.Fprintf(&sb, ", %d flipped faces", n)
+	}
+	if n := len(r.SelfIntersections); n != 0 {
+		fmt.Fprintf(&sb, ", %d self-intersections", n)
+	}
+	return sb.String()
+}
+
+//-----------------------------------------------------------------------------
+
+// edgeKey returns the undirected key for the edge a-b.
+func edgeKey(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+// topology is the face connectivity of an indexed mesh.
+type topology struct {
+	m     *Mesh
+	edges map[[2]int][]int // faces of each undirected edge
+}
+
+// weldTolerance returns the vertex welding tolerance for a mesh.
+func weldTolerance(mesh []*sdf.Triangle3) float64 {
+	bb := mesh[0].BoundingBox()
+	for _, t := range mesh[1:] {
+		bb = bb.Extend(t.BoundingBox())
+	}
+	return 1e-9 * bb.Size().MaxComponent()
+}
+
+// cleanMesh welds a mesh and removes its degenerate and repeated faces.
+func cleanMesh(mesh []*sdf.Triangle3) (m *Mesh, degenerate, repeated int) {
+	tolerance := weldTolerance(mesh)
+	m, _ = NewMesh(mesh, tolerance)
+	degenerate = len(mesh) - len(m.Faces)
+	seen := make(map[[3]int]bool)
+	faces := m.Faces[:0]
+	for _, f := range m.Faces {
+		a := m.Vertices[f[1]].Sub(m.Vertices[f[0]])
+		b := m.Vertices[f[2]].Sub(m.Vertices[f[0]])
+		if a.Cross(b).Length() <= tolerance*tolerance {
+			degenerate++
+			continue
+		}
+		// the same vertices in any order
+		k := f
+		if k[0] > k[1] {
+			k[0], k[1] = k[1], k[0]
+		}
+		if k[1] > k[2] {
+			k[1], k[2] = k[2], k[1]
+		}
+		if k[0] > k[1] {
+			k[0], k[1] = k[1], k[0]
+		}
+		if seen[k] {
+			repeated++
+			continue
+		}
+		seen[k] = true
+		faces = append(faces, f)
+	}
+	m.Faces = faces
+	return m, degenerate, repeated
+}
+
+func newTopology(m *Mesh) *topology {
+	t := &topology{m: m, edges: make(map[[2]int][]int)}
+	for i, f := range m.Faces {
+		for j := range f {
+			k := edgeKey(f[j], f[(j+1)%3])
+			t.edges[k] = append(t.edges[k], i)
+		}
+	}
+	return t
+}
+
+// direction returns true if face i uses edge a->b (rather than b->a).
+func (t *topology) direction(i, a, b int) bool {
+	f := t.m.Faces[i]
+	for j := range f {
+		if f[j] == a {
+			return f[(j+1)%3] == b
+		}
+	}
+	return false
+}
+
+// orientation returns the faces to flip for a consistent outward orientation.
+func (t *topology) orientation() []bool {
+	m := t.m
+	flip := make([]bool, len(m.Faces))
+	done := make([]bool, len(m.Faces))
+	for start := range m.Faces {
+		if done[start] {
+			continue
+		}
+		// walk the component across its manifold edges
+		component := []int{start}
+		done[start] = true
+		closed := true
+		for k := 0; k < len(component); k++ {
+			i := component[k]
+			f := m.Faces[i]
+			for j := range f {
+				a, b := f[j], f[(j+1)%3]
+				faces := t.edges[edgeKey(a, b)]
+				if len(faces) != 2 {
+					closed = false
+					continue
+				}
+				g := faces[0]
+				if g == i {
+					g = faces[1]
+				}
+				if done[g] {
+					continue
+				}
+				done[g] = true
+				// neighbors use the shared edge in opposite directions
+				flip[g] = flip[i] != (t.direction(i, a, b) == t.direction(g, a, b))
+				component = append(component, g)
+			}
+		}
+		// flip the minority
+		n := 0
+		for _, i := range component {
+			if flip[i] {
+				n++
+			}
+		}
+		invert := 2*n > len(component)
+		if closed {
+			// a closed component has a positive volume
+			var volume float64
+			for _, i := range component {
+				f := m.Faces[i]
+				v := m.Vertices[f[0]].Dot(m.Vertices[f[1]].Cross(m.Vertices[f[2]]))
+				if flip[i] {
+					v = -v
+				}
+				volume += v
+			}
+			invert = volume < 0
+		}
+		if invert {
+			for _, i := range component {
+				flip[i] = !flip[i]
+			}
+		}
+	}
+	return flip
+}
+
+// boundaryLoops returns the boundary edge loops, oriented as in their faces.
+func (t *topology) boundaryLoops() [][]int {
+	next := make(map[int][]int)
+	for k, faces := range t.edges {
+		if len(faces) != 1 {
+			continue
+		}
+		a, b := k[0], k[1]
+		if !t.direction(faces[0], a, b) {
+			a, b = b, a
+		}
+		next[a] = append(next[a], b)
+	}
+	var loops [][]int
+	for len(next) != 0 {
+		// start at the lowest vertex for a deterministic result
+		start := -1
+		for v := range next {
+			if start < 0 || v < start {
+				start = v
+			}
+		}
+		// split the walk into simple loops where it revisits a vertex
+		path := []int{start}
+		index := map[int]int{start: 0}
+		v := start
+		for {
+			ns := next[v]
+			if len(ns) == 0 {
+				break
+			}
+			w := ns[0]
+			if len(ns) == 1 {
+				delete(next, v)
+			} else {
+				next[v] = ns[1:]
+			}
+			if i, ok := index[w]; ok {
+				loops = append(loops, append([]int{}, path[i:]...))
+				for _, u := range path[i+1:] {
+					delete(index, u)
+				}
+				path = path[:i+1]
+			} else {
+				index[w] = len(path)
+				path = append(path, w)
+			}
+			v = w
+		}
+		// the rest of the path is an open chain (at a non-manifold vertex), not a loop
+	}
+	return loops
+}
+
+//-----------------------------------------------------------------------------
+
+// segmentTriangle returns true if the segment p-q crosses the interior of a triangle.
+func segmentTriangle(p, q v3.Vec, t *sdf.Triangle3) bool {
+	const eps = 1e-12
+	d := q.Sub(p)
+	e1 := t[1].Sub(t[0])
+	e2 := t[2].Sub(t[0])
+	h := d.Cross(e2)
+	a := e1.Dot(h)
+	if math.Abs(a) < eps*d.Length()*e1.Length()*e2.Length() {
+		// parallel
+		return false
+	}
+	s := p.Sub(t[0])
+	u := s.Dot(h) / a
+	if u <= 0 || u >= 1 {
+		return false
+	}
+	r := s.Cross(e1)
+	v := d.Dot(r) / a
+	if v <= 0 || u+v >= 1 {
+		return false
+	}
+	k := e2.Dot(r) / a
+	return k > 0 && k < 1
+}
+
+// selfIntersections returns the pairs of intersecting faces that share no vertex.
+func selfIntersections(m *Mesh) [][2]int {
+	if len(m.Faces) == 0 {
+		return nil
+	}
+	// hash the face bounding boxes on a grid
+	boxes := make([]sdf.Box3, len(m.Faces))
+	size := 0.0
+	for i := range m.Faces {
+		boxes[i] = m.Triangle(i).BoundingBox()
+		size += boxes[i].Size().MaxComponent()
+	}
+	cell := 2 * size / float64(len(m.Faces))
+	if cell == 0 {
+		return nil
+	}
+	toKey := func(p v3.Vec) v3i.Vec {
+		return v3i.Vec{int(math.Floor(p.X / cell)), int(math.Floor(p.Y / cell)), int(math.Floor(p.Z / cell))}
+	}
+	grid := make(map[v3i.Vec][]int)
+	for i, bb := range boxes {
+		lo, hi := toKey(bb.Min), toKey(bb.Max)
+		for x := lo.X; x <= hi.X; x++ {
+			for y := lo.Y; y <= hi.Y; y++ {
+				for z := lo.Z; z <= hi.Z; z++ {
+					k := v3i.Vec{x, y, z}
+					grid[k] = append(grid[k], i)
+				}
+			}
+		}
+	}
+	tested := make(map[[2]int]bool)
+	var pairs [][2]int
+	for i, f := range m.Faces {
+		lo, hi := toKey(boxes[i].Min), toKey(boxes[i].Max)
+		for x := lo.X; x <= hi.X; x++ {
+			for y := lo.Y; y <= hi.Y; y++ {
+				for z := lo.Z; z <= hi.Z; z++ {
+					for _, j := range grid[v3i.Vec{x, y, z}] {
+						if j <= i || tested[[2]int{i, j}] {
+							continue
+						}
+						tested[[2]int{i, j}] = true
+						g := m.Faces[j]
+						if f[0] == g[0] || f[0] == g[1] || f[0] == g[2] ||
+							f[1] == g[0] || f[1] == g[1] || f[1] == g[2] ||
+							f[2] == g[0] || f[2] == g[1] || f[2] == g[2] {
+							continue
+						}
+						if !overlap3(boxes[i], boxes[j]) {
+							continue
+						}
+						if trianglesIntersect(m.Triangle(i), m.Triangle(j)) {
+							pairs = append(pairs, [2]int{i, j})
+						}
+					}
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// overlap3 returns true if two boxes overlap.
+func overlap3(a, b sdf.Box3) bool {
+	return a.Min.X <= b.Max.X && b.Min.X <= a.Max.X &&
+		a.Min.Y <= b.Max.Y && b.Min.Y <= a.Max.Y &&
+		a.Min.Z <= b.Max.Z && b.Min.Z <= a.Max.Z
+}
+
+// trianglesIntersect returns true if an edge of one triangle crosses the other triangle.
+func trianglesIntersect(a, b *sdf.Triangle3) bool {
+	for i := range a {
+		if segmentTriangle(a[i], a[(i+1)%3], b) || segmentTriangle(b[i], b[(i+1)%3], a) {
+			return true
+		}
+	}
+	return false
+}
+
+//-----------------------------------------------------------------------------
+
+// Validate checks a triangle mesh for degenerate faces, non-manifold edges,
+// holes, flipped normals and self-intersections.
+func Validate(mesh []*sdf.Triangle3) *MeshReport {
+	r := &MeshReport{Triangles: len(mesh)}
+	if len(mesh) == 0 {
+		return r
+	}
+	m, degenerate, repeated := cleanMesh(mesh)
+	r.Vertices = len(m.Vertices)
+	r.Degenerate = degenerate
+	r.Repeated = repeated
+	t := newTopology(m)
+	for k, faces := range t.edges {
+		e := [2]v3.Vec{m.Vertices[k[0]], m.Vertices[k[1]]}
+		switch {
+		case len(faces) == 1:
+			r.BoundaryEdges = append(r.BoundaryEdges, e)
+		case len(faces) > 2:
+			r.NonManifoldEdges = append(r.NonManifoldEdges, e)
+		}
+	}
+	r.Holes = len(t.boundaryLoops())
+	for i, flip := range t.orientation() {
+		if flip {
+			r.Flipped = append(r.Flipped, i)
+		}
+	}
+	r.SelfIntersections = selfIntersections(m)
+	return r
+}
+
+// Repair returns a repaired copy of a triangle mesh. The vertices are welded,
+// degenerate and repeated faces are removed, flipped faces are turned over and
+// holes are filled. Non-manifold edges and self-intersections are not repaired.
+func Repair(mesh []*sdf.Triangle3) []*sdf.Triangle3 {
+	if len(mesh) == 0 {
+		return nil
+	}
+	m, _, _ := cleanMesh(mesh)
+	t := newTopology(m)
+	for i, flip := range t.orientation() {
+		if flip {
+			f := &m.Faces[i]
+			f[1], f[2] = f[2], f[1]
+		}
+	}
+	// fill the holes with a fan from the loop centroid
+	for _, loop := range t.boundaryLoops() {
+		if len(loop) < 3 {
+			continue
+		}
+		if len(loop) == 3 {
+			m.Faces = append(m.Faces, [3]int{loop[0], loop[2], loop[1]})
+			continue
+		}
+		var c v3.Vec
+		for _, v := range loop {
+			c = c.Add(m.Vertices[v])
+		}
+		m.Vertices = append(m.Vertices, c.DivScalar(float64(len(loop))))
+		center := len(m.Vertices) - 1
+		for i, a := range loop {
+			b := loop[(i+1)%len(loop)]
+			// the fill faces use the boundary edges in the opposite direction
+			m.Faces = append(m.Faces, [3]int{b, a, center})
+		}
+	}
+	return m.Triangles()
+}
+
+//-----------------------------------------------------------------------------
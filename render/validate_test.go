@@ -0,0 +1,82 @@
+//-----------------------------------------------------------------------------
+/*
+
+Mesh Validation and Repair Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func testSphereMesh(r float64, center v3.Vec) []*sdf.Triangle3 {
+	s, _ := sdf.Sphere3D(r)
+	s = sdf.Transform3D(s, sdf.Translate3d(center))
+	return ToTriangles(s, NewMarchingCubesUniform(20))
+}
+
+func Test_Validate(t *testing.T) {
+	mesh := testSphereMesh(10, v3.Vec{})
+	if r := Validate(mesh); !r.OK() {
+		t.Fatalf("sphere: %s", r)
+	}
+
+	// holes
+	holes := append([]*sdf.Triangle3{}, mesh[10:]...)
+	holes = append(holes[:200], holes[201:]...)
+	r := Validate(holes)
+	if r.Watertight() || r.Holes == 0 {
+		t.Errorf("holes: %s", r)
+	}
+	if r := Validate(Repair(holes)); !r.OK() {
+		t.Errorf("repaired holes: %s", r)
+	}
+
+	// flipped faces
+	flipped := make([]*sdf.Triangle3, len(mesh))
+	for i, x := range mesh {
+		flipped[i] = x
+		if i%50 == 0 {
+			flipped[i] = &sdf.Triangle3{x[0], x[2], x[1]}
+		}
+	}
+	r = Validate(flipped)
+	if n := (len(mesh) + 49) / 50; len(r.Flipped) != n {
+		t.Errorf("expected %d flipped faces: %s", n, r)
+	}
+	if r := Validate(Repair(flipped)); !r.OK() {
+		t.Errorf("repaired flipped faces: %s", r)
+	}
+
+	// inside out
+	inverted := make([]*sdf.Triangle3, len(mesh))
+	for i, x := range mesh {
+		inverted[i] = &sdf.Triangle3{x[0], x[2], x[1]}
+	}
+	if r := Validate(inverted); len(r.Flipped) != len(mesh) {
+		t.Errorf("inside out: %s", r)
+	}
+
+	// overlapping shells
+	overlap := append(testSphereMesh(10, v3.Vec{}), testSphereMesh(10, v3.Vec{5.3, 0.7, 0.2})...)
+	if r := Validate(overlap); len(r.SelfIntersections) == 0 || !r.Watertight() {
+		t.Errorf("overlapping shells: %s", r)
+	}
+
+	// non-manifold edge and repeated face
+	extra := append(append([]*sdf.Triangle3{}, mesh...), &sdf.Triangle3{mesh[0][0], mesh[0][1], v3.Vec{0, 0, 0}}, mesh[1])
+	r = Validate(extra)
+	if len(r.NonManifoldEdges) != 1 || r.Repeated != 1 {
+		t.Errorf("non-manifold: %s", r)
+	}
+}
+
+//-----------------------------------------------------------------------------
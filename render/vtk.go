@@ -0,0 +1,474 @@
+//-----------------------------------------------------------------------------
+/*
+
+VTK Output
+
+Write sampled SDF values, gradients and analysis fields for inspection with
+ParaView (or any VTK based tool).
+
+Grids: The SDF3 is sampled on a regular grid covering its bounding box. The
+point data is the signed distance, the gradient of the distance and any
+extra scalar fields.
+
+Narrow-band grids: Only the stored samples of a GridSDF3 are written, with
+a hexahedral cell where all 8 corners are stored. The point data is the
+signed distance and any extra scalar fields.
+
+Meshes: The vertices of a triangle mesh are welded and any extra scalar
+fields are sampled at the vertices (e.g. to check FE inputs).
+
+Two formats are supported:
+
+* Legacy VTK (.vtk): ASCII structured points (grids) or polydata (meshes).
+* VTK XML unstructured grids (.vtu): ASCII, with hexahedral cells for grids
+and triangles for meshes.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// VTKField is a named scalar field written as point data.
+type VTKField struct {
+	Name  string
+	Field sdf.ScalarField
+}
+
+// VTK cell types
+const (
+	vtkTriangle   = 5
+	vtkHexahedron = 12
+)
+
+// vtkData is a data set with point data.
+type vtkData struct {
+	points   []v3.Vec
+	dims     v3i.Vec // grid dimensions (points), zero for meshes
+	origin   v3.Vec  // grid origin
+	spacing  float64 // grid spacing
+	cells    [][]int // point indices per cell (meshes)
+	cellType int
+	scalars  []vtkScalars
+	vectors  []vtkVectors
+}
+
+// vtkScalars is a named scalar value per point.
+type vtkScalars struct {
+	name   string
+	values []float64
+}
+
+// vtkVectors is a named vector value per point.
+type vtkVectors struct {
+	name   string
+	values []v3.Vec
+}
+
+// addFields samples scalar fields at the points.
+func (d *vtkData) addFields(fields []VTKField) {
+	for _, f := range fields {
+		x := make([]float64, len(d.points))
+		for i, p := range d.points {
+			x[i] = f.Field(p)
+		}
+		d.scalars = append(d.scalars, vtkScalars{f.Name, x})
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// gradient returns the (unnormalized) gradient of an SDF3 at a point.
+func gradient(s sdf.SDF3, p v3.Vec, eps float64) v3.Vec {
+	return v3.Vec{
+		X: s.Evaluate(p.Add(v3.Vec{X: eps})) - s.Evaluate(p.Add(v3.Vec{X: -eps})),
+		Y: s.Evaluate(p.Add(v3.Vec{Y: eps})) - s.Evaluate(p.Add(v3.Vec{Y: -eps})),
+		Z: s.Evaluate(p.Add(v3.Vec{Z: eps})) - s.Evaluate(p.Add(v3.Vec{Z: -eps})),
+	}.DivScalar(2 * eps)
+}
+
+// vtkGrid samples an SDF3 on a grid with cells along the longest side of the bounding box.
+func vtkGrid(s sdf.SDF3, cells int, fields []VTKField) (*vtkData, error) {
+	if cells <= 0 {
+		return nil, sdf.ErrMsg("cells <= 0")
+	}
+	bb := s.BoundingBox()
+	size := bb.Size()
+	step := size.MaxComponent() / float64(cells)
+	// add a cell of margin so the surface is enclosed
+	bb = bb.Enlarge(v3.Vec{2 * step, 2 * step, 2 * step})
+	size = bb.Size()
+	dims := v3i.Vec{
+		int(math.Ceil(size.X/step)) + 1,
+		int(math.Ceil(size.Y/step)) + 1,
+		int(math.Ceil(size.Z/step)) + 1,
+	}
+	d := &vtkData{
+		dims:     dims,
+		origin:   bb.Min,
+		spacing:  step,
+		cellType: vtkHexahedron,
+	}
+	n := dims.X * dims.Y * dims.Z
+	d.points = make([]v3.Vec, 0, n)
+	for k := 0; k < dims.Z; k++ {
+		for j := 0; j < dims.Y; j++ {
+			for i := 0; i < dims.X; i++ {
+				d.points = append(d.points, bb.Min.Add(v3.Vec{float64(i), float64(j), float64(k)}.MulScalar(step)))
+			}
+		}
+	}
+	dist := make([]float64, n)
+	grad := make([]v3.Vec, n)
+	eps := step * 1e-3
+	for i, p := range d.points {
+		dist[i] = s.Evaluate(p)
+		grad[i] = gradient(s, p, eps)
+	}
+	d.scalars = append(d.scalars, vtkScalars{"distance", dist})
+	d.vectors = append(d.vectors, vtkVectors{"gradient", grad})
+	d.addFields(fields)
+	return d, nil
+}
+
+// vtkSparse returns the stored samples of a narrow-band grid, and the cells between them.
+func vtkSparse(g *sdf.GridSDF3, fields []VTKField) (*vtkData, error) {
+	if g.Blocks() == 0 {
+		return nil, sdf.ErrMsg("empty grid")
+	}
+	type sample struct {
+		i v3i.Vec
+		p v3.Vec
+		d float64
+	}
+	var samples []sample
+	g.Samples(func(i v3i.Vec, p v3.Vec, d float64) {
+		samples = append(samples, sample{i, p, d})
+	})
+	// sort for a repeatable output
+	sort.Slice(samples, func(a, b int) bool {
+		i, j := samples[a].i, samples[b].i
+		if i.Z != j.Z {
+			return i.Z < j.Z
+		}
+		if i.Y != j.Y {
+			return i.Y < j.Y
+		}
+		return i.X < j.X
+	})
+	d := &vtkData{cellType: vtkHexahedron}
+	index := make(map[v3i.Vec]int, len(samples))
+	dist := make([]float64, len(samples))
+	for n, x := range samples {
+		index[x.i] = n
+		d.points = append(d.points, x.p)
+		dist[n] = x.d
+	}
+	corners := []v3i.Vec{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}, {0, 0, 1}, {1, 0, 1}, {1, 1, 1}, {0, 1, 1}}
+	for _, x := range samples {
+		cell := make([]int, 0, 8)
+		for _, c := range corners {
+			n, ok := index[x.i.Add(c)]
+			if !ok {
+				break
+			}
+			cell = append(cell, n)
+		}
+		if len(cell) == 8 {
+			d.cells = append(d.cells, cell)
+		}
+	}
+	d.scalars = append(d.scalars, vtkScalars{"distance", dist})
+	d.addFields(fields)
+	return d, nil
+}
+
+// gridCells returns the hexahedral cells of a grid.
+func (d *vtkData) gridCells() [][]int {
+	idx := func(i, j, k int) int { return i + d.dims.X*(j+d.dims.Y*k) }
+	var cells [][]int
+	for k := 0; k < d.dims.Z-1; k++ {
+		for j := 0; j < d.dims.Y-1; j++ {
+			for i := 0; i < d.dims.X-1; i++ {
+				cells = append(cells, []int{
+					idx(i, j, k), idx(i+1, j, k), idx(i+1, j+1, k), idx(i, j+1, k),
+					idx(i, j, k+1), idx(i+1, j, k+1), idx(i+1, j+1, k+1), idx(i, j+1, k+1),
+				})
+			}
+		}
+	}
+	return cells
+}
+
+// vtkMesh welds the vertices of a triangle mesh.
+func vtkMesh(mesh []*sdf.Triangle3, fields []VTKField) (*vtkData, error) {
+	if len(mesh) == 0 {
+		return nil, sdf.ErrMsg("empty mesh")
+	}
+	d := &vtkData{cellType: vtkTriangle}
+	index := make(map[v3.Vec]int)
+	for _, t := range mesh {
+		cell := make([]int, 3)
+		for i, v := range t {
+			j, ok := index[v]
+			if !ok {
+				j = len(d.points)
+				index[v] = j
+				d.points = append(d.points, v)
+			}
+			cell[i] = j
+		}
+		d.cells = append(d.cells, cell)
+	}
+	d.addFields(fields)
+	return d, nil
+}
+
+//-----------------------------------------------------------------------------
+// Legacy VTK
+
+// writeLegacy writes a data set as an ASCII legacy VTK file.
+func (d *vtkData) writeLegacy(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# vtk DataFile Version 3.0\nsdfx\nASCII\n")
+	if d.dims.X > 0 {
+		fmt.Fprintf(bw, "DATASET STRUCTURED_POINTS\n")
+		fmt.Fprintf(bw, "DIMENSIONS %d %d %d\n", d.dims.X, d.dims.Y, d.dims.Z)
+		fmt.Fprintf(bw, "ORIGIN %g %g %g\n", d.origin.X, d.origin.Y, d.origin.Z)
+		fmt.Fprintf(bw, "SPACING %g %g %g\n", d.spacing, d.spacing, d.spacing)
+	} else {
+		fmt.Fprintf(bw, "DATASET POLYDATA\n")
+		fmt.Fprintf(bw, "POINTS %d double\n", len(d.points))
+		for _, p := range d.points {
+			fmt.Fprintf(bw, "%g %g %g\n", p.X, p.Y, p.Z)
+		}
+		size := 0
+		for _, c := range d.cells {
+			size += len(c) + 1
+		}
+		fmt.Fprintf(bw, "POLYGONS %d %d\n", len(d.cells), size)
+		for _, c := range d.cells {
+			fmt.Fprintf(bw, "%d", len(c))
+			for _, i := range c {
+				fmt.Fprintf(bw, " %d", i)
+			}
+			fmt.Fprintf(bw, "\n")
+		}
+	}
+	if len(d.scalars)+len(d.vectors) > 0 {
+		fmt.Fprintf(bw, "POINT_DATA %d\n", len(d.points))
+	}
+	for _, s := range d.scalars {
+		fmt.Fprintf(bw, "SCALARS %s double 1\nLOOKUP_TABLE default\n", s.name)
+		for _, x := range s.values {
+			fmt.Fprintf(bw, "%g\n", x)
+		}
+	}
+	for _, v := range d.vectors {
+		fmt.Fprintf(bw, "VECTORS %s double\n", v.name)
+		for _, x := range v.values {
+			fmt.Fprintf(bw, "%g %g %g\n", x.X, x.Y, x.Z)
+		}
+	}
+	return bw.Flush()
+}
+
+//-----------------------------------------------------------------------------
+// VTK XML Unstructured Grid
+
+// writeVTU writes a data set as an ASCII VTK XML unstructured grid.
+func (d *vtkData) writeVTU(w io.Writer) error {
+	cells := d.cells
+	if d.dims.X > 0 {
+		cells = d.gridCells()
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<?xml version=\"1.0\"?>\n")
+	fmt.Fprintf(bw, "<VTKFile type=\"UnstructuredGrid\" version=\"1.0\" byte_order=\"LittleEndian\" header_type=\"UInt64\">\n")
+	fmt.Fprintf(bw, "<UnstructuredGrid>\n")
+	fmt.Fprintf(bw, "<Piece NumberOfPoints=\"%d\" NumberOfCells=\"%d\">\n", len(d.points), len(cells))
+	// point data
+	fmt.Fprintf(bw, "<PointData")
+	if len(d.scalars) > 0 {
+		fmt.Fprintf(bw, " Scalars=\"%s\"", d.scalars[0].name)
+	}
+	if len(d.vectors) > 0 {
+		fmt.Fprintf(bw, " Vectors=\"%s\"", d.vectors[0].name)
+	}
+	fmt.Fprintf(bw, ">\n")
+	for _, s := range d.scalars {
+		fmt.Fprintf(bw, "<DataArray type=\"Float64\" Name=\"%s\" format=\"ascii\">\n", s.name)
+		for _, x := range s.values {
+			fmt.Fprintf(bw, "%g\n", x)
+		}
+		fmt.Fprintf(bw, "</DataArray>\n")
+	}
+	for _, v := range d.vectors {
+		fmt.Fprintf(bw, "<DataArray type=\"Float64\" Name=\"%s\" NumberOfComponents=\"3\" format=\"ascii\">\n", v.name)
+		for _, x := range v.values {
+			fmt.Fprintf(bw, "%g %g %g\n", x.X, x.Y, x.Z)
+		}
+		fmt.Fprintf(bw, "</DataArray>\n")
+	}
+	fmt.Fprintf(bw, "</PointData>\n")
+	// points
+	fmt.Fprintf(bw, "<Points>\n<DataArray type=\"Float64\" NumberOfComponents=\"3\" format=\"ascii\">\n")
+	for _, p := range d.points {
+		fmt.Fprintf(bw, "%g %g %g\n", p.X, p.Y, p.Z)
+	}
+	fmt.Fprintf(bw, "</DataArray>\n</Points>\n")
+	// cells
+	fmt.Fprintf(bw, "<Cells>\n<DataArray type=\"Int64\" Name=\"connectivity\" format=\"ascii\">\n")
+	for _, c := range cells {
+		for i, j := range c {
+			if i > 0 {
+				fmt.Fprintf(bw, " ")
+			}
+			fmt.Fprintf(bw, "%d", j)
+		}
+		fmt.Fprintf(bw, "\n")
+	}
+	fmt.Fprintf(bw, "</DataArray>\n<DataArray type=\"Int64\" Name=\"offsets\" format=\"ascii\">\n")
+	ofs := 0
+	for _, c := range cells {
+		ofs += len(c)
+		fmt.Fprintf(bw, "%d\n", ofs)
+	}
+	fmt.Fprintf(bw, "</DataArray>\n<DataArray type=\"UInt8\" Name=\"types\" format=\"ascii\">\n")
+	for range cells {
+		fmt.Fprintf(bw, "%d\n", d.cellType)
+	}
+	fmt.Fprintf(bw, "</DataArray>\n</Cells>\n")
+	fmt.Fprintf(bw, "</Piece>\n</UnstructuredGrid>\n</VTKFile>\n")
+	return bw.Flush()
+}
+
+//-----------------------------------------------------------------------------
+
+// saveVTK writes a data set to a file, using a write function.
+func saveVTK(path string, d *vtkData, write func(*vtkData, io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(d, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// SaveVTKGrid samples an SDF3 on a grid (cells along the longest side) and
+// writes the distance, gradient and extra fields to a legacy VTK file.
+func SaveVTKGrid(path string, s sdf.SDF3, cells int, fields ...VTKField) error {
+	d, err := vtkGrid(s, cells, fields)
+	if err != nil {
+		return err
+	}
+	return saveVTK(path, d, (*vtkData).writeLegacy)
+}
+
+// SaveVTUGrid samples an SDF3 on a grid (cells along the longest side) and
+// writes the distance, gradient and extra fields to a VTK XML unstructured grid file.
+func SaveVTUGrid(path string, s sdf.SDF3, cells int, fields ...VTKField) error {
+	d, err := vtkGrid(s, cells, fields)
+	if err != nil {
+		return err
+	}
+	return saveVTK(path, d, (*vtkData).writeVTU)
+}
+
+// SaveVTUSparse writes the stored samples of a narrow-band grid, with the
+// distance and extra fields, to a VTK XML unstructured grid file.
+func SaveVTUSparse(path string, g *sdf.GridSDF3, fields ...VTKField) error {
+	d, err := vtkSparse(g, fields)
+	if err != nil {
+		return err
+	}
+	return saveVTK(path, d, (*vtkData).writeVTU)
+}
+
+// WriteVTKGrid samples an SDF3 on a grid (cells along the longest side) and
+// writes the distance, gradient and extra fields as a legacy VTK file to a writer.
+func WriteVTKGrid(w io.Writer, s sdf.SDF3, cells int, fields ...VTKField) error {
+	d, err := vtkGrid(s, cells, fields)
+	if err != nil {
+		return err
+	}
+	return d.writeLegacy(w)
+}
+
+// WriteVTUGrid samples an SDF3 on a grid (cells along the longest side) and
+// writes the distance, gradient and extra fields as a VTK XML unstructured grid to a writer.
+func WriteVTUGrid(w io.Writer, s sdf.SDF3, cells int, fields ...VTKField) error {
+	d, err := vtkGrid(s, cells, fields)
+	if err != nil {
+		return err
+	}
+	return d.writeVTU(w)
+}
+
+// WriteVTUSparse writes the stored samples of a narrow-band grid, with the
+// distance and extra fields, as a VTK XML unstructured grid to a writer.
+func WriteVTUSparse(w io.Writer, g *sdf.GridSDF3, fields ...VTKField) error {
+	d, err := vtkSparse(g, fields)
+	if err != nil {
+		return err
+	}
+	return d.writeVTU(w)
+}
+
+// SaveVTKMesh writes a triangle mesh and fields sampled at its vertices to a legacy VTK file.
+func SaveVTKMesh(path string, mesh []*sdf.Triangle3, fields ...VTKField) error {
+	d, err := vtkMesh(mesh, fields)
+	if err != nil {
+		return err
+	}
+	return saveVTK(path, d, (*vtkData).writeLegacy)
+}
+
+// SaveVTUMesh writes a triangle mesh and fields sampled at its vertices to a
+// VTK XML unstructured grid file.
+func SaveVTUMesh(path string, mesh []*sdf.Triangle3, fields ...VTKField) error {
+	d, err := vtkMesh(mesh, fields)
+	if err != nil {
+		return err
+	}
+	return saveVTK(path, d, (*vtkData).writeVTU)
+}
+
+// WriteVTKMesh writes a triangle mesh and fields sampled at its vertices as a
+// legacy VTK file to a writer.
+func WriteVTKMesh(w io.Writer, mesh []*sdf.Triangle3, fields ...VTKField) error {
+	d, err := vtkMesh(mesh, fields)
+	if err != nil {
+		return err
+	}
+	return d.writeLegacy(w)
+}
+
+// WriteVTUMesh writes a triangle mesh and fields sampled at its vertices as a
+// VTK XML unstructured grid to a writer.
+func WriteVTUMesh(w io.Writer, mesh []*sdf.Triangle3, fields ...VTKField) error {
+	d, err := vtkMesh(mesh, fields)
+	if err != nil {
+		return err
+	}
+	return d.writeVTU(w)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,102 @@
+//-----------------------------------------------------------------------------
+/*
+
+VTK Output Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/render/fe"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_VTK(t *testing.T) {
+	s, err := sdf.Sphere3D(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	height := VTKField{"height", func(p v3.Vec) float64 { return p.Z }}
+
+	// grid: read it back as an FE result
+	d, err := vtkGrid(s, 10, []VTKField{height})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := d.writeLegacy(&buf); err != nil {
+		t.Fatal(err)
+	}
+	r, err := fe.ReadVTK(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Nodes) != 13*13*13 {
+		t.Fatalf("expected %d points, got %d", 13*13*13, len(r.Nodes))
+	}
+	for i, p := range r.Nodes {
+		if x := r.Fields["distance"][i]; math.Abs(x-s.Evaluate(p)) > 1e-4 {
+			t.Fatalf("%v: expected distance %f, got %f", p, s.Evaluate(p), x)
+		}
+		if x := r.Fields["height"][i]; math.Abs(x-p.Z) > 1e-4 {
+			t.Fatalf("%v: expected height %f, got %f", p, p.Z, x)
+		}
+		// the gradient of an exact sdf has unit length
+		if x := r.Fields["gradient"][i]; p.Length() > 1 && math.Abs(x-1) > 1e-3 {
+			t.Fatalf("%v: expected unit gradient, got %f", p, x)
+		}
+	}
+
+	// mesh: welded vertices
+	mesh := ToTriangles(s, NewMarchingCubesUniform(20))
+	d, err = vtkMesh(mesh, []VTKField{height})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.points) >= 3*len(mesh) {
+		t.Error("mesh vertices are not welded")
+	}
+	buf.Reset()
+	if err := d.writeVTU(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "NumberOfCells=\"") || strings.Count(buf.String(), "<DataArray") != 5 {
+		t.Error("bad vtu output")
+	}
+
+	// narrow-band grid: only the stored samples
+	g, err := sdf.Voxelize(s, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err = vtkSparse(g, []VTKField{height})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.cells) == 0 || len(d.points) >= g.DenseBytes()/4 {
+		t.Errorf("expected sparse samples, got %d points and %d cells", len(d.points), len(d.cells))
+	}
+	for i, p := range d.points {
+		if x := d.scalars[0].values[i]; math.Abs(x-s.Evaluate(p)) > 1e-5 {
+			t.Fatalf("%v: expected distance %f, got %f", p, s.Evaluate(p), x)
+		}
+	}
+	for _, c := range d.cells {
+		// opposite corners of a cell
+		if x := d.points[c[6]].Sub(d.points[c[0]]); math.Abs(x.X-0.5) > 1e-9 || math.Abs(x.Y-0.5) > 1e-9 || math.Abs(x.Z-0.5) > 1e-9 {
+			t.Fatalf("bad cell %v", x)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,115 @@
+//-----------------------------------------------------------------------------
+/*
+
+io.Writer Export and io.ReaderAt Import Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_MeshWriters(t *testing.T) {
+	s, _ := sdf.Box3D(v3.Vec{X: 10, Y: 20, Z: 30}, 0)
+	r := NewMarchingCubesOctree(20)
+	mesh := ToTriangles(s, r)
+	bb := s.BoundingBox()
+
+	tests := []struct {
+		ext   string
+		write func(w *bytes.Buffer) error
+	}{
+		{".stl", func(w *bytes.Buffer) error { return WriteSTL(w, mesh, ExportOptions{}) }},
+		{".stl", func(w *bytes.Buffer) error { return WriteSTLASCII(w, mesh, ExportOptions{}) }},
+		{".stl", func(w *bytes.Buffer) error { return ToSTLWriter(s, w, r, ExportOptions{Guard: true}) }},
+		{".3mf", func(w *bytes.Buffer) error { return To3MFWriter(s, w, r, ExportOptions{}) }},
+		{".obj", func(w *bytes.Buffer) error { return WriteOBJ(w, mesh) }},
+		{".step", func(w *bytes.Buffer) error { return WriteSTEP(w, mesh, STEPOptions{}) }},
+		{".step", func(w *bytes.Buffer) error { return ToSTEPWriter(s, w, r, STEPOptions{}) }},
+	}
+	for i, test := range tests {
+		var buf bytes.Buffer
+		if err := test.write(&buf); err != nil {
+			t.Fatalf("%d%s: %s", i, test.ext, err)
+		}
+		data := buf.Bytes()
+		out, err := ReadMesh(bytes.NewReader(data), int64(len(data)), test.ext)
+		if err != nil {
+			t.Fatalf("%d%s: %s", i, test.ext, err)
+		}
+		if len(out) == 0 {
+			t.Fatalf("%d%s: no triangles", i, test.ext)
+		}
+		rb := out[0].BoundingBox()
+		for _, x := range out {
+			rb = rb.Extend(x.BoundingBox())
+		}
+		if !rb.Min.Equals(bb.Min, 1e-4) || !rb.Max.Equals(bb.Max, 1e-4) {
+			t.Errorf("%d%s: expected bounding box %v, got %v", i, test.ext, bb, rb)
+		}
+	}
+
+	// the writers write the same files as the savers
+	dir := t.TempDir()
+	path := filepath.Join(dir, "box.stl")
+	if err := SaveSTL(path, mesh); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := os.ReadFile(path)
+	var buf bytes.Buffer
+	WriteSTL(&buf, mesh, ExportOptions{})
+	if !bytes.Equal(saved, buf.Bytes()) {
+		t.Error("SaveSTL and WriteSTL differ")
+	}
+
+	if _, err := ReadMesh(bytes.NewReader(nil), 0, ".ply"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func Test_2DWriters(t *testing.T) {
+	s, _ := sdf.Circle2D(5)
+	r := NewMarchingSquaresQuadtree(50)
+	var buf bytes.Buffer
+	if err := ToDXFWriter(s, &buf, r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "LINE") {
+		t.Error("expected DXF lines")
+	}
+	buf.Reset()
+	if err := ToSVGWriter(s, &buf, r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<line") {
+		t.Error("expected SVG lines")
+	}
+	buf.Reset()
+	if err := ToSVGWriterWithOptions(s, &buf, r, SVGOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<path") {
+		t.Error("expected an SVG path")
+	}
+	buf.Reset()
+	if err := ToPNGWriter(s, &buf, ImageOptions{Width: 64}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("\x89PNG")) {
+		t.Error("expected a PNG")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,219 @@
+//-----------------------------------------------------------------------------
+/*
+
+Script Lexer
+
+*/
+//-----------------------------------------------------------------------------
+
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//-----------------------------------------------------------------------------
+
+// token types
+const (
+	tokEOF = iota
+	tokName
+	tokNumber
+	tokString
+	tokKeyword
+	tokOp
+)
+
+// token is a lexical token.
+type token struct {
+	kind int
+	text string  // name, keyword, operator or string value
+	num  float64 // number value
+	line int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of file"
+	case tokNumber:
+		return strconv.FormatFloat(t.num, 'g', -1, 64)
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return "'" + t.text + "'"
+}
+
+var keywords = map[string]bool{
+	"and": true, "break": true, "do": true, "else": true, "elseif": true,
+	"end": true, "false": true, "for": true, "function": true, "if": true,
+	"in": true, "local": true, "nil": true, "not": true, "or": true,
+	"return": true, "then": true, "true": true, "while": true,
+}
+
+// operators, longest first
+var operators = []string{
+	"==", "~=", "<=", ">=", "..",
+	"+", "-", "*", "/", "%", "^", "#", "<", ">", "=",
+	"(", ")", "{", "}", "[", "]", ";", ":", ",", ".",
+}
+
+// lexer splits a script into tokens.
+type lexer struct {
+	name string // script name for error messages
+	src  string
+	pos  int
+	line int
+}
+
+// errorf returns an error at a line of the script.
+func (l *lexer) errorf(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d: %s", l.name, line, fmt.Sprintf(format, args...))
+}
+
+// skip skips white space and comments.
+func (l *lexer) skip() error {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "--[["):
+			end := strings.Index(l.src[l.pos:], "]]")
+			if end < 0 {
+				return l.errorf(l.line, "unterminated comment")
+			}
+			l.line += strings.Count(l.src[l.pos:l.pos+end], "\n")
+			l.pos += end + 2
+		case strings.HasPrefix(l.src[l.pos:], "--"):
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// next returns the next token.
+func (l *lexer) next() (token, error) {
+	if err := l.skip(); err != nil {
+		return token{}, err
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, line: l.line}, nil
+	}
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case isLetter(c):
+		for l.pos < len(l.src) && (isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		s := l.src[start:l.pos]
+		if keywords[s] {
+			return token{kind: tokKeyword, text: s, line: l.line}, nil
+		}
+		return token{kind: tokName, text: s, line: l.line}, nil
+	case isDigit(c) || (c == '.' && l.pos+1 < len(l.src) && isDigit(l.src[l.pos+1])):
+		for l.pos < len(l.src) {
+			c := l.src[l.pos]
+			if isDigit(c) || isLetter(c) || c == '.' {
+				l.pos++
+			} else if (c == '+' || c == '-') && (l.src[l.pos-1] == 'e' || l.src[l.pos-1] == 'E') && !strings.HasPrefix(l.src[start:], "0x") {
+				l.pos++
+			} else {
+				break
+			}
+		}
+		s := l.src[start:l.pos]
+		var x float64
+		var err error
+		if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+			var n int64
+			n, err = strconv.ParseInt(s[2:], 16, 64)
+			x = float64(n)
+		} else {
+			x, err = strconv.ParseFloat(s, 64)
+		}
+		if err != nil {
+			return token{}, l.errorf(l.line, "bad number \"%s\"", s)
+		}
+		return token{kind: tokNumber, num: x, line: l.line}, nil
+	case c == '"' || c == '\'':
+		return l.str(c)
+	}
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokOp, text: op, line: l.line}, nil
+		}
+	}
+	return token{}, l.errorf(l.line, "unexpected character '%c'", c)
+}
+
+// str returns a quoted string token.
+func (l *lexer) str(q byte) (token, error) {
+	var sb strings.Builder
+	line := l.line
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		c := l.src[l.pos]
+		switch c {
+		case q:
+			l.pos++
+			return token{kind: tokString, text: sb.String(), line: line}, nil
+		case '\n':
+			return token{}, l.errorf(line, "unterminated string")
+		case '\\':
+			l.pos++
+			if l.pos >= len(l.src) {
+				return token{}, l.errorf(line, "unterminated string")
+			}
+			switch e := l.src[l.pos]; e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case '\\', '"', '\'':
+				sb.WriteByte(e)
+			default:
+				return token{}, l.errorf(line, "bad escape \"\\%c\"", e)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return token{}, l.errorf(line, "unterminated string")
+}
+
+// tokens returns the tokens of a script.
+func tokens(name, src string) ([]token, error) {
+	l := &lexer{name: name, src: src, line: 1}
+	var toks []token
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		toks = append(toks, t)
+		if t.kind == tokEOF {
+			return toks, nil
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,728 @@
+//-----------------------------------------------------------------------------
+/*
+
+Script Library
+
+The functions available to scripts. Angles are in degrees, sizes are full
+sizes (as for the sdf package), and shapes are centered on the origin.
+
+Base:
+
+	print(...), type(v), tostring(v), tonumber(s), error(msg), assert(v, msg)
+	ipairs(t), pairs(t), math.*, format(fmt, ...)
+	param(name, default): a model parameter, set by the runner (-D name=value)
+
+2D shapes (sdf2):
+
+	circle(r), rect(w, h, [round]), polygon({{x, y}, ...})
+
+3D shapes (sdf3):
+
+	sphere(r), box(w, d, h, [round]), cylinder(h, r, [round]),
+	cone(h, r0, r1, [round]), capsule(h, r)
+	extrude(s2, h, [round]), revolve(s2, [angle])
+
+Booleans (sdf2 or sdf3):
+
+	union(a, b, ...), difference(a, b, ...), intersect(a, b, ...)
+	smooth_union(k, a, b, ...), smooth_difference(k, a, b),
+	smooth_intersect(k, a, b)
+
+The shapes can also be given as a table: union(parts).
+
+Transforms (sdf2: x, y; sdf3: x, y, z):
+
+	translate(s, x, y, [z]), scale(s, k), rotate(s, angle) (sdf2),
+	rotate_x(s, angle), rotate_y(s, angle), rotate_z(s, angle),
+	mirror_x(s), mirror_y(s), mirror_z(s)
+	offset(s, d), shell(s, t), bbox(s) -> {min = {x, y, z}, max = {...}}
+
+The functions with a shape as the first argument are also methods:
+s:translate(1, 2, 3) is translate(s, 1, 2, 3).
+
+*/
+//-----------------------------------------------------------------------------
+
+package script
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+// Arguments
+
+// args are the arguments of a builtin call.
+type args []Value
+
+// get returns argument i (from 0), or nil.
+func (a args) get(i int) Value {
+	if i < len(a) {
+		return a[i]
+	}
+	return nil
+}
+
+// badArg returns an error for a bad argument.
+func badArg(i int, expected string, v Value) error {
+	return fmt.Errorf("bad argument #%d (%s expected, got %s)", i+1, expected, typeName(v))
+}
+
+// num returns a number argument.
+func (a args) num(i int) (float64, error) {
+	x, ok := a.get(i).(float64)
+	if !ok {
+		return 0, badArg(i, "number", a.get(i))
+	}
+	return x, nil
+}
+
+// optNum returns an optional number argument.
+func (a args) optNum(i int, def float64) (float64, error) {
+	if a.get(i) == nil {
+		return def, nil
+	}
+	return a.num(i)
+}
+
+// nums returns number arguments from i.
+func (a args) nums(i, n int) ([]float64, error) {
+	x := make([]float64, n)
+	for j := range x {
+		var err error
+		if x[j], err = a.num(i + j); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+// str returns a string argument.
+func (a args) str(i int) (string, error) {
+	x, ok := a.get(i).(string)
+	if !ok {
+		return "", badArg(i, "string", a.get(i))
+	}
+	return x, nil
+}
+
+// table returns a table argument.
+func (a args) table(i int) (*Table, error) {
+	x, ok := a.get(i).(*Table)
+	if !ok {
+		return nil, badArg(i, "table", a.get(i))
+	}
+	return x, nil
+}
+
+// sdf2 returns an sdf2 argument.
+func (a args) sdf2(i int) (sdf.SDF2, error) {
+	x, ok := a.get(i).(sdf.SDF2)
+	if !ok {
+		return nil, badArg(i, "sdf2", a.get(i))
+	}
+	return x, nil
+}
+
+// shapes returns the shape arguments from i, or the items of a table argument.
+// The shapes must all be sdf2 or all sdf3.
+func (a args) shapes(i int) ([]sdf.SDF2, []sdf.SDF3, error) {
+	list := a[min(i, len(a)):]
+	if t, ok := a.get(i).(*Table); ok && len(a) == i+1 {
+		list = t.items
+	}
+	var s2 []sdf.SDF2
+	var s3 []sdf.SDF3
+	for j, v := range list {
+		switch x := v.(type) {
+		case sdf.SDF2:
+			s2 = append(s2, x)
+		case sdf.SDF3:
+			s3 = append(s3, x)
+		default:
+			return nil, nil, badArg(i+j, "sdf2 or sdf3", v)
+		}
+	}
+	if len(s2) > 0 && len(s3) > 0 {
+		return nil, nil, fmt.Errorf("mixed sdf2 and sdf3 arguments")
+	}
+	if len(s2)+len(s3) == 0 {
+		return nil, nil, fmt.Errorf("no shapes")
+	}
+	return s2, s3, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// radians converts an angle in degrees to radians.
+func radians(a float64) float64 {
+	return a * sdf.Pi / 180
+}
+
+// methods are the library functions that can be called as shape methods.
+var methods = map[string]bool{
+	"translate": true, "scale": true, "rotate": true,
+	"rotate_x": true, "rotate_y": true, "rotate_z": true,
+	"mirror_x": true, "mirror_y": true, "mirror_z": true,
+	"offset": true, "shell": true, "bbox": true,
+	"extrude": true, "revolve": true,
+	"union": true, "difference": true, "intersect": true,
+}
+
+// fn makes a builtin.
+func fn(name string, f func(in *interp, a args) (Value, error)) *builtin {
+	return &builtin{name, func(in *interp, a []Value) (Value, error) { return f(in, a) }}
+}
+
+// shape3 makes a builtin returning an sdf3 from number arguments.
+func shape3(name string, n int, f func(x []float64) (sdf.SDF3, error)) *builtin {
+	return fn(name, func(in *interp, a args) (Value, error) {
+		x, err := a.nums(0, n)
+		if err != nil {
+			return nil, err
+		}
+		return f(x)
+	})
+}
+
+// transform makes a builtin with a shape and a number of number arguments.
+func transform(name string, n int, f2 func(s sdf.SDF2, x []float64) (sdf.SDF2, error), f3 func(s sdf.SDF3, x []float64) (sdf.SDF3, error)) *builtin {
+	return fn(name, func(in *interp, a args) (Value, error) {
+		switch s := a.get(0).(type) {
+		case sdf.SDF2:
+			if f2 == nil {
+				return nil, badArg(0, "sdf3", s)
+			}
+			x, err := a.nums(1, n)
+			if err != nil {
+				return nil, err
+			}
+			return f2(s, x)
+		case sdf.SDF3:
+			if f3 == nil {
+				return nil, badArg(0, "sdf2", s)
+			}
+			x, err := a.nums(1, n)
+			if err != nil {
+				return nil, err
+			}
+			return f3(s, x)
+		}
+		return nil, badArg(0, "sdf2 or sdf3", a.get(0))
+	})
+}
+
+// library is the global functions of a script.
+var library []*builtin
+
+func init() {
+	library = []*builtin{
+		// base
+		fn("print", func(in *interp, a args) (Value, error) {
+			s := make([]string, len(a))
+			for i, v := range a {
+				s[i] = toString(v)
+			}
+			fmt.Fprintln(in.out, strings.Join(s, "\t"))
+			return nil, nil
+		}),
+		fn("type", func(in *interp, a args) (Value, error) {
+			return typeName(a.get(0)), nil
+		}),
+		fn("tostring", func(in *interp, a args) (Value, error) {
+			return toString(a.get(0)), nil
+		}),
+		fn("tonumber", func(in *interp, a args) (Value, error) {
+			switch x := a.get(0).(type) {
+			case float64:
+				return x, nil
+			case string:
+				if f, err := strconv.ParseFloat(strings.TrimSpace(x), 64); err == nil {
+					return f, nil
+				}
+			}
+			return nil, nil
+		}),
+		fn("error", func(in *interp, a args) (Value, error) {
+			return nil, fmt.Errorf("%s", toString(a.get(0)))
+		}),
+		fn("assert", func(in *interp, a args) (Value, error) {
+			if !truth(a.get(0)) {
+				msg := "assertion failed"
+				if a.get(1) != nil {
+					msg = toString(a.get(1))
+				}
+				return nil, fmt.Errorf("%s", msg)
+			}
+			return a.get(0), nil
+		}),
+		fn("ipairs", func(in *interp, a args) (Value, error) {
+			t, err := a.table(0)
+			if err != nil {
+				return nil, err
+			}
+			it := &iterator{t: t}
+			for i := range t.items {
+				it.keys = append(it.keys, float64(i+1))
+			}
+			return it, nil
+		}),
+		fn("pairs", func(in *interp, a args) (Value, error) {
+			t, err := a.table(0)
+			if err != nil {
+				return nil, err
+			}
+			return &iterator{t: t, keys: t.keys()}, nil
+		}),
+		fn("format", func(in *interp, a args) (Value, error) {
+			f, err := a.str(0)
+			if err != nil {
+				return nil, err
+			}
+			return format(f, a)
+		}),
+		fn("param", func(in *interp, a args) (Value, error) {
+			name, err := a.str(0)
+			if err != nil {
+				return nil, err
+			}
+			in.used[name] = true
+			def := a.get(1)
+			s, ok := in.params[name]
+			if !ok {
+				return def, nil
+			}
+			switch def.(type) {
+			case float64:
+				x, err := strconv.ParseFloat(s, 64)
+				if err != nil {
+					return nil, fmt.Errorf("parameter %s: bad number \"%s\"", name, s)
+				}
+				return x, nil
+			case bool:
+				x, err := strconv.ParseBool(s)
+				if err != nil {
+					return nil, fmt.Errorf("parameter %s: bad boolean \"%s\"", name, s)
+				}
+				return x, nil
+			}
+			return s, nil
+		}),
+
+		// 2d shapes
+		fn("circle", func(in *interp, a args) (Value, error) {
+			r, err := a.num(0)
+			if err != nil {
+				return nil, err
+			}
+			return sdf.Circle2D(r)
+		}),
+		fn("rect", func(in *interp, a args) (Value, error) {
+			x, err := a.nums(0, 2)
+			if err != nil {
+				return nil, err
+			}
+			round, err := a.optNum(2, 0)
+			if err != nil {
+				return nil, err
+			}
+			return sdf.Box2D(v2.Vec{X: x[0], Y: x[1]}, round), nil
+		}),
+		fn("polygon", func(in *interp, a args) (Value, error) {
+			t, err := a.table(0)
+			if err != nil {
+				return nil, err
+			}
+			var v []v2.Vec
+			for _, p := range t.items {
+				pt, ok := p.(*Table)
+				if !ok || pt.Len() != 2 {
+					return nil, fmt.Errorf("polygon vertices must be {x, y}")
+				}
+				x, err := args(pt.items).nums(0, 2)
+				if err != nil {
+					return nil, fmt.Errorf("polygon vertices must be {x, y}")
+				}
+				v = append(v, v2.Vec{X: x[0], Y: x[1]})
+			}
+			return sdf.Polygon2D(v)
+		}),
+
+		// 3d shapes
+		shape3("sphere", 1, func(x []float64) (sdf.SDF3, error) {
+			return sdf.Sphere3D(x[0])
+		}),
+		fn("box", func(in *interp, a args) (Value, error) {
+			x, err := a.nums(0, 3)
+			if err != nil {
+				return nil, err
+			}
+			round, err := a.optNum(3, 0)
+			if err != nil {
+				return nil, err
+			}
+			return sdf.Box3D(v3.Vec{X: x[0], Y: x[1], Z: x[2]}, round)
+		}),
+		fn("cylinder", func(in *interp, a args) (Value, error) {
+			x, err := a.nums(0, 2)
+			if err != nil {
+				return nil, err
+			}
+			round, err := a.optNum(2, 0)
+			if err != nil {
+				return nil, err
+			}
+			return sdf.Cylinder3D(x[0], x[1], round)
+		}),
+		fn("cone", func(in *interp, a args) (Value, error) {
+			x, err := a.nums(0, 3)
+			if err != nil {
+				return nil, err
+			}
+			round, err := a.optNum(3, 0)
+			if err != nil {
+				return nil, err
+			}
+			return sdf.Cone3D(x[0], x[1], x[2], round)
+		}),
+		shape3("capsule", 2, func(x []float64) (sdf.SDF3, error) {
+			return sdf.Capsule3D(x[0], x[1])
+		}),
+		fn("extrude", func(in *interp, a args) (Value, error) {
+			s, err := a.sdf2(0)
+			if err != nil {
+				return nil, err
+			}
+			h, err := a.num(1)
+			if err != nil {
+				return nil, err
+			}
+			round, err := a.optNum(2, 0)
+			if err != nil {
+				return nil, err
+			}
+			if round > 0 {
+				return sdf.ExtrudeRounded3D(s, h, round)
+			}
+			return sdf.Extrude3D(s, h), nil
+		}),
+		fn("revolve", func(in *interp, a args) (Value, error) {
+			s, err := a.sdf2(0)
+			if err != nil {
+				return nil, err
+			}
+			angle, err := a.optNum(1, 360)
+			if err != nil {
+				return nil, err
+			}
+			if angle >= 360 {
+				return sdf.Revolve3D(s)
+			}
+			return sdf.RevolveTheta3D(s, radians(angle))
+		}),
+
+		// booleans
+		boolean("union"),
+		boolean("difference"),
+		boolean("intersect"),
+		smooth("smooth_union"),
+		smooth("smooth_difference"),
+		smooth("smooth_intersect"),
+
+		// transforms
+		fn("translate", func(in *interp, a args) (Value, error) {
+			switch s := a.get(0).(type) {
+			case sdf.SDF2:
+				x, err := a.nums(1, 2)
+				if err != nil {
+					return nil, err
+				}
+				return sdf.Transform2D(s, sdf.Translate2d(v2.Vec{X: x[0], Y: x[1]})), nil
+			case sdf.SDF3:
+				x, err := a.nums(1, 3)
+				if err != nil {
+					return nil, err
+				}
+				return sdf.Transform3D(s, sdf.Translate3d(v3.Vec{X: x[0], Y: x[1], Z: x[2]})), nil
+			}
+			return nil, badArg(0, "sdf2 or sdf3", a.get(0))
+		}),
+		transform("scale", 1, func(s sdf.SDF2, x []float64) (sdf.SDF2, error) {
+			return sdf.ScaleUniform2D(s, x[0]), nil
+		}, func(s sdf.SDF3, x []float64) (sdf.SDF3, error) {
+			return sdf.ScaleUniform3D(s, x[0]), nil
+		}),
+		transform("rotate", 1, func(s sdf.SDF2, x []float64) (sdf.SDF2, error) {
+			return sdf.Transform2D(s, sdf.Rotate2d(radians(x[0]))), nil
+		}, nil),
+		transform("rotate_x", 1, nil, func(s sdf.SDF3, x []float64) (sdf.SDF3, error) {
+			return sdf.Transform3D(s, sdf.RotateX(radians(x[0]))), nil
+		}),
+		transform("rotate_y", 1, nil, func(s sdf.SDF3, x []float64) (sdf.SDF3, error) {
+			return sdf.Transform3D(s, sdf.RotateY(radians(x[0]))), nil
+		}),
+		transform("rotate_z", 1, func(s sdf.SDF2, x []float64) (sdf.SDF2, error) {
+			return sdf.Transform2D(s, sdf.Rotate2d(radians(x[0]))), nil
+		}, func(s sdf.SDF3, x []float64) (sdf.SDF3, error) {
+			return sdf.Transform3D(s, sdf.RotateZ(radians(x[0]))), nil
+		}),
+		transform("mirror_x", 0, func(s sdf.SDF2, _ []float64) (sdf.SDF2, error) {
+			return sdf.Transform2D(s, sdf.MirrorY()), nil
+		}, func(s sdf.SDF3, _ []float64) (sdf.SDF3, error) {
+			return sdf.Transform3D(s, sdf.MirrorYZ()), nil
+		}),
+		transform("mirror_y", 0, func(s sdf.SDF2, _ []float64) (sdf.SDF2, error) {
+			return sdf.Transform2D(s, sdf.MirrorX()), nil
+		}, func(s sdf.SDF3, _ []float64) (sdf.SDF3, error) {
+			return sdf.Transform3D(s, sdf.MirrorXZ()), nil
+		}),
+		transform("mirror_z", 0, nil, func(s sdf.SDF3, _ []float64) (sdf.SDF3, error) {
+			return sdf.Transform3D(s, sdf.MirrorXY()), nil
+		}),
+		transform("offset", 1, func(s sdf.SDF2, x []float64) (sdf.SDF2, error) {
+			return sdf.Offset2D(s, x[0]), nil
+		}, func(s sdf.SDF3, x []float64) (sdf.SDF3, error) {
+			return sdf.Offset3D(s, x[0]), nil
+		}),
+		transform("shell", 1, nil, func(s sdf.SDF3, x []float64) (sdf.SDF3, error) {
+			return sdf.Shell3D(s, x[0])
+		}),
+		fn("bbox", func(in *interp, a args) (Value, error) {
+			switch s := a.get(0).(type) {
+			case sdf.SDF2:
+				bb := s.BoundingBox()
+				return boxTable(NewTable(bb.Min.X, bb.Min.Y), NewTable(bb.Max.X, bb.Max.Y)), nil
+			case sdf.SDF3:
+				bb := s.BoundingBox()
+				return boxTable(NewTable(bb.Min.X, bb.Min.Y, bb.Min.Z), NewTable(bb.Max.X, bb.Max.Y, bb.Max.Z)), nil
+			}
+			return nil, badArg(0, "sdf2 or sdf3", a.get(0))
+		}),
+	}
+}
+
+// boxTable returns a bounding box table.
+func boxTable(min, max *Table) *Table {
+	t := NewTable()
+	t.fields["min"] = min
+	t.fields["max"] = max
+	return t
+}
+
+//-----------------------------------------------------------------------------
+// Booleans
+
+// combine2 combines sdf2 shapes with a boolean operation.
+func combine2(op string, s []sdf.SDF2, min sdf.MinFunc, max sdf.MaxFunc) sdf.SDF2 {
+	switch op {
+	case "union":
+		u := sdf.Union2D(s...)
+		if x, ok := u.(*sdf.UnionSDF2); ok && min != nil {
+			x.SetMin(min)
+		}
+		return u
+	case "difference":
+		x := s[0]
+		if len(s) > 1 {
+			x = sdf.Difference2D(x, sdf.Union2D(s[1:]...))
+			if d, ok := x.(*sdf.DifferenceSDF2); ok && max != nil {
+				d.SetMax(max)
+			}
+		}
+		return x
+	}
+	x := s[0]
+	for _, y := range s[1:] {
+		x = sdf.Intersect2D(x, y)
+		if d, ok := x.(*sdf.IntersectionSDF2); ok && max != nil {
+			d.SetMax(max)
+		}
+	}
+	return x
+}
+
+// combine3 combines sdf3 shapes with a boolean operation.
+func combine3(op string, s []sdf.SDF3, min sdf.MinFunc, max sdf.MaxFunc) sdf.SDF3 {
+	switch op {
+	case "union":
+		u := sdf.Union3D(s...)
+		if x, ok := u.(*sdf.UnionSDF3); ok && min != nil {
+			x.SetMin(min)
+		}
+		return u
+	case "difference":
+		x := s[0]
+		if len(s) > 1 {
+			x = sdf.Difference3D(x, sdf.Union3D(s[1:]...))
+			if d, ok := x.(*sdf.DifferenceSDF3); ok && max != nil {
+				d.SetMax(max)
+			}
+		}
+		return x
+	}
+	x := s[0]
+	for _, y := range s[1:] {
+		x = sdf.Intersect3D(x, y)
+		if d, ok := x.(*sdf.IntersectionSDF3); ok && max != nil {
+			d.SetMax(max)
+		}
+	}
+	return x
+}
+
+// boolean makes a boolean operation builtin.
+func boolean(name string) *builtin {
+	return fn(name, func(in *interp, a args) (Value, error) {
+		s2, s3, err := a.shapes(0)
+		if err != nil {
+			return nil, err
+		}
+		if s2 != nil {
+			return combine2(name, s2, nil, nil), nil
+		}
+		return combine3(name, s3, nil, nil), nil
+	})
+}
+
+// smooth makes a blended boolean operation builtin (smooth_union, ...).
+func smooth(name string) *builtin {
+	op := strings.TrimPrefix(name, "smooth_")
+	return fn(name, func(in *interp, a args) (Value, error) {
+		k, err := a.num(0)
+		if err != nil {
+			return nil, err
+		}
+		if k <= 0 {
+			return nil, fmt.Errorf("k <= 0")
+		}
+		s2, s3, err := a.shapes(1)
+		if err != nil {
+			return nil, err
+		}
+		if s2 != nil {
+			return combine2(op, s2, sdf.PolyMin(k), sdf.PolyMax(k)), nil
+		}
+		return combine3(op, s3, sdf.PolyMin(k), sdf.PolyMax(k)), nil
+	})
+}
+
+//-----------------------------------------------------------------------------
+
+// mathTable returns the math library table.
+func mathTable() *Table {
+	t := NewTable()
+	f1 := func(name string, f func(float64) float64) {
+		t.fields[name] = fn("math."+name, func(in *interp, a args) (Value, error) {
+			x, err := a.num(0)
+			if err != nil {
+				return nil, err
+			}
+			return f(x), nil
+		})
+	}
+	f1("abs", math.Abs)
+	f1("ceil", math.Ceil)
+	f1("floor", math.Floor)
+	f1("sqrt", math.Sqrt)
+	f1("exp", math.Exp)
+	f1("log", math.Log)
+	// trig in radians, as Lua
+	f1("sin", math.Sin)
+	f1("cos", math.Cos)
+	f1("tan", math.Tan)
+	f1("asin", math.Asin)
+	f1("acos", math.Acos)
+	f1("rad", func(x float64) float64 { return radians(x) })
+	f1("deg", func(x float64) float64 { return x * 180 / sdf.Pi })
+	t.fields["atan"] = fn("math.atan", func(in *interp, a args) (Value, error) {
+		y, err := a.num(0)
+		if err != nil {
+			return nil, err
+		}
+		x, err := a.optNum(1, 1)
+		if err != nil {
+			return nil, err
+		}
+		return math.Atan2(y, x), nil
+	})
+	minMax := func(name string, f func(a, b float64) float64) {
+		t.fields[name] = fn("math."+name, func(in *interp, a args) (Value, error) {
+			x, err := a.num(0)
+			if err != nil {
+				return nil, err
+			}
+			for i := 1; i < len(a); i++ {
+				y, err := a.num(i)
+				if err != nil {
+					return nil, err
+				}
+				x = f(x, y)
+			}
+			return x, nil
+		})
+	}
+	minMax("min", math.Min)
+	minMax("max", math.Max)
+	t.fields["pi"] = sdf.Pi
+	t.fields["huge"] = math.Inf(1)
+	return t
+}
+
+// format formats values (from argument 1) with a Lua/C format string.
+func format(f string, a args) (Value, error) {
+	var sb strings.Builder
+	n := 0
+	for i := 0; i < len(f); i++ {
+		if f[i] != '%' {
+			sb.WriteByte(f[i])
+			continue
+		}
+		j := i + 1
+		for j < len(f) && strings.IndexByte("-+ #0123456789.", f[j]) >= 0 {
+			j++
+		}
+		if j >= len(f) {
+			return nil, fmt.Errorf("bad format \"%s\"", f)
+		}
+		spec, verb := f[i:j], f[j]
+		i = j
+		if verb == '%' {
+			sb.WriteByte('%')
+			continue
+		}
+		switch verb {
+		case 'd', 'i', 'x', 'X':
+			x, err := a.num(n + 1)
+			if err != nil {
+				return nil, err
+			}
+			if verb == 'i' {
+				verb = 'd'
+			}
+			fmt.Fprintf(&sb, spec+string(verb), int64(x))
+		case 'f', 'g', 'e', 'G', 'E':
+			x, err := a.num(n + 1)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&sb, spec+string(verb), x)
+		case 's':
+			fmt.Fprintf(&sb, spec+"s", toString(a.get(n+1)))
+		case 'q':
+			sb.WriteString(strconv.Quote(toString(a.get(n + 1))))
+		default:
+			return nil, fmt.Errorf("bad format verb '%c'", verb)
+		}
+		n++
+	}
+	return sb.String(), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,709 @@
+//-----------------------------------------------------------------------------
+/*
+
+Script Parser
+
+The parser builds a syntax tree for the Lua subset (see script.go).
+
+*/
+//-----------------------------------------------------------------------------
+
+package script
+
+//-----------------------------------------------------------------------------
+// Syntax tree
+
+// expr is an expression.
+type expr interface{}
+
+// stmt is a statement.
+type stmt interface{}
+
+// block is a list of statements with its own scope.
+type block []stmt
+
+type constExpr struct{ v Value }
+
+type nameExpr struct {
+	name string
+	line int
+}
+
+type indexExpr struct {
+	obj, key expr
+	line     int
+}
+
+type callExpr struct {
+	fn   expr
+	args []expr
+	line int
+}
+
+type methodExpr struct {
+	obj  expr
+	name string
+	args []expr
+	line int
+}
+
+type funcExpr struct {
+	name   string
+	params []string
+	body   block
+}
+
+type binExpr struct {
+	op   string
+	a, b expr
+	line int
+}
+
+type unExpr struct {
+	op   string
+	a    expr
+	line int
+}
+
+type tableExpr struct {
+	items      []expr // array items
+	keys, vals []expr // keyed fields
+	line       int
+}
+
+type localStmt struct {
+	names []string
+	exprs []expr
+}
+
+// localFuncStmt declares the name before the function, so the function can call itself.
+type localFuncStmt struct {
+	name string
+	fn   *funcExpr
+}
+
+type assignStmt struct {
+	targets []expr
+	exprs   []expr
+	line    int
+}
+
+type callStmt struct{ call expr }
+
+type ifStmt struct {
+	conds  []expr
+	blocks []block
+	els    block
+}
+
+type whileStmt struct {
+	cond expr
+	body block
+}
+
+type numForStmt struct {
+	name              string
+	start, stop, step expr
+	body              block
+	line              int
+}
+
+type inForStmt struct {
+	names []string
+	iter  expr
+	body  block
+	line  int
+}
+
+type returnStmt struct{ e expr }
+
+type breakStmt struct{}
+
+type doStmt struct{ body block }
+
+//-----------------------------------------------------------------------------
+
+// parser is a recursive descent parser.
+type parser struct {
+	lex  *lexer
+	toks []token
+	pos  int
+}
+
+// peek returns the current token.
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+// advance returns the current token and moves to the next.
+func (p *parser) advance() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// is returns true if the current token is an operator or keyword.
+func (p *parser) is(s string) bool {
+	t := p.peek()
+	return (t.kind == tokOp || t.kind == tokKeyword) && t.text == s
+}
+
+// accept skips an operator or keyword if it is the current token.
+func (p *parser) accept(s string) bool {
+	if p.is(s) {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+// expect skips an operator or keyword, or returns an error.
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		t := p.peek()
+		return p.lex.errorf(t.line, "expected '%s' near %s", s, t)
+	}
+	return nil
+}
+
+// name returns a name token.
+func (p *parser) name() (string, error) {
+	t := p.peek()
+	if t.kind != tokName {
+		return "", p.lex.errorf(t.line, "expected a name near %s", t)
+	}
+	p.advance()
+	return t.text, nil
+}
+
+//-----------------------------------------------------------------------------
+// Statements
+
+// parse parses a script.
+func parse(name, src string) (block, error) {
+	toks, err := tokens(name, src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{lex: &lexer{name: name}, toks: toks}
+	b, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.lex.errorf(t.line, "unexpected %s", t)
+	}
+	return b, nil
+}
+
+// blockEnd returns true at the end of a block.
+func (p *parser) blockEnd() bool {
+	return p.peek().kind == tokEOF || p.is("end") || p.is("else") || p.is("elseif")
+}
+
+// block parses statements up to the end of a block.
+func (p *parser) block() (block, error) {
+	var b block
+	for !p.blockEnd() {
+		if p.accept(";") {
+			continue
+		}
+		if p.accept("return") {
+			var r returnStmt
+			if !p.blockEnd() && !p.is(";") {
+				e, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				r.e = e
+			}
+			p.accept(";")
+			b = append(b, &r)
+			if !p.blockEnd() {
+				t := p.peek()
+				return nil, p.lex.errorf(t.line, "'end' expected after return near %s", t)
+			}
+			return b, nil
+		}
+		s, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, s)
+	}
+	return b, nil
+}
+
+// body parses a block followed by 'end'.
+func (p *parser) body() (block, error) {
+	b, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	return b, p.expect("end")
+}
+
+// statement parses a statement.
+func (p *parser) statement() (stmt, error) {
+	t := p.peek()
+	switch {
+	case p.accept("local"):
+		if p.accept("function") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			f, err := p.function(name)
+			if err != nil {
+				return nil, err
+			}
+			return &localFuncStmt{name, f}, nil
+		}
+		var s localStmt
+		for {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			s.names = append(s.names, name)
+			if !p.accept(",") {
+				break
+			}
+		}
+		if p.accept("=") {
+			var err error
+			if s.exprs, err = p.exprList(); err != nil {
+				return nil, err
+			}
+		}
+		return &s, nil
+	case p.accept("function"):
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		var target expr = &nameExpr{name, t.line}
+		for p.accept(".") {
+			key, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			target = &indexExpr{target, &constExpr{key}, t.line}
+			name += "." + key
+		}
+		f, err := p.function(name)
+		if err != nil {
+			return nil, err
+		}
+		return &assignStmt{targets: []expr{target}, exprs: []expr{f}, line: t.line}, nil
+	case p.accept("if"):
+		var s ifStmt
+		for {
+			cond, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("then"); err != nil {
+				return nil, err
+			}
+			b, err := p.block()
+			if err != nil {
+				return nil, err
+			}
+			s.conds = append(s.conds, cond)
+			s.blocks = append(s.blocks, b)
+			if !p.accept("elseif") {
+				break
+			}
+		}
+		if p.accept("else") {
+			b, err := p.block()
+			if err != nil {
+				return nil, err
+			}
+			s.els = b
+		}
+		return &s, p.expect("end")
+	case p.accept("while"):
+		cond, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("do"); err != nil {
+			return nil, err
+		}
+		b, err := p.body()
+		return &whileStmt{cond, b}, err
+	case p.accept("for"):
+		return p.forStatement(t.line)
+	case p.accept("do"):
+		b, err := p.body()
+		return &doStmt{b}, err
+	case p.accept("break"):
+		return &breakStmt{}, nil
+	}
+	// assignment or function call
+	e, err := p.suffixed()
+	if err != nil {
+		return nil, err
+	}
+	if p.is("=") || p.is(",") {
+		s := assignStmt{targets: []expr{e}, line: t.line}
+		for p.accept(",") {
+			e, err := p.suffixed()
+			if err != nil {
+				return nil, err
+			}
+			s.targets = append(s.targets, e)
+		}
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+		for _, x := range s.targets {
+			switch x.(type) {
+			case *nameExpr, *indexExpr:
+			default:
+				return nil, p.lex.errorf(t.line, "cannot assign to an expression")
+			}
+		}
+		if s.exprs, err = p.exprList(); err != nil {
+			return nil, err
+		}
+		return &s, nil
+	}
+	switch e.(type) {
+	case *callExpr, *methodExpr:
+		return &callStmt{e}, nil
+	}
+	return nil, p.lex.errorf(t.line, "syntax error near %s", p.peek())
+}
+
+// forStatement parses a numeric or iterator for loop.
+func (p *parser) forStatement(line int) (stmt, error) {
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept("=") {
+		s := numForStmt{name: name, line: line}
+		if s.start, err = p.expr(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		if s.stop, err = p.expr(); err != nil {
+			return nil, err
+		}
+		if p.accept(",") {
+			if s.step, err = p.expr(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect("do"); err != nil {
+			return nil, err
+		}
+		s.body, err = p.body()
+		return &s, err
+	}
+	s := inForStmt{names: []string{name}, line: line}
+	for p.accept(",") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		s.names = append(s.names, name)
+	}
+	if err := p.expect("in"); err != nil {
+		return nil, err
+	}
+	if s.iter, err = p.expr(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("do"); err != nil {
+		return nil, err
+	}
+	s.body, err = p.body()
+	return &s, err
+}
+
+// function parses the parameters and body of a function.
+func (p *parser) function(name string) (*funcExpr, error) {
+	f := &funcExpr{name: name}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	for !p.is(")") {
+		param, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		f.params = append(f.params, param)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	b, err := p.body()
+	if err != nil {
+		return nil, err
+	}
+	f.body = b
+	return f, nil
+}
+
+//-----------------------------------------------------------------------------
+// Expressions
+
+// binary operator precedences (left, right), right associative operators
+// have a lower right precedence
+var precedence = map[string][2]int{
+	"or":  {1, 1},
+	"and": {2, 2},
+	"<":   {3, 3},
+	">":   {3, 3},
+	"<=":  {3, 3},
+	">=":  {3, 3},
+	"~=":  {3, 3},
+	"==":  {3, 3},
+	"..":  {5, 4},
+	"+":   {6, 6},
+	"-":   {6, 6},
+	"*":   {7, 7},
+	"/":   {7, 7},
+	"%":   {7, 7},
+	"^":   {10, 9},
+}
+
+// unaryPrecedence is the precedence of the unary operators.
+const unaryPrecedence = 8
+
+// exprList parses a comma separated list of expressions.
+func (p *parser) exprList() ([]expr, error) {
+	var list []expr
+	for {
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+		if !p.accept(",") {
+			return list, nil
+		}
+	}
+}
+
+// expr parses an expression.
+func (p *parser) expr() (expr, error) {
+	return p.binary(0)
+}
+
+// binary parses an expression with operators that bind tighter than a limit.
+func (p *parser) binary(limit int) (expr, error) {
+	var a expr
+	t := p.peek()
+	if (t.kind == tokOp && (t.text == "-" || t.text == "#")) || (t.kind == tokKeyword && t.text == "not") {
+		p.advance()
+		x, err := p.binary(unaryPrecedence)
+		if err != nil {
+			return nil, err
+		}
+		a = &unExpr{t.text, x, t.line}
+	} else {
+		var err error
+		if a, err = p.simple(); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		t := p.peek()
+		prec, ok := precedence[t.text]
+		if !ok || (t.kind != tokOp && t.kind != tokKeyword) || prec[0] <= limit {
+			return a, nil
+		}
+		p.advance()
+		b, err := p.binary(prec[1])
+		if err != nil {
+			return nil, err
+		}
+		a = &binExpr{t.text, a, b, t.line}
+	}
+}
+
+// simple parses a simple expression.
+func (p *parser) simple() (expr, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokNumber:
+		p.advance()
+		return &constExpr{t.num}, nil
+	case tokString:
+		p.advance()
+		return &constExpr{t.text}, nil
+	case tokKeyword:
+		switch t.text {
+		case "nil":
+			p.advance()
+			return &constExpr{nil}, nil
+		case "true":
+			p.advance()
+			return &constExpr{true}, nil
+		case "false":
+			p.advance()
+			return &constExpr{false}, nil
+		case "function":
+			p.advance()
+			return p.function("function")
+		}
+	case tokOp:
+		if t.text == "{" {
+			return p.table()
+		}
+	}
+	return p.suffixed()
+}
+
+// primary parses a name or a parenthesized expression.
+func (p *parser) primary() (expr, error) {
+	t := p.peek()
+	if t.kind == tokName {
+		p.advance()
+		return &nameExpr{t.text, t.line}, nil
+	}
+	if p.accept("(") {
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	}
+	return nil, p.lex.errorf(t.line, "unexpected %s", t)
+}
+
+// suffixed parses a primary expression followed by fields, indices and calls.
+func (p *parser) suffixed() (expr, error) {
+	e, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		switch {
+		case p.accept("."):
+			key, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			e = &indexExpr{e, &constExpr{key}, t.line}
+		case p.accept("["):
+			key, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			e = &indexExpr{e, key, t.line}
+		case p.accept(":"):
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			args, err := p.args()
+			if err != nil {
+				return nil, err
+			}
+			e = &methodExpr{e, name, args, t.line}
+		case p.is("(") || p.is("{") || p.peek().kind == tokString:
+			args, err := p.args()
+			if err != nil {
+				return nil, err
+			}
+			e = &callExpr{e, args, t.line}
+		default:
+			return e, nil
+		}
+	}
+}
+
+// args parses the arguments of a call: (a, b), {table} or "string".
+func (p *parser) args() ([]expr, error) {
+	t := p.peek()
+	if t.kind == tokString {
+		p.advance()
+		return []expr{&constExpr{t.text}}, nil
+	}
+	if p.is("{") {
+		e, err := p.table()
+		if err != nil {
+			return nil, err
+		}
+		return []expr{e}, nil
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	if p.accept(")") {
+		return nil, nil
+	}
+	args, err := p.exprList()
+	if err != nil {
+		return nil, err
+	}
+	return args, p.expect(")")
+}
+
+// table parses a table constructor.
+func (p *parser) table() (expr, error) {
+	t := p.advance()
+	e := &tableExpr{line: t.line}
+	for !p.accept("}") {
+		switch {
+		case p.accept("["):
+			key, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			val, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			e.keys = append(e.keys, key)
+			e.vals = append(e.vals, val)
+		case p.peek().kind == tokName && p.toks[p.pos+1].kind == tokOp && p.toks[p.pos+1].text == "=":
+			key := p.advance()
+			p.advance()
+			val, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			e.keys = append(e.keys, &constExpr{key.text})
+			e.vals = append(e.vals, val)
+		default:
+			val, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			e.items = append(e.items, val)
+		}
+		if !p.accept(",") && !p.accept(";") {
+			if err := p.expect("}"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return e, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,882 @@
+//-----------------------------------------------------------------------------
+/*
+
+Model Scripts
+
+Models can be written as scripts in a subset of Lua, evaluated by a runner
+program (see "sdfx run"). The script builds SDFs with the primitive, boolean
+and transform functions of lib.go and returns the model:
+
+	-- a plate with holes
+	local w = param("width", 40)
+	local plate = box(w, 30, 5, 1)
+	for i = 1, 3 do
+		plate = plate:difference(cylinder(10, 3):translate(w*(i-2)/4, 0, 0))
+	end
+	return plate
+
+The language is a small subset of Lua 5:
+
+* values: nil, booleans, numbers, strings, tables, functions and SDFs
+* local variables, closures and recursion
+* if/elseif/else, while, numeric for, for with ipairs/pairs, break
+* operators: + - * / % ^ .. == ~= < <= > >= and or not #
+* tables: arrays ({1, 2, 3}) and string keys ({x = 1}), t[i], t.x
+* method calls: s:translate(1, 2, 3) is translate(s, 1, 2, 3)
+
+Functions return a single value, and there are no metatables, coroutines,
+varargs or goto. Angles are in degrees.
+
+*/
+//-----------------------------------------------------------------------------
+
+package script
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// Value is a script value: nil, bool, float64, string, *Table, a function,
+// sdf.SDF2 or sdf.SDF3.
+type Value interface{}
+
+// Table is a script table, with array items and string keyed fields.
+type Table struct {
+	items  []Value // items 1..n
+	fields map[string]Value
+}
+
+// NewTable returns a table with array items.
+func NewTable(items ...Value) *Table {
+	return &Table{items: items, fields: make(map[string]Value)}
+}
+
+// Len returns the number of array items of a table.
+func (t *Table) Len() int {
+	return len(t.items)
+}
+
+// Item returns an array item (1..n) of a table.
+func (t *Table) Item(i int) Value {
+	if i < 1 || i > len(t.items) {
+		return nil
+	}
+	return t.items[i-1]
+}
+
+// Field returns a field of a table.
+func (t *Table) Field(k string) Value {
+	return t.fields[k]
+}
+
+// get returns a table value.
+func (t *Table) get(k Value) (Value, error) {
+	switch k := k.(type) {
+	case string:
+		return t.fields[k], nil
+	case float64:
+		if k == math.Floor(k) {
+			return t.Item(int(k)), nil
+		}
+	}
+	return nil, fmt.Errorf("bad table key (%s)", typeName(k))
+}
+
+// set sets a table value. Array items can be set at 1..n+1, and nil removes the last item.
+func (t *Table) set(k, v Value) error {
+	switch k := k.(type) {
+	case string:
+		if v == nil {
+			delete(t.fields, k)
+		} else {
+			t.fields[k] = v
+		}
+		return nil
+	case float64:
+		i := int(k)
+		n := len(t.items)
+		switch {
+		case float64(i) != k || i < 1 || i > n+1:
+			return fmt.Errorf("table index %g is not in 1..%d", k, n+1)
+		case i == n+1:
+			if v != nil {
+				t.items = append(t.items, v)
+			}
+		case v == nil && i == n:
+			t.items = t.items[:n-1]
+		case v == nil:
+			return fmt.Errorf("cannot remove table item %d of %d", i, n)
+		default:
+			t.items[i-1] = v
+		}
+		return nil
+	}
+	return fmt.Errorf("bad table key (%s)", typeName(k))
+}
+
+// keys returns the keys of a table: the item indices and then the sorted field names.
+func (t *Table) keys() []Value {
+	var k []Value
+	for i := range t.items {
+		k = append(k, float64(i+1))
+	}
+	names := make([]string, 0, len(t.fields))
+	for name := range t.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		k = append(k, name)
+	}
+	return k
+}
+
+// Function is a script function (a closure).
+type Function struct {
+	fn  *funcExpr
+	env *scope
+}
+
+// builtin is a function implemented in Go.
+type builtin struct {
+	name string
+	fn   func(in *interp, args []Value) (Value, error)
+}
+
+// iterator is the state of a for ... in loop over a table.
+type iterator struct {
+	t    *Table
+	keys []Value
+}
+
+//-----------------------------------------------------------------------------
+
+// typeName returns the script type name of a value.
+func typeName(v Value) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case *Table:
+		return "table"
+	case *Function, *builtin:
+		return "function"
+	case *iterator:
+		return "iterator"
+	case sdf.SDF2:
+		return "sdf2"
+	case sdf.SDF3:
+		return "sdf3"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// truth returns the truth of a value: nil and false are false.
+func truth(v Value) bool {
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return v != nil
+}
+
+// toString returns the string of a value.
+func toString(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		if v == math.Floor(v) && math.Abs(v) < 1e15 {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		return strconv.FormatFloat(v, 'g', 14, 64)
+	case string:
+		return v
+	case *Function:
+		return "function: " + v.fn.name
+	case *builtin:
+		return "builtin: " + v.name
+	}
+	return fmt.Sprintf("%s: %p", typeName(v), v)
+}
+
+// equal returns true if two values are equal.
+func equal(a, b Value) (eq bool) {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	}
+	if ta == nil {
+		return true
+	}
+	if !ta.Comparable() {
+		return false
+	}
+	// comparable structs can hold incomparable values
+	defer func() {
+		if recover() != nil {
+			eq = false
+		}
+	}()
+	return a == b
+}
+
+//-----------------------------------------------------------------------------
+
+// scope is a variable scope.
+type scope struct {
+	vars   map[string]*Value
+	parent *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{vars: make(map[string]*Value), parent: parent}
+}
+
+// lookup returns the variable for a name, or nil.
+func (s *scope) lookup(name string) *Value {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// declare adds a local variable.
+func (s *scope) declare(name string, v Value) {
+	s.vars[name] = &v
+}
+
+//-----------------------------------------------------------------------------
+
+// maxDepth limits the depth of function calls.
+const maxDepth = 200
+
+// flow is the control flow after a statement.
+type flow int
+
+const (
+	flowNormal flow = iota
+	flowBreak
+	flowReturn
+)
+
+// interp is the state of a running script.
+type interp struct {
+	name    string // script name for error messages
+	globals *scope
+	out     io.Writer
+	params  map[string]string
+	used    map[string]bool // params read by the script
+	depth   int
+}
+
+// errorf returns an error at a line of the script.
+func (in *interp) errorf(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d: %s", in.name, line, fmt.Sprintf(format, args...))
+}
+
+// block runs a block in a new scope.
+func (in *interp) block(b block, env *scope) (flow, Value, error) {
+	env = newScope(env)
+	for _, s := range b {
+		f, v, err := in.exec(s, env)
+		if err != nil || f != flowNormal {
+			return f, v, err
+		}
+	}
+	return flowNormal, nil, nil
+}
+
+// exec runs a statement.
+func (in *interp) exec(s stmt, env *scope) (flow, Value, error) {
+	switch s := s.(type) {
+	case *localStmt:
+		vals, err := in.exprs(s.exprs, env)
+		if err != nil {
+			return flowNormal, nil, err
+		}
+		for i, name := range s.names {
+			var v Value
+			if i < len(vals) {
+				v = vals[i]
+			}
+			env.declare(name, v)
+		}
+	case *localFuncStmt:
+		env.declare(s.name, nil)
+		*env.lookup(s.name) = &Function{s.fn, env}
+	case *assignStmt:
+		vals, err := in.exprs(s.exprs, env)
+		if err != nil {
+			return flowNormal, nil, err
+		}
+		for i, t := range s.targets {
+			var v Value
+			if i < len(vals) {
+				v = vals[i]
+			}
+			if err := in.assign(t, v, env); err != nil {
+				return flowNormal, nil, err
+			}
+		}
+	case *callStmt:
+		if _, err := in.eval(s.call, env); err != nil {
+			return flowNormal, nil, err
+		}
+	case *ifStmt:
+		for i, c := range s.conds {
+			x, err := in.eval(c, env)
+			if err != nil {
+				return flowNormal, nil, err
+			}
+			if truth(x) {
+				return in.block(s.blocks[i], env)
+			}
+		}
+		if s.els != nil {
+			return in.block(s.els, env)
+		}
+	case *whileStmt:
+		for {
+			x, err := in.eval(s.cond, env)
+			if err != nil {
+				return flowNormal, nil, err
+			}
+			if !truth(x) {
+				break
+			}
+			f, v, err := in.block(s.body, env)
+			if err != nil || f == flowReturn {
+				return f, v, err
+			}
+			if f == flowBreak {
+				break
+			}
+		}
+	case *numForStmt:
+		return in.numFor(s, env)
+	case *inForStmt:
+		return in.inFor(s, env)
+	case *doStmt:
+		return in.block(s.body, env)
+	case *returnStmt:
+		if s.e == nil {
+			return flowReturn, nil, nil
+		}
+		v, err := in.eval(s.e, env)
+		return flowReturn, v, err
+	case *breakStmt:
+		return flowBreak, nil, nil
+	default:
+		panic(fmt.Sprintf("unknown statement %T", s))
+	}
+	return flowNormal, nil, nil
+}
+
+// numFor runs a numeric for loop.
+func (in *interp) numFor(s *numForStmt, env *scope) (flow, Value, error) {
+	var x [3]float64
+	x[2] = 1
+	for i, e := range []expr{s.start, s.stop, s.step} {
+		if e == nil {
+			continue
+		}
+		v, err := in.eval(e, env)
+		if err != nil {
+			return flowNormal, nil, err
+		}
+		n, ok := v.(float64)
+		if !ok {
+			return flowNormal, nil, in.errorf(s.line, "'for' limit must be a number")
+		}
+		x[i] = n
+	}
+	start, stop, step := x[0], x[1], x[2]
+	if step == 0 {
+		return flowNormal, nil, in.errorf(s.line, "'for' step is zero")
+	}
+	for i := start; (step > 0 && i <= stop) || (step < 0 && i >= stop); i += step {
+		loop := newScope(env)
+		loop.declare(s.name, i)
+		f, v, err := in.block(s.body, loop)
+		if err != nil || f == flowReturn {
+			return f, v, err
+		}
+		if f == flowBreak {
+			break
+		}
+	}
+	return flowNormal, nil, nil
+}
+
+// inFor runs a for loop over an iterator (ipairs/pairs), or a function
+// called until it returns nil.
+func (in *interp) inFor(s *inForStmt, env *scope) (flow, Value, error) {
+	x, err := in.eval(s.iter, env)
+	if err != nil {
+		return flowNormal, nil, err
+	}
+	// next returns the loop values, or false at the end
+	var next func() ([]Value, bool, error)
+	switch it := x.(type) {
+	case *iterator:
+		n := 0
+		next = func() ([]Value, bool, error) {
+			if n >= len(it.keys) {
+				return nil, false, nil
+			}
+			k := it.keys[n]
+			n++
+			v, _ := it.t.get(k)
+			if v == nil {
+				// removed during the loop
+				return nil, false, nil
+			}
+			return []Value{k, v}, true, nil
+		}
+	case *Function, *builtin:
+		next = func() ([]Value, bool, error) {
+			v, err := in.call(x, nil, s.line)
+			return []Value{v}, v != nil, err
+		}
+	default:
+		return flowNormal, nil, in.errorf(s.line, "cannot iterate over a %s value (use ipairs or pairs)", typeName(x))
+	}
+	for {
+		vals, ok, err := next()
+		if err != nil || !ok {
+			return flowNormal, nil, err
+		}
+		loop := newScope(env)
+		for i, name := range s.names {
+			var v Value
+			if i < len(vals) {
+				v = vals[i]
+			}
+			loop.declare(name, v)
+		}
+		f, v, err := in.block(s.body, loop)
+		if err != nil || f == flowReturn {
+			return f, v, err
+		}
+		if f == flowBreak {
+			return flowNormal, nil, nil
+		}
+	}
+}
+
+// assign assigns a value to a variable or table field.
+func (in *interp) assign(target expr, v Value, env *scope) error {
+	switch t := target.(type) {
+	case *nameExpr:
+		if x := env.lookup(t.name); x != nil {
+			*x = v
+		} else {
+			in.globals.declare(t.name, v)
+		}
+		return nil
+	case *indexExpr:
+		obj, err := in.eval(t.obj, env)
+		if err != nil {
+			return err
+		}
+		key, err := in.eval(t.key, env)
+		if err != nil {
+			return err
+		}
+		tbl, ok := obj.(*Table)
+		if !ok {
+			return in.errorf(t.line, "attempt to index a %s value", typeName(obj))
+		}
+		if err := tbl.set(key, v); err != nil {
+			return in.errorf(t.line, "%s", err)
+		}
+		return nil
+	}
+	panic(fmt.Sprintf("bad assignment target %T", target))
+}
+
+//-----------------------------------------------------------------------------
+
+// exprs evaluates a list of expressions.
+func (in *interp) exprs(list []expr, env *scope) ([]Value, error) {
+	vals := make([]Value, len(list))
+	for i, e := range list {
+		v, err := in.eval(e, env)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+// eval evaluates an expression.
+func (in *interp) eval(e expr, env *scope) (Value, error) {
+	switch e := e.(type) {
+	case *constExpr:
+		return e.v, nil
+	case *nameExpr:
+		if x := env.lookup(e.name); x != nil {
+			return *x, nil
+		}
+		return nil, nil
+	case *indexExpr:
+		obj, err := in.eval(e.obj, env)
+		if err != nil {
+			return nil, err
+		}
+		key, err := in.eval(e.key, env)
+		if err != nil {
+			return nil, err
+		}
+		t, ok := obj.(*Table)
+		if !ok {
+			return nil, in.errorf(e.line, "attempt to index a %s value", typeName(obj))
+		}
+		v, err := t.get(key)
+		if err != nil {
+			return nil, in.errorf(e.line, "%s", err)
+		}
+		return v, nil
+	case *callExpr:
+		fn, err := in.eval(e.fn, env)
+		if err != nil {
+			return nil, err
+		}
+		args, err := in.exprs(e.args, env)
+		if err != nil {
+			return nil, err
+		}
+		if fn == nil {
+			if n, ok := e.fn.(*nameExpr); ok {
+				return nil, in.errorf(e.line, "attempt to call a nil value (%s)", n.name)
+			}
+		}
+		return in.call(fn, args, e.line)
+	case *methodExpr:
+		obj, err := in.eval(e.obj, env)
+		if err != nil {
+			return nil, err
+		}
+		args, err := in.exprs(e.args, env)
+		if err != nil {
+			return nil, err
+		}
+		fn, err := in.method(obj, e.name, e.line)
+		if err != nil {
+			return nil, err
+		}
+		return in.call(fn, append([]Value{obj}, args...), e.line)
+	case *funcExpr:
+		return &Function{e, env}, nil
+	case *tableExpr:
+		t := NewTable()
+		for _, x := range e.items {
+			v, err := in.eval(x, env)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				return nil, in.errorf(e.line, "nil array item in table")
+			}
+			t.items = append(t.items, v)
+		}
+		for i := range e.keys {
+			k, err := in.eval(e.keys[i], env)
+			if err != nil {
+				return nil, err
+			}
+			v, err := in.eval(e.vals[i], env)
+			if err != nil {
+				return nil, err
+			}
+			if err := t.set(k, v); err != nil {
+				return nil, in.errorf(e.line, "%s", err)
+			}
+		}
+		return t, nil
+	case *unExpr:
+		a, err := in.eval(e.a, env)
+		if err != nil {
+			return nil, err
+		}
+		return in.unary(e, a)
+	case *binExpr:
+		a, err := in.eval(e.a, env)
+		if err != nil {
+			return nil, err
+		}
+		// short circuit
+		switch e.op {
+		case "and":
+			if !truth(a) {
+				return a, nil
+			}
+			return in.eval(e.b, env)
+		case "or":
+			if truth(a) {
+				return a, nil
+			}
+			return in.eval(e.b, env)
+		}
+		b, err := in.eval(e.b, env)
+		if err != nil {
+			return nil, err
+		}
+		return in.binary(e, a, b)
+	}
+	panic(fmt.Sprintf("unknown expression %T", e))
+}
+
+// unary evaluates a unary operator.
+func (in *interp) unary(e *unExpr, a Value) (Value, error) {
+	switch e.op {
+	case "not":
+		return !truth(a), nil
+	case "-":
+		if x, ok := a.(float64); ok {
+			return -x, nil
+		}
+		return nil, in.errorf(e.line, "attempt to negate a %s value", typeName(a))
+	case "#":
+		switch x := a.(type) {
+		case string:
+			return float64(len(x)), nil
+		case *Table:
+			return float64(len(x.items)), nil
+		}
+		return nil, in.errorf(e.line, "attempt to get the length of a %s value", typeName(a))
+	}
+	panic("unknown unary operator " + e.op)
+}
+
+// binary evaluates a binary operator.
+func (in *interp) binary(e *binExpr, a, b Value) (Value, error) {
+	switch e.op {
+	case "==":
+		return equal(a, b), nil
+	case "~=":
+		return !equal(a, b), nil
+	case "..":
+		sa, oka := concatString(a)
+		sb, okb := concatString(b)
+		if !oka || !okb {
+			bad := a
+			if oka {
+				bad = b
+			}
+			return nil, in.errorf(e.line, "attempt to concatenate a %s value", typeName(bad))
+		}
+		return sa + sb, nil
+	case "<", "<=", ">", ">=":
+		var c int
+		switch x := a.(type) {
+		case float64:
+			y, ok := b.(float64)
+			if !ok {
+				return nil, in.errorf(e.line, "attempt to compare number with %s", typeName(b))
+			}
+			if x < y {
+				c = -1
+			} else if x > y {
+				c = 1
+			} else if x != y {
+				// NaN
+				return false, nil
+			}
+		case string:
+			y, ok := b.(string)
+			if !ok {
+				return nil, in.errorf(e.line, "attempt to compare string with %s", typeName(b))
+			}
+			c = strings.Compare(x, y)
+		default:
+			return nil, in.errorf(e.line, "attempt to compare two %s values", typeName(a))
+		}
+		switch e.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	}
+	x, okx := a.(float64)
+	y, oky := b.(float64)
+	if !okx || !oky {
+		bad := a
+		if okx {
+			bad = b
+		}
+		return nil, in.errorf(e.line, "attempt to perform arithmetic on a %s value", typeName(bad))
+	}
+	switch e.op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		return x / y, nil
+	case "%":
+		return x - math.Floor(x/y)*y, nil
+	case "^":
+		return math.Pow(x, y), nil
+	}
+	panic("unknown binary operator " + e.op)
+}
+
+// concatString returns the string of a value for concatenation.
+func concatString(v Value) (string, bool) {
+	switch v.(type) {
+	case string, float64:
+		return toString(v), true
+	}
+	return "", false
+}
+
+// method returns the function for a method call.
+func (in *interp) method(obj Value, name string, line int) (Value, error) {
+	switch x := obj.(type) {
+	case *Table:
+		return x.fields[name], nil
+	case sdf.SDF2, sdf.SDF3:
+		if methods[name] {
+			if v := in.globals.lookup(name); v != nil {
+				return *v, nil
+			}
+		}
+	}
+	return nil, in.errorf(line, "%s has no method '%s'", typeName(obj), name)
+}
+
+// call calls a function.
+func (in *interp) call(fn Value, args []Value, line int) (Value, error) {
+	switch f := fn.(type) {
+	case *builtin:
+		v, err := f.fn(in, args)
+		if err != nil {
+			if _, ok := err.(*scriptError); ok {
+				return nil, err
+			}
+			return nil, &scriptError{in.errorf(line, "%s: %s", f.name, err)}
+		}
+		return v, nil
+	case *Function:
+		if in.depth >= maxDepth {
+			return nil, in.errorf(line, "stack overflow")
+		}
+		in.depth++
+		defer func() { in.depth-- }()
+		env := newScope(f.env)
+		for i, name := range f.fn.params {
+			var v Value
+			if i < len(args) {
+				v = args[i]
+			}
+			env.declare(name, v)
+		}
+		_, v, err := in.block(f.fn.body, env)
+		return v, err
+	}
+	return nil, in.errorf(line, "attempt to call a %s value", typeName(fn))
+}
+
+// scriptError is an error from a builtin, with its position in the script.
+type scriptError struct {
+	error
+}
+
+//-----------------------------------------------------------------------------
+
+// Options are the settings for running a script.
+type Options struct {
+	Output io.Writer         // output of print (default os.Stdout)
+	Params map[string]string // values for the param function, by name
+}
+
+// Run runs a script and returns the value of its return statement.
+func Run(name string, src []byte, opts Options) (Value, error) {
+	b, err := parse(name, string(src))
+	if err != nil {
+		return nil, err
+	}
+	in := &interp{
+		name:    name,
+		globals: newScope(nil),
+		out:     opts.Output,
+		params:  opts.Params,
+		used:    make(map[string]bool),
+	}
+	if in.out == nil {
+		in.out = os.Stdout
+	}
+	for _, f := range library {
+		in.globals.declare(f.name, f)
+	}
+	in.globals.declare("math", mathTable())
+	_, v, err := in.block(b, in.globals)
+	if err != nil {
+		return nil, err
+	}
+	// parameters that the script doesn't have are probably typos
+	var unused []string
+	for k := range opts.Params {
+		if !in.used[k] {
+			unused = append(unused, k)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return nil, fmt.Errorf("%s: unknown parameters %s", name, strings.Join(unused, ", "))
+	}
+	return v, nil
+}
+
+// RunFile runs a script file and returns the value of its return statement.
+func RunFile(path string, opts Options) (Value, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Run(filepath.Base(path), src, opts)
+}
+
+// Model3 runs a script file that returns an SDF3.
+func Model3(path string, opts Options) (sdf.SDF3, error) {
+	v, err := RunFile(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := v.(sdf.SDF3)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected the script to return an sdf3, got %s", filepath.Base(path), typeName(v))
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
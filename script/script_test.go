@@ -0,0 +1,161 @@
+//-----------------------------------------------------------------------------
+/*
+
+Model Script Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package script
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Language(t *testing.T) {
+	tests := []struct {
+		src    string
+		result Value
+	}{
+		{"return 1 + 2 * 3", 7.0},
+		{"return (1 + 2) * 3", 9.0},
+		{"return 2 ^ 3 ^ 2", 512.0},
+		{"return -2 ^ 2", -4.0},
+		{"return 7 % 3", 1.0},
+		{"return -7 % 3", 2.0},
+		{"return 1 < 2 and 'yes' or 'no'", "yes"},
+		{"return nil or false", false},
+		{"return not nil", true},
+		{"return 'a' .. 1 .. 'b'", "a1b"},
+		{"return #'abc' + #{1, 2}", 5.0},
+		{"return 1 == 1.0 and 'x' ~= 'y'", true},
+		{"return 0x10", 16.0},
+		{"local x = 1 do local x = 2 end return x", 1.0},
+		{"x = 1 local function f() x = x + 1 end f() f() return x", 3.0},
+		{"local function fact(n) if n <= 1 then return 1 end return n * fact(n - 1) end return fact(5)", 120.0},
+		{"local s = 0 for i = 1, 10 do s = s + i end return s", 55.0},
+		{"local s = 0 for i = 10, 1, -2 do s = s + i end return s", 30.0},
+		{"local s = 0 for i = 1, 10 do if i > 3 then break end s = s + i end return s", 6.0},
+		{"local i = 0 while true do i = i + 1 if i == 5 then break end end return i", 5.0},
+		{"local t = {10, 20, 30} local s = 0 for i, v in ipairs(t) do s = s + i * v end return s", 140.0},
+		{"local t = {b = 1, a = 2, 5} local s = '' for k, v in pairs(t) do s = s .. k .. v end return s", "15a2b1"},
+		{"local t = {} t[1] = 'a' t[#t + 1] = 'b' t.n = #t return t[2] .. t.n", "b2"},
+		{"local t = {x = {y = 3}} return t.x.y", 3.0},
+		{"local function counter() local n = 0 return function() n = n + 1 return n end end local c = counter() c() return c()", 2.0},
+		{"local n = 0 local function gen() n = n + 1 if n <= 3 then return n end end local s = 0 for x in gen do s = s + x end return s", 6.0},
+		{"local p = {x = 1} function p.get(self) return self.x end return p:get()", 1.0},
+		{"if false then return 1 elseif nil then return 2 else return 3 end", 3.0},
+		{"--[[ block\ncomment ]] return 1 -- comment", 1.0},
+		{"return format('%d-%.2f-%s', 3.7, 1, 'x')", "3-1.00-x"},
+		{"return math.max(1, 5, 3) + math.floor(-0.5)", 4.0},
+		{"return tostring(1.5) .. type(nil) .. type(tonumber('2'))", "1.5nilnumber"},
+		{"return", nil},
+	}
+	for _, test := range tests {
+		v, err := Run("test", []byte(test.src), Options{})
+		if err != nil {
+			t.Errorf("%s: %s", test.src, err)
+			continue
+		}
+		if !equal(v, test.result) {
+			t.Errorf("%s: expected %v, got %v", test.src, test.result, v)
+		}
+	}
+}
+
+func Test_Errors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"return 1 +", "test:1: unexpected end of file"},
+		{"x = 'abc", "test:1: unterminated string"},
+		{"\n\nreturn nil + 1", "test:3: attempt to perform arithmetic on a nil value"},
+		{"spere(1)", "test:1: attempt to call a nil value (spere)"},
+		{"local t = {} t[3] = 1", "test:1: table index 3 is not in 1..1"},
+		{"return sphere('a')", "test:1: sphere: bad argument #1 (number expected, got string)"},
+		{"return box(1, 2, 3):rotate(1)", "test:1: rotate: bad argument #1 (sdf2 expected, got sdf3)"},
+		{"return union(circle(1), sphere(1))", "test:1: union: mixed sdf2 and sdf3 arguments"},
+		{"local function f() return f() end f()", "stack overflow"},
+		{"error('failed')", "test:1: error: failed"},
+		{"return 1 end", "test:1: unexpected 'end'"},
+		{"return 1 x = 2", "test:1: 'end' expected after return"},
+		{"1 = 2", "test:1: unexpected 1"},
+	}
+	for _, test := range tests {
+		_, err := Run("test", []byte(test.src), Options{})
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected error \"%s\", got %v", test.src, test.err, err)
+		}
+	}
+}
+
+func Test_Model(t *testing.T) {
+	src := `
+-- a plate with holes
+local w = param("width", 40)
+local round = param("round", true)
+local plate = box(w, 30, 5, round and 1 or 0)
+local holes = {}
+for i = 1, 3 do
+	holes[#holes + 1] = cylinder(10, 3):translate(w * (i - 2) / 4, 0, 0)
+end
+print("holes", #holes)
+return plate:difference(holes[1], holes[2], holes[3])
+`
+	var out bytes.Buffer
+	v, err := Run("plate.lua", []byte(src), Options{Output: &out, Params: map[string]string{"width": "60"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "holes\t3\n" {
+		t.Errorf("bad output %q", out.String())
+	}
+	s, ok := v.(sdf.SDF3)
+	if !ok {
+		t.Fatalf("expected an sdf3, got %s", typeName(v))
+	}
+	plate, _ := sdf.Box3D(v3.Vec{X: 60, Y: 30, Z: 5}, 1)
+	hole, _ := sdf.Cylinder3D(10, 3, 0)
+	var holes []sdf.SDF3
+	for i := 1; i <= 3; i++ {
+		holes = append(holes, sdf.Transform3D(hole, sdf.Translate3d(v3.Vec{X: 60 * float64(i-2) / 4})))
+	}
+	ref := sdf.Difference3D(plate, sdf.Union3D(holes...))
+	bb := ref.BoundingBox().ScaleAboutCenter(1.2)
+	for _, p := range bb.RandomSet(1000) {
+		if d, x := ref.Evaluate(p), s.Evaluate(p); math.Abs(d-x) > 1e-9 {
+			t.Fatalf("%v: expected %f, got %f", p, d, x)
+		}
+	}
+
+	// unknown parameters are errors
+	if _, err := Run("plate.lua", []byte(src), Options{Output: &out, Params: map[string]string{"widht": "60"}}); err == nil {
+		t.Error("expected an error for an unknown parameter")
+	}
+
+	// 2d shapes, extrusions and smooth booleans
+	v, err = Run("test", []byte(`
+local s = smooth_union(0.5, rect(10, 4), circle(3):translate(5, 0))
+local p = polygon({{0, 0}, {4, 0}, {0, 4}}):rotate(90)
+local bb = bbox(p)
+assert(math.abs(bb.max[1]) < 1e-9, "bad bbox")
+return extrude(union({s, p}), 2):rotate_x(90)
+`), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.(sdf.SDF3); !ok {
+		t.Fatalf("expected an sdf3, got %s", typeName(v))
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,155 @@
+//-----------------------------------------------------------------------------
+/*
+
+Batched Evaluation
+
+Evaluate an SDF3 for a slice of points in one call. The renderers evaluate
+in batches, so the per point overhead of walking the SDF tree (interface
+calls, transforms) is paid once per batch and the inner loops run over
+plain slices. The loops are scalar Go, there are no vectorized (SIMD)
+kernels, but this is the interface they would implement.
+
+SDF3s that implement BatchSDF3 evaluate the whole batch, any other SDF3 is
+evaluated one point at a time. The batched results are identical to the
+results of Evaluate.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"sync"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// BatchSDF3 is an SDF3 that evaluates a batch of points.
+type BatchSDF3 interface {
+	SDF3
+	// EvaluateN stores the distance for each point of p in d (len(d) >= len(p)).
+	EvaluateN(p []v3.Vec, d []float64)
+}
+
+// EvaluateN evaluates an SDF3 for each point of p and stores the distances in d.
+func EvaluateN(s SDF3, p []v3.Vec, d []float64) {
+	if b, ok := s.(BatchSDF3); ok {
+		b.EvaluateN(p, d)
+		return
+	}
+	for i := range p {
+		d[i] = s.Evaluate(p[i])
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// batchSize is the number of points in a scratch buffer.
+const batchSize = 128
+
+// batchBuffer is scratch space for the operators.
+type batchBuffer struct {
+	p [batchSize]v3.Vec
+	d [batchSize]float64
+}
+
+var batchPool = sync.Pool{New: func() interface{} { return new(batchBuffer) }}
+
+// batchChunks calls fn for each chunk of up to batchSize points with a scratch buffer.
+func batchChunks(p []v3.Vec, d []float64, fn func(p []v3.Vec, d []float64, buf *batchBuffer)) {
+	buf := batchPool.Get().(*batchBuffer)
+	for len(p) > 0 {
+		n := len(p)
+		if n > batchSize {
+			n = batchSize
+		}
+		fn(p[:n], d[:n], buf)
+		p, d = p[n:], d[n:]
+	}
+	batchPool.Put(buf)
+}
+
+//-----------------------------------------------------------------------------
+// Primitives
+
+// EvaluateN returns the minimum distances to a sphere.
+func (s *SphereSDF3) EvaluateN(p []v3.Vec, d []float64) {
+	for i := range p {
+		d[i] = p[i].Length() - s.radius
+	}
+}
+
+// EvaluateN returns the minimum distances to a 3d box.
+func (s *BoxSDF3) EvaluateN(p []v3.Vec, d []float64) {
+	for i := range p {
+		d[i] = sdfBox3d(p[i], s.size) - s.round
+	}
+}
+
+//-----------------------------------------------------------------------------
+// Operators
+
+// EvaluateN returns the minimum distances to a transformed SDF3.
+func (s *TransformSDF3) EvaluateN(p []v3.Vec, d []float64) {
+	batchChunks(p, d, func(p []v3.Vec, d []float64, buf *batchBuffer) {
+		q := buf.p[:len(p)]
+		for i := range p {
+			q[i] = s.inverse.MulPosition(p[i])
+		}
+		EvaluateN(s.sdf, q, d)
+	})
+}
+
+// EvaluateN returns the minimum distances to a uniformly scaled SDF3.
+func (s *ScaleUniformSDF3) EvaluateN(p []v3.Vec, d []float64) {
+	batchChunks(p, d, func(p []v3.Vec, d []float64, buf *batchBuffer) {
+		q := buf.p[:len(p)]
+		for i := range p {
+			q[i] = p[i].MulScalar(s.invK)
+		}
+		EvaluateN(s.sdf, q, d)
+		for i := range d {
+			d[i] *= s.k
+		}
+	})
+}
+
+// EvaluateN returns the minimum distances to an SDF3 union.
+func (s *UnionSDF3) EvaluateN(p []v3.Vec, d []float64) {
+	if s.bvh != nil {
+		// the bvh prunes per point
+		for i := range p {
+			d[i] = s.bvh.evaluate(p[i])
+		}
+		return
+	}
+	batchChunks(p, d, func(p []v3.Vec, d []float64, buf *batchBuffer) {
+		x := buf.d[:len(p)]
+		for i, sdf := range s.sdf {
+			if i == 0 {
+				EvaluateN(sdf, p, d)
+				continue
+			}
+			EvaluateN(sdf, p, x)
+			for j := range d {
+				d[j] = s.min(d[j], x[j])
+			}
+		}
+	})
+}
+
+// EvaluateN returns the minimum distances to the difference of two SDF3s.
+func (s *DifferenceSDF3) EvaluateN(p []v3.Vec, d []float64) {
+	batchChunks(p, d, func(p []v3.Vec, d []float64, buf *batchBuffer) {
+		x := buf.d[:len(p)]
+		EvaluateN(s.s0, p, d)
+		EvaluateN(s.s1, p, x)
+		for i := range d {
+			d[i] = s.max(d[i], -x[i])
+		}
+	})
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,86 @@
+//-----------------------------------------------------------------------------
+/*
+
+Batched Evaluation Testing
+
+Compare the batched and per point evaluation:
+
+	go test -run XXX -bench EvaluateN ./sdf
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// batchTree returns a tree of the operators with batched evaluation.
+func batchTree() SDF3 {
+	box, _ := benchBox3()
+	sphere, _ := benchSphere3()
+	cylinder, _ := benchCylinder3()
+	var items []SDF3
+	for i := 0; i < 8; i++ {
+		m := RotateZ(float64(i)).Mul(Translate3d(v3.Vec{20, 0, 0}))
+		items = append(items, Transform3D(ScaleUniform3D(box, 0.25), m))
+	}
+	u := Union3D(append(items, sphere)...)
+	u.(*UnionSDF3).SetMin(PolyMin(2))
+	return Transform3D(Difference3D(u, cylinder), RotateX(0.5))
+}
+
+// batchSDF3 are the SDF3s for the batched evaluation tests.
+var batchSDF3 = append(benchSDF3, struct {
+	name  string
+	build func() (SDF3, error)
+}{"Tree", func() (SDF3, error) { return batchTree(), nil }})
+
+func Test_EvaluateN(t *testing.T) {
+	for _, x := range batchSDF3 {
+		s, err := x.build()
+		if err != nil {
+			t.Fatalf("%s: %s", x.name, err)
+		}
+		points := benchPoints3(s.BoundingBox())
+		d := make([]float64, len(points))
+		EvaluateN(s, points, d)
+		for i, p := range points {
+			if d0 := s.Evaluate(p); d[i] != d0 {
+				t.Fatalf("%s: %v: batched distance %g, expected %g", x.name, p, d[i], d0)
+			}
+		}
+	}
+}
+
+func Benchmark_EvaluateN(b *testing.B) {
+	for _, x := range batchSDF3 {
+		s, err := x.build()
+		if err != nil {
+			b.Fatalf("%s: %s", x.name, err)
+		}
+		points := benchPoints3(s.BoundingBox())
+		d := make([]float64, len(points))
+		b.Run(x.name+"/point", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for j, p := range points {
+					d[j] = s.Evaluate(p)
+				}
+			}
+		})
+		b.Run(x.name+"/batch", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				EvaluateN(s, points, d)
+			}
+		})
+	}
+}
+
+//-----------------------------------------------------------------------------
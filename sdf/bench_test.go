@@ -0,0 +1,262 @@
+//-----------------------------------------------------------------------------
+/*
+
+Evaluation Benchmarks
+
+Benchmarks for the evaluation of the primitives and operators:
+
+	go test -run XXX -bench . -count 5 ./sdf > new.txt
+	sdfxbench old.txt new.txt
+
+Each benchmark evaluates a fixed set of points sampled over a box 1.2 times
+the bounding box of the object, so runs are comparable. The evaluations are
+allocation free (see Test_EvaluateAllocs), the benchmarks report allocations
+to keep them that way.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math/rand"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/deadsy/sdfx/vec/v2i"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// benchPoints is the number of sample points for an evaluation benchmark.
+const benchPoints = 1024
+
+// benchPoints3 returns sample points over a box 1.2 times the bounding box.
+func benchPoints3(bb Box3) []v3.Vec {
+	r := rand.New(rand.NewSource(1))
+	bb = NewBox3(bb.Center(), bb.Size().MulScalar(1.2))
+	p := make([]v3.Vec, benchPoints)
+	for i := range p {
+		p[i] = v3.Vec{
+			bb.Min.X + r.Float64()*(bb.Max.X-bb.Min.X),
+			bb.Min.Y + r.Float64()*(bb.Max.Y-bb.Min.Y),
+			bb.Min.Z + r.Float64()*(bb.Max.Z-bb.Min.Z),
+		}
+	}
+	return p
+}
+
+// benchPoints2 returns sample points over a box 1.2 times the bounding box.
+func benchPoints2(bb Box2) []v2.Vec {
+	r := rand.New(rand.NewSource(1))
+	bb = NewBox2(bb.Center(), bb.Size().MulScalar(1.2))
+	p := make([]v2.Vec, benchPoints)
+	for i := range p {
+		p[i] = v2.Vec{
+			bb.Min.X + r.Float64()*(bb.Max.X-bb.Min.X),
+			bb.Min.Y + r.Float64()*(bb.Max.Y-bb.Min.Y),
+		}
+	}
+	return p
+}
+
+//-----------------------------------------------------------------------------
+
+func benchBox3() (SDF3, error)    { return Box3D(v3.Vec{10, 20, 30}, 1) }
+func benchSphere3() (SDF3, error) { return Sphere3D(10) }
+func benchCylinder3() (SDF3, error) {
+	return Cylinder3D(20, 5, 1)
+}
+func benchProfile2() SDF2 { return Box2D(v2.Vec{10, 6}, 1) }
+
+// benchSDF3 are the SDF3 benchmarks.
+var benchSDF3 = []struct {
+	name  string
+	build func() (SDF3, error)
+}{
+	// primitives
+	{"Box3D", benchBox3},
+	{"Sphere3D", benchSphere3},
+	{"Cylinder3D", benchCylinder3},
+	{"Capsule3D", func() (SDF3, error) { return Capsule3D(20, 5) }},
+	{"Cone3D", func() (SDF3, error) { return Cone3D(20, 8, 4, 1) }},
+	{"Gyroid3D", func() (SDF3, error) { return Gyroid3D(v3.Vec{5, 5, 5}) }},
+	{"Extrude3D", func() (SDF3, error) { return Extrude3D(benchProfile2(), 10), nil }},
+	{"TwistExtrude3D", func() (SDF3, error) { return TwistExtrude3D(benchProfile2(), 10, Tau), nil }},
+	{"Revolve3D", func() (SDF3, error) {
+		return Revolve3D(Transform2D(benchProfile2(), Translate2d(v2.Vec{10, 0})))
+	}},
+	{"Loft3D", func() (SDF3, error) {
+		c, _ := Circle2D(4)
+		return Loft3D(benchProfile2(), c, 10, 0)
+	}},
+	{"Screw3D", func() (SDF3, error) {
+		t, err := ISOThread(5, 1, true)
+		if err != nil {
+			return nil, err
+		}
+		return Screw3D(t, 20, 0, 1, 1)
+	}},
+	// operators
+	{"Union3D", func() (SDF3, error) {
+		a, _ := benchBox3()
+		b, _ := benchSphere3()
+		return Union3D(a, b), nil
+	}},
+	{"Union3D/PolyMin", func() (SDF3, error) {
+		a, _ := benchBox3()
+		b, _ := benchSphere3()
+		s := Union3D(a, b)
+		s.(*UnionSDF3).SetMin(PolyMin(2))
+		return s, nil
+	}},
+	{"Union3D/100", func() (SDF3, error) { return Union3D(holes3(100)...), nil }},
+	{"Difference3D", func() (SDF3, error) {
+		a, _ := benchBox3()
+		b, _ := benchCylinder3()
+		return Difference3D(a, b), nil
+	}},
+	{"Intersect3D", func() (SDF3, error) {
+		a, _ := benchBox3()
+		b, _ := benchSphere3()
+		return Intersect3D(a, b), nil
+	}},
+	{"Transform3D", func() (SDF3, error) {
+		a, _ := benchBox3()
+		return Transform3D(a, RotateZ(1).Mul(Translate3d(v3.Vec{1, 2, 3}))), nil
+	}},
+	{"ScaleUniform3D", func() (SDF3, error) {
+		a, _ := benchBox3()
+		return ScaleUniform3D(a, 2), nil
+	}},
+	{"Offset3D", func() (SDF3, error) {
+		a, _ := benchBox3()
+		return Offset3D(a, 1), nil
+	}},
+	{"Shell3D", func() (SDF3, error) {
+		a, _ := benchSphere3()
+		return Shell3D(a, 1)
+	}},
+	{"Array3D", func() (SDF3, error) {
+		a, _ := benchSphere3()
+		return Array3D(a, v3i.Vec{4, 4, 4}, v3.Vec{25, 25, 25}), nil
+	}},
+	{"RotateCopy3D", func() (SDF3, error) {
+		a, _ := benchBox3()
+		return RotateCopy3D(Transform3D(a, Translate3d(v3.Vec{30, 0, 0})), 8), nil
+	}},
+	{"Compile3D", func() (SDF3, error) { return Compile3D(compileTree()), nil }},
+}
+
+func Benchmark_SDF3(b *testing.B) {
+	for _, x := range benchSDF3 {
+		s, err := x.build()
+		if err != nil {
+			b.Fatalf("%s: %s", x.name, err)
+		}
+		points := benchPoints3(s.BoundingBox())
+		b.Run(x.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s.Evaluate(points[i%benchPoints])
+			}
+		})
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// benchSDF2 are the SDF2 benchmarks.
+var benchSDF2 = []struct {
+	name  string
+	build func() (SDF2, error)
+}{
+	// primitives
+	{"Circle2D", func() (SDF2, error) { return Circle2D(5) }},
+	{"Box2D", func() (SDF2, error) { return benchProfile2(), nil }},
+	{"Polygon2D/6", func() (SDF2, error) { return Polygon2D(Nagon(6, 10)) }},
+	{"Polygon2D/12", func() (SDF2, error) { return Polygon2D(Nagon(12, 10)) }},
+	{"FlatFlankCam2D", func() (SDF2, error) { return FlatFlankCam2D(30, 20, 5) }},
+	{"ThreeArcCam2D", func() (SDF2, error) { return ThreeArcCam2D(30, 20, 5, 200) }},
+	{"ArcSpiral2D", func() (SDF2, error) { return ArcSpiral2D(1, 0, 0, 4*Tau, 1) }},
+	// operators
+	{"Union2D", func() (SDF2, error) {
+		c, _ := Circle2D(5)
+		return Union2D(benchProfile2(), Transform2D(c, Translate2d(v2.Vec{5, 0}))), nil
+	}},
+	{"Union2D/PolyMin", func() (SDF2, error) {
+		c, _ := Circle2D(5)
+		s := Union2D(benchProfile2(), Transform2D(c, Translate2d(v2.Vec{5, 0})), c)
+		s.(*UnionSDF2).SetMin(PolyMin(1))
+		return s, nil
+	}},
+	{"Difference2D", func() (SDF2, error) {
+		c, _ := Circle2D(2)
+		return Difference2D(benchProfile2(), c), nil
+	}},
+	{"Intersect2D", func() (SDF2, error) {
+		c, _ := Circle2D(5)
+		return Intersect2D(benchProfile2(), c), nil
+	}},
+	{"Offset2D", func() (SDF2, error) { return Offset2D(benchProfile2(), 1), nil }},
+	{"Array2D", func() (SDF2, error) {
+		c, _ := Circle2D(2)
+		return Array2D(c, v2i.Vec{8, 8}, v2.Vec{5, 5}), nil
+	}},
+}
+
+func Benchmark_SDF2(b *testing.B) {
+	for _, x := range benchSDF2 {
+		s, err := x.build()
+		if err != nil {
+			b.Fatalf("%s: %s", x.name, err)
+		}
+		points := benchPoints2(s.BoundingBox())
+		b.Run(x.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s.Evaluate(points[i%benchPoints])
+			}
+		})
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// Test_EvaluateAllocs checks that the benchmarked evaluations don't allocate.
+func Test_EvaluateAllocs(t *testing.T) {
+	for _, x := range benchSDF3 {
+		s, err := x.build()
+		if err != nil {
+			t.Fatalf("%s: %s", x.name, err)
+		}
+		points := benchPoints3(s.BoundingBox())
+		i := 0
+		n := testing.AllocsPerRun(benchPoints, func() {
+			s.Evaluate(points[i%benchPoints])
+			i++
+		})
+		if n != 0 {
+			t.Errorf("%s: %.2f allocations per evaluation", x.name, n)
+		}
+	}
+	for _, x := range benchSDF2 {
+		s, err := x.build()
+		if err != nil {
+			t.Fatalf("%s: %s", x.name, err)
+		}
+		points := benchPoints2(s.BoundingBox())
+		i := 0
+		n := testing.AllocsPerRun(benchPoints, func() {
+			s.Evaluate(points[i%benchPoints])
+			i++
+		})
+		if n != 0 {
+			t.Errorf("%s: %.2f allocations per evaluation", x.name, n)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
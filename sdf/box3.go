@@ -209,6 +209,12 @@ func (a Box3) MinMaxDist2(p v3.Vec) Interval {
 	return Interval{minDist2, maxDist2}
 }
 
+// dist2 returns the squared distance from a point to the box (0 within the box).
+func (a Box3) dist2(p v3.Vec) float64 {
+	d := a.Min.Sub(p).Max(p.Sub(a.Max)).Max(v3.Vec{})
+	return d.Length2()
+}
+
 //-----------------------------------------------------------------------------
 
 // Random returns a random point within 3d box.
@@ -0,0 +1,222 @@
+//-----------------------------------------------------------------------------
+/*
+
+SDF3 Builder
+
+A chained form of the functional API for transform heavy models:
+
+	s, err := sdf.From(sdf.Box3D(size, 0)).
+		Translate(v3.Vec{X: 10}).
+		RotateZ(sdf.DtoR(30)).
+		Union(boss).
+		SmoothRadius(2).
+		Build()
+
+Each step makes the same SDF3 as the function it stands for (Translate is
+Transform3D with Translate3d, Union is Union3D, etc.), so a built model is
+the same tree as the nested calls. The builders are values: each step
+returns a new builder and the first error is kept until Build.
+
+A builder is also an SDF3, so a builder can be an argument of another.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Builder3 builds an SDF3 with chained operations.
+type Builder3 struct {
+	s   SDF3
+	err error
+}
+
+// New returns a builder starting with an SDF3.
+func New(s SDF3) *Builder3 {
+	if s == nil {
+		return &Builder3{err: ErrMsg("nil sdf")}
+	}
+	return &Builder3{s: s}
+}
+
+// From returns a builder starting with the result of an SDF3 constructor,
+// e.g. From(Box3D(size, round)).
+func From(s SDF3, err error) *Builder3 {
+	if err != nil {
+		return &Builder3{err: err}
+	}
+	return New(s)
+}
+
+// Build returns the SDF3, or the first error of the chain.
+func (b *Builder3) Build() (SDF3, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.s, nil
+}
+
+// Evaluate returns the minimum distance to the built SDF3.
+func (b *Builder3) Evaluate(p v3.Vec) float64 {
+	return b.s.Evaluate(p)
+}
+
+// BoundingBox returns the bounding box of the built SDF3.
+func (b *Builder3) BoundingBox() Box3 {
+	return b.s.BoundingBox()
+}
+
+//-----------------------------------------------------------------------------
+
+// apply returns a builder for an operation on the SDF3.
+func (b *Builder3) apply(f func(s SDF3) (SDF3, error)) *Builder3 {
+	if b.err != nil {
+		return b
+	}
+	s, err := f(b.s)
+	if err != nil {
+		return &Builder3{err: err}
+	}
+	return New(s)
+}
+
+// builderArgs returns the SDF3s of the arguments of an operation, unwrapping any builders.
+func builderArgs(sdf []SDF3) ([]SDF3, error) {
+	out := make([]SDF3, len(sdf))
+	for i, x := range sdf {
+		if b, ok := x.(*Builder3); ok {
+			if b.err != nil {
+				return nil, b.err
+			}
+			x = b.s
+		}
+		out[i] = x
+	}
+	return out, nil
+}
+
+//-----------------------------------------------------------------------------
+// Transforms
+
+// Transform applies a transformation matrix.
+func (b *Builder3) Transform(m M44) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) { return Transform3D(s, m), nil })
+}
+
+// Translate translates by a vector.
+func (b *Builder3) Translate(v v3.Vec) *Builder3 {
+	return b.Transform(Translate3d(v))
+}
+
+// RotateX rotates about the x axis by an angle (radians).
+func (b *Builder3) RotateX(a float64) *Builder3 {
+	return b.Transform(RotateX(a))
+}
+
+// RotateY rotates about the y axis by an angle (radians).
+func (b *Builder3) RotateY(a float64) *Builder3 {
+	return b.Transform(RotateY(a))
+}
+
+// RotateZ rotates about the z axis by an angle (radians).
+func (b *Builder3) RotateZ(a float64) *Builder3 {
+	return b.Transform(RotateZ(a))
+}
+
+// Rotate rotates about an axis by an angle (radians).
+func (b *Builder3) Rotate(axis v3.Vec, a float64) *Builder3 {
+	return b.Transform(Rotate3d(axis, a))
+}
+
+// Scale scales uniformly by a factor.
+func (b *Builder3) Scale(k float64) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		if k <= 0 {
+			return nil, ErrMsg("k <= 0")
+		}
+		return ScaleUniform3D(s, k), nil
+	})
+}
+
+//-----------------------------------------------------------------------------
+// Booleans
+
+// Union is the union with other SDF3s.
+func (b *Builder3) Union(sdf ...SDF3) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		x, err := builderArgs(sdf)
+		if err != nil {
+			return nil, err
+		}
+		return Union3D(append([]SDF3{s}, x...)...), nil
+	})
+}
+
+// Difference subtracts an SDF3 (or the union of several SDF3s).
+func (b *Builder3) Difference(sdf ...SDF3) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		x, err := builderArgs(sdf)
+		if err != nil {
+			return nil, err
+		}
+		return Difference3D(s, Union3D(x...)), nil
+	})
+}
+
+// Intersect is the intersection with an SDF3.
+func (b *Builder3) Intersect(sdf SDF3) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		x, err := builderArgs([]SDF3{sdf})
+		if err != nil {
+			return nil, err
+		}
+		return Intersect3D(s, x[0]), nil
+	})
+}
+
+// SmoothRadius blends the last union, difference or intersection with a
+// fillet of radius r (PolyMin or PolyMax). It modifies the SDF3 of the last
+// operation, so it should follow it.
+func (b *Builder3) SmoothRadius(r float64) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		if r <= 0 {
+			return nil, ErrMsg("r <= 0")
+		}
+		switch x := s.(type) {
+		case *UnionSDF3:
+			x.SetMin(PolyMin(r))
+		case *DifferenceSDF3:
+			x.SetMax(PolyMax(r))
+		case *IntersectionSDF3:
+			x.SetMax(PolyMax(r))
+		default:
+			return nil, ErrMsg("SmoothRadius does not follow a union, difference or intersection")
+		}
+		return s, nil
+	})
+}
+
+//-----------------------------------------------------------------------------
+// Other Operations
+
+// Offset offsets the surface by a distance.
+func (b *Builder3) Offset(d float64) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) { return Offset3D(s, d), nil })
+}
+
+// Shell shells the surface with a thickness.
+func (b *Builder3) Shell(thickness float64) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) { return Shell3D(s, thickness) })
+}
+
+// Cut cuts along a plane through a with normal n, the side of the normal remains.
+func (b *Builder3) Cut(a, n v3.Vec) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) { return Cut3D(s, a, n), nil })
+}
+
+//-----------------------------------------------------------------------------
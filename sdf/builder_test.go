@@ -0,0 +1,71 @@
+//-----------------------------------------------------------------------------
+/*
+
+SDF3 Builder Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"reflect"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Builder3(t *testing.T) {
+	box, _ := Box3D(v3.Vec{10, 20, 30}, 1)
+	ball, _ := Sphere3D(8)
+
+	// transforms make the same tree as the functional api
+	s0 := Transform3D(Transform3D(ScaleUniform3D(box, 2), Translate3d(v3.Vec{5, 0, 0})), RotateZ(DtoR(30)))
+	s1, err := New(box).Scale(2).Translate(v3.Vec{5, 0, 0}).RotateZ(DtoR(30)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s0, s1) {
+		t.Error("the built transforms differ from the functional api")
+	}
+
+	// booleans, with a builder as an argument
+	u := Union3D(box, Transform3D(ball, Translate3d(v3.Vec{0, 0, 15})))
+	u.(*UnionSDF3).SetMin(PolyMin(2))
+	s0 = Difference3D(u, Transform3D(ball, Translate3d(v3.Vec{0, 0, -15})))
+	s1, err = From(Box3D(v3.Vec{10, 20, 30}, 1)).
+		Union(New(ball).Translate(v3.Vec{0, 0, 15})).
+		SmoothRadius(2).
+		Difference(New(ball).Translate(v3.Vec{0, 0, -15})).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.TypeOf(s1) != reflect.TypeOf(s0) || s1.BoundingBox() != s0.BoundingBox() {
+		t.Fatalf("expected %T %v, got %T %v", s0, s0.BoundingBox(), s1, s1.BoundingBox())
+	}
+	bb := s0.BoundingBox().Enlarge(v3.Vec{2, 2, 2})
+	for _, p := range bb.RandomSet(1000) {
+		if d0, d1 := s0.Evaluate(p), s1.Evaluate(p); d0 != d1 {
+			t.Fatalf("%v: expected %g, got %g", p, d0, d1)
+		}
+	}
+
+	// the first error is kept
+	errTests := []*Builder3{
+		From(Box3D(v3.Vec{-1, 1, 1}, 0)).Translate(v3.Vec{1, 0, 0}),
+		New(box).Translate(v3.Vec{1, 0, 0}).SmoothRadius(1),
+		New(box).Union(From(Sphere3D(-1))),
+		New(box).Shell(0).Union(ball),
+		New(nil),
+	}
+	for i, b := range errTests {
+		if _, err := b.Build(); err == nil {
+			t.Errorf("%d: expected an error", i)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
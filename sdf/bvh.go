@@ -0,0 +1,286 @@
+//-----------------------------------------------------------------------------
+/*
+
+Bounding Volume Hierarchies for Unions
+
+Large unions (e.g. hundreds of holes or standoffs) build a BVH over the
+bounding boxes of their children. Evaluation visits the nearest boxes
+first and skips any box that is further away than the current minimum
+distance, so a query costs O(log n) child evaluations rather than O(n).
+
+The pruning assumes a child's distance is no smaller than the distance to
+its bounding box. That holds for exact distance fields. For bounded
+(underestimating) fields the result is still a bound with the correct
+sign. Children with degenerate (zero volume/area) bounding boxes, e.g.
+unbounded surfaces, are always evaluated.
+
+The BVH is only used with the default min function: setting a blending
+min function on the union removes it.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"sort"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// unionBVHMin is the minimum number of union children for building a BVH.
+const unionBVHMin = 8
+
+// bvhLeafSize is the maximum number of children in a BVH leaf node.
+const bvhLeafSize = 4
+
+// bvhStack is the traversal stack size that does not need an allocation.
+const bvhStack = 64
+
+// bvhNode is a node of a BVH.
+type bvhNode struct {
+	left, right int // child nodes, left < 0 for a leaf
+	start, end  int // item range for a leaf
+}
+
+// pruneBox returns true if a box at squared distance dist2 can't hold a value less than d.
+func pruneBox(dist2, d float64) bool {
+	return dist2 > 0 && (d <= 0 || dist2 > d*d)
+}
+
+//-----------------------------------------------------------------------------
+// 3D
+
+// bvh3 is a BVH over a set of SDF3s.
+type bvh3 struct {
+	nodes  []bvhNode
+	bb     []Box3 // per node bounding box
+	items  []SDF3 // sdfs in leaf order
+	always []SDF3 // sdfs with degenerate bounding boxes
+}
+
+// newBVH3 returns a BVH for a set of SDF3s.
+func newBVH3(sdf []SDF3) *bvh3 {
+	t := &bvh3{}
+	var boxes []Box3
+	for _, s := range sdf {
+		bb := s.BoundingBox()
+		size := bb.Size()
+		if size.X <= 0 || size.Y <= 0 || size.Z <= 0 {
+			t.always = append(t.always, s)
+			continue
+		}
+		t.items = append(t.items, s)
+		boxes = append(boxes, bb)
+	}
+	if len(t.items) > 0 {
+		t.build(boxes, 0, len(t.items))
+	}
+	return t
+}
+
+// build builds the node for items [start, end) and returns its index.
+func (t *bvh3) build(boxes []Box3, start, end int) int {
+	bb := boxes[start]
+	for _, b := range boxes[start+1 : end] {
+		bb = bb.Extend(b)
+	}
+	n := len(t.nodes)
+	t.nodes = append(t.nodes, bvhNode{left: -1, start: start, end: end})
+	t.bb = append(t.bb, bb)
+	if end-start <= bvhLeafSize {
+		return n
+	}
+	// split at the median center along the longest axis of the centers
+	cb := Box3{boxes[start].Center(), boxes[start].Center()}
+	for _, b := range boxes[start+1 : end] {
+		cb = cb.Include(b.Center())
+	}
+	size := cb.Size()
+	axis := func(v v3.Vec) float64 { return v.X }
+	if size.Y > size.X && size.Y >= size.Z {
+		axis = func(v v3.Vec) float64 { return v.Y }
+	} else if size.Z > size.X && size.Z > size.Y {
+		axis = func(v v3.Vec) float64 { return v.Z }
+	}
+	sort.Sort(bvhSort3{boxes[start:end], t.items[start:end], axis})
+	mid := (start + end) / 2
+	left := t.build(boxes, start, mid)
+	right := t.build(boxes, mid, end)
+	t.nodes[n].left, t.nodes[n].right = left, right
+	return n
+}
+
+// bvhSort3 sorts boxes and items by the box center along an axis.
+type bvhSort3 struct {
+	boxes []Box3
+	items []SDF3
+	axis  func(v3.Vec) float64
+}
+
+func (s bvhSort3) Len() int { return len(s.boxes) }
+func (s bvhSort3) Less(i, j int) bool {
+	return s.axis(s.boxes[i].Center()) < s.axis(s.boxes[j].Center())
+}
+func (s bvhSort3) Swap(i, j int) {
+	s.boxes[i], s.boxes[j] = s.boxes[j], s.boxes[i]
+	s.items[i], s.items[j] = s.items[j], s.items[i]
+}
+
+// evaluate returns the minimum distance over the SDF3s.
+func (t *bvh3) evaluate(p v3.Vec) float64 {
+	d := math.Inf(1)
+	for _, s := range t.always {
+		d = math.Min(d, s.Evaluate(p))
+	}
+	if len(t.nodes) == 0 {
+		return d
+	}
+	var buf [bvhStack]int
+	stack := append(buf[:0], 0)
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if pruneBox(t.bb[n].dist2(p), d) {
+			continue
+		}
+		node := &t.nodes[n]
+		if node.left < 0 {
+			for _, s := range t.items[node.start:node.end] {
+				d = math.Min(d, s.Evaluate(p))
+			}
+			continue
+		}
+		// visit the nearest child first
+		l, r := node.left, node.right
+		if t.bb[r].dist2(p) < t.bb[l].dist2(p) {
+			l, r = r, l
+		}
+		stack = append(stack, r, l)
+	}
+	return d
+}
+
+//-----------------------------------------------------------------------------
+// 2D
+
+// dist2 returns the squared distance from a point to the box (0 within the box).
+func (a Box2) dist2(p v2.Vec) float64 {
+	d := a.Min.Sub(p).Max(p.Sub(a.Max)).Max(v2.Vec{})
+	return d.Length2()
+}
+
+// bvh2 is a BVH over a set of SDF2s.
+type bvh2 struct {
+	nodes  []bvhNode
+	bb     []Box2 // per node bounding box
+	items  []SDF2 // sdfs in leaf order
+	always []SDF2 // sdfs with degenerate bounding boxes
+}
+
+// newBVH2 returns a BVH for a set of SDF2s.
+func newBVH2(sdf []SDF2) *bvh2 {
+	t := &bvh2{}
+	var boxes []Box2
+	for _, s := range sdf {
+		bb := s.BoundingBox()
+		size := bb.Size()
+		if size.X <= 0 || size.Y <= 0 {
+			t.always = append(t.always, s)
+			continue
+		}
+		t.items = append(t.items, s)
+		boxes = append(boxes, bb)
+	}
+	if len(t.items) > 0 {
+		t.build(boxes, 0, len(t.items))
+	}
+	return t
+}
+
+// build builds the node for items [start, end) and returns its index.
+func (t *bvh2) build(boxes []Box2, start, end int) int {
+	bb := boxes[start]
+	for _, b := range boxes[start+1 : end] {
+		bb = bb.Extend(b)
+	}
+	n := len(t.nodes)
+	t.nodes = append(t.nodes, bvhNode{left: -1, start: start, end: end})
+	t.bb = append(t.bb, bb)
+	if end-start <= bvhLeafSize {
+		return n
+	}
+	// split at the median center along the longest axis of the centers
+	cb := Box2{boxes[start].Center(), boxes[start].Center()}
+	for _, b := range boxes[start+1 : end] {
+		cb = cb.Include(b.Center())
+	}
+	size := cb.Size()
+	useY := size.Y > size.X
+	sort.Sort(bvhSort2{boxes[start:end], t.items[start:end], useY})
+	mid := (start + end) / 2
+	left := t.build(boxes, start, mid)
+	right := t.build(boxes, mid, end)
+	t.nodes[n].left, t.nodes[n].right = left, right
+	return n
+}
+
+// bvhSort2 sorts boxes and items by the box center along an axis.
+type bvhSort2 struct {
+	boxes []Box2
+	items []SDF2
+	useY  bool
+}
+
+func (s bvhSort2) Len() int { return len(s.boxes) }
+func (s bvhSort2) Less(i, j int) bool {
+	a, b := s.boxes[i].Center(), s.boxes[j].Center()
+	if s.useY {
+		return a.Y < b.Y
+	}
+	return a.X < b.X
+}
+func (s bvhSort2) Swap(i, j int) {
+	s.boxes[i], s.boxes[j] = s.boxes[j], s.boxes[i]
+	s.items[i], s.items[j] = s.items[j], s.items[i]
+}
+
+// evaluate returns the minimum distance over the SDF2s.
+func (t *bvh2) evaluate(p v2.Vec) float64 {
+	d := math.Inf(1)
+	for _, s := range t.always {
+		d = math.Min(d, s.Evaluate(p))
+	}
+	if len(t.nodes) == 0 {
+		return d
+	}
+	var buf [bvhStack]int
+	stack := append(buf[:0], 0)
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if pruneBox(t.bb[n].dist2(p), d) {
+			continue
+		}
+		node := &t.nodes[n]
+		if node.left < 0 {
+			for _, s := range t.items[node.start:node.end] {
+				d = math.Min(d, s.Evaluate(p))
+			}
+			continue
+		}
+		// visit the nearest child first
+		l, r := node.left, node.right
+		if t.bb[r].dist2(p) < t.bb[l].dist2(p) {
+			l, r = r, l
+		}
+		stack = append(stack, r, l)
+	}
+	return d
+}
+
+//-----------------------------------------------------------------------------
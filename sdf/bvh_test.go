@@ -0,0 +1,94 @@
+//-----------------------------------------------------------------------------
+/*
+
+BVH Union Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// holes3 returns n randomly placed spheres and boxes.
+func holes3(n int) []SDF3 {
+	r := rand.New(rand.NewSource(1))
+	s := make([]SDF3, n)
+	for i := range s {
+		var x SDF3
+		if i%2 == 0 {
+			x, _ = Sphere3D(1 + r.Float64())
+		} else {
+			x, _ = Box3D(v3.Vec{2, 3, 1}, 0.2)
+		}
+		p := v3.Vec{100 * r.Float64(), 100 * r.Float64(), 10 * r.Float64()}
+		s[i] = Transform3D(x, Translate3d(p))
+	}
+	return s
+}
+
+func Test_UnionBVH(t *testing.T) {
+	// 3d: compare with a linear union
+	s3 := holes3(200)
+	u3 := Union3D(s3...)
+	if u3.(*UnionSDF3).bvh == nil {
+		t.Fatal("no bvh")
+	}
+	bb3 := u3.BoundingBox().ScaleAboutCenter(1.2)
+	for _, p := range bb3.RandomSet(5000) {
+		d := s3[0].Evaluate(p)
+		for _, x := range s3[1:] {
+			d = math.Min(d, x.Evaluate(p))
+		}
+		if x := u3.Evaluate(p); x != d {
+			t.Fatalf("%v: expected %f, got %f", p, d, x)
+		}
+	}
+
+	// 2d: compare with the slow evaluation
+	r := rand.New(rand.NewSource(2))
+	var s2 []SDF2
+	for i := 0; i < 100; i++ {
+		c, _ := Circle2D(1 + r.Float64())
+		s2 = append(s2, Transform2D(c, Translate2d(v2.Vec{50 * r.Float64(), 50 * r.Float64()})))
+	}
+	// a degenerate bounding box
+	s2 = append(s2, Line2D(10, 0))
+	u2 := Union2D(s2...).(*UnionSDF2)
+	if len(u2.bvh.always) != 1 {
+		t.Error("expected one always evaluated sdf")
+	}
+	bb2 := u2.BoundingBox().ScaleAboutCenter(1.2)
+	for _, p := range bb2.RandomSet(5000) {
+		if d, x := u2.EvaluateSlow(p), u2.Evaluate(p); x != d {
+			t.Fatalf("%v: expected %f, got %f", p, d, x)
+		}
+	}
+
+	// blending removes the bvh
+	u2.SetMin(PolyMin(1))
+	if u2.bvh != nil {
+		t.Error("expected no bvh with a blending min function")
+	}
+}
+
+func Benchmark_UnionBVH(b *testing.B) {
+	s := Union3D(holes3(500)...)
+	bb := s.BoundingBox()
+	points := bb.RandomSet(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Evaluate(points[i%len(points)])
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,148 @@
+//-----------------------------------------------------------------------------
+/*
+
+3D Evaluation Cache
+
+Expensive SDF3s (text, imported meshes, lattices) can be sampled once on a
+grid over their bounding box and then evaluated by trilinear interpolation.
+The grid is split into bricks of 8x8x8 cells that are sampled on first use,
+so only the regions that are queried (typically near the surface) are
+sampled. Points outside the grid evaluate the underlying SDF3.
+
+The interpolated distance is an approximation: features smaller than the
+grid resolution are smoothed out.
+
+The cache is safe for concurrent use. Two goroutines may sample the same
+brick at the same time, in which case one of the results is kept.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// cacheBrickCells is the number of cells on each side of a brick.
+const cacheBrickCells = 8
+
+// cacheBrickSide is the number of samples on each side of a brick.
+const cacheBrickSide = cacheBrickCells + 1
+
+// cacheMaxBricks limits the size of the brick index.
+const cacheMaxBricks = 1 << 24
+
+// cacheBrick is the distance samples of a brick.
+type cacheBrick [cacheBrickSide * cacheBrickSide * cacheBrickSide]float64
+
+// CacheSDF3 is an SDF3 sampled on a lazily evaluated grid.
+type CacheSDF3 struct {
+	sdf     SDF3
+	bb      Box3    // grid bounds
+	cell    float64 // cell size
+	cells   v3i.Vec // cells on each axis
+	nbricks v3i.Vec // bricks on each axis
+	bricks  []atomic.Pointer[cacheBrick]
+	sampled atomic.Int64 // number of sampled bricks
+}
+
+// Cache3D returns an SDF3 that samples the passed SDF3 on a grid with a
+// given cell size and interpolates between the samples.
+func Cache3D(sdf SDF3, resolution float64) (SDF3, error) {
+	if resolution <= 0 {
+		return nil, ErrMsg("resolution <= 0")
+	}
+	// add a cell of margin on each side of the bounding box
+	bb := sdf.BoundingBox()
+	bb = bb.Enlarge(v3.Vec{2 * resolution, 2 * resolution, 2 * resolution})
+	size := bb.Size()
+	cells := v3i.Vec{
+		int(math.Ceil(size.X / resolution)),
+		int(math.Ceil(size.Y / resolution)),
+		int(math.Ceil(size.Z / resolution)),
+	}
+	nbricks := v3i.Vec{
+		(cells.X + cacheBrickCells - 1) / cacheBrickCells,
+		(cells.Y + cacheBrickCells - 1) / cacheBrickCells,
+		(cells.Z + cacheBrickCells - 1) / cacheBrickCells,
+	}
+	n := float64(nbricks.X) * float64(nbricks.Y) * float64(nbricks.Z)
+	if n > cacheMaxBricks {
+		return nil, ErrMsg("resolution is too small for the bounding box")
+	}
+	return &CacheSDF3{
+		sdf:     sdf,
+		bb:      Box3{bb.Min, bb.Min.Add(v3.Vec{float64(cells.X), float64(cells.Y), float64(cells.Z)}.MulScalar(resolution))},
+		cell:    resolution,
+		cells:   cells,
+		nbricks: nbricks,
+		bricks:  make([]atomic.Pointer[cacheBrick], int(n)),
+	}, nil
+}
+
+func (s *CacheSDF3) String() string {
+	return fmt.Sprintf("bricks %d of %d sampled", s.sampled.Load(), len(s.bricks))
+}
+
+// brick returns the samples of a brick, sampling it if needed.
+func (s *CacheSDF3) brick(b v3i.Vec) *cacheBrick {
+	idx := b.X + s.nbricks.X*(b.Y+s.nbricks.Y*b.Z)
+	if x := s.bricks[idx].Load(); x != nil {
+		return x
+	}
+	x := &cacheBrick{}
+	base := s.bb.Min.Add(v3.Vec{float64(b.X), float64(b.Y), float64(b.Z)}.MulScalar(cacheBrickCells * s.cell))
+	n := 0
+	for k := 0; k < cacheBrickSide; k++ {
+		for j := 0; j < cacheBrickSide; j++ {
+			for i := 0; i < cacheBrickSide; i++ {
+				p := base.Add(v3.Vec{float64(i), float64(j), float64(k)}.MulScalar(s.cell))
+				x[n] = s.sdf.Evaluate(p)
+				n++
+			}
+		}
+	}
+	if s.bricks[idx].CompareAndSwap(nil, x) {
+		s.sampled.Add(1)
+		return x
+	}
+	return s.bricks[idx].Load()
+}
+
+// Evaluate returns the interpolated distance to a cached SDF3.
+func (s *CacheSDF3) Evaluate(p v3.Vec) float64 {
+	if !s.bb.Contains(p) {
+		return s.sdf.Evaluate(p)
+	}
+	x := p.Sub(s.bb.Min).DivScalar(s.cell)
+	i := clampInt(int(x.X), 0, s.cells.X-1)
+	j := clampInt(int(x.Y), 0, s.cells.Y-1)
+	k := clampInt(int(x.Z), 0, s.cells.Z-1)
+	fx, fy, fz := x.X-float64(i), x.Y-float64(j), x.Z-float64(k)
+
+	b := s.brick(v3i.Vec{i / cacheBrickCells, j / cacheBrickCells, k / cacheBrickCells})
+	i, j, k = i%cacheBrickCells, j%cacheBrickCells, k%cacheBrickCells
+	n := i + cacheBrickSide*(j+cacheBrickSide*k)
+	const dy = cacheBrickSide
+	const dz = cacheBrickSide * cacheBrickSide
+	x00 := Mix(b[n], b[n+1], fx)
+	x10 := Mix(b[n+dy], b[n+dy+1], fx)
+	x01 := Mix(b[n+dz], b[n+dz+1], fx)
+	x11 := Mix(b[n+dy+dz], b[n+dy+dz+1], fx)
+	return Mix(Mix(x00, x10, fy), Mix(x01, x11, fy), fz)
+}
+
+// BoundingBox returns the bounding box of a cached SDF3.
+func (s *CacheSDF3) BoundingBox() Box3 {
+	return s.sdf.BoundingBox()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,83 @@
+//-----------------------------------------------------------------------------
+/*
+
+3D Evaluation Cache Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Cache3D(t *testing.T) {
+	s, _ := Box3D(v3.Vec{10, 20, 30}, 2)
+	const res = 0.25
+	c, err := Cache3D(s, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// interpolated values are close to the exact values
+	bb := s.BoundingBox().ScaleAboutCenter(1.5)
+	points := bb.RandomSet(5000)
+	var wg sync.WaitGroup
+	for n := 0; n < 4; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, p := range points {
+				if d, x := s.Evaluate(p), c.Evaluate(p); math.Abs(d-x) > res {
+					t.Errorf("%v: expected %f, got %f", p, d, x)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// samples on the grid are exact
+	cs := c.(*CacheSDF3)
+	p := cs.bb.Min.Add(v3.Vec{17, 33, 5}.MulScalar(res))
+	if d, x := s.Evaluate(p), c.Evaluate(p); math.Abs(d-x) > tolerance {
+		t.Errorf("%v: expected %f, got %f", p, d, x)
+	}
+
+	// only bricks near the queried points are sampled
+	c, _ = Cache3D(s, res)
+	c.Evaluate(v3.Vec{})
+	if n := c.(*CacheSDF3).sampled.Load(); n != 1 {
+		t.Errorf("expected 1 sampled brick, got %d", n)
+	}
+
+	if _, err := Cache3D(s, 0); err == nil {
+		t.Error("expected an error for a zero resolution")
+	}
+	if _, err := Cache3D(s, 1e-4); err == nil {
+		t.Error("expected an error for a tiny resolution")
+	}
+}
+
+func Benchmark_Cache3D(b *testing.B) {
+	s, _ := Cache3D(Union3D(holes3(200)...), 0.5)
+	bb := s.BoundingBox()
+	points := bb.RandomSet(1000)
+	// sample the bricks
+	for _, p := range points {
+		s.Evaluate(p)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Evaluate(points[i%len(points)])
+	}
+}
+
+//-----------------------------------------------------------------------------
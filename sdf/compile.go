@@ -0,0 +1,340 @@
+//-----------------------------------------------------------------------------
+/*
+
+SDF3 Compiler
+
+Compile an SDF3 tree into a flat program for a small register/stack
+machine. Deep trees of transforms and unions otherwise pay an interface
+call (and a matrix multiply) per node for every evaluation.
+
+Optimizations:
+
+* Chains of Transform3D/ScaleUniform3D are pre-multiplied into a single
+matrix. A point is only transformed once per chain.
+* Identity transforms are removed.
+* Nested unions (with the default min function) are merged into one n-way min.
+* Consecutive distance scale/offset operations are folded.
+* Spheres, boxes and cylinders are evaluated with direct calls.
+
+Other SDF3s are evaluated as opaque leaves through their interface.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+type opcode int
+
+const (
+	opTransform opcode = iota // regs[dst] = m * regs[reg]
+	opSphere                  // push sphere distance
+	opBox                     // push box distance
+	opCylinder                // push cylinder distance
+	opSDF                     // push opaque sdf3 distance
+	opMin                     // pop n values, push the minimum
+	opMinFunc                 // pop n values, push the custom minimum
+	opMax                     // pop 2 values, push the maximum (intersection)
+	opDiff                    // pop 2 values, push max(a, -b) (difference)
+	opMulAdd                  // top = top * k + c
+)
+
+var opName = [...]string{"transform", "sphere", "box", "cylinder", "sdf", "min", "minf", "max", "diff", "muladd"}
+
+// instruction is a program instruction.
+type instruction struct {
+	op     opcode
+	reg    int     // source point register
+	dst    int     // destination point register (opTransform)
+	xform  bool    // transform the point before a leaf evaluation
+	m      M44     // transform matrix
+	n      int     // number of values (opMin, opMinFunc)
+	k, c   float64 // opMulAdd
+	sphere *SphereSDF3
+	box    *BoxSDF3
+	cyl    *CylinderSDF3
+	sdf    SDF3
+	min    MinFunc
+	max    MaxFunc // nil for math.Max
+}
+
+// Program registers/stack sizes that are evaluated without allocation.
+const (
+	progRegs  = 16
+	progStack = 32
+)
+
+// ProgramSDF3 is an SDF3 compiled into a flat program.
+type ProgramSDF3 struct {
+	sdf   SDF3 // the source sdf
+	code  []instruction
+	regs  int // number of point registers
+	stack int // maximum value stack depth
+	bb    Box3
+}
+
+// Compile3D compiles an SDF3 tree into a flat program with the same distance field.
+func Compile3D(s SDF3) SDF3 {
+	c := &compiler{}
+	c.compile(s, Identity3d(), false, 0)
+	return &ProgramSDF3{
+		sdf:   s,
+		code:  c.code,
+		regs:  c.regs + 1,
+		stack: c.maxDepth,
+		bb:    s.BoundingBox(),
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// compiler holds the state for compiling a program.
+type compiler struct {
+	code     []instruction
+	regs     int // highest point register used
+	depth    int // current value stack depth
+	maxDepth int
+}
+
+// push records a value stack push.
+func (c *compiler) push() {
+	c.depth++
+	if c.depth > c.maxDepth {
+		c.maxDepth = c.depth
+	}
+}
+
+// isLeaf returns true if the sdf is compiled to a single leaf instruction.
+func isLeaf(s SDF3) bool {
+	switch s.(type) {
+	case *TransformSDF3, *ScaleUniformSDF3, *UnionSDF3, *DifferenceSDF3, *IntersectionSDF3, *OffsetSDF3:
+		return false
+	}
+	return true
+}
+
+// mulAdd emits top = top * k + c, folding into a previous mulAdd.
+func (c *compiler) mulAdd(k, a float64) {
+	if n := len(c.code); n > 0 && c.code[n-1].op == opMulAdd {
+		last := &c.code[n-1]
+		last.k, last.c = last.k*k, last.c*k+a
+		return
+	}
+	c.code = append(c.code, instruction{op: opMulAdd, k: k, c: a})
+}
+
+// leaf emits a leaf instruction.
+func (c *compiler) leaf(s SDF3, m M44, xform bool, reg int) {
+	i := instruction{op: opSDF, reg: reg, xform: xform, m: m, sdf: s}
+	switch x := s.(type) {
+	case *SphereSDF3:
+		i.op, i.sphere = opSphere, x
+	case *BoxSDF3:
+		i.op, i.box = opBox, x
+	case *CylinderSDF3:
+		i.op, i.cyl = opCylinder, x
+	}
+	c.code = append(c.code, i)
+	c.push()
+}
+
+// unionChildren returns the children of a union, merging nested default unions.
+func unionChildren(s *UnionSDF3, merge bool) []SDF3 {
+	var children []SDF3
+	for _, x := range s.sdf {
+		if u, ok := x.(*UnionSDF3); ok && merge && sameFunc(u.min, math.Min) {
+			children = append(children, unionChildren(u, true)...)
+		} else {
+			children = append(children, x)
+		}
+	}
+	return children
+}
+
+// compile emits the code for an sdf. m is the pending transform of the point in register reg.
+func (c *compiler) compile(s SDF3, m M44, xform bool, reg int) {
+	switch x := s.(type) {
+	case *TransformSDF3:
+		m = x.inverse.Mul(m)
+		c.compile(x.sdf, m, !m.Equals(Identity3d(), 0), reg)
+		return
+	case *ScaleUniformSDF3:
+		m = Scale3d(v3.Vec{x.invK, x.invK, x.invK}).Mul(m)
+		c.compile(x.sdf, m, !m.Equals(Identity3d(), 0), reg)
+		c.mulAdd(x.k, 0)
+		return
+	}
+
+	if isLeaf(s) {
+		c.leaf(s, m, xform, reg)
+		return
+	}
+
+	// transform the point once for all the leaves of the operation
+	if xform {
+		dst := reg + 1
+		c.code = append(c.code, instruction{op: opTransform, reg: reg, dst: dst, m: m})
+		if dst > c.regs {
+			c.regs = dst
+		}
+		reg, m, xform = dst, Identity3d(), false
+	}
+
+	switch x := s.(type) {
+	case *UnionSDF3:
+		def := sameFunc(x.min, math.Min)
+		children := unionChildren(x, def)
+		for _, child := range children {
+			c.compile(child, m, xform, reg)
+		}
+		i := instruction{op: opMin, n: len(children)}
+		if !def {
+			i.op, i.min = opMinFunc, x.min
+		}
+		c.code = append(c.code, i)
+		c.depth -= len(children) - 1
+	case *DifferenceSDF3, *IntersectionSDF3:
+		var s0, s1 SDF3
+		var max MaxFunc
+		op := opMax
+		if d, ok := x.(*DifferenceSDF3); ok {
+			s0, s1, max, op = d.s0, d.s1, d.max, opDiff
+		} else {
+			i := x.(*IntersectionSDF3)
+			s0, s1, max = i.s0, i.s1, i.max
+		}
+		c.compile(s0, m, xform, reg)
+		c.compile(s1, m, xform, reg)
+		if sameFunc(max, math.Max) {
+			max = nil
+		}
+		c.code = append(c.code, instruction{op: op, max: max})
+		c.depth--
+	case *OffsetSDF3:
+		c.compile(x.sdf, m, xform, reg)
+		c.mulAdd(1, -x.offset)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// Evaluate returns the minimum distance to a compiled SDF3.
+func (s *ProgramSDF3) Evaluate(p v3.Vec) float64 {
+	var regBuf [progRegs]v3.Vec
+	var stackBuf [progStack]float64
+	regs, stack := regBuf[:], stackBuf[:]
+	if s.regs > progRegs {
+		regs = make([]v3.Vec, s.regs)
+	}
+	if s.stack > progStack {
+		stack = make([]float64, s.stack)
+	}
+	regs[0] = p
+	sp := 0
+	for i := range s.code {
+		c := &s.code[i]
+		switch c.op {
+		case opTransform:
+			regs[c.dst] = c.m.MulPosition(regs[c.reg])
+			continue
+		case opMin:
+			sp -= c.n
+			d := stack[sp]
+			for _, x := range stack[sp+1 : sp+c.n] {
+				d = math.Min(d, x)
+			}
+			stack[sp] = d
+			sp++
+			continue
+		case opMinFunc:
+			sp -= c.n
+			d := stack[sp]
+			for _, x := range stack[sp+1 : sp+c.n] {
+				d = c.min(d, x)
+			}
+			stack[sp] = d
+			sp++
+			continue
+		case opMax, opDiff:
+			sp--
+			a, b := stack[sp-1], stack[sp]
+			if c.op == opDiff {
+				b = -b
+			}
+			if c.max == nil {
+				stack[sp-1] = math.Max(a, b)
+			} else {
+				stack[sp-1] = c.max(a, b)
+			}
+			continue
+		case opMulAdd:
+			stack[sp-1] = stack[sp-1]*c.k + c.c
+			continue
+		}
+		// leaves
+		q := regs[c.reg]
+		if c.xform {
+			q = c.m.MulPosition(q)
+		}
+		switch c.op {
+		case opSphere:
+			stack[sp] = c.sphere.Evaluate(q)
+		case opBox:
+			stack[sp] = c.box.Evaluate(q)
+		case opCylinder:
+			stack[sp] = c.cyl.Evaluate(q)
+		default:
+			stack[sp] = c.sdf.Evaluate(q)
+		}
+		sp++
+	}
+	return stack[0]
+}
+
+// BoundingBox returns the bounding box of a compiled SDF3.
+func (s *ProgramSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+// Children returns the source SDF3.
+func (s *ProgramSDF3) Children() []interface{} {
+	return []interface{}{s.sdf}
+}
+
+// String returns a listing of the program.
+func (s *ProgramSDF3) String() string {
+	var sb strings.Builder
+	for i, c := range s.code {
+		fmt.Fprintf(&sb, "%3d %-9s", i, opName[c.op])
+		switch c.op {
+		case opTransform:
+			fmt.Fprintf(&sb, " r%d <- r%d", c.dst, c.reg)
+		case opMin, opMinFunc:
+			fmt.Fprintf(&sb, " %d", c.n)
+		case opMulAdd:
+			fmt.Fprintf(&sb, " %g %g", c.k, c.c)
+		case opMax, opDiff:
+		default:
+			fmt.Fprintf(&sb, " r%d", c.reg)
+			if c.xform {
+				sb.WriteString(" (transformed)")
+			}
+			if c.op == opSDF {
+				fmt.Fprintf(&sb, " %s", TypeName(c.sdf))
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,86 @@
+//-----------------------------------------------------------------------------
+/*
+
+SDF3 Compiler Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"strings"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// compileTree returns a deep tree of transforms and unions.
+func compileTree() SDF3 {
+	sphere, _ := Sphere3D(2)
+	box, _ := Box3D(v3.Vec{3, 3, 3}, 0.5)
+	cyl, _ := Cylinder3D(4, 1, 0.2)
+	circle, _ := Circle2D(1)
+	ext := Extrude3D(circle, 5)
+	var parts []SDF3
+	for i, s := range []SDF3{sphere, box, cyl, ext} {
+		x := Transform3D(s, RotateZ(DtoR(float64(20*i))).Mul(Translate3d(v3.Vec{float64(4 * i), 0, 0})))
+		parts = append(parts, Transform3D(x, RotateX(DtoR(10))))
+	}
+	ring := Union3D(Union3D(parts[0], parts[1]), Union3D(parts[2], parts[3]))
+	blend := Union3D(sphere, Transform3D(box, Translate3d(v3.Vec{2, 0, 0})))
+	blend.(*UnionSDF3).SetMin(PolyMin(0.5))
+	s := Union3D(
+		ScaleUniform3D(Transform3D(ring, Translate3d(v3.Vec{1, 2, 3})), 1.5),
+		Difference3D(Offset3D(blend, 0.5), Transform3D(cyl, RotateY(DtoR(90)))),
+		Intersect3D(Transform3D(ring, RotateZ(DtoR(45))), box),
+	)
+	return Transform3D(s, Translate3d(v3.Vec{0, 0, -5}))
+}
+
+func Test_Compile3D(t *testing.T) {
+	s := compileTree()
+	c := Compile3D(s)
+	bb := s.BoundingBox().ScaleAboutCenter(1.2)
+	for _, p := range bb.RandomSet(10000) {
+		if d0, d1 := s.Evaluate(p), c.Evaluate(p); !EqualFloat64(d0, d1, tolerance) {
+			t.Fatalf("%v: expected %f, got %f", p, d0, d1)
+		}
+	}
+	listing := c.(*ProgramSDF3).String()
+	// the nested unions are merged
+	if !strings.Contains(listing, "min       4") {
+		t.Errorf("nested unions not merged\n%s", listing)
+	}
+	// the extrusion is an opaque leaf
+	if !strings.Contains(listing, "ExtrudeSDF3") {
+		t.Errorf("missing opaque leaf\n%s", listing)
+	}
+
+	// no transforms or unions
+	s2 := Extrude3D(Box2D(v2.Vec{1, 1}, 0), 1)
+	if d0, d1 := s2.Evaluate(v3.Vec{1, 1, 1}), Compile3D(s2).Evaluate(v3.Vec{1, 1, 1}); d0 != d1 {
+		t.Errorf("expected %f, got %f", d0, d1)
+	}
+}
+
+func Benchmark_Compile3D(b *testing.B) {
+	s := Compile3D(compileTree())
+	p := v3.Vec{1, 2, 3}
+	for i := 0; i < b.N; i++ {
+		s.Evaluate(p)
+	}
+}
+
+func Benchmark_Compile3DTree(b *testing.B) {
+	s := compileTree()
+	p := v3.Vec{1, 2, 3}
+	for i := 0; i < b.N; i++ {
+		s.Evaluate(p)
+	}
+}
+
+//-----------------------------------------------------------------------------
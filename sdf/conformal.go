@@ -0,0 +1,128 @@
+//-----------------------------------------------------------------------------
+/*
+
+Conformal Arrays
+
+Place copies of an item on the surface of a target SDF3. Each copy has its
+origin on the surface and its +z axis along the surface normal, so studs,
+spikes or bumps stand out from a curved shell.
+
+Placement points are found by sampling the target surface and then picking
+points that are as far apart as possible (farthest point sampling).
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// conformalMaxCells limits the surface sampling grid size on each axis.
+const conformalMaxCells = 200
+
+// alignZ returns a rotation of the +z axis onto a direction.
+func alignZ(n v3.Vec) M44 {
+	if n.Z < 0 {
+		// RotateToVector gives a reflection for opposite vectors, and is
+		// inexact for nearly opposite vectors, so turn +z to -z first.
+		return RotateToVector(v3.Vec{0, 0, -1}, n).Mul(RotateX(Pi))
+	}
+	return RotateToVector(v3.Vec{0, 0, 1}, n)
+}
+
+//-----------------------------------------------------------------------------
+
+// ConformalArraySDF3 is a set of copies of an SDF3 placed on the surface of another SDF3.
+type ConformalArraySDF3 struct {
+	sdf    SDF3
+	frames []M44 // inverse placement transforms
+	min    MinFunc
+	bb     Box3
+}
+
+// ConformalArray3D returns up to count copies of an item placed on the surface
+// of a target SDF3 with at least the given spacing between them. The item origin
+// is placed on the surface and the item +z axis is aligned with the surface normal.
+func ConformalArray3D(item, target SDF3, count int, spacing float64) (SDF3, error) {
+	if item == nil || target == nil {
+		return nil, ErrMsg("nil sdf")
+	}
+	if count <= 0 {
+		return nil, ErrMsg("count <= 0")
+	}
+	if spacing <= 0 {
+		return nil, ErrMsg("spacing <= 0")
+	}
+	cell := math.Max(0.25*spacing, target.BoundingBox().Size().MaxComponent()/conformalMaxCells)
+	points, normals := surfaceSamples3(target, cell)
+	if len(points) == 0 {
+		return nil, ErrMsg("no surface found")
+	}
+	// farthest point sampling, starting with the lowest sample
+	first := 0
+	for i, p := range points {
+		if p.Z < points[first].Z {
+			first = i
+		}
+	}
+	dist := make([]float64, len(points)) // distance from each sample to the nearest placement
+	for i := range dist {
+		dist[i] = math.MaxFloat64
+	}
+	s := ConformalArraySDF3{
+		sdf: item,
+		min: math.Min,
+	}
+	next := first
+	for len(s.frames) < count {
+		p, n := points[next], normals[next]
+		m := Translate3d(p).Mul(alignZ(n))
+		bb := m.MulBox(item.BoundingBox())
+		if len(s.frames) == 0 {
+			s.bb = bb
+		} else {
+			s.bb = s.bb.Extend(bb)
+		}
+		s.frames = append(s.frames, m.Inverse())
+		// update the distances and find the next placement
+		far := 0.0
+		for i, x := range points {
+			dist[i] = math.Min(dist[i], x.Sub(p).Length())
+			if dist[i] > far {
+				far = dist[i]
+				next = i
+			}
+		}
+		if far < spacing {
+			break
+		}
+	}
+	return &s, nil
+}
+
+// SetMin sets the minimum function to control blending.
+func (s *ConformalArraySDF3) SetMin(min MinFunc) {
+	s.min = min
+}
+
+// Evaluate returns the minimum distance to a conformal array.
+func (s *ConformalArraySDF3) Evaluate(p v3.Vec) float64 {
+	d := math.MaxFloat64
+	for _, m := range s.frames {
+		d = s.min(d, s.sdf.Evaluate(m.MulPosition(p)))
+	}
+	return d
+}
+
+// BoundingBox returns the bounding box of a conformal array.
+func (s *ConformalArraySDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
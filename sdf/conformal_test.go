@@ -0,0 +1,70 @@
+//-----------------------------------------------------------------------------
+/*
+
+Conformal Array Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_ConformalArray(t *testing.T) {
+	// a chiral item, an L shape
+	a, _ := Box3D(v3.Vec{3, 1, 1}, 0)
+	b, _ := Box3D(v3.Vec{1, 2, 1}, 0)
+	item := Union3D(Transform3D(a, Translate3d(v3.Vec{1.5, 0, 0.5})), Transform3D(b, Translate3d(v3.Vec{0, 1, 0.5})))
+
+	const radius = 20.0
+	sphere, _ := Sphere3D(radius)
+	s, err := ConformalArray3D(item, sphere, 12, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := s.(*ConformalArraySDF3).frames
+	if len(frames) != 12 {
+		t.Fatalf("expected 12 items, got %d", len(frames))
+	}
+	lowest := false
+	for i, f := range frames {
+		m := f.Inverse()
+		// a rotation, not a reflection
+		if !EqualFloat64(m.Determinant(), 1, 1e-9) {
+			t.Errorf("item %d: determinant %g", i, m.Determinant())
+		}
+		// the origin on the surface, +z along the normal
+		p := m.MulPosition(v3.Vec{})
+		if !EqualFloat64(p.Length(), radius, 0.01) {
+			t.Errorf("item %d: %v is not on the surface", i, p)
+		}
+		z := m.MulPosition(v3.Vec{0, 0, 1}).Sub(p)
+		if z.Dot(p.Normalize()) < 0.999 {
+			t.Errorf("item %d: +z %v is not along the normal %v", i, z, p.Normalize())
+		}
+		if p.Z < -0.99*radius {
+			lowest = true
+		}
+		// the item is there
+		if d := s.Evaluate(m.MulPosition(v3.Vec{1.5, 0, 0.5})); d > -0.4 {
+			t.Errorf("item %d: expected the item at %v, got %g", i, p, d)
+		}
+	}
+	if !lowest {
+		t.Error("expected an item at the bottom of the sphere")
+	}
+	for _, n := range []v3.Vec{{0, 0, -1}, {1e-9, 0, -1}, {0, 1, -0.01}, {1, 2, 3}} {
+		m := alignZ(n)
+		if !m.MulPosition(v3.Vec{0, 0, 1}).Equals(n.Normalize(), 1e-6) || !EqualFloat64(m.Determinant(), 1, 1e-9) {
+			t.Errorf("bad rotation for %v", n)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,120 @@
+//-----------------------------------------------------------------------------
+/*
+
+Detail Hints
+
+A model may need a fine render resolution in a few places only, e.g. a
+small engraved logo on a large plate. DetailHint marks a subtree with the
+size of its smallest feature, and renderers that support hints (the octree
+marching cubes renderer) subdivide more finely within the bounding box of
+the subtree, so the rest of the model is rendered at a coarser resolution.
+
+The hints are found by walking the model tree (see visit.go). The bounding
+box and feature size of a hint are mapped through the transforms above it.
+Any other node that moves its children (arrays, mirrors, ...) makes the
+hint cover the bounding box of that node.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// DetailSDF3 is an SDF3 with a render resolution hint.
+type DetailSDF3 struct {
+	sdf  SDF3
+	size float64
+}
+
+// DetailHint marks an SDF3 with the size of its smallest feature, so
+// renderers can use a finer resolution for it. A size <= 0 returns the SDF3.
+func DetailHint(s SDF3, minFeatureSize float64) SDF3 {
+	if s == nil || minFeatureSize <= 0 {
+		return s
+	}
+	return &DetailSDF3{sdf: s, size: minFeatureSize}
+}
+
+// Evaluate returns the minimum distance to a hinted SDF3.
+func (s *DetailSDF3) Evaluate(p v3.Vec) float64 {
+	return s.sdf.Evaluate(p)
+}
+
+// BoundingBox returns the bounding box of a hinted SDF3.
+func (s *DetailSDF3) BoundingBox() Box3 {
+	return s.sdf.BoundingBox()
+}
+
+// MinFeatureSize returns the size of the smallest feature of a hinted SDF3.
+func (s *DetailSDF3) MinFeatureSize() float64 {
+	return s.size
+}
+
+// Children returns the hinted SDF3.
+func (s *DetailSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+//-----------------------------------------------------------------------------
+
+// DetailRegion is a region of a model with a smaller feature size.
+type DetailRegion struct {
+	Box  Box3    // region bounds
+	Size float64 // size of the smallest feature in the region
+}
+
+// matrixScale returns the smallest axis scaling of a transform.
+func matrixScale(m M44) float64 {
+	k := math.Inf(1)
+	for j := 0; j < 3; j++ {
+		k = math.Min(k, math.Sqrt(m[j]*m[j]+m[4+j]*m[4+j]+m[8+j]*m[8+j]))
+	}
+	return k
+}
+
+// detailRegions adds the regions of the hints in a subtree. m maps the
+// subtree to the model, and outer is the region of any node above the
+// subtree that moves its children.
+func detailRegions(s interface{}, m M44, outer *Box3, regions *[]DetailRegion) {
+	switch x := s.(type) {
+	case *DetailSDF3:
+		bb := m.MulBox(x.BoundingBox())
+		if outer != nil {
+			bb = *outer
+		}
+		*regions = append(*regions, DetailRegion{Box: bb, Size: x.size * matrixScale(m)})
+	case *TransformSDF3:
+		m = m.Mul(x.matrix)
+	case *ScaleUniformSDF3:
+		m = m.Mul(Scale3d(v3.Vec{X: x.k, Y: x.k, Z: x.k}))
+	case *UnionSDF3, *DifferenceSDF3, *IntersectionSDF3, *MaterialSDF3, *CacheSDF3:
+		// the children are in place
+	case SDF3:
+		if outer == nil {
+			bb := m.MulBox(x.BoundingBox())
+			outer = &bb
+		}
+	default:
+		// SDF2s have no hints
+		return
+	}
+	if c, ok := s.(Composite); ok {
+		for _, child := range c.Children() {
+			detailRegions(child, m, outer, regions)
+		}
+	}
+}
+
+// DetailRegions returns the regions of the detail hints in a model.
+func DetailRegions(s SDF3) []DetailRegion {
+	var regions []DetailRegion
+	detailRegions(s, Identity3d(), nil, &regions)
+	return regions
+}
+
+//-----------------------------------------------------------------------------
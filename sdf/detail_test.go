@@ -0,0 +1,53 @@
+//-----------------------------------------------------------------------------
+/*
+
+Detail Hint Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_DetailRegions(t *testing.T) {
+	box, _ := Box3D(v3.Vec{4, 4, 4}, 0)
+	sphere, _ := Sphere3D(1)
+	if DetailHint(sphere, 0) != sphere {
+		t.Error("a zero size hint should return the SDF3")
+	}
+	hint := DetailHint(sphere, 0.5)
+	if hint.Evaluate(v3.Vec{}) != sphere.Evaluate(v3.Vec{}) {
+		t.Error("a hint should not change the distance")
+	}
+	if len(DetailRegions(box)) != 0 {
+		t.Error("expected no regions")
+	}
+
+	// the region is mapped through the transforms
+	s := Union3D(box, ScaleUniform3D(Transform3D(hint, Translate3d(v3.Vec{10, 0, 0})), 2))
+	r := DetailRegions(s)
+	if len(r) != 1 {
+		t.Fatalf("expected 1 region, got %d", len(r))
+	}
+	want := Box3{v3.Vec{18, -2, -2}, v3.Vec{22, 2, 2}}
+	if !r[0].Box.Equals(want, tolerance) || !EqualFloat64(r[0].Size, 1, tolerance) {
+		t.Errorf("expected %v size 1, got %v size %g", want, r[0].Box, r[0].Size)
+	}
+
+	// a node that moves its children makes the region its bounding box
+	a := Array3D(hint, v3i.Vec{3, 1, 1}, v3.Vec{5, 0, 0})
+	r = DetailRegions(a)
+	if len(r) != 1 || !r[0].Box.Equals(a.BoundingBox(), tolerance) || r[0].Size != 0.5 {
+		t.Errorf("unexpected regions %v", r)
+	}
+}
+
+//-----------------------------------------------------------------------------
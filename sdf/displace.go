@@ -0,0 +1,103 @@
+//-----------------------------------------------------------------------------
+/*
+
+Displacement Mapping
+
+Perturb the surface of an SDF3 by a bounded scalar function, e.g. noise for
+organic surface detail. Positive values of the function move the surface
+outwards.
+
+Adding a function to a distance field increases its Lipschitz constant by
+the maximum gradient of the function, so the displaced field is scaled down
+to keep it a distance bound. The maximum gradient of the function is
+estimated by sampling, or it can be set explicitly.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// displaceSamples is the number of samples used to estimate the displacement gradient.
+const displaceSamples = 1000
+
+// DisplaceSDF3 is an SDF3 with its surface displaced by a scalar function.
+type DisplaceSDF3 struct {
+	sdf    SDF3
+	f      func(v3.Vec) float64 // displacement function
+	maxAmp float64              // maximum displacement amplitude
+	k      float64              // 1 / (1 + displacement Lipschitz constant)
+	bb     Box3
+}
+
+// Displace3D returns an SDF3 with its surface displaced by a scalar function.
+// The displacement is clamped to +/- maxAmp.
+func Displace3D(s SDF3, f func(v3.Vec) float64, maxAmp float64) (SDF3, error) {
+	if s == nil {
+		return nil, ErrMsg("s == nil")
+	}
+	if f == nil {
+		return nil, ErrMsg("f == nil")
+	}
+	if maxAmp <= 0 {
+		return nil, ErrMsg("maxAmp <= 0")
+	}
+	d := DisplaceSDF3{
+		sdf:    s,
+		f:      f,
+		maxAmp: maxAmp,
+	}
+	d.bb = s.BoundingBox().Enlarge(v3.Vec{2 * maxAmp, 2 * maxAmp, 2 * maxAmp})
+	// estimate the maximum gradient of the displacement function
+	eps := d.bb.Size().MaxComponent() * 1e-4
+	l := 0.0
+	for _, p := range d.bb.RandomSet(displaceSamples) {
+		g := v3.Vec{
+			X: d.displace(p.Add(v3.Vec{X: eps})) - d.displace(p.Add(v3.Vec{X: -eps})),
+			Y: d.displace(p.Add(v3.Vec{Y: eps})) - d.displace(p.Add(v3.Vec{Y: -eps})),
+			Z: d.displace(p.Add(v3.Vec{Z: eps})) - d.displace(p.Add(v3.Vec{Z: -eps})),
+		}
+		l = math.Max(l, g.Length()/(2*eps))
+	}
+	// sampling can miss the steepest point, so allow some margin
+	d.SetLipschitz(1.25 * l)
+	return &d, nil
+}
+
+// SetLipschitz sets the Lipschitz constant (maximum gradient) of the displacement function.
+func (s *DisplaceSDF3) SetLipschitz(l float64) {
+	s.k = 1 / (1 + math.Abs(l))
+}
+
+// displace returns the clamped displacement at a point.
+func (s *DisplaceSDF3) displace(p v3.Vec) float64 {
+	return Clamp(s.f(p), -s.maxAmp, s.maxAmp)
+}
+
+// Evaluate returns the minimum distance to a displaced SDF3.
+func (s *DisplaceSDF3) Evaluate(p v3.Vec) float64 {
+	d0 := s.sdf.Evaluate(p)
+	d := (d0 - s.displace(p)) * s.k
+	// the displaced surface is within maxAmp of the original surface
+	if d0 > s.maxAmp {
+		return math.Max(d, d0-s.maxAmp)
+	}
+	if d0 < -s.maxAmp {
+		return math.Min(d, d0+s.maxAmp)
+	}
+	return d
+}
+
+// BoundingBox returns the bounding box of a displaced SDF3.
+func (s *DisplaceSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,81 @@
+//-----------------------------------------------------------------------------
+/*
+
+Displacement Mapping Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Displace3D(t *testing.T) {
+	sphere, _ := Sphere3D(10)
+	// bumps with a maximum gradient of sqrt(3)
+	bumps := func(p v3.Vec) float64 {
+		return math.Sin(p.X) * math.Sin(p.Y) * math.Sin(p.Z)
+	}
+	s, err := Displace3D(sphere, bumps, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bb := s.BoundingBox()
+	test := bb.Enlarge(v3.Vec{2, 2, 2})
+	for i := 0; i < 10000; i++ {
+		p := test.Random()
+		d := s.Evaluate(p)
+		// the surface is the sphere moved out by the bumps
+		if g := p.Length() - 10 - bumps(p); math.Abs(g) > 1e-9 && math.Signbit(d) != math.Signbit(g) {
+			t.Fatalf("%v: distance %g, expected the sign of %g", p, d, g)
+		}
+		if d <= 0 && !bb.Contains(p) {
+			t.Fatalf("%v: inside point outside the bounding box", p)
+		}
+		// the distance changes no faster than the position (a distance bound)
+		for _, r := range []float64{0.01, 0.5, 3} {
+			q := p.Add(v3.Vec{randomRange(-1, 1), randomRange(-1, 1), randomRange(-1, 1)}.Normalize().MulScalar(r))
+			if dd := math.Abs(s.Evaluate(q) - d); dd > r*(1+1e-6) {
+				t.Fatalf("%v: distance changes by %g over %g", p, dd, r)
+			}
+		}
+	}
+
+	// the displacement is clamped to the amplitude
+	s, _ = Displace3D(sphere, func(p v3.Vec) float64 { return 5 }, 1)
+	if d := s.Evaluate(v3.Vec{11, 0, 0}); math.Abs(d) > 1e-9 {
+		t.Errorf("expected the surface at radius 11, got distance %g", d)
+	}
+	if d := s.Evaluate(v3.Vec{20, 0, 0}); math.Abs(d-9) > 1e-9 {
+		t.Errorf("expected distance 9, got %g", d)
+	}
+
+	// an explicit Lipschitz constant scales the distance
+	s, _ = Displace3D(sphere, bumps, 1)
+	s.(*DisplaceSDF3).SetLipschitz(3)
+	p := v3.Vec{10.5, 0.2, 0.3}
+	if d, expected := s.Evaluate(p), (p.Length()-10-bumps(p))/4; math.Abs(d-expected) > 1e-9 {
+		t.Errorf("expected distance %g, got %g", expected, d)
+	}
+
+	if _, err := Displace3D(nil, bumps, 1); err == nil {
+		t.Error("expected an error for a nil sdf")
+	}
+	if _, err := Displace3D(sphere, nil, 1); err == nil {
+		t.Error("expected an error for a nil function")
+	}
+	for _, amp := range []float64{0, -1} {
+		if _, err := Displace3D(sphere, bumps, amp); err == nil {
+			t.Errorf("expected an error for amplitude %g", amp)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
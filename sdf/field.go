@@ -0,0 +1,256 @@
+//-----------------------------------------------------------------------------
+/*
+
+Scalar Fields
+
+A scalar field maps each point in space to a value. Fields drive the
+parameters of field-driven operators, e.g. the density of a foam, the
+thickness of a shell or the amplitude of a surface texture.
+
+Fields can come from formulas, images, scattered data (e.g. FE stress
+results) or the distance to a feature, and they can be remapped and
+combined before use.
+
+A ScalarField is a plain function, so it can be passed to any operator
+that takes a func(v3.Vec) float64 (e.g. Displace3D, FoamParms.DensityField).
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// fieldSamples is the number of samples used to estimate the field gradient.
+const fieldSamples = 1000
+
+// ScalarField is a scalar function of position.
+type ScalarField func(p v3.Vec) float64
+
+// FieldConstant returns a field with a constant value.
+func FieldConstant(v float64) ScalarField {
+	return func(p v3.Vec) float64 { return v }
+}
+
+// FieldLinear returns a field that varies linearly from v0 at p0 to v1 at p1.
+// The value is clamped beyond p0 and p1.
+func FieldLinear(p0, p1 v3.Vec, v0, v1 float64) ScalarField {
+	d := p1.Sub(p0)
+	l2 := d.Length2()
+	return func(p v3.Vec) float64 {
+		if l2 == 0 {
+			return v0
+		}
+		t := Clamp(p.Sub(p0).Dot(d)/l2, 0, 1)
+		return Mix(v0, v1, t)
+	}
+}
+
+// FieldDistance returns the signed distance to a feature.
+func FieldDistance(s SDF3) ScalarField {
+	return s.Evaluate
+}
+
+// FieldImage returns a field from the luminance (0..1) of an image.
+// The image covers the rectangle in the xy plane and is projected along z.
+// Outside the rectangle the edge pixels are extended.
+func FieldImage(img image.Image, rect Box2) (ScalarField, error) {
+	if img == nil {
+		return nil, ErrMsg("img == nil")
+	}
+	b := img.Bounds()
+	nx, ny := b.Dx(), b.Dy()
+	if nx == 0 || ny == 0 {
+		return nil, ErrMsg("empty image")
+	}
+	size := rect.Size()
+	if size.X <= 0 || size.Y <= 0 {
+		return nil, ErrMsg("rect size <= 0")
+	}
+	// cache the luminance, the image y-axis points down
+	lum := make([]float64, nx*ny)
+	for y := 0; y < ny; y++ {
+		for x := 0; x < nx; x++ {
+			g := color.Gray16Model.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray16)
+			lum[(ny-1-y)*nx+x] = float64(g.Y) / 0xffff
+		}
+	}
+	pixel := func(x, y int) float64 {
+		return lum[clampInt(y, 0, ny-1)*nx+clampInt(x, 0, nx-1)]
+	}
+	return func(p v3.Vec) float64 {
+		// pixel centers are at half pixel offsets
+		q := v2.Vec{p.X, p.Y}.Sub(rect.Min).Div(size).Mul(v2.Vec{float64(nx), float64(ny)}).SubScalar(0.5)
+		x0, y0 := math.Floor(q.X), math.Floor(q.Y)
+		tx, ty := q.X-x0, q.Y-y0
+		x, y := int(x0), int(y0)
+		a := Mix(pixel(x, y), pixel(x+1, y), tx)
+		c := Mix(pixel(x, y+1), pixel(x+1, y+1), tx)
+		return Mix(a, c, ty)
+	}, nil
+}
+
+// clampInt clamps x to the range a..b.
+func clampInt(x, a, b int) int {
+	return maxInt(a, minInt(x, b))
+}
+
+// FieldPoints returns a field interpolated from values at scattered points (e.g. FE results).
+// Points within the radius are blended with inverse distance weighting. The value is 0
+// where there are no points within the radius.
+func FieldPoints(points v3.VecSet, values []float64, radius float64) (ScalarField, error) {
+	if len(points) == 0 || len(points) != len(values) {
+		return nil, ErrMsg("bad points/values")
+	}
+	if radius <= 0 {
+		return nil, ErrMsg("radius <= 0")
+	}
+	key := func(p v3.Vec) v3i.Vec {
+		return v3i.Vec{int(math.Floor(p.X / radius)), int(math.Floor(p.Y / radius)), int(math.Floor(p.Z / radius))}
+	}
+	grid := make(map[v3i.Vec][]int)
+	for i, p := range points {
+		k := key(p)
+		grid[k] = append(grid[k], i)
+	}
+	r2 := radius * radius
+	return func(p v3.Vec) float64 {
+		k := key(p)
+		var sw, sv float64
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for dz := -1; dz <= 1; dz++ {
+					for _, i := range grid[v3i.Vec{k.X + dx, k.Y + dy, k.Z + dz}] {
+						d2 := p.Sub(points[i]).Length2()
+						if d2 >= r2 {
+							continue
+						}
+						if d2 < epsilon*epsilon {
+							return values[i]
+						}
+						// the weight falls to zero at the radius
+						w := (1 - d2/r2) / d2
+						sw += w
+						sv += w * values[i]
+					}
+				}
+			}
+		}
+		if sw == 0 {
+			return 0
+		}
+		return sv / sw
+	}, nil
+}
+
+//-----------------------------------------------------------------------------
+// Modifiers
+
+// Remap linearly maps the field values from in0..in1 to out0..out1 (clamped).
+func (f ScalarField) Remap(in0, in1, out0, out1 float64) ScalarField {
+	return func(p v3.Vec) float64 {
+		if in0 == in1 {
+			return out0
+		}
+		return Mix(out0, out1, Clamp((f(p)-in0)/(in1-in0), 0, 1))
+	}
+}
+
+// Clamp clamps the field values to the range a..b.
+func (f ScalarField) Clamp(a, b float64) ScalarField {
+	return func(p v3.Vec) float64 { return Clamp(f(p), a, b) }
+}
+
+// Add returns the sum of two fields.
+func (f ScalarField) Add(g ScalarField) ScalarField {
+	return func(p v3.Vec) float64 { return f(p) + g(p) }
+}
+
+// Mul returns the product of two fields.
+func (f ScalarField) Mul(g ScalarField) ScalarField {
+	return func(p v3.Vec) float64 { return f(p) * g(p) }
+}
+
+// Lipschitz estimates the maximum gradient of the field within a box by sampling.
+func (f ScalarField) Lipschitz(bb Box3) float64 {
+	eps := bb.Size().MaxComponent() * 1e-4
+	l := 0.0
+	for _, p := range bb.RandomSet(fieldSamples) {
+		g := v3.Vec{
+			X: f(p.Add(v3.Vec{X: eps})) - f(p.Add(v3.Vec{X: -eps})),
+			Y: f(p.Add(v3.Vec{Y: eps})) - f(p.Add(v3.Vec{Y: -eps})),
+			Z: f(p.Add(v3.Vec{Z: eps})) - f(p.Add(v3.Vec{Z: -eps})),
+		}
+		l = math.Max(l, g.Length()/(2*eps))
+	}
+	// sampling can miss the steepest point, so allow some margin
+	return 1.25 * l
+}
+
+//-----------------------------------------------------------------------------
+// Field Driven Operators
+
+// FieldShellSDF3 shells the surface of an SDF3 with a thickness given by a field.
+type FieldShellSDF3 struct {
+	sdf       SDF3
+	thickness ScalarField
+	max       float64 // maximum thickness
+	k         float64 // 1 / (1 + thickness Lipschitz constant / 2)
+	bb        Box3
+}
+
+// FieldShell3D returns an SDF3 that shells the surface of an SDF3 with a variable thickness.
+// The thickness is clamped to 0..maxThickness.
+func FieldShell3D(s SDF3, thickness ScalarField, maxThickness float64) (SDF3, error) {
+	if s == nil || thickness == nil {
+		return nil, ErrMsg("s/thickness == nil")
+	}
+	if maxThickness <= 0 {
+		return nil, ErrMsg("maxThickness <= 0")
+	}
+	t := thickness.Clamp(0, maxThickness)
+	bb := s.BoundingBox().Enlarge(v3.Vec{maxThickness, maxThickness, maxThickness})
+	return &FieldShellSDF3{
+		sdf:       s,
+		thickness: t,
+		max:       maxThickness,
+		k:         1 / (1 + 0.5*t.Lipschitz(bb)),
+		bb:        bb,
+	}, nil
+}
+
+// Evaluate returns the minimum distance to a variable thickness shell.
+func (s *FieldShellSDF3) Evaluate(p v3.Vec) float64 {
+	d0 := math.Abs(s.sdf.Evaluate(p))
+	// the shell is within half the maximum thickness of the surface
+	if d0 > 0.5*s.max {
+		return d0 - 0.5*s.max
+	}
+	return (d0 - 0.5*s.thickness(p)) * s.k
+}
+
+// BoundingBox returns the bounding box of a variable thickness shell.
+func (s *FieldShellSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+// FieldTexture3D returns an SDF3 with a surface texture (e.g. noise in -1..1) whose
+// amplitude is given by a field. The amplitude is clamped to 0..maxAmp.
+func FieldTexture3D(s SDF3, texture, amplitude ScalarField, maxAmp float64) (SDF3, error) {
+	if texture == nil || amplitude == nil {
+		return nil, ErrMsg("texture/amplitude == nil")
+	}
+	return Displace3D(s, texture.Mul(amplitude.Clamp(0, maxAmp)), maxAmp)
+}
+
+//-----------------------------------------------------------------------------
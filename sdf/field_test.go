@@ -0,0 +1,81 @@
+//-----------------------------------------------------------------------------
+/*
+
+Scalar Field Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_ScalarField(t *testing.T) {
+	// linear and remap
+	f := FieldLinear(v3.Vec{0, 0, 0}, v3.Vec{10, 0, 0}, 0, 1).Remap(0, 1, 2, 4)
+	tests := []struct {
+		p v3.Vec
+		v float64
+	}{
+		{v3.Vec{-5, 0, 0}, 2},
+		{v3.Vec{5, 3, 0}, 3},
+		{v3.Vec{20, 0, 0}, 4},
+	}
+	for _, v := range tests {
+		if x := f(v.p); !EqualFloat64(x, v.v, tolerance) {
+			t.Errorf("linear %v: expected %f, got %f", v.p, v.v, x)
+		}
+	}
+
+	// image: black on the left, white on the right
+	img := image.NewGray(image.Rect(0, 0, 2, 1))
+	img.SetGray(1, 0, color.Gray{Y: 255})
+	fi, err := FieldImage(img, Box2{v2.Vec{0, 0}, v2.Vec{2, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct{ x, v float64 }{{0, 0}, {0.5, 0}, {1, 0.5}, {1.5, 1}, {3, 1}} {
+		if x := fi(v3.Vec{v.x, 0.5, 7}); !EqualFloat64(x, v.v, tolerance) {
+			t.Errorf("image %f: expected %f, got %f", v.x, v.v, x)
+		}
+	}
+
+	// scattered points
+	fp, err := FieldPoints(v3.VecSet{{0, 0, 0}, {2, 0, 0}}, []float64{1, 3}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := fp(v3.Vec{1, 0, 0}); !EqualFloat64(x, 2, tolerance) {
+		t.Errorf("points: expected 2, got %f", x)
+	}
+	if x := fp(v3.Vec{0, 0, 0}); !EqualFloat64(x, 1, tolerance) {
+		t.Errorf("points: expected 1, got %f", x)
+	}
+	if x := fp(v3.Vec{20, 0, 0}); x != 0 {
+		t.Errorf("points: expected 0, got %f", x)
+	}
+
+	// variable thickness shell
+	sphere, _ := Sphere3D(10)
+	s, err := FieldShell3D(sphere, FieldLinear(v3.Vec{-10, 0, 0}, v3.Vec{10, 0, 0}, 1, 3), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Evaluate(v3.Vec{-10.4, 0, 0}) >= 0 || s.Evaluate(v3.Vec{-10.6, 0, 0}) <= 0 {
+		t.Error("shell: bad thickness at -x")
+	}
+	if s.Evaluate(v3.Vec{11.4, 0, 0}) >= 0 || s.Evaluate(v3.Vec{11.6, 0, 0}) <= 0 {
+		t.Error("shell: bad thickness at +x")
+	}
+}
+
+//-----------------------------------------------------------------------------
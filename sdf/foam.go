@@ -0,0 +1,161 @@
+//-----------------------------------------------------------------------------
+/*
+
+Stochastic Foam Infill
+
+Random cellular structures for energy absorbing parts (insoles, bumpers,
+padding). They complement the regular lattices (e.g. gyroids).
+
+Closed: the walls of voronoi cells over a jittered grid of seeds.
+Open: struts along the edges of the voronoi cells (trabecular foam).
+Noise: thresholded value noise (a random sponge).
+
+The density can be graded by a user field. For the voronoi styles the
+density sets the wall/strut thickness, for noise it sets the threshold.
+A graded density makes the foam a bounded rather than an exact distance
+field, so keep the grading gentle relative to the cell size.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// FoamStyle is the type of foam structure.
+type FoamStyle int
+
+// Foam styles.
+const (
+	FoamClosed FoamStyle = iota // voronoi cell walls
+	FoamOpen                    // struts along the voronoi cell edges
+	FoamNoise                   // thresholded value noise
+)
+
+// FoamParms defines the parameters for a foam structure.
+type FoamParms struct {
+	Style        FoamStyle   // foam style
+	Cell         float64     // mean cell size
+	Jitter       float64     // randomness of the cell seeds [0,1] (voronoi styles)
+	Seed         uint32      // random seed
+	MinThickness float64     // wall/strut thickness at density 0 (voronoi styles)
+	MaxThickness float64     // wall/strut thickness at density 1 (voronoi styles)
+	Density      float64     // relative density [0,1] (used if DensityField is nil)
+	DensityField ScalarField // graded relative density [0,1]
+	Skin         float64     // thickness of the solid skin around the infill (0 = none)
+}
+
+// FoamSDF3 is an unbounded stochastic foam.
+type FoamSDF3 struct {
+	k     FoamParms
+	cells *CellNoiseSDF3
+}
+
+// Foam3D returns an unbounded stochastic foam.
+func Foam3D(k *FoamParms) (SDF3, error) {
+	if k.Cell <= 0 {
+		return nil, ErrMsg("Cell <= 0")
+	}
+	if k.Density < 0 || k.Density > 1 {
+		return nil, ErrMsg("Density not in [0,1]")
+	}
+	if k.Skin < 0 {
+		return nil, ErrMsg("Skin < 0")
+	}
+	s := FoamSDF3{k: *k}
+	if k.Style == FoamClosed || k.Style == FoamOpen {
+		if k.MinThickness < 0 || k.MaxThickness < k.MinThickness {
+			return nil, ErrMsg("bad MinThickness/MaxThickness")
+		}
+		if k.MaxThickness >= k.Cell {
+			return nil, ErrMsg("MaxThickness >= Cell")
+		}
+		cells, err := CellNoise3D(k.Cell, k.Jitter, 0, VoronoiWalls, k.Seed)
+		if err != nil {
+			return nil, err
+		}
+		s.cells = cells.(*CellNoiseSDF3)
+	} else if k.Style != FoamNoise {
+		return nil, ErrMsg("unknown foam style")
+	}
+	return &s, nil
+}
+
+// density returns the relative density at p.
+func (s *FoamSDF3) density(p v3.Vec) float64 {
+	if s.k.DensityField == nil {
+		return s.k.Density
+	}
+	return Clamp(s.k.DensityField(p), 0, 1)
+}
+
+// valueNoise returns smooth value noise in [-1,1] with a unit lattice.
+func valueNoise(p v3.Vec, seed uint32) float64 {
+	x0, y0, z0 := math.Floor(p.X), math.Floor(p.Y), math.Floor(p.Z)
+	ix, iy, iz := int(x0), int(y0), int(z0)
+	// smoothstep weights
+	w := func(t float64) float64 { return t * t * (3 - 2*t) }
+	wx, wy, wz := w(p.X-x0), w(p.Y-y0), w(p.Z-z0)
+	v := func(i, j, k int) float64 { return 2*hash3(ix+i, iy+j, iz+k, seed) - 1 }
+	x00 := v(0, 0, 0) + wx*(v(1, 0, 0)-v(0, 0, 0))
+	x10 := v(0, 1, 0) + wx*(v(1, 1, 0)-v(0, 1, 0))
+	x01 := v(0, 0, 1) + wx*(v(1, 0, 1)-v(0, 0, 1))
+	x11 := v(0, 1, 1) + wx*(v(1, 1, 1)-v(0, 1, 1))
+	y0v := x00 + wy*(x10-x00)
+	y1v := x01 + wy*(x11-x01)
+	return y0v + wz*(y1v-y0v)
+}
+
+// valueNoiseLipschitz is a bound on the gradient of valueNoise.
+// The smoothstep slope is <= 1.5 and the lattice values span 2.
+var valueNoiseLipschitz = math.Sqrt(3) * 1.5 * 2
+
+// Evaluate returns the minimum distance to the foam.
+func (s *FoamSDF3) Evaluate(p v3.Vec) float64 {
+	density := s.density(p)
+	switch s.k.Style {
+	case FoamClosed, FoamOpen:
+		t := s.k.MinThickness + density*(s.k.MaxThickness-s.k.MinThickness)
+		e0, e1 := s.cells.planes(p)
+		if s.k.Style == FoamOpen {
+			// the cell edges are where two bisector planes meet
+			return math.Hypot(e0, e1) - 0.5*t
+		}
+		return e0 - 0.5*t
+	}
+	// noise: solid below the threshold, scaled to a distance bound
+	level := 2*density - 1
+	n := valueNoise(p.DivScalar(s.k.Cell), s.k.Seed)
+	return (n - level) * s.k.Cell / valueNoiseLipschitz
+}
+
+// BoundingBox returns the bounding box for a foam.
+func (s *FoamSDF3) BoundingBox() Box3 {
+	// The foam is defined for all xyz, so the bounding box is a point at the origin.
+	// To use the foam it needs to be intersected with an external bounding volume.
+	return Box3{}
+}
+
+//-----------------------------------------------------------------------------
+
+// FoamInfill3D returns an SDF3 filled with a stochastic foam inside an optional solid skin.
+func FoamInfill3D(s SDF3, k *FoamParms) (SDF3, error) {
+	foam, err := Foam3D(k)
+	if err != nil {
+		return nil, err
+	}
+	infill := Intersect3D(s, foam)
+	if k.Skin == 0 {
+		return infill, nil
+	}
+	skin := Difference3D(s, Offset3D(s, -k.Skin))
+	return Union3D(skin, infill), nil
+}
+
+//-----------------------------------------------------------------------------
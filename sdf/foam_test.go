@@ -0,0 +1,51 @@
+//-----------------------------------------------------------------------------
+/*
+
+Foam Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Foam3D(t *testing.T) {
+	// the density increases along x
+	box := NewBox3(v3.Vec{0, 0, 0}, v3.Vec{100, 100, 100})
+	grade := func(p v3.Vec) float64 { return (p.X + 50) / 100 }
+	for _, style := range []FoamStyle{FoamClosed, FoamOpen, FoamNoise} {
+		foam, err := Foam3D(&FoamParms{
+			Style:        style,
+			Cell:         10,
+			Jitter:       1,
+			MinThickness: 0.5,
+			MaxThickness: 4,
+			DensityField: grade,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var solid [2]int
+		for _, p := range box.RandomSet(4000) {
+			if foam.Evaluate(p) < 0 {
+				if p.X < 0 {
+					solid[0]++
+				} else {
+					solid[1]++
+				}
+			}
+		}
+		if solid[0] == 0 || solid[1] <= solid[0] {
+			t.Errorf("style %d: expected graded density, got %v", style, solid)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,485 @@
+//-----------------------------------------------------------------------------
+/*
+
+Narrow-Band Voxel Grids
+
+A GridSDF3 stores the distance field on a regular grid, but only near the
+surface (the narrow band). The grid is split into blocks of 8x8x8 cells and
+only the blocks that the band passes through are stored, in a hash map. A
+block holds the 9x9x9 samples on its cell corners, so a cell can always be
+interpolated from a single block.
+
+Within the band the distance is trilinearly interpolated. Outside the band
+the magnitude of the distance is only known to be more than the band width,
+so the band width is returned with the sign of the region. The sign is found
+by scanning along +x to the next stored block: the surface is inside the
+band, so the sign doesn't change before the scan reaches it.
+
+Voxelize builds a grid from an SDF3 by subdividing the block grid like an
+octree and skipping the regions that are further than the band from the
+surface. This relies on the SDF3 being a distance bound. VoxelizeMesh
+builds a grid from a triangle mesh, storing the blocks near each triangle.
+
+The band width is a trade off: a wider band gives exact distances further
+from the surface (e.g. for offsets), a narrower band stores fewer blocks.
+The memory of a grid grows with the surface area rather than the volume, so
+at fine resolutions a grid is 100x smaller than a dense grid (see Bytes and
+DenseBytes).
+
+Grids are a snapshot of a (possibly heavy) evaluation tree: they are fast to
+evaluate, can be combined and processed cell by cell, and their samples can
+be exchanged with volume tools. Grids that don't fit in memory can be
+written to and evaluated from grid files (see grid3file.go).
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"runtime"
+	"sync"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// gridBlockCells is the number of cells on each side of a grid block.
+const gridBlockCells = 8
+
+// gridBlockSide is the number of samples on each side of a grid block.
+const gridBlockSide = gridBlockCells + 1
+
+// gridBandCells is the default band width in cells.
+const gridBandCells = 3
+
+// gridMaxBlocks limits the size of the block grid.
+const gridMaxBlocks = 1 << 30
+
+// gridBlock is the distance samples of a grid block.
+type gridBlock [gridBlockSide * gridBlockSide * gridBlockSide]float32
+
+// at returns a sample of a block.
+func (b *gridBlock) at(n int) float64 {
+	return float64(b[n])
+}
+
+// gridShape is the geometry of a grid.
+type gridShape struct {
+	origin  v3.Vec  // position of sample (0, 0, 0)
+	cell    float64 // cell size
+	band    float64 // band width
+	cells   v3i.Vec // cells on each axis
+	nblocks v3i.Vec // blocks on each axis
+}
+
+// GridSDF3 is an SDF3 stored on a sparse narrow-band grid.
+type GridSDF3 struct {
+	gridShape
+	blocks map[v3i.Vec]*gridBlock
+}
+
+// NewGrid3 returns an empty grid with a number of cells on each axis.
+// All points of an empty grid are outside.
+func NewGrid3(origin v3.Vec, cells v3i.Vec, cellSize, band float64) (*GridSDF3, error) {
+	if cellSize <= 0 {
+		return nil, ErrMsg("cellSize <= 0")
+	}
+	if band < cellSize {
+		return nil, ErrMsg("band < cellSize")
+	}
+	if cells.X <= 0 || cells.Y <= 0 || cells.Z <= 0 {
+		return nil, ErrMsg("cells <= 0")
+	}
+	nblocks := v3i.Vec{
+		(cells.X + gridBlockCells - 1) / gridBlockCells,
+		(cells.Y + gridBlockCells - 1) / gridBlockCells,
+		(cells.Z + gridBlockCells - 1) / gridBlockCells,
+	}
+	if float64(nblocks.X)*float64(nblocks.Y)*float64(nblocks.Z) > gridMaxBlocks {
+		return nil, ErrMsg("grid is too large")
+	}
+	return &GridSDF3{
+		gridShape: gridShape{
+			origin:  origin,
+			cell:    cellSize,
+			band:    band,
+			cells:   cells,
+			nblocks: nblocks,
+		},
+		blocks: make(map[v3i.Vec]*gridBlock),
+	}, nil
+}
+
+// boxGrid returns an empty grid covering a bounding box and the band around it.
+func boxGrid(bb Box3, cellSize, band float64) (*GridSDF3, error) {
+	if cellSize <= 0 {
+		return nil, ErrMsg("cellSize <= 0")
+	}
+	bb = bb.Enlarge(v3.Vec{1, 1, 1}.MulScalar(2 * (band + cellSize)))
+	// the origin is a multiple of the cell size, so grids with the same cell
+	// size are on the same lattice and can be combined sample by sample
+	origin := v3.Vec{
+		math.Floor(bb.Min.X/cellSize) * cellSize,
+		math.Floor(bb.Min.Y/cellSize) * cellSize,
+		math.Floor(bb.Min.Z/cellSize) * cellSize,
+	}
+	size := bb.Max.Sub(origin)
+	cells := v3i.Vec{
+		int(math.Ceil(size.X / cellSize)),
+		int(math.Ceil(size.Y / cellSize)),
+		int(math.Ceil(size.Z / cellSize)),
+	}
+	return NewGrid3(origin, cells, cellSize, band)
+}
+
+// voxelGrid returns an empty grid for voxelizing an SDF3, and the blocks in the band.
+func voxelGrid(s SDF3, cellSize, band float64) (*GridSDF3, []v3i.Vec, error) {
+	g, err := boxGrid(s.BoundingBox(), cellSize, band)
+	if err != nil {
+		return nil, nil, err
+	}
+	// find the blocks in the band
+	var blocks []v3i.Vec
+	n := 1
+	for n < g.nblocks.MaxComponent() {
+		n *= 2
+	}
+	g.findBlocks(s, v3i.Vec{}, n, &blocks)
+	return g, blocks, nil
+}
+
+// sampleBlocks returns the samples of an SDF3 for a set of blocks, sampled in parallel.
+func (g *gridShape) sampleBlocks(s SDF3, blocks []v3i.Vec) []*gridBlock {
+	samples := make([]*gridBlock, len(blocks))
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				samples[i] = g.sampleBlock(s, blocks[i])
+			}
+		}()
+	}
+	for i := range blocks {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return samples
+}
+
+// Voxelize samples an SDF3 on a narrow-band grid with a given cell size.
+// The band is 3 cells wide.
+func Voxelize(s SDF3, cellSize float64) (*GridSDF3, error) {
+	return VoxelizeBand(s, cellSize, gridBandCells*cellSize)
+}
+
+// VoxelizeBand samples an SDF3 on a narrow-band grid with a given cell size and band width.
+func VoxelizeBand(s SDF3, cellSize, band float64) (*GridSDF3, error) {
+	g, blocks, err := voxelGrid(s, cellSize, band)
+	if err != nil {
+		return nil, err
+	}
+	g.addBlocks(s, blocks)
+	return g, nil
+}
+
+// VoxelizeMesh samples the distance to a closed triangle mesh on a narrow-band
+// grid with a given cell size and band width. The blocks are found from the
+// triangles, so the mesh is only evaluated near its surface.
+func VoxelizeMesh(mesh []*Triangle3, cellSize, band float64) (*GridSDF3, error) {
+	s, err := Mesh3D(mesh)
+	if err != nil {
+		return nil, err
+	}
+	g, err := boxGrid(s.BoundingBox(), cellSize, band)
+	if err != nil {
+		return nil, err
+	}
+	// the blocks within the band of the triangle bounding boxes
+	set := make(map[v3i.Vec]bool)
+	for _, t := range mesh {
+		bb := t.BoundingBox().Enlarge(v3.Vec{2 * band, 2 * band, 2 * band})
+		b0, b1 := g.blockOf(bb.Min), g.blockOf(bb.Max)
+		for z := b0.Z; z <= b1.Z; z++ {
+			for y := b0.Y; y <= b1.Y; y++ {
+				for x := b0.X; x <= b1.X; x++ {
+					set[v3i.Vec{x, y, z}] = true
+				}
+			}
+		}
+	}
+	blocks := make([]v3i.Vec, 0, len(set))
+	for b := range set {
+		blocks = append(blocks, b)
+	}
+	g.addBlocks(s, blocks)
+	return g, nil
+}
+
+// blockOf returns the block containing a point, clamped to the grid.
+func (g *gridShape) blockOf(p v3.Vec) v3i.Vec {
+	x := p.Sub(g.origin).DivScalar(float64(gridBlockCells) * g.cell)
+	return v3i.Vec{
+		clampInt(int(math.Floor(x.X)), 0, g.nblocks.X-1),
+		clampInt(int(math.Floor(x.Y)), 0, g.nblocks.Y-1),
+		clampInt(int(math.Floor(x.Z)), 0, g.nblocks.Z-1),
+	}
+}
+
+// addBlocks samples an SDF3 for a set of blocks and stores them.
+func (g *GridSDF3) addBlocks(s SDF3, blocks []v3i.Vec) {
+	for i, blk := range g.sampleBlocks(s, blocks) {
+		g.blocks[blocks[i]] = blk
+	}
+}
+
+// findBlocks adds the blocks in the band within a cube of n blocks at b.
+func (g *gridShape) findBlocks(s SDF3, b v3i.Vec, n int, blocks *[]v3i.Vec) {
+	if b.X >= g.nblocks.X || b.Y >= g.nblocks.Y || b.Z >= g.nblocks.Z {
+		return
+	}
+	side := float64(n*gridBlockCells) * g.cell
+	center := g.sample(b.MulScalar(gridBlockCells)).AddScalar(0.5 * side)
+	if math.Abs(s.Evaluate(center)) > 0.5*math.Sqrt(3)*side+g.band {
+		return
+	}
+	if n == 1 {
+		*blocks = append(*blocks, b)
+		return
+	}
+	n /= 2
+	for i := 0; i < 8; i++ {
+		g.findBlocks(s, b.Add(v3i.Vec{i & 1, (i >> 1) & 1, (i >> 2) & 1}.MulScalar(n)), n, blocks)
+	}
+}
+
+// sampleBlock returns the samples of an SDF3 for a block.
+func (g *gridShape) sampleBlock(s SDF3, b v3i.Vec) *gridBlock {
+	x := &gridBlock{}
+	base := b.MulScalar(gridBlockCells)
+	n := 0
+	for k := 0; k < gridBlockSide; k++ {
+		for j := 0; j < gridBlockSide; j++ {
+			for i := 0; i < gridBlockSide; i++ {
+				x[n] = float32(s.Evaluate(g.sample(base.Add(v3i.Vec{i, j, k}))))
+				n++
+			}
+		}
+	}
+	return x
+}
+
+//-----------------------------------------------------------------------------
+
+// sample returns the position of a grid sample.
+func (g *gridShape) sample(i v3i.Vec) v3.Vec {
+	return g.origin.Add(v3.Vec{float64(i.X), float64(i.Y), float64(i.Z)}.MulScalar(g.cell))
+}
+
+// gridLocal returns the index of a sample within a block.
+func gridLocal(i, j, k int) int {
+	return i + gridBlockSide*(j+gridBlockSide*k)
+}
+
+// CellSize returns the cell size of a grid.
+func (g *gridShape) CellSize() float64 {
+	return g.cell
+}
+
+// Band returns the band width of a grid.
+func (g *gridShape) Band() float64 {
+	return g.band
+}
+
+// Cells returns the number of cells on each axis of a grid.
+func (g *gridShape) Cells() v3i.Vec {
+	return g.cells
+}
+
+// Blocks returns the number of stored blocks of a grid.
+func (g *GridSDF3) Blocks() int {
+	return len(g.blocks)
+}
+
+// Bytes returns the memory used by the samples of a grid.
+func (g *GridSDF3) Bytes() int {
+	return len(g.blocks) * 4 * len(gridBlock{})
+}
+
+// DenseBytes returns the memory a dense grid of float32 samples would use.
+func (g *gridShape) DenseBytes() int {
+	return 4 * (g.cells.X + 1) * (g.cells.Y + 1) * (g.cells.Z + 1)
+}
+
+// gridBlocks returns the blocks (upper first) on an axis with n blocks that store sample i.
+func gridBlocks(i, n int) []int {
+	var b []int
+	if i/gridBlockCells < n {
+		b = append(b, i/gridBlockCells)
+	}
+	if i%gridBlockCells == 0 && i > 0 {
+		b = append(b, i/gridBlockCells-1)
+	}
+	return b
+}
+
+// inGrid returns true if a sample index is within the grid.
+func (g *gridShape) inGrid(i v3i.Vec) bool {
+	return i.X >= 0 && i.Y >= 0 && i.Z >= 0 && i.X <= g.cells.X && i.Y <= g.cells.Y && i.Z <= g.cells.Z
+}
+
+// owner returns the stored block that owns a sample, preferring the upper blocks.
+func (g *GridSDF3) owner(i v3i.Vec) (v3i.Vec, *gridBlock) {
+	for _, x := range gridBlocks(i.X, g.nblocks.X) {
+		for _, y := range gridBlocks(i.Y, g.nblocks.Y) {
+			for _, z := range gridBlocks(i.Z, g.nblocks.Z) {
+				b := v3i.Vec{x, y, z}
+				if blk, ok := g.blocks[b]; ok {
+					return b, blk
+				}
+			}
+		}
+	}
+	return v3i.Vec{}, nil
+}
+
+// Get returns the value of a grid sample, and false if it is not stored.
+func (g *GridSDF3) Get(i v3i.Vec) (float64, bool) {
+	if !g.inGrid(i) {
+		return 0, false
+	}
+	b, blk := g.owner(i)
+	if blk == nil {
+		return 0, false
+	}
+	l := i.Sub(b.MulScalar(gridBlockCells))
+	return float64(blk[gridLocal(l.X, l.Y, l.Z)]), true
+}
+
+// Set sets the value of a grid sample. A new block is filled with the band
+// width (outside). Set is not safe for concurrent use.
+func (g *GridSDF3) Set(i v3i.Vec, d float64) {
+	if !g.inGrid(i) {
+		return
+	}
+	// a sample on a block boundary is stored in the neighboring blocks
+	for _, x := range gridBlocks(i.X, g.nblocks.X) {
+		for _, y := range gridBlocks(i.Y, g.nblocks.Y) {
+			for _, z := range gridBlocks(i.Z, g.nblocks.Z) {
+				b := v3i.Vec{x, y, z}
+				blk, ok := g.blocks[b]
+				if !ok {
+					blk = &gridBlock{}
+					for n := range blk {
+						blk[n] = float32(g.band)
+					}
+					g.blocks[b] = blk
+				}
+				l := i.Sub(b.MulScalar(gridBlockCells))
+				blk[gridLocal(l.X, l.Y, l.Z)] = float32(d)
+			}
+		}
+	}
+}
+
+// Samples calls fn for each stored grid sample with its index, position and value.
+// Samples on block boundaries are visited once.
+func (g *GridSDF3) Samples(fn func(i v3i.Vec, p v3.Vec, d float64)) {
+	for b, blk := range g.blocks {
+		base := b.MulScalar(gridBlockCells)
+		for k := 0; k < gridBlockSide; k++ {
+			for j := 0; j < gridBlockSide; j++ {
+				for i := 0; i < gridBlockSide; i++ {
+					idx := base.Add(v3i.Vec{i, j, k})
+					if !g.inGrid(idx) {
+						continue
+					}
+					// boundary samples are visited from their owner block
+					if i == 0 || j == 0 || k == 0 || i == gridBlockCells || j == gridBlockCells || k == gridBlockCells {
+						if o, _ := g.owner(idx); o != b {
+							continue
+						}
+					}
+					fn(idx, g.sample(idx), float64(blk[gridLocal(i, j, k)]))
+				}
+			}
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// gridSamples is the sample storage of a grid block.
+type gridSamples interface {
+	at(n int) float64
+}
+
+// gridEvaluate returns the distance to a grid, with the blocks from a lookup function.
+func gridEvaluate[B gridSamples](g *gridShape, p v3.Vec, lookup func(b v3i.Vec) (B, bool)) float64 {
+	x := p.Sub(g.origin).DivScalar(g.cell)
+	if x.X < 0 || x.Y < 0 || x.Z < 0 || x.X > float64(g.cells.X) || x.Y > float64(g.cells.Y) || x.Z > float64(g.cells.Z) {
+		// outside the grid
+		return math.Sqrt(g.bbox().dist2(p)) + g.band
+	}
+	i := clampInt(int(x.X), 0, g.cells.X-1)
+	j := clampInt(int(x.Y), 0, g.cells.Y-1)
+	k := clampInt(int(x.Z), 0, g.cells.Z-1)
+	fx, fy, fz := x.X-float64(i), x.Y-float64(j), x.Z-float64(k)
+	b := v3i.Vec{i / gridBlockCells, j / gridBlockCells, k / gridBlockCells}
+	i, j, k = i%gridBlockCells, j%gridBlockCells, k%gridBlockCells
+
+	const dy = gridBlockSide
+	const dz = gridBlockSide * gridBlockSide
+	blk, ok := lookup(b)
+	if !ok {
+		// outside the band, scan along +x for the sign
+		for b.X++; b.X < g.nblocks.X; b.X++ {
+			if blk, ok := lookup(b); ok {
+				n := gridLocal(0, j, k)
+				y0 := Mix(blk.at(n), blk.at(n+dy), fy)
+				y1 := Mix(blk.at(n+dz), blk.at(n+dy+dz), fy)
+				if Mix(y0, y1, fz) < 0 {
+					return -g.band
+				}
+				return g.band
+			}
+		}
+		return g.band
+	}
+	n := gridLocal(i, j, k)
+	x00 := Mix(blk.at(n), blk.at(n+1), fx)
+	x10 := Mix(blk.at(n+dy), blk.at(n+dy+1), fx)
+	x01 := Mix(blk.at(n+dz), blk.at(n+dz+1), fx)
+	x11 := Mix(blk.at(n+dy+dz), blk.at(n+dy+dz+1), fx)
+	return Mix(Mix(x00, x10, fy), Mix(x01, x11, fy), fz)
+}
+
+// block returns a stored block.
+func (g *GridSDF3) block(b v3i.Vec) (*gridBlock, bool) {
+	blk, ok := g.blocks[b]
+	return blk, ok
+}
+
+// Evaluate returns the distance to a grid SDF3.
+func (g *GridSDF3) Evaluate(p v3.Vec) float64 {
+	return gridEvaluate(&g.gridShape, p, g.block)
+}
+
+// bbox returns the box covered by the grid.
+func (g *gridShape) bbox() Box3 {
+	return Box3{g.origin, g.sample(g.cells)}
+}
+
+// BoundingBox returns the bounding box of a grid SDF3.
+func (g *GridSDF3) BoundingBox() Box3 {
+	return g.bbox()
+}
+
+//-----------------------------------------------------------------------------
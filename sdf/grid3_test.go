@@ -0,0 +1,156 @@
+//-----------------------------------------------------------------------------
+/*
+
+Narrow-Band Voxel Grid Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Voxelize(t *testing.T) {
+	s, _ := Sphere3D(50)
+	const cell = 0.5
+	g, err := Voxelize(s, cell)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the band is stored
+	cells := g.Cells()
+	dense := (cells.X / gridBlockCells) * (cells.Y / gridBlockCells) * (cells.Z / gridBlockCells)
+	if g.Blocks() == 0 || g.Blocks() > dense/3 {
+		t.Errorf("expected a sparse grid, got %d of %d blocks", g.Blocks(), dense)
+	}
+
+	// within the band the distance is interpolated, outside it has the right sign
+	bb := g.BoundingBox().ScaleAboutCenter(1.2)
+	for _, p := range bb.RandomSet(5000) {
+		d, x := s.Evaluate(p), g.Evaluate(p)
+		if math.Abs(d) < g.Band()-cell {
+			if math.Abs(d-x) > 0.1*cell {
+				t.Fatalf("%v: expected %f, got %f", p, d, x)
+			}
+		} else if math.Abs(d) > g.Band()+cell {
+			if (d < 0) != (x < 0) || math.Abs(x) > math.Abs(d)+0.1*cell {
+				t.Fatalf("%v: expected a bound for %f, got %f", p, d, x)
+			}
+		}
+	}
+	if x := g.Evaluate(v3.Vec{}); x != -g.Band() {
+		t.Errorf("expected the center to be inside, got %f", x)
+	}
+
+	// samples round trip, including block boundaries
+	n := 0
+	g.Samples(func(i v3i.Vec, p v3.Vec, d float64) {
+		n++
+		if x, ok := g.Get(i); !ok || x != d {
+			t.Fatalf("%v: expected %f, got %f", i, d, x)
+		}
+	})
+	if n == 0 {
+		t.Fatal("no samples")
+	}
+	h, _ := NewGrid3(v3.Vec{}, v3i.Vec{32, 32, 32}, 1, 3)
+	i := v3i.Vec{8, 16, 3}
+	h.Set(i, -1)
+	if h.Blocks() != 4 {
+		t.Errorf("expected a boundary sample in 4 blocks, got %d", h.Blocks())
+	}
+	if x, _ := h.Get(i); x != -1 {
+		t.Errorf("expected -1, got %f", x)
+	}
+	if x := h.Evaluate(v3.Vec{8, 16, 3}); x != -1 {
+		t.Errorf("expected -1, got %f", x)
+	}
+	// (3 + 3 + 3 - 1) / 4
+	if x := h.Evaluate(v3.Vec{7.5, 15.5, 3}); math.Abs(x-2) > 1e-9 {
+		t.Errorf("expected 2, got %f", x)
+	}
+
+	if _, err := Voxelize(s, 0); err == nil {
+		t.Error("expected an error for a zero cell size")
+	}
+}
+
+func Test_VoxelizeBand(t *testing.T) {
+	s, _ := Sphere3D(50)
+	const cell = 0.5
+	narrow, err := VoxelizeBand(s, cell, 1.5*cell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wide, err := VoxelizeBand(s, cell, 20*cell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if narrow.Band() != 1.5*cell || wide.Band() != 20*cell {
+		t.Errorf("expected band widths %f and %f, got %f and %f", 1.5*cell, 20*cell, narrow.Band(), wide.Band())
+	}
+	if narrow.Blocks() >= wide.Blocks() {
+		t.Errorf("expected fewer blocks for a narrower band, got %d and %d", narrow.Blocks(), wide.Blocks())
+	}
+	if narrow.Bytes()*2 > narrow.DenseBytes() {
+		t.Errorf("expected a sparse grid, got %d of %d bytes", narrow.Bytes(), narrow.DenseBytes())
+	}
+	// the wide band is exact further from the surface
+	p := v3.Vec{X: 42}
+	if x := wide.Evaluate(p); math.Abs(x+8) > 0.1*cell {
+		t.Errorf("expected -8, got %f", x)
+	}
+	if x := narrow.Evaluate(p); x != -narrow.Band() {
+		t.Errorf("expected %f, got %f", -narrow.Band(), x)
+	}
+	if _, err := VoxelizeBand(s, cell, 0.5*cell); err == nil {
+		t.Error("expected an error for a band narrower than a cell")
+	}
+}
+
+func Test_VoxelizeMesh(t *testing.T) {
+	mesh := cubeMesh(10, 4)
+	box, _ := Box3D(v3.Vec{X: 10, Y: 10, Z: 10}, 0)
+	const cell = 0.25
+	g, err := VoxelizeMesh(mesh, cell, 4*cell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// within the band the samples match a voxelized box
+	h, _ := VoxelizeBand(box, cell, 4*cell)
+	bb := g.BoundingBox()
+	for _, p := range bb.RandomSet(2000) {
+		d, x := box.Evaluate(p), g.Evaluate(p)
+		if math.Abs(d) < g.Band()-cell {
+			if y := h.Evaluate(p); math.Abs(y-x) > 1e-4 {
+				t.Fatalf("%v: expected %f, got %f", p, y, x)
+			}
+		} else if math.Abs(d) > g.Band()+cell && (d < 0) != (x < 0) {
+			t.Fatalf("%v: expected the sign of %f, got %f", p, d, x)
+		}
+	}
+	if _, err := VoxelizeMesh(nil, cell, 4*cell); err == nil {
+		t.Error("expected an error for an empty mesh")
+	}
+}
+
+func Benchmark_Grid3(b *testing.B) {
+	g, _ := Voxelize(Union3D(holes3(200)...), 0.5)
+	bb := g.BoundingBox()
+	points := bb.RandomSet(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.Evaluate(points[i%len(points)])
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,184 @@
+//-----------------------------------------------------------------------------
+/*
+
+Narrow-Band Grid CSG
+
+Booleans and offsets of narrow-band grids, computed sample by sample. The
+result is another grid, so a workflow that repeatedly combines large
+(e.g. imported mesh) objects stays a flat grid rather than growing a deep
+evaluation tree.
+
+The grids must have the same cell size, and be on the same lattice (their
+origins differ by a whole number of cells). Grids made by Voxelize with the
+same cell size are always on the same lattice.
+
+The results are approximate: samples outside the band of a grid are only
+known to be further than the band, so the result is exact within the
+narrowest band and a distance bound elsewhere. An offset moves the surface
+within the band, so the band of the result is narrower by the offset.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// gridOp is an SDF3 that combines grid samples, used to sample the result of a grid operation.
+type gridOp struct {
+	out    *GridSDF3
+	grids  []*GridSDF3
+	offset []v3i.Vec // sample offset of each grid within the result
+	fn     func(x, y float64) float64
+}
+
+// gridLattice returns an empty grid covering a set of grids on the same lattice,
+// with the offset of each grid within it.
+func gridLattice(grids []*GridSDF3) (*GridSDF3, []v3i.Vec, error) {
+	if len(grids) == 0 {
+		return nil, nil, ErrMsg("no grids")
+	}
+	cell := grids[0].cell
+	band := grids[0].band
+	bb := grids[0].bbox()
+	for _, g := range grids[1:] {
+		if math.Abs(g.cell-cell) > 1e-9*cell {
+			return nil, nil, ErrMsg("grids have different cell sizes")
+		}
+		band = math.Min(band, g.band)
+		bb = bb.Extend(g.bbox())
+	}
+	size := bb.Size().DivScalar(cell)
+	cells := v3i.Vec{int(math.Round(size.X)), int(math.Round(size.Y)), int(math.Round(size.Z))}
+	out, err := NewGrid3(bb.Min, cells, cell, band)
+	if err != nil {
+		return nil, nil, err
+	}
+	offset := make([]v3i.Vec, len(grids))
+	for i, g := range grids {
+		x := g.origin.Sub(bb.Min).DivScalar(cell)
+		offset[i] = v3i.Vec{int(math.Round(x.X)), int(math.Round(x.Y)), int(math.Round(x.Z))}
+		if math.Abs(x.X-float64(offset[i].X)) > 1e-6 || math.Abs(x.Y-float64(offset[i].Y)) > 1e-6 || math.Abs(x.Z-float64(offset[i].Z)) > 1e-6 {
+			return nil, nil, ErrMsg("grids are not on the same lattice")
+		}
+	}
+	return out, offset, nil
+}
+
+// value returns a grid sample, or the distance bound outside the band.
+func (g *GridSDF3) value(i v3i.Vec, p v3.Vec) float64 {
+	if d, ok := g.Get(i); ok {
+		return d
+	}
+	return g.Evaluate(p)
+}
+
+// Evaluate returns the combined value of the grid samples at a sample of the result.
+func (s *gridOp) Evaluate(p v3.Vec) float64 {
+	x := p.Sub(s.out.origin).DivScalar(s.out.cell)
+	i := v3i.Vec{int(math.Round(x.X)), int(math.Round(x.Y)), int(math.Round(x.Z))}
+	d := s.grids[0].value(i.Sub(s.offset[0]), p)
+	for k, g := range s.grids[1:] {
+		d = s.fn(d, g.value(i.Sub(s.offset[k+1]), p))
+	}
+	return d
+}
+
+// BoundingBox returns the bounding box of the result.
+func (s *gridOp) BoundingBox() Box3 {
+	return s.out.bbox()
+}
+
+// combineGrids combines the samples of grids with a function.
+func combineGrids(grids []*GridSDF3, fn func(x, y float64) float64) (*GridSDF3, error) {
+	out, offset, err := gridLattice(grids)
+	if err != nil {
+		return nil, err
+	}
+	// the result blocks overlapping the stored blocks of any grid
+	set := make(map[v3i.Vec]bool)
+	last := out.nblocks.SubScalar(1)
+	for k, g := range grids {
+		for b := range g.blocks {
+			lo := b.MulScalar(gridBlockCells).Add(offset[k])
+			hi := lo.AddScalar(gridBlockCells)
+			for z := lo.Z / gridBlockCells; z <= min(hi.Z/gridBlockCells, last.Z); z++ {
+				for y := lo.Y / gridBlockCells; y <= min(hi.Y/gridBlockCells, last.Y); y++ {
+					for x := lo.X / gridBlockCells; x <= min(hi.X/gridBlockCells, last.X); x++ {
+						set[v3i.Vec{x, y, z}] = true
+					}
+				}
+			}
+		}
+	}
+	blocks := make([]v3i.Vec, 0, len(set))
+	for b := range set {
+		blocks = append(blocks, b)
+	}
+	out.addBlocks(&gridOp{out, grids, offset, fn}, blocks)
+	out.prune()
+	return out, nil
+}
+
+// prune removes the blocks that the band doesn't pass through.
+func (g *GridSDF3) prune() {
+	for b, blk := range g.blocks {
+		inside, outside := true, true
+		for _, x := range blk {
+			inside = inside && float64(x) < -g.band
+			outside = outside && float64(x) > g.band
+		}
+		if inside || outside {
+			delete(g.blocks, b)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// GridUnion returns the union of narrow-band grids.
+func GridUnion(grids ...*GridSDF3) (*GridSDF3, error) {
+	return combineGrids(grids, math.Min)
+}
+
+// GridIntersect returns the intersection of narrow-band grids.
+func GridIntersect(grids ...*GridSDF3) (*GridSDF3, error) {
+	return combineGrids(grids, math.Max)
+}
+
+// GridDifference returns grid a with grid b removed.
+func GridDifference(a, b *GridSDF3) (*GridSDF3, error) {
+	return combineGrids([]*GridSDF3{a, b}, func(x, y float64) float64 { return math.Max(x, -y) })
+}
+
+// Offset returns a grid with the surface moved outwards by a distance
+// (inwards for a negative distance). The band of the result is narrower by
+// the distance, so the distance must be less than the band width less a cell.
+func (g *GridSDF3) Offset(d float64) (*GridSDF3, error) {
+	band := g.band - math.Abs(d)
+	if band < g.cell {
+		return nil, ErrMsg("offset is too large for the band")
+	}
+	out, err := NewGrid3(g.origin, g.cells, g.cell, band)
+	if err != nil {
+		return nil, err
+	}
+	for b, blk := range g.blocks {
+		x := &gridBlock{}
+		for n, v := range blk {
+			x[n] = v - float32(d)
+		}
+		out.blocks[b] = x
+	}
+	out.prune()
+	return out, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,99 @@
+//-----------------------------------------------------------------------------
+/*
+
+Narrow-Band Grid CSG Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// gridCompare checks a grid against the voxelized SDF3 within the band, and
+// the sign of the SDF3 outside it.
+func gridCompare(t *testing.T, name string, g *GridSDF3, s SDF3) {
+	t.Helper()
+	ref, err := VoxelizeBand(s, g.CellSize(), g.Band())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bb := g.BoundingBox().ScaleAboutCenter(1.1)
+	for _, p := range bb.RandomSet(5000) {
+		d, x := s.Evaluate(p), g.Evaluate(p)
+		if math.Abs(d) < g.Band()-g.CellSize() {
+			if y := ref.Evaluate(p); math.Abs(y-x) > 1e-4 {
+				t.Fatalf("%s %v: expected %f, got %f", name, p, y, x)
+			}
+		} else if math.Abs(d) > g.Band()+g.CellSize() && (d < 0) != (x < 0) {
+			t.Fatalf("%s %v: expected the sign of %f, got %f", name, p, d, x)
+		}
+	}
+}
+
+func Test_GridCSG(t *testing.T) {
+	const cell = 0.25
+	s0, _ := Sphere3D(5)
+	s1 := Transform3D(s0, Translate3d(v3.Vec{X: 6, Y: 1}))
+	box, _ := Box3D(v3.Vec{X: 4, Y: 4, Z: 20}, 0)
+	g0, _ := VoxelizeBand(s0, cell, 6*cell)
+	g1, _ := Voxelize(s1, cell)
+	g2, _ := Voxelize(box, cell)
+
+	u, err := GridUnion(g0, g1, g2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gridCompare(t, "union", u, Union3D(s0, s1, box))
+
+	i, err := GridIntersect(g0, g1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gridCompare(t, "intersect", i, Intersect3D(s0, s1))
+
+	d, err := GridDifference(g0, g2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gridCompare(t, "difference", d, Difference3D(s0, box))
+	if d.Blocks() >= u.Blocks() {
+		t.Errorf("expected fewer blocks for the difference, got %d and %d", d.Blocks(), u.Blocks())
+	}
+
+	o, err := g0.Offset(0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Band() != g0.Band()-0.5 {
+		t.Errorf("expected band %f, got %f", g0.Band()-0.5, o.Band())
+	}
+	s2, _ := Sphere3D(5.5)
+	gridCompare(t, "offset", o, s2)
+	if _, err := g0.Offset(g0.Band()); err == nil {
+		t.Error("expected an error for an offset wider than the band")
+	}
+
+	// grids on different lattices
+	h, _ := NewGrid3(v3.Vec{X: 0.1}, v3i.Vec{8, 8, 8}, cell, 3*cell)
+	if _, err := GridUnion(g0, h); err == nil {
+		t.Error("expected an error for grids on different lattices")
+	}
+	h, _ = NewGrid3(v3.Vec{}, v3i.Vec{8, 8, 8}, 2*cell, 3*cell)
+	if _, err := GridUnion(g0, h); err == nil {
+		t.Error("expected an error for grids with different cell sizes")
+	}
+	if _, err := GridUnion(); err == nil {
+		t.Error("expected an error for no grids")
+	}
+}
+
+//-----------------------------------------------------------------------------
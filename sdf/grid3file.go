@@ -0,0 +1,422 @@
+//-----------------------------------------------------------------------------
+/*
+
+Memory-Mapped Grid Files
+
+A grid file stores the blocks of a narrow-band grid so that grids larger
+than memory can back an SDF3. The file is memory-mapped and only the block
+index is read up front, the blocks are paged in by the OS as they are
+evaluated. Where memory-mapping isn't available the blocks are read on
+demand into a bounded cache.
+
+The file is little-endian:
+
+	header (128 bytes)
+	  magic    "SDFXGRID"
+	  version  uint32
+	  side     uint32     samples on each side of a block (9)
+	  origin   3 x float64
+	  cell     float64    cell size
+	  band     float64    band width
+	  cells    3 x int64  cells on each axis
+	  count    uint64     number of blocks
+	index
+	  count x uint64      block keys (x + nx * (y + ny * z)), ascending
+	padding to a 4096 byte boundary
+	data
+	  count x 729 x float32, the block samples in key order (x fastest)
+
+VoxelizeFile samples an SDF3 straight to a grid file, a chunk of blocks at
+a time, so a 4096^3 narrow-band field never has to fit in memory.
+
+	err := sdf.VoxelizeFile(scan, 0.05, "scan.grid")
+	g, err := sdf.OpenGrid3("scan.grid")
+	defer g.Close()
+	render.ToSTL(g, "scan.stl", render.NewMarchingCubesOctree(1000))
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+const (
+	grid3Magic     = "SDFXGRID"
+	grid3Version   = 1
+	grid3Align     = 4096                                              // alignment of the block data
+	grid3BlockSize = gridBlockSide * gridBlockSide * gridBlockSide * 4 // bytes per block
+	grid3Chunk     = 4096                                              // blocks sampled at a time by VoxelizeFile
+	grid3CacheSize = 4096                                              // blocks cached without memory-mapping
+	grid3IndexSize = 8                                                 // bytes per index entry
+	grid3HeadSize  = 128                                               // bytes in the header
+)
+
+// grid3Header is the header of a grid file.
+type grid3Header struct {
+	Magic   [8]byte
+	Version uint32
+	Side    uint32
+	Origin  [3]float64
+	Cell    float64
+	Band    float64
+	Cells   [3]int64
+	Count   uint64
+	_       [40]byte
+}
+
+// key returns the index key of a block.
+func (g *gridShape) key(b v3i.Vec) uint64 {
+	return uint64(b.X) + uint64(g.nblocks.X)*(uint64(b.Y)+uint64(g.nblocks.Y)*uint64(b.Z))
+}
+
+// keyBlock returns the block of an index key.
+func (g *gridShape) keyBlock(key uint64) v3i.Vec {
+	nx, ny := uint64(g.nblocks.X), uint64(g.nblocks.Y)
+	return v3i.Vec{int(key % nx), int(key / nx % ny), int(key / (nx * ny))}
+}
+
+// grid3DataOffset returns the file offset of the block data.
+func grid3DataOffset(count int) int64 {
+	n := int64(grid3HeadSize + count*grid3IndexSize)
+	return (n + grid3Align - 1) / grid3Align * grid3Align
+}
+
+//-----------------------------------------------------------------------------
+// Writing
+
+// grid3Writer writes a grid file.
+type grid3Writer struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+// newGrid3Writer creates a grid file and writes the header and index.
+func newGrid3Writer(path string, g *gridShape, keys []uint64) (*grid3Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriterSize(f, 1<<20)
+	h := grid3Header{
+		Version: grid3Version,
+		Side:    gridBlockSide,
+		Origin:  [3]float64{g.origin.X, g.origin.Y, g.origin.Z},
+		Cell:    g.cell,
+		Band:    g.band,
+		Cells:   [3]int64{int64(g.cells.X), int64(g.cells.Y), int64(g.cells.Z)},
+		Count:   uint64(len(keys)),
+	}
+	copy(h.Magic[:], grid3Magic)
+	err = binary.Write(w, binary.LittleEndian, &h)
+	if err == nil {
+		err = binary.Write(w, binary.LittleEndian, keys)
+	}
+	if err == nil {
+		pad := grid3DataOffset(len(keys)) - int64(grid3HeadSize+len(keys)*grid3IndexSize)
+		_, err = w.Write(make([]byte, pad))
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return &grid3Writer{f: f, w: w}, nil
+}
+
+// writeBlock writes the samples of the next block.
+func (gw *grid3Writer) writeBlock(blk *gridBlock) error {
+	return binary.Write(gw.w, binary.LittleEndian, blk[:])
+}
+
+// close flushes and closes the grid file. The file is removed on an error.
+func (gw *grid3Writer) close(err error) error {
+	if err == nil {
+		err = gw.w.Flush()
+	}
+	if cerr := gw.f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(gw.f.Name())
+	}
+	return err
+}
+
+// sortedKeys returns the index keys of a set of blocks, sorting the blocks in key order.
+func (g *gridShape) sortedKeys(blocks []v3i.Vec) []uint64 {
+	sort.Slice(blocks, func(i, j int) bool { return g.key(blocks[i]) < g.key(blocks[j]) })
+	keys := make([]uint64, len(blocks))
+	for i, b := range blocks {
+		keys[i] = g.key(b)
+	}
+	return keys
+}
+
+// SaveGrid3 writes a grid to a grid file.
+func SaveGrid3(path string, g *GridSDF3) error {
+	blocks := make([]v3i.Vec, 0, len(g.blocks))
+	for b := range g.blocks {
+		blocks = append(blocks, b)
+	}
+	keys := g.sortedKeys(blocks)
+	gw, err := newGrid3Writer(path, &g.gridShape, keys)
+	if err != nil {
+		return err
+	}
+	for _, b := range blocks {
+		if err = gw.writeBlock(g.blocks[b]); err != nil {
+			break
+		}
+	}
+	return gw.close(err)
+}
+
+// VoxelizeFile samples an SDF3 on a narrow-band grid (as Voxelize) and writes it to a grid file.
+// The blocks are sampled and written a chunk at a time, so the grid doesn't have to fit in memory.
+func VoxelizeFile(s SDF3, cellSize float64, path string) error {
+	return VoxelizeFileBand(s, cellSize, gridBandCells*cellSize, path)
+}
+
+// VoxelizeFileBand samples an SDF3 on a narrow-band grid with a given band width
+// (as VoxelizeBand) and writes it to a grid file.
+func VoxelizeFileBand(s SDF3, cellSize, band float64, path string) error {
+	g, blocks, err := voxelGrid(s, cellSize, band)
+	if err != nil {
+		return err
+	}
+	keys := g.sortedKeys(blocks)
+	gw, err := newGrid3Writer(path, &g.gridShape, keys)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(blocks) && err == nil; i += grid3Chunk {
+		chunk := blocks[i:min(i+grid3Chunk, len(blocks))]
+		for _, blk := range g.sampleBlocks(s, chunk) {
+			if err = gw.writeBlock(blk); err != nil {
+				break
+			}
+		}
+	}
+	return gw.close(err)
+}
+
+//-----------------------------------------------------------------------------
+// Reading
+
+// mappedBlock is the little-endian samples of a block in a grid file.
+type mappedBlock []byte
+
+// at returns a sample of a block.
+func (b mappedBlock) at(n int) float64 {
+	return float64(math.Float32frombits(binary.LittleEndian.Uint32(b[4*n:])))
+}
+
+// MappedGridSDF3 is an SDF3 backed by a grid file.
+type MappedGridSDF3 struct {
+	gridShape
+	keys   []uint64 // block index
+	offset int64    // file offset of the block data
+	data   []byte   // memory-mapped file, or nil
+	f      *os.File // grid file, when it isn't memory-mapped
+	mu     sync.Mutex
+	cache  map[int]mappedBlock // blocks read from the file
+	err    error               // first read error
+}
+
+// OpenGrid3 opens a grid file as an SDF3. The file is memory-mapped where
+// possible. The grid must be closed after use.
+func OpenGrid3(path string) (*MappedGridSDF3, error) {
+	return openGrid3(path, true)
+}
+
+// openGrid3 opens a grid file, optionally memory-mapping it.
+func openGrid3(path string, mapped bool) (*MappedGridSDF3, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	g, err := readGrid3(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if mapped {
+		if g.data, err = mmapFile(f); err == nil {
+			f.Close()
+			return g, nil
+		}
+	}
+	g.f = f
+	g.cache = make(map[int]mappedBlock)
+	return g, nil
+}
+
+// readGrid3 reads the header and index of a grid file and checks the file size.
+func readGrid3(f *os.File) (*MappedGridSDF3, error) {
+	r := bufio.NewReader(f)
+	var h grid3Header
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return nil, err
+	}
+	if string(h.Magic[:]) != grid3Magic {
+		return nil, ErrMsg("not a grid file")
+	}
+	if h.Version != grid3Version {
+		return nil, ErrMsg(fmt.Sprintf("unsupported grid file version %d", h.Version))
+	}
+	if h.Side != gridBlockSide {
+		return nil, ErrMsg(fmt.Sprintf("unsupported grid block side %d", h.Side))
+	}
+	origin := v3.Vec{X: h.Origin[0], Y: h.Origin[1], Z: h.Origin[2]}
+	cells := v3i.Vec{int(h.Cells[0]), int(h.Cells[1]), int(h.Cells[2])}
+	shape, err := NewGrid3(origin, cells, h.Cell, h.Band)
+	if err != nil {
+		return nil, err
+	}
+	if h.Count > gridMaxBlocks {
+		return nil, ErrMsg("bad grid block count")
+	}
+	g := &MappedGridSDF3{
+		gridShape: shape.gridShape,
+		keys:      make([]uint64, h.Count),
+		offset:    grid3DataOffset(int(h.Count)),
+	}
+	if err := binary.Read(r, binary.LittleEndian, g.keys); err != nil {
+		return nil, err
+	}
+	n := uint64(g.nblocks.X) * uint64(g.nblocks.Y) * uint64(g.nblocks.Z)
+	for i, k := range g.keys {
+		if k >= n || (i > 0 && k <= g.keys[i-1]) {
+			return nil, ErrMsg("bad grid block index")
+		}
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() < g.offset+int64(len(g.keys))*grid3BlockSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return g, nil
+}
+
+// index returns the index of a block in the grid file.
+func (g *MappedGridSDF3) index(b v3i.Vec) (int, bool) {
+	k := g.key(b)
+	i := sort.Search(len(g.keys), func(i int) bool { return g.keys[i] >= k })
+	return i, i < len(g.keys) && g.keys[i] == k
+}
+
+// read returns the n-th block of the grid file.
+func (g *MappedGridSDF3) read(n int) (mappedBlock, bool) {
+	ofs := g.offset + int64(n)*grid3BlockSize
+	if g.data != nil {
+		return mappedBlock(g.data[ofs : ofs+grid3BlockSize]), true
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if blk, ok := g.cache[n]; ok {
+		return blk, true
+	}
+	blk := make(mappedBlock, grid3BlockSize)
+	if _, err := g.f.ReadAt(blk, ofs); err != nil {
+		if g.err == nil {
+			g.err = err
+		}
+		return nil, false
+	}
+	if len(g.cache) >= grid3CacheSize {
+		// drop an arbitrary block
+		for k := range g.cache {
+			delete(g.cache, k)
+			break
+		}
+	}
+	g.cache[n] = blk
+	return blk, true
+}
+
+// lookup returns a stored block.
+func (g *MappedGridSDF3) lookup(b v3i.Vec) (mappedBlock, bool) {
+	n, ok := g.index(b)
+	if !ok {
+		return nil, false
+	}
+	return g.read(n)
+}
+
+// Evaluate returns the distance to a grid file SDF3.
+func (g *MappedGridSDF3) Evaluate(p v3.Vec) float64 {
+	return gridEvaluate(&g.gridShape, p, g.lookup)
+}
+
+// BoundingBox returns the bounding box of a grid file SDF3.
+func (g *MappedGridSDF3) BoundingBox() Box3 {
+	return g.bbox()
+}
+
+// Blocks returns the number of stored blocks of a grid file.
+func (g *MappedGridSDF3) Blocks() int {
+	return len(g.keys)
+}
+
+// Err returns the first error reading a block from the grid file.
+// The blocks that failed to read were evaluated as outside the band.
+func (g *MappedGridSDF3) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+// Load reads all the blocks of a grid file into an in-memory grid.
+func (g *MappedGridSDF3) Load() (*GridSDF3, error) {
+	grid := &GridSDF3{
+		gridShape: g.gridShape,
+		blocks:    make(map[v3i.Vec]*gridBlock, len(g.keys)),
+	}
+	for n, k := range g.keys {
+		data, ok := g.read(n)
+		if !ok {
+			return nil, g.Err()
+		}
+		blk := &gridBlock{}
+		for i := range blk {
+			blk[i] = float32(data.at(i))
+		}
+		grid.blocks[g.keyBlock(k)] = blk
+	}
+	return grid, nil
+}
+
+// Close closes a grid file. The grid can't be evaluated after it is closed.
+func (g *MappedGridSDF3) Close() error {
+	var err error
+	if g.data != nil {
+		err = munmapFile(g.data)
+		g.data = nil
+	}
+	if g.f != nil {
+		if cerr := g.f.Close(); err == nil {
+			err = cerr
+		}
+		g.f = nil
+	}
+	return err
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,90 @@
+//-----------------------------------------------------------------------------
+/*
+
+Memory-Mapped Grid File Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Grid3File(t *testing.T) {
+	s0, _ := Sphere3D(20)
+	s1, _ := Box3D(v3.Vec{30, 10, 10}, 1)
+	s := Union3D(s0, Transform3D(s1, Translate3d(v3.Vec{20, 0, 0})))
+	const cell = 0.5
+	g, err := Voxelize(s, cell)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	saved := filepath.Join(dir, "saved.grid")
+	if err := SaveGrid3(saved, g); err != nil {
+		t.Fatal(err)
+	}
+	streamed := filepath.Join(dir, "streamed.grid")
+	if err := VoxelizeFile(s, cell, streamed); err != nil {
+		t.Fatal(err)
+	}
+
+	bb := g.BoundingBox().ScaleAboutCenter(1.2)
+	points := bb.RandomSet(5000)
+	check := func(name string, mapped bool) {
+		m, err := openGrid3(name, mapped)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer m.Close()
+		if mapped && m.data == nil {
+			t.Logf("%s: memory-mapping is not supported", name)
+		}
+		if m.Blocks() != g.Blocks() || m.Cells() != g.Cells() || m.BoundingBox() != g.BoundingBox() {
+			t.Fatalf("%s: expected %d blocks %v, got %d blocks %v", name, g.Blocks(), g.Cells(), m.Blocks(), m.Cells())
+		}
+		for _, p := range points {
+			if d0, d1 := g.Evaluate(p), m.Evaluate(p); d0 != d1 {
+				t.Fatalf("%s: at %v expected %f, got %f", name, p, d0, d1)
+			}
+		}
+		if m.Err() != nil {
+			t.Fatal(m.Err())
+		}
+		l, err := m.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range points[:500] {
+			if d0, d1 := g.Evaluate(p), l.Evaluate(p); d0 != d1 {
+				t.Fatalf("%s: loaded grid at %v expected %f, got %f", name, p, d0, d1)
+			}
+		}
+	}
+	check(saved, true)
+	check(saved, false)
+	check(streamed, true)
+
+	// bad files
+	bad := filepath.Join(dir, "bad.grid")
+	os.WriteFile(bad, []byte("not a grid file"), 0644)
+	if _, err := OpenGrid3(bad); err == nil {
+		t.Error("expected an error for a bad file")
+	}
+	data, _ := os.ReadFile(saved)
+	os.WriteFile(bad, data[:len(data)-1], 0644)
+	if _, err := OpenGrid3(bad); err == nil {
+		t.Error("expected an error for a truncated file")
+	}
+}
+
+//-----------------------------------------------------------------------------
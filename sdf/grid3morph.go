@@ -0,0 +1,240 @@
+//-----------------------------------------------------------------------------
+/*
+
+Morphological Operations
+
+Erosion and dilation move the surface of a solid inwards or outwards by a
+radius, which is an offset of the distance field. Opening (erode then
+dilate) removes spikes, ridges and bridges thinner than twice the radius.
+Closing (dilate then erode) seals gaps, cracks and holes narrower than
+twice the radius. Both keep the rest of the surface where it was, which
+makes them useful for cleaning up scans before export.
+
+An offset of a distance field isn't the distance field of the offset
+solid: e.g. the distance from a removed spike is the distance from the
+spike, not from what remains. Offsetting back would just restore the
+original solid, so the grid is redistanced between the two offsets. The
+distances are recomputed from the zero crossings of the grid edges, which
+is accurate to a fraction of a cell.
+
+The operations are on narrow-band grids: the band must be wider than the
+radius plus a cell. The band of the result is narrower by the radius.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// crossing is a point where a grid edge crosses the surface.
+type crossing struct {
+	p, n v3.Vec // position and surface normal
+}
+
+// crossings returns the points where the grid edges cross the surface.
+func (g *GridSDF3) crossings() []crossing {
+	var pts []crossing
+	axes := [3]v3i.Vec{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	steps := [3]int{1, gridBlockSide, gridBlockSide * gridBlockSide}
+	for b, blk := range g.blocks {
+		base := b.MulScalar(gridBlockCells)
+		for k := 0; k < gridBlockSide; k++ {
+			for j := 0; j < gridBlockSide; j++ {
+				for i := 0; i < gridBlockSide; i++ {
+					l := [3]int{i, j, k}
+					n := gridLocal(i, j, k)
+					d0 := blk[n]
+					for a, axis := range axes {
+						if l[a] == gridBlockCells {
+							continue
+						}
+						d1 := blk[n+steps[a]]
+						if (d0 < 0) == (d1 < 0) {
+							continue
+						}
+						t := float64(d0 / (d0 - d1))
+						p := g.sample(base.Add(v3i.Vec{i, j, k}))
+						p = p.Add(v3.Vec{float64(axis.X), float64(axis.Y), float64(axis.Z)}.MulScalar(t * g.cell))
+						pts = append(pts, crossing{p, Normal3(g, p, 0.5*g.cell)})
+					}
+				}
+			}
+		}
+	}
+	return pts
+}
+
+// pointHash is a spatial hash of points for nearest point queries.
+type pointHash struct {
+	size    float64 // bucket size
+	buckets map[v3i.Vec][]crossing
+}
+
+func newPointHash(pts []crossing, size float64) *pointHash {
+	h := &pointHash{size: size, buckets: make(map[v3i.Vec][]crossing)}
+	for _, p := range pts {
+		k := h.key(p.p)
+		h.buckets[k] = append(h.buckets[k], p)
+	}
+	return h
+}
+
+// key returns the bucket of a point.
+func (h *pointHash) key(p v3.Vec) v3i.Vec {
+	return v3i.Vec{int(math.Floor(p.X / h.size)), int(math.Floor(p.Y / h.size)), int(math.Floor(p.Z / h.size))}
+}
+
+// nearest returns the nearest point, if it is within the bucket size.
+func (h *pointHash) nearest(p v3.Vec) (crossing, float64, bool) {
+	k := h.key(p)
+	d2 := h.size * h.size
+	var c crossing
+	found := false
+	for z := -1; z <= 1; z++ {
+		for y := -1; y <= 1; y++ {
+			for x := -1; x <= 1; x++ {
+				for _, q := range h.buckets[k.Add(v3i.Vec{x, y, z})] {
+					if x := p.Sub(q.p).Length2(); x <= d2 {
+						c, d2, found = q, x, true
+					}
+				}
+			}
+		}
+	}
+	return c, math.Sqrt(d2), found
+}
+
+// redistance is an SDF3 that samples the distance to the surface of a grid.
+type redistance struct {
+	g    *GridSDF3
+	out  *GridSDF3
+	hash *pointHash
+}
+
+// Evaluate returns the distance from a sample of the result to the surface of the grid.
+func (s *redistance) Evaluate(p v3.Vec) float64 {
+	x := p.Sub(s.g.origin).DivScalar(s.g.cell)
+	old := s.g.value(v3i.Vec{int(math.Round(x.X)), int(math.Round(x.Y)), int(math.Round(x.Z))}, p)
+	sign := 1.0
+	if old < 0 {
+		sign = -1
+	}
+	c, d, ok := s.hash.nearest(p)
+	if !ok {
+		return sign * s.out.band
+	}
+	// next to the surface the distance to the tangent plane is more accurate
+	if d < 2*s.g.cell {
+		d = math.Abs(p.Sub(c.p).Dot(c.n))
+	}
+	return sign * d
+}
+
+// BoundingBox returns the bounding box of the result.
+func (s *redistance) BoundingBox() Box3 {
+	return s.out.bbox()
+}
+
+// Redistance returns a grid with the distances recomputed from the surface
+// of the grid, with a given band width.
+func (g *GridSDF3) Redistance(band float64) (*GridSDF3, error) {
+	out, err := NewGrid3(g.origin, g.cells, g.cell, band)
+	if err != nil {
+		return nil, err
+	}
+	pts := g.crossings()
+	// the blocks within the band of the surface
+	set := make(map[v3i.Vec]bool)
+	for _, p := range pts {
+		b0 := out.blockOf(p.p.SubScalar(band))
+		b1 := out.blockOf(p.p.AddScalar(band))
+		for z := b0.Z; z <= b1.Z; z++ {
+			for y := b0.Y; y <= b1.Y; y++ {
+				for x := b0.X; x <= b1.X; x++ {
+					set[v3i.Vec{x, y, z}] = true
+				}
+			}
+		}
+	}
+	blocks := make([]v3i.Vec, 0, len(set))
+	for b := range set {
+		blocks = append(blocks, b)
+	}
+	out.addBlocks(&redistance{g, out, newPointHash(pts, band)}, blocks)
+	out.prune()
+	return out, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// Erode returns a grid with the surface moved inwards by a radius.
+func (g *GridSDF3) Erode(r float64) (*GridSDF3, error) {
+	if r < 0 {
+		return nil, ErrMsg("r < 0")
+	}
+	return g.Offset(-r)
+}
+
+// Dilate returns a grid with the surface moved outwards by a radius.
+func (g *GridSDF3) Dilate(r float64) (*GridSDF3, error) {
+	if r < 0 {
+		return nil, ErrMsg("r < 0")
+	}
+	return g.Offset(r)
+}
+
+// morph offsets a grid, redistances it and offsets it back.
+func (g *GridSDF3) morph(d float64) (*GridSDF3, error) {
+	x, err := g.Offset(d)
+	if err != nil {
+		return nil, err
+	}
+	if x, err = x.Redistance(g.band); err != nil {
+		return nil, err
+	}
+	return x.Offset(-d)
+}
+
+// Open returns a grid with the features thinner than twice a radius removed.
+func (g *GridSDF3) Open(r float64) (*GridSDF3, error) {
+	if r < 0 {
+		return nil, ErrMsg("r < 0")
+	}
+	return g.morph(-r)
+}
+
+// Close returns a grid with the gaps narrower than twice a radius filled.
+func (g *GridSDF3) Close(r float64) (*GridSDF3, error) {
+	if r < 0 {
+		return nil, ErrMsg("r < 0")
+	}
+	return g.morph(r)
+}
+
+// Open3D voxelizes an SDF3 with a given cell size and opens it (see GridSDF3.Open).
+func Open3D(s SDF3, r, cellSize float64) (*GridSDF3, error) {
+	g, err := VoxelizeBand(s, cellSize, r+gridBandCells*cellSize)
+	if err != nil {
+		return nil, err
+	}
+	return g.Open(r)
+}
+
+// Close3D voxelizes an SDF3 with a given cell size and closes it (see GridSDF3.Close).
+func Close3D(s SDF3, r, cellSize float64) (*GridSDF3, error) {
+	g, err := VoxelizeBand(s, cellSize, r+gridBandCells*cellSize)
+	if err != nil {
+		return nil, err
+	}
+	return g.Close(r)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,104 @@
+//-----------------------------------------------------------------------------
+/*
+
+Morphological Operation Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Redistance(t *testing.T) {
+	const cell = 0.25
+	s, _ := Sphere3D(5)
+	g, _ := VoxelizeBand(s, cell, 4*cell)
+	// a scaled field has the right surface but the wrong distances
+	h, _ := VoxelizeBand(ScaleUniform3D(s, 1), cell, 4*cell)
+	for _, blk := range h.blocks {
+		for n := range blk {
+			blk[n] *= 0.5
+		}
+	}
+	r, err := h.Redistance(6 * cell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Band() != 6*cell {
+		t.Errorf("expected band %f, got %f", 6*cell, r.Band())
+	}
+	bb := g.BoundingBox()
+	for _, p := range bb.RandomSet(2000) {
+		d := s.Evaluate(p)
+		if math.Abs(d) > r.Band()-cell {
+			continue
+		}
+		if x := r.Evaluate(p); math.Abs(x-d) > 0.5*cell {
+			t.Fatalf("%v: expected %f, got %f", p, d, x)
+		}
+	}
+}
+
+func Test_Morphology(t *testing.T) {
+	const cell = 0.25
+	const r = 1.0
+
+	// open: a box with a thin spike
+	box, _ := Box3D(v3.Vec{X: 20, Y: 20, Z: 10}, 0)
+	spike, _ := Cylinder3D(10, 0.4, 0)
+	spike = Transform3D(spike, Translate3d(v3.Vec{Z: 8}))
+	s := Union3D(box, spike)
+	g, err := Open3D(s, r, cell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := g.Evaluate(v3.Vec{Z: 9}); x <= 0 {
+		t.Errorf("expected the spike to be removed, got %f", x)
+	}
+	// the faces away from the spike don't move
+	for _, p := range []v3.Vec{{X: 5, Y: 5, Z: 5}, {X: 10, Y: -3, Z: 0}, {X: 4, Y: -3, Z: -5}} {
+		if x := g.Evaluate(p); math.Abs(x) > 0.5*cell {
+			t.Errorf("%v: expected the surface, got %f", p, x)
+		}
+	}
+
+	// close: two boxes with a narrow gap
+	a, _ := Box3D(v3.Vec{X: 10, Y: 10, Z: 10}, 0)
+	b := Transform3D(a, Translate3d(v3.Vec{X: 10.8}))
+	s = Union3D(a, b)
+	g, err = Close3D(s, r, cell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := g.Evaluate(v3.Vec{X: 5.4}); x >= 0 {
+		t.Errorf("expected the gap to be filled, got %f", x)
+	}
+	if x := g.Evaluate(v3.Vec{X: -5, Y: 1, Z: 2}); math.Abs(x) > 0.5*cell {
+		t.Errorf("expected the surface, got %f", x)
+	}
+
+	// erode and dilate are offsets
+	e, err := g.Erode(0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := e.Evaluate(v3.Vec{X: -4.5, Y: 1, Z: 2}); math.Abs(x) > 0.5*cell {
+		t.Errorf("expected the eroded surface, got %f", x)
+	}
+	if _, err := g.Dilate(-1); err == nil {
+		t.Error("expected an error for a negative radius")
+	}
+	if _, err := g.Open(g.Band()); err == nil {
+		t.Error("expected an error for a radius wider than the band")
+	}
+}
+
+//-----------------------------------------------------------------------------
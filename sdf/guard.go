@@ -0,0 +1,131 @@
+//-----------------------------------------------------------------------------
+/*
+
+Non-Finite Distance Detection
+
+A NaN or Inf distance (a zero scale, a degenerate primitive, a division by
+zero in a custom SDF) doesn't stop a renderer, it quietly gives a broken
+mesh. A guarded SDF3 checks every evaluation and records the first
+non-finite distance with the sample point. The diagnostic then walks the SDF
+tree from the root to the deepest node that is non-finite at the sample
+point, to find the node that produced it.
+
+The walk maps the point through transforms and uniform scales. Other nodes
+that move the point (extrusions, arrays, ...) pass it through unchanged, so
+for those the path may stop at the parent of the culprit.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// NonFiniteError is the diagnostic for a NaN or Inf distance.
+type NonFiniteError struct {
+	Point v3.Vec   // sample point
+	Value float64  // the non-finite distance
+	Path  []string // SDF node path from the root to the culprit node
+}
+
+func (e *NonFiniteError) Error() string {
+	return fmt.Sprintf("non-finite distance %g at %v: %s", e.Value, e.Point, strings.Join(e.Path, " > "))
+}
+
+// isFinite returns true if a distance is not NaN or Inf.
+func isFinite(x float64) bool {
+	return !math.IsNaN(x) && !math.IsInf(x, 0)
+}
+
+//-----------------------------------------------------------------------------
+
+// NonFinitePath returns the path from the root of an SDF3 tree to the deepest
+// node that evaluates to a non-finite distance at a point. Each path element
+// is the node type name, with the child index for all but the root.
+func NonFinitePath(s SDF3, p v3.Vec) []string {
+	path := []string{TypeName(s)}
+	for {
+		c, ok := s.(Composite)
+		if !ok {
+			return path
+		}
+		q := p
+		switch x := s.(type) {
+		case *TransformSDF3:
+			q = x.inverse.MulPosition(p)
+		case *ScaleUniformSDF3:
+			q = p.MulScalar(x.invK)
+		}
+		found := false
+		for i, child := range c.Children() {
+			if cs, ok := child.(SDF3); ok && !isFinite(cs.Evaluate(q)) {
+				path = append(path, fmt.Sprintf("[%d]%s", i, TypeName(cs)))
+				s, p, found = cs, q, true
+				break
+			}
+		}
+		if !found {
+			return path
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// GuardSDF3 is an SDF3 that records the first non-finite distance.
+type GuardSDF3 struct {
+	sdf  SDF3
+	mu   sync.Mutex
+	bad  bool
+	p    v3.Vec
+	dist float64
+}
+
+// Guard3D returns an SDF3 that checks the distances of an SDF3 for NaN and Inf.
+func Guard3D(s SDF3) *GuardSDF3 {
+	return &GuardSDF3{sdf: s}
+}
+
+// Evaluate returns the minimum distance to the SDF3, recording it if it's not finite.
+func (s *GuardSDF3) Evaluate(p v3.Vec) float64 {
+	d := s.sdf.Evaluate(p)
+	if !isFinite(d) {
+		s.mu.Lock()
+		if !s.bad {
+			s.bad, s.p, s.dist = true, p, d
+		}
+		s.mu.Unlock()
+	}
+	return d
+}
+
+// BoundingBox returns the bounding box of the SDF3.
+func (s *GuardSDF3) BoundingBox() Box3 {
+	return s.sdf.BoundingBox()
+}
+
+// Children returns the guarded SDF3.
+func (s *GuardSDF3) Children() []interface{} {
+	return []interface{}{s.sdf}
+}
+
+// Err returns a *NonFiniteError for the first non-finite distance, or nil.
+func (s *GuardSDF3) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.bad {
+		return nil
+	}
+	return &NonFiniteError{Point: s.p, Value: s.dist, Path: NonFinitePath(s.sdf, s.p)}
+}
+
+//-----------------------------------------------------------------------------
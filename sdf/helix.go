@@ -0,0 +1,143 @@
+//-----------------------------------------------------------------------------
+/*
+
+Helical Sweeps
+
+A 2D profile is swept along a helix about the z-axis. The profile x-axis is
+the radial offset from the helix and the profile y-axis is the axial (z)
+offset. A circle profile gives a coil spring, a thread profile gives a
+screw thread, and a long thin profile gives an auger flight.
+
+The sweep is evaluated in helical coordinates: the turns of the helix that
+pass near the point are found directly, so the cost of evaluation doesn't
+depend on the number of turns.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// HelixSDF3 is a 2D profile swept along a helix.
+type HelixSDF3 struct {
+	profile SDF2    // 2D sweep profile
+	pitch   float64 // distance per turn
+	turns   float64 // number of turns
+	radius  float64 // starting radius
+	slope   float64 // radius increase per unit height (tan(taper))
+	inner   float64 // inner radius of the sweep
+	n       int     // number of turns to check either side of the nearest turn
+	bb      Box3    // bounding box
+}
+
+// HelicalSweep3D returns a 2D profile swept along a right handed helix about the z-axis.
+// The helix starts on the x-axis at z = 0 with the given radius and rises by pitch per turn.
+// A non-zero taper angle (radians) changes the radius as the helix rises.
+// Use Transform3D with a mirroring matrix for a left handed helix.
+func HelicalSweep3D(profile SDF2, pitch, turns, startRadius, taper float64) (SDF3, error) {
+	if profile == nil {
+		return nil, ErrMsg("profile == nil")
+	}
+	if pitch <= 0 {
+		return nil, ErrMsg("pitch <= 0")
+	}
+	if turns <= 0 {
+		return nil, ErrMsg("turns <= 0")
+	}
+	if startRadius < 0 {
+		return nil, ErrMsg("startRadius < 0")
+	}
+	if math.Abs(taper) >= Pi*0.5 {
+		return nil, ErrMsg("abs(taper) >= Pi * 0.5")
+	}
+	s := HelixSDF3{
+		profile: profile,
+		pitch:   pitch,
+		turns:   turns,
+		radius:  startRadius,
+		slope:   math.Tan(taper),
+	}
+	pbb := profile.BoundingBox()
+	// profiles taller than a pitch overlap the adjacent turns
+	h := math.Max(math.Abs(pbb.Min.Y), math.Abs(pbb.Max.Y))
+	s.n = int(math.Ceil(h/pitch)) + 1
+	// work out the bounding box
+	height := pitch * turns
+	r := math.Max(s.radiusAt(0), s.radiusAt(height)) + pbb.Max.X
+	s.inner = math.Min(s.radiusAt(0), s.radiusAt(height)) + pbb.Min.X
+	s.bb = Box3{v3.Vec{-r, -r, pbb.Min.Y}, v3.Vec{r, r, height + pbb.Max.Y}}
+	return &s, nil
+}
+
+// radiusAt returns the helix radius at height z.
+func (s *HelixSDF3) radiusAt(z float64) float64 {
+	return s.radius + z*s.slope
+}
+
+// capDistance returns the distance to an end of the sweep.
+// The end is the profile in the plane at angle theta, extended along
+// the helix tangent and cut by the plane. b > 0 is beyond the end.
+func (s *HelixSDF3) capDistance(p v3.Vec, t, sign float64) (float64, bool) {
+	sin, cos := math.Sincos(Tau * t)
+	a := p.X*cos + p.Y*sin          // radial position in the end plane
+	b := sign * (p.Y*cos - p.X*sin) // distance beyond the end plane
+	z := s.pitch * t
+	d := s.profile.Evaluate(v2.Vec{a - s.radiusAt(z), p.Z - z})
+	return math.Max(d, b), b > 0
+}
+
+// Evaluate returns the minimum distance to a helical sweep.
+func (s *HelixSDF3) Evaluate(p v3.Vec) float64 {
+	rho := math.Max(math.Hypot(p.X, p.Y), epsilon)
+	// helix parameter for the angle of p within a turn
+	t0 := math.Atan2(p.Y, p.X) / Tau
+	if t0 < 0 {
+		t0++
+	}
+	// The profile distance is measured in the rz-plane but the sweep surface
+	// is inclined at the helix angle, so scale the distance to keep it a bound.
+	k := Tau * rho / math.Hypot(Tau*rho, s.pitch)
+	// check the turns near the point
+	kc := int(math.Round(p.Z/s.pitch - t0))
+	kc = maxInt(kc, int(math.Ceil(-t0)))
+	kc = minInt(kc, int(math.Floor(s.turns-t0)))
+	d := math.MaxFloat64
+	for i := kc - s.n; i <= kc+s.n; i++ {
+		t := t0 + float64(i)
+		if t < 0 || t > s.turns {
+			continue
+		}
+		z := s.pitch * t
+		x := s.profile.Evaluate(v2.Vec{rho - s.radiusAt(z), p.Z - z})
+		d = math.Min(d, x*k)
+	}
+	// check the ends
+	d0, beyond0 := s.capDistance(p, 0, -1)
+	d1, beyond1 := s.capDistance(p, s.turns, 1)
+	if beyond0 {
+		d = math.Min(d, d0)
+	}
+	if beyond1 {
+		d = math.Min(d, d1)
+	}
+	if d == math.MaxFloat64 {
+		d = math.Min(d0, d1)
+	}
+	// points inside the inner radius are at least this far away
+	return math.Max(d, s.inner-rho)
+}
+
+// BoundingBox returns the bounding box of a helical sweep.
+func (s *HelixSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
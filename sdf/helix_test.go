@@ -0,0 +1,155 @@
+//-----------------------------------------------------------------------------
+/*
+
+Helical Sweep Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// helixRef is a brute force reference for a circular profile helical sweep.
+type helixRef struct {
+	profile       SDF2
+	r             float64 // profile radius
+	pitch, turns  float64
+	radius, slope float64
+	surface       []v3.Vec // sampled surface points
+}
+
+func newHelixRef(r, pitch, turns, radius, taper float64) *helixRef {
+	profile, _ := Circle2D(r)
+	h := &helixRef{profile: profile, r: r, pitch: pitch, turns: turns, radius: radius, slope: math.Tan(taper)}
+	const spacing = 0.05
+	// the profile in the plane at angle theta
+	plane := func(t float64, x v2.Vec) v3.Vec {
+		sin, cos := math.Sincos(Tau * t)
+		rho := h.radius + h.slope*pitch*t + x.X
+		return v3.Vec{rho * cos, rho * sin, pitch*t + x.Y}
+	}
+	nt := int(math.Ceil(turns * Tau * (radius + 2*r) / spacing))
+	na := int(math.Ceil(Tau * r / spacing))
+	for i := 0; i <= nt; i++ {
+		t := turns * float64(i) / float64(nt)
+		for j := 0; j < na; j++ {
+			sin, cos := math.Sincos(Tau * float64(j) / float64(na))
+			h.surface = append(h.surface, plane(t, v2.Vec{r * cos, r * sin}))
+		}
+	}
+	// the flat ends
+	for x := -r; x <= r; x += spacing {
+		for y := -r; y <= r; y += spacing {
+			if x*x+y*y < r*r {
+				h.surface = append(h.surface, plane(0, v2.Vec{x, y}), plane(turns, v2.Vec{x, y}))
+			}
+		}
+	}
+	return h
+}
+
+// inside returns true if a point is inside the sweep.
+func (h *helixRef) inside(p v3.Vec) bool {
+	rho := math.Hypot(p.X, p.Y)
+	t0 := math.Atan2(p.Y, p.X) / Tau
+	if t0 < 0 {
+		t0++
+	}
+	for t := t0; t <= h.turns; t++ {
+		z := h.pitch * t
+		if h.profile.Evaluate(v2.Vec{rho - (h.radius + h.slope*z), p.Z - z}) < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// distance returns the signed distance to the sampled surface.
+func (h *helixRef) distance(p v3.Vec) float64 {
+	d2 := math.MaxFloat64
+	for _, x := range h.surface {
+		d2 = math.Min(d2, p.Sub(x).Length2())
+	}
+	if h.inside(p) {
+		return -math.Sqrt(d2)
+	}
+	return math.Sqrt(d2)
+}
+
+func Test_HelicalSweep3D(t *testing.T) {
+	for _, taper := range []float64{0, DtoR(10)} {
+		const r, pitch, turns, radius = 1.0, 4.0, 2.5, 8.0
+		circle, _ := Circle2D(r)
+		s, err := HelicalSweep3D(circle, pitch, turns, radius, taper)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref := newHelixRef(r, pitch, turns, radius, taper)
+
+		// the bounding box contains the surface and is tight
+		bb := s.BoundingBox()
+		var tight Box3
+		for i, p := range ref.surface {
+			if !bb.Contains(p) {
+				t.Fatalf("taper %g: %v is outside the bounding box %v", taper, p, bb)
+			}
+			if i == 0 {
+				tight = Box3{p, p}
+			}
+			tight = tight.Include(p)
+		}
+		// the box is square in xy, for the largest radius of the sweep
+		rmax := math.Max(math.Max(-tight.Min.X, tight.Max.X), math.Max(-tight.Min.Y, tight.Max.Y))
+		if math.Abs(bb.Max.X-rmax) > 0.1 || math.Abs(bb.Min.Z-tight.Min.Z) > 0.1 || math.Abs(bb.Max.Z-tight.Max.Z) > 0.1 {
+			t.Errorf("taper %g: bounding box %v, expected radius %g and z %g to %g", taper, bb, rmax, tight.Min.Z, tight.Max.Z)
+		}
+
+		// the distance has the sign of the reference, and is a bound on it
+		test := bb.Enlarge(v3.Vec{2, 2, 2})
+		for i := 0; i < 300; i++ {
+			p := test.Random()
+			d, dref := s.Evaluate(p), ref.distance(p)
+			if math.Abs(dref) < 0.1 {
+				// too close to the sampled surface to compare
+				continue
+			}
+			if math.Signbit(d) != math.Signbit(dref) {
+				t.Fatalf("taper %g: %v: distance %g, expected %g", taper, p, d, dref)
+			}
+			if math.Abs(d) > math.Abs(dref)+0.05 {
+				t.Fatalf("taper %g: %v: distance %g is more than %g", taper, p, d, dref)
+			}
+			if math.Abs(dref) < 2 && math.Abs(d) < 0.5*math.Abs(dref) {
+				t.Errorf("taper %g: %v: distance %g is much less than %g", taper, p, d, dref)
+			}
+		}
+	}
+
+	circle, _ := Circle2D(1)
+	for _, x := range []struct {
+		pitch, turns, radius, taper float64
+	}{
+		{0, 1, 5, 0},
+		{1, 0, 5, 0},
+		{1, 1, -1, 0},
+		{1, 1, 5, Pi / 2},
+	} {
+		if _, err := HelicalSweep3D(circle, x.pitch, x.turns, x.radius, x.taper); err == nil {
+			t.Errorf("expected an error for %+v", x)
+		}
+	}
+	if _, err := HelicalSweep3D(nil, 1, 1, 5, 0); err == nil {
+		t.Error("expected an error for a nil profile")
+	}
+}
+
+//-----------------------------------------------------------------------------
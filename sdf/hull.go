@@ -0,0 +1,425 @@
+//-----------------------------------------------------------------------------
+/*
+
+Convex Hulls
+
+The convex hull of a set of SDF2s or SDF3s, e.g. a rounded enclosure from
+spheres at its corners.
+
+The surfaces of the children are sampled (see surface.go): the grid points
+(with a given spacing) the surface may pass near are moved onto the
+surface along the gradient. The hull of the sample points is a polygon
+(2D) or a triangle mesh (3D, quickhull) with an exact distance. The faces
+are chords between the samples, so a curved surface is inside the true hull
+by about spacing^2/(8*radius).
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+// 2D Hull
+
+// convexHull2 returns the convex hull of a set of points (anti-clockwise).
+func convexHull2(points []v2.Vec) []v2.Vec {
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].X != points[j].X {
+			return points[i].X < points[j].X
+		}
+		return points[i].Y < points[j].Y
+	})
+	cross := func(o, a, b v2.Vec) float64 {
+		return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+	}
+	hull := make([]v2.Vec, 0, 2*len(points))
+	// lower hull
+	for _, p := range points {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	// upper hull
+	n := len(hull) + 1
+	for i := len(points) - 2; i >= 0; i-- {
+		p := points[i]
+		for len(hull) >= n && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	return hull[:len(hull)-1]
+}
+
+// HullSDF2 is the convex hull of SDF2s.
+type HullSDF2 struct {
+	sdf  []SDF2
+	hull SDF2
+}
+
+// Hull2D returns the convex hull of SDF2s. The boundaries are sampled with
+// a spacing of resolution.
+func Hull2D(resolution float64, sdf ...SDF2) (SDF2, error) {
+	if resolution <= 0 {
+		return nil, ErrMsg("resolution <= 0")
+	}
+	s := HullSDF2{}
+	var points []v2.Vec
+	for _, x := range sdf {
+		if x != nil {
+			s.sdf = append(s.sdf, x)
+			points = append(points, surfaceSamples2(x, resolution)...)
+		}
+	}
+	if len(s.sdf) == 0 {
+		return nil, ErrMsg("no sdf")
+	}
+	hull := convexHull2(points)
+	if len(hull) < 3 {
+		return nil, ErrMsg("hull has no area")
+	}
+	var err error
+	s.hull, err = Polygon2D(hull)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Evaluate returns the minimum distance to a 2d hull.
+func (s *HullSDF2) Evaluate(p v2.Vec) float64 {
+	return s.hull.Evaluate(p)
+}
+
+// BoundingBox returns the bounding box of a 2d hull.
+func (s *HullSDF2) BoundingBox() Box2 {
+	return s.hull.BoundingBox()
+}
+
+// Children returns the SDF2s of a 2d hull.
+func (s *HullSDF2) Children() []interface{} {
+	out := make([]interface{}, len(s.sdf))
+	for i, x := range s.sdf {
+		out[i] = x
+	}
+	return out
+}
+
+//-----------------------------------------------------------------------------
+// 3D Hull (quickhull)
+
+// hullJoggle is the size of the random offsets of the 3d hull points (relative to the hull size).
+const hullJoggle = 1e-6
+
+// hullFace is a triangle of a 3d hull.
+type hullFace struct {
+	v       [3]int       // vertices, anti-clockwise from outside
+	n       v3.Vec       // outward unit normal
+	d       float64      // plane offset (n.p = d)
+	adj     [3]*hullFace // face across edge v[i] -> v[i+1]
+	outside []int        // points outside the face
+	dead    bool
+	visible bool
+}
+
+// distance returns the signed distance from the plane of a face.
+func (f *hullFace) distance(p v3.Vec) float64 {
+	return f.n.Dot(p) - f.d
+}
+
+// quickHull is the state of a 3d hull computation.
+type quickHull struct {
+	points []v3.Vec
+	eps    float64 // distance of a point outside a face
+	flat   float64 // minimum extent of a hull with volume
+	faces  []*hullFace
+}
+
+// newFace returns a face with vertices a, b, c (anti-clockwise from outside).
+func (q *quickHull) newFace(a, b, c int) *hullFace {
+	pa, pb, pc := q.points[a], q.points[b], q.points[c]
+	n := pb.Sub(pa).Cross(pc.Sub(pa)).Normalize()
+	f := &hullFace{v: [3]int{a, b, c}, n: n, d: n.Dot(pa)}
+	q.faces = append(q.faces, f)
+	return f
+}
+
+// assign adds the points to the first face they are outside of.
+func (q *quickHull) assign(points []int, faces []*hullFace) {
+	for _, i := range points {
+		for _, f := range faces {
+			if f.distance(q.points[i]) > q.eps {
+				f.outside = append(f.outside, i)
+				break
+			}
+		}
+	}
+}
+
+// link sets the adjacency of faces sharing edges.
+func link(faces []*hullFace) {
+	edges := make(map[[2]int]*hullFace)
+	for _, f := range faces {
+		for i := 0; i < 3; i++ {
+			edges[[2]int{f.v[i], f.v[(i+1)%3]}] = f
+		}
+	}
+	for _, f := range faces {
+		for i := 0; i < 3; i++ {
+			if g, ok := edges[[2]int{f.v[(i+1)%3], f.v[i]}]; ok {
+				f.adj[i] = g
+			}
+		}
+	}
+}
+
+// simplex returns the initial tetrahedron of the hull.
+func (q *quickHull) simplex() ([]*hullFace, error) {
+	p := q.points
+	// the most distant pair of the extreme points on each axis
+	var extremes []int
+	for axis := 0; axis < 3; axis++ {
+		lo, hi := 0, 0
+		for i := range p {
+			if p[i].Get(axis) < p[lo].Get(axis) {
+				lo = i
+			}
+			if p[i].Get(axis) > p[hi].Get(axis) {
+				hi = i
+			}
+		}
+		extremes = append(extremes, lo, hi)
+	}
+	a, b, dmax := 0, 0, 0.0
+	for _, i := range extremes {
+		for _, j := range extremes {
+			if d := p[i].Sub(p[j]).Length(); d > dmax {
+				a, b, dmax = i, j, d
+			}
+		}
+	}
+	if dmax <= q.flat {
+		return nil, ErrMsg("hull has no volume")
+	}
+	// the most distant point from the line
+	ab := p[b].Sub(p[a]).Normalize()
+	c, dmax := 0, 0.0
+	for i := range p {
+		v := p[i].Sub(p[a])
+		if d := v.Sub(ab.MulScalar(v.Dot(ab))).Length(); d > dmax {
+			c, dmax = i, d
+		}
+	}
+	if dmax <= q.flat {
+		return nil, ErrMsg("hull has no volume")
+	}
+	// the most distant point from the plane
+	n := p[b].Sub(p[a]).Cross(p[c].Sub(p[a])).Normalize()
+	d, dmax := 0, 0.0
+	for i := range p {
+		if x := math.Abs(p[i].Sub(p[a]).Dot(n)); x > dmax {
+			d, dmax = i, x
+		}
+	}
+	if dmax <= q.flat {
+		return nil, ErrMsg("hull has no volume")
+	}
+	if p[d].Sub(p[a]).Dot(n) > 0 {
+		// d is above abc, so abc faces down
+		b, c = c, b
+	}
+	faces := []*hullFace{
+		q.newFace(a, b, c),
+		q.newFace(a, d, b),
+		q.newFace(b, d, c),
+		q.newFace(c, d, a),
+	}
+	link(faces)
+	return faces, nil
+}
+
+// build returns the hull triangles of the points.
+func (q *quickHull) build() ([]*Triangle3, error) {
+	faces, err := q.simplex()
+	if err != nil {
+		return nil, err
+	}
+	all := make([]int, len(q.points))
+	for i := range all {
+		all[i] = i
+	}
+	q.assign(all, faces)
+
+	stack := append([]*hullFace(nil), faces...)
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if f.dead || len(f.outside) == 0 {
+			continue
+		}
+		// the furthest point outside the face
+		eye, dmax := -1, 0.0
+		for _, i := range f.outside {
+			if d := f.distance(q.points[i]); d > dmax {
+				eye, dmax = i, d
+			}
+		}
+		pe := q.points[eye]
+
+		// the faces visible from the point, and the horizon edges around them
+		type edge struct {
+			a, b  int
+			other *hullFace
+		}
+		var visible []*hullFace
+		var horizon []edge
+		f.visible = true
+		todo := []*hullFace{f}
+		for len(todo) > 0 {
+			g := todo[len(todo)-1]
+			todo = todo[:len(todo)-1]
+			visible = append(visible, g)
+			for i, h := range g.adj {
+				if h.visible {
+					continue
+				}
+				if h.distance(pe) > q.eps {
+					h.visible = true
+					todo = append(todo, h)
+				} else {
+					horizon = append(horizon, edge{g.v[i], g.v[(i+1)%3], h})
+				}
+			}
+		}
+
+		// new faces from the horizon edges to the point
+		created := make([]*hullFace, len(horizon))
+		for i, e := range horizon {
+			g := q.newFace(e.a, e.b, eye)
+			g.adj[0] = e.other
+			for j := range e.other.adj {
+				if e.other.v[j] == e.b && e.other.v[(j+1)%3] == e.a {
+					e.other.adj[j] = g
+				}
+			}
+			created[i] = g
+		}
+		// link the new faces to each other
+		from := make(map[int]*hullFace, len(created))
+		to := make(map[int]*hullFace, len(created))
+		for _, g := range created {
+			from[g.v[0]] = g // edge eye -> v[0] is g.adj[2]
+			to[g.v[1]] = g   // edge v[1] -> eye is g.adj[1]
+		}
+		for _, g := range created {
+			g.adj[1] = from[g.v[1]]
+			g.adj[2] = to[g.v[0]]
+		}
+
+		// reassign the points outside the visible faces
+		for _, g := range visible {
+			g.dead = true
+			outside := g.outside[:0]
+			for _, i := range g.outside {
+				if i != eye {
+					outside = append(outside, i)
+				}
+			}
+			q.assign(outside, created)
+			g.outside = nil
+		}
+		stack = append(stack, created...)
+	}
+
+	var mesh []*Triangle3
+	for _, f := range q.faces {
+		if !f.dead {
+			mesh = append(mesh, &Triangle3{q.points[f.v[0]], q.points[f.v[1]], q.points[f.v[2]]})
+		}
+	}
+	return mesh, nil
+}
+
+// HullSDF3 is the convex hull of SDF3s.
+type HullSDF3 struct {
+	sdf  []SDF3
+	hull SDF3
+}
+
+// Hull3D returns the convex hull of SDF3s. The surfaces are sampled with a
+// spacing of resolution.
+func Hull3D(resolution float64, sdf ...SDF3) (SDF3, error) {
+	if resolution <= 0 {
+		return nil, ErrMsg("resolution <= 0")
+	}
+	s := HullSDF3{}
+	var points []v3.Vec
+	var bb Box3
+	for _, x := range sdf {
+		if x == nil {
+			continue
+		}
+		if len(s.sdf) == 0 {
+			bb = x.BoundingBox()
+		}
+		bb = bb.Extend(x.BoundingBox())
+		s.sdf = append(s.sdf, x)
+		p, _ := surfaceSamples3(x, resolution)
+		points = append(points, p...)
+	}
+	if len(s.sdf) == 0 {
+		return nil, ErrMsg("no sdf")
+	}
+	if len(points) < 4 {
+		return nil, ErrMsg("hull has no volume")
+	}
+	// Joggle the points so that no 4 are coplanar, the sampled flat surfaces
+	// otherwise make sliver faces with unreliable normals.
+	size := bb.Size().MaxComponent()
+	r := rand.New(rand.NewSource(1))
+	for i := range points {
+		j := v3.Vec{X: r.Float64() - 0.5, Y: r.Float64() - 0.5, Z: r.Float64() - 0.5}
+		points[i] = points[i].Add(j.MulScalar(hullJoggle * size))
+	}
+	q := quickHull{points: points, eps: 1e-9 * size, flat: 10 * hullJoggle * size}
+	mesh, err := q.build()
+	if err != nil {
+		return nil, err
+	}
+	s.hull, err = Mesh3D(mesh)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Evaluate returns the minimum distance to a 3d hull.
+func (s *HullSDF3) Evaluate(p v3.Vec) float64 {
+	return s.hull.Evaluate(p)
+}
+
+// BoundingBox returns the bounding box of a 3d hull.
+func (s *HullSDF3) BoundingBox() Box3 {
+	return s.hull.BoundingBox()
+}
+
+// Children returns the SDF3s of a 3d hull.
+func (s *HullSDF3) Children() []interface{} {
+	out := make([]interface{}, len(s.sdf))
+	for i, x := range s.sdf {
+		out[i] = x
+	}
+	return out
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,89 @@
+//-----------------------------------------------------------------------------
+/*
+
+Convex Hull Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Hull2D(t *testing.T) {
+	c, _ := Circle2D(2)
+	s, err := Hull2D(0.1,
+		Transform2D(c, Translate2d(v2.Vec{X: -10})),
+		Transform2D(c, Translate2d(v2.Vec{X: 10})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a stadium
+	tests := []struct {
+		p v2.Vec
+		d float64
+	}{
+		{v2.Vec{}, -2},
+		{v2.Vec{Y: 5}, 3},
+		{v2.Vec{Y: -2}, 0},
+		{v2.Vec{X: 15}, 3},
+		{v2.Vec{X: -10, Y: 1}, -1},
+	}
+	for _, test := range tests {
+		if d := s.Evaluate(test.p); math.Abs(d-test.d) > 1e-2 {
+			t.Errorf("%v: expected %g, got %g", test.p, test.d, d)
+		}
+	}
+	if len(s.(*HullSDF2).Children()) != 2 {
+		t.Error("expected 2 children")
+	}
+	if _, err := Hull2D(0, c); err == nil {
+		t.Error("expected an error for resolution 0")
+	}
+}
+
+func Test_Hull3D(t *testing.T) {
+	// spheres at the corners of a box make a rounded box
+	sphere, _ := Sphere3D(2)
+	var corners []SDF3
+	for _, x := range []float64{-10, 10} {
+		for _, y := range []float64{-10, 10} {
+			for _, z := range []float64{-10, 10} {
+				corners = append(corners, Transform3D(sphere, Translate3d(v3.Vec{X: x, Y: y, Z: z})))
+			}
+		}
+	}
+	s, err := Hull3D(0.25, corners...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	box, _ := Box3D(v3.Vec{X: 24, Y: 24, Z: 24}, 2)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		p := v3.Vec{X: r.Float64()*40 - 20, Y: r.Float64()*40 - 20, Z: r.Float64()*40 - 20}
+		if d0, d1 := s.Evaluate(p), box.Evaluate(p); math.Abs(d0-d1) > 0.02 {
+			t.Fatalf("%v: expected %g, got %g", p, d1, d0)
+		}
+	}
+	if len(s.(*HullSDF3).Children()) != 8 {
+		t.Error("expected 8 children")
+	}
+
+	// a flat hull
+	square := Extrude3D(Box2D(v2.Vec{X: 10, Y: 10}, 0), 1e-9)
+	if _, err := Hull3D(1, square); err == nil {
+		t.Error("expected an error for a flat hull")
+	}
+}
+
+//-----------------------------------------------------------------------------
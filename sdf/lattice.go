@@ -0,0 +1,181 @@
+//-----------------------------------------------------------------------------
+/*
+
+Conformal Lattices
+
+Fill the space between an inner and an outer surface (e.g. the shelled wall
+of a helmet) with a lattice whose cells follow the surfaces rather than a
+global grid.
+
+The mapping uses the SDFs and the gradient of the inner surface:
+
+* Thickness direction: the fraction of the wall thickness w = a/(a-b), where
+a and b are the distances to the inner and outer surfaces. The wall holds a
+whole number of cell layers, so the cells stretch with the wall thickness
+and are never clipped by the surfaces.
+
+* Tangential directions: the point is projected onto the inner surface
+along the gradient and the projection is cube mapped, i.e. the two axes
+most perpendicular to the surface normal give the cell coordinates. Cells
+are distorted where the surface is steep relative to those axes, and there
+are seams where the dominant axis of the normal changes.
+
+The result is a bounded (not exact) distance field.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// LatticeCell is the type of lattice unit cell.
+type LatticeCell int
+
+// Lattice unit cells.
+const (
+	LatticeCubic  LatticeCell = iota // struts along the cell edges
+	LatticeBCC                       // body centered cubic: struts from the cell center to the corners
+	LatticeGyroid                    // gyroid sheet
+)
+
+// ConformalLatticeParms defines the parameters for a conformal lattice.
+type ConformalLatticeParms struct {
+	Cell      LatticeCell // unit cell type
+	Size      float64     // cell size along the surface
+	Layers    int         // number of cell layers between the surfaces
+	Thickness float64     // strut diameter or sheet thickness
+	Skin      float64     // thickness of the solid skins on the inner and outer surfaces (0 = none)
+}
+
+// ConformalLatticeSDF3 is a lattice between two surfaces.
+type ConformalLatticeSDF3 struct {
+	inner, outer SDF3
+	k            ConformalLatticeParms
+	eps          float64
+}
+
+// ConformalLattice3D returns a lattice filling the space outside of the inner
+// surface and inside of the outer surface.
+func ConformalLattice3D(inner, outer SDF3, k *ConformalLatticeParms) (SDF3, error) {
+	if inner == nil || outer == nil {
+		return nil, ErrMsg("nil surface")
+	}
+	if k.Size <= 0 {
+		return nil, ErrMsg("Size <= 0")
+	}
+	if k.Layers < 1 {
+		return nil, ErrMsg("Layers < 1")
+	}
+	if k.Thickness <= 0 || k.Thickness >= k.Size {
+		return nil, ErrMsg("Thickness not in (0,Size)")
+	}
+	if k.Skin < 0 {
+		return nil, ErrMsg("Skin < 0")
+	}
+	if k.Cell < LatticeCubic || k.Cell > LatticeGyroid {
+		return nil, ErrMsg("unknown lattice cell")
+	}
+	return &ConformalLatticeSDF3{
+		inner: inner,
+		outer: outer,
+		k:     *k,
+		eps:   k.Size * 1e-3,
+	}, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// fract returns the fractional part of x in [0,1).
+func fract(x float64) float64 {
+	return x - math.Floor(x)
+}
+
+// cellDistance returns the distance to the unit cell structure at
+// position l within a cell of size c.
+func (s *ConformalLatticeSDF3) cellDistance(l, c v3.Vec) float64 {
+	r := 0.5 * s.k.Thickness
+	switch s.k.Cell {
+	case LatticeCubic:
+		// fold to the nearest corner
+		d := v3.Vec{
+			math.Min(l.X, c.X-l.X),
+			math.Min(l.Y, c.Y-l.Y),
+			math.Min(l.Z, c.Z-l.Z),
+		}
+		e := math.Min(math.Hypot(d.X, d.Y), math.Min(math.Hypot(d.Y, d.Z), math.Hypot(d.Z, d.X)))
+		return e - r
+	case LatticeBCC:
+		// fold to the octant with the (0,0,0) corner, the strut runs from the center to it
+		h := c.MulScalar(0.5)
+		q := l.Sub(h).Abs()
+		t := Clamp(q.Dot(h)/h.Dot(h), 0, 1)
+		return q.Sub(h.MulScalar(t)).Length() - r
+	}
+	// gyroid: first order distance estimate
+	k := v3.Vec{Tau / c.X, Tau / c.Y, Tau / c.Z}
+	x := l.Mul(k)
+	sx, cx := math.Sincos(x.X)
+	sy, cy := math.Sincos(x.Y)
+	sz, cz := math.Sincos(x.Z)
+	g := sx*cy + sy*cz + sz*cx
+	grad := v3.Vec{
+		k.X * (cx*cy - sz*sx),
+		k.Y * (cy*cz - sx*sy),
+		k.Z * (cz*cx - sy*sz),
+	}
+	d := math.Abs(g) / math.Max(grad.Length(), s.eps)
+	return math.Min(d, 0.5*c.MinComponent()) - r
+}
+
+// Evaluate returns the minimum distance to a conformal lattice.
+func (s *ConformalLatticeSDF3) Evaluate(p v3.Vec) float64 {
+	a := s.inner.Evaluate(p)
+	b := s.outer.Evaluate(p)
+	wall := math.Max(-a, b)
+
+	// thickness coordinate
+	thickness := math.Max(a-b, s.eps)
+	w := Clamp(a/thickness, 0, 1) * float64(s.k.Layers)
+	h := thickness / float64(s.k.Layers)
+
+	// tangential coordinates: cube map the projection onto the inner surface
+	n := Normal3(s.inner, p, s.eps)
+	q := p.Sub(n.MulScalar(a))
+	var u, v float64
+	m := n.Abs()
+	switch {
+	case m.X >= m.Y && m.X >= m.Z:
+		u, v = q.Y, q.Z
+	case m.Y >= m.Z:
+		u, v = q.Z, q.X
+	default:
+		u, v = q.X, q.Y
+	}
+
+	l := v3.Vec{
+		fract(u/s.k.Size) * s.k.Size,
+		fract(v/s.k.Size) * s.k.Size,
+		fract(w) * h,
+	}
+	d := math.Max(s.cellDistance(l, v3.Vec{s.k.Size, s.k.Size, h}), wall)
+
+	if s.k.Skin > 0 {
+		d = math.Min(d, math.Max(-a, a-s.k.Skin))
+		d = math.Min(d, math.Max(b, -b-s.k.Skin))
+	}
+	return d
+}
+
+// BoundingBox returns the bounding box for a conformal lattice.
+func (s *ConformalLatticeSDF3) BoundingBox() Box3 {
+	return s.outer.BoundingBox()
+}
+
+//-----------------------------------------------------------------------------
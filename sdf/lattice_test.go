@@ -0,0 +1,61 @@
+//-----------------------------------------------------------------------------
+/*
+
+Conformal Lattice Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_ConformalLattice3D(t *testing.T) {
+	inner, _ := Sphere3D(20)
+	outer, _ := Sphere3D(30)
+	for _, cell := range []LatticeCell{LatticeCubic, LatticeBCC, LatticeGyroid} {
+		k := ConformalLatticeParms{
+			Cell:      cell,
+			Size:      8,
+			Layers:    2,
+			Thickness: 1.5,
+			Skin:      1,
+		}
+		s, err := ConformalLattice3D(inner, outer, &k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// outside of the wall
+		for _, p := range []v3.Vec{{0, 0, 0}, {0, 15, 0}, {0, 0, 35}, {-31, 0, 0}} {
+			if d := s.Evaluate(p); d <= 0 {
+				t.Errorf("cell %d %v: expected > 0, got %f", cell, p, d)
+			}
+		}
+		// in the skins
+		for _, p := range []v3.Vec{{0, 0, 20.5}, {0, 29.5, 0}, {-20.3, 1, 1}} {
+			if d := s.Evaluate(p); d >= 0 {
+				t.Errorf("cell %d %v: expected < 0, got %f", cell, p, d)
+			}
+		}
+	}
+
+	// the strut corners follow the surface: the layer boundary is at the mid wall
+	k := ConformalLatticeParms{Cell: LatticeCubic, Size: 8, Layers: 2, Thickness: 1.5}
+	s, _ := ConformalLattice3D(inner, outer, &k)
+	for _, p := range []v3.Vec{{0, 0, 25}, {0, 0, -25}, {25, 0, 0}, {0, -25, 0}} {
+		if d := s.Evaluate(p); d >= 0 {
+			t.Errorf("%v: expected < 0, got %f", p, d)
+		}
+	}
+	if d := s.Evaluate(v3.Vec{4, 4, 26}); d <= 0 {
+		t.Errorf("expected > 0 between struts, got %f", d)
+	}
+}
+
+//-----------------------------------------------------------------------------
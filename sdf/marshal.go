@@ -0,0 +1,1003 @@
+//-----------------------------------------------------------------------------
+/*
+
+SDF Tree Serialization
+
+The constructive tree of an SDF (primitives, parameters, transforms and
+boolean operations) is converted to a tree of nodes with a stable schema:
+
+	{"type": "Union3", "children": [...]}
+	{"type": "Box3", "params": {"size": [10, 20, 30], "round": [1]}}
+
+All parameters are arrays of numbers (vectors and matrices are flattened).
+The node tree can be written as JSON or CBOR and reconstructed with the
+public constructors, so it can be used for model files, caching, a viewer
+protocol or diffing parametric designs.
+
+Only the SDFs listed in the codec tables are supported. SDFs with custom
+functions (e.g. blended unions, twisted extrusions) are not serializable.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/deadsy/sdfx/vec/v2i"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// Node is a serialized SDF.
+type Node struct {
+	Type     string               `json:"type"`
+	Params   map[string][]float64 `json:"params,omitempty"`
+	Children []*Node              `json:"children,omitempty"`
+}
+
+// newNode returns a node with parameters.
+func newNode(t string, params map[string][]float64, children ...*Node) *Node {
+	return &Node{Type: t, Params: params, Children: children}
+}
+
+// param returns a named parameter with n values.
+func (n *Node) param(name string, count int) ([]float64, error) {
+	x, ok := n.Params[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: missing parameter \"%s\"", n.Type, name)
+	}
+	if len(x) != count && count >= 0 {
+		return nil, fmt.Errorf("%s: parameter \"%s\" has %d values, expected %d", n.Type, name, len(x), count)
+	}
+	return x, nil
+}
+
+// scalar returns a named scalar parameter.
+func (n *Node) scalar(name string) (float64, error) {
+	x, err := n.param(name, 1)
+	if err != nil {
+		return 0, err
+	}
+	return x[0], nil
+}
+
+// vec2 returns a named 2d vector parameter.
+func (n *Node) vec2(name string) (v2.Vec, error) {
+	x, err := n.param(name, 2)
+	if err != nil {
+		return v2.Vec{}, err
+	}
+	return v2.Vec{x[0], x[1]}, nil
+}
+
+// vec3 returns a named 3d vector parameter.
+func (n *Node) vec3(name string) (v3.Vec, error) {
+	x, err := n.param(name, 3)
+	if err != nil {
+		return v3.Vec{}, err
+	}
+	return v3.Vec{x[0], x[1], x[2]}, nil
+}
+
+// children2 returns the child SDF2s of a node.
+func (n *Node) children2(count int) ([]SDF2, error) {
+	if count >= 0 && len(n.Children) != count {
+		return nil, fmt.Errorf("%s: %d children, expected %d", n.Type, len(n.Children), count)
+	}
+	s := make([]SDF2, len(n.Children))
+	for i, c := range n.Children {
+		if c == nil {
+			return nil, fmt.Errorf("%s: nil child", n.Type)
+		}
+		var err error
+		if s[i], err = c.SDF2(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// children3 returns the child SDF3s of a node.
+func (n *Node) children3(count int) ([]SDF3, error) {
+	if count >= 0 && len(n.Children) != count {
+		return nil, fmt.Errorf("%s: %d children, expected %d", n.Type, len(n.Children), count)
+	}
+	s := make([]SDF3, len(n.Children))
+	for i, c := range n.Children {
+		if c == nil {
+			return nil, fmt.Errorf("%s: nil child", n.Type)
+		}
+		var err error
+		if s[i], err = c.SDF3(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// sameFunc returns true if two functions are the same top level function.
+func sameFunc(a, b interface{}) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+// checkMin returns an error if a min function is not the default.
+func checkMin(t string, f MinFunc) error {
+	if !sameFunc(f, math.Min) {
+		return fmt.Errorf("%s: custom min function is not serializable", t)
+	}
+	return nil
+}
+
+// checkMax returns an error if a max function is not the default.
+func checkMax(t string, f MaxFunc) error {
+	if !sameFunc(f, math.Max) {
+		return fmt.Errorf("%s: custom max function is not serializable", t)
+	}
+	return nil
+}
+
+// encode2 returns the nodes for a set of SDF2s.
+func encode2(s ...SDF2) ([]*Node, error) {
+	nodes := make([]*Node, len(s))
+	for i, x := range s {
+		var err error
+		if nodes[i], err = NewNode(x); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// encode3 returns the nodes for a set of SDF3s.
+func encode3(s ...SDF3) ([]*Node, error) {
+	nodes := make([]*Node, len(s))
+	for i, x := range s {
+		var err error
+		if nodes[i], err = NewNode(x); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// NewNode returns the node tree for an SDF2 or SDF3.
+func NewNode(s interface{}) (*Node, error) {
+	type params = map[string][]float64
+	switch s := s.(type) {
+
+	// 2d primitives
+	case *CircleSDF2:
+		return newNode("Circle2", params{"radius": {s.radius}}), nil
+	case *BoxSDF2:
+		size := s.size.AddScalar(s.round).MulScalar(2)
+		return newNode("Box2", params{"size": {size.X, size.Y}, "round": {s.round}}), nil
+	case *LineSDF2:
+		return newNode("Line2", params{"length": {2 * s.l}, "round": {s.round}}), nil
+	case *MeshSDF2:
+		lines := make([]float64, 0, 4*len(s.mesh))
+		for _, l := range s.mesh {
+			lines = append(lines, l[0].X, l[0].Y, l[1].X, l[1].Y)
+		}
+		return newNode("Mesh2", params{"lines": lines}), nil
+
+	// 2d operations
+	case *TransformSDF2:
+		c, err := encode2(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("Transform2", params{"matrix": s.m[:]}, c...), nil
+	case *ScaleUniformSDF2:
+		c, err := encode2(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("ScaleUniform2", params{"k": {s.k}}, c...), nil
+	case *UnionSDF2:
+		if err := checkMin("Union2", s.min); err != nil {
+			return nil, err
+		}
+		c, err := encode2(s.sdf...)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("Union2", nil, c...), nil
+	case *DifferenceSDF2:
+		if err := checkMax("Difference2", s.max); err != nil {
+			return nil, err
+		}
+		c, err := encode2(s.s0, s.s1)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("Difference2", nil, c...), nil
+	case *IntersectionSDF2:
+		if err := checkMax("Intersection2", s.max); err != nil {
+			return nil, err
+		}
+		c, err := encode2(s.s0, s.s1)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("Intersection2", nil, c...), nil
+	case *OffsetSDF2:
+		c, err := encode2(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("Offset2", params{"offset": {s.offset}}, c...), nil
+	case *CutSDF2:
+		c, err := encode2(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		// the cut direction is the normal rotated by -90 degrees
+		return newNode("Cut2", params{"a": {s.a.X, s.a.Y}, "v": {s.n.Y, -s.n.X}}, c...), nil
+	case *ElongateSDF2:
+		c, err := encode2(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		h := s.hp.MulScalar(2)
+		return newNode("Elongate2", params{"h": {h.X, h.Y}}, c...), nil
+	case *ArraySDF2:
+		if err := checkMin("Array2", s.min); err != nil {
+			return nil, err
+		}
+		c, err := encode2(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		p := params{"num": {float64(s.num.X), float64(s.num.Y)}, "step": {s.step.X, s.step.Y}}
+		return newNode("Array2", p, c...), nil
+	case *RotateCopySDF2:
+		c, err := encode2(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("RotateCopy2", params{"num": {math.Round(Tau / s.theta)}}, c...), nil
+
+	// 3d primitives
+	case *SphereSDF3:
+		return newNode("Sphere3", params{"radius": {s.radius}}), nil
+	case *BoxSDF3:
+		size := s.size.AddScalar(s.round).MulScalar(2)
+		return newNode("Box3", params{"size": {size.X, size.Y, size.Z}, "round": {s.round}}), nil
+	case *CylinderSDF3:
+		p := params{
+			"height": {2 * (s.height + s.round)},
+			"radius": {s.radius + s.round},
+			"round":  {s.round},
+		}
+		return newNode("Cylinder3", p), nil
+	case *ConeSDF3:
+		// undo the rounding inset of the radii
+		ofs := s.round / s.n.X
+		p := params{
+			"height": {2 * (s.height + s.round)},
+			"r0":     {s.r0 + (1+s.n.Y)*ofs},
+			"r1":     {s.r1 + (1-s.n.Y)*ofs},
+			"round":  {s.round},
+		}
+		return newNode("Cone3", p), nil
+
+	// 2d to 3d
+	case *ExtrudeSDF3:
+		if !sameFunc(s.extrude, NormalExtrude) {
+			return nil, fmt.Errorf("Extrude3: custom extrude function is not serializable")
+		}
+		c, err := encode2(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("Extrude3", params{"height": {2 * s.height}}, c...), nil
+	case *ExtrudeRoundedSDF3:
+		c, err := encode2(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		p := params{"height": {2 * (s.height + s.round)}, "round": {s.round}}
+		return newNode("ExtrudeRounded3", p, c...), nil
+	case *LoftSDF3:
+		c, err := encode2(s.sdf0, s.sdf1)
+		if err != nil {
+			return nil, err
+		}
+		p := params{"height": {2 * (s.height + s.round)}, "round": {s.round}}
+		return newNode("Loft3", p, c...), nil
+	case *SorSDF3:
+		c, err := encode2(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("Revolve3", params{"theta": {s.theta}}, c...), nil
+
+	// 3d operations
+	case *TransformSDF3:
+		c, err := encode3(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("Transform3", params{"matrix": s.matrix[:]}, c...), nil
+	case *ScaleUniformSDF3:
+		c, err := encode3(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("ScaleUniform3", params{"k": {s.k}}, c...), nil
+	case *UnionSDF3:
+		if err := checkMin("Union3", s.min); err != nil {
+			return nil, err
+		}
+		c, err := encode3(s.sdf...)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("Union3", nil, c...), nil
+	case *DifferenceSDF3:
+		if err := checkMax("Difference3", s.max); err != nil {
+			return nil, err
+		}
+		c, err := encode3(s.s0, s.s1)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("Difference3", nil, c...), nil
+	case *IntersectionSDF3:
+		if err := checkMax("Intersection3", s.max); err != nil {
+			return nil, err
+		}
+		c, err := encode3(s.s0, s.s1)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("Intersection3", nil, c...), nil
+	case *OffsetSDF3:
+		c, err := encode3(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("Offset3", params{"offset": {s.offset}}, c...), nil
+	case *ShellSDF3:
+		c, err := encode3(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("Shell3", params{"thickness": {2 * s.delta}}, c...), nil
+	case *ElongateSDF3:
+		c, err := encode3(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		h := s.hp.MulScalar(2)
+		return newNode("Elongate3", params{"h": {h.X, h.Y, h.Z}}, c...), nil
+	case *CutSDF3:
+		c, err := encode3(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		// the stored normal points away from the kept side
+		n := s.n.Neg()
+		return newNode("Cut3", params{"a": {s.a.X, s.a.Y, s.a.Z}, "n": {n.X, n.Y, n.Z}}, c...), nil
+	case *ArraySDF3:
+		if err := checkMin("Array3", s.min); err != nil {
+			return nil, err
+		}
+		c, err := encode3(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		p := params{
+			"num":  {float64(s.num.X), float64(s.num.Y), float64(s.num.Z)},
+			"step": {s.step.X, s.step.Y, s.step.Z},
+		}
+		return newNode("Array3", p, c...), nil
+	case *RotateCopySDF3:
+		c, err := encode3(s.sdf)
+		if err != nil {
+			return nil, err
+		}
+		return newNode("RotateCopy3", params{"num": {math.Round(Tau / s.theta)}}, c...), nil
+	}
+	return nil, fmt.Errorf("%T is not serializable", s)
+}
+
+//-----------------------------------------------------------------------------
+
+// SDF2 returns the SDF2 for a node tree.
+func (n *Node) SDF2() (SDF2, error) {
+	switch n.Type {
+	case "Circle2":
+		r, err := n.scalar("radius")
+		if err != nil {
+			return nil, err
+		}
+		return Circle2D(r)
+	case "Box2":
+		size, err := n.vec2("size")
+		if err != nil {
+			return nil, err
+		}
+		round, err := n.scalar("round")
+		if err != nil {
+			return nil, err
+		}
+		return Box2D(size, round), nil
+	case "Line2":
+		l, err := n.scalar("length")
+		if err != nil {
+			return nil, err
+		}
+		round, err := n.scalar("round")
+		if err != nil {
+			return nil, err
+		}
+		return Line2D(l, round), nil
+	case "Mesh2":
+		x, err := n.param("lines", -1)
+		if err != nil {
+			return nil, err
+		}
+		if len(x)%4 != 0 {
+			return nil, fmt.Errorf("Mesh2: bad lines")
+		}
+		lines := make([]*Line2, len(x)/4)
+		for i := range lines {
+			lines[i] = &Line2{{x[4*i], x[4*i+1]}, {x[4*i+2], x[4*i+3]}}
+		}
+		return Mesh2D(lines)
+	}
+
+	// operations
+	switch n.Type {
+	case "Union2":
+		c, err := n.children2(-1)
+		if err != nil {
+			return nil, err
+		}
+		return Union2D(c...), nil
+	case "Difference2", "Intersection2":
+		c, err := n.children2(2)
+		if err != nil {
+			return nil, err
+		}
+		if n.Type == "Difference2" {
+			return Difference2D(c[0], c[1]), nil
+		}
+		return Intersect2D(c[0], c[1]), nil
+	}
+	c, err := n.children2(1)
+	if err != nil {
+		return nil, err
+	}
+	switch n.Type {
+	case "Transform2":
+		x, err := n.param("matrix", 9)
+		if err != nil {
+			return nil, err
+		}
+		var m M33
+		copy(m[:], x)
+		return Transform2D(c[0], m), nil
+	case "ScaleUniform2":
+		k, err := n.scalar("k")
+		if err != nil {
+			return nil, err
+		}
+		return ScaleUniform2D(c[0], k), nil
+	case "Offset2":
+		ofs, err := n.scalar("offset")
+		if err != nil {
+			return nil, err
+		}
+		return Offset2D(c[0], ofs), nil
+	case "Cut2":
+		a, err := n.vec2("a")
+		if err != nil {
+			return nil, err
+		}
+		v, err := n.vec2("v")
+		if err != nil {
+			return nil, err
+		}
+		return Cut2D(c[0], a, v), nil
+	case "Elongate2":
+		h, err := n.vec2("h")
+		if err != nil {
+			return nil, err
+		}
+		return Elongate2D(c[0], h), nil
+	case "Array2":
+		num, err := n.vec2("num")
+		if err != nil {
+			return nil, err
+		}
+		step, err := n.vec2("step")
+		if err != nil {
+			return nil, err
+		}
+		return Array2D(c[0], v2i.Vec{int(num.X), int(num.Y)}, step), nil
+	case "RotateCopy2":
+		num, err := n.scalar("num")
+		if err != nil {
+			return nil, err
+		}
+		return RotateCopy2D(c[0], int(num)), nil
+	}
+	return nil, fmt.Errorf("unknown SDF2 type \"%s\"", n.Type)
+}
+
+// SDF3 returns the SDF3 for a node tree.
+func (n *Node) SDF3() (SDF3, error) {
+	switch n.Type {
+	case "Sphere3":
+		r, err := n.scalar("radius")
+		if err != nil {
+			return nil, err
+		}
+		return Sphere3D(r)
+	case "Box3":
+		size, err := n.vec3("size")
+		if err != nil {
+			return nil, err
+		}
+		round, err := n.scalar("round")
+		if err != nil {
+			return nil, err
+		}
+		return Box3D(size, round)
+	case "Cylinder3":
+		h, err := n.scalar("height")
+		if err != nil {
+			return nil, err
+		}
+		r, err := n.scalar("radius")
+		if err != nil {
+			return nil, err
+		}
+		round, err := n.scalar("round")
+		if err != nil {
+			return nil, err
+		}
+		return Cylinder3D(h, r, round)
+	case "Cone3":
+		h, err := n.scalar("height")
+		if err != nil {
+			return nil, err
+		}
+		r0, err := n.scalar("r0")
+		if err != nil {
+			return nil, err
+		}
+		r1, err := n.scalar("r1")
+		if err != nil {
+			return nil, err
+		}
+		round, err := n.scalar("round")
+		if err != nil {
+			return nil, err
+		}
+		return Cone3D(h, r0, r1, round)
+	}
+
+	// 2d to 3d
+	switch n.Type {
+	case "Extrude3", "ExtrudeRounded3", "Revolve3":
+		c, err := n.children2(1)
+		if err != nil {
+			return nil, err
+		}
+		if n.Type == "Revolve3" {
+			theta, err := n.scalar("theta")
+			if err != nil {
+				return nil, err
+			}
+			return RevolveTheta3D(c[0], theta)
+		}
+		h, err := n.scalar("height")
+		if err != nil {
+			return nil, err
+		}
+		if n.Type == "Extrude3" {
+			return Extrude3D(c[0], h), nil
+		}
+		round, err := n.scalar("round")
+		if err != nil {
+			return nil, err
+		}
+		return ExtrudeRounded3D(c[0], h, round)
+	case "Loft3":
+		c, err := n.children2(2)
+		if err != nil {
+			return nil, err
+		}
+		h, err := n.scalar("height")
+		if err != nil {
+			return nil, err
+		}
+		round, err := n.scalar("round")
+		if err != nil {
+			return nil, err
+		}
+		return Loft3D(c[0], c[1], h, round)
+	}
+
+	// operations
+	switch n.Type {
+	case "Union3":
+		c, err := n.children3(-1)
+		if err != nil {
+			return nil, err
+		}
+		return Union3D(c...), nil
+	case "Difference3", "Intersection3":
+		c, err := n.children3(2)
+		if err != nil {
+			return nil, err
+		}
+		if n.Type == "Difference3" {
+			return Difference3D(c[0], c[1]), nil
+		}
+		return Intersect3D(c[0], c[1]), nil
+	}
+	c, err := n.children3(1)
+	if err != nil {
+		return nil, err
+	}
+	switch n.Type {
+	case "Transform3":
+		x, err := n.param("matrix", 16)
+		if err != nil {
+			return nil, err
+		}
+		var m M44
+		copy(m[:], x)
+		return Transform3D(c[0], m), nil
+	case "ScaleUniform3":
+		k, err := n.scalar("k")
+		if err != nil {
+			return nil, err
+		}
+		return ScaleUniform3D(c[0], k), nil
+	case "Offset3":
+		ofs, err := n.scalar("offset")
+		if err != nil {
+			return nil, err
+		}
+		return Offset3D(c[0], ofs), nil
+	case "Shell3":
+		t, err := n.scalar("thickness")
+		if err != nil {
+			return nil, err
+		}
+		return Shell3D(c[0], t)
+	case "Elongate3":
+		h, err := n.vec3("h")
+		if err != nil {
+			return nil, err
+		}
+		return Elongate3D(c[0], h), nil
+	case "Cut3":
+		a, err := n.vec3("a")
+		if err != nil {
+			return nil, err
+		}
+		nv, err := n.vec3("n")
+		if err != nil {
+			return nil, err
+		}
+		return Cut3D(c[0], a, nv), nil
+	case "Array3":
+		num, err := n.vec3("num")
+		if err != nil {
+			return nil, err
+		}
+		step, err := n.vec3("step")
+		if err != nil {
+			return nil, err
+		}
+		return Array3D(c[0], v3i.Vec{int(num.X), int(num.Y), int(num.Z)}, step), nil
+	case "RotateCopy3":
+		num, err := n.scalar("num")
+		if err != nil {
+			return nil, err
+		}
+		return RotateCopy3D(c[0], int(num)), nil
+	}
+	return nil, fmt.Errorf("unknown SDF3 type \"%s\"", n.Type)
+}
+
+//-----------------------------------------------------------------------------
+// JSON
+
+// Marshal returns the JSON encoding of an SDF2 or SDF3 tree.
+func Marshal(s interface{}) ([]byte, error) {
+	n, err := NewNode(s)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(n)
+}
+
+// Unmarshal returns the node tree of a JSON or CBOR encoded SDF tree.
+// Use the SDF2 or SDF3 method of the node to reconstruct the SDF.
+func Unmarshal(data []byte) (*Node, error) {
+	if len(data) > 0 && data[0]>>5 == cborMap {
+		return unmarshalCBOR(data)
+	}
+	n := &Node{}
+	if err := json.Unmarshal(data, n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Unmarshal3 reconstructs an SDF3 from its JSON or CBOR encoding.
+func Unmarshal3(data []byte) (SDF3, error) {
+	n, err := Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return n.SDF3()
+}
+
+// Unmarshal2 reconstructs an SDF2 from its JSON or CBOR encoding.
+func Unmarshal2(data []byte) (SDF2, error) {
+	n, err := Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return n.SDF2()
+}
+
+//-----------------------------------------------------------------------------
+// CBOR (RFC 8949), limited to the node schema.
+
+// CBOR major types
+const (
+	cborUint  = 0
+	cborNint  = 1
+	cborText  = 3
+	cborArray = 4
+	cborMap   = 5
+	cborOther = 7
+)
+
+// cborHead appends a CBOR item head.
+func cborHead(b []byte, major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(b, m|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, m|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, m|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, m|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, m|27), n)
+}
+
+// cborString appends a CBOR text string.
+func cborString(b []byte, s string) []byte {
+	return append(cborHead(b, cborText, uint64(len(s))), s...)
+}
+
+// appendCBOR appends the CBOR encoding of a node. Map keys are sorted for a stable encoding.
+func (n *Node) appendCBOR(b []byte) []byte {
+	fields := 1
+	if len(n.Params) > 0 {
+		fields++
+	}
+	if len(n.Children) > 0 {
+		fields++
+	}
+	b = cborHead(b, cborMap, uint64(fields))
+	b = cborString(b, "type")
+	b = cborString(b, n.Type)
+	if len(n.Params) > 0 {
+		b = cborString(b, "params")
+		keys := make([]string, 0, len(n.Params))
+		for k := range n.Params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b = cborHead(b, cborMap, uint64(len(keys)))
+		for _, k := range keys {
+			b = cborString(b, k)
+			b = cborHead(b, cborArray, uint64(len(n.Params[k])))
+			for _, x := range n.Params[k] {
+				b = binary.BigEndian.AppendUint64(append(b, cborOther<<5|27), math.Float64bits(x))
+			}
+		}
+	}
+	if len(n.Children) > 0 {
+		b = cborString(b, "children")
+		b = cborHead(b, cborArray, uint64(len(n.Children)))
+		for _, c := range n.Children {
+			b = c.appendCBOR(b)
+		}
+	}
+	return b
+}
+
+// MarshalCBOR returns the CBOR encoding of an SDF2 or SDF3 tree.
+func MarshalCBOR(s interface{}) ([]byte, error) {
+	n, err := NewNode(s)
+	if err != nil {
+		return nil, err
+	}
+	return n.appendCBOR(nil), nil
+}
+
+// cborDecoder decodes the CBOR node schema.
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+var errCBOR = ErrMsg("bad cbor data")
+
+// head returns the major type and argument of the next item.
+func (d *cborDecoder) head() (byte, uint64, error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, errCBOR
+	}
+	b := d.data[d.pos]
+	d.pos++
+	major, info := b>>5, b&0x1f
+	if info < 24 {
+		return major, uint64(info), nil
+	}
+	size := 0
+	switch info {
+	case 24:
+		size = 1
+	case 25:
+		size = 2
+	case 26:
+		size = 4
+	case 27:
+		size = 8
+	default:
+		return 0, 0, errCBOR
+	}
+	if d.pos+size > len(d.data) {
+		return 0, 0, errCBOR
+	}
+	var n uint64
+	for _, x := range d.data[d.pos : d.pos+size] {
+		n = n<<8 | uint64(x)
+	}
+	d.pos += size
+	return major, n, nil
+}
+
+// remaining returns the number of undecoded bytes.
+func (d *cborDecoder) remaining() uint64 {
+	return uint64(len(d.data) - d.pos)
+}
+
+// text returns the next text string.
+func (d *cborDecoder) text() (string, error) {
+	major, n, err := d.head()
+	if err != nil || major != cborText || n > d.remaining() {
+		return "", errCBOR
+	}
+	s := string(d.data[d.pos : d.pos+int(n)])
+	d.pos += int(n)
+	return s, nil
+}
+
+// number returns the next number.
+func (d *cborDecoder) number() (float64, error) {
+	if d.pos >= len(d.data) {
+		return 0, errCBOR
+	}
+	info := d.data[d.pos] & 0x1f
+	major, n, err := d.head()
+	if err != nil {
+		return 0, err
+	}
+	switch major {
+	case cborUint:
+		return float64(n), nil
+	case cborNint:
+		return -1 - float64(n), nil
+	case cborOther:
+		switch info {
+		case 26:
+			return float64(math.Float32frombits(uint32(n))), nil
+		case 27:
+			return math.Float64frombits(n), nil
+		}
+	}
+	return 0, errCBOR
+}
+
+// node decodes a node.
+func (d *cborDecoder) node() (*Node, error) {
+	major, fields, err := d.head()
+	if err != nil || major != cborMap {
+		return nil, errCBOR
+	}
+	n := &Node{}
+	for i := uint64(0); i < fields; i++ {
+		key, err := d.text()
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "type":
+			if n.Type, err = d.text(); err != nil {
+				return nil, err
+			}
+		case "params":
+			major, count, err := d.head()
+			// each entry takes at least two bytes
+			if err != nil || major != cborMap || count > d.remaining()/2 {
+				return nil, errCBOR
+			}
+			n.Params = make(map[string][]float64, count)
+			for j := uint64(0); j < count; j++ {
+				name, err := d.text()
+				if err != nil {
+					return nil, err
+				}
+				major, size, err := d.head()
+				if err != nil || major != cborArray || size > d.remaining() {
+					return nil, errCBOR
+				}
+				x := make([]float64, size)
+				for k := range x {
+					if x[k], err = d.number(); err != nil {
+						return nil, err
+					}
+				}
+				n.Params[name] = x
+			}
+		case "children":
+			major, count, err := d.head()
+			if err != nil || major != cborArray || count > d.remaining() {
+				return nil, errCBOR
+			}
+			n.Children = make([]*Node, count)
+			for j := range n.Children {
+				if n.Children[j], err = d.node(); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unknown node field \"%s\"", key)
+		}
+	}
+	return n, nil
+}
+
+// unmarshalCBOR returns the node tree of a CBOR encoded SDF tree.
+func unmarshalCBOR(data []byte) (*Node, error) {
+	d := &cborDecoder{data: data}
+	n, err := d.node()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(data) {
+		return nil, errCBOR
+	}
+	return n, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,122 @@
+//-----------------------------------------------------------------------------
+/*
+
+SDF Serialization Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"reflect"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Marshal(t *testing.T) {
+	box, _ := Box3D(v3.Vec{10, 20, 30}, 1)
+	cyl, _ := Cylinder3D(40, 4, 0.5)
+	cone, _ := Cone3D(10, 5, 2, 0.5)
+	sphere, _ := Sphere3D(6)
+	circle, _ := Circle2D(3)
+	rev, _ := RevolveTheta3D(Transform2D(circle, Translate2d(v2.Vec{8, 0})), Pi)
+	ext := Extrude3D(Union2D(Box2D(v2.Vec{8, 4}, 1), Line2D(10, 1)), 6)
+	s := Union3D(
+		Difference3D(box, Transform3D(cyl, RotateX(DtoR(30)).Mul(Translate3d(v3.Vec{1, 2, 3})))),
+		Intersect3D(Transform3D(cone, Translate3d(v3.Vec{0, 0, 20})), sphere),
+		Array3D(sphere, v3i.Vec{2, 1, 1}, v3.Vec{15, 0, 0}),
+		Transform3D(rev, Translate3d(v3.Vec{0, 30, 0})),
+		Cut3D(ext, v3.Vec{}, v3.Vec{1, 1, 0}),
+	)
+
+	bb := s.BoundingBox()
+	points := bb.RandomSet(1000)
+	for _, encode := range []func(interface{}) ([]byte, error){Marshal, MarshalCBOR} {
+		data, err := encode(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s1, err := Unmarshal3(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range points {
+			if d0, d1 := s.Evaluate(p), s1.Evaluate(p); !EqualFloat64(d0, d1, tolerance) {
+				t.Fatalf("%v: expected %f, got %f", p, d0, d1)
+			}
+		}
+		// stable encoding
+		data1, _ := encode(s)
+		if string(data) != string(data1) {
+			t.Error("encoding is not stable")
+		}
+	}
+
+	// json and cbor give the same node tree
+	j, _ := Marshal(s)
+	c, _ := MarshalCBOR(s)
+	n0, _ := Unmarshal(j)
+	n1, _ := Unmarshal(c)
+	if !reflect.DeepEqual(n0, n1) {
+		t.Error("json and cbor node trees differ")
+	}
+
+	// blended unions are not serializable
+	u := Union3D(box, sphere)
+	u.(*UnionSDF3).SetMin(PolyMin(1))
+	if _, err := Marshal(u); err == nil {
+		t.Error("expected an error for a blended union")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_UnmarshalMalformed(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,
+		{0xa1},
+		// a text length of 2^63 + 15
+		append([]byte{0xa1, 0x64, 't', 'y', 'p', 'e', 0x7b, 0x80}, make([]byte, 7)...),
+		// a text length of 2^64 - 1
+		{0xa1, 0x64, 't', 'y', 'p', 'e', 0x7b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		// huge parameter and children counts
+		{0xa1, 0x66, 'p', 'a', 'r', 'a', 'm', 's', 0xbb, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		{0xa1, 0x66, 'p', 'a', 'r', 'a', 'm', 's', 0xa1, 0x61, 'x', 0x9b, 0x80, 0, 0, 0, 0, 0, 0, 0},
+		{0xa1, 0x68, 'c', 'h', 'i', 'l', 'd', 'r', 'e', 'n', 0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		// json
+		[]byte(`{"type":`),
+		[]byte(`{"type":"Box3","params":{"size":[1]}}`),
+		[]byte(`{"type":"Union3","children":[null]}`),
+	} {
+		if _, err := Unmarshal3(data); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+		if _, err := Unmarshal2(data); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
+
+func Fuzz_Unmarshal(f *testing.F) {
+	box, _ := Box3D(v3.Vec{10, 20, 30}, 1)
+	circle, _ := Circle2D(3)
+	for _, s := range []interface{}{box, Extrude3D(circle, 4), circle} {
+		data, _ := Marshal(s)
+		f.Add(data)
+		data, _ = MarshalCBOR(s)
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// malformed data is an error, never a panic
+		Unmarshal3(data)
+		Unmarshal2(data)
+	})
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,152 @@
+//-----------------------------------------------------------------------------
+/*
+
+Material Tags
+
+WithMaterial tags a subtree of a model with a material (a name and a display
+color) so the exported mesh can carry per-face colors: multi-color prints
+and clearer CAD review.
+
+The regions are found at mesh time: a surface point has the material of the
+nearest tagged subtree. The model tree is walked to find the tagged subtrees,
+and the point is mapped through the transforms and uniform scales above each
+one, so it is evaluated in place. Other nodes that move their children (e.g.
+arrays) are not followed, and the tags below them see the unmoved point.
+Where a point is on the surface of nested tagged subtrees the innermost tag
+wins.
+
+The walk doesn't change the model, so it may run while the model is being
+evaluated elsewhere (e.g. while it is rendered).
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"image/color"
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Material is a named display color for a region of a model.
+type Material struct {
+	Name  string
+	Color color.RGBA
+}
+
+// MaterialSDF3 is an SDF3 tagged with a material.
+type MaterialSDF3 struct {
+	sdf      SDF3
+	material Material
+}
+
+// WithMaterial tags an SDF3 with a material.
+func WithMaterial(s SDF3, m Material) SDF3 {
+	if s == nil {
+		return nil
+	}
+	return &MaterialSDF3{sdf: s, material: m}
+}
+
+// Evaluate returns the minimum distance to a tagged SDF3.
+func (s *MaterialSDF3) Evaluate(p v3.Vec) float64 {
+	return s.sdf.Evaluate(p)
+}
+
+// BoundingBox returns the bounding box of a tagged SDF3.
+func (s *MaterialSDF3) BoundingBox() Box3 {
+	return s.sdf.BoundingBox()
+}
+
+// Material returns the material of a tagged SDF3.
+func (s *MaterialSDF3) Material() Material {
+	return s.material
+}
+
+// Children returns the tagged SDF3.
+func (s *MaterialSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+//-----------------------------------------------------------------------------
+
+// materialHit is the distance to a tagged subtree at a point.
+type materialHit struct {
+	material Material
+	d        float64 // absolute distance
+	depth    int     // nesting depth of the tag
+}
+
+// materialHits adds the distances at a point to the tagged subtrees of a
+// subtree. k scales the subtree distances to the model, and depth is the
+// number of tags above the subtree.
+func materialHits(s interface{}, p v3.Vec, k float64, depth int, hits *[]materialHit) {
+	switch x := s.(type) {
+	case *MaterialSDF3:
+		*hits = append(*hits, materialHit{x.material, math.Abs(x.sdf.Evaluate(p) * k), depth})
+		depth++
+	case *TransformSDF3:
+		p = x.inverse.MulPosition(p)
+	case *ScaleUniformSDF3:
+		p = p.MulScalar(x.invK)
+		k *= x.k
+	case SDF3:
+		// the children are in place (or not followed)
+	default:
+		// SDF2s have no tags
+		return
+	}
+	if c, ok := s.(Composite); ok {
+		for _, child := range c.Children() {
+			materialHits(child, p, k, depth, hits)
+		}
+	}
+}
+
+// MaterialMap finds the materials of the surface points of a model.
+type MaterialMap struct {
+	s         SDF3
+	Materials []Material // the materials found so far
+	index     map[Material]int
+}
+
+// NewMaterialMap returns a material map for a model.
+func NewMaterialMap(s SDF3) *MaterialMap {
+	return &MaterialMap{s: s, index: make(map[Material]int)}
+}
+
+// At returns the index (in Materials) of the material of a surface point,
+// or -1 if no tagged subtree is evaluated at the point. Distances within
+// the tolerance of the nearest are the same, and the innermost tag wins.
+func (m *MaterialMap) At(p v3.Vec, tol float64) int {
+	var hits []materialHit
+	materialHits(m.s, p, 1, 0, &hits)
+	if len(hits) == 0 {
+		return -1
+	}
+	dmin := math.Inf(1)
+	for _, h := range hits {
+		dmin = math.Min(dmin, h.d)
+	}
+	best := -1
+	for i, h := range hits {
+		if h.d > dmin+tol {
+			continue
+		}
+		if best < 0 || h.depth > hits[best].depth || (h.depth == hits[best].depth && h.d < hits[best].d) {
+			best = i
+		}
+	}
+	mat := hits[best].material
+	k, ok := m.index[mat]
+	if !ok {
+		k = len(m.Materials)
+		m.index[mat] = k
+		m.Materials = append(m.Materials, mat)
+	}
+	return k
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,103 @@
+//-----------------------------------------------------------------------------
+/*
+
+Material Tag Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"image/color"
+	"sync"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_MaterialMap(t *testing.T) {
+	red := Material{"red", color.RGBA{255, 0, 0, 255}}
+	blue := Material{"blue", color.RGBA{0, 0, 255, 255}}
+	green := Material{"green", color.RGBA{0, 255, 0, 255}}
+
+	box, _ := Box3D(v3.Vec{10, 10, 10}, 0)
+	ball, _ := Sphere3D(4)
+	// a blue ball on top of a red box, with a green (untagged) hole through the box
+	hole, _ := Cylinder3D(20, 2, 0)
+	top := Transform3D(WithMaterial(ball, blue), Translate3d(v3.Vec{0, 0, 8}))
+	s := Difference3D(WithMaterial(Union3D(box, top), red), Transform3D(hole, RotateX(DtoR(90))))
+	// a green tag outside the others
+	s = WithMaterial(s, green)
+
+	m := NewMaterialMap(s)
+	tests := []struct {
+		p v3.Vec
+		m Material
+	}{
+		{v3.Vec{5, 3, 0}, red},    // box side
+		{v3.Vec{0, 0, 12}, blue},  // top of the ball (the nested tag)
+		{v3.Vec{4, 0, 8}, blue},   // side of the ball
+		{v3.Vec{3, 5, 2}, red},    // box face around the hole
+		{v3.Vec{0, 0, -2}, green}, // inside the hole
+	}
+	for _, test := range tests {
+		k := m.At(test.p, 1e-3)
+		if k < 0 || m.Materials[k] != test.m {
+			t.Errorf("%v: expected %s, got %d %v", test.p, test.m.Name, k, m.Materials)
+		}
+	}
+
+	// untagged models have no materials
+	if k := NewMaterialMap(box).At(v3.Vec{5, 0, 0}, 1e-3); k != -1 {
+		t.Errorf("expected no material, got %d", k)
+	}
+	// tagging doesn't change the distance
+	p := v3.Vec{1, 2, 3}
+	if WithMaterial(box, red).Evaluate(p) != box.Evaluate(p) {
+		t.Error("a tagged sdf has a different distance")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_MaterialMapConcurrent(t *testing.T) {
+	red := Material{"red", color.RGBA{255, 0, 0, 255}}
+	blue := Material{"blue", color.RGBA{0, 0, 255, 255}}
+	box, _ := Box3D(v3.Vec{10, 10, 10}, 0)
+	ball, _ := Sphere3D(2)
+	// a scaled blue ball (radius 4) beside a red box
+	top := Transform3D(ScaleUniform3D(WithMaterial(ball, blue), 2), Translate3d(v3.Vec{10, 0, 0}))
+	s := Union3D(WithMaterial(box, red), top)
+
+	// classify points in several maps while the model is evaluated elsewhere
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			m := NewMaterialMap(s)
+			for j := 0; j < 100; j++ {
+				if k := m.At(v3.Vec{-5, 1, 2}, 1e-3); k < 0 || m.Materials[k] != red {
+					t.Errorf("expected red, got %d %v", k, m.Materials)
+					return
+				}
+				if k := m.At(v3.Vec{14, 0, 0}, 1e-3); k < 0 || m.Materials[k] != blue {
+					t.Errorf("expected blue, got %d %v", k, m.Materials)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Evaluate(v3.Vec{float64(j), 0, 0})
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+//-----------------------------------------------------------------------------
@@ -264,8 +264,9 @@ func (node *qtNode) winding(p v2.Vec, wn int) int {
 
 // MeshSDF2 is SDF2 made from a set of line segments.
 type MeshSDF2 struct {
-	qt *qtNode // quadtree root
-	bb Box2    // bounding box
+	qt   *qtNode  // quadtree root
+	mesh []*Line2 // line segments
+	bb   Box2     // bounding box
 }
 
 // Mesh2D returns an SDF2 made from a set of line segments.
@@ -290,8 +291,9 @@ func Mesh2D(mesh []*Line2) (SDF2, error) {
 	qt := qtBuild(0, qtBox, mesh)
 
 	return &MeshSDF2{
-		qt: qt,
-		bb: bb,
+		qt:   qt,
+		mesh: mesh,
+		bb:   bb,
 	}, nil
 }
 
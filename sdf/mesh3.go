@@ -3,6 +3,10 @@
 
 3D Mesh, 3d triangles connected to create manifold objects.
 
+The distance is the distance to the closest triangle. The sign comes from
+the generalized winding number of the point (see winding.go), so meshes
+with small holes or inconsistent normals are still classified correctly.
+
 */
 //-----------------------------------------------------------------------------
 
@@ -137,15 +141,17 @@ func (a *triangleInfo) minDistance2(p v3.Vec) float64 {
 }
 
 //-----------------------------------------------------------------------------
-// Mesh3D. 3D mesh evaluation with octree speedup.
+// Mesh3D. 3D mesh evaluation with BVH speedup.
 
 // MeshSDF3 is an SDF3 made from a set of 3d triangles.
 type MeshSDF3 struct {
-	mesh []*triangleInfo // Pre-calculated triangle info
-	bb   Box3            // bounding box
+	bvh *meshBVH // triangle BVH
+	bb  Box3     // bounding box
 }
 
-// Mesh3D returns an SDF3 made from a set of triangles.
+// Mesh3D returns an SDF3 made from a set of triangles. The triangles should
+// form a closed mesh with anticlockwise (outward) faces, but small holes and
+// a few flipped faces are tolerated.
 func Mesh3D(mesh []*Triangle3) (SDF3, error) {
 	n := len(mesh)
 	if n == 0 {
@@ -159,24 +165,18 @@ func Mesh3D(mesh []*Triangle3) (SDF3, error) {
 	}
 
 	return &MeshSDF3{
-		mesh: convertTriangles(mesh),
-		bb:   bb,
+		bvh: newMeshBVH(mesh),
+		bb:  bb,
 	}, nil
 }
 
 // Evaluate returns the minimum distance for a 3d mesh.
 func (s *MeshSDF3) Evaluate(p v3.Vec) float64 {
-	// TODO: This should be sped up with an octree. For now, it uses the slow method.
-	minD2 := math.MaxFloat64
-	for _, t := range s.mesh {
-		d2 := t.minDistance2(p)
-		if d2 < minD2 {
-			minD2 = d2
-		}
+	d := math.Sqrt(s.bvh.distance2(p))
+	if s.bvh.winding(p) > 0.5 {
+		return -d
 	}
-	// TODO: This is an unsigned distance. The sign of the SDF for a
-	// closed mesh needs to be determined (e.g., via ray casting).
-	return math.Sqrt(minD2)
+	return d
 }
 
 // BoundingBox returns the bounding box of a 3d mesh.
@@ -189,6 +189,7 @@ func (s *MeshSDF3) BoundingBox() Box3 {
 
 // MeshSDF3Slow is an SDF3 made from a set of 3d triangles.
 type MeshSDF3Slow struct {
+	tri  []*Triangle3    // triangles
 	mesh []*triangleInfo // Pre-calculated triangle info
 	bb   Box3            // bounding box
 }
@@ -207,6 +208,7 @@ func Mesh3DSlow(mesh []*Triangle3) (SDF3, error) {
 	}
 
 	return &MeshSDF3Slow{
+		tri:  mesh,
 		mesh: convertTriangles(mesh),
 		bb:   bb,
 	}, nil
@@ -221,8 +223,10 @@ func (s *MeshSDF3Slow) Evaluate(p v3.Vec) float64 {
 			minD2 = d2
 		}
 	}
-	// TODO: This is an unsigned distance. The sign of the SDF for a
-	// closed mesh needs to be determined (e.g., via ray casting).
+	// exact winding number
+	if windingNumber(s.tri, p) > 0.5 {
+		return -math.Sqrt(minD2)
+	}
 	return math.Sqrt(minD2)
 }
 
@@ -9,6 +9,7 @@ Mesh 3D Testing and Benchmarking
 package sdf
 
 import (
+	"math"
 	"testing"
 
 	v3 "github.com/deadsy/sdfx/vec/v3"
@@ -77,3 +78,74 @@ func Test_Mesh3_minDistance2(t *testing.T) {
 }
 
 //-----------------------------------------------------------------------------
+
+// cubeMesh returns a cube of size s centered on the origin with n x n squares on each face.
+func cubeMesh(s float64, n int) []*Triangle3 {
+	var mesh []*Triangle3
+	h := 0.5 * s
+	step := s / float64(n)
+	// face frames: normal, u, v with u x v = normal
+	frames := [][3]v3.Vec{
+		{{X: 1}, {Y: 1}, {Z: 1}},
+		{{X: -1}, {Z: 1}, {Y: 1}},
+		{{Y: 1}, {Z: 1}, {X: 1}},
+		{{Y: -1}, {X: 1}, {Z: 1}},
+		{{Z: 1}, {X: 1}, {Y: 1}},
+		{{Z: -1}, {Y: 1}, {X: 1}},
+	}
+	for _, f := range frames {
+		nrm, u, v := f[0], f[1], f[2]
+		at := func(i, j int) v3.Vec {
+			return nrm.MulScalar(h).Add(u.MulScalar(-h + float64(i)*step)).Add(v.MulScalar(-h + float64(j)*step))
+		}
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				mesh = append(mesh,
+					&Triangle3{at(i, j), at(i+1, j), at(i+1, j+1)},
+					&Triangle3{at(i, j), at(i+1, j+1), at(i, j+1)})
+			}
+		}
+	}
+	return mesh
+}
+
+func Test_Mesh3D(t *testing.T) {
+	mesh := cubeMesh(10, 8)
+	box, _ := Box3D(v3.Vec{X: 10, Y: 10, Z: 10}, 0)
+	s, err := Mesh3D(mesh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slow, _ := Mesh3DSlow(mesh)
+
+	// a hole and some flipped faces
+	damaged := append([]*Triangle3{}, mesh[:40]...)
+	damaged = append(damaged, mesh[42:]...)
+	for i := 100; i < len(damaged); i += 37 {
+		x := damaged[i]
+		damaged[i] = &Triangle3{x[0], x[2], x[1]}
+	}
+	d, _ := Mesh3D(damaged)
+
+	b := NewBox3(v3.Vec{}, v3.Vec{X: 14, Y: 14, Z: 14})
+	for i := 0; i < 2000; i++ {
+		p := b.Random()
+		expected := box.Evaluate(p)
+		if x := s.Evaluate(p); math.Abs(x-expected) > 1e-9 {
+			t.Fatalf("%v: expected %f, got %f", p, expected, x)
+		}
+		if x := slow.Evaluate(p); math.Abs(x-expected) > 1e-9 {
+			t.Fatalf("slow %v: expected %f, got %f", p, expected, x)
+		}
+		if math.Abs(expected) > 0.5 {
+			if x := d.Evaluate(p); (x < 0) != (expected < 0) {
+				t.Fatalf("damaged %v: expected %f, got %f", p, expected, x)
+			}
+		}
+	}
+	if w := windingNumber(mesh, v3.Vec{X: 1, Y: 2, Z: 3}); math.Abs(w-1) > 1e-9 {
+		t.Errorf("inside winding number %f", w)
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,122 @@
+//-----------------------------------------------------------------------------
+/*
+
+Metaballs (blobby implicit surfaces)
+
+Each ball contributes a compactly supported kernel to a scalar field.
+The surface is where the field equals a threshold value. The field is
+converted to a pseudo-SDF by dividing by its Lipschitz constant, which
+gives a distance that is never more than the true distance.
+
+Only the balls whose support contains a point add to the gradient there,
+and these all overlap the support of any one of them. So the Lipschitz
+constant is the largest sum over a ball and the balls overlapping it,
+rather than the sum over all the balls.
+
+Kernel: k(r) = (1 - r^2)^3 for r < 1, 0 otherwise (r = distance/radius)
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// kernelLipschitz is the maximum slope of the metaball kernel (at r = 1/sqrt(5)).
+const kernelLipschitz = 1.7173002
+
+// metaKernel returns the kernel value for a squared normalized radius.
+func metaKernel(r2 float64) float64 {
+	if r2 >= 1 {
+		return 0
+	}
+	k := 1 - r2
+	return k * k * k
+}
+
+// MetaballsSDF3 is a set of blended metaballs.
+type MetaballsSDF3 struct {
+	centers   []v3.Vec  // ball centers
+	invR2     []float64 // 1 / radius^2
+	radii     []float64 // kernel support radii
+	threshold float64   // field threshold value for the surface
+	invL      float64   // 1 / Lipschitz constant of the field
+	bb        Box3
+}
+
+// Metaballs3D returns an SDF3 for a set of blended metaballs.
+// The radii are the kernel support radii. A single ball has a surface radius
+// of radius * sqrt(1 - cbrt(threshold)), and the threshold is in (0, 1).
+func Metaballs3D(centers []v3.Vec, radii []float64, threshold float64) (SDF3, error) {
+	if len(centers) == 0 {
+		return nil, ErrMsg("len(centers) == 0")
+	}
+	if len(centers) != len(radii) {
+		return nil, ErrMsg("len(centers) != len(radii)")
+	}
+	if threshold <= 0 || threshold >= 1 {
+		return nil, ErrMsg("threshold <= 0 || threshold >= 1")
+	}
+	s := MetaballsSDF3{
+		centers:   centers,
+		radii:     radii,
+		invR2:     make([]float64, len(radii)),
+		threshold: threshold,
+	}
+	for i, r := range radii {
+		if r <= 0 {
+			return nil, ErrMsg("radius <= 0")
+		}
+		s.invR2[i] = 1 / (r * r)
+		bb := NewBox3(centers[i], v3.Vec{2 * r, 2 * r, 2 * r})
+		if i == 0 {
+			s.bb = bb
+		} else {
+			s.bb = s.bb.Extend(bb)
+		}
+	}
+	// the largest gradient from a ball and the balls overlapping it
+	l := 0.0
+	for i, ci := range centers {
+		li := 0.0
+		for j, cj := range centers {
+			r := radii[i] + radii[j]
+			if ci.Sub(cj).Length2() < r*r {
+				li += kernelLipschitz / radii[j]
+			}
+		}
+		l = math.Max(l, li)
+	}
+	s.invL = 1 / l
+	return &s, nil
+}
+
+// Evaluate returns the minimum distance to a set of metaballs.
+func (s *MetaballsSDF3) Evaluate(p v3.Vec) float64 {
+	f := 0.0
+	support := math.MaxFloat64 // distance to the nearest kernel support sphere
+	for i, c := range s.centers {
+		d2 := p.Sub(c).Length2()
+		f += metaKernel(d2 * s.invR2[i])
+		support = math.Min(support, math.Sqrt(d2)-s.radii[i])
+	}
+	d := (s.threshold - f) * s.invL
+	if d > 0 {
+		// the surface is within the support spheres
+		return math.Max(d, support)
+	}
+	return d
+}
+
+// BoundingBox returns the bounding box for a set of metaballs.
+func (s *MetaballsSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,70 @@
+//-----------------------------------------------------------------------------
+/*
+
+Metaball Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Metaballs(t *testing.T) {
+	for _, k := range []float64{0, 1, 1.5, -0.5} {
+		if _, err := Metaballs3D([]v3.Vec{{}}, []float64{1}, k); err == nil {
+			t.Errorf("expected an error for threshold %g", k)
+		}
+	}
+
+	// a single ball is a sphere
+	const radius, threshold = 10.0, 0.3
+	c := v3.Vec{1, 2, 3}
+	s, err := Metaballs3D([]v3.Vec{c}, []float64{radius}, threshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sphere, _ := Sphere3D(radius * math.Sqrt(1-math.Cbrt(threshold)))
+	sphere = Transform3D(sphere, Translate3d(c))
+	if !s.BoundingBox().Contains(sphere.BoundingBox().Min) || !s.BoundingBox().Contains(sphere.BoundingBox().Max) {
+		t.Errorf("bounding box %v doesn't contain the sphere", s.BoundingBox())
+	}
+	bb := s.BoundingBox().ScaleAboutCenter(1.2)
+	for i := 0; i < 10000; i++ {
+		p := bb.Random()
+		d, ds := s.Evaluate(p), sphere.Evaluate(p)
+		if math.Abs(ds) > 1e-6 && (d < 0) != (ds < 0) {
+			t.Fatalf("%v: sign %g, expected %g", p, d, ds)
+		}
+		// the distance is never more than the true distance
+		if math.Abs(d) > math.Abs(ds)+1e-9 {
+			t.Fatalf("%v: distance %g is more than %g", p, d, ds)
+		}
+	}
+
+	// far apart balls don't change the distance near a ball
+	centers := []v3.Vec{c}
+	radii := []float64{radius}
+	for i := 1; i < 100; i++ {
+		centers = append(centers, c.Add(v3.Vec{100 * float64(i), 0, 0}))
+		radii = append(radii, radius)
+	}
+	many, err := Metaballs3D(centers, radii, threshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []v3.Vec{c, c.Add(v3.Vec{0, 5, 0}), c.Add(v3.Vec{0, 0, 9})} {
+		if !EqualFloat64(many.Evaluate(p), s.Evaluate(p), tolerance) {
+			t.Errorf("%v: expected %g, got %g", p, s.Evaluate(p), many.Evaluate(p))
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
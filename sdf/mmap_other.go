@@ -0,0 +1,29 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+//-----------------------------------------------------------------------------
+/*
+
+Memory-Mapped Files (Unsupported)
+
+Files are read on demand instead.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import "os"
+
+//-----------------------------------------------------------------------------
+
+// mmapFile returns an error, memory-mapping isn't supported.
+func mmapFile(f *os.File) ([]byte, error) {
+	return nil, ErrMsg("memory-mapping is not supported")
+}
+
+// munmapFile does nothing, memory-mapping isn't supported.
+func munmapFile(data []byte) error {
+	return nil
+}
+
+//-----------------------------------------------------------------------------
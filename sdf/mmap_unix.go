@@ -0,0 +1,38 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+//-----------------------------------------------------------------------------
+/*
+
+Memory-Mapped Files (Unix)
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"os"
+	"syscall"
+)
+
+//-----------------------------------------------------------------------------
+
+// mmapFile maps a file read-only into memory.
+func mmapFile(f *os.File) ([]byte, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+	if size <= 0 || int64(int(size)) != size {
+		return nil, ErrMsg("can't map file")
+	}
+	return syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmapFile unmaps a memory-mapped file.
+func munmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,106 @@
+//-----------------------------------------------------------------------------
+/*
+
+Morphing
+
+Interpolate between two SDF3s by blending their distance fields. The blend
+can be a constant, or it can vary with position to build transitional
+shapes, e.g. a square to round duct adapter where the blend varies with z.
+
+A constant blend of two distance bounds is a distance bound. A varying
+blend adds the difference of the fields times the gradient of the blend
+to the gradient of the field, so the field is scaled down to keep it a
+distance bound. The gradient is estimated by sampling.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// morphSamples is the number of samples used to estimate the morph field gradient.
+const morphSamples = 1000
+
+// MorphSDF3 is an interpolation between two SDF3s.
+type MorphSDF3 struct {
+	a, b SDF3
+	t    func(p v3.Vec) float64 // blend function (0 = a, 1 = b)
+	k    float64                // distance scaling
+	bb   Box3
+}
+
+// Morph3D returns an SDF3 that is the interpolation between two SDF3s.
+// t = 0 gives a, t = 1 gives b.
+func Morph3D(a, b SDF3, t float64) (SDF3, error) {
+	if a == nil || b == nil {
+		return nil, ErrMsg("nil sdf")
+	}
+	t = Clamp(t, 0, 1)
+	return &MorphSDF3{
+		a:  a,
+		b:  b,
+		t:  func(p v3.Vec) float64 { return t },
+		k:  1,
+		bb: a.BoundingBox().Extend(b.BoundingBox()),
+	}, nil
+}
+
+// MorphFunc3D returns an SDF3 that is the interpolation between two SDF3s
+// where the interpolation varies with position. t(p) = 0 gives a, t(p) = 1 gives b.
+// E.g. t(p) = p.Z/height morphs from a to b as z goes from 0 to height.
+func MorphFunc3D(a, b SDF3, t func(p v3.Vec) float64) (SDF3, error) {
+	if a == nil || b == nil {
+		return nil, ErrMsg("nil sdf")
+	}
+	if t == nil {
+		return nil, ErrMsg("t == nil")
+	}
+	s := MorphSDF3{
+		a:  a,
+		b:  b,
+		t:  func(p v3.Vec) float64 { return Clamp(t(p), 0, 1) },
+		k:  1,
+		bb: a.BoundingBox().Extend(b.BoundingBox()),
+	}
+	// estimate the maximum gradient of the morphed field
+	eps := s.bb.Size().MaxComponent() * 1e-4
+	l := 0.0
+	for _, p := range s.bb.RandomSet(morphSamples) {
+		l = math.Max(l, gradientLength(&s, p, eps))
+	}
+	// sampling can miss the steepest point, so allow some margin
+	if l *= 1.25; l > 1 {
+		s.k = 1 / l
+	}
+	return &s, nil
+}
+
+// gradientLength returns the length of the gradient of an SDF3 at a point.
+func gradientLength(s SDF3, p v3.Vec, eps float64) float64 {
+	return v3.Vec{
+		X: s.Evaluate(p.Add(v3.Vec{X: eps})) - s.Evaluate(p.Add(v3.Vec{X: -eps})),
+		Y: s.Evaluate(p.Add(v3.Vec{Y: eps})) - s.Evaluate(p.Add(v3.Vec{Y: -eps})),
+		Z: s.Evaluate(p.Add(v3.Vec{Z: eps})) - s.Evaluate(p.Add(v3.Vec{Z: -eps})),
+	}.Length() / (2 * eps)
+}
+
+// Evaluate returns the minimum distance to a morphed SDF3.
+func (s *MorphSDF3) Evaluate(p v3.Vec) float64 {
+	t := s.t(p)
+	// exactly a at t = 0 and b at t = 1
+	return (s.a.Evaluate(p)*(1-t) + s.b.Evaluate(p)*t) * s.k
+}
+
+// BoundingBox returns the bounding box of a morphed SDF3.
+func (s *MorphSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,98 @@
+//-----------------------------------------------------------------------------
+/*
+
+Morphing Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Morph3D(t *testing.T) {
+	a, _ := Box3D(v3.Vec{20, 10, 10}, 0)
+	b, _ := Sphere3D(8)
+	b = Transform3D(b, Translate3d(v3.Vec{0, 0, 4}))
+	bb := a.BoundingBox().Extend(b.BoundingBox())
+	test := bb.Enlarge(v3.Vec{4, 4, 4})
+
+	// the endpoints are the input sdfs, t is clamped to 0..1
+	for _, x := range []struct {
+		t float64
+		s SDF3
+	}{
+		{0, a}, {-1, a}, {1, b}, {2, b},
+	} {
+		s, err := Morph3D(a, b, x.t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.BoundingBox() != bb {
+			t.Errorf("t = %g: bounding box %v, expected %v", x.t, s.BoundingBox(), bb)
+		}
+		for i := 0; i < 1000; i++ {
+			p := test.Random()
+			if s.Evaluate(p) != x.s.Evaluate(p) {
+				t.Fatalf("t = %g: %v: the morph isn't an endpoint", x.t, p)
+			}
+		}
+	}
+	// half way
+	s, _ := Morph3D(a, b, 0.5)
+	for i := 0; i < 1000; i++ {
+		p := test.Random()
+		if d := s.Evaluate(p); math.Abs(d-0.5*(a.Evaluate(p)+b.Evaluate(p))) > 1e-9 {
+			t.Fatalf("%v: distance %g is not the mean", p, d)
+		}
+	}
+
+	// a box at the bottom morphing to a sphere at the top
+	const height = 10
+	blend := func(p v3.Vec) float64 { return (p.Z + 5) / height }
+	s, err := MorphFunc3D(a, b, blend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.BoundingBox() != bb {
+		t.Errorf("bounding box %v, expected %v", s.BoundingBox(), bb)
+	}
+	for i := 0; i < 10000; i++ {
+		p := test.Random()
+		d := s.Evaluate(p)
+		// the surface is that of the blended field, and matches the endpoints beyond the blend
+		x := Mix(a.Evaluate(p), b.Evaluate(p), Clamp(blend(p), 0, 1))
+		if math.Abs(x) > 1e-9 && math.Signbit(d) != math.Signbit(x) {
+			t.Fatalf("%v: distance %g, expected the sign of %g", p, d, x)
+		}
+		if d <= 0 && !bb.Contains(p) {
+			t.Fatalf("%v: inside point outside the bounding box", p)
+		}
+		// the distance changes no faster than the position (a distance bound)
+		for _, r := range []float64{0.01, 0.5, 3} {
+			q := p.Add(v3.Vec{randomRange(-1, 1), randomRange(-1, 1), randomRange(-1, 1)}.Normalize().MulScalar(r))
+			if dd := math.Abs(s.Evaluate(q) - d); dd > r*(1+1e-6) {
+				t.Fatalf("%v: distance changes by %g over %g", p, dd, r)
+			}
+		}
+	}
+
+	if _, err := Morph3D(nil, b, 0); err == nil {
+		t.Error("expected an error for a nil sdf")
+	}
+	if _, err := MorphFunc3D(a, nil, blend); err == nil {
+		t.Error("expected an error for a nil sdf")
+	}
+	if _, err := MorphFunc3D(a, b, nil); err == nil {
+		t.Error("expected an error for a nil function")
+	}
+}
+
+//-----------------------------------------------------------------------------
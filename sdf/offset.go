@@ -0,0 +1,102 @@
+//-----------------------------------------------------------------------------
+/*
+
+Corrected Offsets
+
+Offset3D subtracts a constant from the distance field. That is exact for an
+exact distance field, but many operations (smooth unions, scaling, twists,
+etc.) give distance bounds where the field gradient is not unit length. An
+offset of such a field moves the surface by offset/|gradient| rather than by
+offset, so shells and clearances come out thin or thick.
+
+Renormalize3D divides the field by its gradient length within a narrow band
+around the surface. To first order this is the distance to the surface, and
+it leaves exact fields unchanged. Outside the band the field is returned as is.
+The correction is faded out over the outer half of the band, and for gradient
+lengths approaching minGradient, so the result stays continuous.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// minGradient is the smallest gradient length that will be renormalized.
+// Smaller gradients occur on the medial axis where the field has no useful normal.
+const minGradient = 0.1
+
+// RenormalizeSDF3 is an SDF3 with a distance field corrected to unit gradient length near the surface.
+type RenormalizeSDF3 struct {
+	sdf  SDF3
+	band float64 // renormalize within this distance of the surface
+	eps  float64 // gradient sampling distance
+}
+
+// Renormalize3D returns an SDF3 with the distance field of another SDF3 divided by
+// its gradient length for points within band of the surface.
+func Renormalize3D(sdf SDF3, band float64) (SDF3, error) {
+	if sdf == nil {
+		return nil, ErrMsg("nil sdf")
+	}
+	if band <= 0 {
+		return nil, ErrMsg("band <= 0")
+	}
+	return &RenormalizeSDF3{
+		sdf:  sdf,
+		band: band,
+		eps:  sdf.BoundingBox().Size().MaxComponent() * 1e-5,
+	}, nil
+}
+
+// Evaluate returns the minimum distance to a renormalized SDF3.
+func (s *RenormalizeSDF3) Evaluate(p v3.Vec) float64 {
+	d := s.sdf.Evaluate(p)
+	if math.Abs(d) > s.band {
+		return d
+	}
+	g := gradientLength(s.sdf, p, s.eps)
+	if g <= minGradient {
+		return d
+	}
+	// fade the correction out towards the band edge and the gradient limit
+	w := smoothStep(2 - 2*math.Abs(d)/s.band)
+	w *= smoothStep(g/minGradient - 1)
+	return d / (1 + w*(g-1))
+}
+
+// smoothStep returns a smooth step from 0 at x <= 0 to 1 at x >= 1.
+func smoothStep(x float64) float64 {
+	x = Clamp(x, 0, 1)
+	return x * x * (3 - 2*x)
+}
+
+// BoundingBox returns the bounding box of a renormalized SDF3.
+func (s *RenormalizeSDF3) BoundingBox() Box3 {
+	return s.sdf.BoundingBox()
+}
+
+//-----------------------------------------------------------------------------
+
+// CorrectedOffset3D returns an SDF3 that offsets the renormalized distance function of another SDF3.
+// Use this in place of Offset3D when the SDF3 is not an exact distance field.
+func CorrectedOffset3D(sdf SDF3, offset float64) (SDF3, error) {
+	if sdf == nil {
+		return nil, ErrMsg("nil sdf")
+	}
+	// the band must cover the offset surface and the cells around it
+	band := 2*math.Abs(offset) + sdf.BoundingBox().Size().MaxComponent()*0.05
+	s, err := Renormalize3D(sdf, band)
+	if err != nil {
+		return nil, err
+	}
+	return Offset3D(s, offset), nil
+}
+
+//-----------------------------------------------------------------------------
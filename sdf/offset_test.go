@@ -0,0 +1,91 @@
+//-----------------------------------------------------------------------------
+/*
+
+Corrected Offset Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// scaledSDF3 is a non-exact distance field with a constant gradient length.
+type scaledSDF3 struct {
+	sdf SDF3
+	k   float64
+}
+
+func (s *scaledSDF3) Evaluate(p v3.Vec) float64 {
+	return s.k * s.sdf.Evaluate(p)
+}
+
+func (s *scaledSDF3) BoundingBox() Box3 {
+	return s.sdf.BoundingBox()
+}
+
+func Test_Renormalize(t *testing.T) {
+	const band = 2.0
+	sphere, _ := Sphere3D(5)
+	bb := sphere.BoundingBox().ScaleAboutCenter(2)
+
+	// exact fields are unchanged
+	s, err := Renormalize3D(sphere, band)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10000; i++ {
+		p := bb.Random()
+		if d0, d1 := s.Evaluate(p), sphere.Evaluate(p); math.Abs(d0-d1) > 1e-6 {
+			t.Fatalf("%v: distance %g, expected %g", p, d0, d1)
+		}
+	}
+
+	// scaled fields are the exact distance in the inner half of the band
+	for _, k := range []float64{0.15, 0.5, 3} {
+		s, _ = Renormalize3D(&scaledSDF3{sphere, k}, band)
+		for i := 0; i < 10000; i++ {
+			p := bb.Random()
+			d := sphere.Evaluate(p)
+			if k >= 2*minGradient && math.Abs(d*k) < 0.5*band {
+				if d0 := s.Evaluate(p); math.Abs(d0-d) > 1e-6 {
+					t.Fatalf("k %g %v: distance %g, expected %g", k, p, d0, d)
+				}
+			}
+		}
+	}
+
+	// the result is continuous across the band edge
+	for _, k := range []float64{0.15, 3} {
+		s, _ = Renormalize3D(&scaledSDF3{sphere, k}, band)
+		const h = 1e-4
+		prev := s.Evaluate(v3.Vec{X: 5})
+		for x := 5 + h; x < 5+2*band/k; x += h {
+			d := s.Evaluate(v3.Vec{X: x})
+			if math.Abs(d-prev) > 10*h {
+				t.Fatalf("k %g: step of %g at x = %g", k, d-prev, x)
+			}
+			prev = d
+		}
+	}
+
+	// the result is continuous for gradients near the limit
+	prev := 0.0
+	for k := 0.05; k < 0.3; k += 1e-4 {
+		s, _ = Renormalize3D(&scaledSDF3{sphere, k}, band)
+		d := s.Evaluate(v3.Vec{X: 6})
+		if k > 0.05 && math.Abs(d-prev) > 1e-2 {
+			t.Fatalf("step of %g at gradient %g", d-prev, k)
+		}
+		prev = d
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,165 @@
+//-----------------------------------------------------------------------------
+/*
+
+Options
+
+Settings that would otherwise be package level constants are passed per
+call in an Options struct, so concurrent programs with different needs
+don't conflict. The zero value of Options gives the default settings.
+
+	opts := &sdf.Options{Min: sdf.PolyMin(2)}
+	s := sdf.Union3DWithOptions(opts, a, b, c)
+
+Blend3D and Blend2D apply the options to an SDF built elsewhere.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/deadsy/sdfx/vec/v2i"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// defaultFacetAngle is the maximum angle subtended by a facet of an
+// automatically faceted fillet (10 degrees).
+const defaultFacetAngle = 10.0 * Pi / 180.0
+
+// Options are per call settings for building SDFs.
+type Options struct {
+	Tolerance  float64 // points closer than this are coincident (0 = 1e-9)
+	Epsilon    float64 // angles and values smaller than this are zero (0 = 1e-12)
+	FacetAngle float64 // maximum angle of an automatic fillet facet in radians (0 = 10 degrees)
+	Min        MinFunc // minimum function for unions and arrays (nil = math.Min)
+	Max        MaxFunc // maximum function for differences and intersections (nil = math.Max)
+}
+
+func (o *Options) tolerance() float64 {
+	if o == nil || o.Tolerance <= 0 {
+		return tolerance
+	}
+	return o.Tolerance
+}
+
+func (o *Options) epsilon() float64 {
+	if o == nil || o.Epsilon <= 0 {
+		return epsilon
+	}
+	return o.Epsilon
+}
+
+func (o *Options) facetAngle() float64 {
+	if o == nil || o.FacetAngle <= 0 {
+		return defaultFacetAngle
+	}
+	return o.FacetAngle
+}
+
+//-----------------------------------------------------------------------------
+
+// minSetter is an SDF with a minimum function.
+type minSetter interface {
+	SetMin(min MinFunc)
+}
+
+// maxSetter is an SDF with a maximum function.
+type maxSetter interface {
+	SetMax(max MaxFunc)
+}
+
+// blend sets the minimum and maximum functions of an SDF.
+func (o *Options) blend(s interface{}) {
+	if o == nil {
+		return
+	}
+	if x, ok := s.(minSetter); ok && o.Min != nil {
+		x.SetMin(o.Min)
+	}
+	if x, ok := s.(maxSetter); ok && o.Max != nil {
+		x.SetMax(o.Max)
+	}
+}
+
+// Blend3D sets the minimum and maximum functions of an SDF3 (e.g. a union,
+// array, difference or intersection) to those of the options and returns it.
+func (o *Options) Blend3D(s SDF3) SDF3 {
+	o.blend(s)
+	return s
+}
+
+// Blend2D sets the minimum and maximum functions of an SDF2 (e.g. a union,
+// array, difference or intersection) to those of the options and returns it.
+func (o *Options) Blend2D(s SDF2) SDF2 {
+	o.blend(s)
+	return s
+}
+
+//-----------------------------------------------------------------------------
+// Constructors
+
+// Union3DWithOptions returns the union of multiple SDF3 objects using the
+// minimum function of the options.
+func Union3DWithOptions(opts *Options, sdf ...SDF3) SDF3 {
+	return opts.Blend3D(Union3D(sdf...))
+}
+
+// Difference3DWithOptions returns the difference of two SDF3s using the
+// maximum function of the options.
+func Difference3DWithOptions(opts *Options, s0, s1 SDF3) SDF3 {
+	return opts.Blend3D(Difference3D(s0, s1))
+}
+
+// Intersect3DWithOptions returns the intersection of two SDF3s using the
+// maximum function of the options.
+func Intersect3DWithOptions(opts *Options, s0, s1 SDF3) SDF3 {
+	return opts.Blend3D(Intersect3D(s0, s1))
+}
+
+// Array3DWithOptions returns an XYZ array of a given SDF3 using the minimum
+// function of the options.
+func Array3DWithOptions(opts *Options, sdf SDF3, num v3i.Vec, step v3.Vec) SDF3 {
+	return opts.Blend3D(Array3D(sdf, num, step))
+}
+
+// RotateUnion3DWithOptions creates a union of SDF3s rotated/translated by a
+// matrix using the minimum function of the options.
+func RotateUnion3DWithOptions(opts *Options, sdf SDF3, num int, step M44) SDF3 {
+	return opts.Blend3D(RotateUnion3D(sdf, num, step))
+}
+
+// Union2DWithOptions returns the union of multiple SDF2 objects using the
+// minimum function of the options.
+func Union2DWithOptions(opts *Options, sdf ...SDF2) SDF2 {
+	return opts.Blend2D(Union2D(sdf...))
+}
+
+// Difference2DWithOptions returns the difference of two SDF2s using the
+// maximum function of the options.
+func Difference2DWithOptions(opts *Options, s0, s1 SDF2) SDF2 {
+	return opts.Blend2D(Difference2D(s0, s1))
+}
+
+// Intersect2DWithOptions returns the intersection of two SDF2s using the
+// maximum function of the options.
+func Intersect2DWithOptions(opts *Options, s0, s1 SDF2) SDF2 {
+	return opts.Blend2D(Intersect2D(s0, s1))
+}
+
+// Array2DWithOptions returns an XY array of a given SDF2 using the minimum
+// function of the options.
+func Array2DWithOptions(opts *Options, sdf SDF2, num v2i.Vec, step v2.Vec) SDF2 {
+	return opts.Blend2D(Array2D(sdf, num, step))
+}
+
+// RotateUnion2DWithOptions creates a union of SDF2s rotated/translated by a
+// matrix using the minimum function of the options.
+func RotateUnion2DWithOptions(opts *Options, sdf SDF2, num int, step M33) SDF2 {
+	return opts.Blend2D(RotateUnion2D(sdf, num, step))
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,164 @@
+//-----------------------------------------------------------------------------
+/*
+
+Options Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"sync"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/deadsy/sdfx/vec/v2i"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_OptionsBlend(t *testing.T) {
+	box, _ := Box3D(v3.Vec{10, 10, 10}, 0)
+	sphere, _ := Sphere3D(6)
+	moved := Transform3D(sphere, Translate3d(v3.Vec{5, 0, 0}))
+
+	// the zero value is the default
+	var zero Options
+	u0 := Union3D(box, moved)
+	u1 := zero.Blend3D(Union3D(box, moved))
+
+	// blended union and difference
+	opts := Options{Min: PolyMin(2), Max: PolyMax(2)}
+	u2 := opts.Blend3D(Union3D(box, moved))
+	u3 := Union3D(box, moved)
+	u3.(*UnionSDF3).SetMin(PolyMin(2))
+	d2 := opts.Blend3D(Difference3D(box, moved))
+	d3 := Difference3D(box, moved)
+	d3.(*DifferenceSDF3).SetMax(PolyMax(2))
+
+	bb := u0.BoundingBox().ScaleAboutCenter(1.2)
+	blended := false
+	for i := 0; i < 1000; i++ {
+		p := bb.Random()
+		if u0.Evaluate(p) != u1.Evaluate(p) {
+			t.Fatalf("%v: zero options changed the union", p)
+		}
+		if u2.Evaluate(p) != u3.Evaluate(p) || d2.Evaluate(p) != d3.Evaluate(p) {
+			t.Fatalf("%v: options not applied", p)
+		}
+		if u2.Evaluate(p) != u0.Evaluate(p) {
+			blended = true
+		}
+	}
+	if !blended {
+		t.Error("the union isn't blended")
+	}
+
+	// 2d, and SDFs without blending are returned as is
+	c, _ := Circle2D(5)
+	u := opts.Blend2D(Union2D(c, Transform2D(c, Translate2d(v2.Vec{4, 0}))))
+	if u.(*UnionSDF2).min == nil {
+		t.Error("no 2d minimum function")
+	}
+	if opts.Blend3D(box) != box {
+		t.Error("a box was changed")
+	}
+}
+
+func Test_OptionsConstructors(t *testing.T) {
+	box, _ := Box3D(v3.Vec{10, 10, 10}, 0)
+	sphere, _ := Sphere3D(6)
+	moved := Transform3D(sphere, Translate3d(v3.Vec{5, 0, 0}))
+	circle, _ := Circle2D(5)
+	shifted := Transform2D(circle, Translate2d(v2.Vec{4, 0}))
+	opts := &Options{Min: PolyMin(2), Max: PolyMax(2)}
+
+	tests3 := []struct {
+		s0, s1 SDF3
+	}{
+		{Union3DWithOptions(opts, box, moved), opts.Blend3D(Union3D(box, moved))},
+		{Difference3DWithOptions(opts, box, moved), opts.Blend3D(Difference3D(box, moved))},
+		{Intersect3DWithOptions(opts, box, moved), opts.Blend3D(Intersect3D(box, moved))},
+		{Array3DWithOptions(opts, box, v3i.Vec{2, 1, 1}, v3.Vec{8, 0, 0}), opts.Blend3D(Array3D(box, v3i.Vec{2, 1, 1}, v3.Vec{8, 0, 0}))},
+		{RotateUnion3DWithOptions(opts, moved, 3, RotateZ(DtoR(30))), opts.Blend3D(RotateUnion3D(moved, 3, RotateZ(DtoR(30))))},
+		// nil options are the defaults
+		{Union3DWithOptions(nil, box, moved), Union3D(box, moved)},
+	}
+	tests2 := []struct {
+		s0, s1 SDF2
+	}{
+		{Union2DWithOptions(opts, circle, shifted), opts.Blend2D(Union2D(circle, shifted))},
+		{Difference2DWithOptions(opts, circle, shifted), opts.Blend2D(Difference2D(circle, shifted))},
+		{Intersect2DWithOptions(opts, circle, shifted), opts.Blend2D(Intersect2D(circle, shifted))},
+		{Array2DWithOptions(opts, circle, v2i.Vec{2, 1}, v2.Vec{8, 0}), opts.Blend2D(Array2D(circle, v2i.Vec{2, 1}, v2.Vec{8, 0}))},
+		{RotateUnion2DWithOptions(opts, shifted, 3, Rotate2d(DtoR(30))), opts.Blend2D(RotateUnion2D(shifted, 3, Rotate2d(DtoR(30))))},
+	}
+	bb := Box3{v3.Vec{-20, -20, -20}, v3.Vec{20, 20, 20}}
+	for i := 0; i < 1000; i++ {
+		p := bb.Random()
+		for j, x := range tests3 {
+			if x.s0.Evaluate(p) != x.s1.Evaluate(p) {
+				t.Fatalf("3d test %d: %v: options not applied", j, p)
+			}
+		}
+		for j, x := range tests2 {
+			if x.s0.Evaluate(v2.Vec{p.X, p.Y}) != x.s1.Evaluate(v2.Vec{p.X, p.Y}) {
+				t.Fatalf("2d test %d: %v: options not applied", j, p)
+			}
+		}
+	}
+}
+
+func Test_OptionsPolygon(t *testing.T) {
+	square := []v2.Vec{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	// concurrent fillets with different facet angles don't interfere
+	var wg sync.WaitGroup
+	for _, x := range []struct {
+		angle float64
+		n     int
+	}{
+		{0, 4 * 10},
+		{DtoR(30), 4 * 4},
+		{DtoR(45), 4 * 3},
+	} {
+		x := x
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				v, err := FilletPolygonWithOptions(square, 1, 0, Options{FacetAngle: x.angle})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if len(v) != x.n {
+					t.Errorf("facet angle %g: expected %d vertices, got %d", x.angle, x.n, len(v))
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// coincident points
+	v, _ := FilletPolygonWithOptions([]v2.Vec{{0, 0}, {10, 0}, {10, 0.1}, {10, 10}, {0, 10}}, 1, 1, Options{Tolerance: 0.5})
+	if len(v) != 4*2 {
+		t.Errorf("expected 8 vertices, got %d", len(v))
+	}
+
+	// a polygon with options
+	p := NewPolygonWithOptions(Options{FacetAngle: DtoR(45)})
+	p.Add(0, 0)
+	p.Add(10, 0).Fillet(2)
+	p.Add(0, 10)
+	p.Close()
+	if v := p.Vertices(); len(v) != 2+4 {
+		// 135 degrees at 45 degrees per facet is 3 facets
+		t.Errorf("expected 6 vertices, got %d", len(v))
+	}
+}
+
+//-----------------------------------------------------------------------------
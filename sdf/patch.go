@@ -0,0 +1,308 @@
+//-----------------------------------------------------------------------------
+/*
+
+Bezier and NURBS Surface Patches
+
+A surface patch is defined by a grid of control points. The patch is
+converted to an SDF3 slab of a given thickness centered on the surface.
+
+The surface is tessellated into triangles and the distance is evaluated
+against the triangles. The triangles are grouped into tiles with bounding
+boxes so distant tiles can be skipped.
+
+See: "The NURBS Book", Piegl & Tiller
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"sort"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// NURBSSurface is a non-uniform rational B-spline surface.
+type NURBSSurface struct {
+	ctrl             [][]v3.Vec  // control points [u][v]
+	weights          [][]float64 // control point weights [u][v]
+	degreeU, degreeV int         // degree in u and v
+	knotsU, knotsV   []float64   // knot vectors
+}
+
+// clampedKnots returns a clamped uniform knot vector for n control points of degree p.
+func clampedKnots(n, p int) []float64 {
+	k := make([]float64, n+p+1)
+	spans := n - p
+	for i := range k {
+		switch {
+		case i <= p:
+			k[i] = 0
+		case i >= n:
+			k[i] = 1
+		default:
+			k[i] = float64(i-p) / float64(spans)
+		}
+	}
+	return k
+}
+
+// NewNURBSSurface returns a NURBS surface.
+// ctrl is the control point grid indexed as [u][v].
+// If weights is nil all weights are 1. If a knot vector is nil a clamped
+// uniform knot vector is used.
+func NewNURBSSurface(
+	ctrl [][]v3.Vec, // control points
+	weights [][]float64, // control point weights
+	degreeU, degreeV int, // degree in u and v
+	knotsU, knotsV []float64, // knot vectors
+) (*NURBSSurface, error) {
+	nu := len(ctrl)
+	if nu < 2 {
+		return nil, ErrMsg("need at least 2 rows of control points")
+	}
+	nv := len(ctrl[0])
+	if nv < 2 {
+		return nil, ErrMsg("need at least 2 columns of control points")
+	}
+	for i := range ctrl {
+		if len(ctrl[i]) != nv {
+			return nil, ErrMsg("control point grid is not rectangular")
+		}
+	}
+	if degreeU < 1 || degreeU >= nu {
+		return nil, ErrMsg("bad u degree")
+	}
+	if degreeV < 1 || degreeV >= nv {
+		return nil, ErrMsg("bad v degree")
+	}
+	if weights == nil {
+		weights = make([][]float64, nu)
+		for i := range weights {
+			weights[i] = make([]float64, nv)
+			for j := range weights[i] {
+				weights[i][j] = 1
+			}
+		}
+	}
+	if len(weights) != nu {
+		return nil, ErrMsg("weight grid does not match control point grid")
+	}
+	for i := range weights {
+		if len(weights[i]) != nv {
+			return nil, ErrMsg("weight grid does not match control point grid")
+		}
+		for _, w := range weights[i] {
+			if w <= 0 {
+				return nil, ErrMsg("weight <= 0")
+			}
+		}
+	}
+	if knotsU == nil {
+		knotsU = clampedKnots(nu, degreeU)
+	}
+	if knotsV == nil {
+		knotsV = clampedKnots(nv, degreeV)
+	}
+	if len(knotsU) != nu+degreeU+1 {
+		return nil, ErrMsg("bad u knot vector length")
+	}
+	if len(knotsV) != nv+degreeV+1 {
+		return nil, ErrMsg("bad v knot vector length")
+	}
+	if !sort.Float64sAreSorted(knotsU) || !sort.Float64sAreSorted(knotsV) {
+		return nil, ErrMsg("knot vectors must be non-decreasing")
+	}
+	return &NURBSSurface{
+		ctrl:    ctrl,
+		weights: weights,
+		degreeU: degreeU,
+		degreeV: degreeV,
+		knotsU:  knotsU,
+		knotsV:  knotsV,
+	}, nil
+}
+
+// NewBezierSurface returns a Bezier surface for a grid of control points.
+// The degree in each direction is one less than the number of control points.
+// E.g. a 4x4 grid gives a bicubic Bezier patch.
+func NewBezierSurface(ctrl [][]v3.Vec) (*NURBSSurface, error) {
+	if len(ctrl) == 0 {
+		return nil, ErrMsg("no control points")
+	}
+	return NewNURBSSurface(ctrl, nil, len(ctrl)-1, len(ctrl[0])-1, nil, nil)
+}
+
+// findSpan returns the knot span index for parameter t.
+func findSpan(n, p int, t float64, k []float64) int {
+	if t >= k[n+1] {
+		return n
+	}
+	if t <= k[p] {
+		return p
+	}
+	lo, hi := p, n+1
+	mid := (lo + hi) / 2
+	for t < k[mid] || t >= k[mid+1] {
+		if t < k[mid] {
+			hi = mid
+		} else {
+			lo = mid
+		}
+		mid = (lo + hi) / 2
+	}
+	return mid
+}
+
+// basisFuns returns the non-zero basis functions for span i at parameter t.
+func basisFuns(i, p int, t float64, k []float64) []float64 {
+	n := make([]float64, p+1)
+	left := make([]float64, p+1)
+	right := make([]float64, p+1)
+	n[0] = 1
+	for j := 1; j <= p; j++ {
+		left[j] = t - k[i+1-j]
+		right[j] = k[i+j] - t
+		saved := 0.0
+		for r := 0; r < j; r++ {
+			tmp := n[r] / (right[r+1] + left[j-r])
+			n[r] = saved + right[r+1]*tmp
+			saved = left[j-r] * tmp
+		}
+		n[j] = saved
+	}
+	return n
+}
+
+// Point returns the surface point at normalized parameters u, v in [0,1].
+func (s *NURBSSurface) Point(u, v float64) v3.Vec {
+	nu := len(s.ctrl) - 1
+	nv := len(s.ctrl[0]) - 1
+	// map the normalized parameters onto the knot domain
+	u = Mix(s.knotsU[s.degreeU], s.knotsU[nu+1], Clamp(u, 0, 1))
+	v = Mix(s.knotsV[s.degreeV], s.knotsV[nv+1], Clamp(v, 0, 1))
+	spanU := findSpan(nu, s.degreeU, u, s.knotsU)
+	spanV := findSpan(nv, s.degreeV, v, s.knotsV)
+	bu := basisFuns(spanU, s.degreeU, u, s.knotsU)
+	bv := basisFuns(spanV, s.degreeV, v, s.knotsV)
+	var sum v3.Vec
+	var wsum float64
+	for i := 0; i <= s.degreeU; i++ {
+		iu := spanU - s.degreeU + i
+		for j := 0; j <= s.degreeV; j++ {
+			iv := spanV - s.degreeV + j
+			w := bu[i] * bv[j] * s.weights[iu][iv]
+			sum = sum.Add(s.ctrl[iu][iv].MulScalar(w))
+			wsum += w
+		}
+	}
+	return sum.DivScalar(wsum)
+}
+
+//-----------------------------------------------------------------------------
+
+// patchTileSize is the number of grid cells per side of a tile.
+const patchTileSize = 4
+
+// patchTile is a group of triangles with a bounding box.
+type patchTile struct {
+	bb        Box3
+	triangles []*triangleInfo
+}
+
+// PatchSDF3 is a slab of given thickness centered on a surface patch.
+type PatchSDF3 struct {
+	tiles []patchTile
+	half  float64 // half thickness
+	bb    Box3
+}
+
+// SurfacePatch3D returns an SDF3 slab of the given thickness centered on a surface patch.
+// The surface is tessellated with facets x facets grid cells.
+func SurfacePatch3D(surface *NURBSSurface, thickness float64, facets int) (SDF3, error) {
+	if surface == nil {
+		return nil, ErrMsg("surface == nil")
+	}
+	if thickness <= 0 {
+		return nil, ErrMsg("thickness <= 0")
+	}
+	if facets < 1 {
+		return nil, ErrMsg("facets < 1")
+	}
+	// sample the surface
+	grid := make([][]v3.Vec, facets+1)
+	for i := range grid {
+		grid[i] = make([]v3.Vec, facets+1)
+		for j := range grid[i] {
+			grid[i][j] = surface.Point(float64(i)/float64(facets), float64(j)/float64(facets))
+		}
+	}
+	s := PatchSDF3{half: 0.5 * thickness}
+	// build the tiles of triangles
+	for i0 := 0; i0 < facets; i0 += patchTileSize {
+		for j0 := 0; j0 < facets; j0 += patchTileSize {
+			var t patchTile
+			t.bb = Box3{grid[i0][j0], grid[i0][j0]}
+			for i := i0; i < minInt(i0+patchTileSize, facets); i++ {
+				for j := j0; j < minInt(j0+patchTileSize, facets); j++ {
+					a, b, c, d := grid[i][j], grid[i+1][j], grid[i+1][j+1], grid[i][j+1]
+					for _, x := range []Triangle3{{a, b, c}, {a, c, d}} {
+						if x[1].Sub(x[0]).Cross(x[2].Sub(x[0])).Length() < epsilon {
+							continue
+						}
+						x := x
+						t.triangles = append(t.triangles, newTriangleInfo(&x))
+						t.bb = t.bb.Extend(x.BoundingBox())
+					}
+				}
+			}
+			if len(t.triangles) != 0 {
+				s.tiles = append(s.tiles, t)
+			}
+		}
+	}
+	if len(s.tiles) == 0 {
+		return nil, ErrMsg("degenerate surface")
+	}
+	s.bb = s.tiles[0].bb
+	for _, t := range s.tiles {
+		s.bb = s.bb.Extend(t.bb)
+	}
+	s.bb = s.bb.Enlarge(v3.Vec{thickness, thickness, thickness})
+	return &s, nil
+}
+
+// Evaluate returns the minimum distance to a surface patch slab.
+func (s *PatchSDF3) Evaluate(p v3.Vec) float64 {
+	// the closest triangle is no further away than the furthest corner of any tile
+	d2 := math.MaxFloat64
+	for i := range s.tiles {
+		far := 0.0
+		for _, v := range s.tiles[i].bb.Vertices() {
+			far = math.Max(far, v.Sub(p).Length2())
+		}
+		d2 = math.Min(d2, far)
+	}
+	for i := range s.tiles {
+		t := &s.tiles[i]
+		if t.bb.dist2(p) > d2 {
+			// this tile can't have a closer triangle
+			continue
+		}
+		for _, x := range t.triangles {
+			d2 = math.Min(d2, x.minDistance2(p))
+		}
+	}
+	return math.Sqrt(d2) - s.half
+}
+
+// BoundingBox returns the bounding box of a surface patch slab.
+func (s *PatchSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,118 @@
+//-----------------------------------------------------------------------------
+/*
+
+Surface Patch Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_SurfacePatch3D(t *testing.T) {
+	// a bicubic bezier patch with a raised center
+	ctrl := make([][]v3.Vec, 4)
+	for i := range ctrl {
+		ctrl[i] = make([]v3.Vec, 4)
+		for j := range ctrl[i] {
+			z := 0.0
+			if (i == 1 || i == 2) && (j == 1 || j == 2) {
+				z = 4
+			}
+			ctrl[i][j] = v3.Vec{float64(i) * 10, float64(j) * 10, z}
+		}
+	}
+	bezier, err := NewBezierSurface(ctrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bernstein weights at t = 0.5 are 1/8, 3/8, 3/8, 1/8
+	p := bezier.Point(0.5, 0.5)
+	if !p.Equals(v3.Vec{15, 15, 4 * (0.75 * 0.75)}, tolerance) {
+		t.Errorf("bad bezier point %v", p)
+	}
+
+	// a degree 1 nurbs surface with all weights equal is a bilinear patch
+	flat, err := NewNURBSSurface([][]v3.Vec{{{0, 0, 0}, {0, 10, 0}}, {{10, 0, 0}, {10, 10, 0}}}, nil, 1, 1, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := SurfacePatch3D(flat, 2, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		p v3.Vec
+		d float64
+	}{
+		{v3.Vec{5, 5, 0}, -1},
+		{v3.Vec{5, 5, 3}, 2},
+		{v3.Vec{2, 7, -1.5}, 0.5},
+		{v3.Vec{13, 5, 0}, 2},
+	}
+	for _, v := range tests {
+		d := s.Evaluate(v.p)
+		if !EqualFloat64(d, v.d, tolerance) {
+			t.Errorf("%v: expected %f, got %f", v.p, v.d, d)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_SurfacePatch3DCurved(t *testing.T) {
+	// a strongly curved patch, the tile box corners are far from the surface
+	ctrl := make([][]v3.Vec, 4)
+	for i := range ctrl {
+		ctrl[i] = make([]v3.Vec, 4)
+		for j := range ctrl[i] {
+			z := 0.0
+			if (i == 1 || i == 2) && (j == 1 || j == 2) {
+				z = 20
+			}
+			ctrl[i][j] = v3.Vec{float64(i) * 10, float64(j) * 10, z}
+		}
+	}
+	bezier, _ := NewBezierSurface(ctrl)
+	s, err := SurfacePatch3D(bezier, 1, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch := s.(*PatchSDF3)
+	// brute force distance over all the triangles
+	brute := func(p v3.Vec) float64 {
+		d2 := math.MaxFloat64
+		for i := range patch.tiles {
+			for _, x := range patch.tiles[i].triangles {
+				d2 = math.Min(d2, x.minDistance2(p))
+			}
+		}
+		return math.Sqrt(d2) - 0.5
+	}
+	if d0, d1 := s.Evaluate(v3.Vec{0, 0, 6.33}), brute(v3.Vec{0, 0, 6.33}); !EqualFloat64(d0, d1, tolerance) {
+		t.Errorf("expected %f, got %f", d1, d0)
+	}
+	bb := s.BoundingBox()
+	for _, x := range patch.tiles {
+		if !bb.Contains(x.bb.Min) || !bb.Contains(x.bb.Max) {
+			t.Errorf("tile %v is outside the bounding box %v", x.bb, bb)
+		}
+	}
+	bb = bb.ScaleAboutCenter(1.5)
+	for i := 0; i < 2000; i++ {
+		p := bb.Random()
+		if d0, d1 := s.Evaluate(p), brute(p); !EqualFloat64(d0, d1, tolerance) {
+			t.Fatalf("%v: expected %f, got %f", p, d1, d0)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,284 @@
+//-----------------------------------------------------------------------------
+/*
+
+Patterns
+
+Polar, linear and grid patterns of an SDF3 with optional per-instance
+scaling and rotation.
+
+A naive union of N instances evaluates all N instances for every point.
+Patterns keep the instance bounding boxes in a spatial hash grid and only
+evaluate the instances near the point, so patterns with hundreds of
+instances remain fast to evaluate.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/vec/conv"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// InstanceFunc returns the uniform scale and rotation for instance i of a pattern.
+// The scale and rotation are applied to the instance about its own origin.
+type InstanceFunc func(i int) (scale float64, rotate M44)
+
+// patternMaxCells limits the number of spatial hash cells on each axis.
+const patternMaxCells = 64
+
+// patternInstance is a single instance of a pattern.
+type patternInstance struct {
+	inverse M44     // world to instance transform
+	scale   float64 // instance scale
+	bb      Box3    // instance bounding box
+}
+
+// PatternSDF3 is a pattern of instances of an SDF3.
+type PatternSDF3 struct {
+	sdf       SDF3
+	instances []patternInstance
+	min       MinFunc   // blending function (nil for math.Min)
+	cells     [][]int32 // spatial hash of instance indices
+	ncells    v3i.Vec   // number of cells on each axis
+	cellSize  v3.Vec    // cell size
+	bb        Box3      // bounding box
+}
+
+// newPattern3D returns a pattern for a set of instance transforms.
+func newPattern3D(s SDF3, transforms []M44, fn InstanceFunc) (SDF3, error) {
+	if s == nil {
+		return nil, ErrMsg("s == nil")
+	}
+	if len(transforms) == 0 {
+		return nil, ErrMsg("no instances")
+	}
+	p := PatternSDF3{sdf: s}
+	sbb := s.BoundingBox()
+	avg := 0.0
+	for i, m := range transforms {
+		k := 1.0
+		if fn != nil {
+			var r M44
+			k, r = fn(i)
+			if k <= 0 {
+				return nil, ErrMsg("instance scale <= 0")
+			}
+			m = m.Mul(r).Mul(Scale3d(v3.Vec{k, k, k}))
+		}
+		bb := m.MulBox(sbb)
+		p.instances = append(p.instances, patternInstance{m.Inverse(), k, bb})
+		if i == 0 {
+			p.bb = bb
+		} else {
+			p.bb = p.bb.Extend(bb)
+		}
+		avg += bb.Size().MaxComponent()
+	}
+	avg /= float64(len(transforms))
+	p.buildGrid(avg)
+	return &p, nil
+}
+
+// buildGrid puts the instances into a spatial hash grid.
+func (s *PatternSDF3) buildGrid(cell float64) {
+	size := s.bb.Size()
+	cell = math.Max(cell, size.MaxComponent()/patternMaxCells)
+	s.ncells = v3i.Vec{
+		maxInt(1, int(math.Ceil(size.X/cell))),
+		maxInt(1, int(math.Ceil(size.Y/cell))),
+		maxInt(1, int(math.Ceil(size.Z/cell))),
+	}
+	s.cellSize = v3.Vec{
+		size.X / float64(s.ncells.X),
+		size.Y / float64(s.ncells.Y),
+		size.Z / float64(s.ncells.Z),
+	}
+	s.cells = make([][]int32, s.ncells.X*s.ncells.Y*s.ncells.Z)
+	for i := range s.instances {
+		lo := s.cellIndex(s.instances[i].bb.Min)
+		hi := s.cellIndex(s.instances[i].bb.Max)
+		for x := lo.X; x <= hi.X; x++ {
+			for y := lo.Y; y <= hi.Y; y++ {
+				for z := lo.Z; z <= hi.Z; z++ {
+					j := s.cellOffset(x, y, z)
+					s.cells[j] = append(s.cells[j], int32(i))
+				}
+			}
+		}
+	}
+}
+
+// cellIndex returns the (clamped) cell index containing a point.
+func (s *PatternSDF3) cellIndex(p v3.Vec) v3i.Vec {
+	f := func(x, min, size float64, n int) int {
+		i := int(math.Floor((x - min) / size))
+		return maxInt(0, minInt(i, n-1))
+	}
+	return v3i.Vec{
+		f(p.X, s.bb.Min.X, s.cellSize.X, s.ncells.X),
+		f(p.Y, s.bb.Min.Y, s.cellSize.Y, s.ncells.Y),
+		f(p.Z, s.bb.Min.Z, s.cellSize.Z, s.ncells.Z),
+	}
+}
+
+// cellOffset returns the offset of a cell in the cell array.
+func (s *PatternSDF3) cellOffset(x, y, z int) int {
+	return (x*s.ncells.Y+y)*s.ncells.Z + z
+}
+
+// evaluate returns the distance to a single instance.
+func (s *PatternSDF3) evaluate(i int, p v3.Vec) float64 {
+	x := &s.instances[i]
+	return x.scale * s.sdf.Evaluate(x.inverse.MulPosition(p))
+}
+
+// SetMin sets the minimum function to control blending.
+// A blended pattern evaluates every instance.
+func (s *PatternSDF3) SetMin(min MinFunc) {
+	s.min = min
+}
+
+// Evaluate returns the minimum distance to a pattern.
+func (s *PatternSDF3) Evaluate(p v3.Vec) float64 {
+	d := math.MaxFloat64
+	if s.min != nil {
+		for i := range s.instances {
+			d = s.min(d, s.evaluate(i, p))
+		}
+		return d
+	}
+	// search outwards in rings of cells from the cell nearest to p
+	var buf [32]int32
+	seen := buf[:0] // instances spanning several cells are only checked once
+	c := s.cellIndex(p)
+	maxRing := maxInt(s.ncells.X, maxInt(s.ncells.Y, s.ncells.Z))
+	for r := 0; r <= maxRing; r++ {
+		lo := v3i.Vec{maxInt(c.X-r, 0), maxInt(c.Y-r, 0), maxInt(c.Z-r, 0)}
+		hi := v3i.Vec{minInt(c.X+r, s.ncells.X-1), minInt(c.Y+r, s.ncells.Y-1), minInt(c.Z+r, s.ncells.Z-1)}
+		for x := lo.X; x <= hi.X; x++ {
+			for y := lo.Y; y <= hi.Y; y++ {
+				for z := lo.Z; z <= hi.Z; z++ {
+					if maxInt(absInt(x-c.X), maxInt(absInt(y-c.Y), absInt(z-c.Z))) != r {
+						// inner cells were checked by the previous rings
+						continue
+					}
+					for _, i := range s.cells[s.cellOffset(x, y, z)] {
+						if containsInt32(seen, i) {
+							continue
+						}
+						seen = append(seen, i)
+						if d == math.MaxFloat64 || s.instances[i].bb.dist2(p) <= d*d {
+							d = math.Min(d, s.evaluate(int(i), p))
+						}
+					}
+				}
+			}
+		}
+		if d <= s.ringBound(p, lo, hi) {
+			break
+		}
+	}
+	return d
+}
+
+// ringBound returns a lower bound on the distance from p to any instance
+// that is not within the block of cells lo..hi.
+func (s *PatternSDF3) ringBound(p v3.Vec, lo, hi v3i.Vec) float64 {
+	bmin := s.bb.Min.Add(conv.V3iToV3(lo).Mul(s.cellSize))
+	bmax := s.bb.Min.Add(conv.V3iToV3(hi.AddScalar(1)).Mul(s.cellSize))
+	// Instances outside the block are beyond one of the block faces
+	// that are within the grid.
+	bound := math.MaxFloat64
+	face := func(x float64, inside bool) {
+		if inside {
+			bound = math.Min(bound, math.Max(x, 0))
+		}
+	}
+	face(p.X-bmin.X, lo.X > 0)
+	face(p.Y-bmin.Y, lo.Y > 0)
+	face(p.Z-bmin.Z, lo.Z > 0)
+	face(bmax.X-p.X, hi.X < s.ncells.X-1)
+	face(bmax.Y-p.Y, hi.Y < s.ncells.Y-1)
+	face(bmax.Z-p.Z, hi.Z < s.ncells.Z-1)
+	return bound
+}
+
+// absInt returns the absolute value of an integer.
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// containsInt32 returns true if x is in the slice.
+func containsInt32(a []int32, x int32) bool {
+	for _, v := range a {
+		if v == x {
+			return true
+		}
+	}
+	return false
+}
+
+// BoundingBox returns the bounding box of a pattern.
+func (s *PatternSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
+// PatternPolar3D returns num instances of an SDF3 evenly spaced on a circle
+// of the given radius about the z-axis. Each instance is rotated to face
+// outwards, with the instance x-axis along the radius.
+// fn gives an optional per-instance scale and rotation (may be nil).
+func PatternPolar3D(s SDF3, num int, radius float64, fn InstanceFunc) (SDF3, error) {
+	if num <= 0 {
+		return nil, ErrMsg("num <= 0")
+	}
+	transforms := make([]M44, num)
+	for i := range transforms {
+		transforms[i] = RotateZ(Tau * float64(i) / float64(num)).Mul(Translate3d(v3.Vec{radius, 0, 0}))
+	}
+	return newPattern3D(s, transforms, fn)
+}
+
+// PatternLinear3D returns num instances of an SDF3 spaced by a step vector.
+// fn gives an optional per-instance scale and rotation (may be nil).
+func PatternLinear3D(s SDF3, num int, step v3.Vec, fn InstanceFunc) (SDF3, error) {
+	if num <= 0 {
+		return nil, ErrMsg("num <= 0")
+	}
+	transforms := make([]M44, num)
+	for i := range transforms {
+		transforms[i] = Translate3d(step.MulScalar(float64(i)))
+	}
+	return newPattern3D(s, transforms, fn)
+}
+
+// PatternGrid3D returns an XYZ grid of instances of an SDF3.
+// Instances are numbered with x varying fastest, then y, then z.
+// fn gives an optional per-instance scale and rotation (may be nil).
+func PatternGrid3D(s SDF3, num v3i.Vec, step v3.Vec, fn InstanceFunc) (SDF3, error) {
+	if num.X <= 0 || num.Y <= 0 || num.Z <= 0 {
+		return nil, ErrMsg("num <= 0")
+	}
+	transforms := make([]M44, 0, num.X*num.Y*num.Z)
+	for k := 0; k < num.Z; k++ {
+		for j := 0; j < num.Y; j++ {
+			for i := 0; i < num.X; i++ {
+				transforms = append(transforms, Translate3d(v3.Vec{float64(i), float64(j), float64(k)}.Mul(step)))
+			}
+		}
+	}
+	return newPattern3D(s, transforms, fn)
+}
+
+//-----------------------------------------------------------------------------
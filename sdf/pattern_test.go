@@ -0,0 +1,111 @@
+//-----------------------------------------------------------------------------
+/*
+
+Pattern Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// patternUnion returns the naive union of the instances of a pattern.
+func patternUnion(s SDF3, transforms []M44, fn InstanceFunc) SDF3 {
+	var items []SDF3
+	for i, m := range transforms {
+		k, r := 1.0, Identity3d()
+		if fn != nil {
+			k, r = fn(i)
+		}
+		items = append(items, Transform3D(ScaleUniform3D(s, k), m.Mul(r)))
+	}
+	return Union3D(items...)
+}
+
+// comparePattern compares a pattern with a union at random points.
+func comparePattern(t *testing.T, name string, p, u SDF3) {
+	t.Helper()
+	bb := u.BoundingBox()
+	if !p.BoundingBox().Equals(bb, 1e-9) {
+		t.Errorf("%s: bounding box %v, expected %v", name, p.BoundingBox(), bb)
+	}
+	bb = bb.ScaleAboutCenter(1.5)
+	for i := 0; i < 5000; i++ {
+		x := bb.Random()
+		if d0, d1 := p.Evaluate(x), u.Evaluate(x); !EqualFloat64(d0, d1, 1e-9) {
+			t.Fatalf("%s: %v: distance %g, expected %g", name, x, d0, d1)
+		}
+	}
+}
+
+func Test_Pattern(t *testing.T) {
+	box, _ := Box3D(v3.Vec{4, 1, 2}, 0.2)
+	twist := func(i int) (float64, M44) {
+		return 1 + 0.1*float64(i%5), RotateZ(0.3 * float64(i)).Mul(RotateX(0.2 * float64(i)))
+	}
+
+	// polar
+	var transforms []M44
+	for i := 0; i < 24; i++ {
+		transforms = append(transforms, RotateZ(Tau*float64(i)/24).Mul(Translate3d(v3.Vec{20, 0, 0})))
+	}
+	p, err := PatternPolar3D(box, 24, 20, twist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePattern(t, "polar", p, patternUnion(box, transforms, twist))
+
+	// grid
+	transforms = nil
+	for k := 0; k < 3; k++ {
+		for j := 0; j < 4; j++ {
+			for i := 0; i < 5; i++ {
+				transforms = append(transforms, Translate3d(v3.Vec{6 * float64(i), 3 * float64(j), 4 * float64(k)}))
+			}
+		}
+	}
+	p, err = PatternGrid3D(box, v3i.Vec{5, 4, 3}, v3.Vec{6, 3, 4}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePattern(t, "grid", p, patternUnion(box, transforms, nil))
+
+	// instances spanning several cells: a few large instances among small ones
+	grow := func(i int) (float64, M44) {
+		if i%10 == 0 {
+			return 8, RotateY(0.5)
+		}
+		return 0.5, Identity3d()
+	}
+	transforms = nil
+	for i := 0; i < 40; i++ {
+		transforms = append(transforms, Translate3d(v3.Vec{3 * float64(i), 0, 0}))
+	}
+	p, err = PatternLinear3D(box, 40, v3.Vec{3, 0, 0}, grow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePattern(t, "spanning", p, patternUnion(box, transforms, grow))
+
+	// more than 32 instances overlapping the same cells
+	bar, _ := Box3D(v3.Vec{30, 1, 1}, 0)
+	transforms = nil
+	for i := 0; i < 100; i++ {
+		transforms = append(transforms, RotateZ(Tau*float64(i)/100).Mul(Translate3d(v3.Vec{2, 0, 0})))
+	}
+	p, err = PatternPolar3D(bar, 100, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparePattern(t, "overlapping", p, patternUnion(bar, transforms, nil))
+}
+
+//-----------------------------------------------------------------------------
@@ -24,6 +24,7 @@ type Polygon struct {
 	closed  bool            // is the polygon closed or open?
 	reverse bool            // return the vertices in reverse order
 	vlist   []PolygonVertex // list of polygon vertices
+	opts    *Options        // tolerances (nil = defaults)
 }
 
 // PolygonVertex is a polygon vertex.
@@ -31,17 +32,19 @@ type PolygonVertex struct {
 	relative bool    // vertex position is relative to previous vertex
 	vtype    pvType  // type of polygon vertex
 	vertex   v2.Vec  // vertex coordinates
-	facets   int     // number of polygon facets to create when smoothing
-	radius   float64 // radius of smoothing (0 == none)
+	facets   int     // number of polygon facets to create when smoothing (0 == auto)
+	radius   float64 // radius of smoothing, or chamfer size (0 == none)
 }
 
 // pvType is the type of a polygon vertex.
 type pvType int
 
 const (
-	pvNormal pvType = iota // normal vertex
-	pvSmooth               // smooth the vertex
-	pvArc                  // replace the line segment with an arc
+	pvNormal          pvType = iota // normal vertex
+	pvSmooth                        // smooth the vertex
+	pvArc                           // replace the line segment with an arc
+	pvChamfer                       // chamfer the vertex with a given face length
+	pvChamferDistance               // chamfer the vertex at a given distance along each edge
 )
 
 //-----------------------------------------------------------------------------
@@ -69,6 +72,19 @@ func (v *PolygonVertex) Smooth(radius float64, facets int) *PolygonVertex {
 	return v
 }
 
+// Fillet marks the polygon vertex for a fillet of the given radius.
+// The number of facets is set by the angle of the corner so that
+// no facet subtends more than 10 degrees of arc (or the facet angle of the
+// polygon options).
+func (v *PolygonVertex) Fillet(radius float64) *PolygonVertex {
+	if radius != 0 {
+		v.radius = radius
+		v.facets = 0
+		v.vtype = pvSmooth
+	}
+	return v
+}
+
 // Chamfer marks the polygon vertex for chamfering.
 func (v *PolygonVertex) Chamfer(size float64) *PolygonVertex {
 	// Fake it with a 1 facet smoothing.
@@ -82,6 +98,26 @@ func (v *PolygonVertex) Chamfer(size float64) *PolygonVertex {
 	return v
 }
 
+// ChamferFace marks the polygon vertex for chamfering.
+// The size is the length of the chamfer face at any corner angle.
+func (v *PolygonVertex) ChamferFace(size float64) *PolygonVertex {
+	if size != 0 {
+		v.radius = size
+		v.vtype = pvChamfer
+	}
+	return v
+}
+
+// ChamferDistance marks the polygon vertex for chamfering.
+// The chamfer cuts each edge at the given distance from the vertex.
+func (v *PolygonVertex) ChamferDistance(distance float64) *PolygonVertex {
+	if distance != 0 {
+		v.radius = distance
+		v.vtype = pvChamferDistance
+	}
+	return v
+}
+
 // Arc replaces a line segment with a circular arc.
 func (v *PolygonVertex) Arc(radius float64, facets int) *PolygonVertex {
 	if radius != 0 && facets != 0 {
@@ -189,7 +225,7 @@ func (p *Polygon) createArcs() {
 func (p *Polygon) smoothVertex(i int) bool {
 	// check the vertex
 	v := p.vlist[i]
-	if v.vtype != pvSmooth {
+	if v.vtype != pvSmooth && v.vtype != pvChamfer && v.vtype != pvChamferDistance {
 		// fixed point
 		return false
 	}
@@ -203,28 +239,51 @@ func (p *Polygon) smoothVertex(i int) bool {
 	// work out the angle
 	v0 := vp.vertex.Sub(v.vertex).Normalize()
 	v1 := vn.vertex.Sub(v.vertex).Normalize()
-	theta := math.Acos(v0.Dot(v1))
+	theta := math.Acos(Clamp(v0.Dot(v1), -1, 1))
+	if math.IsNaN(theta) || theta > Pi-p.opts.epsilon() {
+		// unable to smooth - coincident points or a straight line
+		return false
+	}
 	// distance from vertex to circle tangent
-	d1 := v.radius / math.Tan(theta/2.0)
+	var d1 float64
+	switch v.vtype {
+	case pvChamfer:
+		d1 = v.radius / (2.0 * math.Sin(theta/2.0))
+	case pvChamferDistance:
+		d1 = v.radius
+	default:
+		d1 = v.radius / math.Tan(theta/2.0)
+	}
 	if d1 > vp.vertex.Sub(v.vertex).Length() || d1 > vn.vertex.Sub(v.vertex).Length() {
 		// unable to smooth - radius is too large
 		return false
 	}
 	// tangent points
 	p0 := v.vertex.Add(v0.MulScalar(d1))
+	if v.vtype != pvSmooth {
+		// chamfer: replace the old point with the tangent points
+		p1 := v.vertex.Add(v1.MulScalar(d1))
+		points := []PolygonVertex{{vertex: p0}, {vertex: p1}}
+		p.vlist = append(p.vlist[:i], append(points, p.vlist[i+1:]...)...)
+		return true
+	}
+	facets := v.facets
+	if facets <= 0 {
+		facets = int(math.Ceil((Pi - theta) / p.opts.facetAngle()))
+	}
 	// distance from vertex to circle center
 	d2 := v.radius / math.Sin(theta/2.0)
 	// center of circle
 	vc := v0.Add(v1).Normalize()
 	c := v.vertex.Add(vc.MulScalar(d2))
 	// rotation angle
-	dtheta := Sign(v1.Cross(v0)) * (Pi - theta) / float64(v.facets)
+	dtheta := Sign(v1.Cross(v0)) * (Pi - theta) / float64(facets)
 	// rotation matrix
 	rm := Rotate(dtheta)
 	// radius vector
 	rv := p0.Sub(c)
 	// work out the new points
-	points := make([]PolygonVertex, v.facets+1)
+	points := make([]PolygonVertex, facets+1)
 	for j := range points {
 		points[j] = PolygonVertex{vertex: c.Add(rv)}
 		rv = rm.MulPosition(rv)
@@ -296,6 +355,12 @@ func NewPolygon() *Polygon {
 	return &Polygon{}
 }
 
+// NewPolygonWithOptions returns an empty polygon using the tolerance, epsilon
+// and facet angle of the options.
+func NewPolygonWithOptions(opts Options) *Polygon {
+	return &Polygon{opts: &opts}
+}
+
 // AddV2 adds a V2 vertex to a polygon.
 func (p *Polygon) AddV2(x v2.Vec) *PolygonVertex {
 	v := PolygonVertex{}
@@ -349,6 +414,51 @@ func (p *Polygon) Mesh2D() (SDF2, error) {
 
 //-----------------------------------------------------------------------------
 
+// FilletPolygon fillets the corners of a closed polygon, e.g. an outline
+// imported from a DXF file. Corners that turn by less than the angle of
+// a facet are left alone, as are corners where the fillet doesn't fit.
+// If facets <= 0 the number of facets is set by the angle of each corner.
+func FilletPolygon(vertex []v2.Vec, radius float64, facets int) ([]v2.Vec, error) {
+	return FilletPolygonWithOptions(vertex, radius, facets, Options{})
+}
+
+// FilletPolygonWithOptions fillets the corners of a closed polygon using the
+// tolerance, epsilon and facet angle of the options.
+func FilletPolygonWithOptions(vertex []v2.Vec, radius float64, facets int, opts Options) ([]v2.Vec, error) {
+	// remove coincident points
+	tolerance := opts.tolerance()
+	vlist := make([]v2.Vec, 0, len(vertex))
+	for i, v := range vertex {
+		if i > 0 && v.Equals(vlist[len(vlist)-1], tolerance) {
+			continue
+		}
+		vlist = append(vlist, v)
+	}
+	for len(vlist) > 1 && vlist[0].Equals(vlist[len(vlist)-1], tolerance) {
+		vlist = vlist[:len(vlist)-1]
+	}
+	n := len(vlist)
+	if n < 3 {
+		return nil, ErrMsg("polygon needs at least 3 distinct vertices")
+	}
+	if radius < 0 {
+		return nil, ErrMsg("radius < 0")
+	}
+	p := NewPolygonWithOptions(opts)
+	p.Close()
+	for i, v := range vlist {
+		pv := p.AddV2(v)
+		v0 := vlist[(i+n-1)%n].Sub(v).Normalize()
+		v1 := vlist[(i+1)%n].Sub(v).Normalize()
+		if Pi-math.Acos(Clamp(v0.Dot(v1), -1, 1)) >= opts.facetAngle() {
+			pv.Fillet(radius).facets = facets
+		}
+	}
+	return p.Vertices(), nil
+}
+
+//-----------------------------------------------------------------------------
+
 // Nagon return the vertices of a N sided regular polygon.
 func Nagon(n int, radius float64) v2.VecSet {
 	if n < 3 {
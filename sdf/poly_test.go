@@ -0,0 +1,99 @@
+//-----------------------------------------------------------------------------
+/*
+
+Polygon Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_PolygonChamfer(t *testing.T) {
+	// a 60 degree corner
+	corner := v2.Vec{X: 10, Y: 10 * math.Sqrt(3)}
+	tests := []struct {
+		chamfer func(v *PolygonVertex)
+		d       float64 // setback along each edge
+	}{
+		{func(v *PolygonVertex) { v.Chamfer(2) }, 2 * sqrtHalf / math.Tan(Pi/6)},
+		{func(v *PolygonVertex) { v.ChamferFace(2) }, 2},
+		{func(v *PolygonVertex) { v.ChamferDistance(3) }, 3},
+	}
+	for _, test := range tests {
+		p := NewPolygon()
+		p.Add(0, 0)
+		p.Add(20, 0)
+		test.chamfer(p.AddV2(corner))
+		p.Close()
+		v := p.Vertices()
+		if len(v) != 4 {
+			t.Fatalf("expected 4 vertices, got %d", len(v))
+		}
+		if d := v[2].Sub(corner).Length(); math.Abs(d-test.d) > 1e-9 {
+			t.Errorf("expected setback %g, got %g", test.d, d)
+		}
+		if d := v[3].Sub(corner).Length(); math.Abs(d-test.d) > 1e-9 {
+			t.Errorf("expected setback %g, got %g", test.d, d)
+		}
+	}
+}
+
+func Test_PolygonFillet(t *testing.T) {
+	p := NewPolygon()
+	p.Add(0, 0)
+	p.Add(10, 0).Fillet(2)
+	p.Add(10, 10).Smooth(2, 3)
+	p.Add(0, 10)
+	p.Close()
+	v := p.Vertices()
+	// 90 degrees at 10 degrees per facet is 9 facets
+	if len(v) != 4+9+3 {
+		t.Fatalf("expected 16 vertices, got %d", len(v))
+	}
+	c := v2.Vec{X: 8, Y: 2}
+	for i := 1; i <= 10; i++ {
+		if d := v[i].Sub(c).Length(); math.Abs(d-2) > 1e-9 {
+			t.Errorf("vertex %d is %g from the fillet center", i, d)
+		}
+	}
+}
+
+func Test_FilletPolygon(t *testing.T) {
+	// a square with a duplicate point and a straight vertex
+	square := []v2.Vec{{0, 0}, {5, 0}, {10, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}
+	v, err := FilletPolygon(square, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v) != 4*10+1 {
+		t.Fatalf("expected 41 vertices, got %d", len(v))
+	}
+	s, _ := Polygon2D(v)
+	// the corner is rounded
+	d := s.Evaluate(v2.Vec{X: 10, Y: 10})
+	if expected := math.Sqrt2 - 1; math.Abs(d-expected) > 1e-2 {
+		t.Errorf("expected %g, got %g", expected, d)
+	}
+	// the fillet only fits the first corner at (10, 10)
+	v, err = FilletPolygon(square, 6, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v) != 5-1+5 {
+		t.Errorf("expected 9 vertices, got %d", len(v))
+	}
+	if _, err := FilletPolygon(square[2:4], 1, 0); err == nil {
+		t.Error("expected an error for a degenerate polygon")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,299 @@
+//-----------------------------------------------------------------------------
+/*
+
+Robust Construction
+
+Degenerate parameters (a zero size box, a zero length extrusion, a union of
+nothing, a zero scale) make the plain constructors return errors, nil SDFs
+or SDFs that evaluate to NaN or Inf, which then corrupt a render far from
+the cause.
+
+A Robust builder has the same constructors, but degenerate parameters give
+a well-defined empty SDF and a warning. Parameters that are only out of
+range (e.g. a rounding radius larger than the box) are clamped, also with a
+warning. The empty SDFs are absorbed by the operators: they are dropped from
+unions, make intersections empty and leave differences unchanged.
+
+	var r sdf.Robust
+	s := r.Union3D(r.Box3D(size, round), r.Sphere3D(radius))
+	for _, w := range r.Warnings() {
+		log.Print(w)
+	}
+
+A Robust builder is safe for concurrent use.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// emptyDistance is the distance to an empty SDF. It is finite, so the
+// operators and blending functions stay well-defined.
+const emptyDistance = math.MaxFloat32
+
+// EmptySDF3 is an SDF3 with no interior.
+type EmptySDF3 struct{}
+
+// Empty3D returns an empty SDF3.
+func Empty3D() SDF3 {
+	return &EmptySDF3{}
+}
+
+// Evaluate returns the distance to an empty SDF3.
+func (s *EmptySDF3) Evaluate(p v3.Vec) float64 {
+	return emptyDistance
+}
+
+// BoundingBox returns the bounding box of an empty SDF3, a point at the origin.
+func (s *EmptySDF3) BoundingBox() Box3 {
+	return Box3{}
+}
+
+// EmptySDF2 is an SDF2 with no interior.
+type EmptySDF2 struct{}
+
+// Empty2D returns an empty SDF2.
+func Empty2D() SDF2 {
+	return &EmptySDF2{}
+}
+
+// Evaluate returns the distance to an empty SDF2.
+func (s *EmptySDF2) Evaluate(p v2.Vec) float64 {
+	return emptyDistance
+}
+
+// BoundingBox returns the bounding box of an empty SDF2, a point at the origin.
+func (s *EmptySDF2) BoundingBox() Box2 {
+	return Box2{}
+}
+
+// IsEmpty3 returns true if an SDF3 is nil or empty.
+func IsEmpty3(s SDF3) bool {
+	if s == nil {
+		return true
+	}
+	_, ok := s.(*EmptySDF3)
+	return ok
+}
+
+// IsEmpty2 returns true if an SDF2 is nil or empty.
+func IsEmpty2(s SDF2) bool {
+	if s == nil {
+		return true
+	}
+	_, ok := s.(*EmptySDF2)
+	return ok
+}
+
+//-----------------------------------------------------------------------------
+
+// Robust builds SDFs that degrade to empty SDFs on degenerate parameters.
+// The zero value is ready to use.
+type Robust struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+// warn records a warning.
+func (r *Robust) warn(format string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.warnings = append(r.warnings, fmt.Sprintf(format, args...))
+}
+
+// Warnings returns the warnings recorded so far.
+func (r *Robust) Warnings() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.warnings...)
+}
+
+// positive returns true if a value is positive and finite.
+func positive(x float64) bool {
+	return x > 0 && !math.IsInf(x, 1)
+}
+
+// clampRound returns a rounding radius clamped to [0, max].
+func (r *Robust) clampRound(name string, round, max float64) float64 {
+	if !(round >= 0) {
+		r.warn("%s: round %g < 0, using 0", name, round)
+		return 0
+	}
+	if round > max {
+		r.warn("%s: round %g > %g, using %g", name, round, max, max)
+		return max
+	}
+	return round
+}
+
+//-----------------------------------------------------------------------------
+// 3D primitives
+
+// Box3D returns a 3d box, or an empty SDF3 if a side is not positive.
+func (r *Robust) Box3D(size v3.Vec, round float64) SDF3 {
+	if !positive(size.X) || !positive(size.Y) || !positive(size.Z) {
+		r.warn("Box3D: degenerate size %v, empty", size)
+		return Empty3D()
+	}
+	round = r.clampRound("Box3D", round, math.Min(size.X, math.Min(size.Y, size.Z))/2)
+	s, _ := Box3D(size, round)
+	return s
+}
+
+// Sphere3D returns a sphere, or an empty SDF3 if the radius is not positive.
+func (r *Robust) Sphere3D(radius float64) SDF3 {
+	if !positive(radius) {
+		r.warn("Sphere3D: degenerate radius %g, empty", radius)
+		return Empty3D()
+	}
+	s, _ := Sphere3D(radius)
+	return s
+}
+
+// Cylinder3D returns a cylinder, or an empty SDF3 if the height or radius is not positive.
+func (r *Robust) Cylinder3D(height, radius, round float64) SDF3 {
+	if !positive(height) || !positive(radius) {
+		r.warn("Cylinder3D: degenerate height %g or radius %g, empty", height, radius)
+		return Empty3D()
+	}
+	round = r.clampRound("Cylinder3D", round, math.Min(radius, height/2))
+	s, _ := Cylinder3D(height, radius, round)
+	return s
+}
+
+// Extrude3D returns a linear extrusion, or an empty SDF3 for an empty SDF2 or a height that is not positive.
+func (r *Robust) Extrude3D(s SDF2, height float64) SDF3 {
+	if IsEmpty2(s) {
+		r.warn("Extrude3D: empty profile, empty")
+		return Empty3D()
+	}
+	if !positive(height) {
+		r.warn("Extrude3D: degenerate height %g, empty", height)
+		return Empty3D()
+	}
+	return Extrude3D(s, height)
+}
+
+//-----------------------------------------------------------------------------
+// 3D operators
+
+// Union3D returns the union of the non-empty SDF3s, or an empty SDF3 if there are none.
+func (r *Robust) Union3D(sdf ...SDF3) SDF3 {
+	var s []SDF3
+	for _, x := range sdf {
+		if !IsEmpty3(x) {
+			s = append(s, x)
+		}
+	}
+	if len(s) == 0 {
+		r.warn("Union3D: nothing to union, empty")
+		return Empty3D()
+	}
+	return Union3D(s...)
+}
+
+// Difference3D returns s0 - s1. It is empty if s0 is empty, and s0 if s1 is empty.
+func (r *Robust) Difference3D(s0, s1 SDF3) SDF3 {
+	if IsEmpty3(s0) {
+		return Empty3D()
+	}
+	if IsEmpty3(s1) {
+		return s0
+	}
+	return Difference3D(s0, s1)
+}
+
+// Intersect3D returns the intersection of two SDF3s. It is empty if either is empty.
+func (r *Robust) Intersect3D(s0, s1 SDF3) SDF3 {
+	if IsEmpty3(s0) || IsEmpty3(s1) {
+		r.warn("Intersect3D: empty operand, empty")
+		return Empty3D()
+	}
+	return Intersect3D(s0, s1)
+}
+
+// Transform3D returns a transformed SDF3, or an empty SDF3 for a singular matrix.
+func (r *Robust) Transform3D(s SDF3, matrix M44) SDF3 {
+	if IsEmpty3(s) {
+		return Empty3D()
+	}
+	if d := matrix.Determinant(); d == 0 || math.IsNaN(d) || math.IsInf(d, 0) {
+		r.warn("Transform3D: singular matrix, empty")
+		return Empty3D()
+	}
+	return Transform3D(s, matrix)
+}
+
+// ScaleUniform3D returns a uniformly scaled SDF3, or an empty SDF3 if the scale is not positive.
+func (r *Robust) ScaleUniform3D(s SDF3, k float64) SDF3 {
+	if IsEmpty3(s) {
+		return Empty3D()
+	}
+	if !positive(k) {
+		r.warn("ScaleUniform3D: degenerate scale %g, empty", k)
+		return Empty3D()
+	}
+	return ScaleUniform3D(s, k)
+}
+
+//-----------------------------------------------------------------------------
+// 2D primitives and operators
+
+// Box2D returns a 2d box, or an empty SDF2 if a side is not positive.
+func (r *Robust) Box2D(size v2.Vec, round float64) SDF2 {
+	if !positive(size.X) || !positive(size.Y) {
+		r.warn("Box2D: degenerate size %v, empty", size)
+		return Empty2D()
+	}
+	round = r.clampRound("Box2D", round, math.Min(size.X, size.Y)/2)
+	return Box2D(size, round)
+}
+
+// Circle2D returns a circle, or an empty SDF2 if the radius is not positive.
+func (r *Robust) Circle2D(radius float64) SDF2 {
+	if !positive(radius) {
+		r.warn("Circle2D: degenerate radius %g, empty", radius)
+		return Empty2D()
+	}
+	s, _ := Circle2D(radius)
+	return s
+}
+
+// Union2D returns the union of the non-empty SDF2s, or an empty SDF2 if there are none.
+func (r *Robust) Union2D(sdf ...SDF2) SDF2 {
+	var s []SDF2
+	for _, x := range sdf {
+		if !IsEmpty2(x) {
+			s = append(s, x)
+		}
+	}
+	if len(s) == 0 {
+		r.warn("Union2D: nothing to union, empty")
+		return Empty2D()
+	}
+	return Union2D(s...)
+}
+
+// Difference2D returns s0 - s1. It is empty if s0 is empty, and s0 if s1 is empty.
+func (r *Robust) Difference2D(s0, s1 SDF2) SDF2 {
+	if IsEmpty2(s0) {
+		return Empty2D()
+	}
+	if IsEmpty2(s1) {
+		return s0
+	}
+	return Difference2D(s0, s1)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,103 @@
+//-----------------------------------------------------------------------------
+/*
+
+Robust Construction Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// robustModel builds a model from fuzzed parameters.
+func robustModel(r *Robust, size, round, radius, height, scale float64) SDF3 {
+	box := r.Box3D(v3.Vec{size, size / 2, size * 2}, round)
+	sphere := r.Sphere3D(radius)
+	hole := r.Cylinder3D(height, radius/2, round)
+	profile := r.Union2D(r.Box2D(v2.Vec{size, radius}, round), r.Circle2D(radius))
+	plate := r.Extrude3D(r.Difference2D(profile, r.Circle2D(radius/4)), height)
+	s := r.Union3D(r.Difference3D(box, hole), r.Intersect3D(sphere, plate))
+	s = r.Transform3D(s, Scale3d(v3.Vec{scale, 1, 1}))
+	return r.ScaleUniform3D(s, scale)
+}
+
+// checkModel returns an error message if a model has non-finite values.
+func checkModel(s SDF3) string {
+	bb := s.BoundingBox()
+	for _, v := range []float64{bb.Min.X, bb.Min.Y, bb.Min.Z, bb.Max.X, bb.Max.Y, bb.Max.Z} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "non-finite bounding box"
+		}
+	}
+	for _, p := range []v3.Vec{{0, 0, 0}, {1, 2, 3}, {-5, 0.5, 7}, bb.Min, bb.Max, bb.Center()} {
+		if d := s.Evaluate(p); math.IsNaN(d) || math.IsInf(d, 0) {
+			return "non-finite distance"
+		}
+	}
+	return ""
+}
+
+func Test_Robust(t *testing.T) {
+	var r Robust
+	s := robustModel(&r, 10, 1, 4, 6, 2)
+	if msg := checkModel(s); msg != "" {
+		t.Fatal(msg)
+	}
+	if len(r.Warnings()) != 0 {
+		t.Errorf("unexpected warnings %v", r.Warnings())
+	}
+	if s.Evaluate(v3.Vec{14, 0, 0}) >= 0 {
+		t.Error("expected (14, 0, 0) to be inside")
+	}
+
+	// degenerate parameters give empty SDFs
+	r = Robust{}
+	s = robustModel(&r, 0, -1, 0, 0, 1)
+	if !IsEmpty3(s) || checkModel(s) != "" {
+		t.Errorf("expected an empty SDF3, got %T", s)
+	}
+	if len(r.Warnings()) == 0 {
+		t.Error("expected warnings")
+	}
+	if s.Evaluate(v3.Vec{}) <= 0 {
+		t.Error("an empty SDF3 has no inside")
+	}
+
+	// an empty operand doesn't change a difference
+	r = Robust{}
+	box := r.Box3D(v3.Vec{1, 1, 1}, 0)
+	if s := r.Difference3D(box, r.Sphere3D(-1)); s != box {
+		t.Error("expected the box")
+	}
+	// out of range rounding is clamped
+	if s := r.Box3D(v3.Vec{1, 2, 3}, 5); IsEmpty3(s) || len(r.Warnings()) != 2 {
+		t.Errorf("expected a clamped box, warnings %v", r.Warnings())
+	}
+	if !IsEmpty3(r.Transform3D(box, Scale3d(v3.Vec{1, 0, 1}))) {
+		t.Error("expected an empty SDF3 for a singular transform")
+	}
+}
+
+func Fuzz_Robust(f *testing.F) {
+	f.Add(10.0, 1.0, 4.0, 6.0, 2.0)
+	f.Add(0.0, 0.0, 0.0, 0.0, 0.0)
+	f.Add(-1.0, 100.0, 1e-300, 1e300, -2.0)
+	f.Add(math.Inf(1), math.NaN(), 1.0, math.Inf(-1), math.NaN())
+	f.Fuzz(func(t *testing.T, size, round, radius, height, scale float64) {
+		var r Robust
+		if msg := checkModel(robustModel(&r, size, round, radius, height, scale)); msg != "" {
+			t.Errorf("%s for (%g, %g, %g, %g, %g), warnings %v", msg, size, round, radius, height, scale, r.Warnings())
+		}
+	})
+}
+
+//-----------------------------------------------------------------------------
@@ -245,6 +245,7 @@ func (s *CutSDF2) BoundingBox() Box2 {
 // TransformSDF2 transorms an SDF2 with rotation, translation and scaling.
 type TransformSDF2 struct {
 	sdf  SDF2
+	m    M33
 	mInv M33
 	bb   Box2
 }
@@ -254,6 +255,7 @@ type TransformSDF2 struct {
 func Transform2D(sdf SDF2, m M33) SDF2 {
 	s := TransformSDF2{}
 	s.sdf = sdf
+	s.m = m
 	s.mInv = m.Inverse()
 	s.bb = m.MulBox(sdf.BoundingBox())
 	return &s
@@ -542,6 +544,7 @@ type UnionSDF2 struct {
 	sdf []SDF2
 	min MinFunc
 	bb  Box2
+	bvh *bvh2 // large unions with the default min function
 }
 
 // Union2D returns the union of multiple SDF2 objects.
@@ -571,14 +574,25 @@ func Union2D(sdf ...SDF2) SDF2 {
 	}
 	s.bb = bb
 	s.min = math.Min
+	if len(s.sdf) >= unionBVHMin {
+		s.bvh = newBVH2(s.sdf)
+	}
 	return &s
 }
 
 // Evaluate returns the minimum distance to the SDF2 union.
 func (s *UnionSDF2) Evaluate(p v2.Vec) float64 {
+	if s.bvh != nil {
+		return s.bvh.evaluate(p)
+	}
 
 	// work out the min/max distance for every bounding box
-	vs := make([]Interval, len(s.sdf))
+	// (on the stack for the unions without a BVH, blended unions may be larger)
+	var buf [unionBVHMin]Interval
+	vs := buf[:]
+	if len(s.sdf) > len(buf) {
+		vs = make([]Interval, len(s.sdf))
+	}
 	minDist2 := -1.0
 	minIndex := 0
 	for i := range s.sdf {
@@ -623,8 +637,10 @@ func (s *UnionSDF2) EvaluateSlow(p v2.Vec) float64 {
 }
 
 // SetMin sets the minimum function to control SDF2 blending.
+// The union is evaluated without a BVH.
 func (s *UnionSDF2) SetMin(min MinFunc) {
 	s.min = min
+	s.bvh = nil
 }
 
 // BoundingBox returns the bounding box of an SDF2 union.
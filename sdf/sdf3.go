@@ -203,6 +203,49 @@ func ScaleTwistExtrude3D(sdf SDF2, height, twist float64, scale v2.Vec) SDF3 {
 	return &s
 }
 
+// profileSamples is the number of samples used to check a profile function.
+const profileSamples = 64
+
+// ProfileExtrude3D extrudes an SDF2 and scales and twists it with z according to a profile function.
+// The profile is called with z in [-height/2, height/2]. E.g. a variable pitch auger is a profile
+// with a non-linear angle, a bottle neck or flared horn is a profile with a non-linear scale.
+// maxScale is the largest scale the profile returns at any z. The bounding box is the radius of
+// the shape times maxScale, so it holds for any twist.
+func ProfileExtrude3D(sdf SDF2, height float64, profile ExtrudeProfile, maxScale float64) (SDF3, error) {
+	if height <= 0 {
+		return nil, ErrMsg("height <= 0")
+	}
+	if profile == nil {
+		return nil, ErrMsg("profile == nil")
+	}
+	if maxScale <= 0 {
+		return nil, ErrMsg("maxScale <= 0")
+	}
+	s := ExtrudeSDF3{}
+	s.sdf = sdf
+	s.height = height / 2
+	s.extrude = ProfileExtrude(profile)
+	// check the profile
+	for i := 0; i <= profileSamples; i++ {
+		z := -s.height + height*float64(i)/profileSamples
+		_, scale := profile(z)
+		if scale.X <= 0 || scale.Y <= 0 {
+			return nil, ErrMsg("profile scale <= 0")
+		}
+		if scale.MaxComponent() > maxScale {
+			return nil, ErrMsg("profile scale > maxScale")
+		}
+	}
+	// work out the bounding box
+	l := 0.0
+	for _, v := range sdf.BoundingBox().Vertices() {
+		l = math.Max(l, v.Length())
+	}
+	l *= maxScale
+	s.bb = Box3{v3.Vec{-l, -l, -s.height}, v3.Vec{l, l, s.height}}
+	return &s, nil
+}
+
 // Evaluate returns the minimum distance to an extrusion.
 func (s *ExtrudeSDF3) Evaluate(p v3.Vec) float64 {
 	// sdf for the projected 2d surface
@@ -649,6 +692,7 @@ type UnionSDF3 struct {
 	sdf []SDF3
 	min MinFunc
 	bb  Box3
+	bvh *bvh3 // large unions with the default min function
 }
 
 // Union3D returns the union of multiple SDF3 objects.
@@ -678,11 +722,17 @@ func Union3D(sdf ...SDF3) SDF3 {
 	}
 	s.bb = bb
 	s.min = math.Min
+	if len(s.sdf) >= unionBVHMin {
+		s.bvh = newBVH3(s.sdf)
+	}
 	return &s
 }
 
 // Evaluate returns the minimum distance to an SDF3 union.
 func (s *UnionSDF3) Evaluate(p v3.Vec) float64 {
+	if s.bvh != nil {
+		return s.bvh.evaluate(p)
+	}
 	var d float64
 	for i, x := range s.sdf {
 		if i == 0 {
@@ -695,8 +745,10 @@ func (s *UnionSDF3) Evaluate(p v3.Vec) float64 {
 }
 
 // SetMin sets the minimum function to control blending.
+// The union is evaluated without a BVH.
 func (s *UnionSDF3) SetMin(min MinFunc) {
 	s.min = min
+	s.bvh = nil
 }
 
 // BoundingBox returns the bounding box of an SDF3 union.
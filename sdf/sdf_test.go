@@ -816,3 +816,57 @@ func Test_Normal(t *testing.T) {
 }
 
 //-----------------------------------------------------------------------------
+
+func Test_ProfileExtrude3D(t *testing.T) {
+	square := Box2D(v2.Vec{4, 2}, 0)
+	// a narrow bulge between the profile samples, with a twist
+	const height = 10
+	bulge := func(z float64) (float64, v2.Vec) {
+		k := 1 + 2*math.Exp(-math.Pow((z-0.07)/0.05, 2))
+		return z * 0.3, v2.Vec{k, k}
+	}
+	s, err := ProfileExtrude3D(square, height, bulge, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bb := s.BoundingBox()
+	// the widest part of the shape is inside the bounding box
+	angle, scale := bulge(0.07)
+	m := Rotate(-angle)
+	for _, v := range square.BoundingBox().Vertices() {
+		p := m.MulPosition(v).Mul(scale)
+		q := v3.Vec{p.X, p.Y, 0.07}
+		if d := s.Evaluate(q); math.Abs(d) > 1e-6 {
+			t.Fatalf("%v: expected a corner of the shape, got distance %g", q, d)
+		}
+		if !bb.Contains(q) {
+			t.Fatalf("%v: outside the bounding box %v", q, bb)
+		}
+	}
+	test := bb.Enlarge(v3.Vec{2, 2, 2})
+	for i := 0; i < 10000; i++ {
+		if p := test.Random(); s.Evaluate(p) <= 0 && !bb.Contains(p) {
+			t.Fatalf("%v: inside point outside the bounding box", p)
+		}
+	}
+
+	wide := func(z float64) (float64, v2.Vec) { return 0, v2.Vec{1, 2.5} }
+	if _, err := ProfileExtrude3D(square, height, wide, 2); err == nil {
+		t.Error("expected an error for a profile scale over the maximum")
+	}
+	if _, err := ProfileExtrude3D(square, height, bulge, 0); err == nil {
+		t.Error("expected an error for maxScale <= 0")
+	}
+	if _, err := ProfileExtrude3D(square, 0, bulge, 3); err == nil {
+		t.Error("expected an error for height <= 0")
+	}
+	if _, err := ProfileExtrude3D(square, height, nil, 3); err == nil {
+		t.Error("expected an error for a nil profile")
+	}
+	negative := func(z float64) (float64, v2.Vec) { return 0, v2.Vec{1, -1} }
+	if _, err := ProfileExtrude3D(square, height, negative, 3); err == nil {
+		t.Error("expected an error for a negative profile scale")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -48,20 +48,16 @@ func (s *arcSpiral) radius(theta float64) float64 {
 	return r
 }
 
-// theta returns the theta(s) for a given radius.
-func (s *arcSpiral) theta(radius float64) ([]float64, error) {
+// theta returns the theta for a given radius.
+// There is no single solution for a spiral of constant radius.
+func (s *arcSpiral) theta(radius float64) (float64, bool) {
 	if s.a == 0 {
-		if s.k == radius {
-			// infinite solutions
-			return nil, errors.New("inf")
-		}
-		// no solutions
-		return nil, nil
+		return 0, false
 	}
 	if s.n == 1.0 {
-		return []float64{(radius - s.k) / s.a}, nil
+		return (radius - s.k) / s.a, true
 	}
-	return []float64{math.Exp(s.n * math.Log((radius-s.k)/s.a))}, nil
+	return math.Exp(s.n * math.Log((radius-s.k)/s.a)), true
 }
 
 //-----------------------------------------------------------------------------
@@ -114,35 +110,32 @@ func (s *ArcSpiralSDF2) Evaluate(p v2.Vec) float64 {
 	// end points
 	d2 := math.Min(polarDist2(pp, s.start), polarDist2(pp, s.end))
 
-	thetas, err := s.spiral.theta(pp.R)
-	if err == nil {
-		for _, theta := range thetas {
-			n := math.Round((pp.Theta - theta) / Tau)
-			theta = pp.Theta - (Tau * n)
-
-			if theta >= s.start.Theta && theta <= s.end.Theta {
-				d2 = math.Min(d2, polarDist2(pp, p2.Vec{s.spiral.radius(theta), theta}))
-			} else {
-
-				if theta < s.start.Theta {
-					for theta < s.start.Theta {
-						theta += Tau
-					}
-					if theta < s.end.Theta {
-						d2 = math.Min(d2, polarDist2(pp, p2.Vec{s.spiral.radius(theta), theta}))
-					}
-				}
+	if theta, ok := s.spiral.theta(pp.R); ok {
+		n := math.Round((pp.Theta - theta) / Tau)
+		theta = pp.Theta - (Tau * n)
 
-				if theta > s.end.Theta {
-					for theta > s.end.Theta {
-						theta -= Tau
-					}
-					if theta > s.start.Theta {
-						d2 = math.Min(d2, polarDist2(pp, p2.Vec{s.spiral.radius(theta), theta}))
-					}
+		if theta >= s.start.Theta && theta <= s.end.Theta {
+			d2 = math.Min(d2, polarDist2(pp, p2.Vec{s.spiral.radius(theta), theta}))
+		} else {
+
+			if theta < s.start.Theta {
+				for theta < s.start.Theta {
+					theta += Tau
+				}
+				if theta < s.end.Theta {
+					d2 = math.Min(d2, polarDist2(pp, p2.Vec{s.spiral.radius(theta), theta}))
 				}
+			}
 
+			if theta > s.end.Theta {
+				for theta > s.end.Theta {
+					theta -= Tau
+				}
+				if theta > s.start.Theta {
+					d2 = math.Min(d2, polarDist2(pp, p2.Vec{s.spiral.radius(theta), theta}))
+				}
 			}
+
 		}
 	}
 
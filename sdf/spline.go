@@ -17,7 +17,6 @@ package sdf
 
 import (
 	"errors"
-	"fmt"
 	"math"
 
 	v2 "github.com/deadsy/sdfx/vec/v2"
@@ -297,7 +296,7 @@ func (s *CubicSplineSDF2) Evaluate(p v2.Vec) float64 {
 
 		tOld := t
 		t = cs.nrIterate(t, p)
-		fmt.Printf("%d tOld %f t %f\n", cs.idx, tOld, t)
+		//fmt.Printf("%d tOld %f t %f\n", cs.idx, tOld, t)
 
 		if t < 0 {
 			// previous spline
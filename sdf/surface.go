@@ -0,0 +1,92 @@
+//-----------------------------------------------------------------------------
+/*
+
+Surface Sampling
+
+Find points on the surface of an SDF. The bounding box is sampled on a grid,
+and the grid points close enough to the surface that it may pass through
+their cell are moved onto the surface along the gradient. The points have a
+spacing of about the grid cell size.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// surfaceIterations is the number of steps taken to move a point onto the surface.
+const surfaceIterations = 4
+
+// surfaceSamples2 returns points on the boundary of an SDF2 with a spacing of about h.
+func surfaceSamples2(s SDF2, h float64) []v2.Vec {
+	bb := s.BoundingBox()
+	n := bb.Size().DivScalar(h).Ceil()
+	r := h * math.Sqrt2 * 0.5
+	eps := 1e-3 * h
+	var points []v2.Vec
+	for j := 0; j < int(n.Y)+1; j++ {
+		for i := 0; i < int(n.X)+1; i++ {
+			p := bb.Min.Add(v2.Vec{X: float64(i), Y: float64(j)}.MulScalar(h))
+			d := s.Evaluate(p)
+			if math.Abs(d) > r {
+				continue
+			}
+			// move the point onto the boundary
+			var nv v2.Vec
+			for k := 0; k < surfaceIterations; k++ {
+				nv = Normal2(s, p, eps)
+				p = p.Sub(nv.MulScalar(d))
+				d = s.Evaluate(p)
+			}
+			if math.Abs(d) > 10*eps || math.IsNaN(nv.X) {
+				continue
+			}
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+// surfaceSamples3 returns points on the surface of an SDF3 with a spacing of
+// about h, and the surface normals at the points.
+func surfaceSamples3(s SDF3, h float64) (v3.VecSet, v3.VecSet) {
+	bb := s.BoundingBox()
+	n := bb.Size().DivScalar(h).Ceil()
+	r := h * math.Sqrt(3) * 0.5
+	eps := 1e-3 * h
+	var points, normals v3.VecSet
+	for k := 0; k < int(n.Z)+1; k++ {
+		for j := 0; j < int(n.Y)+1; j++ {
+			for i := 0; i < int(n.X)+1; i++ {
+				p := bb.Min.Add(v3.Vec{X: float64(i), Y: float64(j), Z: float64(k)}.MulScalar(h))
+				d := s.Evaluate(p)
+				if math.Abs(d) > r {
+					continue
+				}
+				// move the point onto the surface
+				var nv v3.Vec
+				for m := 0; m < surfaceIterations; m++ {
+					nv = Normal3(s, p, eps)
+					p = p.Sub(nv.MulScalar(d))
+					d = s.Evaluate(p)
+				}
+				if math.Abs(d) > 10*eps || math.IsNaN(nv.X) {
+					continue
+				}
+				points = append(points, p)
+				normals = append(normals, nv)
+			}
+		}
+	}
+	return points, normals
+}
+
+//-----------------------------------------------------------------------------
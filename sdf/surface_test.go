@@ -0,0 +1,71 @@
+//-----------------------------------------------------------------------------
+/*
+
+Surface Sampling Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_SurfaceSamples(t *testing.T) {
+	const h = 0.5
+	sphere, _ := Sphere3D(5)
+	points, normals := surfaceSamples3(sphere, h)
+	if len(points) == 0 || len(points) != len(normals) {
+		t.Fatalf("%d points, %d normals", len(points), len(normals))
+	}
+	for i, p := range points {
+		if d := sphere.Evaluate(p); math.Abs(d) > 1e-2*h {
+			t.Fatalf("%v: %g from the surface", p, d)
+		}
+		if !normals[i].Equals(p.Normalize(), 1e-3) {
+			t.Fatalf("%v: normal %v", p, normals[i])
+		}
+	}
+	// the samples cover the surface
+	for i := 0; i < 100; i++ {
+		q := v3.Vec{randomRange(-1, 1), randomRange(-1, 1), randomRange(-1, 1)}.Normalize().MulScalar(5)
+		dmin := math.Inf(1)
+		for _, p := range points {
+			dmin = math.Min(dmin, p.Sub(q).Length())
+		}
+		if dmin > h {
+			t.Fatalf("%v: nearest sample is %g away", q, dmin)
+		}
+	}
+
+	circle, _ := Circle2D(5)
+	points2 := surfaceSamples2(circle, h)
+	if len(points2) == 0 {
+		t.Fatal("no 2d points")
+	}
+	for _, p := range points2 {
+		if d := circle.Evaluate(p); math.Abs(d) > 1e-2*h {
+			t.Fatalf("%v: %g from the boundary", p, d)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		a := randomRange(0, 2*Pi)
+		q := v2.Vec{X: 5 * math.Cos(a), Y: 5 * math.Sin(a)}
+		dmin := math.Inf(1)
+		for _, p := range points2 {
+			dmin = math.Min(dmin, p.Sub(q).Length())
+		}
+		if dmin > h {
+			t.Fatalf("%v: nearest sample is %g away", q, dmin)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
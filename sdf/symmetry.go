@@ -0,0 +1,145 @@
+//-----------------------------------------------------------------------------
+/*
+
+Symmetry Operators
+
+These operators fold the evaluation space so that only part of a symmetric
+model needs to be defined. The model is evaluated once per point regardless
+of the number of symmetric copies.
+
+The distance is exact when the defined part of the model lies within the
+fundamental region of the fold (e.g. x >= 0 for MirrorX3D). Any part of the
+model outside the fundamental region is ignored.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// MirrorSDF3 mirrors an SDF3 across one or more of the axis planes.
+type MirrorSDF3 struct {
+	sdf     SDF3
+	x, y, z bool // mirror across the yz, xz, xy planes
+	bb      Box3
+}
+
+// mirror3D returns an SDF3 mirrored across the selected axis planes.
+func mirror3D(sdf SDF3, x, y, z bool) SDF3 {
+	s := MirrorSDF3{sdf: sdf}
+	// mirroring an already mirrored sdf adds axes to the existing fold
+	if m, ok := sdf.(*MirrorSDF3); ok {
+		s = *m
+	}
+	s.x = s.x || x
+	s.y = s.y || y
+	s.z = s.z || z
+	// the fundamental region is the positive half of each mirrored axis
+	bb := s.sdf.BoundingBox()
+	if s.x {
+		bb.Max.X = math.Max(bb.Max.X, 0)
+		bb.Min.X = -bb.Max.X
+	}
+	if s.y {
+		bb.Max.Y = math.Max(bb.Max.Y, 0)
+		bb.Min.Y = -bb.Max.Y
+	}
+	if s.z {
+		bb.Max.Z = math.Max(bb.Max.Z, 0)
+		bb.Min.Z = -bb.Max.Z
+	}
+	s.bb = bb
+	return &s
+}
+
+// MirrorX3D returns an SDF3 that is symmetric about the yz plane.
+// Only the x >= 0 half of the SDF3 needs to be defined.
+func MirrorX3D(sdf SDF3) SDF3 {
+	return mirror3D(sdf, true, false, false)
+}
+
+// MirrorY3D returns an SDF3 that is symmetric about the xz plane.
+// Only the y >= 0 half of the SDF3 needs to be defined.
+func MirrorY3D(sdf SDF3) SDF3 {
+	return mirror3D(sdf, false, true, false)
+}
+
+// MirrorZ3D returns an SDF3 that is symmetric about the xy plane.
+// Only the z >= 0 half of the SDF3 needs to be defined.
+func MirrorZ3D(sdf SDF3) SDF3 {
+	return mirror3D(sdf, false, false, true)
+}
+
+// Evaluate returns the minimum distance to a mirrored SDF3.
+func (s *MirrorSDF3) Evaluate(p v3.Vec) float64 {
+	if s.x {
+		p.X = math.Abs(p.X)
+	}
+	if s.y {
+		p.Y = math.Abs(p.Y)
+	}
+	if s.z {
+		p.Z = math.Abs(p.Z)
+	}
+	return s.sdf.Evaluate(p)
+}
+
+// BoundingBox returns the bounding box of a mirrored SDF3.
+func (s *MirrorSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
+// SymmetricPolarSDF3 has n-fold rotational and mirror symmetry about the z-axis.
+type SymmetricPolarSDF3 struct {
+	sdf   SDF3
+	theta float64 // sector angle
+	bb    Box3
+}
+
+// SymmetricPolar3D returns an SDF3 with n-fold rotational symmetry about the z-axis,
+// where each sector is also mirror symmetric about its center line. Only the wedge
+// of the xy-plane with 0 <= angle <= pi/n needs to be defined (e.g. half of one
+// spoke of a wheel with n spokes, with the spoke center line on the x-axis).
+func SymmetricPolar3D(sdf SDF3, n int) (SDF3, error) {
+	if n <= 0 {
+		return nil, ErrMsg("n <= 0")
+	}
+	s := SymmetricPolarSDF3{
+		sdf:   sdf,
+		theta: Tau / float64(n),
+	}
+	// work out the bounding box from the radius of the bounding box vertices
+	bb := sdf.BoundingBox()
+	rmax := 0.0
+	for _, v := range bb.Vertices() {
+		rmax = math.Max(rmax, v2.Vec{v.X, v.Y}.Length())
+	}
+	s.bb = Box3{v3.Vec{-rmax, -rmax, bb.Min.Z}, v3.Vec{rmax, rmax, bb.Max.Z}}
+	return &s, nil
+}
+
+// Evaluate returns the minimum distance to a polar symmetric SDF3.
+func (s *SymmetricPolarSDF3) Evaluate(p v3.Vec) float64 {
+	// fold the angle into the first half sector
+	r := math.Hypot(p.X, p.Y)
+	a := math.Abs(SawTooth(math.Atan2(p.Y, p.X), s.theta))
+	sin, cos := math.Sincos(a)
+	return s.sdf.Evaluate(v3.Vec{r * cos, r * sin, p.Z})
+}
+
+// BoundingBox returns the bounding box of a polar symmetric SDF3.
+func (s *SymmetricPolarSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
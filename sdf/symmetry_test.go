@@ -0,0 +1,116 @@
+//-----------------------------------------------------------------------------
+/*
+
+Symmetry Operator Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// symmetryCheck compares an SDF3 to a reference at random points.
+func symmetryCheck(t *testing.T, name string, s, ref SDF3) {
+	t.Helper()
+	test := ref.BoundingBox().Enlarge(v3.Vec{4, 4, 4})
+	for i := 0; i < 10000; i++ {
+		p := test.Random()
+		if d, dref := s.Evaluate(p), ref.Evaluate(p); math.Abs(d-dref) > 1e-9 {
+			t.Fatalf("%s: %v: distance %g, expected %g", name, p, d, dref)
+		}
+	}
+}
+
+func Test_Mirror3D(t *testing.T) {
+	ball, _ := Sphere3D(2)
+	at := func(x, y, z float64) SDF3 {
+		return Transform3D(ball, Translate3d(v3.Vec{x, y, z}))
+	}
+	mx, my, mz := v3.Vec{-1, 1, 1}, v3.Vec{1, -1, 1}, v3.Vec{1, 1, -1}
+	tests := []struct {
+		name    string
+		s       SDF3
+		ref     SDF3
+		mirrors []v3.Vec
+	}{
+		{"x", MirrorX3D(at(5, 3, 2)), Union3D(at(5, 3, 2), at(-5, 3, 2)), []v3.Vec{mx}},
+		{"y", MirrorY3D(at(5, 3, 2)), Union3D(at(5, 3, 2), at(5, -3, 2)), []v3.Vec{my}},
+		{"z", MirrorZ3D(at(5, 3, 2)), Union3D(at(5, 3, 2), at(5, 3, -2)), []v3.Vec{mz}},
+		{"xy", MirrorY3D(MirrorX3D(at(5, 3, 2))), Union3D(at(5, 3, 2), at(-5, 3, 2), at(5, -3, 2), at(-5, -3, 2)), []v3.Vec{mx, my}},
+	}
+	for _, x := range tests {
+		symmetryCheck(t, x.name, x.s, x.ref)
+		if x.s.BoundingBox() != x.ref.BoundingBox() {
+			t.Errorf("%s: bounding box %v, expected %v", x.name, x.s.BoundingBox(), x.ref.BoundingBox())
+		}
+		// the evaluation is symmetric
+		bb := x.ref.BoundingBox()
+		for i := 0; i < 1000; i++ {
+			p := bb.Random()
+			for _, m := range x.mirrors {
+				if x.s.Evaluate(p.Mul(m)) != x.s.Evaluate(p) {
+					t.Fatalf("%s: %v: not symmetric across %v", x.name, p, m)
+				}
+			}
+		}
+	}
+	// the nested mirrors are folded into one
+	if _, ok := tests[3].s.(*MirrorSDF3).sdf.(*MirrorSDF3); ok {
+		t.Error("nested mirrors")
+	}
+
+	// a part crossing the mirror plane is cut at the plane
+	s := MirrorX3D(at(1, 0, 0))
+	if d := s.Evaluate(v3.Vec{-1, 0, 0}); d != -2 {
+		t.Errorf("expected distance -2, got %g", d)
+	}
+	if d := s.Evaluate(v3.Vec{-3, 0, 0}); d != 0 {
+		t.Errorf("expected distance 0, got %g", d)
+	}
+	if bb := s.BoundingBox(); bb.Min.X != -3 || bb.Max.X != 3 {
+		t.Errorf("expected x -3 to 3, got %v", bb)
+	}
+}
+
+func Test_SymmetricPolar3D(t *testing.T) {
+	const n = 5
+	ball, _ := Sphere3D(0.8)
+	// half a spoke in the first half sector, the rest by symmetry
+	a := math.Atan2(1, 10)
+	var balls []SDF3
+	for k := 0; k < n; k++ {
+		for _, b := range []float64{a, -a} {
+			theta := float64(k)*Tau/n + b
+			balls = append(balls, Transform3D(ball, Translate3d(v3.Vec{10.05 * math.Cos(theta), 10.05 * math.Sin(theta), 0.5})))
+		}
+	}
+	s, err := SymmetricPolar3D(balls[0], n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := Union3D(balls...)
+	symmetryCheck(t, "polar", s, ref)
+
+	// the bounding box holds the copies
+	bb, rbb := s.BoundingBox(), ref.BoundingBox()
+	if !bb.Contains(rbb.Min) || !bb.Contains(rbb.Max) {
+		t.Errorf("bounding box %v doesn't contain %v", bb, rbb)
+	}
+	if bb.Min.Z != rbb.Min.Z || bb.Max.Z != rbb.Max.Z {
+		t.Errorf("bounding box %v, expected z %g to %g", bb, rbb.Min.Z, rbb.Max.Z)
+	}
+
+	if _, err := SymmetricPolar3D(ball, 0); err == nil {
+		t.Error("expected an error for n <= 0")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -12,6 +12,7 @@ package sdf
 
 import (
 	"io/ioutil"
+	"math"
 	"strings"
 
 	v2 "github.com/deadsy/sdfx/vec/v2"
@@ -111,13 +112,20 @@ func glyphConvert(g *truetype.GlyphBuf) (SDF2, error) {
 
 //-----------------------------------------------------------------------------
 
-// lineSDF2 returns an SDF2 slice for a line of text
-func lineSDF2(f *truetype.Font, l string) ([]SDF2, float64, error) {
+// glyphSDF2 is the SDF2 for a glyph positioned within a line of text.
+type glyphSDF2 struct {
+	s       SDF2    // glyph sdf2 (unpositioned)
+	x       float64 // x offset of the glyph within the line
+	advance float64 // glyph advance width
+}
+
+// lineGlyphs returns the glyphs for a line of text
+func lineGlyphs(f *truetype.Font, l string) ([]glyphSDF2, float64, error) {
 	iPrev := truetype.Index(0)
 	scale := fixed.Int26_6(f.FUnitsPerEm())
 	xOfs := 0.0
 
-	var ss []SDF2
+	var gs []glyphSDF2
 
 	for _, r := range l {
 		i := f.Index(r)
@@ -142,16 +150,39 @@ func lineSDF2(f *truetype.Font, l string) ([]SDF2, float64, error) {
 			return nil, 0, err
 		}
 		if s != nil {
-			s = Transform2D(s, Translate2d(v2.Vec{xOfs, 0}))
-			ss = append(ss, s)
+			gs = append(gs, glyphSDF2{s, xOfs, float64(hm.AdvanceWidth)})
 		}
 
 		xOfs += float64(hm.AdvanceWidth)
 	}
 
+	return gs, xOfs, nil
+}
+
+// lineSDF2 returns an SDF2 slice for a line of text
+func lineSDF2(f *truetype.Font, l string) ([]SDF2, float64, error) {
+	gs, xOfs, err := lineGlyphs(f, l)
+	if err != nil {
+		return nil, 0, err
+	}
+	ss := make([]SDF2, len(gs))
+	for i, g := range gs {
+		ss[i] = Transform2D(g.s, Translate2d(v2.Vec{g.x, 0}))
+	}
 	return ss, xOfs, nil
 }
 
+// alignOffset returns the x offset of a line of text for an alignment.
+func (t *Text) alignOffset(hlen float64) float64 {
+	switch t.halign {
+	case rAlign:
+		return -hlen
+	case cAlign:
+		return -hlen / 2.0
+	}
+	return 0
+}
+
 //-----------------------------------------------------------------------------
 // public api
 
@@ -188,12 +219,7 @@ func Text2D(f *truetype.Font, t *Text, h float64) (SDF2, error) {
 		if err != nil {
 			return nil, err
 		}
-		xOfs := 0.0
-		if t.halign == rAlign {
-			xOfs = -hlen
-		} else if t.halign == cAlign {
-			xOfs = -hlen / 2.0
-		}
+		xOfs := t.alignOffset(hlen)
 		for i := range ssLine {
 			ssLine[i] = Transform2D(ssLine[i], Translate2d(v2.Vec{xOfs, yOfs}))
 		}
@@ -205,3 +231,74 @@ func Text2D(f *truetype.Font, t *Text, h float64) (SDF2, error) {
 }
 
 //-----------------------------------------------------------------------------
+
+// ArcTextParms defines the layout of text along a circular arc.
+type ArcTextParms struct {
+	Height float64 // text height (as per Text2D)
+	Radius float64 // baseline radius of the first line
+	Theta  float64 // angle of the text alignment point (radians)
+	Inside bool    // glyphs are inside the baseline with their tops towards the center
+}
+
+// ArcText2D returns an SDF2 for a text object laid out along a circular arc centered on the origin.
+// The text alignment sets which part of the line is placed at Theta. Outside text reads clockwise
+// with the glyphs outside the baseline, e.g. the top of a dial face. Inside text reads anticlockwise
+// with the glyphs inside the baseline, e.g. the bottom of a dial face. Subsequent lines are placed
+// below the first line. Glyphs are rotated into place, not bent.
+func ArcText2D(f *truetype.Font, t *Text, k *ArcTextParms) (SDF2, error) {
+	if k.Height <= 0 {
+		return nil, ErrMsg("Height <= 0")
+	}
+	if k.Radius <= 0 {
+		return nil, ErrMsg("Radius <= 0")
+	}
+	scale := fixed.Int26_6(f.FUnitsPerEm())
+	lines := strings.Split(t.s, "\n")
+	vm := f.VMetric(scale, f.Index('\n'))
+	ah := float64(vm.AdvanceHeight)
+	// font units to output units
+	sk := k.Height / ah
+
+	var ss []SDF2
+
+	for i := range lines {
+		gs, hlen, err := lineGlyphs(f, lines[i])
+		if err != nil {
+			return nil, err
+		}
+		xOfs := t.alignOffset(hlen)
+		// the next line is below this one
+		r := k.Radius - float64(i)*k.Height
+		if k.Inside {
+			r = k.Radius + float64(i)*k.Height
+		}
+		if r <= 0 {
+			return nil, ErrMsg("text lines overlap the arc center")
+		}
+		for _, g := range gs {
+			// arc length from the alignment point to the center of the glyph
+			xm := g.x + 0.5*g.advance
+			a := (xOfs + xm) * sk / r
+			// glyph angle and rotation of the glyph y-axis onto its radial direction
+			var phi, rot float64
+			if k.Inside {
+				phi = k.Theta + a
+				rot = phi + 0.5*Pi
+			} else {
+				phi = k.Theta - a
+				rot = phi - 0.5*Pi
+			}
+			s := Transform2D(g.s, Translate2d(v2.Vec{-0.5 * g.advance, 0}))
+			s = ScaleUniform2D(s, sk)
+			m := Translate2d(v2.Vec{r * math.Cos(phi), r * math.Sin(phi)}).Mul(Rotate2d(rot))
+			ss = append(ss, Transform2D(s, m))
+		}
+	}
+
+	if len(ss) == 0 {
+		return nil, ErrMsg("no glyphs")
+	}
+	return Union2D(ss...), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,210 @@
+//-----------------------------------------------------------------------------
+/*
+
+Text Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+//-----------------------------------------------------------------------------
+
+func testFont(t *testing.T) *truetype.Font {
+	f, err := LoadFont("../files/cmr10.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// refLineSDF2 is the line layout of Text2D before the glyph positions were split out.
+func refLineSDF2(f *truetype.Font, l string) ([]SDF2, float64, error) {
+	iPrev := truetype.Index(0)
+	scale := fixed.Int26_6(f.FUnitsPerEm())
+	xOfs := 0.0
+	var ss []SDF2
+	for _, r := range l {
+		i := f.Index(r)
+		hm := f.HMetric(scale, i)
+		xOfs += float64(f.Kern(scale, iPrev, i))
+		iPrev = i
+		g := &truetype.GlyphBuf{}
+		if err := g.Load(f, scale, i, font.HintingNone); err != nil {
+			return nil, 0, err
+		}
+		s, err := glyphConvert(g)
+		if err != nil {
+			return nil, 0, err
+		}
+		if s != nil {
+			ss = append(ss, Transform2D(s, Translate2d(v2.Vec{xOfs, 0})))
+		}
+		xOfs += float64(hm.AdvanceWidth)
+	}
+	return ss, xOfs, nil
+}
+
+// refText2D is Text2D before the line layout was shared with ArcText2D.
+func refText2D(f *truetype.Font, t *Text, h float64) (SDF2, error) {
+	scale := fixed.Int26_6(f.FUnitsPerEm())
+	yOfs := 0.0
+	ah := float64(f.VMetric(scale, f.Index('\n')).AdvanceHeight)
+	var ss []SDF2
+	for _, line := range strings.Split(t.s, "\n") {
+		ssLine, hlen, err := refLineSDF2(f, line)
+		if err != nil {
+			return nil, err
+		}
+		xOfs := 0.0
+		if t.halign == rAlign {
+			xOfs = -hlen
+		} else if t.halign == cAlign {
+			xOfs = -hlen / 2.0
+		}
+		for i := range ssLine {
+			ssLine[i] = Transform2D(ssLine[i], Translate2d(v2.Vec{xOfs, yOfs}))
+		}
+		ss = append(ss, ssLine...)
+		yOfs -= ah
+	}
+	return CenterAndScale2D(Union2D(ss...), h/ah), nil
+}
+
+func Test_Text2D(t *testing.T) {
+	f := testFont(t)
+	for _, halign := range []align{lAlign, rAlign, cAlign} {
+		txt := &Text{s: "AVAST\nye lubbers", halign: halign}
+		// the glyph curves are sampled with random midpoints, use the same ones
+		sdfRand.Seed(1)
+		s, err := Text2D(f, txt, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sdfRand.Seed(1)
+		ref, _ := refText2D(f, txt, 10)
+		if s.BoundingBox() != ref.BoundingBox() {
+			t.Fatalf("align %d: bounding box %v, expected %v", halign, s.BoundingBox(), ref.BoundingBox())
+		}
+		bb := ref.BoundingBox()
+		for i := 0; i < 2000; i++ {
+			p := bb.Random()
+			if d, dref := s.Evaluate(p), ref.Evaluate(p); d != dref {
+				t.Fatalf("align %d: %v: distance %g, expected %g", halign, p, d, dref)
+			}
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// insidePoints returns random points inside an SDF2.
+func insidePoints(s SDF2, n int) []v2.Vec {
+	bb := s.BoundingBox()
+	var points []v2.Vec
+	for len(points) < n {
+		p := bb.Random()
+		if s.Evaluate(p) < 0 {
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+func Test_ArcText2D(t *testing.T) {
+	f := testFont(t)
+	const h, r = 5.0, 40.0
+	theta := DtoR(60)
+
+	// the alignment point is at theta: centered text is either side of it,
+	// left aligned text reads away from it, right aligned text reads up to it
+	hello := "HELLO"
+	for _, inside := range []bool{false, true} {
+		k := &ArcTextParms{Height: h, Radius: r, Theta: theta, Inside: inside}
+		for _, halign := range []align{lAlign, rAlign, cAlign} {
+			s, err := ArcText2D(f, &Text{s: hello, halign: halign}, k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lo, hi := math.Inf(1), math.Inf(-1)
+			for _, p := range insidePoints(s, 500) {
+				rho := p.Length()
+				// the glyphs are outside (or inside) the baseline
+				if (!inside && (rho < r-0.1*h || rho > r+h)) || (inside && (rho > r+0.1*h || rho < r-h)) {
+					t.Fatalf("inside %v, align %d: %v: radius %g is off the baseline %g", inside, halign, p, rho, r)
+				}
+				a := math.Atan2(p.Y, p.X) - theta
+				lo, hi = math.Min(lo, a), math.Max(hi, a)
+			}
+			// outside text reads clockwise, inside text anticlockwise
+			start, end := hi, lo
+			if inside {
+				start, end = lo, hi
+			}
+			switch halign {
+			case lAlign:
+				if math.Abs(start) > 0.02 {
+					t.Errorf("inside %v: left aligned text starts %g radians from theta", inside, start)
+				}
+			case rAlign:
+				if math.Abs(end) > 0.02 {
+					t.Errorf("inside %v: right aligned text ends %g radians from theta", inside, end)
+				}
+			case cAlign:
+				if math.Abs(lo+hi) > 0.04 {
+					t.Errorf("inside %v: centered text spans %g to %g radians from theta", inside, lo, hi)
+				}
+			}
+		}
+	}
+
+	// the glyphs are spaced as in a line of Text2D
+	s, _ := ArcText2D(f, &Text{s: hello, halign: lAlign}, &ArcTextParms{Height: h, Radius: 1e5})
+	line, _ := Text2D(f, &Text{s: hello, halign: lAlign}, h)
+	arc, width := s.BoundingBox().Size(), line.BoundingBox().Size()
+	if math.Abs(arc.Y-width.X) > 0.05*width.X {
+		t.Errorf("expected the text to span %g on a large radius, got %g", width.X, arc.Y)
+	}
+
+	// the second line is below the first
+	s, _ = ArcText2D(f, NewText("I\nI"), &ArcTextParms{Height: h, Radius: r})
+	var lower int
+	for _, p := range insidePoints(s, 200) {
+		rho := p.Length()
+		if rho < r-h-0.1*h {
+			t.Fatalf("%v: radius %g is below the second line", p, rho)
+		}
+		if rho < r-0.1*h {
+			lower++
+		}
+	}
+	if lower == 0 {
+		t.Error("expected glyphs on the second line")
+	}
+
+	for _, k := range []ArcTextParms{
+		{Height: 0, Radius: r},
+		{Height: h, Radius: 0},
+		{Height: h, Radius: h},
+	} {
+		if _, err := ArcText2D(f, NewText("A\nB\nC"), &k); err == nil {
+			t.Errorf("expected an error for %+v", k)
+		}
+	}
+	if _, err := ArcText2D(f, NewText(" "), &ArcTextParms{Height: h, Radius: r}); err == nil {
+		t.Error("expected an error for no glyphs")
+	}
+}
+
+//-----------------------------------------------------------------------------
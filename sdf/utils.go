@@ -9,6 +9,7 @@ import (
 	"math"
 	"math/rand"
 	"runtime"
+	"sync"
 
 	"github.com/deadsy/sdfx/vec/conv"
 	v2 "github.com/deadsy/sdfx/vec/v2"
@@ -44,7 +45,33 @@ const epsilon = 1e-12
 // results from run to run for binary verification, so we have
 // our own local random source.
 
-var sdfRand = rand.New(rand.NewSource(1))
+// A rand.Rand is not safe for concurrent use, so the source is locked.
+
+var sdfRand = rand.New(&lockedSource{src: rand.NewSource(1).(rand.Source64)})
+
+// lockedSource is a random source that is safe for concurrent use.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
 
 // randomRange returns a random float64 [a,b)
 func randomRange(a, b float64) float64 {
@@ -227,6 +254,19 @@ func ScaleTwistExtrude(height, twist float64, scale v2.Vec) ExtrudeFunc {
 	}
 }
 
+// ExtrudeProfile returns the rotation angle and the xy scale of an extrusion at height z.
+type ExtrudeProfile func(z float64) (angle float64, scale v2.Vec)
+
+// ProfileExtrude returns an extrusion function that scales and twists with z
+// according to a profile function.
+func ProfileExtrude(profile ExtrudeProfile) ExtrudeFunc {
+	return func(p v3.Vec) v2.Vec {
+		angle, scale := profile(p.Z)
+		pnew := v2.Vec{p.X / scale.X, p.Y / scale.Y} // Scale
+		return Rotate(angle).MulPosition(pnew)       // Twist
+	}
+}
+
 //-----------------------------------------------------------------------------
 // Raycasting
 
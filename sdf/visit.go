@@ -0,0 +1,267 @@
+//-----------------------------------------------------------------------------
+/*
+
+SDF Tree Inspection
+
+Composite SDFs (boolean operations, transforms, extrusions, ...) expose
+their child SDFs so tools can walk the constructive tree for serialization,
+statistics, bounding box debugging or optimization passes.
+
+A node of the tree is an SDF2 or an SDF3. Leaf nodes (primitives) have no
+children.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+//-----------------------------------------------------------------------------
+
+// Composite is an SDF2 or SDF3 built from other SDF2s and/or SDF3s.
+type Composite interface {
+	// Children returns the child SDF2s and SDF3s.
+	Children() []interface{}
+}
+
+// Visitor is called for each node of an SDF tree with the depth of the node (root = 0).
+// Returning false skips the children of the node.
+type Visitor interface {
+	Visit(s interface{}, depth int) bool
+}
+
+// VisitorFunc is a function used as a Visitor.
+type VisitorFunc func(s interface{}, depth int) bool
+
+// Visit calls the visitor function.
+func (f VisitorFunc) Visit(s interface{}, depth int) bool {
+	return f(s, depth)
+}
+
+// Accept walks an SDF tree depth first calling the visitor for each node.
+func Accept(s interface{}, v Visitor) {
+	accept(s, v, 0)
+}
+
+func accept(s interface{}, v Visitor, depth int) {
+	if !v.Visit(s, depth) {
+		return
+	}
+	if c, ok := s.(Composite); ok {
+		for _, child := range c.Children() {
+			accept(child, v, depth+1)
+		}
+	}
+}
+
+// TypeName returns the type name of an SDF tree node (e.g. "UnionSDF3").
+func TypeName(s interface{}) string {
+	t := reflect.TypeOf(s)
+	if t == nil {
+		return "nil"
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+//-----------------------------------------------------------------------------
+
+// TreeStats are the statistics of an SDF tree.
+type TreeStats struct {
+	Nodes  int            // number of nodes
+	Leaves int            // number of leaf nodes
+	Depth  int            // maximum depth (a single node has depth 1)
+	Types  map[string]int // node count by type name
+}
+
+// Stats returns the statistics of an SDF tree.
+func Stats(s interface{}) *TreeStats {
+	st := &TreeStats{Types: make(map[string]int)}
+	Accept(s, VisitorFunc(func(s interface{}, depth int) bool {
+		st.Nodes++
+		st.Types[TypeName(s)]++
+		if c, ok := s.(Composite); !ok || len(c.Children()) == 0 {
+			st.Leaves++
+		}
+		if depth+1 > st.Depth {
+			st.Depth = depth + 1
+		}
+		return true
+	}))
+	return st
+}
+
+// TreeString returns an indented listing of an SDF tree with the bounding box of each node.
+func TreeString(s interface{}) string {
+	var sb strings.Builder
+	Accept(s, VisitorFunc(func(s interface{}, depth int) bool {
+		fmt.Fprintf(&sb, "%s%s", strings.Repeat("  ", depth), TypeName(s))
+		switch s := s.(type) {
+		case SDF3:
+			bb := s.BoundingBox()
+			fmt.Fprintf(&sb, " %v %v", bb.Min, bb.Max)
+		case SDF2:
+			bb := s.BoundingBox()
+			fmt.Fprintf(&sb, " %v %v", bb.Min, bb.Max)
+		}
+		sb.WriteString("\n")
+		return true
+	}))
+	return sb.String()
+}
+
+//-----------------------------------------------------------------------------
+// SDF2 children
+
+// Children returns the child SDF2s.
+func (s *UnionSDF2) Children() []interface{} {
+	c := make([]interface{}, len(s.sdf))
+	for i, x := range s.sdf {
+		c[i] = x
+	}
+	return c
+}
+
+// Children returns the child SDF2s.
+func (s *DifferenceSDF2) Children() []interface{} { return []interface{}{s.s0, s.s1} }
+
+// Children returns the child SDF2s.
+func (s *IntersectionSDF2) Children() []interface{} { return []interface{}{s.s0, s.s1} }
+
+// Children returns the child SDF2.
+func (s *OffsetSDF2) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF2.
+func (s *CutSDF2) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF2.
+func (s *TransformSDF2) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF2.
+func (s *ScaleUniformSDF2) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF2.
+func (s *ArraySDF2) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF2.
+func (s *RotateUnionSDF2) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF2.
+func (s *RotateCopySDF2) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF2.
+func (s *ElongateSDF2) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF2.
+func (s *CacheSDF2) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF2.
+func (s *GearRackSDF2) Children() []interface{} { return []interface{}{s.tooth} }
+
+// Children returns the child SDF2.
+func (s *glyphSDF2) Children() []interface{} { return []interface{}{s.s} }
+
+// Children returns the sliced SDF3.
+func (s *SliceSDF2) Children() []interface{} { return []interface{}{s.sdf} }
+
+//-----------------------------------------------------------------------------
+// SDF3 children
+
+// Children returns the child SDF3s.
+func (s *UnionSDF3) Children() []interface{} {
+	c := make([]interface{}, len(s.sdf))
+	for i, x := range s.sdf {
+		c[i] = x
+	}
+	return c
+}
+
+// Children returns the child SDF3s.
+func (s *DifferenceSDF3) Children() []interface{} { return []interface{}{s.s0, s.s1} }
+
+// Children returns the child SDF3s.
+func (s *IntersectionSDF3) Children() []interface{} { return []interface{}{s.s0, s.s1} }
+
+// Children returns the child SDF3.
+func (s *TransformSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3.
+func (s *ScaleUniformSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3.
+func (s *ElongateSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3.
+func (s *CutSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3.
+func (s *ArraySDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3.
+func (s *RotateUnionSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3.
+func (s *RotateCopySDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3.
+func (s *OffsetSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3.
+func (s *ShellSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3.
+func (s *MirrorSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3.
+func (s *SymmetricPolarSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3.
+func (s *PatternSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3.
+func (s *ConformalArraySDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3.
+func (s *DisplaceSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3.
+func (s *FieldShellSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3.
+func (s *RenormalizeSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the child SDF3s.
+func (s *MorphSDF3) Children() []interface{} { return []interface{}{s.a, s.b} }
+
+// Children returns the inner and outer SDF3s.
+func (s *ConformalLatticeSDF3) Children() []interface{} { return []interface{}{s.inner, s.outer} }
+
+// Children returns the cached SDF3.
+func (s *CacheSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the profile SDF2.
+func (s *SorSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the profile SDF2.
+func (s *ExtrudeSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the profile SDF2.
+func (s *ExtrudeRoundedSDF3) Children() []interface{} { return []interface{}{s.sdf} }
+
+// Children returns the bottom and top profile SDF2s.
+func (s *LoftSDF3) Children() []interface{} { return []interface{}{s.sdf0, s.sdf1} }
+
+// Children returns the profile SDF2.
+func (s *HelixSDF3) Children() []interface{} { return []interface{}{s.profile} }
+
+// Children returns the thread profile SDF2.
+func (s *ScrewSDF3) Children() []interface{} { return []interface{}{s.thread} }
+
+//-----------------------------------------------------------------------------
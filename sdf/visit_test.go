@@ -0,0 +1,53 @@
+//-----------------------------------------------------------------------------
+/*
+
+SDF Tree Inspection Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"strings"
+	"testing"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Visitor(t *testing.T) {
+	box, _ := Box3D(v3.Vec{10, 10, 10}, 0)
+	sphere, _ := Sphere3D(6)
+	circle, _ := Circle2D(2)
+	ext := Extrude3D(Difference2D(Box2D(v2.Vec{4, 4}, 0), circle), 20)
+	s := Union3D(Difference3D(box, sphere), Transform3D(ext, Translate3d(v3.Vec{0, 0, 10})))
+
+	st := Stats(s)
+	if st.Nodes != 9 || st.Leaves != 4 || st.Depth != 5 {
+		t.Errorf("expected 9 nodes, 4 leaves, depth 5, got %d %d %d", st.Nodes, st.Leaves, st.Depth)
+	}
+	if st.Types["BoxSDF3"] != 1 || st.Types["BoxSDF2"] != 1 || st.Types["CircleSDF2"] != 1 {
+		t.Errorf("bad type counts %v", st.Types)
+	}
+
+	// skip the children of the difference
+	n := 0
+	Accept(s, VisitorFunc(func(s interface{}, depth int) bool {
+		n++
+		_, ok := s.(*DifferenceSDF3)
+		return !ok
+	}))
+	if n != 7 {
+		t.Errorf("expected 7 visited nodes, got %d", n)
+	}
+
+	lines := strings.Split(strings.TrimSpace(TreeString(s)), "\n")
+	if len(lines) != 9 || !strings.HasPrefix(lines[0], "UnionSDF3 ") || !strings.HasPrefix(lines[8], "        CircleSDF2 ") {
+		t.Errorf("bad tree string\n%s", TreeString(s))
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,290 @@
+//-----------------------------------------------------------------------------
+/*
+
+3D Voronoi Cells and Cellular Noise
+
+A Voronoi diagram partitions space into convex cells, one for each seed
+point. Each cell is the intersection of the half-spaces bounded by the
+bisector planes between its seed and every other seed, so the distance
+from a point to the boundary of its cell is the minimum distance to those
+bisector planes.
+
+Walls: the cell boundaries thickened to a given wall thickness.
+Cells: the cell interiors separated by gaps of the wall thickness.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"sort"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// VoronoiMode selects the type of voronoi solid.
+type VoronoiMode int
+
+const (
+	// VoronoiWalls is the set of cell walls.
+	VoronoiWalls VoronoiMode = iota
+	// VoronoiCells is the set of cell interiors.
+	VoronoiCells
+)
+
+//-----------------------------------------------------------------------------
+
+// voronoiNeighbor is a neighboring seed of a voronoi cell.
+type voronoiNeighbor struct {
+	idx  int     // seed index
+	half float64 // half the distance between the seeds
+}
+
+// VoronoiSDF3 is a 3d voronoi cell structure.
+type VoronoiSDF3 struct {
+	seeds     v3.VecSet           // seed points
+	neighbors [][]voronoiNeighbor // per seed neighbors sorted by distance
+	mode      VoronoiMode         // walls or cells
+	wall      float64             // half wall thickness
+	round     float64             // cell edge rounding
+	box       v3.Vec              // half size of the clamping box
+	center    v3.Vec              // center of the clamping box
+	bb        Box3                // bounding box
+}
+
+// Voronoi3D returns the voronoi walls or cells for a set of seed points.
+// The result is clamped to the box. round > 0 gives blobby cells.
+func Voronoi3D(seeds v3.VecSet, mode VoronoiMode, wall, round float64, box Box3) (SDF3, error) {
+	if len(seeds) < 2 {
+		return nil, ErrMsg("len(seeds) < 2")
+	}
+	if wall < 0 {
+		return nil, ErrMsg("wall < 0")
+	}
+	if round < 0 {
+		return nil, ErrMsg("round < 0")
+	}
+	if box.Size().LTEZero() {
+		return nil, ErrMsg("box size <= 0")
+	}
+	s := VoronoiSDF3{
+		seeds: seeds,
+		mode:  mode,
+		wall:  0.5 * wall,
+		round: round,
+		box:   box.Size().MulScalar(0.5),
+	}
+	s.center = box.Center()
+	s.bb = box
+	// work out the neighbors of each seed, nearest first
+	s.neighbors = make([][]voronoiNeighbor, len(seeds))
+	for i, a := range seeds {
+		n := make([]voronoiNeighbor, 0, len(seeds)-1)
+		for j, b := range seeds {
+			if i != j {
+				n = append(n, voronoiNeighbor{j, 0.5 * b.Sub(a).Length()})
+			}
+		}
+		sort.Slice(n, func(x, y int) bool { return n[x].half < n[y].half })
+		s.neighbors[i] = n
+	}
+	return &s, nil
+}
+
+// VoronoiRandom3D returns the voronoi walls or cells for n random seed points within the box.
+func VoronoiRandom3D(n int, mode VoronoiMode, wall, round float64, box Box3) (SDF3, error) {
+	if n < 2 {
+		return nil, ErrMsg("n < 2")
+	}
+	return Voronoi3D(box.RandomSet(n), mode, wall, round, box)
+}
+
+// nearest returns the index of the seed nearest to p.
+func (s *VoronoiSDF3) nearest(p v3.Vec) int {
+	idx := 0
+	d2 := math.MaxFloat64
+	for i, a := range s.seeds {
+		x := p.Sub(a).Length2()
+		if x < d2 {
+			d2 = x
+			idx = i
+		}
+	}
+	return idx
+}
+
+// edge returns the distance from p to the boundary of the cell of seed i.
+func (s *VoronoiSDF3) edge(p v3.Vec, i int) float64 {
+	a := s.seeds[i]
+	pa := p.Sub(a).Length()
+	d := math.MaxFloat64  // smallest plane distance
+	d1 := math.MaxFloat64 // second smallest plane distance (for rounding)
+	for _, n := range s.neighbors[i] {
+		// the bisector plane distance is >= half - |p-a|
+		bound := d
+		if s.round > 0 {
+			bound = d1
+		}
+		if n.half-pa > bound {
+			break
+		}
+		b := s.seeds[n.idx]
+		mid := a.Add(b).MulScalar(0.5)
+		x := mid.Sub(p).Dot(b.Sub(a).Normalize())
+		if x < d {
+			d, d1 = x, d
+		} else if x < d1 {
+			d1 = x
+		}
+	}
+	if s.round > 0 && d1 != math.MaxFloat64 {
+		// smooth the minimum of the two nearest planes to round the cell edges
+		return PolyMin(s.round)(d, d1)
+	}
+	return d
+}
+
+// Evaluate returns the minimum distance to a 3d voronoi structure.
+func (s *VoronoiSDF3) Evaluate(p v3.Vec) float64 {
+	e := s.edge(p, s.nearest(p))
+	var d float64
+	if s.mode == VoronoiCells {
+		d = s.wall - e
+	} else {
+		d = e - s.wall
+	}
+	// clamp to the box
+	return math.Max(d, sdfBox3d(p.Sub(s.center), s.box))
+}
+
+// BoundingBox returns the bounding box for a 3d voronoi structure.
+func (s *VoronoiSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+// Cellular Noise: voronoi walls over an unbounded jittered grid of seeds.
+
+// CellNoiseSDF3 is an unbounded 3d cellular (Worley) noise structure.
+type CellNoiseSDF3 struct {
+	cell   float64 // cell size
+	jitter float64 // seed jitter within a cell [0,1]
+	mode   VoronoiMode
+	wall   float64 // half wall thickness
+	seed   uint32  // hash seed
+}
+
+// CellNoise3D returns unbounded cellular noise walls or cells.
+// Seeds are placed one per grid cell with a hashed random jitter.
+func CellNoise3D(cell, jitter, wall float64, mode VoronoiMode, seed uint32) (SDF3, error) {
+	if cell <= 0 {
+		return nil, ErrMsg("cell <= 0")
+	}
+	if jitter < 0 || jitter > 1 {
+		return nil, ErrMsg("jitter not in [0,1]")
+	}
+	if wall < 0 {
+		return nil, ErrMsg("wall < 0")
+	}
+	return &CellNoiseSDF3{
+		cell:   cell,
+		jitter: jitter,
+		mode:   mode,
+		wall:   0.5 * wall,
+		seed:   seed,
+	}, nil
+}
+
+// hash3 returns a pseudo random value in [0,1) for an integer cell position.
+func hash3(x, y, z int, seed uint32) float64 {
+	h := seed ^ 0x9e3779b9
+	h ^= uint32(x) * 0x85ebca6b
+	h = (h << 13) | (h >> 19)
+	h ^= uint32(y) * 0xc2b2ae35
+	h = (h << 13) | (h >> 19)
+	h ^= uint32(z) * 0x27d4eb2f
+	h ^= h >> 16
+	h *= 0x7feb352d
+	h ^= h >> 15
+	h *= 0x846ca68b
+	h ^= h >> 16
+	return float64(h) / (1 << 32)
+}
+
+// cellSeed returns the seed point of a grid cell.
+func (s *CellNoiseSDF3) cellSeed(x, y, z int) v3.Vec {
+	j := v3.Vec{
+		hash3(x, y, z, s.seed),
+		hash3(x, y, z, s.seed+1),
+		hash3(x, y, z, s.seed+2),
+	}
+	j = j.SubScalar(0.5).MulScalar(s.jitter).AddScalar(0.5)
+	return v3.Vec{float64(x), float64(y), float64(z)}.Add(j).MulScalar(s.cell)
+}
+
+// planes returns the distances from p to the nearest and second nearest
+// bisector planes of the cell containing p.
+func (s *CellNoiseSDF3) planes(p v3.Vec) (float64, float64) {
+	cx := int(math.Floor(p.X / s.cell))
+	cy := int(math.Floor(p.Y / s.cell))
+	cz := int(math.Floor(p.Z / s.cell))
+	// find the nearest seed
+	var a v3.Vec
+	var ax, ay, az int
+	d2 := math.MaxFloat64
+	for i := -1; i <= 1; i++ {
+		for j := -1; j <= 1; j++ {
+			for k := -1; k <= 1; k++ {
+				b := s.cellSeed(cx+i, cy+j, cz+k)
+				x := p.Sub(b).Length2()
+				if x < d2 {
+					d2 = x
+					a = b
+					ax, ay, az = cx+i, cy+j, cz+k
+				}
+			}
+		}
+	}
+	// distance to the nearest bisector planes
+	e, e1 := math.MaxFloat64, math.MaxFloat64
+	for i := -2; i <= 2; i++ {
+		for j := -2; j <= 2; j++ {
+			for k := -2; k <= 2; k++ {
+				if i == 0 && j == 0 && k == 0 {
+					continue
+				}
+				b := s.cellSeed(ax+i, ay+j, az+k)
+				mid := a.Add(b).MulScalar(0.5)
+				x := mid.Sub(p).Dot(b.Sub(a).Normalize())
+				if x < e {
+					e, e1 = x, e
+				} else if x < e1 {
+					e1 = x
+				}
+			}
+		}
+	}
+	return e, e1
+}
+
+// Evaluate returns the minimum distance to cellular noise.
+func (s *CellNoiseSDF3) Evaluate(p v3.Vec) float64 {
+	e, _ := s.planes(p)
+	if s.mode == VoronoiCells {
+		return s.wall - e
+	}
+	return e - s.wall
+}
+
+// BoundingBox returns the bounding box for cellular noise.
+func (s *CellNoiseSDF3) BoundingBox() Box3 {
+	// The noise is defined for all xyz, so the bounding box is a point at the origin.
+	// To use the noise it needs to be intersected with an external bounding volume.
+	return Box3{}
+}
+
+//-----------------------------------------------------------------------------
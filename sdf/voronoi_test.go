@@ -0,0 +1,67 @@
+//-----------------------------------------------------------------------------
+/*
+
+Voronoi Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Voronoi3D(t *testing.T) {
+	seeds := v3.VecSet{{-5, 0, 0}, {5, 0, 0}}
+	box := NewBox3(v3.Vec{0, 0, 0}, v3.Vec{20, 20, 20})
+
+	walls, err := Voronoi3D(seeds, VoronoiWalls, 2, 0, box)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		p v3.Vec
+		d float64
+	}{
+		{v3.Vec{0, 0, 0}, -1},
+		{v3.Vec{0, 3, 4}, -1},
+		{v3.Vec{3, 0, 0}, 2},
+		{v3.Vec{-4, 1, 1}, 3},
+		{v3.Vec{0, 0, 12}, 2},
+	}
+	for _, v := range tests {
+		d := walls.Evaluate(v.p)
+		if !EqualFloat64(d, v.d, tolerance) {
+			t.Errorf("walls %v: expected %f, got %f", v.p, v.d, d)
+		}
+	}
+
+	cells, err := Voronoi3D(seeds, VoronoiCells, 2, 0, box)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range tests[:4] {
+		d := cells.Evaluate(v.p)
+		if !EqualFloat64(d, -v.d, tolerance) {
+			t.Errorf("cells %v: expected %f, got %f", v.p, -v.d, d)
+		}
+	}
+
+	noise, err := CellNoise3D(10, 1, 1, VoronoiWalls, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range box.RandomSet(100) {
+		d := noise.Evaluate(p)
+		if d < -0.5 || d > 10 {
+			t.Errorf("noise %v: distance %f out of range", p, d)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
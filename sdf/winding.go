@@ -0,0 +1,190 @@
+//-----------------------------------------------------------------------------
+/*
+
+Fast Winding Numbers for Triangle Meshes
+
+The generalized winding number of a point is the sum of the signed solid
+angles of the mesh triangles seen from the point, divided by 4 pi. It is 1
+inside and 0 outside a closed, consistently oriented mesh. For meshes with
+small holes, repeated faces or a few flipped faces it changes smoothly
+rather than jumping, so thresholding it at 0.5 still gives a good
+inside/outside classification where ray casting or the normal of the
+closest face fail.
+
+The mesh triangles are held in a BVH. Far from a node its triangles are
+approximated by a dipole: the sum of the area weighted normals at the area
+weighted center (Barill et al., "Fast Winding Numbers for Soups and
+Clouds"). Near a node its children (and at the leaves the triangles) are
+evaluated exactly. The same BVH gives the closest triangle for the distance.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"sort"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// windingBeta is the distance (in node radii) beyond which a node is approximated by a dipole.
+const windingBeta = 2.0
+
+// solidAngle returns the signed solid angle of a triangle seen from a point.
+func solidAngle(t *Triangle3, p v3.Vec) float64 {
+	a, b, c := t[0].Sub(p), t[1].Sub(p), t[2].Sub(p)
+	la, lb, lc := a.Length(), b.Length(), c.Length()
+	num := a.Dot(b.Cross(c))
+	den := la*lb*lc + a.Dot(b)*lc + b.Dot(c)*la + c.Dot(a)*lb
+	return 2 * math.Atan2(num, den)
+}
+
+// windingNumber returns the exact generalized winding number of a point for a set of triangles.
+func windingNumber(mesh []*Triangle3, p v3.Vec) float64 {
+	w := 0.0
+	for _, t := range mesh {
+		w += solidAngle(t, p)
+	}
+	return w / (4 * Pi)
+}
+
+//-----------------------------------------------------------------------------
+
+// meshBVH is a BVH over the triangles of a mesh.
+type meshBVH struct {
+	nodes  []bvhNode
+	bb     []Box3          // per node bounding box
+	normal []v3.Vec        // per node sum of the area weighted normals
+	center []v3.Vec        // per node area weighted center
+	radius []float64       // per node radius around the center
+	tri    []*Triangle3    // triangles in leaf order
+	info   []*triangleInfo // triangle info in leaf order
+}
+
+// newMeshBVH returns a BVH for a set of triangles.
+func newMeshBVH(mesh []*Triangle3) *meshBVH {
+	t := &meshBVH{
+		tri: append([]*Triangle3{}, mesh...),
+	}
+	t.build(0, len(t.tri))
+	t.info = convertTriangles(t.tri)
+	return t
+}
+
+// build builds the node for triangles [start, end) and returns its index.
+func (t *meshBVH) build(start, end int) int {
+	tri := t.tri[start:end]
+	bb := tri[0].BoundingBox()
+	cb := Box3{tri[0].centroid(), tri[0].centroid()}
+	var normal, center v3.Vec
+	var area float64
+	for _, x := range tri {
+		bb = bb.Extend(x.BoundingBox())
+		cb = cb.Include(x.centroid())
+		// the cross product is twice the area weighted normal
+		n := x[1].Sub(x[0]).Cross(x[2].Sub(x[0])).MulScalar(0.5)
+		a := n.Length()
+		normal = normal.Add(n)
+		center = center.Add(x.centroid().MulScalar(a))
+		area += a
+	}
+	if area > 0 {
+		center = center.DivScalar(area)
+	} else {
+		center = bb.Center()
+	}
+	radius := 0.0
+	for _, x := range tri {
+		for _, v := range x {
+			radius = math.Max(radius, v.Sub(center).Length())
+		}
+	}
+	n := len(t.nodes)
+	t.nodes = append(t.nodes, bvhNode{left: -1, start: start, end: end})
+	t.bb = append(t.bb, bb)
+	t.normal = append(t.normal, normal)
+	t.center = append(t.center, center)
+	t.radius = append(t.radius, radius)
+	if end-start <= bvhLeafSize {
+		return n
+	}
+	// split at the median centroid along the longest axis of the centroids
+	size := cb.Size()
+	axis := func(v v3.Vec) float64 { return v.X }
+	if size.Y > size.X && size.Y >= size.Z {
+		axis = func(v v3.Vec) float64 { return v.Y }
+	} else if size.Z > size.X && size.Z > size.Y {
+		axis = func(v v3.Vec) float64 { return v.Z }
+	}
+	sort.Slice(tri, func(i, j int) bool { return axis(tri[i].centroid()) < axis(tri[j].centroid()) })
+	mid := (start + end) / 2
+	left := t.build(start, mid)
+	right := t.build(mid, end)
+	t.nodes[n].left, t.nodes[n].right = left, right
+	return n
+}
+
+// centroid returns the centroid of a triangle.
+func (t *Triangle3) centroid() v3.Vec {
+	return t[0].Add(t[1]).Add(t[2]).DivScalar(3)
+}
+
+// distance2 returns the squared distance from a point to the closest triangle.
+func (t *meshBVH) distance2(p v3.Vec) float64 {
+	d2 := math.Inf(1)
+	var buf [bvhStack]int
+	stack := append(buf[:0], 0)
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if t.bb[n].dist2(p) >= d2 {
+			continue
+		}
+		node := &t.nodes[n]
+		if node.left < 0 {
+			for _, x := range t.info[node.start:node.end] {
+				d2 = math.Min(d2, x.minDistance2(p))
+			}
+			continue
+		}
+		// visit the nearest child first
+		l, r := node.left, node.right
+		if t.bb[r].dist2(p) < t.bb[l].dist2(p) {
+			l, r = r, l
+		}
+		stack = append(stack, r, l)
+	}
+	return d2
+}
+
+// winding returns the approximate generalized winding number of a point.
+func (t *meshBVH) winding(p v3.Vec) float64 {
+	w := 0.0
+	var buf [bvhStack]int
+	stack := append(buf[:0], 0)
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		d := t.center[n].Sub(p)
+		if l := d.Length(); l > windingBeta*t.radius[n] {
+			// far field dipole
+			w += t.normal[n].Dot(d) / (l * l * l)
+			continue
+		}
+		node := &t.nodes[n]
+		if node.left < 0 {
+			for _, x := range t.tri[node.start:node.end] {
+				w += solidAngle(x, p)
+			}
+			continue
+		}
+		stack = append(stack, node.left, node.right)
+	}
+	return w / (4 * Pi)
+}
+
+//-----------------------------------------------------------------------------
@@ -2,6 +2,7 @@ package step
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/deadsy/sdfx/sdf"
 	v3 "github.com/deadsy/sdfx/vec/v3"
@@ -9,23 +10,56 @@ import (
 
 // MeshConverter converts a triangle mesh to STEP BREP entities
 type MeshConverter struct {
-	entities  []Entity
-	idCounter int
+	entities   []Entity
+	idCounter  int
+	lengthUnit string  // short name of the length unit ("" is millimetres)
+	faceColors []int   // palette index of each triangle (nil for no colors)
+	palette    []Color // face colors
 
 	// Cache for deduplication
-	pointCache  map[v3.Vec]int
-	edgeCache   map[edgeKey]int
-	normalCache map[v3.Vec]int
+	pointCache  map[pointKey]int
+	vertexCache map[pointKey]int
+	edgeCache   map[edgeKey]edgeRef
+	normalCache map[pointKey]int
+}
+
+// pointTolerance is the distance below which points are the same point,
+// the uncertainty of the file.
+const pointTolerance = 1e-6
+
+// pointKey is a point quantized to the point tolerance
+type pointKey [3]int64
+
+func newPointKey(p v3.Vec) pointKey {
+	return pointKey{
+		int64(math.Round(p.X / pointTolerance)),
+		int64(math.Round(p.Y / pointTolerance)),
+		int64(math.Round(p.Z / pointTolerance)),
+	}
+}
+
+func (a pointKey) less(b pointKey) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
 }
 
 type edgeKey struct {
-	v1, v2 v3.Vec
+	v1, v2 pointKey
 }
 
-func newEdgeKey(v1, v2 v3.Vec) edgeKey {
+// edgeRef is a cached edge and its start vertex
+type edgeRef struct {
+	id    int
+	start pointKey
+}
+
+func newEdgeKey(v1, v2 pointKey) edgeKey {
 	// Normalize edge key by ordering vertices
-	if v1.X < v2.X || (v1.X == v2.X && v1.Y < v2.Y) ||
-		(v1.X == v2.X && v1.Y == v2.Y && v1.Z < v2.Z) {
+	if v1.less(v2) {
 		return edgeKey{v1, v2}
 	}
 	return edgeKey{v2, v1}
@@ -36,10 +70,57 @@ func NewMeshConverter() *MeshConverter {
 	return &MeshConverter{
 		entities:    make([]Entity, 0),
 		idCounter:   1,
-		pointCache:  make(map[v3.Vec]int),
-		edgeCache:   make(map[edgeKey]int),
-		normalCache: make(map[v3.Vec]int),
+		pointCache:  make(map[pointKey]int),
+		vertexCache: make(map[pointKey]int),
+		edgeCache:   make(map[edgeKey]edgeRef),
+		normalCache: make(map[pointKey]int),
+	}
+}
+
+// lengthUnits are the supported length units: the SI prefix of metres, or
+// the millimetres per unit for a conversion based unit.
+var lengthUnits = map[string]struct {
+	prefix string
+	name   string
+	mm     float64
+}{
+	"mm": {prefix: "MILLI"},
+	"um": {prefix: "MICRO"},
+	"cm": {prefix: "CENTI"},
+	"m":  {},
+	"in": {name: "INCH", mm: 25.4},
+	"ft": {name: "FOOT", mm: 304.8},
+}
+
+// SetLengthUnit sets the length unit of the coordinates ("mm", "um", "cm", "m", "in" or "ft").
+func (c *MeshConverter) SetLengthUnit(unit string) error {
+	if _, ok := lengthUnits[unit]; !ok {
+		return fmt.Errorf("unsupported length unit \"%s\"", unit)
+	}
+	c.lengthUnit = unit
+	return nil
+}
+
+// SetFaceColors sets the colors of the triangles of the mesh: the palette
+// index of each triangle, -1 for none.
+func (c *MeshConverter) SetFaceColors(faceColors []int, palette []Color) {
+	c.faceColors = faceColors
+	c.palette = palette
+}
+
+// addLengthUnit adds the length unit entities and returns the unit ID.
+func (c *MeshConverter) addLengthUnit() int {
+	u, ok := lengthUnits[c.lengthUnit]
+	if !ok {
+		u = lengthUnits["mm"]
 	}
+	if u.name == "" {
+		return c.addEntity(&LengthUnit{Prefix: u.prefix})
+	}
+	mm := c.addEntity(&LengthUnit{Prefix: "MILLI"})
+	dims := c.addEntity(&DimensionalExponents{})
+	conversion := c.addEntity(&LengthMeasureWithUnit{Value: u.mm, Unit: mm})
+	return c.addEntity(&ConversionBasedLengthUnit{Name: u.name, Conversion: conversion, Dimensions: dims})
 }
 
 // addEntity adds an entity and assigns it an ID
@@ -52,12 +133,10 @@ func (c *MeshConverter) addEntity(e Entity) int {
 
 // getOrCreatePoint creates or retrieves a cached CARTESIAN_POINT
 func (c *MeshConverter) getOrCreatePoint(p v3.Vec) int {
-	// Check cache with tolerance
-	const tolerance = 1e-6
-	for cached, id := range c.pointCache {
-		if cached.Equals(p, tolerance) {
-			return id
-		}
+	// Check cache
+	key := newPointKey(p)
+	if id, ok := c.pointCache[key]; ok {
+		return id
 	}
 
 	// Create new point
@@ -66,7 +145,7 @@ func (c *MeshConverter) getOrCreatePoint(p v3.Vec) int {
 		Coordinates: []float64{p.X, p.Y, p.Z},
 	}
 	id := c.addEntity(point)
-	c.pointCache[p] = id
+	c.pointCache[key] = id
 	return id
 }
 
@@ -75,7 +154,8 @@ func (c *MeshConverter) getOrCreateDirection(d v3.Vec) int {
 	d = d.Normalize()
 
 	// Check cache
-	if id, ok := c.normalCache[d]; ok {
+	key := newPointKey(d)
+	if id, ok := c.normalCache[key]; ok {
 		return id
 	}
 
@@ -85,7 +165,7 @@ func (c *MeshConverter) getOrCreateDirection(d v3.Vec) int {
 		DirectionRatios: []float64{d.X, d.Y, d.Z},
 	}
 	id := c.addEntity(dir)
-	c.normalCache[d] = id
+	c.normalCache[key] = id
 	return id
 }
 
@@ -104,27 +184,35 @@ func (c *MeshConverter) createAxis2Placement(origin v3.Vec, zAxis, xAxis v3.Vec)
 	return c.addEntity(placement)
 }
 
-// createVertexPoint creates a VERTEX_POINT
-func (c *MeshConverter) createVertexPoint(p v3.Vec) int {
+// getOrCreateVertexPoint creates or retrieves a cached VERTEX_POINT
+func (c *MeshConverter) getOrCreateVertexPoint(p v3.Vec) int {
+	key := newPointKey(p)
+	if id, ok := c.vertexCache[key]; ok {
+		return id
+	}
 	pointID := c.getOrCreatePoint(p)
 	vertex := &VertexPoint{
 		Name:           "",
 		VertexGeometry: pointID,
 	}
-	return c.addEntity(vertex)
+	id := c.addEntity(vertex)
+	c.vertexCache[key] = id
+	return id
 }
 
-// createEdgeCurve creates an EDGE_CURVE with a LINE
-func (c *MeshConverter) createEdgeCurve(v1, v2 v3.Vec) int {
+// createEdgeCurve creates an EDGE_CURVE with a LINE. It returns the edge ID
+// and true if the edge runs from v1 to v2 (a cached edge may run backwards).
+func (c *MeshConverter) createEdgeCurve(v1, v2 v3.Vec) (int, bool) {
 	// Check cache
-	key := newEdgeKey(v1, v2)
-	if id, ok := c.edgeCache[key]; ok {
-		return id
+	k1 := newPointKey(v1)
+	key := newEdgeKey(k1, newPointKey(v2))
+	if e, ok := c.edgeCache[key]; ok {
+		return e.id, e.start == k1
 	}
 
 	// Create vertices
-	vertex1ID := c.createVertexPoint(v1)
-	vertex2ID := c.createVertexPoint(v2)
+	vertex1ID := c.getOrCreateVertexPoint(v1)
+	vertex2ID := c.getOrCreateVertexPoint(v2)
 
 	// Create line geometry
 	startPointID := c.getOrCreatePoint(v1)
@@ -157,8 +245,14 @@ func (c *MeshConverter) createEdgeCurve(v1, v2 v3.Vec) int {
 	edgeID := c.addEntity(edge)
 
 	// Cache the edge
-	c.edgeCache[key] = edgeID
-	return edgeID
+	c.edgeCache[key] = edgeRef{edgeID, k1}
+	return edgeID, true
+}
+
+// degenerate returns true if the vertices of a triangle are not distinct points.
+func degenerate(t *sdf.Triangle3) bool {
+	k0, k1, k2 := newPointKey(t[0]), newPointKey(t[1]), newPointKey(t[2])
+	return k0 == k1 || k1 == k2 || k2 == k0
 }
 
 // createTriangleFace creates an ADVANCED_FACE from a triangle
@@ -167,29 +261,29 @@ func (c *MeshConverter) createTriangleFace(t *sdf.Triangle3) int {
 	v0, v1, v2 := t[0], t[1], t[2]
 
 	// Create edges for the triangle
-	edge1ID := c.createEdgeCurve(v0, v1)
-	edge2ID := c.createEdgeCurve(v1, v2)
-	edge3ID := c.createEdgeCurve(v2, v0)
+	edge1ID, forward1 := c.createEdgeCurve(v0, v1)
+	edge2ID, forward2 := c.createEdgeCurve(v1, v2)
+	edge3ID, forward3 := c.createEdgeCurve(v2, v0)
 
 	// Create oriented edges
 	orientedEdge1 := &OrientedEdge{
 		Name:        "",
 		EdgeElement: edge1ID,
-		Orientation: true,
+		Orientation: forward1,
 	}
 	oe1ID := c.addEntity(orientedEdge1)
 
 	orientedEdge2 := &OrientedEdge{
 		Name:        "",
 		EdgeElement: edge2ID,
-		Orientation: true,
+		Orientation: forward2,
 	}
 	oe2ID := c.addEntity(orientedEdge2)
 
 	orientedEdge3 := &OrientedEdge{
 		Name:        "",
 		EdgeElement: edge3ID,
-		Orientation: true,
+		Orientation: forward3,
 	}
 	oe3ID := c.addEntity(orientedEdge3)
 
@@ -242,9 +336,10 @@ func (c *MeshConverter) ConvertMesh(mesh []*sdf.Triangle3, name string) []Entity
 	// Reset for new conversion
 	c.entities = make([]Entity, 0)
 	c.idCounter = 1
-	c.pointCache = make(map[v3.Vec]int)
-	c.edgeCache = make(map[edgeKey]int)
-	c.normalCache = make(map[v3.Vec]int)
+	c.pointCache = make(map[pointKey]int)
+	c.vertexCache = make(map[pointKey]int)
+	c.edgeCache = make(map[edgeKey]edgeRef)
+	c.normalCache = make(map[pointKey]int)
 
 	fmt.Println("ConvertMesh: Creating application context...")
 	// Create application context
@@ -254,8 +349,7 @@ func (c *MeshConverter) ConvertMesh(mesh []*sdf.Triangle3, name string) []Entity
 	appContextID := c.addEntity(appContext)
 
 	// Create units
-	lengthUnit := &LengthUnit{}
-	lengthUnitID := c.addEntity(lengthUnit)
+	lengthUnitID := c.addLengthUnit()
 
 	planeAngleUnit := &PlaneAngleUnit{}
 	planeAngleUnitID := c.addEntity(planeAngleUnit)
@@ -327,13 +421,17 @@ func (c *MeshConverter) ConvertMesh(mesh []*sdf.Triangle3, name string) []Entity
 	// Convert triangles to faces
 	fmt.Printf("ConvertMesh: Converting %d triangles to faces...\n", len(mesh))
 	faceIDs := make([]int, 0, len(mesh))
+	var faceColors []int
 	for i, triangle := range mesh {
 		if i%100 == 0 {
 			fmt.Printf("ConvertMesh: Processing triangle %d/%d\n", i, len(mesh))
 		}
-		if !triangle.Degenerate(1e-9) {
+		if !degenerate(triangle) {
 			faceID := c.createTriangleFace(triangle)
 			faceIDs = append(faceIDs, faceID)
+			if i < len(c.faceColors) {
+				faceColors = append(faceColors, c.faceColors[i])
+			}
 		}
 	}
 	fmt.Printf("ConvertMesh: Created %d faces\n", len(faceIDs))
@@ -385,6 +483,10 @@ func (c *MeshConverter) ConvertMesh(mesh []*sdf.Triangle3, name string) []Entity
 	}
 	c.addEntity(shapeDefRep)
 
+	if len(faceColors) == len(faceIDs) && len(faceIDs) != 0 {
+		c.addStyles(faceIDs, faceColors, geomContextID)
+	}
+
 	fmt.Printf("ConvertMesh: Conversion complete with %d entities\n", len(c.entities))
 	return c.entities
 }
@@ -0,0 +1,72 @@
+package step
+
+import (
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+// countEntities returns the number of entities of each type.
+func countEntities(entities []Entity) map[string]int {
+	n := make(map[string]int)
+	for _, e := range entities {
+		switch e.(type) {
+		case *CartesianPoint:
+			n["CARTESIAN_POINT"]++
+		case *VertexPoint:
+			n["VERTEX_POINT"]++
+		case *EdgeCurve:
+			n["EDGE_CURVE"]++
+		case *AdvancedFace:
+			n["ADVANCED_FACE"]++
+		}
+	}
+	return n
+}
+
+func Test_ConvertMesh(t *testing.T) {
+	a := v3.Vec{X: 1, Y: 1, Z: 1}
+	b := v3.Vec{X: 2, Y: 1, Z: 1}
+	c := v3.Vec{X: 1, Y: 2, Z: 1}
+	d := v3.Vec{X: 1, Y: 1, Z: 2}
+	// the vertices of the last face are within the tolerance of the others
+	e := v3.Vec{X: 1 + 1e-8, Y: 1, Z: 1}
+	mesh := []*sdf.Triangle3{
+		{a, c, b},
+		{a, b, d},
+		{a, d, c},
+		{b, c, d.Add(v3.Vec{Z: 2e-7})},
+		// a sliver that collapses to an edge
+		{e, b, b.Add(v3.Vec{Y: 1e-7})},
+	}
+	c0 := NewMeshConverter()
+	n := countEntities(c0.ConvertMesh(mesh, "tetra"))
+	// 4 vertices and the origin point
+	expected := map[string]int{"CARTESIAN_POINT": 5, "VERTEX_POINT": 4, "EDGE_CURVE": 6, "ADVANCED_FACE": 4}
+	for k, v := range expected {
+		if n[k] != v {
+			t.Errorf("expected %d %s entities, got %d", v, k, n[k])
+		}
+	}
+}
+
+func Test_FormatFloat(t *testing.T) {
+	tests := []struct {
+		x float64
+		s string
+	}{
+		{0, "0."},
+		{-1e-9, "0."},
+		{2, "2."},
+		{-10, "-10."},
+		{1.5, "1.5"},
+		{0.1234567, "0.123457"},
+		{-0.000001, "-0.000001"},
+	}
+	for _, test := range tests {
+		if s := formatFloat(test.x); s != test.s {
+			t.Errorf("%g: expected %s, got %s", test.x, test.s, s)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package step
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -285,7 +286,7 @@ type Vector struct {
 }
 
 func (e *Vector) String() string {
-	return fmt.Sprintf("#%d=VECTOR('%s',#%d,%.6f);", e.id, e.Name, e.Orientation, e.Magnitude)
+	return fmt.Sprintf("#%d=VECTOR('%s',#%d,%s);", e.id, e.Name, e.Orientation, formatFloat(e.Magnitude))
 }
 
 // Axis2Placement3D represents AXIS2_PLACEMENT_3D entity
@@ -323,7 +324,7 @@ type Circle struct {
 }
 
 func (e *Circle) String() string {
-	return fmt.Sprintf("#%d=CIRCLE('%s',#%d,%.6f);", e.id, e.Name, e.Position, e.Radius)
+	return fmt.Sprintf("#%d=CIRCLE('%s',#%d,%s);", e.id, e.Name, e.Position, formatFloat(e.Radius))
 }
 
 // Plane represents PLANE entity
@@ -346,8 +347,8 @@ type CylindricalSurface struct {
 }
 
 func (e *CylindricalSurface) String() string {
-	return fmt.Sprintf("#%d=CYLINDRICAL_SURFACE('%s',#%d,%.6f);",
-		e.id, e.Name, e.Position, e.Radius)
+	return fmt.Sprintf("#%d=CYLINDRICAL_SURFACE('%s',#%d,%s);",
+		e.id, e.Name, e.Position, formatFloat(e.Radius))
 }
 
 // ConicalSurface represents CONICAL_SURFACE entity
@@ -360,8 +361,8 @@ type ConicalSurface struct {
 }
 
 func (e *ConicalSurface) String() string {
-	return fmt.Sprintf("#%d=CONICAL_SURFACE('%s',#%d,%.6f,%.6f);",
-		e.id, e.Name, e.Position, e.Radius, e.SemiAngle)
+	return fmt.Sprintf("#%d=CONICAL_SURFACE('%s',#%d,%s,%s);",
+		e.id, e.Name, e.Position, formatFloat(e.Radius), formatFloat(e.SemiAngle))
 }
 
 // SphericalSurface represents SPHERICAL_SURFACE entity
@@ -373,8 +374,8 @@ type SphericalSurface struct {
 }
 
 func (e *SphericalSurface) String() string {
-	return fmt.Sprintf("#%d=SPHERICAL_SURFACE('%s',#%d,%.6f);",
-		e.id, e.Name, e.Position, e.Radius)
+	return fmt.Sprintf("#%d=SPHERICAL_SURFACE('%s',#%d,%s);",
+		e.id, e.Name, e.Position, formatFloat(e.Radius))
 }
 
 // ToroidalSurface represents TOROIDAL_SURFACE entity
@@ -387,8 +388,8 @@ type ToroidalSurface struct {
 }
 
 func (e *ToroidalSurface) String() string {
-	return fmt.Sprintf("#%d=TOROIDAL_SURFACE('%s',#%d,%.6f,%.6f);",
-		e.id, e.Name, e.Position, e.MajorRadius, e.MinorRadius)
+	return fmt.Sprintf("#%d=TOROIDAL_SURFACE('%s',#%d,%s,%s);",
+		e.id, e.Name, e.Position, formatFloat(e.MajorRadius), formatFloat(e.MinorRadius))
 }
 
 // BSplineCurveWithKnots represents B_SPLINE_CURVE_WITH_KNOTS entity
@@ -460,10 +461,47 @@ func (e *UncertaintyMeasureWithUnit) String() string {
 // LengthUnit represents LENGTH_UNIT complex entity
 type LengthUnit struct {
 	BaseEntity
+	Prefix string // SI prefix, e.g. "MILLI" ("" for metres)
 }
 
 func (e *LengthUnit) String() string {
-	return fmt.Sprintf("#%d=(LENGTH_UNIT()\nNAMED_UNIT(*)\nSI_UNIT(.MILLI.,.METRE.));", e.id)
+	prefix := "$"
+	if e.Prefix != "" {
+		prefix = "." + e.Prefix + "."
+	}
+	return fmt.Sprintf("#%d=(LENGTH_UNIT()\nNAMED_UNIT(*)\nSI_UNIT(%s,.METRE.));", e.id, prefix)
+}
+
+// DimensionalExponents represents DIMENSIONAL_EXPONENTS entity (of a length)
+type DimensionalExponents struct {
+	BaseEntity
+}
+
+func (e *DimensionalExponents) String() string {
+	return fmt.Sprintf("#%d=DIMENSIONAL_EXPONENTS(1.,0.,0.,0.,0.,0.,0.);", e.id)
+}
+
+// LengthMeasureWithUnit represents LENGTH_MEASURE_WITH_UNIT entity
+type LengthMeasureWithUnit struct {
+	BaseEntity
+	Value float64
+	Unit  int // ref to UNIT
+}
+
+func (e *LengthMeasureWithUnit) String() string {
+	return fmt.Sprintf("#%d=LENGTH_MEASURE_WITH_UNIT(LENGTH_MEASURE(%s),#%d);", e.id, formatFloats([]float64{e.Value}), e.Unit)
+}
+
+// ConversionBasedLengthUnit represents a CONVERSION_BASED_UNIT LENGTH_UNIT complex entity (e.g. INCH)
+type ConversionBasedLengthUnit struct {
+	BaseEntity
+	Name       string
+	Conversion int // ref to LENGTH_MEASURE_WITH_UNIT
+	Dimensions int // ref to DIMENSIONAL_EXPONENTS
+}
+
+func (e *ConversionBasedLengthUnit) String() string {
+	return fmt.Sprintf("#%d=(CONVERSION_BASED_UNIT('%s',#%d)\nLENGTH_UNIT()\nNAMED_UNIT(#%d));", e.id, e.Name, e.Conversion, e.Dimensions)
 }
 
 // PlaneAngleUnit represents PLANE_ANGLE_UNIT complex entity
@@ -494,10 +532,20 @@ func formatRefs(refs []int) string {
 	return strings.Join(strs, ",")
 }
 
+// formatFloat formats a real with 6 decimal places and no trailing zeros
+// (a STEP real keeps its decimal point, e.g. "2.").
+func formatFloat(val float64) string {
+	s := strings.TrimRight(strconv.FormatFloat(val, 'f', 6, 64), "0")
+	if s == "-0." {
+		return "0."
+	}
+	return s
+}
+
 func formatFloats(vals []float64) string {
 	strs := make([]string, len(vals))
 	for i, val := range vals {
-		strs[i] = fmt.Sprintf("%.6f", val)
+		strs[i] = formatFloat(val)
 	}
 	return strings.Join(strs, ",")
 }
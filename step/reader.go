@@ -0,0 +1,601 @@
+package step
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+// ref is a reference to an entity instance (#n)
+type ref int
+
+// enum is an enumeration value (.NAME.)
+type enum string
+
+// typed is a typed parameter value (e.g. LENGTH_MEASURE(25.4))
+type typed struct {
+	name string
+	args []interface{}
+}
+
+// record is a simple entity, or one part of a complex entity
+type record struct {
+	name string
+	args []interface{}
+}
+
+// instance is an entity instance, with one record for a simple entity
+type instance []record
+
+// get returns the record of an instance with a name
+func (in instance) get(name string) (*record, bool) {
+	for i := range in {
+		if in[i].name == name {
+			return &in[i], true
+		}
+	}
+	return nil, false
+}
+
+// parser parses the records of the data section
+type parser struct {
+	s string
+	i int
+}
+
+func (p *parser) skipSpace() {
+	for p.i < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+func (p *parser) peek() byte {
+	p.skipSpace()
+	if p.i < len(p.s) {
+		return p.s[p.i]
+	}
+	return 0
+}
+
+func (p *parser) expect(c byte) error {
+	if p.peek() != c {
+		return fmt.Errorf("expected '%c' at \"%s\"", c, p.rest())
+	}
+	p.i++
+	return nil
+}
+
+// rest returns the start of the unparsed text for error messages
+func (p *parser) rest() string {
+	r := p.s[p.i:]
+	if len(r) > 20 {
+		r = r[:20] + "..."
+	}
+	return r
+}
+
+// keyword parses an entity or type name
+func (p *parser) keyword() string {
+	p.skipSpace()
+	j := p.i
+	for j < len(p.s) && (p.s[j] == '_' || p.s[j] == '-' || (p.s[j] >= 'A' && p.s[j] <= 'Z') || (p.s[j] >= 'a' && p.s[j] <= 'z') || (p.s[j] >= '0' && p.s[j] <= '9')) {
+		j++
+	}
+	k := p.s[p.i:j]
+	p.i = j
+	return strings.ToUpper(k)
+}
+
+// list parses a parenthesized parameter list
+func (p *parser) list() ([]interface{}, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var args []interface{}
+	if p.peek() == ')' {
+		p.i++
+		return args, nil
+	}
+	for {
+		x, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, x)
+		switch p.peek() {
+		case ',':
+			p.i++
+		case ')':
+			p.i++
+			return args, nil
+		default:
+			return nil, fmt.Errorf("expected ',' or ')' at \"%s\"", p.rest())
+		}
+	}
+}
+
+// value parses a parameter value
+func (p *parser) value() (interface{}, error) {
+	c := p.peek()
+	switch {
+	case c == '$' || c == '*':
+		p.i++
+		return nil, nil
+	case c == '(':
+		return p.list()
+	case c == '#':
+		p.i++
+		j := p.i
+		for j < len(p.s) && p.s[j] >= '0' && p.s[j] <= '9' {
+			j++
+		}
+		n, err := strconv.Atoi(p.s[p.i:j])
+		if err != nil {
+			return nil, fmt.Errorf("bad reference at \"%s\"", p.rest())
+		}
+		p.i = j
+		return ref(n), nil
+	case c == '\'':
+		var sb strings.Builder
+		for p.i++; p.i < len(p.s); p.i++ {
+			if p.s[p.i] == '\'' {
+				if p.i+1 < len(p.s) && p.s[p.i+1] == '\'' {
+					sb.WriteByte('\'')
+					p.i++
+					continue
+				}
+				p.i++
+				return sb.String(), nil
+			}
+			sb.WriteByte(p.s[p.i])
+		}
+		return nil, fmt.Errorf("unterminated string")
+	case c == '.':
+		j := strings.IndexByte(p.s[p.i+1:], '.')
+		if j < 0 {
+			return nil, fmt.Errorf("unterminated enumeration")
+		}
+		e := enum(p.s[p.i+1 : p.i+1+j])
+		p.i += j + 2
+		return e, nil
+	case c == '"':
+		// binary, not used by geometry
+		j := strings.IndexByte(p.s[p.i+1:], '"')
+		if j < 0 {
+			return nil, fmt.Errorf("unterminated binary")
+		}
+		p.i += j + 2
+		return nil, nil
+	case c == '-' || c == '+' || (c >= '0' && c <= '9'):
+		j := p.i + 1
+		for j < len(p.s) && strings.IndexByte("0123456789.eE+-", p.s[j]) >= 0 {
+			j++
+		}
+		x, err := strconv.ParseFloat(p.s[p.i:j], 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number \"%s\"", p.s[p.i:j])
+		}
+		p.i = j
+		return x, nil
+	default:
+		name := p.keyword()
+		if name == "" {
+			return nil, fmt.Errorf("unexpected \"%s\"", p.rest())
+		}
+		args, err := p.list()
+		if err != nil {
+			return nil, err
+		}
+		return typed{name, args}, nil
+	}
+}
+
+// instance parses the right hand side of an instance (a simple or complex entity)
+func (p *parser) instance() (instance, error) {
+	if p.peek() != '(' {
+		name := p.keyword()
+		args, err := p.list()
+		if err != nil {
+			return nil, err
+		}
+		return instance{{name, args}}, nil
+	}
+	p.i++
+	var in instance
+	for p.peek() != ')' {
+		name := p.keyword()
+		if name == "" {
+			return nil, fmt.Errorf("expected an entity name at \"%s\"", p.rest())
+		}
+		args, err := p.list()
+		if err != nil {
+			return nil, err
+		}
+		in = append(in, record{name, args})
+	}
+	p.i++
+	return in, nil
+}
+
+// statements splits the data section of a STEP file into statements (without the ';')
+func statements(r io.Reader) ([]string, error) {
+	br := bufio.NewReader(r)
+	var stmts []string
+	var sb strings.Builder
+	inString, inComment, inData := false, false, false
+	var prev byte
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case inComment:
+			if prev == '*' && c == '/' {
+				inComment = false
+				c = 0
+			}
+		case inString:
+			sb.WriteByte(c)
+			if c == '\'' {
+				inString = false
+			}
+		case c == '*' && prev == '/':
+			// drop the '/' of the comment start
+			s := sb.String()
+			sb.Reset()
+			sb.WriteString(s[:len(s)-1])
+			inComment = true
+			c = 0
+		case c == '\'':
+			sb.WriteByte(c)
+			inString = true
+		case c == ';':
+			s := strings.TrimSpace(sb.String())
+			sb.Reset()
+			switch {
+			case s == "DATA":
+				inData = true
+			case s == "ENDSEC":
+				inData = false
+			case inData:
+				stmts = append(stmts, s)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+		prev = c
+	}
+	return stmts, nil
+}
+
+// readInstances reads the entity instances of a STEP file
+func readInstances(r io.Reader) (map[ref]instance, error) {
+	stmts, err := statements(r)
+	if err != nil {
+		return nil, err
+	}
+	instances := make(map[ref]instance, len(stmts))
+	for _, s := range stmts {
+		p := &parser{s: s}
+		id, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		n, ok := id.(ref)
+		if !ok {
+			return nil, fmt.Errorf("expected an instance name at \"%s\"", p.rest())
+		}
+		if err := p.expect('='); err != nil {
+			return nil, err
+		}
+		in, err := p.instance()
+		if err != nil {
+			return nil, fmt.Errorf("#%d: %w", n, err)
+		}
+		instances[n] = in
+	}
+	return instances, nil
+}
+
+// meshReader converts the faces of a faceted BREP to triangles
+type meshReader struct {
+	instances map[ref]instance
+}
+
+// arg returns an argument of a named record of an instance
+func (m *meshReader) arg(r ref, name string, i int) (interface{}, error) {
+	in, ok := m.instances[r]
+	if !ok {
+		return nil, fmt.Errorf("#%d is not defined", r)
+	}
+	rec, ok := in.get(name)
+	if !ok {
+		return nil, fmt.Errorf("#%d is not a %s", r, name)
+	}
+	if i >= len(rec.args) {
+		return nil, fmt.Errorf("#%d: %s has too few parameters", r, name)
+	}
+	return rec.args[i], nil
+}
+
+// refArg returns a reference argument of a named record of an instance
+func (m *meshReader) refArg(r ref, name string, i int) (ref, error) {
+	x, err := m.arg(r, name, i)
+	if err != nil {
+		return 0, err
+	}
+	x1, ok := x.(ref)
+	if !ok {
+		return 0, fmt.Errorf("#%d: %s parameter %d is not a reference", r, name, i)
+	}
+	return x1, nil
+}
+
+// boolArg returns a logical argument of a named record of an instance
+func (m *meshReader) boolArg(r ref, name string, i int) (bool, error) {
+	x, err := m.arg(r, name, i)
+	if err != nil {
+		return false, err
+	}
+	return x != enum("F"), nil
+}
+
+// name returns the entity name of a simple instance
+func (m *meshReader) name(r ref) string {
+	if in, ok := m.instances[r]; ok && len(in) == 1 {
+		return in[0].name
+	}
+	return ""
+}
+
+// point returns the coordinates of a CARTESIAN_POINT
+func (m *meshReader) point(r ref) (v3.Vec, error) {
+	x, err := m.arg(r, "CARTESIAN_POINT", 1)
+	if err != nil {
+		return v3.Vec{}, err
+	}
+	c, ok := x.([]interface{})
+	if !ok || len(c) < 2 {
+		return v3.Vec{}, fmt.Errorf("#%d: bad coordinates", r)
+	}
+	var p [3]float64
+	for i := range c {
+		if i < 3 {
+			p[i], ok = c[i].(float64)
+			if !ok {
+				return v3.Vec{}, fmt.Errorf("#%d: bad coordinates", r)
+			}
+		}
+	}
+	return v3.Vec{X: p[0], Y: p[1], Z: p[2]}, nil
+}
+
+// vertex returns the point of a VERTEX_POINT
+func (m *meshReader) vertex(r ref) (v3.Vec, error) {
+	p, err := m.refArg(r, "VERTEX_POINT", 1)
+	if err != nil {
+		return v3.Vec{}, err
+	}
+	return m.point(p)
+}
+
+// loop returns the vertices of an EDGE_LOOP or POLY_LOOP
+func (m *meshReader) loop(r ref) ([]v3.Vec, error) {
+	var v []v3.Vec
+	switch m.name(r) {
+	case "POLY_LOOP":
+		x, _ := m.arg(r, "POLY_LOOP", 1)
+		points, _ := x.([]interface{})
+		for _, x := range points {
+			pr, _ := x.(ref)
+			p, err := m.point(pr)
+			if err != nil {
+				return nil, err
+			}
+			v = append(v, p)
+		}
+	case "EDGE_LOOP":
+		x, _ := m.arg(r, "EDGE_LOOP", 1)
+		edges, _ := x.([]interface{})
+		for _, x := range edges {
+			oe, _ := x.(ref)
+			e, err := m.refArg(oe, "ORIENTED_EDGE", 3)
+			if err != nil {
+				return nil, err
+			}
+			forward, err := m.boolArg(oe, "ORIENTED_EDGE", 4)
+			if err != nil {
+				return nil, err
+			}
+			// the loop vertices are the start vertices of the oriented edges
+			i := 1
+			if !forward {
+				i = 2
+			}
+			vr, err := m.refArg(e, "EDGE_CURVE", i)
+			if err != nil {
+				return nil, err
+			}
+			p, err := m.vertex(vr)
+			if err != nil {
+				return nil, err
+			}
+			v = append(v, p)
+		}
+	default:
+		return nil, fmt.Errorf("#%d: unsupported loop type %s", r, m.name(r))
+	}
+	if len(v) < 3 {
+		return nil, fmt.Errorf("#%d: loop has less than 3 vertices", r)
+	}
+	return v, nil
+}
+
+// face returns the triangles of a planar face
+func (m *meshReader) face(r ref, name string) ([]*sdf.Triangle3, error) {
+	surface, err := m.refArg(r, name, 2)
+	if err != nil {
+		return nil, err
+	}
+	if m.name(surface) != "PLANE" {
+		return nil, fmt.Errorf("#%d: unsupported face surface %s (only planar faces can be read)", r, m.name(surface))
+	}
+	sameSense, err := m.boolArg(r, name, 3)
+	if err != nil {
+		return nil, err
+	}
+	x, _ := m.arg(r, name, 1)
+	bounds, _ := x.([]interface{})
+	if len(bounds) != 1 {
+		return nil, fmt.Errorf("#%d: faces with holes are not supported", r)
+	}
+	b, _ := bounds[0].(ref)
+	boundName := m.name(b)
+	if boundName != "FACE_OUTER_BOUND" && boundName != "FACE_BOUND" {
+		return nil, fmt.Errorf("#%d: unsupported face bound %s", r, boundName)
+	}
+	l, err := m.refArg(b, boundName, 1)
+	if err != nil {
+		return nil, err
+	}
+	orientation, err := m.boolArg(b, boundName, 2)
+	if err != nil {
+		return nil, err
+	}
+	v, err := m.loop(l)
+	if err != nil {
+		return nil, err
+	}
+	if orientation != sameSense {
+		for i, j := 0, len(v)-1; i < j; i, j = i+1, j-1 {
+			v[i], v[j] = v[j], v[i]
+		}
+	}
+	// fan triangulation, the faces of a faceted BREP are convex
+	t := make([]*sdf.Triangle3, 0, len(v)-2)
+	for i := 1; i < len(v)-1; i++ {
+		t = append(t, &sdf.Triangle3{v[0], v[i], v[i+1]})
+	}
+	return t, nil
+}
+
+// lengthScale returns the number of millimetres per length unit of a unit instance
+func (m *meshReader) lengthScale(r ref) (float64, error) {
+	in, ok := m.instances[r]
+	if !ok {
+		return 0, fmt.Errorf("#%d is not defined", r)
+	}
+	if rec, ok := in.get("SI_UNIT"); ok && len(rec.args) == 2 {
+		prefixes := map[enum]float64{"MILLI": 1, "MICRO": 1e-3, "CENTI": 10, "DECI": 100, "KILO": 1e6}
+		if rec.args[0] == nil {
+			return 1000, nil
+		}
+		if k, ok := prefixes[rec.args[0].(enum)]; ok {
+			return k, nil
+		}
+		return 0, fmt.Errorf("#%d: unsupported SI prefix %v", r, rec.args[0])
+	}
+	if rec, ok := in.get("CONVERSION_BASED_UNIT"); ok && len(rec.args) == 2 {
+		c, _ := rec.args[1].(ref)
+		x, err := m.arg(c, "LENGTH_MEASURE_WITH_UNIT", 0)
+		if err != nil {
+			return 0, err
+		}
+		var value float64
+		switch x := x.(type) {
+		case float64:
+			value = x
+		case typed:
+			if len(x.args) == 1 {
+				value, _ = x.args[0].(float64)
+			}
+		}
+		u, err := m.refArg(c, "LENGTH_MEASURE_WITH_UNIT", 1)
+		if err != nil {
+			return 0, err
+		}
+		k, err := m.lengthScale(u)
+		if err != nil {
+			return 0, err
+		}
+		return value * k, nil
+	}
+	return 0, fmt.Errorf("#%d: unsupported length unit", r)
+}
+
+// scale returns the number of millimetres per model length unit (1 if there's no length unit)
+func (m *meshReader) scale() (float64, error) {
+	for _, in := range m.instances {
+		rec, ok := in.get("GLOBAL_UNIT_ASSIGNED_CONTEXT")
+		if !ok || len(rec.args) != 1 {
+			continue
+		}
+		units, _ := rec.args[0].([]interface{})
+		for _, x := range units {
+			u, _ := x.(ref)
+			if un, ok := m.instances[u]; ok {
+				if _, ok := un.get("LENGTH_UNIT"); ok {
+					return m.lengthScale(u)
+				}
+			}
+		}
+	}
+	return 1, nil
+}
+
+// ReadMesh reads the planar faces of a STEP file (e.g. a faceted BREP as
+// written by Writer) as a triangle mesh in millimetres. Faces on other
+// surfaces and faces with holes are not supported.
+func ReadMesh(r io.Reader) ([]*sdf.Triangle3, error) {
+	instances, err := readInstances(r)
+	if err != nil {
+		return nil, err
+	}
+	m := &meshReader{instances: instances}
+	k, err := m.scale()
+	if err != nil {
+		return nil, err
+	}
+	// faces in instance order, so the mesh doesn't depend on map order
+	var faces []ref
+	for r, in := range instances {
+		if len(in) == 1 && (in[0].name == "ADVANCED_FACE" || in[0].name == "FACE_SURFACE") {
+			faces = append(faces, r)
+		}
+	}
+	sortRefs(faces)
+	var mesh []*sdf.Triangle3
+	for _, r := range faces {
+		t, err := m.face(r, m.name(r))
+		if err != nil {
+			return nil, err
+		}
+		mesh = append(mesh, t...)
+	}
+	if k != 1 {
+		for _, t := range mesh {
+			for i := range t {
+				t[i] = t[i].MulScalar(k)
+			}
+		}
+	}
+	return mesh, nil
+}
+
+// sortRefs sorts a list of references
+func sortRefs(r []ref) {
+	for i := 1; i < len(r); i++ {
+		for j := i; j > 0 && r[j] < r[j-1]; j-- {
+			r[j], r[j-1] = r[j-1], r[j]
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package step
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Color is a named display color with components in [0,1]
+type Color struct {
+	Name    string
+	R, G, B float64
+}
+
+// ColourRGB represents COLOUR_RGB entity
+type ColourRGB struct {
+	BaseEntity
+	Name    string
+	R, G, B float64
+}
+
+func (e *ColourRGB) String() string {
+	return fmt.Sprintf("#%d=COLOUR_RGB('%s',%s);", e.id, strings.ReplaceAll(e.Name, "'", "''"), formatFloats([]float64{e.R, e.G, e.B}))
+}
+
+// FillAreaStyleColour represents FILL_AREA_STYLE_COLOUR entity
+type FillAreaStyleColour struct {
+	BaseEntity
+	Colour int // ref to COLOUR_RGB
+}
+
+func (e *FillAreaStyleColour) String() string {
+	return fmt.Sprintf("#%d=FILL_AREA_STYLE_COLOUR('',#%d);", e.id, e.Colour)
+}
+
+// FillAreaStyle represents FILL_AREA_STYLE entity
+type FillAreaStyle struct {
+	BaseEntity
+	Styles []int
+}
+
+func (e *FillAreaStyle) String() string {
+	return fmt.Sprintf("#%d=FILL_AREA_STYLE('',(%s));", e.id, formatRefs(e.Styles))
+}
+
+// SurfaceStyleFillArea represents SURFACE_STYLE_FILL_AREA entity
+type SurfaceStyleFillArea struct {
+	BaseEntity
+	FillArea int // ref to FILL_AREA_STYLE
+}
+
+func (e *SurfaceStyleFillArea) String() string {
+	return fmt.Sprintf("#%d=SURFACE_STYLE_FILL_AREA(#%d);", e.id, e.FillArea)
+}
+
+// SurfaceSideStyle represents SURFACE_SIDE_STYLE entity
+type SurfaceSideStyle struct {
+	BaseEntity
+	Styles []int
+}
+
+func (e *SurfaceSideStyle) String() string {
+	return fmt.Sprintf("#%d=SURFACE_SIDE_STYLE('',(%s));", e.id, formatRefs(e.Styles))
+}
+
+// SurfaceStyleUsage represents SURFACE_STYLE_USAGE entity
+type SurfaceStyleUsage struct {
+	BaseEntity
+	Style int // ref to SURFACE_SIDE_STYLE
+}
+
+func (e *SurfaceStyleUsage) String() string {
+	return fmt.Sprintf("#%d=SURFACE_STYLE_USAGE(.BOTH.,#%d);", e.id, e.Style)
+}
+
+// PresentationStyleAssignment represents PRESENTATION_STYLE_ASSIGNMENT entity
+type PresentationStyleAssignment struct {
+	BaseEntity
+	Styles []int
+}
+
+func (e *PresentationStyleAssignment) String() string {
+	return fmt.Sprintf("#%d=PRESENTATION_STYLE_ASSIGNMENT((%s));", e.id, formatRefs(e.Styles))
+}
+
+// StyledItem represents STYLED_ITEM entity
+type StyledItem struct {
+	BaseEntity
+	Name   string
+	Styles []int
+	Item   int // ref to the styled item (e.g. ADVANCED_FACE)
+}
+
+func (e *StyledItem) String() string {
+	return fmt.Sprintf("#%d=STYLED_ITEM('%s',(%s),#%d);", e.id, e.Name, formatRefs(e.Styles), e.Item)
+}
+
+// MechanicalDesignGeometricPresentationRepresentation represents
+// MECHANICAL_DESIGN_GEOMETRIC_PRESENTATION_REPRESENTATION entity
+type MechanicalDesignGeometricPresentationRepresentation struct {
+	BaseEntity
+	Items          []int
+	ContextOfItems int
+}
+
+func (e *MechanicalDesignGeometricPresentationRepresentation) String() string {
+	return fmt.Sprintf("#%d=MECHANICAL_DESIGN_GEOMETRIC_PRESENTATION_REPRESENTATION('',(%s),#%d);",
+		e.id, formatRefs(e.Items), e.ContextOfItems)
+}
+
+// addStyles adds the styled items for the colored faces: one style per color
+// and a styled item per face. faceColors is the palette index of each face
+// (-1 for none).
+func (c *MeshConverter) addStyles(faceIDs, faceColors []int, contextID int) {
+	styles := make([]int, len(c.palette))
+	for i := range styles {
+		styles[i] = -1
+	}
+	var items []int
+	for i, face := range faceIDs {
+		k := faceColors[i]
+		if k < 0 || k >= len(c.palette) {
+			continue
+		}
+		if styles[k] < 0 {
+			col := c.palette[k]
+			colour := c.addEntity(&ColourRGB{Name: col.Name, R: col.R, G: col.G, B: col.B})
+			fill := c.addEntity(&FillAreaStyleColour{Colour: colour})
+			area := c.addEntity(&FillAreaStyle{Styles: []int{fill}})
+			surface := c.addEntity(&SurfaceStyleFillArea{FillArea: area})
+			side := c.addEntity(&SurfaceSideStyle{Styles: []int{surface}})
+			usage := c.addEntity(&SurfaceStyleUsage{Style: side})
+			styles[k] = c.addEntity(&PresentationStyleAssignment{Styles: []int{usage}})
+		}
+		items = append(items, c.addEntity(&StyledItem{Name: "color", Styles: []int{styles[k]}, Item: face}))
+	}
+	if len(items) != 0 {
+		c.addEntity(&MechanicalDesignGeometricPresentationRepresentation{Items: items, ContextOfItems: contextID})
+	}
+}
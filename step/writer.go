@@ -3,6 +3,7 @@ package step
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,12 +15,16 @@ import (
 
 // Writer handles STEP file generation
 type Writer struct {
-	file       *os.File
-	writer     *bufio.Writer
-	converter  *MeshConverter
-	fileName   string
-	authorName string
-	orgName    string
+	file        io.Closer
+	writer      *bufio.Writer
+	converter   *MeshConverter
+	fileName    string
+	authorName  string
+	orgName     string
+	description string
+	timestamp   time.Time
+	faceColors  []int
+	palette     []Color
 }
 
 // NewWriter creates a new STEP writer
@@ -29,14 +34,21 @@ func NewWriter(path string) (*Writer, error) {
 		return nil, err
 	}
 
+	w := NewWriterFor(file, filepath.Base(path))
+	w.file = file
+	return w, nil
+}
+
+// NewWriterFor creates a new STEP writer that writes to an io.Writer.
+// The file name is the name in the file header.
+func NewWriterFor(out io.Writer, fileName string) *Writer {
 	return &Writer{
-		file:       file,
-		writer:     bufio.NewWriter(file),
+		writer:     bufio.NewWriter(out),
 		converter:  NewMeshConverter(),
-		fileName:   filepath.Base(path),
+		fileName:   fileName,
 		authorName: "sdfx User",
 		orgName:    "sdfx Organization",
-	}, nil
+	}
 }
 
 // SetAuthor sets the author information
@@ -45,9 +57,35 @@ func (w *Writer) SetAuthor(name, org string) {
 	w.orgName = org
 }
 
+// SetDescription adds a line of text (e.g. a model fingerprint) to the file description.
+func (w *Writer) SetDescription(s string) {
+	w.description = s
+}
+
+// SetTime sets the time stamp of the file header (the default is the current time).
+func (w *Writer) SetTime(t time.Time) {
+	w.timestamp = t
+}
+
+// SetFaceColors sets the colors of the triangles written by WriteMesh: the
+// palette index of each triangle, -1 for none.
+func (w *Writer) SetFaceColors(faceColors []int, palette []Color) {
+	w.faceColors = faceColors
+	w.palette = palette
+}
+
+// SetLengthUnit sets the length unit of the mesh coordinates ("mm", "um", "cm", "m", "in" or "ft").
+func (w *Writer) SetLengthUnit(unit string) error {
+	return w.converter.SetLengthUnit(unit)
+}
+
 // Close closes the writer and flushes any remaining data
 func (w *Writer) Close() error {
-	if err := w.writer.Flush(); err != nil {
+	err := w.writer.Flush()
+	if w.file == nil {
+		return err
+	}
+	if err != nil {
 		w.file.Close()
 		return err
 	}
@@ -56,13 +94,21 @@ func (w *Writer) Close() error {
 
 // writeHeader writes the STEP file header
 func (w *Writer) writeHeader() error {
+	description := "'STEP AP214'"
+	if w.description != "" {
+		description += ",'" + strings.ReplaceAll(w.description, "'", "''") + "'"
+	}
+	timestamp := w.timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
 	header := []string{
 		"ISO-10303-21;",
 		"HEADER;",
-		"FILE_DESCRIPTION(('STEP AP214'),'1');",
+		fmt.Sprintf("FILE_DESCRIPTION((%s),'1');", description),
 		fmt.Sprintf("FILE_NAME('%s','%s',('%s'),('%s'),'sdfx STEP Writer','sdfx','');",
 			w.fileName,
-			time.Now().Format("2006-01-02T15:04:05"),
+			timestamp.Format("2006-01-02T15:04:05"),
 			w.authorName,
 			w.orgName),
 		"FILE_SCHEMA(('AUTOMOTIVE_DESIGN'));",
@@ -127,7 +173,23 @@ func (w *Writer) WriteMesh(mesh []*sdf.Triangle3, name string) error {
 	fmt.Printf("WriteMesh: Starting with %d triangles\n", len(mesh))
 
 	// Optimize mesh
-	optimizedMesh := OptimizeMesh(mesh)
+	var optimizedMesh []*sdf.Triangle3
+	if w.faceColors == nil {
+		optimizedMesh = OptimizeMesh(mesh)
+	} else {
+		if len(w.faceColors) != len(mesh) {
+			return fmt.Errorf("%d face colors for %d triangles", len(w.faceColors), len(mesh))
+		}
+		// keep the colors of the remaining triangles
+		var colors []int
+		for i, t := range mesh {
+			if !t.Degenerate(1e-9) {
+				optimizedMesh = append(optimizedMesh, t)
+				colors = append(colors, w.faceColors[i])
+			}
+		}
+		w.converter.SetFaceColors(colors, w.palette)
+	}
 	fmt.Printf("WriteMesh: Optimized to %d triangles\n", len(optimizedMesh))
 
 	// Convert mesh to STEP entities
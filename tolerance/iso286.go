@@ -0,0 +1,312 @@
+//-----------------------------------------------------------------------------
+/*
+
+ISO 286 Limits and Fits
+
+Tolerance classes are a fundamental deviation letter and a standard
+tolerance grade, e.g. "H7" (hole) or "g6" (shaft). A fit is a hole and
+shaft class for the same nominal size, e.g. "H7/g6".
+
+Standard tolerance grades IT1 to IT16 are tabulated for sizes up to 500 mm.
+Fundamental deviations are computed from the ISO 286-1 formulas, so they
+may differ from the rounded values in the standard tables by a micron or so.
+
+Supported deviations:
+holes: D E F G H JS K M N P R S
+shafts: d e f g h js k m n p r s
+
+*/
+//-----------------------------------------------------------------------------
+
+package tolerance
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// sizeRanges are the upper limits of the nominal size ranges (mm).
+var sizeRanges = []float64{3, 6, 10, 18, 30, 50, 80, 120, 180, 250, 315, 400, 500}
+
+// itTable are the standard tolerance grades IT1..IT16 (microns) for each size range.
+var itTable = [][]float64{
+	{0.8, 1, 1, 1.2, 1.5, 1.5, 2, 2.5, 3.5, 4.5, 6, 7, 8},
+	{1.2, 1.5, 1.5, 2, 2.5, 2.5, 3, 4, 5, 7, 8, 9, 10},
+	{2, 2.5, 2.5, 3, 4, 4, 5, 6, 8, 10, 12, 13, 15},
+	{3, 4, 4, 5, 6, 7, 8, 10, 12, 14, 16, 18, 20},
+	{4, 5, 6, 8, 9, 11, 13, 15, 18, 20, 23, 25, 27},
+	{6, 8, 9, 11, 13, 16, 19, 22, 25, 29, 32, 36, 40},
+	{10, 12, 15, 18, 21, 25, 30, 35, 40, 46, 52, 57, 63},
+	{14, 18, 22, 27, 33, 39, 46, 54, 63, 72, 81, 89, 97},
+	{25, 30, 36, 43, 52, 62, 74, 87, 100, 115, 130, 140, 155},
+	{40, 48, 58, 70, 84, 100, 120, 140, 160, 185, 210, 230, 250},
+	{60, 75, 90, 110, 130, 160, 190, 220, 250, 290, 320, 360, 400},
+	{100, 120, 150, 180, 210, 250, 300, 350, 400, 460, 520, 570, 630},
+	{140, 180, 220, 270, 330, 390, 460, 540, 630, 720, 810, 890, 970},
+	{250, 300, 360, 430, 520, 620, 740, 870, 1000, 1150, 1300, 1400, 1550},
+	{400, 480, 580, 700, 840, 1000, 1200, 1400, 1600, 1850, 2100, 2300, 2500},
+	{600, 750, 900, 1100, 1300, 1600, 1900, 2200, 2500, 2900, 3200, 3600, 4000},
+}
+
+// sizeRange returns the size range index for a nominal size.
+func sizeRange(nominal float64) (int, error) {
+	if nominal <= 0 {
+		return 0, sdf.ErrMsg("nominal size <= 0")
+	}
+	for i, x := range sizeRanges {
+		if nominal <= x {
+			return i, nil
+		}
+	}
+	return 0, sdf.ErrMsg("nominal size > 500 mm")
+}
+
+// meanSize returns the geometric mean of a size range (used by the deviation formulas).
+func meanSize(r int) float64 {
+	lo := 1.0
+	if r > 0 {
+		lo = sizeRanges[r-1]
+	}
+	return math.Sqrt(lo * sizeRanges[r])
+}
+
+// it returns the standard tolerance (microns) for a grade and size range.
+func it(grade, r int) float64 {
+	return itTable[grade-1][r]
+}
+
+// ITGrade returns the standard tolerance (mm) for a grade (1..16) and nominal size.
+func ITGrade(grade int, nominal float64) (float64, error) {
+	if grade < 1 || grade > len(itTable) {
+		return 0, sdf.ErrMsg(fmt.Sprintf("bad tolerance grade IT%d", grade))
+	}
+	r, err := sizeRange(nominal)
+	if err != nil {
+		return 0, err
+	}
+	return it(grade, r) * 1e-3, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// shaftDeviation returns the fundamental deviation (microns) for a shaft.
+// For a-h this is the upper deviation (es), for k-zc it is the lower deviation (ei).
+func shaftDeviation(letter string, grade, r int) (float64, error) {
+	D := meanSize(r)
+	switch letter {
+	case "d":
+		return -math.Round(16 * math.Pow(D, 0.44)), nil
+	case "e":
+		return -math.Round(11 * math.Pow(D, 0.41)), nil
+	case "f":
+		return -math.Round(5.5 * math.Pow(D, 0.41)), nil
+	case "g":
+		return -math.Round(2.5 * math.Pow(D, 0.34)), nil
+	case "h":
+		return 0, nil
+	case "k":
+		if grade >= 4 && grade <= 7 {
+			return math.Round(0.6 * math.Cbrt(D)), nil
+		}
+		return 0, nil
+	case "m":
+		return it(7, r) - it(6, r), nil
+	case "n":
+		return math.Round(5 * math.Pow(D, 0.34)), nil
+	case "p":
+		return it(7, r), nil
+	case "r":
+		p := it(7, r)
+		s, _ := shaftDeviation("s", grade, r)
+		return math.Round(math.Sqrt(p * s)), nil
+	case "s":
+		if D <= 50 {
+			return it(8, r) + 1, nil
+		}
+		return math.Round(it(7, r) + 0.4*D), nil
+	}
+	return 0, sdf.ErrMsg(fmt.Sprintf("unsupported shaft deviation \"%s\"", letter))
+}
+
+// parseClass splits a tolerance class into a deviation letter and a grade.
+func parseClass(class string) (string, int, error) {
+	i := strings.IndexAny(class, "0123456789")
+	if i <= 0 {
+		return "", 0, sdf.ErrMsg(fmt.Sprintf("bad tolerance class \"%s\"", class))
+	}
+	grade, err := strconv.Atoi(class[i:])
+	if err != nil || grade < 1 || grade > len(itTable) {
+		return "", 0, sdf.ErrMsg(fmt.Sprintf("bad tolerance class \"%s\"", class))
+	}
+	return class[:i], grade, nil
+}
+
+// Shaft returns the dimension of a shaft (external feature) for a nominal
+// size and ISO 286 tolerance class, e.g. Shaft(10, "g6").
+func Shaft(nominal float64, class string) (Dimension, error) {
+	letter, grade, err := parseClass(class)
+	if err != nil {
+		return Dimension{}, err
+	}
+	if letter != strings.ToLower(letter) {
+		return Dimension{}, sdf.ErrMsg(fmt.Sprintf("shaft class \"%s\" must be lower case", class))
+	}
+	r, err := sizeRange(nominal)
+	if err != nil {
+		return Dimension{}, err
+	}
+	t := it(grade, r)
+	if letter == "js" {
+		return Sym(nominal, 0.5*t*1e-3), nil
+	}
+	dev, err := shaftDeviation(letter, grade, r)
+	if err != nil {
+		return Dimension{}, err
+	}
+	if letter <= "h" {
+		// upper deviation
+		return New(nominal, dev*1e-3, (dev-t)*1e-3), nil
+	}
+	// lower deviation
+	return New(nominal, (dev+t)*1e-3, dev*1e-3), nil
+}
+
+// Hole returns the dimension of a hole (internal feature) for a nominal
+// size and ISO 286 tolerance class, e.g. Hole(10, "H7").
+func Hole(nominal float64, class string) (Dimension, error) {
+	letter, grade, err := parseClass(class)
+	if err != nil {
+		return Dimension{}, err
+	}
+	if letter != strings.ToUpper(letter) {
+		return Dimension{}, sdf.ErrMsg(fmt.Sprintf("hole class \"%s\" must be upper case", class))
+	}
+	r, err := sizeRange(nominal)
+	if err != nil {
+		return Dimension{}, err
+	}
+	t := it(grade, r)
+	if letter == "JS" {
+		return Sym(nominal, 0.5*t*1e-3), nil
+	}
+	dev, err := shaftDeviation(strings.ToLower(letter), grade, r)
+	if err != nil {
+		return Dimension{}, err
+	}
+	if letter <= "H" {
+		// lower deviation (EI = -es)
+		return New(nominal, (t-dev)*1e-3, -dev*1e-3), nil
+	}
+	// upper deviation (ES = -ei), with the delta correction for the finer grades
+	es := -dev
+	delta := 0.0
+	if grade > 1 {
+		delta = t - it(grade-1, r)
+	}
+	switch letter {
+	case "K", "N":
+		if grade <= 8 {
+			es += delta
+		} else {
+			es = 0
+		}
+	case "M":
+		if grade <= 8 {
+			es += delta
+		}
+	default:
+		if grade <= 7 {
+			es += delta
+		}
+	}
+	return New(nominal, es*1e-3, (es-t)*1e-3), nil
+}
+
+//-----------------------------------------------------------------------------
+
+// FitKind is the type of a fit.
+type FitKind int
+
+const (
+	// Clearance fits always have a gap between hole and shaft.
+	Clearance FitKind = iota
+	// Transition fits may have a gap or an interference.
+	Transition
+	// Interference fits always have an interference between hole and shaft.
+	Interference
+)
+
+func (k FitKind) String() string {
+	switch k {
+	case Clearance:
+		return "clearance"
+	case Transition:
+		return "transition"
+	}
+	return "interference"
+}
+
+// Fit is a mating hole and shaft.
+type Fit struct {
+	Hole, Shaft Dimension
+}
+
+// NewFit returns an ISO 286 fit for a nominal size, e.g. NewFit(10, "H7/g6").
+func NewFit(nominal float64, fit string) (*Fit, error) {
+	classes := strings.Split(fit, "/")
+	if len(classes) != 2 {
+		return nil, sdf.ErrMsg(fmt.Sprintf("bad fit \"%s\"", fit))
+	}
+	hole, err := Hole(nominal, classes[0])
+	if err != nil {
+		return nil, err
+	}
+	shaft, err := Shaft(nominal, classes[1])
+	if err != nil {
+		return nil, err
+	}
+	return &Fit{hole, shaft}, nil
+}
+
+// MaxClearance returns the maximum clearance of the fit (negative for interference).
+func (f *Fit) MaxClearance() float64 {
+	return f.Hole.Max() - f.Shaft.Min()
+}
+
+// MinClearance returns the minimum clearance of the fit (negative for interference).
+func (f *Fit) MinClearance() float64 {
+	return f.Hole.Min() - f.Shaft.Max()
+}
+
+// Kind returns the type of the fit.
+func (f *Fit) Kind() FitKind {
+	switch {
+	case f.MinClearance() >= 0:
+		return Clearance
+	case f.MaxClearance() <= 0:
+		return Interference
+	}
+	return Transition
+}
+
+// HoleSize returns the build size of the hole.
+func (f *Fit) HoleSize() float64 {
+	return HoleSize(f.Hole)
+}
+
+// ShaftSize returns the build size of the shaft.
+func (f *Fit) ShaftSize() float64 {
+	return ShaftSize(f.Shaft)
+}
+
+func (f *Fit) String() string {
+	return fmt.Sprintf("hole %s, shaft %s (%s)", f.Hole, f.Shaft, f.Kind())
+}
+
+//-----------------------------------------------------------------------------
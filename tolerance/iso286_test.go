@@ -0,0 +1,99 @@
+//-----------------------------------------------------------------------------
+/*
+
+ISO 286 Limits and Fits Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package tolerance
+
+import (
+	"math"
+	"testing"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_ISO286(t *testing.T) {
+	// values from the ISO 286-2 tables (microns)
+	tests := []struct {
+		nominal      float64
+		class        string
+		upper, lower float64
+	}{
+		{12, "H7", 18, 0},
+		{12, "g6", -6, -17},
+		{12, "f7", -16, -34},
+		{12, "k6", 12, 1},
+		{12, "n6", 23, 12},
+		{12, "p6", 29, 18},
+		{12, "K7", 6, -12},
+		{12, "N7", -5, -23},
+		{12, "P7", -11, -29},
+		{25, "H8", 33, 0},
+		{25, "e8", -40, -73},
+		{40, "js6", 8, -8},
+		{100, "h7", 0, -35},
+	}
+	for _, v := range tests {
+		var d Dimension
+		var err error
+		if v.class[0] >= 'a' {
+			d, err = Shaft(v.nominal, v.class)
+		} else {
+			d, err = Hole(v.nominal, v.class)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(d.Upper*1e3-v.upper) > 1e-6 || math.Abs(d.Lower*1e3-v.lower) > 1e-6 {
+			t.Errorf("%g%s: expected %+g/%+g, got %+g/%+g", v.nominal, v.class, v.upper, v.lower, d.Upper*1e3, d.Lower*1e3)
+		}
+	}
+
+	fits := []struct {
+		fit  string
+		kind FitKind
+	}{
+		{"H7/g6", Clearance},
+		{"H7/k6", Transition},
+		{"H7/p6", Interference},
+	}
+	for _, v := range fits {
+		f, err := NewFit(12, v.fit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Kind() != v.kind {
+			t.Errorf("%s: expected %s, got %s", v.fit, v.kind, f.Kind())
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Target(t *testing.T) {
+	d := New(10, 0.2, -0.1)
+	tests := []struct {
+		target      Target
+		hole, shaft float64
+	}{
+		{TargetMean, 10.05, 10.05},
+		{TargetMMC, 9.9, 10.2},
+		{TargetLMC, 10.2, 9.9},
+	}
+	for _, x := range tests {
+		if h := x.target.HoleSize(d); math.Abs(h-x.hole) > 1e-9 {
+			t.Errorf("target %d: expected hole %g, got %g", x.target, x.hole, h)
+		}
+		if s := x.target.ShaftSize(d); math.Abs(s-x.shaft) > 1e-9 {
+			t.Errorf("target %d: expected shaft %g, got %g", x.target, x.shaft, s)
+		}
+	}
+	if HoleSize(d) != DefaultTarget.HoleSize(d) || ShaftSize(d) != DefaultTarget.ShaftSize(d) {
+		t.Error("the default target isn't used")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,197 @@
+//-----------------------------------------------------------------------------
+/*
+
+Tolerances
+
+A dimension is a nominal size with upper and lower deviations. Dimensions
+are used to size mating features (holes and shafts) consistently, and can
+be combined in a stack to find the worst case and statistical (RSS)
+variation of a chain of dimensions.
+
+All sizes are in mm.
+
+*/
+//-----------------------------------------------------------------------------
+
+package tolerance
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// Dimension is a nominal size with upper and lower deviations.
+type Dimension struct {
+	Nominal float64 // nominal size
+	Upper   float64 // upper deviation (added to the nominal size)
+	Lower   float64 // lower deviation (added to the nominal size)
+}
+
+// New returns a dimension with the given upper and lower deviations.
+func New(nominal, upper, lower float64) Dimension {
+	if lower > upper {
+		upper, lower = lower, upper
+	}
+	return Dimension{nominal, upper, lower}
+}
+
+// Sym returns a dimension with a symmetric tolerance (nominal ± tol).
+func Sym(nominal, tol float64) Dimension {
+	tol = math.Abs(tol)
+	return Dimension{nominal, tol, -tol}
+}
+
+// Max returns the maximum size of the dimension.
+func (d Dimension) Max() float64 {
+	return d.Nominal + d.Upper
+}
+
+// Min returns the minimum size of the dimension.
+func (d Dimension) Min() float64 {
+	return d.Nominal + d.Lower
+}
+
+// Mean returns the size at the center of the tolerance zone.
+func (d Dimension) Mean() float64 {
+	return d.Nominal + 0.5*(d.Upper+d.Lower)
+}
+
+// Tolerance returns the width of the tolerance zone.
+func (d Dimension) Tolerance() float64 {
+	return d.Upper - d.Lower
+}
+
+// Contains returns true if a size is within the tolerance zone.
+func (d Dimension) Contains(x float64) bool {
+	return x >= d.Min() && x <= d.Max()
+}
+
+func (d Dimension) String() string {
+	if d.Upper == -d.Lower {
+		return fmt.Sprintf("%g ±%g", d.Nominal, d.Upper)
+	}
+	return fmt.Sprintf("%g %+g/%+g", d.Nominal, d.Upper, d.Lower)
+}
+
+//-----------------------------------------------------------------------------
+
+// Target selects the size within a tolerance zone used to build a feature.
+type Target int
+
+const (
+	// TargetMean builds features at the center of the tolerance zone.
+	TargetMean Target = iota
+	// TargetMMC builds features at maximum material condition
+	// (smallest hole, largest shaft).
+	TargetMMC
+	// TargetLMC builds features at least material condition
+	// (largest hole, smallest shaft).
+	TargetLMC
+)
+
+// DefaultTarget is the target used by HoleSize and ShaftSize.
+// Use the methods of a Target to build at another target.
+const DefaultTarget = TargetMean
+
+// HoleSize returns the build size at the target for an internal feature.
+func (t Target) HoleSize(d Dimension) float64 {
+	switch t {
+	case TargetMMC:
+		return d.Min()
+	case TargetLMC:
+		return d.Max()
+	}
+	return d.Mean()
+}
+
+// ShaftSize returns the build size at the target for an external feature.
+func (t Target) ShaftSize(d Dimension) float64 {
+	switch t {
+	case TargetMMC:
+		return d.Max()
+	case TargetLMC:
+		return d.Min()
+	}
+	return d.Mean()
+}
+
+// HoleSize returns the build size for an internal feature.
+func HoleSize(d Dimension) float64 {
+	return DefaultTarget.HoleSize(d)
+}
+
+// ShaftSize returns the build size for an external feature.
+func ShaftSize(d Dimension) float64 {
+	return DefaultTarget.ShaftSize(d)
+}
+
+// Hole3D returns a cylindrical hole (to be subtracted) for a diameter dimension.
+func Hole3D(height float64, diameter Dimension) (sdf.SDF3, error) {
+	return sdf.Cylinder3D(height, 0.5*HoleSize(diameter), 0)
+}
+
+// Shaft3D returns a cylindrical shaft for a diameter dimension.
+func Shaft3D(height float64, diameter Dimension) (sdf.SDF3, error) {
+	return sdf.Cylinder3D(height, 0.5*ShaftSize(diameter), 0)
+}
+
+//-----------------------------------------------------------------------------
+
+// stackTerm is a dimension in a tolerance stack.
+type stackTerm struct {
+	d    Dimension
+	sign float64 // +1 or -1
+}
+
+// Stack is a chain of dimensions that add or subtract to give a result.
+type Stack struct {
+	terms []stackTerm
+}
+
+// Add adds a dimension to the stack.
+func (s *Stack) Add(d Dimension) *Stack {
+	s.terms = append(s.terms, stackTerm{d, 1})
+	return s
+}
+
+// Sub subtracts a dimension from the stack.
+func (s *Stack) Sub(d Dimension) *Stack {
+	s.terms = append(s.terms, stackTerm{d, -1})
+	return s
+}
+
+// WorstCase returns the result of the stack with worst case tolerances.
+func (s *Stack) WorstCase() Dimension {
+	var r Dimension
+	for _, t := range s.terms {
+		r.Nominal += t.sign * t.d.Nominal
+		if t.sign > 0 {
+			r.Upper += t.d.Upper
+			r.Lower += t.d.Lower
+		} else {
+			r.Upper -= t.d.Lower
+			r.Lower -= t.d.Upper
+		}
+	}
+	return r
+}
+
+// RSS returns the result of the stack with root sum square (statistical) tolerances.
+// The result is symmetric about the mean of the worst case result.
+func (s *Stack) RSS() Dimension {
+	wc := s.WorstCase()
+	sum := 0.0
+	for _, t := range s.terms {
+		x := 0.5 * t.d.Tolerance()
+		sum += x * x
+	}
+	tol := math.Sqrt(sum)
+	mid := 0.5 * (wc.Upper + wc.Lower)
+	return Dimension{wc.Nominal, mid + tol, mid - tol}
+}
+
+//-----------------------------------------------------------------------------
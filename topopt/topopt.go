@@ -0,0 +1,605 @@
+//-----------------------------------------------------------------------------
+/*
+
+Topology Optimization (experimental)
+
+SIMP density topology optimization on a voxel grid. The design domain is
+an SDF3, the supports are SDF3 regions where the nodes are fixed and the
+loads are SDF3 regions where a force is spread over the nodes. The cells of
+the domain are hex8 finite elements with a density x in (0, 1] and a
+Young's modulus Emin + x^p (E - Emin). Each iteration solves the linear
+elastic problem, finds the compliance sensitivities, smooths them with a
+sensitivity filter and updates the densities with the optimality criteria
+method to keep the volume fraction (Sigmund, "A 99 line topology
+optimization code written in Matlab" and its 3D variants).
+
+The stiffness system is solved matrix free with a Jacobi preconditioned
+conjugate gradient, warm started from the previous displacements.
+
+The result is the element densities. Its SDF3 is the density iso-surface,
+so the optimized structure can be rendered, checked or combined with other
+sdfx geometry.
+
+The material is linear isotropic with E = 1 unless given, so the compliance
+is only meaningful relative to other runs with the same loads.
+
+*/
+//-----------------------------------------------------------------------------
+
+package topopt
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// Load is a force spread evenly over the nodes within a region.
+type Load struct {
+	Region sdf.SDF3 // nodes within the region are loaded
+	Force  v3.Vec   // total force
+}
+
+// OptimizeParms defines the parameters for topology optimization.
+type OptimizeParms struct {
+	Domain         sdf.SDF3   // design domain
+	Supports       []sdf.SDF3 // nodes within a support region are fixed
+	Loads          []Load     // loads
+	VolumeFraction float64    // fraction of the domain volume to keep (0, 1)
+	Cells          int        // cells on the longest axis of the domain (0 = 30)
+	Penalty        float64    // SIMP penalty exponent (0 = 3)
+	FilterRadius   float64    // sensitivity filter radius in cells (0 = 1.5)
+	Iterations     int        // maximum number of iterations (0 = 60)
+	Tolerance      float64    // stop when no density changes by more than this (0 = 0.01)
+	Young          float64    // Young's modulus (0 = 1)
+	Poisson        float64    // Poisson's ratio (0 = 0.3)
+}
+
+// defaults returns the parameters with the defaults filled in.
+func (k OptimizeParms) defaults() (OptimizeParms, error) {
+	if k.Domain == nil {
+		return k, sdf.ErrMsg("no design domain")
+	}
+	if len(k.Supports) == 0 {
+		return k, sdf.ErrMsg("no supports")
+	}
+	if len(k.Loads) == 0 {
+		return k, sdf.ErrMsg("no loads")
+	}
+	if k.VolumeFraction <= 0 || k.VolumeFraction >= 1 {
+		return k, sdf.ErrMsg("VolumeFraction must be in (0, 1)")
+	}
+	if k.Cells < 0 || k.Iterations < 0 || k.Penalty < 0 || k.FilterRadius < 0 || k.Tolerance < 0 || k.Young < 0 {
+		return k, sdf.ErrMsg("negative parameter")
+	}
+	if k.Cells == 0 {
+		k.Cells = 30
+	}
+	if k.Penalty == 0 {
+		k.Penalty = 3
+	}
+	if k.FilterRadius == 0 {
+		k.FilterRadius = 1.5
+	}
+	if k.Iterations == 0 {
+		k.Iterations = 60
+	}
+	if k.Tolerance == 0 {
+		k.Tolerance = 0.01
+	}
+	if k.Young == 0 {
+		k.Young = 1
+	}
+	if k.Poisson == 0 {
+		k.Poisson = 0.3
+	}
+	if k.Poisson <= -1 || k.Poisson >= 0.5 {
+		return k, sdf.ErrMsg("Poisson must be in (-1, 0.5)")
+	}
+	return k, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// Result is the result of a topology optimization.
+type Result struct {
+	Origin     v3.Vec    // position of grid node (0, 0, 0)
+	CellSize   float64   // cell size
+	Cells      v3i.Vec   // cells on each axis
+	Density    []float64 // element densities, x fastest (0 outside the domain)
+	Compliance []float64 // compliance at each iteration
+	Volume     float64   // final volume fraction of the domain
+}
+
+// element returns the index of an element.
+func (r *Result) element(x, y, z int) int {
+	return x + r.Cells.X*(y+r.Cells.Y*z)
+}
+
+// SDF3 returns the iso-surface of the density at a threshold (0 = 0.5) as an SDF3.
+// The distance is approximate, it is the density difference scaled by the cell size.
+func (r *Result) SDF3(threshold float64) (sdf.SDF3, error) {
+	if threshold < 0 || threshold >= 1 {
+		return nil, sdf.ErrMsg("threshold must be in [0, 1)")
+	}
+	if threshold == 0 {
+		threshold = 0.5
+	}
+	// the grid is padded by a cell so the surface is closed
+	h := r.CellSize
+	n := r.Cells.AddScalar(2)
+	g, err := sdf.NewGrid3(r.Origin.SubScalar(h), n, h, 2*h)
+	if err != nil {
+		return nil, err
+	}
+	for k := 0; k <= n.Z; k++ {
+		for j := 0; j <= n.Y; j++ {
+			for i := 0; i <= n.X; i++ {
+				// the node density is the mean density of the adjacent elements
+				rho := 0.0
+				for _, c := range hexCorners {
+					x, y, z := i-1-c[0], j-1-c[1], k-1-c[2]
+					if x >= 0 && y >= 0 && z >= 0 && x < r.Cells.X && y < r.Cells.Y && z < r.Cells.Z {
+						rho += r.Density[r.element(x, y, z)]
+					}
+				}
+				g.Set(v3i.Vec{i, j, k}, 2*h*(threshold-rho/8))
+			}
+		}
+	}
+	return g, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// problem is the finite element problem on the voxel grid.
+type problem struct {
+	k      OptimizeParms
+	r      *Result
+	ke     [24][24]float64 // element stiffness for E = 1
+	active []bool          // elements in the domain
+	nodes  v3i.Vec         // nodes on each axis
+	fixed  []bool          // fixed degrees of freedom
+	force  []float64       // nodal forces
+	colors [8][]int        // active elements by color, elements of a color share no nodes
+}
+
+// node returns the index of a grid node.
+func (p *problem) node(i, j, k int) int {
+	return i + p.nodes.X*(j+p.nodes.Y*k)
+}
+
+// dofs returns the degrees of freedom of an element.
+func (p *problem) dofs(e int, d *[24]int) {
+	c := p.r.Cells
+	x, y, z := e%c.X, (e/c.X)%c.Y, e/(c.X*c.Y)
+	for i, o := range hexCorners {
+		n := p.node(x+o[0], y+o[1], z+o[2])
+		d[3*i], d[3*i+1], d[3*i+2] = 3*n, 3*n+1, 3*n+2
+	}
+}
+
+// nodePosition returns the position of a grid node.
+func (p *problem) nodePosition(n int) v3.Vec {
+	i, j, k := n%p.nodes.X, (n/p.nodes.X)%p.nodes.Y, n/(p.nodes.X*p.nodes.Y)
+	return p.r.Origin.Add(v3.Vec{float64(i), float64(j), float64(k)}.MulScalar(p.r.CellSize))
+}
+
+// newProblem sets up the grid, the supports and the loads.
+func newProblem(k OptimizeParms) (*problem, error) {
+	bb := k.Domain.BoundingBox()
+	size := bb.Size()
+	h := size.MaxComponent() / float64(k.Cells)
+	cells := v3i.Vec{
+		int(math.Max(1, math.Ceil(size.X/h))),
+		int(math.Max(1, math.Ceil(size.Y/h))),
+		int(math.Max(1, math.Ceil(size.Z/h))),
+	}
+	origin := bb.Center().Sub(v3.Vec{float64(cells.X), float64(cells.Y), float64(cells.Z)}.MulScalar(h / 2))
+	p := &problem{
+		k:     k,
+		r:     &Result{Origin: origin, CellSize: h, Cells: cells},
+		ke:    elementStiffness(k.Poisson),
+		nodes: cells.AddScalar(1),
+	}
+	n := cells.X * cells.Y * cells.Z
+	p.active = make([]bool, n)
+	p.r.Density = make([]float64, n)
+	connected := make([]bool, p.nodes.X*p.nodes.Y*p.nodes.Z)
+	var d [24]int
+	for e := range p.active {
+		x, y, z := e%cells.X, (e/cells.X)%cells.Y, e/(cells.X*cells.Y)
+		center := origin.Add(v3.Vec{float64(x) + 0.5, float64(y) + 0.5, float64(z) + 0.5}.MulScalar(h))
+		if k.Domain.Evaluate(center) > 0 {
+			continue
+		}
+		p.active[e] = true
+		p.r.Density[e] = k.VolumeFraction
+		color := (x & 1) | (y&1)<<1 | (z&1)<<2
+		p.colors[color] = append(p.colors[color], e)
+		p.dofs(e, &d)
+		for i := 0; i < 8; i++ {
+			connected[d[3*i]/3] = true
+		}
+	}
+	if len(p.colors[0])+len(p.colors[1])+len(p.colors[2])+len(p.colors[3])+
+		len(p.colors[4])+len(p.colors[5])+len(p.colors[6])+len(p.colors[7]) == 0 {
+		return nil, sdf.ErrMsg("the design domain has no elements")
+	}
+
+	p.fixed = make([]bool, 3*len(connected))
+	p.force = make([]float64, 3*len(connected))
+	nfixed := 0
+	for n, ok := range connected {
+		if !ok {
+			// not part of the structure
+			p.fixed[3*n], p.fixed[3*n+1], p.fixed[3*n+2] = true, true, true
+			continue
+		}
+		for _, s := range k.Supports {
+			if s.Evaluate(p.nodePosition(n)) <= 0 {
+				p.fixed[3*n], p.fixed[3*n+1], p.fixed[3*n+2] = true, true, true
+				nfixed++
+				break
+			}
+		}
+	}
+	if nfixed == 0 {
+		return nil, sdf.ErrMsg("no nodes within the support regions")
+	}
+	for i, l := range k.Loads {
+		var loaded []int
+		for n, ok := range connected {
+			if ok && !p.fixed[3*n] && l.Region.Evaluate(p.nodePosition(n)) <= 0 {
+				loaded = append(loaded, n)
+			}
+		}
+		if len(loaded) == 0 {
+			return nil, sdf.ErrMsg(fmt.Sprintf("load %d: no free nodes within the region", i))
+		}
+		f := l.Force.DivScalar(float64(len(loaded)))
+		for _, n := range loaded {
+			p.force[3*n] += f.X
+			p.force[3*n+1] += f.Y
+			p.force[3*n+2] += f.Z
+		}
+	}
+	return p, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// hexCorners are the grid offsets of the corners of a hex8 element.
+var hexCorners = [8][3]int{
+	{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}, {0, 0, 1}, {1, 0, 1}, {1, 1, 1}, {0, 1, 1},
+}
+
+// elementStiffness returns the stiffness matrix of a unit cube hex8 element
+// with E = 1, by 2x2x2 Gauss quadrature. The stiffness of a cube with side h is h times this.
+func elementStiffness(nu float64) [24][24]float64 {
+	// isotropic elasticity matrix
+	var d [6][6]float64
+	c := 1 / ((1 + nu) * (1 - 2*nu))
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			d[i][j] = c * nu
+		}
+		d[i][i] = c * (1 - nu)
+		d[i+3][i+3] = c * (1 - 2*nu) / 2
+	}
+	var ke [24][24]float64
+	g := 1 / math.Sqrt(3)
+	for _, gx := range []float64{-g, g} {
+		for _, gy := range []float64{-g, g} {
+			for _, gz := range []float64{-g, g} {
+				// strain-displacement matrix, dN/dx = 2 dN/dxi for the unit cube
+				var b [6][24]float64
+				for a, o := range hexCorners {
+					sx, sy, sz := float64(2*o[0]-1), float64(2*o[1]-1), float64(2*o[2]-1)
+					dx := 2 * sx * (1 + sy*gy) * (1 + sz*gz) / 8
+					dy := 2 * sy * (1 + sx*gx) * (1 + sz*gz) / 8
+					dz := 2 * sz * (1 + sx*gx) * (1 + sy*gy) / 8
+					b[0][3*a] = dx
+					b[1][3*a+1] = dy
+					b[2][3*a+2] = dz
+					b[3][3*a], b[3][3*a+1] = dy, dx
+					b[4][3*a+1], b[4][3*a+2] = dz, dy
+					b[5][3*a], b[5][3*a+2] = dz, dx
+				}
+				// the jacobian determinant is 1/8, the weights are 1
+				var db [6][24]float64
+				for i := 0; i < 6; i++ {
+					for j := 0; j < 24; j++ {
+						for m := 0; m < 6; m++ {
+							db[i][j] += d[i][m] * b[m][j]
+						}
+					}
+				}
+				for i := 0; i < 24; i++ {
+					for j := 0; j < 24; j++ {
+						s := 0.0
+						for m := 0; m < 6; m++ {
+							s += b[m][i] * db[m][j]
+						}
+						ke[i][j] += s / 8
+					}
+				}
+			}
+		}
+	}
+	return ke
+}
+
+//-----------------------------------------------------------------------------
+
+// stiffness returns the element stiffness scale for the element densities.
+func (p *problem) stiffness() []float64 {
+	emin := 1e-9 * p.k.Young
+	s := make([]float64, len(p.r.Density))
+	for e, x := range p.r.Density {
+		if p.active[e] {
+			s[e] = (emin + math.Pow(x, p.k.Penalty)*(p.k.Young-emin)) * p.r.CellSize
+		}
+	}
+	return s
+}
+
+// multiply sets y = K x. Fixed degrees of freedom have identity rows.
+func (p *problem) multiply(s, x, y []float64) {
+	for i := range y {
+		y[i] = 0
+	}
+	workers := runtime.NumCPU()
+	for _, elements := range p.colors {
+		// elements of a color don't share nodes, so they can be added in parallel
+		var wg sync.WaitGroup
+		n := (len(elements) + workers - 1) / workers
+		for w := 0; w < len(elements); w += n {
+			wg.Add(1)
+			go func(elements []int) {
+				defer wg.Done()
+				var d [24]int
+				var u [24]float64
+				for _, e := range elements {
+					p.dofs(e, &d)
+					for i, dof := range d {
+						u[i] = x[dof]
+						if p.fixed[dof] {
+							u[i] = 0
+						}
+					}
+					for i, dof := range d {
+						f := 0.0
+						for j := range u {
+							f += p.ke[i][j] * u[j]
+						}
+						y[dof] += s[e] * f
+					}
+				}
+			}(elements[w:minInt(w+n, len(elements))])
+		}
+		wg.Wait()
+	}
+	for i, fixed := range p.fixed {
+		if fixed {
+			y[i] = x[i]
+		}
+	}
+}
+
+// solve solves K u = f with a Jacobi preconditioned conjugate gradient, starting from u.
+func (p *problem) solve(s, u []float64) {
+	n := len(u)
+	diag := make([]float64, n)
+	var d [24]int
+	for e, active := range p.active {
+		if !active {
+			continue
+		}
+		p.dofs(e, &d)
+		for i, dof := range d {
+			diag[dof] += s[e] * p.ke[i][i]
+		}
+	}
+	for i := range diag {
+		if p.fixed[i] {
+			diag[i] = 1
+			u[i] = 0
+		}
+	}
+	r := make([]float64, n)
+	z := make([]float64, n)
+	q := make([]float64, n)
+	dir := make([]float64, n)
+	p.multiply(s, u, q)
+	fnorm := 0.0
+	for i := range r {
+		if !p.fixed[i] {
+			r[i] = p.force[i] - q[i]
+			fnorm += p.force[i] * p.force[i]
+		}
+	}
+	tol := 1e-12 * fnorm
+	rz := 0.0
+	for i := range z {
+		z[i] = r[i] / diag[i]
+		dir[i] = z[i]
+		rz += r[i] * z[i]
+	}
+	for iter := 0; iter < 10*n; iter++ {
+		rr := 0.0
+		for i := range r {
+			rr += r[i] * r[i]
+		}
+		if rr <= tol {
+			break
+		}
+		p.multiply(s, dir, q)
+		dq := 0.0
+		for i := range q {
+			dq += dir[i] * q[i]
+		}
+		alpha := rz / dq
+		rzNew := 0.0
+		for i := range u {
+			u[i] += alpha * dir[i]
+			r[i] -= alpha * q[i]
+			z[i] = r[i] / diag[i]
+			rzNew += r[i] * z[i]
+		}
+		beta := rzNew / rz
+		rz = rzNew
+		for i := range dir {
+			dir[i] = z[i] + beta*dir[i]
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// filter applies the sensitivity filter to the compliance sensitivities.
+func (p *problem) filter(dc []float64) []float64 {
+	c := p.r.Cells
+	x := p.r.Density
+	rmin := p.k.FilterRadius
+	w := int(math.Ceil(rmin)) - 1
+	out := make([]float64, len(dc))
+	for e, active := range p.active {
+		if !active {
+			continue
+		}
+		ex, ey, ez := e%c.X, (e/c.X)%c.Y, e/(c.X*c.Y)
+		sum, hsum := 0.0, 0.0
+		for k := maxInt(ez-w, 0); k <= minInt(ez+w, c.Z-1); k++ {
+			for j := maxInt(ey-w, 0); j <= minInt(ey+w, c.Y-1); j++ {
+				for i := maxInt(ex-w, 0); i <= minInt(ex+w, c.X-1); i++ {
+					f := p.r.element(i, j, k)
+					if !p.active[f] {
+						continue
+					}
+					dx, dy, dz := float64(i-ex), float64(j-ey), float64(k-ez)
+					h := rmin - math.Sqrt(dx*dx+dy*dy+dz*dz)
+					if h > 0 {
+						sum += h * x[f] * dc[f]
+						hsum += h
+					}
+				}
+			}
+		}
+		out[e] = sum / (math.Max(1e-3, x[e]) * hsum)
+	}
+	return out
+}
+
+// update updates the densities with the optimality criteria method and returns the largest change.
+func (p *problem) update(dc []float64, volume float64) float64 {
+	const move = 0.2
+	const xmin = 1e-3
+	x := p.r.Density
+	next := make([]float64, len(x))
+	l1, l2 := 0.0, 1e9
+	for (l2-l1)/(l1+l2) > 1e-4 {
+		lmid := (l1 + l2) / 2
+		sum := 0.0
+		for e, active := range p.active {
+			if !active {
+				continue
+			}
+			be := math.Sqrt(math.Max(-dc[e], 0) / lmid)
+			next[e] = math.Max(xmin, math.Max(x[e]-move, math.Min(1, math.Min(x[e]+move, x[e]*be))))
+			sum += next[e]
+		}
+		if sum > volume {
+			l1 = lmid
+		} else {
+			l2 = lmid
+		}
+	}
+	change := 0.0
+	for e := range x {
+		change = math.Max(change, math.Abs(next[e]-x[e]))
+	}
+	copy(x, next)
+	return change
+}
+
+//-----------------------------------------------------------------------------
+
+// Optimize runs a SIMP topology optimization.
+func Optimize(k *OptimizeParms) (*Result, error) {
+	parms, err := k.defaults()
+	if err != nil {
+		return nil, err
+	}
+	p, err := newProblem(parms)
+	if err != nil {
+		return nil, err
+	}
+	nactive := 0
+	for _, active := range p.active {
+		if active {
+			nactive++
+		}
+	}
+	volume := parms.VolumeFraction * float64(nactive)
+	u := make([]float64, len(p.force))
+	dc := make([]float64, len(p.active))
+	var d [24]int
+	for iter := 0; iter < parms.Iterations; iter++ {
+		s := p.stiffness()
+		p.solve(s, u)
+		// compliance and its sensitivities
+		compliance := 0.0
+		for e, active := range p.active {
+			if !active {
+				continue
+			}
+			p.dofs(e, &d)
+			ce := 0.0
+			for i, a := range d {
+				for j, b := range d {
+					ce += u[a] * p.ke[i][j] * u[b]
+				}
+			}
+			ce *= p.r.CellSize
+			x := p.r.Density[e]
+			compliance += s[e] / p.r.CellSize * ce
+			dc[e] = -parms.Penalty * math.Pow(x, parms.Penalty-1) * parms.Young * ce
+		}
+		p.r.Compliance = append(p.r.Compliance, compliance)
+		if p.update(p.filter(dc), volume) <= parms.Tolerance {
+			break
+		}
+	}
+	sum := 0.0
+	for _, x := range p.r.Density {
+		sum += x
+	}
+	p.r.Volume = sum / float64(nactive)
+	return p.r, nil
+}
+
+//-----------------------------------------------------------------------------
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,133 @@
+//-----------------------------------------------------------------------------
+/*
+
+Topology Optimization Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package topopt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// slab returns a thin box region across a plane x = const.
+func slab(x float64) sdf.SDF3 {
+	s, _ := sdf.Box3D(v3.Vec{0.1, 100, 100}, 0)
+	return sdf.Transform3D(s, sdf.Translate3d(v3.Vec{x, 0, 0}))
+}
+
+func Test_ElementStiffness(t *testing.T) {
+	ke := elementStiffness(0.3)
+	for i := 0; i < 24; i++ {
+		for j := 0; j < 24; j++ {
+			if math.Abs(ke[i][j]-ke[j][i]) > 1e-12 {
+				t.Fatalf("not symmetric at %d, %d", i, j)
+			}
+		}
+		// rigid body translations give no force
+		for c := 0; c < 3; c++ {
+			f := 0.0
+			for j := c; j < 24; j += 3 {
+				f += ke[i][j]
+			}
+			if math.Abs(f) > 1e-12 {
+				t.Fatalf("translation %d gives a force %g at %d", c, f, i)
+			}
+		}
+	}
+}
+
+func Test_Bar(t *testing.T) {
+	// a solid bar in tension: u = F L / (E A)
+	bar, _ := sdf.Box3D(v3.Vec{40, 4, 4}, 0)
+	k, err := OptimizeParms{
+		Domain:         bar,
+		Supports:       []sdf.SDF3{slab(-20)},
+		Loads:          []Load{{slab(20), v3.Vec{16, 0, 0}}},
+		VolumeFraction: 0.5,
+		Cells:          20,
+		Young:          100,
+	}.defaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := newProblem(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for e := range p.r.Density {
+		p.r.Density[e] = 1
+	}
+	u := make([]float64, len(p.force))
+	p.solve(p.stiffness(), u)
+	sum, n := 0.0, 0
+	for i, f := range p.force {
+		if f != 0 {
+			sum += u[i]
+			n++
+		}
+	}
+	expected := 16.0 * 40 / (100 * 16)
+	if x := sum / float64(n); math.Abs(x-expected) > 0.1*expected {
+		t.Errorf("expected a displacement of %f, got %f", expected, x)
+	}
+}
+
+func Test_Optimize(t *testing.T) {
+	beam, _ := sdf.Box3D(v3.Vec{32, 8, 8}, 0)
+	r, err := Optimize(&OptimizeParms{
+		Domain:         beam,
+		Supports:       []sdf.SDF3{slab(-16)},
+		Loads:          []Load{{slab(16), v3.Vec{0, 0, -1}}},
+		VolumeFraction: 0.4,
+		Cells:          16,
+		Iterations:     20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(r.Volume-0.4) > 0.01 {
+		t.Errorf("expected a volume fraction of 0.4, got %f", r.Volume)
+	}
+	c := r.Compliance
+	if len(c) < 2 || c[len(c)-1] >= c[0] {
+		t.Errorf("the compliance should drop, got %v", c)
+	}
+	s, err := r.SDF3(0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bending keeps the flanges at the clamped end and removes the web
+	if d := s.Evaluate(v3.Vec{-14, 0, 3}); d > 0 {
+		t.Errorf("expected a solid flange, got %f", d)
+	}
+	if d := s.Evaluate(v3.Vec{10, 0, 0}); d < 0 {
+		t.Errorf("expected an empty web, got %f", d)
+	}
+	if mesh := render.ToTriangles(s, render.NewMarchingCubesUniform(40)); len(mesh) == 0 {
+		t.Error("empty mesh")
+	}
+
+	if _, err := Optimize(&OptimizeParms{Domain: beam, Supports: []sdf.SDF3{slab(-16)}, VolumeFraction: 0.4}); err == nil {
+		t.Error("expected an error without loads")
+	}
+	if _, err := Optimize(&OptimizeParms{
+		Domain:         beam,
+		Supports:       []sdf.SDF3{slab(100)},
+		Loads:          []Load{{slab(16), v3.Vec{0, 0, -1}}},
+		VolumeFraction: 0.4,
+	}); err == nil {
+		t.Error("expected an error for a support outside the domain")
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,255 @@
+//-----------------------------------------------------------------------------
+/*
+
+Physical Units
+
+sdfx models are unitless numbers, by convention millimeters. Mixing in a
+dimension in inches (or an angle in degrees where radians are expected)
+gives a part that renders fine and is the wrong size. This package has
+Length and Angle types with explicit unit constructors, and primitive
+constructors that take them:
+
+	plate, err := units.Box3D(units.Vec3{units.In(4), units.In(2), units.MM(3)}, units.MM(1))
+	hole, err := units.Cylinder3D(units.MM(10), units.In(0.25).Half(), 0)
+	s := units.Rotate3D(plate, v3.Vec{0, 0, 1}, units.Deg(30))
+
+A Length is stored in millimeters, the model unit. An Angle is stored in
+radians. The Unit of an exported file (render.ExportOptions.Unit) scales the
+STL, 3MF and STEP coordinates, and 3MF and STEP record the unit in the file.
+
+*/
+//-----------------------------------------------------------------------------
+
+package units
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Unit is a unit of length. The zero value is millimeters.
+type Unit int
+
+// Length units.
+const (
+	Millimeter Unit = iota
+	Micrometer
+	Centimeter
+	Meter
+	Inch
+	Foot
+)
+
+var unitInfo = [...]struct {
+	name  string  // short name
+	scale float64 // millimeters per unit
+}{
+	Millimeter: {"mm", 1},
+	Micrometer: {"um", 1e-3},
+	Centimeter: {"cm", 10},
+	Meter:      {"m", 1000},
+	Inch:       {"in", 25.4},
+	Foot:       {"ft", 304.8},
+}
+
+// valid returns true for a known unit.
+func (u Unit) valid() bool {
+	return u >= 0 && int(u) < len(unitInfo)
+}
+
+func (u Unit) String() string {
+	if !u.valid() {
+		return fmt.Sprintf("unit(%d)", int(u))
+	}
+	return unitInfo[u].name
+}
+
+// Scale returns the number of millimeters per unit.
+func (u Unit) Scale() float64 {
+	if !u.valid() {
+		return 1
+	}
+	return unitInfo[u].scale
+}
+
+// ParseUnit returns the unit with a short name ("mm", "um", "cm", "m", "in", "ft").
+func ParseUnit(s string) (Unit, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, x := range unitInfo {
+		if x.name == s {
+			return Unit(i), nil
+		}
+	}
+	switch s {
+	case "", "millimeter":
+		return Millimeter, nil
+	case "inch", "\"":
+		return Inch, nil
+	}
+	return Millimeter, sdf.ErrMsg(fmt.Sprintf("unknown unit \"%s\"", s))
+}
+
+//-----------------------------------------------------------------------------
+
+// Length is a length in millimeters.
+type Length float64
+
+// MM returns a length in millimeters.
+func MM(x float64) Length { return Length(x) }
+
+// CM returns a length in centimeters.
+func CM(x float64) Length { return Length(x * 10) }
+
+// M returns a length in meters.
+func M(x float64) Length { return Length(x * 1000) }
+
+// In returns a length in inches.
+func In(x float64) Length { return Length(x * 25.4) }
+
+// Ft returns a length in feet.
+func Ft(x float64) Length { return Length(x * 304.8) }
+
+// Of returns a length in a unit.
+func Of(x float64, u Unit) Length { return Length(x * u.Scale()) }
+
+// MM returns the length in millimeters, the model unit.
+func (l Length) MM() float64 { return float64(l) }
+
+// In returns the length in inches.
+func (l Length) In() float64 { return float64(l) / 25.4 }
+
+// To returns the length in a unit.
+func (l Length) To(u Unit) float64 { return float64(l) / u.Scale() }
+
+// Half returns half the length (e.g. a radius from a diameter).
+func (l Length) Half() Length { return l / 2 }
+
+func (l Length) String() string {
+	return fmt.Sprintf("%gmm", float64(l))
+}
+
+// Angle is an angle in radians.
+type Angle float64
+
+// Rad returns an angle in radians.
+func Rad(x float64) Angle { return Angle(x) }
+
+// Deg returns an angle in degrees.
+func Deg(x float64) Angle { return Angle(sdf.DtoR(x)) }
+
+// Rad returns the angle in radians.
+func (a Angle) Rad() float64 { return float64(a) }
+
+// Deg returns the angle in degrees.
+func (a Angle) Deg() float64 { return sdf.RtoD(float64(a)) }
+
+func (a Angle) String() string {
+	return fmt.Sprintf("%gdeg", a.Deg())
+}
+
+// Vec2 is a 2d vector of lengths.
+type Vec2 struct {
+	X, Y Length
+}
+
+// MM returns the vector in millimeters.
+func (v Vec2) MM() v2.Vec { return v2.Vec{X: v.X.MM(), Y: v.Y.MM()} }
+
+// Vec3 is a 3d vector of lengths.
+type Vec3 struct {
+	X, Y, Z Length
+}
+
+// MM returns the vector in millimeters.
+func (v Vec3) MM() v3.Vec { return v3.Vec{X: v.X.MM(), Y: v.Y.MM(), Z: v.Z.MM()} }
+
+//-----------------------------------------------------------------------------
+// Primitive constructors with lengths and angles.
+
+// Box3D returns a box with rounded edges.
+func Box3D(size Vec3, round Length) (sdf.SDF3, error) {
+	return sdf.Box3D(size.MM(), round.MM())
+}
+
+// Sphere3D returns a sphere.
+func Sphere3D(radius Length) (sdf.SDF3, error) {
+	return sdf.Sphere3D(radius.MM())
+}
+
+// Cylinder3D returns a cylinder with rounded edges.
+func Cylinder3D(height, radius, round Length) (sdf.SDF3, error) {
+	return sdf.Cylinder3D(height.MM(), radius.MM(), round.MM())
+}
+
+// Capsule3D returns a capsule.
+func Capsule3D(height, radius Length) (sdf.SDF3, error) {
+	return sdf.Capsule3D(height.MM(), radius.MM())
+}
+
+// Cone3D returns a truncated cone with rounded edges.
+func Cone3D(height, r0, r1, round Length) (sdf.SDF3, error) {
+	return sdf.Cone3D(height.MM(), r0.MM(), r1.MM(), round.MM())
+}
+
+// Box2D returns a 2d box with rounded corners.
+func Box2D(size Vec2, round Length) sdf.SDF2 {
+	return sdf.Box2D(size.MM(), round.MM())
+}
+
+// Circle2D returns a circle.
+func Circle2D(radius Length) (sdf.SDF2, error) {
+	return sdf.Circle2D(radius.MM())
+}
+
+// Extrude3D extrudes an SDF2 to a height.
+func Extrude3D(s sdf.SDF2, height Length) sdf.SDF3 {
+	return sdf.Extrude3D(s, height.MM())
+}
+
+// TwistExtrude3D extrudes an SDF2 to a height with a twist.
+func TwistExtrude3D(s sdf.SDF2, height Length, twist Angle) sdf.SDF3 {
+	return sdf.TwistExtrude3D(s, height.MM(), twist.Rad())
+}
+
+// RevolveTheta3D revolves an SDF2 about the y axis by an angle.
+func RevolveTheta3D(s sdf.SDF2, theta Angle) (sdf.SDF3, error) {
+	return sdf.RevolveTheta3D(s, theta.Rad())
+}
+
+// Offset3D offsets the surface of an SDF3.
+func Offset3D(s sdf.SDF3, offset Length) sdf.SDF3 {
+	return sdf.Offset3D(s, offset.MM())
+}
+
+// Shell3D returns a shell of an SDF3 with a wall thickness.
+func Shell3D(s sdf.SDF3, thickness Length) (sdf.SDF3, error) {
+	return sdf.Shell3D(s, thickness.MM())
+}
+
+// Translate3D translates an SDF3.
+func Translate3D(s sdf.SDF3, v Vec3) sdf.SDF3 {
+	return sdf.Transform3D(s, sdf.Translate3d(v.MM()))
+}
+
+// Rotate3D rotates an SDF3 about an axis.
+func Rotate3D(s sdf.SDF3, axis v3.Vec, a Angle) sdf.SDF3 {
+	return sdf.Transform3D(s, sdf.Rotate3d(axis, a.Rad()))
+}
+
+// Translate2D translates an SDF2.
+func Translate2D(s sdf.SDF2, v Vec2) sdf.SDF2 {
+	return sdf.Transform2D(s, sdf.Translate2d(v.MM()))
+}
+
+// Rotate2D rotates an SDF2 about the origin.
+func Rotate2D(s sdf.SDF2, a Angle) sdf.SDF2 {
+	return sdf.Transform2D(s, sdf.Rotate2d(a.Rad()))
+}
+
+//-----------------------------------------------------------------------------
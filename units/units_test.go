@@ -0,0 +1,86 @@
+//-----------------------------------------------------------------------------
+/*
+
+Physical Units Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package units
+
+import (
+	"math"
+	"testing"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+const tolerance = 1e-9
+
+func Test_Units(t *testing.T) {
+	lengths := []struct {
+		l  Length
+		mm float64
+	}{
+		{MM(3), 3},
+		{CM(2), 20},
+		{M(1.5), 1500},
+		{In(1), 25.4},
+		{Ft(1), 304.8},
+		{Of(2, Inch), 50.8},
+		{Of(500, Micrometer), 0.5},
+	}
+	for _, x := range lengths {
+		if math.Abs(x.l.MM()-x.mm) > tolerance {
+			t.Errorf("%v: expected %gmm", x.l, x.mm)
+		}
+	}
+	if math.Abs(In(2).In()-2) > tolerance || math.Abs(Ft(1).To(Inch)-12) > tolerance {
+		t.Error("bad length conversion")
+	}
+	if math.Abs(Deg(180).Rad()-math.Pi) > tolerance || math.Abs(Rad(math.Pi/2).Deg()-90) > tolerance {
+		t.Error("bad angle conversion")
+	}
+
+	for _, name := range []string{"mm", "um", "cm", "m", "in", "ft"} {
+		u, err := ParseUnit(name)
+		if err != nil || u.String() != name {
+			t.Errorf("%s: got %v %v", name, u, err)
+		}
+	}
+	if u, err := ParseUnit(""); err != nil || u != Millimeter {
+		t.Error("expected mm for no unit")
+	}
+	if _, err := ParseUnit("furlong"); err == nil {
+		t.Error("expected an unknown unit error")
+	}
+}
+
+func Test_Primitives(t *testing.T) {
+	// a 1 x 2 inch plate, 3mm thick
+	s, err := Box3D(Vec3{In(1), In(2), MM(3)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := s.BoundingBox().Size()
+	if !size.Equals(v3.Vec{25.4, 50.8, 3}, tolerance) {
+		t.Errorf("got size %v", size)
+	}
+	// rotated a quarter turn about z
+	s = Rotate3D(s, v3.Vec{0, 0, 1}, Deg(90))
+	size = s.BoundingBox().Size()
+	if !size.Equals(v3.Vec{50.8, 25.4, 3}, 1e-6) {
+		t.Errorf("got rotated size %v", size)
+	}
+	c, err := Cylinder3D(In(1), In(0.5).Half(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.BoundingBox().Size().Equals(v3.Vec{12.7, 12.7, 25.4}, tolerance) {
+		t.Errorf("got cylinder size %v", c.BoundingBox().Size())
+	}
+}
+
+//-----------------------------------------------------------------------------
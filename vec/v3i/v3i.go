@@ -32,4 +32,19 @@ func (a Vec) Add(b Vec) Vec {
 	return Vec{a.X + b.X, a.Y + b.Y, a.Z + b.Z}
 }
 
+// Sub subtracts two vectors. Return v = a - b.
+func (a Vec) Sub(b Vec) Vec {
+	return Vec{a.X - b.X, a.Y - b.Y, a.Z - b.Z}
+}
+
+// MulScalar multiplies each component of the vector by a scalar.
+func (a Vec) MulScalar(k int) Vec {
+	return Vec{a.X * k, a.Y * k, a.Z * k}
+}
+
+// MaxComponent returns the maximum component of the vector.
+func (a Vec) MaxComponent() int {
+	return max(a.X, a.Y, a.Z)
+}
+
 //-----------------------------------------------------------------------------
@@ -0,0 +1,143 @@
+//go:build js && wasm
+
+//-----------------------------------------------------------------------------
+/*
+
+JavaScript Bindings
+
+Register sets a global "sdfxGo" object with a compile function. The
+functions return an Error value instead of throwing, sdfx.js turns these
+into exceptions.
+
+	sdfxGo.compile(src, params) -> model | Error
+	model.evaluate(x, y, z) -> number
+	model.bbox() -> {min: [x, y, z], max: [x, y, z]}
+	model.output() -> string
+	model.mesh(cells) -> {positions: Float32Array, normals: Float32Array}
+	model.stl(cells) -> Uint8Array
+	model.glb(cells) -> Uint8Array | Error
+
+*/
+//-----------------------------------------------------------------------------
+
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"syscall/js"
+	"unsafe"
+)
+
+//-----------------------------------------------------------------------------
+
+// jsError returns a JavaScript Error.
+func jsError(err error) js.Value {
+	return js.Global().Get("Error").New(err.Error())
+}
+
+// jsBytes returns a Uint8Array copy of a byte slice.
+func jsBytes(b []byte) js.Value {
+	a := js.Global().Get("Uint8Array").New(len(b))
+	js.CopyBytesToJS(a, b)
+	return a
+}
+
+// jsFloats returns a Float32Array copy of a float32 slice.
+func jsFloats(f []float32) js.Value {
+	b := unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(f))), 4*len(f))
+	return js.Global().Get("Float32Array").New(jsBytes(b).Get("buffer"))
+}
+
+// jsArray returns a JavaScript array of numbers.
+func jsArray(v [3]float64) js.Value {
+	return js.ValueOf([]interface{}{v[0], v[1], v[2]})
+}
+
+// jsParams converts a JavaScript object to model parameters.
+func jsParams(v js.Value) map[string]string {
+	params := make(map[string]string)
+	if v.Type() != js.TypeObject {
+		return params
+	}
+	keys := js.Global().Get("Object").Call("keys", v)
+	for i := 0; i < keys.Length(); i++ {
+		k := keys.Index(i).String()
+		switch x := v.Get(k); x.Type() {
+		case js.TypeNumber:
+			params[k] = fmt.Sprint(x.Float())
+		case js.TypeBoolean:
+			params[k] = fmt.Sprint(x.Bool())
+		default:
+			params[k] = x.String()
+		}
+	}
+	return params
+}
+
+// jsCells returns the cells argument of a render function.
+func jsCells(args []js.Value) int {
+	if len(args) == 0 || args[0].Type() != js.TypeNumber {
+		return 200
+	}
+	return args[0].Int()
+}
+
+// jsModel returns the JavaScript object for a model.
+func jsModel(m *Model) js.Value {
+	obj := js.Global().Get("Object").New()
+	obj.Set("evaluate", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		return m.Evaluate(args[0].Float(), args[1].Float(), args[2].Float())
+	}))
+	obj.Set("bbox", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		min, max := m.BoundingBox()
+		return js.ValueOf(map[string]interface{}{"min": jsArray(min), "max": jsArray(max)})
+	}))
+	obj.Set("output", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		return m.Output()
+	}))
+	obj.Set("mesh", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		mesh := m.Mesh(jsCells(args))
+		return js.ValueOf(map[string]interface{}{
+			"positions": jsFloats(mesh.Positions),
+			"normals":   jsFloats(mesh.Normals),
+		})
+	}))
+	obj.Set("stl", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		var buf bytes.Buffer
+		if err := m.WriteSTL(&buf, jsCells(args)); err != nil {
+			return jsError(err)
+		}
+		return jsBytes(buf.Bytes())
+	}))
+	obj.Set("glb", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		var buf bytes.Buffer
+		if err := m.WriteGLB(&buf, jsCells(args)); err != nil {
+			return jsError(err)
+		}
+		return jsBytes(buf.Bytes())
+	}))
+	return obj
+}
+
+// Register sets the global sdfxGo object.
+func Register() {
+	api := js.Global().Get("Object").New()
+	api.Set("compile", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) == 0 {
+			return jsError(fmt.Errorf("compile: no model source"))
+		}
+		var params js.Value
+		if len(args) > 1 {
+			params = args[1]
+		}
+		m, err := Compile(args[0].String(), jsParams(params))
+		if err != nil {
+			return jsError(err)
+		}
+		return jsModel(m)
+	}))
+	js.Global().Set("sdfxGo", api)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,61 @@
+//-----------------------------------------------------------------------------
+/*
+
+sdfx browser API
+
+Loads the sdfx WebAssembly module (see the web package) and wraps its
+functions so that errors are thrown as exceptions. Needs wasm_exec.js from
+the Go distribution.
+
+	const sdfx = await loadSdfx("sdfx.wasm");
+	const model = sdfx.compile(src, {width: 60});
+	const {positions, normals} = model.mesh(100);
+
+*/
+//-----------------------------------------------------------------------------
+
+"use strict";
+
+// check throws a returned Error value.
+function sdfxCheck(v) {
+  if (v instanceof Error) {
+    throw v;
+  }
+  return v;
+}
+
+// sdfxModel wraps a compiled model.
+function sdfxModel(m) {
+  return {
+    // distance from a point to the surface
+    evaluate: (x, y, z) => m.evaluate(x, y, z),
+    // bounding box {min: [x, y, z], max: [x, y, z]}
+    bbox: () => m.bbox(),
+    // printed output of the script
+    output: () => m.output(),
+    // triangle mesh {positions, normals} as Float32Arrays, 9 values per triangle
+    mesh: (cells) => sdfxCheck(m.mesh(cells)),
+    // binary STL file as a Uint8Array
+    stl: (cells) => sdfxCheck(m.stl(cells)),
+    // binary glTF file as a Uint8Array
+    glb: (cells) => sdfxCheck(m.glb(cells)),
+  };
+}
+
+// loadSdfx loads the sdfx module and returns the API.
+async function loadSdfx(url) {
+  const go = new Go();
+  const result = await WebAssembly.instantiateStreaming(fetch(url), go.importObject);
+  go.run(result.instance);
+  const api = globalThis.sdfxGo;
+  return {
+    // compile a model script with parameters (an object of names and values)
+    compile: (src, params) => sdfxModel(sdfxCheck(api.compile(src, params || {}))),
+  };
+}
+
+if (typeof module !== "undefined") {
+  module.exports = { loadSdfx };
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,131 @@
+//-----------------------------------------------------------------------------
+/*
+
+Browser API
+
+A small API for evaluating and meshing models in a browser, e.g. for an
+interactive configurator. The models are scripts (see the script package),
+the results are plain arrays and byte streams, and nothing touches the file
+system.
+
+The API is bound to JavaScript by the js/wasm build (see js.go, sdfx.js and
+cmd/sdfxwasm). Build it with:
+
+	GOOS=js GOARCH=wasm go build -o sdfx.wasm ./cmd/sdfxwasm
+	cp $(go env GOROOT)/lib/wasm/wasm_exec.js .
+
+and load it from a page with:
+
+	<script src="wasm_exec.js"></script>
+	<script src="sdfx.js"></script>
+	const sdfx = await loadSdfx("sdfx.wasm");
+	const model = sdfx.compile(src, {width: 60});
+	const mesh = model.mesh(100); // {positions, normals} as Float32Arrays
+	const stl = model.stl(200);   // Uint8Array
+
+*/
+//-----------------------------------------------------------------------------
+
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/script"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Model is a compiled model.
+type Model struct {
+	s      sdf.SDF3
+	output string // output of the script print calls
+}
+
+// Compile runs a model script with a set of parameters. The script must
+// return an SDF3.
+func Compile(src string, params map[string]string) (*Model, error) {
+	var out bytes.Buffer
+	v, err := script.Run("model", []byte(src), script.Options{Output: &out, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	s, ok := v.(sdf.SDF3)
+	if !ok {
+		return nil, fmt.Errorf("model: expected the script to return an sdf3, got %T", v)
+	}
+	return &Model{s: s, output: out.String()}, nil
+}
+
+// NewModel returns a model for an SDF3.
+func NewModel(s sdf.SDF3) *Model {
+	return &Model{s: s}
+}
+
+// Output returns the printed output of the model script.
+func (m *Model) Output() string {
+	return m.output
+}
+
+// Evaluate returns the distance to the surface of the model.
+func (m *Model) Evaluate(x, y, z float64) float64 {
+	return m.s.Evaluate(v3.Vec{X: x, Y: y, Z: z})
+}
+
+// BoundingBox returns the minimum and maximum corners of the model bounding box.
+func (m *Model) BoundingBox() ([3]float64, [3]float64) {
+	bb := m.s.BoundingBox()
+	return [3]float64{bb.Min.X, bb.Min.Y, bb.Min.Z}, [3]float64{bb.Max.X, bb.Max.Y, bb.Max.Z}
+}
+
+// triangles renders the model with a number of cells on the longest axis.
+func (m *Model) triangles(cells int) []*sdf.Triangle3 {
+	return render.ToTriangles(m.s, render.NewMarchingCubesOctree(cells))
+}
+
+//-----------------------------------------------------------------------------
+
+// Mesh is a triangle mesh as flat arrays, 9 values (3 vertices) per triangle.
+// This is the layout of an unindexed WebGL vertex buffer.
+type Mesh struct {
+	Positions []float32 // vertex positions
+	Normals   []float32 // vertex normals (the triangle normal)
+}
+
+// Mesh renders the model with a number of cells on the longest axis.
+func (m *Model) Mesh(cells int) *Mesh {
+	tris := m.triangles(cells)
+	mesh := &Mesh{
+		Positions: make([]float32, 0, 9*len(tris)),
+		Normals:   make([]float32, 0, 9*len(tris)),
+	}
+	for _, t := range tris {
+		n := t.Normal()
+		for _, v := range t {
+			mesh.Positions = append(mesh.Positions, float32(v.X), float32(v.Y), float32(v.Z))
+			mesh.Normals = append(mesh.Normals, float32(n.X), float32(n.Y), float32(n.Z))
+		}
+	}
+	return mesh
+}
+
+// WriteSTL renders the model with a number of cells on the longest axis and
+// writes it as a binary STL.
+func (m *Model) WriteSTL(w io.Writer, cells int) error {
+	return render.WriteSTL(w, m.triangles(cells), render.ExportOptions{})
+}
+
+// WriteGLB renders the model with a number of cells on the longest axis and
+// writes it as binary glTF.
+func (m *Model) WriteGLB(w io.Writer, cells int) error {
+	part := render.GLTFPart{Name: "model", Color: color.RGBA{180, 180, 180, 255}, Mesh: m.triangles(cells)}
+	return render.WriteGLTF(w, []render.GLTFPart{part}, nil, true)
+}
+
+//-----------------------------------------------------------------------------
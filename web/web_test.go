@@ -0,0 +1,68 @@
+//-----------------------------------------------------------------------------
+/*
+
+Browser API Tests
+
+*/
+//-----------------------------------------------------------------------------
+
+package web
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Model(t *testing.T) {
+	m, err := Compile("print('box') return box(param('w', 10), 5, 5)", map[string]string{"w": "20"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Output() != "box\n" {
+		t.Errorf("bad output %q", m.Output())
+	}
+	if d := m.Evaluate(0, 0, 0); d != -2.5 {
+		t.Errorf("expected -2.5, got %f", d)
+	}
+	if min, max := m.BoundingBox(); min != [3]float64{-10, -2.5, -2.5} || max != [3]float64{10, 2.5, 2.5} {
+		t.Errorf("bad bounding box %v %v", min, max)
+	}
+
+	mesh := m.Mesh(20)
+	if len(mesh.Positions) == 0 || len(mesh.Positions)%9 != 0 || len(mesh.Normals) != len(mesh.Positions) {
+		t.Fatalf("bad mesh %d positions, %d normals", len(mesh.Positions), len(mesh.Normals))
+	}
+	for _, x := range mesh.Positions {
+		if x < -10.001 || x > 10.001 {
+			t.Fatalf("vertex %f outside the bounding box", x)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := m.WriteSTL(&buf, 20); err != nil {
+		t.Fatal(err)
+	}
+	n := binary.LittleEndian.Uint32(buf.Bytes()[80:])
+	if int(n) != len(mesh.Positions)/9 || buf.Len() != 84+50*int(n) {
+		t.Errorf("bad stl, %d triangles in %d bytes", n, buf.Len())
+	}
+	buf.Reset()
+	if err := m.WriteGLB(&buf, 20); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf.Bytes()[:4]) != "glTF" {
+		t.Errorf("bad glb header %q", buf.Bytes()[:4])
+	}
+
+	if _, err := Compile("return 1 +", nil); err == nil {
+		t.Error("expected a syntax error")
+	}
+	if _, err := Compile("return circle(1)", nil); err == nil {
+		t.Error("expected an error for an sdf2 model")
+	}
+}
+
+//-----------------------------------------------------------------------------